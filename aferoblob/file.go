@@ -0,0 +1,399 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package aferoblob
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+var (
+	errIsDirectory  = errors.New("is a directory")
+	errNotDirectory = errors.New("not a directory")
+)
+
+// file is a directory, an object streamed for reading, or an object buffered for writing
+type file struct {
+	fs   *Fs
+	name string
+	info *fileInfo
+
+	// offset is the position of Read, Write and Seek
+	offset int64
+	// reader streams the read-only object from the offset, it's opened by the first Read
+	reader io.ReadCloser
+
+	writable bool
+	append   bool
+	body     []byte
+	dirty    bool
+
+	// entries are the remaining entries of the directory, listed by the first Readdir
+	entries []os.FileInfo
+	listed  bool
+
+	closed bool
+}
+
+var _ afero.File = (*file)(nil)
+
+func newDirFile(fs *Fs, name string) *file {
+	return &file{
+		fs:   fs,
+		name: name,
+		info: &fileInfo{name: path.Base(key(name)), dir: true},
+	}
+}
+
+func newReadFile(fs *Fs, name string, info *fileInfo) *file {
+	return &file{
+		fs:   fs,
+		name: name,
+		info: info,
+	}
+}
+
+func newWriteFile(fs *Fs, name string, body []byte, flag int, dirty bool) *file {
+	return &file{
+		fs:       fs,
+		name:     name,
+		info:     &fileInfo{name: path.Base(key(name))},
+		writable: true,
+		append:   flag&os.O_APPEND != 0,
+		body:     body,
+		dirty:    dirty,
+	}
+}
+
+func (f *file) error(op string, err error) error {
+	return pathError(op, f.name, err)
+}
+
+func (f *file) Name() string {
+	return f.name
+}
+
+// Close uploads the written file
+func (f *file) Close() error {
+	if f.closed {
+		return f.error("close", os.ErrClosed)
+	}
+
+	err := f.Sync()
+	f.closed = true
+
+	if f.reader != nil {
+		f.reader.Close()
+		f.reader = nil
+	}
+
+	return err
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if err := f.check("read"); err != nil {
+		return 0, err
+	}
+
+	if f.writable {
+		n, err := f.ReadAt(p, f.offset)
+		f.offset += int64(n)
+
+		return n, err
+	}
+
+	if f.offset >= f.info.size {
+		return 0, io.EOF
+	}
+
+	if f.reader == nil {
+		reader, err := f.fs.storage.GetRangeReader(f.fs.ctx, key(f.name), f.offset, -1)
+		if err != nil {
+			return 0, f.error("read", err)
+		}
+
+		f.reader = reader
+	}
+
+	n, err := f.reader.Read(p)
+	f.offset += int64(n)
+
+	return n, err
+}
+
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if err := f.check("read"); err != nil {
+		return 0, err
+	}
+
+	if f.writable {
+		if off >= int64(len(f.body)) {
+			return 0, io.EOF
+		}
+
+		n := copy(p, f.body[off:])
+		if n < len(p) {
+			return n, io.EOF
+		}
+
+		return n, nil
+	}
+
+	if off >= f.info.size {
+		return 0, io.EOF
+	}
+
+	reader, err := f.fs.storage.GetRangeReader(f.fs.ctx, key(f.name), off, int64(len(p)))
+	if err != nil {
+		return 0, f.error("read", err)
+	}
+	defer reader.Close()
+
+	n, err := io.ReadFull(reader, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+
+	return n, err
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	if err := f.check("seek"); err != nil {
+		return 0, err
+	}
+
+	size := f.info.size
+	if f.writable {
+		size = int64(len(f.body))
+	}
+
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += size
+	}
+
+	if offset < 0 {
+		return 0, f.error("seek", errors.New("negative offset"))
+	}
+
+	// the stream is reopened at the new offset by the next Read
+	if offset != f.offset && f.reader != nil {
+		f.reader.Close()
+		f.reader = nil
+	}
+
+	f.offset = offset
+
+	return offset, nil
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	if f.append {
+		f.offset = int64(len(f.body))
+	}
+
+	n, err := f.WriteAt(p, f.offset)
+	f.offset += int64(n)
+
+	return n, err
+}
+
+func (f *file) WriteAt(p []byte, off int64) (int, error) {
+	if err := f.check("write"); err != nil {
+		return 0, err
+	}
+
+	if !f.writable {
+		return 0, f.error("write", os.ErrPermission)
+	}
+
+	if end := off + int64(len(p)); end > int64(len(f.body)) {
+		f.body = append(f.body, make([]byte, end-int64(len(f.body)))...)
+	}
+
+	copy(f.body[off:], p)
+	f.dirty = true
+
+	return len(p), nil
+}
+
+func (f *file) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func (f *file) Truncate(size int64) error {
+	if err := f.check("truncate"); err != nil {
+		return err
+	}
+
+	if !f.writable {
+		return f.error("truncate", os.ErrPermission)
+	}
+
+	if size < int64(len(f.body)) {
+		f.body = f.body[:size]
+	} else {
+		f.body = append(f.body, make([]byte, size-int64(len(f.body)))...)
+	}
+
+	f.dirty = true
+
+	return nil
+}
+
+// Sync uploads the written file
+func (f *file) Sync() error {
+	if !f.dirty {
+		return nil
+	}
+
+	if err := f.fs.storage.Write(f.fs.ctx, key(f.name), f.body, nil); err != nil {
+		return f.error("sync", err)
+	}
+
+	f.dirty = false
+
+	return nil
+}
+
+func (f *file) Stat() (os.FileInfo, error) {
+	if f.writable {
+		return &fileInfo{
+			name:    f.info.name,
+			size:    int64(len(f.body)),
+			modTime: time.Now(),
+		}, nil
+	}
+
+	return f.info, nil
+}
+
+// Readdir lists the files and directories directly under the directory, sorted by name
+func (f *file) Readdir(count int) ([]os.FileInfo, error) {
+	if f.closed {
+		return nil, f.error("readdir", os.ErrClosed)
+	}
+
+	if !f.info.dir {
+		return nil, f.error("readdir", errNotDirectory)
+	}
+
+	if !f.listed {
+		if err := f.list(); err != nil {
+			return nil, err
+		}
+	}
+
+	if count <= 0 {
+		entries := f.entries
+		f.entries = nil
+
+		return entries, nil
+	}
+
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	if count > len(f.entries) {
+		count = len(f.entries)
+	}
+
+	entries := f.entries[:count]
+	f.entries = f.entries[count:]
+
+	return entries, nil
+}
+
+func (f *file) Readdirnames(n int) ([]string, error) {
+	entries, err := f.Readdir(n)
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+
+	return names, err
+}
+
+// list groups the objects under the directory by their first path segment
+func (f *file) list() error {
+	prefix := key(f.name)
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	dirs := make(map[string]bool)
+	iter := f.fs.storage.List(f.fs.ctx, prefix)
+
+	for {
+		object, err := iter.Next(f.fs.ctx)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return f.error("readdir", err)
+		}
+
+		name := strings.TrimPrefix(object.Key, prefix)
+
+		if i := strings.Index(name, "/"); i >= 0 {
+			if dir := name[:i]; !dirs[dir] {
+				dirs[dir] = true
+				f.entries = append(f.entries, &fileInfo{name: dir, dir: true})
+			}
+
+			continue
+		}
+
+		f.entries = append(f.entries, &fileInfo{
+			name:    name,
+			size:    object.Size,
+			modTime: object.ModTime,
+		})
+	}
+
+	sort.Slice(f.entries, func(i, j int) bool {
+		return f.entries[i].Name() < f.entries[j].Name()
+	})
+
+	f.listed = true
+
+	return nil
+}
+
+func (f *file) check(op string) error {
+	if f.closed {
+		return f.error(op, os.ErrClosed)
+	}
+
+	if f.info.dir {
+		return f.error(op, errIsDirectory)
+	}
+
+	return nil
+}
@@ -0,0 +1,297 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+// Package aferoblob exposes a CloudStorage bucket as an afero.Fs.
+//
+// The object keys are the slash-separated paths of the files, without the leading slash.
+// The directories are implied by the keys: a directory exists as long as it contains a file,
+// so Mkdir and MkdirAll don't store anything.
+package aferoblob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	commonblobgo "github.com/AccelByte/common-blob-go"
+	"github.com/spf13/afero"
+)
+
+// ErrNotEmpty is returned by Remove for a directory containing files
+var ErrNotEmpty = errors.New("directory not empty")
+
+// Fs is an afero.Fs reading and writing the objects of a CloudStorage.
+// The files opened for writing are buffered in memory and uploaded when they are synced or closed.
+type Fs struct {
+	ctx     context.Context
+	storage commonblobgo.CloudStorage
+}
+
+var _ afero.Fs = (*Fs)(nil)
+
+// New returns an Fs over the storage. The requests are made with ctx, since afero doesn't take any.
+func New(ctx context.Context, storage commonblobgo.CloudStorage) *Fs {
+	return &Fs{
+		ctx:     ctx,
+		storage: storage,
+	}
+}
+
+// key returns the object key of the file name, "" being the root directory
+func key(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// pathError converts the not found errors of the storage to os.ErrNotExist
+func pathError(op string, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, commonblobgo.ErrNotFound) {
+		err = os.ErrNotExist
+	}
+
+	return &os.PathError{Op: op, Path: name, Err: err}
+}
+
+func (fs *Fs) Name() string {
+	return "aferoblob"
+}
+
+// Create creates or truncates the file
+func (fs *Fs) Create(name string) (afero.File, error) {
+	return fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+}
+
+// Mkdir doesn't store anything, since the directories only exist through their files
+func (fs *Fs) Mkdir(name string, perm os.FileMode) error {
+	return nil
+}
+
+// MkdirAll doesn't store anything, since the directories only exist through their files
+func (fs *Fs) MkdirAll(path string, perm os.FileMode) error {
+	return nil
+}
+
+func (fs *Fs) Open(name string) (afero.File, error) {
+	return fs.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (fs *Fs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	info, err := fs.stat(name)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	exists := err == nil
+
+	if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		if !exists {
+			return nil, err
+		}
+
+		if info.IsDir() {
+			return newDirFile(fs, name), nil
+		}
+
+		return newReadFile(fs, name, info), nil
+	}
+
+	switch {
+	case exists && info.IsDir():
+		return nil, &os.PathError{Op: "open", Path: name, Err: errIsDirectory}
+	case exists && flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	case !exists && flag&os.O_CREATE == 0:
+		return nil, err
+	}
+
+	var body []byte
+
+	if exists && flag&os.O_TRUNC == 0 {
+		body, err = fs.storage.Get(fs.ctx, key(name))
+		if err != nil {
+			return nil, pathError("open", name, err)
+		}
+	}
+
+	// a new or truncated file is stored on close even if nothing is written
+	return newWriteFile(fs, name, body, flag, !exists || flag&os.O_TRUNC != 0), nil
+}
+
+// Remove deletes the file, or the directory if it contains no file
+func (fs *Fs) Remove(name string) error {
+	err := fs.storage.Delete(fs.ctx, key(name))
+	if err == nil || !errors.Is(err, commonblobgo.ErrNotFound) {
+		return pathError("remove", name, err)
+	}
+
+	info, statErr := fs.stat(name)
+	if statErr != nil {
+		return statErr
+	}
+
+	if info.IsDir() && key(name) != "" {
+		return &os.PathError{Op: "remove", Path: name, Err: ErrNotEmpty}
+	}
+
+	return pathError("remove", name, err)
+}
+
+// RemoveAll deletes the file, or all the files of the directory
+func (fs *Fs) RemoveAll(path string) error {
+	prefix := key(path)
+
+	err := fs.storage.Delete(fs.ctx, prefix)
+	if err != nil && !errors.Is(err, commonblobgo.ErrNotFound) {
+		return pathError("removeall", path, err)
+	}
+
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	iter := fs.storage.List(fs.ctx, prefix)
+
+	for {
+		object, err := iter.Next(fs.ctx)
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return pathError("removeall", path, err)
+		}
+
+		if err := fs.storage.Delete(fs.ctx, object.Key); err != nil && !errors.Is(err, commonblobgo.ErrNotFound) {
+			return pathError("removeall", path, err)
+		}
+	}
+}
+
+// Rename copies the file to the new name then deletes it, the directories can't be renamed
+func (fs *Fs) Rename(oldname, newname string) error {
+	reader, attrs, err := fs.storage.GetWithAttributes(fs.ctx, key(oldname))
+	if err != nil {
+		return pathError("rename", oldname, err)
+	}
+	defer reader.Close()
+
+	err = fs.storage.Upload(fs.ctx, key(newname), reader, &commonblobgo.UploadOption{
+		ContentType: attrs.ContentType,
+	})
+	if err != nil {
+		return pathError("rename", newname, err)
+	}
+
+	return pathError("rename", oldname, fs.storage.Delete(fs.ctx, key(oldname)))
+}
+
+func (fs *Fs) Stat(name string) (os.FileInfo, error) {
+	info, err := fs.stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+func (fs *Fs) stat(name string) (*fileInfo, error) {
+	objectKey := key(name)
+	if objectKey == "" {
+		return &fileInfo{name: "/", dir: true}, nil
+	}
+
+	attrs, err := fs.storage.Attributes(fs.ctx, objectKey)
+	if err == nil {
+		return &fileInfo{
+			name:    path.Base(objectKey),
+			size:    attrs.Size,
+			modTime: attrs.ModTime,
+		}, nil
+	}
+
+	if !errors.Is(err, commonblobgo.ErrNotFound) {
+		return nil, pathError("stat", name, err)
+	}
+
+	// a directory exists when at least one object is under its prefix
+	_, err = fs.storage.List(fs.ctx, objectKey+"/").Next(fs.ctx)
+	if err == io.EOF {
+		return nil, pathError("stat", name, os.ErrNotExist)
+	}
+
+	if err != nil {
+		return nil, pathError("stat", name, err)
+	}
+
+	return &fileInfo{name: path.Base(objectKey), dir: true}, nil
+}
+
+// Chmod does nothing, the objects have no permissions
+func (fs *Fs) Chmod(name string, mode os.FileMode) error {
+	return nil
+}
+
+// Chown does nothing, the objects have no owner
+func (fs *Fs) Chown(name string, uid, gid int) error {
+	return nil
+}
+
+// Chtimes does nothing, the modification time of the objects is set by the provider
+func (fs *Fs) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return nil
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (fi *fileInfo) Name() string {
+	return fi.name
+}
+
+func (fi *fileInfo) Size() int64 {
+	return fi.size
+}
+
+func (fi *fileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return os.ModeDir | 0755
+	}
+
+	return 0644
+}
+
+func (fi *fileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+func (fi *fileInfo) IsDir() bool {
+	return fi.dir
+}
+
+func (fi *fileInfo) Sys() interface{} {
+	return nil
+}
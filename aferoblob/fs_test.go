@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package aferoblob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	commonblobgo "github.com/AccelByte/common-blob-go"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFsReadWrite(t *testing.T) {
+	fs := New(context.Background(), commonblobgo.NewFakeCloudStorage("bucket"))
+
+	require.NoError(t, fs.MkdirAll("/folder/sub", 0755))
+	require.NoError(t, afero.WriteFile(fs, "/folder/a.txt", []byte("0123456789"), 0644))
+
+	body, err := afero.ReadFile(fs, "/folder/a.txt")
+	require.NoError(t, err)
+	require.Equal(t, []byte("0123456789"), body)
+
+	f, err := fs.Open("/folder/a.txt")
+	require.NoError(t, err)
+
+	_, err = f.Seek(4, io.SeekStart)
+	require.NoError(t, err)
+
+	body, err = ioutil.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, []byte("456789"), body)
+
+	buf := make([]byte, 3)
+	_, err = f.ReadAt(buf, 2)
+	require.NoError(t, err)
+	require.Equal(t, []byte("234"), buf)
+	require.NoError(t, f.Close())
+
+	f, err = fs.OpenFile("/folder/a.txt", os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+
+	_, err = f.WriteString("abc")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	body, err = afero.ReadFile(fs, "folder/a.txt")
+	require.NoError(t, err)
+	require.Equal(t, []byte("0123456789abc"), body)
+
+	_, err = fs.OpenFile("/folder/a.txt", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	require.True(t, os.IsExist(err))
+
+	_, err = fs.Open("/missing")
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestFsDirectories(t *testing.T) {
+	fs := New(context.Background(), commonblobgo.NewFakeCloudStorage("bucket"))
+
+	require.NoError(t, afero.WriteFile(fs, "/folder/a.txt", []byte("a"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/folder/sub/b.txt", []byte("b"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/folder/sub/c.txt", []byte("c"), 0644))
+
+	info, err := fs.Stat("/folder/sub")
+	require.NoError(t, err)
+	require.True(t, info.IsDir())
+
+	infos, err := afero.ReadDir(fs, "/folder")
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	require.Equal(t, "a.txt", infos[0].Name())
+	require.False(t, infos[0].IsDir())
+	require.Equal(t, "sub", infos[1].Name())
+	require.True(t, infos[1].IsDir())
+
+	var walked []string
+
+	require.NoError(t, afero.Walk(fs, "/", func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			walked = append(walked, path)
+		}
+
+		return err
+	}))
+	require.Equal(t, []string{"/folder/a.txt", "/folder/sub/b.txt", "/folder/sub/c.txt"}, walked)
+
+	require.True(t, errors.Is(fs.Remove("/folder/sub"), ErrNotEmpty))
+
+	require.NoError(t, fs.Rename("/folder/a.txt", "/folder/sub/a.txt"))
+	require.NoError(t, fs.RemoveAll("/folder/sub"))
+
+	_, err = fs.Stat("/folder")
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"cloud.google.com/go/storage"
+	"gocloud.dev/blob/gcsblob"
+	"gocloud.dev/gcp"
+	"google.golang.org/api/option"
+)
+
+// newAnonymousGCPCloudStorage returns an ExplicitGCPCloudStorage sending the requests without credentials,
+// for the public buckets. It can't sign, so the read-only interceptor rejects the operations needing credentials.
+func newAnonymousGCPCloudStorage(
+	ctx context.Context,
+	bucketName string,
+	cloudStorageOpts *CloudStorageOption,
+) (*ExplicitGCPCloudStorage, error) {
+	httpClient := gcp.NewAnonymousHTTPClient(newGCPTransport(cloudStorageOpts))
+
+	client, err := storage.NewClient(ctx, option.WithHTTPClient(&http.Client{Transport: httpClient.Transport}))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create GCP client: %v", err)
+	}
+
+	bucket, err := gcsblob.OpenBucket(ctx, httpClient, bucketName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := loggerOrNoop(cloudStorageOpts.Logger)
+	logger.Info("anonymous GCP CloudStorage created", Fields{"bucket": bucketName})
+
+	return &ExplicitGCPCloudStorage{
+		client:            client,
+		bucketName:        bucketName,
+		bucket:            bucket,
+		notificationQueue: notificationQueueName(cloudStorageOpts, bucketName),
+		userProject:       cloudStorageOpts.GCPUserProject,
+		folders:           newGCPFolders(nil, bucketName, cloudStorageOpts),
+		logger:            logger,
+		clock:             clockOrNow(cloudStorageOpts.Clock),
+		bucketCloseFunc: func() {
+			bucket.Close()
+		},
+	}, nil
+}
+
+// newReadOnlyInterceptor rejects the operations changing the bucket or granting access to it with
+// ErrPermissionDenied, since the anonymous clients can't sign them
+func newReadOnlyInterceptor() Interceptor {
+	return func(ctx context.Context, op *OperationInfo, next func(ctx context.Context) error) error {
+		if auditedOperations[op.Name] || op.Name == "ResumeUpload" {
+			return fmt.Errorf("%w: the anonymous client is read-only", ErrPermissionDenied)
+		}
+
+		return next(ctx)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnonymousAWSCloudStorage(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		mu            sync.Mutex
+		authorization []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("public"))
+	}))
+	defer server.Close()
+
+	storage, err := NewCloudStorageWithOption(ctx, false, "aws", "bucket", CloudStorageOption{
+		AWSS3Endpoint:        server.URL,
+		AWSS3Region:          "us-east-1",
+		AWSS3AccessKeyID:     "ignored",
+		AWSS3SecretAccessKey: "ignored",
+		Anonymous:            true,
+	})
+	require.NoError(t, err)
+
+	body, err := storage.Get(ctx, "file.txt")
+	require.NoError(t, err)
+	require.Equal(t, "public", string(body))
+
+	mu.Lock()
+	require.Equal(t, []string{""}, authorization)
+	mu.Unlock()
+
+	// the writes are rejected without any request
+	require.True(t, errors.Is(storage.Write(ctx, "file.txt", []byte("body"), nil), ErrPermissionDenied))
+	require.True(t, errors.Is(storage.Delete(ctx, "file.txt"), ErrPermissionDenied))
+
+	_, err = storage.GetSignedURL(ctx, "file.txt", &SignedURLOption{Method: http.MethodGet})
+	require.True(t, errors.Is(err, ErrPermissionDenied))
+
+	mu.Lock()
+	require.Len(t, authorization, 1)
+	mu.Unlock()
+}
+
+func TestAnonymousGCPCloudStorage(t *testing.T) {
+	ctx := context.Background()
+
+	// no credentials are needed outside of GCP
+	storage, err := NewCloudStorageWithOption(ctx, false, "gcp", "bucket", CloudStorageOption{Anonymous: true})
+	require.NoError(t, err)
+	defer storage.Close()
+
+	require.True(t, errors.Is(storage.Write(ctx, "file.txt", []byte("body"), nil), ErrPermissionDenied))
+
+	_, err = storage.GetWriter(ctx, "file.txt")
+	require.True(t, errors.Is(err, ErrPermissionDenied))
+}
@@ -0,0 +1,322 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+)
+
+// ArchiveFormat is the format of the archives written by ArchivePrefix and read by ExtractArchive
+type ArchiveFormat string
+
+const (
+	ArchiveTarGz ArchiveFormat = "tar.gz"
+	ArchiveZip   ArchiveFormat = "zip"
+	ArchiveTar   ArchiveFormat = "tar"
+)
+
+// archiveWriter adds the objects to an archive
+type archiveWriter interface {
+	add(object *ListObject, name string) (io.Writer, error)
+	Close() error
+}
+
+// ArchivePrefix streams all the objects under the prefix to w as a tar, tar.gz or zip archive, one object at a time.
+// The entries are named after the keys without the prefix. Nothing is buffered besides the compression window,
+// so the archive of a large prefix can be sent as an HTTP response.
+func ArchivePrefix(
+	ctx context.Context,
+	storage CloudStorage,
+	prefix string,
+	w io.Writer,
+	format ArchiveFormat,
+) error {
+	archive, err := newArchiveWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	if err := archivePrefix(ctx, storage, prefix, archive); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// archivePrefix adds the objects under the prefix to the archive, without closing it
+func archivePrefix(ctx context.Context, storage CloudStorage, prefix string, archive archiveWriter) error {
+	iter := storage.List(ctx, prefix)
+
+	for {
+		object, err := iter.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimPrefix(strings.TrimPrefix(object.Key, prefix), "/")
+		if name == "" || strings.HasSuffix(name, "/") {
+			// the folder placeholders have no content
+			continue
+		}
+
+		if err := archiveObject(ctx, storage, archive, object, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func archiveObject(
+	ctx context.Context,
+	storage CloudStorage,
+	archive archiveWriter,
+	object *ListObject,
+	name string,
+) error {
+	reader, err := storage.GetReader(ctx, object.Key)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	entry, err := archive.add(object, name)
+	if err != nil {
+		return err
+	}
+
+	// the tar header already gives the listed size, an object changed since the listing can't be written
+	n, err := io.Copy(entry, io.LimitReader(reader, object.Size+1))
+	if err != nil {
+		return err
+	}
+
+	if n != object.Size {
+		return fmt.Errorf("object '%s' changed while being archived: %d bytes listed, %d bytes read",
+			object.Key, object.Size, n)
+	}
+
+	return nil
+}
+
+func newArchiveWriter(w io.Writer, format ArchiveFormat) (archiveWriter, error) {
+	switch format {
+	case ArchiveTarGz:
+		gzipWriter := gzip.NewWriter(w)
+
+		return &tarWriter{gzip: gzipWriter, tar: tar.NewWriter(gzipWriter)}, nil
+	case ArchiveTar:
+		return &tarWriter{tar: tar.NewWriter(w)}, nil
+	case ArchiveZip:
+		return &zipWriter{zip: zip.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported archive format '%s'", format)
+	}
+}
+
+type tarWriter struct {
+	// gzip is nil for the uncompressed archives
+	gzip *gzip.Writer
+	tar  *tar.Writer
+}
+
+func (a *tarWriter) add(object *ListObject, name string) (io.Writer, error) {
+	err := a.tar.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     object.Size,
+		Mode:     0644,
+		ModTime:  object.ModTime,
+	})
+
+	return a.tar, err
+}
+
+func (a *tarWriter) Close() error {
+	if err := a.tar.Close(); err != nil || a.gzip == nil {
+		return err
+	}
+
+	return a.gzip.Close()
+}
+
+type zipWriter struct {
+	zip *zip.Writer
+}
+
+func (a *zipWriter) add(object *ListObject, name string) (io.Writer, error) {
+	header := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: object.ModTime,
+	}
+	header.SetMode(0644)
+
+	return a.zip.CreateHeader(header)
+}
+
+func (a *zipWriter) Close() error {
+	return a.zip.Close()
+}
+
+// ExtractArchive writes every file of the tar, tar.gz or zip object as an object under dstPrefix, and returns their keys.
+// The format is detected from the content. The entries are uploaded one at a time while being read,
+// the zip central directory is read with range requests, so nothing is stored on the disk.
+// The entries escaping dstPrefix with ".." are rejected, the directories and links are skipped.
+func ExtractArchive(
+	ctx context.Context,
+	storage CloudStorage,
+	key string,
+	dstPrefix string,
+) ([]string, error) {
+	attrs, err := storage.Attributes(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	content := &objectReadSeeker{
+		ctx:     ctx,
+		storage: storage,
+		key:     key,
+		size:    attrs.Size,
+	}
+	defer content.Close()
+
+	buffered := bufio.NewReader(content)
+
+	magic, err := buffered.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK")):
+		return extractZip(ctx, storage, content, attrs.Size, dstPrefix)
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gzipReader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+
+		return extractTar(ctx, storage, gzipReader, dstPrefix)
+	default:
+		return extractTar(ctx, storage, buffered, dstPrefix)
+	}
+}
+
+func extractTar(ctx context.Context, storage CloudStorage, reader io.Reader, dstPrefix string) ([]string, error) {
+	archive := tar.NewReader(reader)
+
+	var keys []string
+
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return keys, nil
+		}
+
+		if err != nil {
+			return keys, err
+		}
+
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		key, err := extractObject(ctx, storage, header.Name, archive, dstPrefix)
+		if err != nil {
+			return keys, err
+		}
+
+		keys = append(keys, key)
+	}
+}
+
+func extractZip(
+	ctx context.Context,
+	storage CloudStorage,
+	content io.ReaderAt,
+	size int64,
+	dstPrefix string,
+) ([]string, error) {
+	archive, err := zip.NewReader(content, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+
+	for _, file := range archive.File {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+
+		entry, err := file.Open()
+		if err != nil {
+			return keys, err
+		}
+
+		key, err := extractObject(ctx, storage, file.Name, entry, dstPrefix)
+		entry.Close()
+
+		if err != nil {
+			return keys, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func extractObject(
+	ctx context.Context,
+	storage CloudStorage,
+	name string,
+	reader io.Reader,
+	dstPrefix string,
+) (string, error) {
+	name = strings.Replace(name, "\\", "/", -1)
+
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("archive entry '%s' escapes the prefix", name)
+		}
+	}
+
+	key := dstPrefix + strings.TrimPrefix(path.Clean("/"+name), "/")
+
+	err := storage.Upload(ctx, key, reader, &UploadOption{
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+	})
+
+	return key, err
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newArchiveTestStorage(t *testing.T) CloudStorage {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.Write(ctx, "users/1/profile.json", []byte(`{"name": "user"}`), nil))
+	require.NoError(t, storage.Write(ctx, "users/1/avatars/a.png", []byte("png"), nil))
+	require.NoError(t, storage.Write(ctx, "users/2/profile.json", []byte("other user"), nil))
+
+	return storage
+}
+
+func TestArchivePrefixTarGz(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, ArchivePrefix(context.Background(), newArchiveTestStorage(t), "users/1/", &buf, ArchiveTarGz))
+
+	gzipReader, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+
+	reader := tar.NewReader(gzipReader)
+	entries := make(map[string]string)
+
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+
+		body, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+
+		entries[header.Name] = string(body)
+	}
+
+	require.Equal(t, map[string]string{
+		"avatars/a.png": "png",
+		"profile.json":  `{"name": "user"}`,
+	}, entries)
+}
+
+func TestArchivePrefixZip(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, ArchivePrefix(context.Background(), newArchiveTestStorage(t), "users/1", &buf, ArchiveZip))
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, reader.File, 2)
+
+	file, err := reader.File[0].Open()
+	require.NoError(t, err)
+
+	body, err := ioutil.ReadAll(file)
+	require.NoError(t, err)
+	require.Equal(t, "avatars/a.png", reader.File[0].Name)
+	require.Equal(t, "png", string(body))
+}
+
+func TestArchivePrefixUnsupportedFormat(t *testing.T) {
+	require.Error(t, ArchivePrefix(context.Background(), newArchiveTestStorage(t), "", ioutil.Discard, "rar"))
+}
+
+func TestExtractArchive(t *testing.T) {
+	for _, format := range []ArchiveFormat{ArchiveTar, ArchiveTarGz, ArchiveZip} {
+		t.Run(string(format), func(t *testing.T) {
+			ctx := context.Background()
+			storage := newArchiveTestStorage(t)
+
+			var buf bytes.Buffer
+
+			require.NoError(t, ArchivePrefix(ctx, storage, "users/1/", &buf, format))
+			require.NoError(t, storage.Write(ctx, "uploads/archive", buf.Bytes(), nil))
+
+			keys, err := ExtractArchive(ctx, storage, "uploads/archive", "extracted/")
+			require.NoError(t, err)
+			require.Equal(t, []string{"extracted/avatars/a.png", "extracted/profile.json"}, keys)
+
+			body, err := storage.Get(ctx, "extracted/profile.json")
+			require.NoError(t, err)
+			require.Equal(t, `{"name": "user"}`, string(body))
+		})
+	}
+}
+
+func TestExtractArchiveRejectsTraversal(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	var buf bytes.Buffer
+
+	writer := tar.NewWriter(&buf)
+	require.NoError(t, writer.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "../escape", Size: 1, Mode: 0644}))
+	_, err := writer.Write([]byte("x"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	require.NoError(t, storage.Write(ctx, "uploads/archive.tar", buf.Bytes(), nil))
+
+	_, err = ExtractArchive(ctx, storage, "uploads/archive.tar", "extracted/")
+	require.Error(t, err)
+}
@@ -0,0 +1,310 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+)
+
+const (
+	defaultAsyncQueueSize = 1000
+	defaultAsyncWorkers   = 4
+)
+
+// ErrAsyncWriterClosed is returned by Write once the AsyncCloudStorage has been closed
+var ErrAsyncWriterClosed = errors.New("async writer is closed")
+
+// AsyncWriteOption configures the write-behind queue.
+type AsyncWriteOption struct {
+	// QueueSize is the maximum number of pending writes, shared out between the workers. Write blocks while the
+	// queue of the worker of its key is full. Defaults to 1000.
+	QueueSize int
+	// Workers is the number of background workers flushing the queue. The writes of a key always go to the same
+	// worker, so they're uploaded in order. Defaults to 4.
+	Workers int
+	// RetryPolicy is applied to every queued write. The default policy is used when it's nil.
+	RetryPolicy *RetryPolicy
+	// OnError is called with the writes which failed after all the retries. They are logged when it's nil.
+	OnError func(key string, err error)
+	// Logger receives the failed writes when OnError is nil. They are discarded when it's nil.
+	Logger Logger
+}
+
+// Flusher is implemented by the CloudStorage created with the AsyncWrite option
+type Flusher interface {
+	// Flush waits until all the writes queued so far are uploaded or ctx is done
+	Flush(ctx context.Context) error
+}
+
+// flushStorage flushes storage when it implements Flusher, so the wrappers don't hide the Flusher of the storage
+// they wrap
+func flushStorage(ctx context.Context, storage CloudStorage) error {
+	if flusher, ok := storage.(Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+
+	return nil
+}
+
+type asyncWrite struct {
+	seq         uint64
+	key         string
+	body        []byte
+	contentType *string
+	opts        []WriteOption
+}
+
+// AsyncCloudStorage enqueues Write calls and uploads them in the background.
+// Reads don't see the writes which are still in the queue.
+// WriteIf is sent right away, since its result depends on the current object. Delete and DeleteIf wait for the
+// queued writes of the key first, so they can't be undone by an earlier write.
+type AsyncCloudStorage struct {
+	CloudStorage
+
+	opts   AsyncWriteOption
+	policy RetryPolicy
+	queues []chan asyncWrite
+
+	mu        sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+	workers   sync.WaitGroup
+
+	// pending are the keys of the queued writes by sequence number, changed is closed and replaced every time
+	// a write is done
+	pendingMu sync.Mutex
+	seq       uint64
+	pending   map[uint64]string
+	changed   chan struct{}
+}
+
+// NewAsyncCloudStorage wraps the storage with a write-behind queue and starts its workers
+func NewAsyncCloudStorage(storage CloudStorage, opts AsyncWriteOption) *AsyncCloudStorage {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultAsyncQueueSize
+	}
+
+	if opts.Workers <= 0 {
+		opts.Workers = defaultAsyncWorkers
+	}
+
+	opts.Logger = loggerOrNoop(opts.Logger)
+
+	var policy RetryPolicy
+	if opts.RetryPolicy != nil {
+		policy = *opts.RetryPolicy
+	}
+
+	ts := &AsyncCloudStorage{
+		CloudStorage: storage,
+		opts:         opts,
+		policy:       policy.withDefaults(),
+		queues:       make([]chan asyncWrite, opts.Workers),
+		pending:      map[uint64]string{},
+		changed:      make(chan struct{}),
+	}
+
+	ts.workers.Add(opts.Workers)
+
+	for i := range ts.queues {
+		ts.queues[i] = make(chan asyncWrite, (opts.QueueSize+opts.Workers-1)/opts.Workers)
+		go ts.work(ts.queues[i])
+	}
+
+	return ts
+}
+
+// queue returns the queue of the worker of the key, the writes of a key being uploaded in order by a single worker
+func (ts *AsyncCloudStorage) queue(key string) chan asyncWrite {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+
+	return ts.queues[hash.Sum32()%uint32(len(ts.queues))]
+}
+
+func (ts *AsyncCloudStorage) work(queue chan asyncWrite) {
+	defer ts.workers.Done()
+
+	for write := range queue {
+		write := write
+
+		// the context of the caller is usually gone by the time the write is flushed
+		err := ts.policy.do(context.Background(), func() error {
+			return ts.CloudStorage.Write(context.Background(), write.key, write.body, write.contentType, write.opts...)
+		})
+		if err != nil {
+			if ts.opts.OnError != nil {
+				ts.opts.OnError(write.key, err)
+			} else {
+				ts.opts.Logger.Error("unable to write asynchronously", Fields{"key": write.key, "error": err})
+			}
+		}
+
+		ts.done(write.seq)
+	}
+}
+
+// enqueue records the pending write of the key and returns its sequence number
+func (ts *AsyncCloudStorage) enqueue(key string) uint64 {
+	ts.pendingMu.Lock()
+	defer ts.pendingMu.Unlock()
+
+	ts.seq++
+	ts.pending[ts.seq] = key
+
+	return ts.seq
+}
+
+// done removes the pending write and wakes up the waiting calls
+func (ts *AsyncCloudStorage) done(seq uint64) {
+	ts.pendingMu.Lock()
+	defer ts.pendingMu.Unlock()
+
+	delete(ts.pending, seq)
+	close(ts.changed)
+	ts.changed = make(chan struct{})
+}
+
+// wait waits until the writes queued so far, or only those of the key when it's not nil, are done
+func (ts *AsyncCloudStorage) wait(ctx context.Context, key *string) error {
+	ts.pendingMu.Lock()
+	last := ts.seq
+	ts.pendingMu.Unlock()
+
+	for {
+		ts.pendingMu.Lock()
+
+		waiting := false
+
+		for seq, pendingKey := range ts.pending {
+			if seq <= last && (key == nil || pendingKey == *key) {
+				waiting = true
+				break
+			}
+		}
+
+		changed := ts.changed
+		ts.pendingMu.Unlock()
+
+		if !waiting {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Write enqueues the object and returns as soon as it's queued.
+// The body is copied, so the caller can reuse it.
+// The attributes of WithWrittenAttributes are filled once the object is uploaded, they can be read after Flush.
+func (ts *AsyncCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	if ts.closed {
+		return ErrAsyncWriterClosed
+	}
+
+	write := asyncWrite{
+		seq:         ts.enqueue(key),
+		key:         key,
+		body:        append([]byte(nil), body...),
+		contentType: contentType,
+		opts:        opts,
+	}
+
+	select {
+	case ts.queue(key) <- write:
+		return nil
+	case <-ctx.Done():
+		ts.done(write.seq)
+		return ctx.Err()
+	}
+}
+
+// Delete waits for the queued writes of the key first, which would bring the object back otherwise
+func (ts *AsyncCloudStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	if err := ts.wait(ctx, &key); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.Delete(ctx, key)
+}
+
+// DeleteIf waits for the queued writes of the key first, the generation being the one of the latest write
+func (ts *AsyncCloudStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	if err := ts.wait(ctx, &key); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.DeleteIf(ctx, key, generation)
+}
+
+// Copy waits for the queued writes first, so the source written asynchronously is copied
+func (ts *AsyncCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	if err := ts.Flush(ctx); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.Copy(ctx, srcKey, dstKey, opts)
+}
+
+// Flush waits until all the writes queued so far are uploaded or ctx is done
+// The writes queued after the call aren't waited for.
+func (ts *AsyncCloudStorage) Flush(ctx context.Context) error {
+	return ts.wait(ctx, nil)
+}
+
+// Close stops accepting writes, drains the queue and closes the wrapped storage
+func (ts *AsyncCloudStorage) Close() {
+	ts.closeOnce.Do(func() {
+		ts.mu.Lock()
+		ts.closed = true
+		for _, queue := range ts.queues {
+			close(queue)
+		}
+		ts.mu.Unlock()
+
+		ts.workers.Wait()
+		ts.CloudStorage.Close()
+	})
+}
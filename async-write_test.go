@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingCloudStorage struct {
+	CloudStorage
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (ts *recordingCloudStorage) Write(ctx context.Context, key string, body []byte, contentType *string, opts ...WriteOption) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.objects[key] = body
+
+	return nil
+}
+
+func (ts *recordingCloudStorage) Close() {}
+
+func TestAsyncCloudStorage(t *testing.T) {
+	ctx := context.Background()
+	backend := &recordingCloudStorage{objects: make(map[string][]byte)}
+	storage := NewAsyncCloudStorage(backend, AsyncWriteOption{QueueSize: 2, Workers: 2})
+
+	body := []byte("body")
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, storage.Write(ctx, fmt.Sprintf("key-%d", i), body, nil))
+	}
+
+	// the queued body is a copy
+	body[0] = 'x'
+
+	require.NoError(t, storage.Flush(ctx))
+	require.Len(t, backend.objects, 10)
+	require.Equal(t, []byte("body"), backend.objects["key-9"])
+
+	storage.Close()
+	require.Equal(t, ErrAsyncWriterClosed, storage.Write(ctx, "key", body, nil))
+}
+
+// gatedCloudStorage blocks the writes until the gate is opened
+type gatedCloudStorage struct {
+	CloudStorage
+	gate chan struct{}
+}
+
+func (ts *gatedCloudStorage) Write(ctx context.Context, key string, body []byte, contentType *string, opts ...WriteOption) error {
+	<-ts.gate
+
+	return ts.CloudStorage.Write(ctx, key, body, contentType, opts...)
+}
+
+func TestAsyncCloudStorageDelete(t *testing.T) {
+	ctx := context.Background()
+	backend := &gatedCloudStorage{CloudStorage: NewFakeCloudStorage("bucket"), gate: make(chan struct{})}
+	storage := NewAsyncCloudStorage(backend, AsyncWriteOption{Workers: 2})
+
+	require.NoError(t, storage.Write(ctx, "a.txt", []byte("a"), nil))
+	require.NoError(t, storage.Write(ctx, "b.txt", []byte("b"), nil))
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	// the deletes and the flushes wait for the queued writes
+	require.ErrorIs(t, storage.Flush(timeout), context.DeadlineExceeded)
+	require.ErrorIs(t, storage.Delete(timeout, "a.txt"), context.DeadlineExceeded)
+
+	deleted := make(chan error, 1)
+
+	go func() {
+		deleted <- storage.Delete(ctx, "a.txt")
+	}()
+
+	close(backend.gate)
+	require.NoError(t, <-deleted)
+	require.NoError(t, storage.Flush(ctx))
+
+	_, err := storage.Get(ctx, "a.txt")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	attrs, err := storage.Attributes(ctx, "b.txt")
+	require.NoError(t, err)
+
+	require.NoError(t, storage.Write(ctx, "b.txt", []byte("c"), nil))
+	require.ErrorIs(t, storage.DeleteIf(ctx, "b.txt", attrs.Generation), ErrPreconditionFailed)
+
+	storage.Close()
+}
+
+// slowCloudStorage delays the writes of a body, so a later write could overtake them
+type slowCloudStorage struct {
+	CloudStorage
+	slow string
+}
+
+func (ts *slowCloudStorage) Write(ctx context.Context, key string, body []byte, contentType *string, opts ...WriteOption) error {
+	if string(body) == ts.slow {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	return ts.CloudStorage.Write(ctx, key, body, contentType, opts...)
+}
+
+func TestAsyncCloudStorageWriteOrder(t *testing.T) {
+	ctx := context.Background()
+	backend := &slowCloudStorage{CloudStorage: NewFakeCloudStorage("bucket"), slow: "v1"}
+	storage := NewAsyncCloudStorage(backend, AsyncWriteOption{Workers: 4})
+
+	// the second write of the key is the last one, even though the first one is slower
+	require.NoError(t, storage.Write(ctx, "key", []byte("v1"), nil))
+	require.NoError(t, storage.Write(ctx, "key", []byte("v2"), nil))
+	require.NoError(t, storage.Flush(ctx))
+
+	body, err := storage.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), body)
+
+	storage.Close()
+}
+
+// the wrappers forward Flush, so the Flusher of the storage they wrap isn't hidden behind them
+var (
+	_ Flusher = (*CachedCloudStorage)(nil)
+	_ Flusher = (*CDNCloudStorage)(nil)
+	_ Flusher = (*ChunkedCloudStorage)(nil)
+	_ Flusher = (*DedupCloudStorage)(nil)
+	_ Flusher = (*ignoreMissingDeletesCloudStorage)(nil)
+	_ Flusher = (*DryRunCloudStorage)(nil)
+	_ Flusher = (*FailoverStorage)(nil)
+	_ Flusher = (*FaultInjectingCloudStorage)(nil)
+	_ Flusher = (*FixtureRecorder)(nil)
+	_ Flusher = (*IdempotentCloudStorage)(nil)
+	_ Flusher = (*interceptedCloudStorage)(nil)
+	_ Flusher = (*LazyCloudStorage)(nil)
+	_ Flusher = (*MirrorStorage)(nil)
+	_ Flusher = (*progressCloudStorage)(nil)
+	_ Flusher = (*QuotaCloudStorage)(nil)
+	_ Flusher = (*ReloadableCloudStorage)(nil)
+	_ Flusher = (*retryCloudStorage)(nil)
+	_ Flusher = (*SchemaValidatingCloudStorage)(nil)
+	_ Flusher = (*ScopedCloudStorage)(nil)
+	_ Flusher = (*IndexedCloudStorage)(nil)
+	_ Flusher = (*ShutdownCloudStorage)(nil)
+	_ Flusher = (*signedURLPolicyCloudStorage)(nil)
+	_ Flusher = (*sizeLimitedCloudStorage)(nil)
+	_ Flusher = (*throttledCloudStorage)(nil)
+	_ Flusher = (*transferUsageCloudStorage)(nil)
+	_ Flusher = (*TransformingCloudStorage)(nil)
+	_ Flusher = (*TrashCloudStorage)(nil)
+	_ Flusher = (*WebhookCloudStorage)(nil)
+	_ Flusher = (*writeDefaultsCloudStorage)(nil)
+	_ Flusher = (*WriteOnceCloudStorage)(nil)
+)
+
+func TestFlushStorage(t *testing.T) {
+	ctx := context.Background()
+	backend := &recordingCloudStorage{objects: make(map[string][]byte)}
+	async := NewAsyncCloudStorage(backend, AsyncWriteOption{QueueSize: 1, Workers: 1})
+	defer async.Close()
+
+	storage := newRetryCloudStorage(async, RetryPolicy{})
+
+	require.NoError(t, storage.Write(ctx, "key", []byte("body"), nil))
+	require.NoError(t, flushStorage(ctx, storage))
+	require.Equal(t, []byte("body"), backend.objects["key"])
+
+	require.NoError(t, flushStorage(ctx, backend))
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"net/http"
+)
+
+// RequestAttributionOption tags the requests sent to the providers, so their access logs and cost reports tell
+// which service issued them
+type RequestAttributionOption struct {
+	// UserAgent is appended to the User-Agent of the SDKs, e.g. "billing-service/1.4.2". The User-Agent is
+	// recorded by the S3 server access logs, CloudTrail and the Cloud Audit Logs.
+	UserAgent string
+	// Headers are added to every request. The "X-Amz-" headers can't be added, since S3 requires them to be signed.
+	Headers http.Header
+}
+
+type requestHeadersContextKey struct{}
+
+// ContextWithRequestHeaders returns a context adding the headers to the provider requests of the operations
+// made with it, e.g. the tenant of the request. It requires the RequestAttribution option.
+func ContextWithRequestHeaders(ctx context.Context, headers http.Header) context.Context {
+	return context.WithValue(ctx, requestHeadersContextKey{}, headers)
+}
+
+// attributionTransport adds the User-Agent and the headers of the attribution to the requests
+type attributionTransport struct {
+	base        http.RoundTripper
+	attribution *RequestAttributionOption
+}
+
+func withRequestAttribution(transport http.RoundTripper, cloudStorageOpts *CloudStorageOption) http.RoundTripper {
+	if cloudStorageOpts.RequestAttribution == nil {
+		return transport
+	}
+
+	return &attributionTransport{base: transport, attribution: cloudStorageOpts.RequestAttribution}
+}
+
+func (t *attributionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fromContext, _ := req.Context().Value(requestHeadersContextKey{}).(http.Header)
+
+	if t.attribution.UserAgent == "" && len(t.attribution.Headers) == 0 && len(fromContext) == 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	// a RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+
+	if t.attribution.UserAgent != "" {
+		userAgent := t.attribution.UserAgent
+		if sdk := req.Header.Get("User-Agent"); sdk != "" {
+			userAgent = sdk + " " + userAgent
+		}
+
+		req.Header.Set("User-Agent", userAgent)
+	}
+
+	for _, headers := range []http.Header{t.attribution.Headers, fromContext} {
+		for name, values := range headers {
+			req.Header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+
+	return t.base.RoundTrip(req)
+}
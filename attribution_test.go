@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestAttribution(t *testing.T) {
+	var received http.Header
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newHTTPTransport(&CloudStorageOption{
+		RequestAttribution: &RequestAttributionOption{
+			UserAgent: "billing-service/1.4.2",
+			Headers:   http.Header{"X-Service": {"billing"}},
+		},
+	}, http.DefaultTransport)}
+
+	ctx := ContextWithRequestHeaders(context.Background(), http.Header{"x-tenant": {"acme"}})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("User-Agent", "aws-sdk-go/1.40.50")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.Equal(t, "aws-sdk-go/1.40.50 billing-service/1.4.2", received.Get("User-Agent"))
+	require.Equal(t, "billing", received.Get("X-Service"))
+	require.Equal(t, "acme", received.Get("X-Tenant"))
+
+	// the request of the caller is left untouched
+	require.Equal(t, "aws-sdk-go/1.40.50", req.Header.Get("User-Agent"))
+	require.Empty(t, req.Header.Get("X-Service"))
+}
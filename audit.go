@@ -0,0 +1,152 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// auditedOperations are the operations changing the bucket or granting access to it
+var auditedOperations = map[string]bool{
+	"Write":        true,
+	"GetWriter":    true,
+	"Upload":       true,
+	"Delete":       true,
+	"WriteIf":      true,
+	"DeleteIf":     true,
+	"BeginUpload":  true,
+	"GetSignedURL": true,
+	// GetScopedCredentials grants access to the bucket
+	"GetScopedCredentials":    true,
+	"CreateBucket":            true,
+	"CreateBucketWithOptions": true,
+	"SetObjectRetention":      true,
+	"SetLegalHold":            true,
+	"SetStorageClass":         true,
+	"Copy":                    true,
+	"Undelete":                true,
+	"SetBucketPolicy":         true,
+	"SetBucketEncryption":     true,
+	"SetBucketLogging":        true,
+	"SetBucketReplication":    true,
+	// Subscribe adds a notification to the bucket
+	"Subscribe":         true,
+	"AbortStaleUploads": true,
+	"CreateFolder":      true,
+	"DeleteFolder":      true,
+	"RenameFolder":      true,
+}
+
+// AuditEvent records a mutation of the bucket
+type AuditEvent struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Bucket    string    `json:"bucket"`
+	Key       string    `json:"key,omitempty"`
+	// DestinationKey is the destination of Copy and the new folder of RenameFolder
+	DestinationKey string `json:"destination_key,omitempty"`
+	Actor          string `json:"actor,omitempty"`
+	// Error is the error message of a failed operation
+	Error string `json:"error,omitempty"`
+}
+
+// AuditOption records every mutation with the actor taken from the context.
+// The events are sent to OnEvent and/or stored as JSON objects under Prefix in the bucket.
+type AuditOption struct {
+	// ActorFromContext returns the actor of the operation. Defaults to ActorFromContext.
+	ActorFromContext func(ctx context.Context) string
+	// OnEvent is called synchronously with every event
+	OnEvent func(event AuditEvent)
+	// Prefix stores every event as "<Prefix>/<date>/<time>-<uuid>.json" in the bucket when it's set
+	Prefix string
+}
+
+type actorContextKey struct{}
+
+// ContextWithActor returns a context carrying the actor recorded by the audit
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by ContextWithActor
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorContextKey{}).(string)
+
+	return actor
+}
+
+// newAuditInterceptor records the audited operations, the events are stored with storage so they aren't audited themselves
+func newAuditInterceptor(opts AuditOption, storage CloudStorage, logger Logger) Interceptor {
+	actorFromContext := opts.ActorFromContext
+	if actorFromContext == nil {
+		actorFromContext = ActorFromContext
+	}
+
+	contentType := "application/json"
+
+	return func(ctx context.Context, op *OperationInfo, next func(ctx context.Context) error) error {
+		if !auditedOperations[op.Name] {
+			return next(ctx)
+		}
+
+		err := next(ctx)
+
+		event := AuditEvent{
+			Time:           time.Now().UTC(),
+			Operation:      op.Name,
+			Bucket:         op.Bucket,
+			Key:            op.Key,
+			DestinationKey: op.DestinationKey,
+			Actor:          actorFromContext(ctx),
+		}
+		if err != nil {
+			event.Error = err.Error()
+		}
+
+		if opts.OnEvent != nil {
+			opts.OnEvent(event)
+		}
+
+		if opts.Prefix != "" {
+			body, marshalErr := json.Marshal(event)
+			if marshalErr == nil {
+				marshalErr = storage.Write(ctx, auditKey(opts.Prefix, event.Time), body, &contentType)
+			}
+
+			if marshalErr != nil {
+				logger.Error("unable to store audit event", Fields{"operation": op.Name, "key": op.Key, "error": marshalErr})
+			}
+		}
+
+		return err
+	}
+}
+
+func auditKey(prefix string, t time.Time) string {
+	return fmt.Sprintf("%s/%s/%s-%s.json",
+		strings.TrimSuffix(prefix, "/"),
+		t.Format("2006-01-02"),
+		t.Format("150405.000000000"),
+		uuid.New().String(),
+	)
+}
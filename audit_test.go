@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditInterceptor(t *testing.T) {
+	ctx := ContextWithActor(context.Background(), "user-1")
+	backend := &recordingCloudStorage{objects: make(map[string][]byte)}
+
+	var events []AuditEvent
+
+	audit := newAuditInterceptor(AuditOption{
+		OnEvent: func(event AuditEvent) { events = append(events, event) },
+		Prefix:  "audit/",
+	}, backend, noopLogger{})
+	storage := newInterceptedCloudStorage(backend, "aws", "bucket", audit)
+
+	require.NoError(t, storage.Write(ctx, "key", []byte("body"), nil))
+	require.Len(t, events, 1)
+	require.Equal(t, "Write", events[0].Operation)
+	require.Equal(t, "bucket", events[0].Bucket)
+	require.Equal(t, "key", events[0].Key)
+	require.Equal(t, "user-1", events[0].Actor)
+
+	require.Len(t, backend.objects, 2)
+
+	for key, body := range backend.objects {
+		if key == "key" {
+			continue
+		}
+
+		require.True(t, strings.HasPrefix(key, "audit/"+events[0].Time.Format("2006-01-02")+"/"))
+
+		var stored AuditEvent
+		require.NoError(t, json.Unmarshal(body, &stored))
+		require.Equal(t, "user-1", stored.Actor)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+)
+
+// awsAccessPoint is an S3 access point given as the bucket, e.g. arn:aws:s3:us-west-2:123456789012:accesspoint/reports.
+// The SDK sends its requests to the endpoint of the access point, in its region.
+type awsAccessPoint struct {
+	arn       string
+	partition string
+	region    string
+	accountID string
+	name      string
+}
+
+// parseAWSAccessPoint returns the access point of the ARN, or nil when the bucket is a bucket name.
+// The multi-region access points fail with ErrNotSupported, since their requests are signed with SigV4A,
+// which the AWS SDK doesn't support yet.
+func parseAWSAccessPoint(bucketName string) (*awsAccessPoint, error) {
+	if !arn.IsARN(bucketName) {
+		return nil, nil
+	}
+
+	parsed, err := arn.Parse(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	// the resource is either accesspoint/<name> or accesspoint:<name>
+	resource := strings.SplitN(strings.Replace(parsed.Resource, ":", "/", 1), "/", 2)
+
+	switch {
+	case parsed.Service != "s3" || resource[0] != "accesspoint" || len(resource) < 2 || resource[1] == "":
+		return nil, fmt.Errorf("%w: '%s' isn't an S3 access point ARN", ErrNotSupported, bucketName)
+	case parsed.Region == "":
+		return nil, fmt.Errorf("%w: multi-region access point '%s'", ErrNotSupported, bucketName)
+	case strings.Contains(resource[1], "/"):
+		return nil, fmt.Errorf("%w: '%s' isn't an S3 access point ARN", ErrNotSupported, bucketName)
+	}
+
+	return &awsAccessPoint{
+		arn:       bucketName,
+		partition: parsed.Partition,
+		region:    parsed.Region,
+		accountID: parsed.AccountID,
+		name:      resource[1],
+	}, nil
+}
+
+// publicURL returns the URL of the object through the access point
+func (ap *awsAccessPoint) publicURL(key string) string {
+	domain := "amazonaws.com"
+	if ap.partition == "aws-cn" {
+		domain = "amazonaws.com.cn"
+	}
+
+	return fmt.Sprintf("https://%s-%s.s3-accesspoint.%s.%s/%s", ap.name, ap.accountID, ap.region, domain, escapeKey(key))
+}
+
+// awsPolicyResources returns the IAM resources of the bucket, or of the access point, and of the objects under the prefix
+func awsPolicyResources(bucketName string, prefix string) (bucket string, objects string) {
+	if arn.IsARN(bucketName) {
+		return bucketName, fmt.Sprintf("%s/object/%s*", bucketName, prefix)
+	}
+
+	return "arn:aws:s3:::" + bucketName, fmt.Sprintf("arn:aws:s3:::%s/%s*", bucketName, prefix)
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAWSAccessPoint(t *testing.T) {
+	accessPoint, err := parseAWSAccessPoint("bucket")
+	require.NoError(t, err)
+	require.Nil(t, accessPoint)
+
+	for _, name := range []string{
+		"arn:aws:s3:us-west-2:123456789012:accesspoint/reports",
+		"arn:aws:s3:us-west-2:123456789012:accesspoint:reports",
+	} {
+		accessPoint, err = parseAWSAccessPoint(name)
+		require.NoError(t, err)
+		require.Equal(t, "us-west-2", accessPoint.region)
+		require.Equal(t, "123456789012", accessPoint.accountID)
+		require.Equal(t, "reports", accessPoint.name)
+	}
+
+	require.Equal(t, "https://reports-123456789012.s3-accesspoint.us-west-2.amazonaws.com/a%20b.txt",
+		accessPoint.publicURL("a b.txt"))
+
+	for _, name := range []string{
+		"arn:aws:s3::123456789012:accesspoint/mfzwi23gnjvgw.mrap",
+		"arn:aws:s3-outposts:us-west-2:123456789012:outpost/op-01234567890123456/accesspoint/reports",
+		"arn:aws:s3:us-west-2:123456789012:accesspoint/",
+		"arn:aws:sqs:us-west-2:123456789012:queue",
+	} {
+		_, err = parseAWSAccessPoint(name)
+		require.True(t, errors.Is(err, ErrNotSupported), name)
+	}
+}
+
+func TestAWSAccessPointCloudStorage(t *testing.T) {
+	ctx := context.Background()
+	name := "arn:aws:s3:us-west-2:123456789012:accesspoint/reports"
+
+	storage, err := newAWSCloudStorage(ctx, "", "us-east-1", name, &CloudStorageOption{})
+	require.NoError(t, err)
+
+	storage.client.Config.Credentials = credentials.NewStaticCredentials("id", "secret", "")
+
+	// the access point is requested in its own region
+	signedURL, err := storage.GetSignedURL(ctx, "key", &SignedURLOption{Expiry: time.Minute})
+	require.NoError(t, err)
+
+	parsed, err := url.Parse(signedURL)
+	require.NoError(t, err)
+	require.Equal(t, "reports-123456789012.s3-accesspoint.us-west-2.amazonaws.com", parsed.Host)
+	require.Contains(t, parsed.Query().Get("X-Amz-Credential"), "/us-west-2/s3/")
+
+	require.Equal(t, "https://reports-123456789012.s3-accesspoint.us-west-2.amazonaws.com/key",
+		storage.GetPublicURL("key"))
+
+	_, err = storage.GetBucketPolicy(ctx)
+	require.True(t, errors.Is(err, ErrNotSupported))
+
+	_, err = storage.Subscribe(ctx, "")
+	require.True(t, errors.Is(err, ErrNotSupported))
+
+	_, err = newAWSCloudStorage(ctx, "", "us-east-1", name, &CloudStorageOption{AWSEnableS3Accelerate: true})
+	require.Error(t, err)
+
+	_, err = newAWSCloudStorage(ctx, "", "us-east-1", "arn:aws:s3::123456789012:accesspoint/mfzwi23gnjvgw.mrap",
+		&CloudStorageOption{})
+	require.True(t, errors.Is(err, ErrNotSupported))
+}
+
+func TestAWSAccessPointPolicy(t *testing.T) {
+	bucket, objects := awsPolicyResources("bucket", "tenants/a/")
+	require.Equal(t, "arn:aws:s3:::bucket", bucket)
+	require.Equal(t, "arn:aws:s3:::bucket/tenants/a/*", objects)
+
+	bucket, objects = awsPolicyResources("arn:aws:s3:us-west-2:123456789012:accesspoint/reports", "tenants/a/")
+	require.Equal(t, "arn:aws:s3:us-west-2:123456789012:accesspoint/reports", bucket)
+	require.Equal(t, "arn:aws:s3:us-west-2:123456789012:accesspoint/reports/object/tenants/a/*", objects)
+}
+
+func TestNewCloudStorageWithAWSBucketName(t *testing.T) {
+	ctx := context.Background()
+
+	storage, err := NewCloudStorageWithOption(ctx, false, "aws", "arn:aws:s3:us-west-2:123456789012:accesspoint/reports",
+		CloudStorageOption{AWSS3Region: "us-east-1"})
+	require.NoError(t, err)
+	require.Equal(t, "https://reports-123456789012.s3-accesspoint.us-west-2.amazonaws.com/key",
+		storage.GetPublicURL("key"))
+
+	storage, err = NewCloudStorageWithOption(ctx, false, "aws", "reports", CloudStorageOption{AWSS3Region: "us-east-1"})
+	require.NoError(t, err)
+	require.Equal(t, "https://reports.s3.us-east-1.amazonaws.com/key", storage.GetPublicURL("key"))
+
+	// the dotted names are checked against the accelerate endpoint
+	_, err = NewCloudStorageWithOption(ctx, false, "aws", "my.reports", CloudStorageOption{
+		AWSS3Region:           "us-east-1",
+		AWSEnableS3Accelerate: true,
+	})
+	require.Error(t, err)
+}
@@ -18,19 +18,35 @@ package commonblobgo
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/sirupsen/logrus"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sts"
 	"gocloud.dev/blob"
 	"gocloud.dev/blob/s3blob"
 )
 
 type AWSCloudStorage struct {
-	bucket          *blob.Bucket
-	bucketName      string
-	bucketCloseFunc func()
+	client            *s3.S3
+	stsClient         *sts.STS
+	sqsClient         *sqs.SQS
+	notificationQueue string
+	bucket            *blob.Bucket
+	bucketName        string
+	s3Endpoint        string
+	s3Region          string
+	accelerate        bool
+	fips              bool
+	accessPoint       *awsAccessPoint
+	logger            Logger
+	clock             func() time.Time
+	listRetry         RetryPolicy
+	bucketCloseFunc   func()
 }
 
 func newAWSCloudStorage(
@@ -38,27 +54,46 @@ func newAWSCloudStorage(
 	s3Endpoint string,
 	s3Region string,
 	bucketName string,
-	accelerateEndpoint *bool,
+	cloudStorageOpts *CloudStorageOption,
 ) (*AWSCloudStorage, error) {
 	// create vanilla AWS client
-	var awsConfig aws.Config
+	awsConfig := newAWSConfig(cloudStorageOpts)
+	awsConfig.Region = aws.String(s3Region)
 
-	if s3Endpoint != "" {
-		awsConfig = aws.Config{
-			Endpoint:         aws.String(s3Endpoint),
-			Region:           aws.String(s3Region),
-			S3ForcePathStyle: aws.Bool(true),
-		}
-	} else {
-		awsConfig = aws.Config{
-			Region: aws.String(s3Region),
+	fips := s3Endpoint == "" && cloudStorageOpts.AWSUseFIPSEndpoint
+
+	accessPoint, err := parseAWSAccessPoint(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	if accessPoint != nil {
+		// the requests are sent to the region of the access point, whatever the region of the client
+		awsConfig.S3UseARNRegion = aws.Bool(true)
+	}
+
+	switch {
+	case accessPoint != nil && (fips || cloudStorageOpts.AWSEnableS3Accelerate):
+		return nil, fmt.Errorf("unable to use the FIPS or S3 accelerate endpoints with the access point '%s'", bucketName)
+	case s3Endpoint != "":
+		awsConfig.Endpoint = aws.String(s3Endpoint)
+		awsConfig.S3ForcePathStyle = aws.Bool(true)
+	case fips:
+		if cloudStorageOpts.AWSEnableS3Accelerate {
+			return nil, fmt.Errorf("unable to use the S3 accelerate endpoint with FIPS endpoints")
 		}
-		if accelerateEndpoint != nil {
-			awsConfig.S3UseAccelerate = accelerateEndpoint
+
+		awsConfig.EndpointResolver = awsFIPSResolver()
+	default:
+		// the accelerate endpoint is virtual-hosted style only, which doesn't work with TLS for the names with dots
+		if cloudStorageOpts.AWSEnableS3Accelerate && strings.Contains(bucketName, ".") {
+			return nil, fmt.Errorf("unable to use the S3 accelerate endpoint with bucket '%s': its name contains dots", bucketName)
 		}
+
+		awsConfig.S3UseAccelerate = aws.Bool(cloudStorageOpts.AWSEnableS3Accelerate)
 	}
 
-	awsSession, err := session.NewSession(&awsConfig)
+	awsSession, err := newAWSSession(awsConfig, cloudStorageOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -68,11 +103,24 @@ func newAWSCloudStorage(
 		return nil, err
 	}
 
-	logrus.Infof("AWSCloudStorage created")
+	logger := loggerOrNoop(cloudStorageOpts.Logger)
+	logger.Info("AWSCloudStorage created", Fields{"bucket": bucketName})
 
 	return &AWSCloudStorage{
-		bucketName: bucketName,
-		bucket:     bucket,
+		client:            s3.New(awsSession),
+		stsClient:         sts.New(awsSession),
+		sqsClient:         sqs.New(awsSession),
+		notificationQueue: notificationQueueName(cloudStorageOpts, bucketName),
+		bucketName:        bucketName,
+		bucket:            bucket,
+		s3Endpoint:        s3Endpoint,
+		s3Region:          s3Region,
+		accelerate:        s3Endpoint == "" && cloudStorageOpts.AWSEnableS3Accelerate,
+		fips:              fips,
+		accessPoint:       accessPoint,
+		logger:            logger,
+		clock:             clockOrNow(cloudStorageOpts.Clock),
+		listRetry:         listRetryPolicy(cloudStorageOpts),
 		bucketCloseFunc: func() {
 			bucket.Close()
 		},
@@ -82,54 +130,88 @@ func newAWSCloudStorage(
 func (ts *AWSCloudStorage) List(
 	ctx context.Context,
 	prefix string,
+	opts ...ListOption,
 ) *ListIterator {
-	iter := ts.bucket.List(&blob.ListOptions{
-		Prefix: prefix,
-	})
-
-	return newListIterator(func() (*ListObject, error) {
-		attrs, err := iter.Next(ctx)
-		if err != nil {
-			return nil, err
-		}
+	return listBucket(ctx, ts.bucket, prefix, opts, ts.listRetry, awsListObject)
+}
 
-		return &ListObject{
-			Key:     attrs.Key,
-			ModTime: attrs.ModTime,
-			Size:    attrs.Size,
-			MD5:     attrs.MD5,
-		}, nil
-	})
+// awsListObject returns the listed object with the ETag and the storage class of the S3 object
+func awsListObject(attrs *blob.ListObject) *ListObject {
+	object := &ListObject{
+		Key:     attrs.Key,
+		ModTime: attrs.ModTime,
+		Size:    attrs.Size,
+		MD5:     attrs.MD5,
+	}
+
+	var s3Object s3.Object
+	if attrs.As(&s3Object) {
+		object.ETag = strings.Trim(aws.StringValue(s3Object.ETag), `"`)
+		object.StorageClass = aws.StringValue(s3Object.StorageClass)
+	}
+
+	return object
 }
 
 func (ts *AWSCloudStorage) Get(
 	ctx context.Context,
 	key string,
+	opts ...ReadOption,
 ) ([]byte, error) {
-	return ts.bucket.ReadAll(ctx, key)
+	body, err := ts.bucket.ReadAll(ctx, key)
+
+	return body, translateError(err)
 }
 
 func (ts *AWSCloudStorage) GetReader(
 	ctx context.Context,
 	key string,
+	opts ...ReadOption,
 ) (io.ReadCloser, error) {
-	return ts.bucket.NewReader(ctx, key, nil)
+	reader, err := ts.bucket.NewReader(ctx, key, nil)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return reader, nil
 }
 
+// GetRangeReader reads the last -offset bytes of the object when the offset is negative
 func (ts *AWSCloudStorage) GetRangeReader(
 	ctx context.Context,
 	key string,
 	offset,
 	length int64,
+	opts ...ReadOption,
 ) (io.ReadCloser, error) {
-	return ts.bucket.NewRangeReader(ctx, key, offset, length, nil)
+	if offset < 0 {
+		return awsTailReader(ctx, ts.client, ts.bucketName, key, -offset, length)
+	}
+
+	reader, err := ts.bucket.NewRangeReader(ctx, key, offset, length, nil)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return reader, nil
 }
 
 func (ts *AWSCloudStorage) GetWriter(
 	ctx context.Context,
 	key string,
+	opts ...WriteOption,
 ) (io.WriteCloser, error) {
-	return ts.bucket.NewWriter(ctx, key, nil)
+	call := writeOptions(opts)
+
+	if call.hasContentLength && call.contentLength <= awsMaxPutObjectSize {
+		return awsPutWriter(ctx, ts.client, ts.bucketName, key, call.contentLength, opts), nil
+	}
+
+	if call.written != nil {
+		return awsWriter(ctx, ts.client, ts.bucketName, key, opts), nil
+	}
+
+	return ts.bucket.NewWriter(ctx, key, call.writerOptions(key))
 }
 
 func (ts *AWSCloudStorage) CreateBucket(
@@ -141,6 +223,17 @@ func (ts *AWSCloudStorage) CreateBucket(
 	return nil
 }
 
+func (ts *AWSCloudStorage) CreateBucketWithOptions(
+	ctx context.Context,
+	opts *CreateBucketOption,
+) error {
+	if ts.accessPoint != nil {
+		return fmt.Errorf("%w: a bucket can't be created through an access point", ErrNotSupported)
+	}
+
+	return awsCreateBucket(ctx, ts.client, ts.bucketName, opts)
+}
+
 func (ts *AWSCloudStorage) Close() {
 	ts.bucketCloseFunc()
 }
@@ -150,14 +243,7 @@ func (ts *AWSCloudStorage) GetSignedURL(
 	key string,
 	opts *SignedURLOption,
 ) (string, error) {
-	options := &blob.SignedURLOptions{
-		Expiry:                   opts.Expiry,
-		Method:                   opts.Method,
-		ContentType:              opts.ContentType,
-		EnforceAbsentContentType: opts.EnforceAbsentContentType,
-	}
-
-	return ts.bucket.SignedURL(context.Background(), key, options)
+	return awsSignedURL(ts.client, ts.bucketName, key, opts, ts.clock)
 }
 
 func (ts *AWSCloudStorage) Write(
@@ -165,32 +251,52 @@ func (ts *AWSCloudStorage) Write(
 	key string,
 	body []byte,
 	contentType *string,
+	opts ...WriteOption,
 ) error {
-	options := &blob.WriterOptions{}
-	if contentType != nil {
-		options.ContentType = *contentType
+	if writeOptions(opts).written != nil {
+		return translateError(awsWrite(ctx, ts.client, ts.bucketName, key, body, contentType, opts))
 	}
 
-	return ts.bucket.WriteAll(ctx, key, body, options)
+	return translateError(ts.bucket.WriteAll(ctx, key, body, writeOptions(opts).writeAllOptions(key, body, contentType)))
 }
 
 func (ts *AWSCloudStorage) Delete(
 	ctx context.Context,
 	key string,
 ) error {
-	return ts.bucket.Delete(ctx, key)
+	return translateError(ts.bucket.Delete(ctx, key))
+}
+
+// WriteIf writes the object when the condition is met, and returns its new ETag
+func (ts *AWSCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	return awsWriteIf(ctx, ts.client, ts.bucketName, key, body, contentType, condition)
+}
+
+func (ts *AWSCloudStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	return awsDeleteIf(ctx, ts.client, ts.bucketName, key, generation)
 }
 
 func (ts *AWSCloudStorage) Attributes(
 	ctx context.Context,
 	key string,
+	opts ...ReadOption,
 ) (*Attributes, error) {
 	attrs, err := ts.bucket.Attributes(ctx, key)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 
-	return &Attributes{
+	result := &Attributes{
 		CacheControl:       attrs.CacheControl,
 		ContentDisposition: attrs.ContentDisposition,
 		ContentEncoding:    attrs.ContentEncoding,
@@ -200,5 +306,281 @@ func (ts *AWSCloudStorage) Attributes(
 		ModTime:            attrs.ModTime,
 		Size:               attrs.Size,
 		MD5:                attrs.MD5,
-	}, nil
+	}
+
+	result.setVersion(attrs)
+
+	return result, nil
+}
+
+func (ts *AWSCloudStorage) SetObjectRetention(
+	ctx context.Context,
+	key string,
+	retention *ObjectRetention,
+) error {
+	return awsSetObjectRetention(ctx, ts.client, ts.bucketName, key, retention)
+}
+
+func (ts *AWSCloudStorage) GetObjectRetention(
+	ctx context.Context,
+	key string,
+) (*ObjectRetention, error) {
+	return awsGetObjectRetention(ctx, ts.client, ts.bucketName, key)
+}
+
+func (ts *AWSCloudStorage) SetLegalHold(
+	ctx context.Context,
+	key string,
+	enabled bool,
+) error {
+	return awsSetLegalHold(ctx, ts.client, ts.bucketName, key, enabled)
+}
+
+func (ts *AWSCloudStorage) GetLegalHold(
+	ctx context.Context,
+	key string,
+) (bool, error) {
+	return awsGetLegalHold(ctx, ts.client, ts.bucketName, key)
+}
+
+// SetStorageClass rewrites the object with the storage class
+func (ts *AWSCloudStorage) SetStorageClass(
+	ctx context.Context,
+	key string,
+	storageClass string,
+) error {
+	return awsSetStorageClass(ctx, ts.client, ts.bucketName, key, storageClass)
+}
+
+// Copy copies the object within the bucket with a CopyObject, limited to 5GB
+func (ts *AWSCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	return awsCopy(ctx, ts.client, ts.bucketName, srcKey, dstKey, opts)
+}
+
+// Undelete removes the delete marker of the object in a versioned bucket
+func (ts *AWSCloudStorage) Undelete(
+	ctx context.Context,
+	key string,
+) error {
+	return awsUndelete(ctx, ts.client, ts.bucketName, key)
+}
+
+func (ts *AWSCloudStorage) GetScopedCredentials(
+	ctx context.Context,
+	opts *ScopedCredentialsOption,
+) (*ScopedCredentials, error) {
+	return awsGetScopedCredentials(ctx, ts.stsClient, ts.bucketName, opts)
+}
+
+// CreateFolder isn't supported, S3 has no folders
+func (ts *AWSCloudStorage) CreateFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	return ErrNotSupported
+}
+
+// DeleteFolder isn't supported, S3 has no folders
+func (ts *AWSCloudStorage) DeleteFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	return ErrNotSupported
+}
+
+// RenameFolder isn't supported, S3 has no folders
+func (ts *AWSCloudStorage) RenameFolder(
+	ctx context.Context,
+	folder string,
+	newFolder string,
+) error {
+	return ErrNotSupported
+}
+
+// GetBucketPolicy fails with ErrNotSupported on an access point, whose policy is managed with S3 Control
+func (ts *AWSCloudStorage) GetBucketPolicy(
+	ctx context.Context,
+) (*BucketPolicy, error) {
+	if ts.accessPoint != nil {
+		return nil, fmt.Errorf("%w: bucket policy of the access point '%s'", ErrNotSupported, ts.bucketName)
+	}
+
+	return awsGetBucketPolicy(ctx, ts.client, ts.bucketName)
+}
+
+func (ts *AWSCloudStorage) SetBucketPolicy(
+	ctx context.Context,
+	policy *BucketPolicy,
+) error {
+	if ts.accessPoint != nil {
+		return fmt.Errorf("%w: bucket policy of the access point '%s'", ErrNotSupported, ts.bucketName)
+	}
+
+	return awsSetBucketPolicy(ctx, ts.client, ts.bucketName, policy)
+}
+
+// GetBucketEncryption fails with ErrNotSupported on an access point, the encryption is set on the bucket
+func (ts *AWSCloudStorage) GetBucketEncryption(
+	ctx context.Context,
+) (*BucketEncryption, error) {
+	if ts.accessPoint != nil {
+		return nil, fmt.Errorf("%w: bucket encryption of the access point '%s'", ErrNotSupported, ts.bucketName)
+	}
+
+	return awsGetBucketEncryption(ctx, ts.client, ts.bucketName)
+}
+
+func (ts *AWSCloudStorage) SetBucketEncryption(
+	ctx context.Context,
+	encryption *BucketEncryption,
+) error {
+	if ts.accessPoint != nil {
+		return fmt.Errorf("%w: bucket encryption of the access point '%s'", ErrNotSupported, ts.bucketName)
+	}
+
+	return awsSetBucketEncryption(ctx, ts.client, ts.bucketName, encryption)
+}
+
+// GetBucketLogging fails with ErrNotSupported on an access point, the logging is set on the bucket
+func (ts *AWSCloudStorage) GetBucketLogging(
+	ctx context.Context,
+) (*BucketLogging, error) {
+	if ts.accessPoint != nil {
+		return nil, fmt.Errorf("%w: bucket logging of the access point '%s'", ErrNotSupported, ts.bucketName)
+	}
+
+	return awsGetBucketLogging(ctx, ts.client, ts.bucketName)
+}
+
+func (ts *AWSCloudStorage) SetBucketLogging(
+	ctx context.Context,
+	logging *BucketLogging,
+) error {
+	if ts.accessPoint != nil {
+		return fmt.Errorf("%w: bucket logging of the access point '%s'", ErrNotSupported, ts.bucketName)
+	}
+
+	return awsSetBucketLogging(ctx, ts.client, ts.bucketName, logging)
+}
+
+// GetBucketReplication fails with ErrNotSupported on an access point, the replication is set on the bucket
+func (ts *AWSCloudStorage) GetBucketReplication(
+	ctx context.Context,
+) (*BucketReplication, error) {
+	if ts.accessPoint != nil {
+		return nil, fmt.Errorf("%w: bucket replication of the access point '%s'", ErrNotSupported, ts.bucketName)
+	}
+
+	return awsGetBucketReplication(ctx, ts.client, ts.bucketName)
+}
+
+func (ts *AWSCloudStorage) SetBucketReplication(
+	ctx context.Context,
+	replication *BucketReplication,
+) error {
+	if ts.accessPoint != nil {
+		return fmt.Errorf("%w: bucket replication of the access point '%s'", ErrNotSupported, ts.bucketName)
+	}
+
+	return awsSetBucketReplication(ctx, ts.client, ts.bucketName, replication)
+}
+
+func (ts *AWSCloudStorage) GetPublicURL(
+	key string,
+) string {
+	if ts.accessPoint != nil && ts.s3Endpoint == "" {
+		return ts.accessPoint.publicURL(key)
+	}
+
+	return awsPublicURL(ts.s3Endpoint, ts.s3Region, ts.bucketName, ts.accelerate, ts.fips, key)
+}
+
+// As sets target to the S3 client when it's a **s3.S3
+func (ts *AWSCloudStorage) As(target interface{}) bool {
+	return ts.bucket.As(target)
+}
+
+// ErrorAs sets target to the S3 error wrapped by err when it's a *awserr.Error
+func (ts *AWSCloudStorage) ErrorAs(err error, target interface{}) bool {
+	return bucketErrorAs(ts.bucket, err, target)
+}
+
+func (ts *AWSCloudStorage) Ping(
+	ctx context.Context,
+) error {
+	return awsPing(ctx, ts.client, ts.bucketName)
+}
+
+// Subscribe fails with ErrNotSupported on an access point, the notifications being configured on the bucket
+func (ts *AWSCloudStorage) Subscribe(
+	ctx context.Context,
+	prefix string,
+) (<-chan ObjectEvent, error) {
+	if ts.accessPoint != nil {
+		return nil, fmt.Errorf("%w: notifications of the access point '%s'", ErrNotSupported, ts.bucketName)
+	}
+
+	return awsSubscribe(ctx, ts.client, ts.sqsClient, ts.bucketName, ts.notificationQueue, prefix, ts.logger)
+}
+
+func (ts *AWSCloudStorage) ListIncompleteUploads(
+	ctx context.Context,
+	prefix string,
+) ([]*IncompleteUpload, error) {
+	return awsListIncompleteUploads(ctx, ts.client, ts.bucketName, prefix)
+}
+
+func (ts *AWSCloudStorage) AbortStaleUploads(
+	ctx context.Context,
+	olderThan time.Duration,
+) ([]*IncompleteUpload, error) {
+	return awsAbortStaleUploads(ctx, ts.client, ts.bucketName, olderThan, ts.clock())
+}
+
+// Query runs the SQL on the object with S3 Select, and streams the matching records as JSON Lines
+func (ts *AWSCloudStorage) Query(
+	ctx context.Context,
+	key string,
+	sql string,
+	format QueryInputFormat,
+) (io.ReadCloser, error) {
+	return awsQuery(ctx, ts.client, ts.bucketName, key, sql, format)
+}
+
+func (ts *AWSCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	return getWithAttributes(ctx, ts.bucket, key)
+}
+
+func (ts *AWSCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	return awsUpload(ctx, ts.client, ts.bucketName, key, reader, opts, writeOpts)
+}
+
+func (ts *AWSCloudStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	return awsBeginUpload(ctx, ts.client, ts.bucketName, key, opts)
+}
+
+func (ts *AWSCloudStorage) ResumeUpload(
+	ctx context.Context,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	return awsResumeUpload(ctx, ts.client, ts.bucketName, state)
 }
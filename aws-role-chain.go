@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+const (
+	defaultAWSRoleSessionName  = "common-blob-go"
+	defaultAWSRoleExpiryWindow = time.Minute
+)
+
+// AWSAssumeRole is a hop of an AWSRoleChainOption
+type AWSAssumeRole struct {
+	RoleARN string
+	// ExternalID is required by the trust policies of the roles shared with third parties
+	ExternalID string
+	// SessionName identifies the session in CloudTrail, "common-blob-go" by default
+	SessionName string
+	// Duration is the lifetime of the credentials, 15 minutes by default. AWS caps it to 1 hour for a chained role.
+	Duration time.Duration
+}
+
+// AWSRoleChainOption assumes the roles in order, each one with the credentials of the previous one,
+// e.g. a bastion role then the role of the data account. The first role is assumed with the configured
+// credentials, or the default credential chain of the SDK.
+type AWSRoleChainOption struct {
+	Roles []AWSAssumeRole
+	// ExpiryWindow is how long before their expiry the credentials of a role are assumed again, 1 minute by default
+	ExpiryWindow time.Duration
+}
+
+// newAWSSession returns the session of the config, with the credentials of the last role of the chain if any.
+// The credentials of every hop are assumed again before they expire, the first time they're needed.
+func newAWSSession(awsConfig aws.Config, cloudStorageOpts *CloudStorageOption) (*session.Session, error) {
+	chain := cloudStorageOpts.AWSRoleChain
+	if chain == nil {
+		return session.NewSession(&awsConfig)
+	}
+
+	if len(chain.Roles) == 0 {
+		return nil, fmt.Errorf("the AWS role chain has no role")
+	}
+
+	if cloudStorageOpts.Anonymous {
+		return nil, fmt.Errorf("unable to assume AWS roles with an anonymous client")
+	}
+
+	expiryWindow := chain.ExpiryWindow
+	if expiryWindow <= 0 {
+		expiryWindow = defaultAWSRoleExpiryWindow
+	}
+
+	hopSession, err := session.NewSession(&awsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, role := range chain.Roles {
+		if role.RoleARN == "" {
+			return nil, fmt.Errorf("the role %d of the AWS role chain has no ARN", i)
+		}
+
+		role := role
+		creds := stscreds.NewCredentials(hopSession, role.RoleARN, func(provider *stscreds.AssumeRoleProvider) {
+			provider.RoleSessionName = role.SessionName
+			if provider.RoleSessionName == "" {
+				provider.RoleSessionName = defaultAWSRoleSessionName
+			}
+
+			if role.ExternalID != "" {
+				provider.ExternalID = aws.String(role.ExternalID)
+			}
+
+			if role.Duration > 0 {
+				provider.Duration = role.Duration
+			}
+
+			provider.ExpiryWindow = expiryWindow
+		})
+
+		// the next hop is assumed with the credentials of this one
+		hopSession = hopSession.Copy(&aws.Config{Credentials: creds})
+	}
+
+	return hopSession, nil
+}
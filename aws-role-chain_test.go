@@ -0,0 +1,149 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// signingKey returns the access key ID signing the request
+func signingKey(r *http.Request) string {
+	authorization := r.Header.Get("Authorization")
+
+	start := strings.Index(authorization, "Credential=")
+	if start < 0 {
+		return ""
+	}
+
+	credential := authorization[start+len("Credential="):]
+
+	return credential[:strings.Index(credential, "/")]
+}
+
+func TestAWSRoleChain(t *testing.T) {
+	ctx := context.Background()
+
+	for _, name := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"} {
+		previous, ok := os.LookupEnv(name)
+		if ok {
+			defer os.Setenv(name, previous)
+		} else {
+			defer os.Unsetenv(name)
+		}
+	}
+
+	require.NoError(t, os.Setenv("AWS_ACCESS_KEY_ID", "BASE"))
+	require.NoError(t, os.Setenv("AWS_SECRET_ACCESS_KEY", "secret"))
+
+	var (
+		mu      sync.Mutex
+		assumed []string
+		reads   []string
+	)
+
+	// the STS and S3 requests go to the same endpoint
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.Method == http.MethodPost {
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, "AssumeRole", r.Form.Get("Action"))
+
+			role := strings.TrimPrefix(r.Form.Get("RoleArn"), "arn:aws:iam::123456789012:role/")
+			assumed = append(assumed, role+" by "+signingKey(r))
+
+			// the credentials of the data role expire within the expiry window, so they're assumed again every time
+			expiration := time.Now().Add(time.Hour)
+			if role == "data" {
+				require.Equal(t, "external", r.Form.Get("ExternalId"))
+				require.Equal(t, "1800", r.Form.Get("DurationSeconds"))
+				expiration = time.Now().Add(30 * time.Second)
+			}
+
+			w.Header().Set("Content-Type", "text/xml")
+			fmt.Fprintf(w, `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleResult>
+    <Credentials>
+      <AccessKeyId>%s</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>token</SessionToken>
+      <Expiration>%s</Expiration>
+    </Credentials>
+  </AssumeRoleResult>
+</AssumeRoleResponse>`, strings.ToUpper(role), expiration.UTC().Format(time.RFC3339))
+
+			return
+		}
+
+		reads = append(reads, signingKey(r))
+		_, _ = w.Write([]byte("body"))
+	}))
+	defer server.Close()
+
+	storage, err := newAWSCloudStorage(ctx, server.URL, "us-east-1", "bucket", &CloudStorageOption{
+		AWSRoleChain: &AWSRoleChainOption{
+			Roles: []AWSAssumeRole{
+				{RoleARN: "arn:aws:iam::123456789012:role/bastion"},
+				{RoleARN: "arn:aws:iam::123456789012:role/data", ExternalID: "external", Duration: 30 * time.Minute},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		body, err := storage.Get(ctx, "key")
+		require.NoError(t, err)
+		require.Equal(t, "body", string(body))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Equal(t, []string{"bastion by BASE", "data by BASTION", "data by BASTION"}, assumed)
+	require.Equal(t, []string{"DATA", "DATA"}, reads)
+}
+
+func TestAWSRoleChainValidation(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := newAWSCloudStorage(ctx, "", "us-east-1", "bucket", &CloudStorageOption{
+		AWSRoleChain: &AWSRoleChainOption{},
+	})
+	require.Error(t, err)
+
+	_, err = newAWSCloudStorage(ctx, "", "us-east-1", "bucket", &CloudStorageOption{
+		AWSRoleChain: &AWSRoleChainOption{Roles: []AWSAssumeRole{{}}},
+	})
+	require.Error(t, err)
+
+	_, err = newAWSCloudStorage(ctx, "", "us-east-1", "bucket", &CloudStorageOption{
+		AWSRoleChain: &AWSRoleChainOption{Roles: []AWSAssumeRole{{RoleARN: "arn:aws:iam::123456789012:role/data"}}},
+		Anonymous:    true,
+	})
+	require.Error(t, err)
+}
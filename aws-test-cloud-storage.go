@@ -20,19 +20,25 @@ import (
 	"context"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/sirupsen/logrus"
+	"github.com/aws/aws-sdk-go/service/sts"
 	"gocloud.dev/blob"
 	"gocloud.dev/blob/s3blob"
 )
 
 type AWSTestCloudStorage struct {
 	client          *s3.S3
+	stsClient       *sts.STS
 	bucket          *blob.Bucket
 	bucketName      string
+	s3Endpoint      string
+	s3Region        string
+	logger          Logger
+	clock           func() time.Time
+	listRetry       RetryPolicy
 	bucketCloseFunc func()
 }
 
@@ -41,24 +47,18 @@ func newAWSTestCloudStorage(
 	s3Endpoint string,
 	s3Region string,
 	bucketName string,
+	cloudStorageOpts *CloudStorageOption,
 ) (*AWSTestCloudStorage, error) {
 	// create vanilla AWS client
-	var awsConfig aws.Config
+	awsConfig := newAWSConfig(cloudStorageOpts)
+	awsConfig.Region = aws.String(s3Region)
+	awsConfig.S3ForcePathStyle = aws.Bool(true) //path style for localstack
 
 	if s3Endpoint != "" {
-		awsConfig = aws.Config{
-			Endpoint:         aws.String(s3Endpoint),
-			Region:           aws.String(s3Region),
-			S3ForcePathStyle: aws.Bool(true), //path style for localstack
-		}
-	} else {
-		awsConfig = aws.Config{
-			Region:           aws.String(s3Region),
-			S3ForcePathStyle: aws.Bool(true), //path style for localstack
-		}
+		awsConfig.Endpoint = aws.String(s3Endpoint)
 	}
 
-	awsSession, err := session.NewSession(&awsConfig)
+	awsSession, err := newAWSSession(awsConfig, cloudStorageOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -70,12 +70,19 @@ func newAWSTestCloudStorage(
 		return nil, err
 	}
 
-	logrus.Infof("AWSTestCloudStorage created")
+	logger := loggerOrNoop(cloudStorageOpts.Logger)
+	logger.Info("AWSTestCloudStorage created", Fields{"bucket": bucketName})
 
 	return &AWSTestCloudStorage{
 		client:     client,
+		stsClient:  sts.New(awsSession),
 		bucketName: bucketName,
 		bucket:     bucket,
+		s3Endpoint: s3Endpoint,
+		s3Region:   s3Region,
+		logger:     logger,
+		clock:      clockOrNow(cloudStorageOpts.Clock),
+		listRetry:  listRetryPolicy(cloudStorageOpts),
 		bucketCloseFunc: func() {
 			bucket.Close()
 		},
@@ -85,54 +92,70 @@ func newAWSTestCloudStorage(
 func (ts *AWSTestCloudStorage) List(
 	ctx context.Context,
 	prefix string,
+	opts ...ListOption,
 ) *ListIterator {
-	iter := ts.bucket.List(&blob.ListOptions{
-		Prefix: prefix,
-	})
-
-	return newListIterator(func() (*ListObject, error) {
-		attrs, err := iter.Next(ctx)
-		if err != nil {
-			return nil, err
-		}
-
-		return &ListObject{
-			Key:     attrs.Key,
-			ModTime: attrs.ModTime,
-			Size:    attrs.Size,
-			MD5:     attrs.MD5,
-		}, nil
-	})
+	return listBucket(ctx, ts.bucket, prefix, opts, ts.listRetry, awsListObject)
 }
 
 func (ts *AWSTestCloudStorage) Get(
 	ctx context.Context,
 	key string,
+	opts ...ReadOption,
 ) ([]byte, error) {
-	return ts.bucket.ReadAll(ctx, key)
+	body, err := ts.bucket.ReadAll(ctx, key)
+
+	return body, translateError(err)
 }
 
 func (ts *AWSTestCloudStorage) GetReader(
 	ctx context.Context,
 	key string,
+	opts ...ReadOption,
 ) (io.ReadCloser, error) {
-	return ts.bucket.NewReader(ctx, key, nil)
+	reader, err := ts.bucket.NewReader(ctx, key, nil)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return reader, nil
 }
 
+// GetRangeReader reads the last -offset bytes of the object when the offset is negative
 func (ts *AWSTestCloudStorage) GetRangeReader(
 	ctx context.Context,
 	key string,
 	offset,
 	length int64,
+	opts ...ReadOption,
 ) (io.ReadCloser, error) {
-	return ts.bucket.NewRangeReader(ctx, key, offset, length, nil)
+	if offset < 0 {
+		return awsTailReader(ctx, ts.client, ts.bucketName, key, -offset, length)
+	}
+
+	reader, err := ts.bucket.NewRangeReader(ctx, key, offset, length, nil)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return reader, nil
 }
 
 func (ts *AWSTestCloudStorage) GetWriter(
 	ctx context.Context,
 	key string,
+	opts ...WriteOption,
 ) (io.WriteCloser, error) {
-	return ts.bucket.NewWriter(ctx, key, nil)
+	call := writeOptions(opts)
+
+	if call.hasContentLength && call.contentLength <= awsMaxPutObjectSize {
+		return awsPutWriter(ctx, ts.client, ts.bucketName, key, call.contentLength, opts), nil
+	}
+
+	if call.written != nil {
+		return awsWriter(ctx, ts.client, ts.bucketName, key, opts), nil
+	}
+
+	return ts.bucket.NewWriter(ctx, key, call.writerOptions(key))
 }
 
 func (ts *AWSTestCloudStorage) CreateBucket(
@@ -140,7 +163,7 @@ func (ts *AWSTestCloudStorage) CreateBucket(
 	bucketPrefix string,
 	expirationTimeDays int64,
 ) error {
-	logrus.Printf("CreateBucket. Name: %s, Prefix: %s, Exp Time: %v", ts.bucketName, bucketPrefix, expirationTimeDays)
+	ts.logger.Debug("CreateBucket", Fields{"bucket": ts.bucketName, "prefix": bucketPrefix, "expirationTimeDays": expirationTimeDays})
 
 	if _, err := ts.client.CreateBucket(&s3.CreateBucketInput{
 		Bucket: aws.String(ts.bucketName)}); err != nil {
@@ -148,7 +171,7 @@ func (ts *AWSTestCloudStorage) CreateBucket(
 			return nil
 		}
 
-		logrus.Errorf("unable to create bucket '%s': %v", ts.bucketName, err)
+		ts.logger.Error("unable to create bucket", Fields{"bucket": ts.bucketName, "error": err})
 
 		return err
 	}
@@ -185,15 +208,22 @@ func (ts *AWSTestCloudStorage) CreateBucket(
 		MaxKeys: aws.Int64(1), // nolint:gomnd
 	})
 	if err != nil {
-		logrus.Errorf("unable access bucket '%s': %v", ts.bucketName, err)
+		ts.logger.Error("unable to access bucket", Fields{"bucket": ts.bucketName, "error": err})
 		return err
 	}
 
-	logrus.Printf("Bucket %v created.\n", ts.bucketName)
+	ts.logger.Info("bucket created", Fields{"bucket": ts.bucketName})
 
 	return nil
 }
 
+func (ts *AWSTestCloudStorage) CreateBucketWithOptions(
+	ctx context.Context,
+	opts *CreateBucketOption,
+) error {
+	return awsCreateBucket(ctx, ts.client, ts.bucketName, opts)
+}
+
 func (ts *AWSTestCloudStorage) Close() {
 	ts.bucketCloseFunc()
 }
@@ -203,13 +233,7 @@ func (ts *AWSTestCloudStorage) GetSignedURL(
 	key string,
 	opts *SignedURLOption,
 ) (string, error) {
-	options := &blob.SignedURLOptions{
-		Expiry:                   opts.Expiry,
-		Method:                   opts.Method,
-		ContentType:              opts.ContentType,
-		EnforceAbsentContentType: opts.EnforceAbsentContentType,
-	}
-	return ts.bucket.SignedURL(context.Background(), key, options)
+	return awsSignedURL(ts.client, ts.bucketName, key, opts, ts.clock)
 }
 
 func (ts *AWSTestCloudStorage) Write(
@@ -217,32 +241,52 @@ func (ts *AWSTestCloudStorage) Write(
 	key string,
 	body []byte,
 	contentType *string,
+	opts ...WriteOption,
 ) error {
-	options := &blob.WriterOptions{}
-	if contentType != nil {
-		options.ContentType = *contentType
+	if writeOptions(opts).written != nil {
+		return translateError(awsWrite(ctx, ts.client, ts.bucketName, key, body, contentType, opts))
 	}
 
-	return ts.bucket.WriteAll(ctx, key, body, options)
+	return translateError(ts.bucket.WriteAll(ctx, key, body, writeOptions(opts).writeAllOptions(key, body, contentType)))
 }
 
 func (ts *AWSTestCloudStorage) Delete(
 	ctx context.Context,
 	key string,
 ) error {
-	return ts.bucket.Delete(ctx, key)
+	return translateError(ts.bucket.Delete(ctx, key))
+}
+
+// WriteIf writes the object when the condition is met, and returns its new ETag
+func (ts *AWSTestCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	return awsWriteIf(ctx, ts.client, ts.bucketName, key, body, contentType, condition)
+}
+
+func (ts *AWSTestCloudStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	return awsDeleteIf(ctx, ts.client, ts.bucketName, key, generation)
 }
 
 func (ts *AWSTestCloudStorage) Attributes(
 	ctx context.Context,
 	key string,
+	opts ...ReadOption,
 ) (*Attributes, error) {
 	attrs, err := ts.bucket.Attributes(ctx, key)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 
-	return &Attributes{
+	result := &Attributes{
 		CacheControl:       attrs.CacheControl,
 		ContentDisposition: attrs.ContentDisposition,
 		ContentEncoding:    attrs.ContentEncoding,
@@ -252,5 +296,237 @@ func (ts *AWSTestCloudStorage) Attributes(
 		ModTime:            attrs.ModTime,
 		Size:               attrs.Size,
 		MD5:                attrs.MD5,
-	}, nil
+	}
+
+	result.setVersion(attrs)
+
+	return result, nil
+}
+
+func (ts *AWSTestCloudStorage) SetObjectRetention(
+	ctx context.Context,
+	key string,
+	retention *ObjectRetention,
+) error {
+	return awsSetObjectRetention(ctx, ts.client, ts.bucketName, key, retention)
+}
+
+func (ts *AWSTestCloudStorage) GetObjectRetention(
+	ctx context.Context,
+	key string,
+) (*ObjectRetention, error) {
+	return awsGetObjectRetention(ctx, ts.client, ts.bucketName, key)
+}
+
+func (ts *AWSTestCloudStorage) SetLegalHold(
+	ctx context.Context,
+	key string,
+	enabled bool,
+) error {
+	return awsSetLegalHold(ctx, ts.client, ts.bucketName, key, enabled)
+}
+
+func (ts *AWSTestCloudStorage) GetLegalHold(
+	ctx context.Context,
+	key string,
+) (bool, error) {
+	return awsGetLegalHold(ctx, ts.client, ts.bucketName, key)
+}
+
+// SetStorageClass rewrites the object with the storage class
+func (ts *AWSTestCloudStorage) SetStorageClass(
+	ctx context.Context,
+	key string,
+	storageClass string,
+) error {
+	return awsSetStorageClass(ctx, ts.client, ts.bucketName, key, storageClass)
+}
+
+// Copy copies the object within the bucket with a CopyObject, limited to 5GB
+func (ts *AWSTestCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	return awsCopy(ctx, ts.client, ts.bucketName, srcKey, dstKey, opts)
+}
+
+// Undelete removes the delete marker of the object in a versioned bucket
+func (ts *AWSTestCloudStorage) Undelete(
+	ctx context.Context,
+	key string,
+) error {
+	return awsUndelete(ctx, ts.client, ts.bucketName, key)
+}
+
+func (ts *AWSTestCloudStorage) GetScopedCredentials(
+	ctx context.Context,
+	opts *ScopedCredentialsOption,
+) (*ScopedCredentials, error) {
+	return awsGetScopedCredentials(ctx, ts.stsClient, ts.bucketName, opts)
+}
+
+// CreateFolder isn't supported, S3 has no folders
+func (ts *AWSTestCloudStorage) CreateFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	return ErrNotSupported
+}
+
+// DeleteFolder isn't supported, S3 has no folders
+func (ts *AWSTestCloudStorage) DeleteFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	return ErrNotSupported
+}
+
+// RenameFolder isn't supported, S3 has no folders
+func (ts *AWSTestCloudStorage) RenameFolder(
+	ctx context.Context,
+	folder string,
+	newFolder string,
+) error {
+	return ErrNotSupported
+}
+
+func (ts *AWSTestCloudStorage) GetBucketPolicy(
+	ctx context.Context,
+) (*BucketPolicy, error) {
+	return awsGetBucketPolicy(ctx, ts.client, ts.bucketName)
+}
+
+func (ts *AWSTestCloudStorage) SetBucketPolicy(
+	ctx context.Context,
+	policy *BucketPolicy,
+) error {
+	return awsSetBucketPolicy(ctx, ts.client, ts.bucketName, policy)
+}
+
+func (ts *AWSTestCloudStorage) GetBucketEncryption(
+	ctx context.Context,
+) (*BucketEncryption, error) {
+	return awsGetBucketEncryption(ctx, ts.client, ts.bucketName)
+}
+
+func (ts *AWSTestCloudStorage) SetBucketEncryption(
+	ctx context.Context,
+	encryption *BucketEncryption,
+) error {
+	return awsSetBucketEncryption(ctx, ts.client, ts.bucketName, encryption)
+}
+
+func (ts *AWSTestCloudStorage) GetBucketLogging(
+	ctx context.Context,
+) (*BucketLogging, error) {
+	return awsGetBucketLogging(ctx, ts.client, ts.bucketName)
+}
+
+func (ts *AWSTestCloudStorage) SetBucketLogging(
+	ctx context.Context,
+	logging *BucketLogging,
+) error {
+	return awsSetBucketLogging(ctx, ts.client, ts.bucketName, logging)
+}
+
+func (ts *AWSTestCloudStorage) GetBucketReplication(
+	ctx context.Context,
+) (*BucketReplication, error) {
+	return awsGetBucketReplication(ctx, ts.client, ts.bucketName)
+}
+
+func (ts *AWSTestCloudStorage) SetBucketReplication(
+	ctx context.Context,
+	replication *BucketReplication,
+) error {
+	return awsSetBucketReplication(ctx, ts.client, ts.bucketName, replication)
+}
+
+func (ts *AWSTestCloudStorage) GetPublicURL(
+	key string,
+) string {
+	return awsPublicURL(ts.s3Endpoint, ts.s3Region, ts.bucketName, false, false, key)
+}
+
+// As sets target to the S3 client when it's a **s3.S3
+func (ts *AWSTestCloudStorage) As(target interface{}) bool {
+	return ts.bucket.As(target)
+}
+
+// ErrorAs sets target to the S3 error wrapped by err when it's a *awserr.Error
+func (ts *AWSTestCloudStorage) ErrorAs(err error, target interface{}) bool {
+	return bucketErrorAs(ts.bucket, err, target)
+}
+
+func (ts *AWSTestCloudStorage) Ping(
+	ctx context.Context,
+) error {
+	return awsPing(ctx, ts.client, ts.bucketName)
+}
+
+// Subscribe isn't supported, the S3 emulator doesn't send notifications
+func (ts *AWSTestCloudStorage) Subscribe(
+	ctx context.Context,
+	prefix string,
+) (<-chan ObjectEvent, error) {
+	return nil, ErrNotSupported
+}
+
+func (ts *AWSTestCloudStorage) ListIncompleteUploads(
+	ctx context.Context,
+	prefix string,
+) ([]*IncompleteUpload, error) {
+	return awsListIncompleteUploads(ctx, ts.client, ts.bucketName, prefix)
+}
+
+func (ts *AWSTestCloudStorage) AbortStaleUploads(
+	ctx context.Context,
+	olderThan time.Duration,
+) ([]*IncompleteUpload, error) {
+	return awsAbortStaleUploads(ctx, ts.client, ts.bucketName, olderThan, ts.clock())
+}
+
+// Query runs the SQL on the client side, the S3 emulator doesn't support S3 Select
+func (ts *AWSTestCloudStorage) Query(
+	ctx context.Context,
+	key string,
+	sql string,
+	format QueryInputFormat,
+) (io.ReadCloser, error) {
+	return queryObject(ctx, ts, key, sql, format)
+}
+
+func (ts *AWSTestCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	return getWithAttributes(ctx, ts.bucket, key)
+}
+
+func (ts *AWSTestCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	return awsUpload(ctx, ts.client, ts.bucketName, key, reader, opts, writeOpts)
+}
+
+func (ts *AWSTestCloudStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	return awsBeginUpload(ctx, ts.client, ts.bucketName, key, opts)
+}
+
+func (ts *AWSTestCloudStorage) ResumeUpload(
+	ctx context.Context,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	return awsResumeUpload(ctx, ts.client, ts.bucketName, state)
 }
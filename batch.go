@@ -0,0 +1,357 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	// DefaultBatchConcurrency is the number of keys processed in parallel by RunBatchJob
+	// when BatchJobOption.Concurrency is not set
+	DefaultBatchConcurrency = 8
+
+	defaultBatchCheckpointInterval = 100
+)
+
+// BatchOperation is applied to every key of a batch job manifest
+type BatchOperation func(ctx context.Context, storage CloudStorage, key string) error
+
+// BatchDelete deletes the keys
+func BatchDelete() BatchOperation {
+	return func(ctx context.Context, storage CloudStorage, key string) error {
+		return storage.Delete(ctx, key)
+	}
+}
+
+// BatchCopy copies the keys to dstPrefix+key in dst, which can be the same storage
+func BatchCopy(dst CloudStorage, dstPrefix string) BatchOperation {
+	return func(ctx context.Context, storage CloudStorage, key string) error {
+		return copyObject(ctx, storage, key, dst, dstPrefix+key)
+	}
+}
+
+// BatchRewrite rewrites the objects in place with their content type. The rewritten objects get the current
+// server-side encryption settings of the bucket, e.g. after a change of the default KMS key.
+// The objects are buffered in memory, since the source and destination keys are the same.
+func BatchRewrite() BatchOperation {
+	return func(ctx context.Context, storage CloudStorage, key string) error {
+		attrs, err := storage.Attributes(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		body, err := storage.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		return storage.Upload(ctx, key, bytes.NewReader(body), &UploadOption{ContentType: attrs.ContentType})
+	}
+}
+
+// BatchJobOption configures RunBatchJob
+type BatchJobOption struct {
+	// Concurrency is the number of keys processed in parallel. Defaults to DefaultBatchConcurrency.
+	Concurrency int
+	// RetryPolicy is applied to the operation of every key. The default policy is used when it's nil.
+	RetryPolicy *RetryPolicy
+	// ResultKey is the key of the result manifest. Defaults to the manifest key with the ".result.ndjson" suffix.
+	ResultKey string
+	// CheckpointKey stores the processed keys, so a stopped job resumes where it stopped.
+	// Defaults to the manifest key with the ".checkpoint.json" suffix.
+	CheckpointKey string
+	// CheckpointInterval is the number of processed keys between two checkpoint saves. Defaults to 100.
+	CheckpointInterval int
+	// Logger receives the progress of the job. It's discarded when it's nil.
+	Logger Logger
+}
+
+// BatchResult is a line of the result manifest
+type BatchResult struct {
+	Key string `json:"key"`
+	// Status is "succeeded", "failed" or "skipped" for the keys processed by a previous run
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchReport summarizes a batch job
+type BatchReport struct {
+	Total     int
+	Succeeded int
+	// Skipped is the number of keys already processed according to the checkpoint
+	Skipped int
+	// Failed holds the error of every failed key, they are retried by the next run
+	Failed map[string]error
+	// ResultKey is the key of the result manifest
+	ResultKey string
+}
+
+// batchCheckpoint lists the keys processed successfully
+type batchCheckpoint struct {
+	Done []string `json:"done"`
+}
+
+// RunBatchJob applies the operation to every key listed by the manifest object, like S3 Batch Operations.
+// The manifest is either a CSV file with the key in the first column, or a NDJSON file of {"key": "..."} objects.
+// The failed keys don't stop the job: they are reported and written to the result manifest, stored next to
+// the manifest with the status of every key. An error is returned when the manifest or the checkpoint fail.
+// nolint:funlen
+func RunBatchJob(
+	ctx context.Context,
+	storage CloudStorage,
+	manifestKey string,
+	operation BatchOperation,
+	opts *BatchJobOption,
+) (*BatchReport, error) {
+	var options BatchJobOption
+	if opts != nil {
+		options = *opts
+	}
+
+	if options.Concurrency <= 0 {
+		options.Concurrency = DefaultBatchConcurrency
+	}
+
+	if options.CheckpointInterval <= 0 {
+		options.CheckpointInterval = defaultBatchCheckpointInterval
+	}
+
+	if options.ResultKey == "" {
+		options.ResultKey = manifestKey + ".result.ndjson"
+	}
+
+	if options.CheckpointKey == "" {
+		options.CheckpointKey = manifestKey + ".checkpoint.json"
+	}
+
+	var policy RetryPolicy
+	if options.RetryPolicy != nil {
+		policy = *options.RetryPolicy
+	}
+
+	policy = policy.withDefaults()
+	logger := loggerOrNoop(options.Logger)
+
+	report := &BatchReport{Failed: make(map[string]error), ResultKey: options.ResultKey}
+
+	manifest, err := storage.Get(ctx, manifestKey)
+	if err != nil {
+		return report, err
+	}
+
+	keys, err := parseBatchManifest(manifest)
+	if err != nil {
+		return report, err
+	}
+
+	report.Total = len(keys)
+
+	checkpoint, err := loadBatchCheckpoint(ctx, storage, options.CheckpointKey)
+	if err != nil {
+		return report, err
+	}
+
+	done := make(map[string]bool, len(checkpoint.Done))
+	for _, key := range checkpoint.Done {
+		done[key] = true
+	}
+
+	results := make([]BatchResult, len(keys))
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		unsaved int
+		saveErr error
+	)
+
+	semaphore := make(chan struct{}, options.Concurrency)
+
+	for i, key := range keys {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if done[key] {
+			results[i] = BatchResult{Key: key, Status: "skipped"}
+			report.Skipped++
+
+			continue
+		}
+
+		i, key := i, key
+
+		semaphore <- struct{}{}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			err := policy.do(ctx, func() error {
+				return operation(ctx, storage, key)
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				results[i] = BatchResult{Key: key, Status: "failed", Error: err.Error()}
+				report.Failed[key] = err
+				logger.Warn("batch operation failed", Fields{"key": key, "error": err})
+
+				return
+			}
+
+			results[i] = BatchResult{Key: key, Status: "succeeded"}
+			report.Succeeded++
+			checkpoint.Done = append(checkpoint.Done, key)
+
+			if unsaved++; unsaved >= options.CheckpointInterval {
+				unsaved = 0
+
+				if err := saveBatchCheckpoint(ctx, storage, options.CheckpointKey, checkpoint); err != nil {
+					saveErr = err
+				}
+
+				logger.Info("batch job progress", Fields{
+					"manifest":  manifestKey,
+					"succeeded": report.Succeeded,
+					"failed":    len(report.Failed),
+					"total":     report.Total,
+				})
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if err := saveBatchCheckpoint(ctx, storage, options.CheckpointKey, checkpoint); err != nil {
+		return report, err
+	}
+
+	if saveErr != nil {
+		return report, saveErr
+	}
+
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+
+	return report, writeBatchResults(ctx, storage, options.ResultKey, results)
+}
+
+// parseBatchManifest returns the keys of a CSV or NDJSON manifest, the format is detected from the first character
+func parseBatchManifest(manifest []byte) ([]string, error) {
+	manifest = bytes.TrimSpace(manifest)
+
+	var keys []string
+
+	if !bytes.HasPrefix(manifest, []byte("{")) {
+		reader := csv.NewReader(bytes.NewReader(manifest))
+		reader.FieldsPerRecord = -1
+
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV manifest: %w", err)
+		}
+
+		for _, record := range records {
+			if len(record) > 0 && record[0] != "" {
+				keys = append(keys, record[0])
+			}
+		}
+
+		return keys, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(manifest))
+	scanner.Buffer(nil, 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var entry struct {
+			Key string `json:"key"`
+		}
+
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid NDJSON manifest at line %d: %w", line, err)
+		}
+
+		if entry.Key == "" {
+			return nil, fmt.Errorf("invalid NDJSON manifest at line %d: missing key", line)
+		}
+
+		keys = append(keys, entry.Key)
+	}
+
+	return keys, scanner.Err()
+}
+
+func loadBatchCheckpoint(ctx context.Context, storage CloudStorage, key string) (*batchCheckpoint, error) {
+	checkpoint := &batchCheckpoint{}
+
+	body, err := storage.Get(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		return checkpoint, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return checkpoint, json.Unmarshal(body, checkpoint)
+}
+
+func saveBatchCheckpoint(ctx context.Context, storage CloudStorage, key string, checkpoint *batchCheckpoint) error {
+	body, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	contentType := "application/json"
+
+	return storage.Write(ctx, key, body, &contentType)
+}
+
+func writeBatchResults(ctx context.Context, storage CloudStorage, key string, results []BatchResult) error {
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+
+	for _, result := range results {
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	}
+
+	contentType := "application/x-ndjson"
+
+	return storage.Write(ctx, key, buf.Bytes(), &contentType)
+}
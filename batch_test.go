@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunBatchJobCSV(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+	backup := NewFakeCloudStorage("backup")
+
+	require.NoError(t, storage.Write(ctx, "a.txt", []byte("a"), nil))
+	require.NoError(t, storage.Write(ctx, "b.txt", []byte("b"), nil))
+	require.NoError(t, storage.Write(ctx, "jobs/copy.csv", []byte("a.txt,bucket\nb.txt,bucket\nmissing.txt,bucket\n"), nil))
+
+	opts := &BatchJobOption{RetryPolicy: &RetryPolicy{MaxAttempts: 1}}
+
+	report, err := RunBatchJob(ctx, storage, "jobs/copy.csv", BatchCopy(backup, "copies/"), opts)
+	require.NoError(t, err)
+	require.Equal(t, 3, report.Total)
+	require.Equal(t, 2, report.Succeeded)
+	require.Len(t, report.Failed, 1)
+	require.True(t, errors.Is(report.Failed["missing.txt"], ErrNotFound))
+
+	body, err := backup.Get(ctx, "copies/b.txt")
+	require.NoError(t, err)
+	require.Equal(t, []byte("b"), body)
+
+	results, err := storage.Get(ctx, report.ResultKey)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(results)), "\n")
+	require.Len(t, lines, 3)
+	require.Equal(t, `{"key":"a.txt","status":"succeeded"}`, lines[0])
+	require.Contains(t, lines[2], `"status":"failed"`)
+
+	// the keys processed successfully are skipped by the next run
+	require.NoError(t, storage.Write(ctx, "missing.txt", []byte("found"), nil))
+
+	report, err = RunBatchJob(ctx, storage, "jobs/copy.csv", BatchCopy(backup, "copies/"), opts)
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Skipped)
+	require.Equal(t, 1, report.Succeeded)
+	require.Empty(t, report.Failed)
+}
+
+func TestRunBatchJobNDJSON(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	contentType := "text/plain"
+	require.NoError(t, storage.Write(ctx, "a.txt", []byte("a"), &contentType))
+	require.NoError(t, storage.Write(ctx, "b.txt", []byte("b"), nil))
+
+	manifest := "{\"key\": \"a.txt\"}\n\n{\"key\": \"b.txt\"}\n"
+	require.NoError(t, storage.Write(ctx, "jobs/keys.ndjson", []byte(manifest), nil))
+
+	report, err := RunBatchJob(ctx, storage, "jobs/keys.ndjson", BatchRewrite(), nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Succeeded)
+
+	attrs, err := storage.Attributes(ctx, "a.txt")
+	require.NoError(t, err)
+	require.Equal(t, "text/plain", attrs.ContentType)
+
+	report, err = RunBatchJob(ctx, storage, "jobs/keys.ndjson", BatchDelete(), &BatchJobOption{
+		CheckpointKey: "jobs/keys.delete.checkpoint.json",
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Succeeded)
+
+	_, err = storage.Get(ctx, "a.txt")
+	require.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestParseBatchManifest(t *testing.T) {
+	keys, err := parseBatchManifest([]byte("\"a,b.txt\",x\nc.txt\n"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"a,b.txt", "c.txt"}, keys)
+
+	_, err = parseBatchManifest([]byte("{\"key\": \"a.txt\"}\n{\"other\": 1}\n"))
+	require.EqualError(t, err, "invalid NDJSON manifest at line 2: missing key")
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// awsManagedKMSKey is the alias of the KMS key managed by AWS for S3, used by SSE-KMS without a key ID
+const awsManagedKMSKey = "alias/aws/s3"
+
+// BucketEncryption is the default encryption of the objects written to a bucket
+type BucketEncryption struct {
+	// KMSKeyName is the key encrypting the objects by default: the KMS key ID, ARN or alias on AWS (SSE-KMS,
+	// "alias/aws/s3" for the key managed by AWS), or the Cloud KMS key name on GCP (CMEK).
+	// The objects are encrypted with the keys of the provider when it's empty (SSE-S3 on AWS).
+	KMSKeyName string
+	// AWSBucketKey enables the S3 Bucket Keys, which reduce the KMS requests of SSE-KMS. Only used on AWS.
+	AWSBucketKey bool
+}
+
+func awsGetBucketEncryption(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+) (*BucketEncryption, error) {
+	out, err := client.GetBucketEncryptionWithContext(ctx, &s3.GetBucketEncryptionInput{
+		Bucket: aws.String(bucketName),
+	})
+
+	// the buckets without a configuration are encrypted with SSE-S3
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == "ServerSideEncryptionConfigurationNotFoundError" {
+		return &BucketEncryption{}, nil
+	}
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	encryption := &BucketEncryption{}
+
+	for _, rule := range out.ServerSideEncryptionConfiguration.Rules {
+		byDefault := rule.ApplyServerSideEncryptionByDefault
+		if byDefault == nil || aws.StringValue(byDefault.SSEAlgorithm) != s3.ServerSideEncryptionAwsKms {
+			continue
+		}
+
+		encryption.KMSKeyName = aws.StringValue(byDefault.KMSMasterKeyID)
+		if encryption.KMSKeyName == "" {
+			encryption.KMSKeyName = awsManagedKMSKey
+		}
+
+		encryption.AWSBucketKey = aws.BoolValue(rule.BucketKeyEnabled)
+	}
+
+	return encryption, nil
+}
+
+func awsSetBucketEncryption(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	encryption *BucketEncryption,
+) error {
+	byDefault := &s3.ServerSideEncryptionByDefault{SSEAlgorithm: aws.String(s3.ServerSideEncryptionAes256)}
+	if encryption.KMSKeyName != "" {
+		byDefault = &s3.ServerSideEncryptionByDefault{
+			SSEAlgorithm:   aws.String(s3.ServerSideEncryptionAwsKms),
+			KMSMasterKeyID: aws.String(encryption.KMSKeyName),
+		}
+	}
+
+	_, err := client.PutBucketEncryptionWithContext(ctx, &s3.PutBucketEncryptionInput{
+		Bucket: aws.String(bucketName),
+		ServerSideEncryptionConfiguration: &s3.ServerSideEncryptionConfiguration{
+			Rules: []*s3.ServerSideEncryptionRule{{
+				ApplyServerSideEncryptionByDefault: byDefault,
+				BucketKeyEnabled:                   aws.Bool(encryption.AWSBucketKey),
+			}},
+		},
+	})
+
+	return translateError(err)
+}
+
+func gcpGetBucketEncryption(
+	ctx context.Context,
+	client *storage.Client,
+	bucketName string,
+) (*BucketEncryption, error) {
+	attrs, err := client.Bucket(bucketName).Attrs(ctx)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	encryption := &BucketEncryption{}
+	if attrs.Encryption != nil {
+		encryption.KMSKeyName = attrs.Encryption.DefaultKMSKeyName
+	}
+
+	return encryption, nil
+}
+
+// gcpSetBucketEncryption sets the default CMEK of the bucket, or removes it when the key name is empty
+func gcpSetBucketEncryption(
+	ctx context.Context,
+	client *storage.Client,
+	bucketName string,
+	encryption *BucketEncryption,
+) error {
+	_, err := client.Bucket(bucketName).Update(ctx, storage.BucketAttrsToUpdate{
+		Encryption: &storage.BucketEncryption{DefaultKMSKeyName: encryption.KMSKeyName},
+	})
+
+	return translateError(err)
+}
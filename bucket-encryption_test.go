@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSBucketEncryption(t *testing.T) {
+	var (
+		configuration string
+		written       string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if configuration == "" {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`<Error><Code>ServerSideEncryptionConfigurationNotFoundError</Code></Error>`))
+
+				return
+			}
+
+			w.Write([]byte(configuration))
+		case http.MethodPut:
+			body, _ := ioutil.ReadAll(r.Body)
+			written = string(body)
+		}
+	}))
+	defer server.Close()
+
+	awsSession, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(server.URL),
+		Region:           aws.String("us-east-1"),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	client := s3.New(awsSession)
+
+	// the buckets without a configuration use SSE-S3
+	encryption, err := awsGetBucketEncryption(ctx, client, "bucket")
+	require.NoError(t, err)
+	require.Equal(t, &BucketEncryption{}, encryption)
+
+	configuration = `<ServerSideEncryptionConfiguration><Rule><ApplyServerSideEncryptionByDefault>` +
+		`<SSEAlgorithm>aws:kms</SSEAlgorithm></ApplyServerSideEncryptionByDefault>` +
+		`<BucketKeyEnabled>true</BucketKeyEnabled></Rule></ServerSideEncryptionConfiguration>`
+
+	encryption, err = awsGetBucketEncryption(ctx, client, "bucket")
+	require.NoError(t, err)
+	require.Equal(t, &BucketEncryption{KMSKeyName: "alias/aws/s3", AWSBucketKey: true}, encryption)
+
+	require.NoError(t, awsSetBucketEncryption(ctx, client, "bucket", &BucketEncryption{KMSKeyName: "key-id"}))
+	require.Contains(t, written, "<SSEAlgorithm>aws:kms</SSEAlgorithm>")
+	require.Contains(t, written, "<KMSMasterKeyID>key-id</KMSMasterKeyID>")
+
+	require.NoError(t, awsSetBucketEncryption(ctx, client, "bucket", &BucketEncryption{}))
+	require.Contains(t, written, "<SSEAlgorithm>AES256</SSEAlgorithm>")
+	require.NotContains(t, written, "KMSMasterKeyID")
+}
+
+func TestFakeCloudStorageBucketEncryption(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.CreateBucketWithOptions(ctx, &CreateBucketOption{KMSKeyName: "key"}))
+
+	encryption, err := storage.GetBucketEncryption(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &BucketEncryption{KMSKeyName: "key"}, encryption)
+
+	require.NoError(t, storage.SetBucketEncryption(ctx, &BucketEncryption{}))
+
+	encryption, err = storage.GetBucketEncryption(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &BucketEncryption{}, encryption)
+
+	scoped, err := ScopedStorage(storage, "tenant/")
+	require.NoError(t, err)
+
+	_, err = scoped.GetBucketEncryption(ctx)
+	require.ErrorIs(t, err, ErrPermissionDenied)
+}
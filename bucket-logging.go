@@ -0,0 +1,116 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// BucketLogging is the destination of the access logs of a bucket
+type BucketLogging struct {
+	// TargetBucket is the bucket receiving the logs, the logging is disabled when it's empty.
+	// It must grant the write access to the log delivery of the provider.
+	TargetBucket string
+	// TargetPrefix is the prefix of the log objects, e.g. "logs/my-bucket/"
+	TargetPrefix string
+}
+
+func awsGetBucketLogging(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+) (*BucketLogging, error) {
+	out, err := client.GetBucketLoggingWithContext(ctx, &s3.GetBucketLoggingInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	if out.LoggingEnabled == nil {
+		return &BucketLogging{}, nil
+	}
+
+	return &BucketLogging{
+		TargetBucket: aws.StringValue(out.LoggingEnabled.TargetBucket),
+		TargetPrefix: aws.StringValue(out.LoggingEnabled.TargetPrefix),
+	}, nil
+}
+
+func awsSetBucketLogging(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	logging *BucketLogging,
+) error {
+	status := &s3.BucketLoggingStatus{}
+	if logging.TargetBucket != "" {
+		status.LoggingEnabled = &s3.LoggingEnabled{
+			TargetBucket: aws.String(logging.TargetBucket),
+			TargetPrefix: aws.String(logging.TargetPrefix),
+		}
+	}
+
+	_, err := client.PutBucketLoggingWithContext(ctx, &s3.PutBucketLoggingInput{
+		Bucket:              aws.String(bucketName),
+		BucketLoggingStatus: status,
+	})
+
+	return translateError(err)
+}
+
+func gcpGetBucketLogging(
+	ctx context.Context,
+	client *storage.Client,
+	bucketName string,
+) (*BucketLogging, error) {
+	attrs, err := client.Bucket(bucketName).Attrs(ctx)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	if attrs.Logging == nil {
+		return &BucketLogging{}, nil
+	}
+
+	return &BucketLogging{TargetBucket: attrs.Logging.LogBucket, TargetPrefix: attrs.Logging.LogObjectPrefix}, nil
+}
+
+// gcpSetBucketLogging sets the logging of the bucket, or removes it when the target bucket is empty
+func gcpSetBucketLogging(
+	ctx context.Context,
+	client *storage.Client,
+	bucketName string,
+	logging *BucketLogging,
+) error {
+	update := &storage.BucketLogging{}
+	if logging.TargetBucket != "" {
+		update = gcpBucketLogging(logging)
+	}
+
+	_, err := client.Bucket(bucketName).Update(ctx, storage.BucketAttrsToUpdate{Logging: update})
+
+	return translateError(err)
+}
+
+func gcpBucketLogging(logging *BucketLogging) *storage.BucketLogging {
+	return &storage.BucketLogging{LogBucket: logging.TargetBucket, LogObjectPrefix: logging.TargetPrefix}
+}
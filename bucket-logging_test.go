@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSBucketLogging(t *testing.T) {
+	var (
+		status  = `<BucketLoggingStatus></BucketLoggingStatus>`
+		written string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(status))
+		case http.MethodPut:
+			body, _ := ioutil.ReadAll(r.Body)
+			written = string(body)
+		}
+	}))
+	defer server.Close()
+
+	awsSession, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(server.URL),
+		Region:           aws.String("us-east-1"),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	client := s3.New(awsSession)
+
+	logging, err := awsGetBucketLogging(ctx, client, "bucket")
+	require.NoError(t, err)
+	require.Equal(t, &BucketLogging{}, logging)
+
+	status = `<BucketLoggingStatus><LoggingEnabled><TargetBucket>logs</TargetBucket>` +
+		`<TargetPrefix>bucket/</TargetPrefix></LoggingEnabled></BucketLoggingStatus>`
+
+	logging, err = awsGetBucketLogging(ctx, client, "bucket")
+	require.NoError(t, err)
+	require.Equal(t, &BucketLogging{TargetBucket: "logs", TargetPrefix: "bucket/"}, logging)
+
+	require.NoError(t, awsSetBucketLogging(ctx, client, "bucket", &BucketLogging{TargetBucket: "logs"}))
+	require.Contains(t, written, "<TargetBucket>logs</TargetBucket>")
+
+	// the logging is disabled with an empty status
+	require.NoError(t, awsSetBucketLogging(ctx, client, "bucket", &BucketLogging{}))
+	require.NotContains(t, written, "LoggingEnabled")
+}
+
+func TestFakeCloudStorageBucketLogging(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.CreateBucketWithOptions(ctx, &CreateBucketOption{
+		Logging: &BucketLogging{TargetBucket: "logs", TargetPrefix: "bucket/"},
+	}))
+
+	logging, err := storage.GetBucketLogging(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &BucketLogging{TargetBucket: "logs", TargetPrefix: "bucket/"}, logging)
+
+	require.NoError(t, storage.SetBucketLogging(ctx, &BucketLogging{}))
+
+	logging, err = storage.GetBucketLogging(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &BucketLogging{}, logging)
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/iam"
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// BucketPolicy holds the access policy of a bucket.
+type BucketPolicy struct {
+	// Policy is the S3 bucket policy JSON document, empty when the bucket has none. Only used on AWS:
+	// SetBucketPolicy deletes the policy of the bucket when it's empty.
+	Policy string
+	// Bindings maps an IAM role (e.g. "roles/storage.objectViewer") to its members
+	// (e.g. "serviceAccount:reader@project.iam.gserviceaccount.com"). Only used on GCP.
+	// Roles which aren't present in the map are left untouched by SetBucketPolicy.
+	Bindings map[string][]string
+	// UniformBucketLevelAccess is whether the access is only granted by the IAM bindings, the ACLs being
+	// disabled. Only used on GCP: GetBucketPolicy sets it, and SetBucketPolicy enables or disables it when
+	// it's set. SetBucketPolicy returns ErrNotSupported when it's set on AWS.
+	UniformBucketLevelAccess *bool
+}
+
+func awsGetBucketPolicy(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+) (*BucketPolicy, error) {
+	out, err := client.GetBucketPolicyWithContext(ctx, &s3.GetBucketPolicyInput{
+		Bucket: aws.String(bucketName),
+	})
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == "NoSuchBucketPolicy" {
+		return &BucketPolicy{}, nil
+	}
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return &BucketPolicy{
+		Policy: aws.StringValue(out.Policy),
+	}, nil
+}
+
+func awsSetBucketPolicy(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	policy *BucketPolicy,
+) error {
+	if policy.UniformBucketLevelAccess != nil {
+		return fmt.Errorf("%w: uniform bucket-level access is only available on GCP", ErrNotSupported)
+	}
+
+	// S3 rejects an empty policy document, the policy is deleted instead
+	if policy.Policy == "" {
+		_, err := client.DeleteBucketPolicyWithContext(ctx, &s3.DeleteBucketPolicyInput{
+			Bucket: aws.String(bucketName),
+		})
+
+		return translateError(err)
+	}
+
+	_, err := client.PutBucketPolicyWithContext(ctx, &s3.PutBucketPolicyInput{
+		Bucket: aws.String(bucketName),
+		Policy: aws.String(policy.Policy),
+	})
+
+	return translateError(err)
+}
+
+func gcpGetBucketPolicy(
+	ctx context.Context,
+	client *storage.Client,
+	bucketName string,
+) (*BucketPolicy, error) {
+	attrs, err := client.Bucket(bucketName).Attrs(ctx)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	policy, err := client.Bucket(bucketName).IAM().Policy(ctx)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	bindings := make(map[string][]string)
+	for _, role := range policy.Roles() {
+		bindings[string(role)] = policy.Members(role)
+	}
+
+	return &BucketPolicy{
+		Bindings:                 bindings,
+		UniformBucketLevelAccess: aws.Bool(attrs.UniformBucketLevelAccess.Enabled),
+	}, nil
+}
+
+func gcpSetBucketPolicy(
+	ctx context.Context,
+	client *storage.Client,
+	bucketName string,
+	policy *BucketPolicy,
+) error {
+	// the uniform bucket-level access is enabled first, so the bindings are the only grants from then on
+	if policy.UniformBucketLevelAccess != nil {
+		_, err := client.Bucket(bucketName).Update(ctx, storage.BucketAttrsToUpdate{
+			UniformBucketLevelAccess: &storage.UniformBucketLevelAccess{Enabled: *policy.UniformBucketLevelAccess},
+		})
+		if err != nil {
+			return translateError(err)
+		}
+	}
+
+	if len(policy.Bindings) == 0 {
+		return nil
+	}
+
+	handle := client.Bucket(bucketName).IAM()
+
+	// read-modify-write, the etag of the fetched policy protects from concurrent updates
+	current, err := handle.Policy(ctx)
+	if err != nil {
+		return translateError(err)
+	}
+
+	for role, members := range policy.Bindings {
+		roleName := iam.RoleName(role)
+
+		for _, member := range current.Members(roleName) {
+			current.Remove(member, roleName)
+		}
+
+		for _, member := range members {
+			current.Add(member, roleName)
+		}
+	}
+
+	return translateError(handle.SetPolicy(ctx, current))
+}
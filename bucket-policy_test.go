@@ -0,0 +1,204 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+func TestAWSBucketPolicy(t *testing.T) {
+	var stored string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<Error><Code>NoSuchBucket</Code><Message>The specified bucket does not exist</Message></Error>`))
+
+			return
+		}
+
+		require.Equal(t, "/bucket", r.URL.Path)
+		require.Contains(t, r.URL.Query(), "policy")
+
+		switch r.Method {
+		case http.MethodGet:
+			if stored == "" {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`<Error><Code>NoSuchBucketPolicy</Code><Message>The bucket policy does not exist</Message></Error>`))
+
+				return
+			}
+
+			w.Write([]byte(stored))
+		case http.MethodPut:
+			body, _ := ioutil.ReadAll(r.Body)
+			stored = string(body)
+		case http.MethodDelete:
+			stored = ""
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	awsSession, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(server.URL),
+		Region:           aws.String("us-east-1"),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	client := s3.New(awsSession)
+
+	// a bucket without a policy has an empty one
+	got, err := awsGetBucketPolicy(ctx, client, "bucket")
+	require.NoError(t, err)
+	require.Equal(t, &BucketPolicy{}, got)
+
+	policy := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::bucket/*"}]}`
+
+	require.NoError(t, awsSetBucketPolicy(ctx, client, "bucket", &BucketPolicy{Policy: policy}))
+	require.Equal(t, policy, stored)
+
+	got, err = awsGetBucketPolicy(ctx, client, "bucket")
+	require.NoError(t, err)
+	require.Equal(t, policy, got.Policy)
+
+	// an empty policy deletes it
+	require.NoError(t, awsSetBucketPolicy(ctx, client, "bucket", &BucketPolicy{}))
+	require.Equal(t, "", stored)
+
+	err = awsSetBucketPolicy(ctx, client, "bucket", &BucketPolicy{UniformBucketLevelAccess: aws.Bool(true)})
+	require.ErrorIs(t, err, ErrNotSupported)
+
+	_, err = awsGetBucketPolicy(ctx, client, "missing")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestGCPBucketPolicy(t *testing.T) {
+	type binding struct {
+		Role    string   `json:"role"`
+		Members []string `json:"members"`
+	}
+
+	var (
+		uniform  bool
+		bindings = []binding{{Role: "roles/storage.objectViewer", Members: []string{"allUsers"}}}
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /storage/v1/b/bucket":
+		case "PATCH /storage/v1/b/bucket":
+			var update struct {
+				IamConfiguration struct {
+					UniformBucketLevelAccess struct {
+						Enabled bool `json:"enabled"`
+					} `json:"uniformBucketLevelAccess"`
+				} `json:"iamConfiguration"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&update))
+			uniform = update.IamConfiguration.UniformBucketLevelAccess.Enabled
+		case "GET /storage/v1/b/bucket/iam":
+			json.NewEncoder(w).Encode(map[string]interface{}{"bindings": bindings, "etag": "CAE="})
+
+			return
+		case "PUT /storage/v1/b/bucket/iam":
+			var policy struct {
+				Bindings []binding `json:"bindings"`
+				Etag     string    `json:"etag"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&policy))
+			require.Equal(t, "CAE=", policy.Etag)
+			bindings = policy.Bindings
+			json.NewEncoder(w).Encode(map[string]interface{}{"bindings": bindings, "etag": "CAI="})
+
+			return
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":404,"message":"Not Found"}}`))
+
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"name": "bucket",
+			"iamConfiguration": map[string]interface{}{
+				"uniformBucketLevelAccess": map[string]interface{}{"enabled": uniform},
+			},
+		})
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	client, err := storage.NewClient(ctx, option.WithEndpoint(server.URL+"/storage/v1/"), option.WithoutAuthentication())
+	require.NoError(t, err)
+
+	defer client.Close()
+
+	got, err := gcpGetBucketPolicy(ctx, client, "bucket")
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"roles/storage.objectViewer": {"allUsers"}}, got.Bindings)
+	require.False(t, *got.UniformBucketLevelAccess)
+
+	// the roles of the policy replace their members, the other roles are untouched
+	err = gcpSetBucketPolicy(ctx, client, "bucket", &BucketPolicy{
+		Bindings: map[string][]string{
+			"roles/storage.objectAdmin": {"serviceAccount:writer@project.iam.gserviceaccount.com"},
+		},
+		UniformBucketLevelAccess: aws.Bool(true),
+	})
+	require.NoError(t, err)
+	require.True(t, uniform)
+
+	got, err = gcpGetBucketPolicy(ctx, client, "bucket")
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{
+		"roles/storage.objectViewer": {"allUsers"},
+		"roles/storage.objectAdmin":  {"serviceAccount:writer@project.iam.gserviceaccount.com"},
+	}, got.Bindings)
+	require.True(t, *got.UniformBucketLevelAccess)
+
+	// an empty policy leaves the bindings as they are
+	require.NoError(t, gcpSetBucketPolicy(ctx, client, "bucket", &BucketPolicy{}))
+	require.Len(t, bindings, 2)
+
+	// a bucket without bindings has an empty policy
+	bindings = nil
+
+	got, err = gcpGetBucketPolicy(ctx, client, "bucket")
+	require.NoError(t, err)
+	require.Empty(t, got.Bindings)
+
+	_, err = gcpGetBucketPolicy(ctx, client, "missing")
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,230 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	// awsReplicationRuleID is the ID of the replication rule set by SetBucketReplication
+	awsReplicationRuleID = "common-blob-go"
+	// awsBucketARNPrefix is the prefix of the ARN of an S3 bucket
+	awsBucketARNPrefix = "arn:aws:s3:::"
+	// awsReplicationTimeMinutes is the only threshold of the S3 Replication Time Control
+	awsReplicationTimeMinutes = 15
+
+	// gcpRPODefault and gcpRPOAsyncTurbo are the recovery point objectives of a dual-region GCS bucket
+	gcpRPODefault    = "DEFAULT"
+	gcpRPOAsyncTurbo = "ASYNC_TURBO"
+)
+
+// BucketReplication is the replication of the objects of a bucket to another region, e.g. for the disaster recovery.
+//
+// S3 replicates the objects to a destination bucket, both buckets must have the versioning enabled. The objects of
+// a GCS bucket are replicated within its location: the dual-region or multi-region location is chosen when the
+// bucket is created, e.g. "NAM4", so only Turbo can be set.
+type BucketReplication struct {
+	// DestinationBucket is the name or the ARN of the S3 bucket receiving the replicas, the replication is
+	// disabled when it's empty. Only used on AWS.
+	DestinationBucket string
+	// Role is the ARN of the IAM role assumed by S3 to replicate the objects. Only used on AWS.
+	Role string
+	// Prefix limits the replication to the objects under it, all the objects are replicated when it's empty.
+	// Only used on AWS.
+	Prefix string
+	// StorageClass is the storage class of the replicas, the class of the objects when it's empty. Only used on AWS.
+	StorageClass string
+	// ReplicateDeletes replicates the delete markers of the deleted objects. Only used on AWS.
+	ReplicateDeletes bool
+	// Turbo replicates the new objects within 15 minutes: the turbo replication of a dual-region GCS bucket, or
+	// the Replication Time Control of S3, which also enables the replication metrics
+	Turbo bool
+}
+
+func awsGetBucketReplication(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+) (*BucketReplication, error) {
+	out, err := client.GetBucketReplicationWithContext(ctx, &s3.GetBucketReplicationInput{
+		Bucket: aws.String(bucketName),
+	})
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == "ReplicationConfigurationNotFoundError" {
+		return &BucketReplication{}, nil
+	}
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	config := out.ReplicationConfiguration
+	if config == nil || len(config.Rules) == 0 {
+		return &BucketReplication{}, nil
+	}
+
+	// the rule set by SetBucketReplication, or the first one of a configuration set elsewhere
+	rule := config.Rules[0]
+	for _, candidate := range config.Rules {
+		if aws.StringValue(candidate.ID) == awsReplicationRuleID {
+			rule = candidate
+			break
+		}
+	}
+
+	if aws.StringValue(rule.Status) != s3.ReplicationRuleStatusEnabled || rule.Destination == nil {
+		return &BucketReplication{}, nil
+	}
+
+	replication := &BucketReplication{
+		DestinationBucket: strings.TrimPrefix(aws.StringValue(rule.Destination.Bucket), awsBucketARNPrefix),
+		Role:              aws.StringValue(config.Role),
+		Prefix:            aws.StringValue(rule.Prefix),
+		StorageClass:      aws.StringValue(rule.Destination.StorageClass),
+	}
+
+	if rule.Filter != nil && rule.Filter.Prefix != nil {
+		replication.Prefix = aws.StringValue(rule.Filter.Prefix)
+	}
+
+	if rule.DeleteMarkerReplication != nil {
+		replication.ReplicateDeletes = aws.StringValue(rule.DeleteMarkerReplication.Status) ==
+			s3.DeleteMarkerReplicationStatusEnabled
+	}
+
+	if rule.Destination.ReplicationTime != nil {
+		replication.Turbo = aws.StringValue(rule.Destination.ReplicationTime.Status) ==
+			s3.ReplicationTimeStatusEnabled
+	}
+
+	return replication, nil
+}
+
+// awsSetBucketReplication replaces the replication configuration of the bucket, or deletes it when the destination
+// bucket is empty
+func awsSetBucketReplication(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	replication *BucketReplication,
+) error {
+	if replication.DestinationBucket == "" {
+		_, err := client.DeleteBucketReplicationWithContext(ctx, &s3.DeleteBucketReplicationInput{
+			Bucket: aws.String(bucketName),
+		})
+
+		return translateError(err)
+	}
+
+	destination := replication.DestinationBucket
+	if !strings.HasPrefix(destination, "arn:") {
+		destination = awsBucketARNPrefix + destination
+	}
+
+	deleteMarkers := s3.DeleteMarkerReplicationStatusDisabled
+	if replication.ReplicateDeletes {
+		deleteMarkers = s3.DeleteMarkerReplicationStatusEnabled
+	}
+
+	rule := &s3.ReplicationRule{
+		ID:                      aws.String(awsReplicationRuleID),
+		Priority:                aws.Int64(1),
+		Status:                  aws.String(s3.ReplicationRuleStatusEnabled),
+		Filter:                  &s3.ReplicationRuleFilter{Prefix: aws.String(replication.Prefix)},
+		DeleteMarkerReplication: &s3.DeleteMarkerReplication{Status: aws.String(deleteMarkers)},
+		Destination:             &s3.Destination{Bucket: aws.String(destination)},
+	}
+
+	if replication.StorageClass != "" {
+		rule.Destination.StorageClass = aws.String(replication.StorageClass)
+	}
+
+	if replication.Turbo {
+		threshold := &s3.ReplicationTimeValue{Minutes: aws.Int64(awsReplicationTimeMinutes)}
+
+		rule.Destination.ReplicationTime = &s3.ReplicationTime{
+			Status: aws.String(s3.ReplicationTimeStatusEnabled),
+			Time:   threshold,
+		}
+		rule.Destination.Metrics = &s3.Metrics{
+			Status:         aws.String(s3.MetricsStatusEnabled),
+			EventThreshold: threshold,
+		}
+	}
+
+	_, err := client.PutBucketReplicationWithContext(ctx, &s3.PutBucketReplicationInput{
+		Bucket: aws.String(bucketName),
+		ReplicationConfiguration: &s3.ReplicationConfiguration{
+			Role:  aws.String(replication.Role),
+			Rules: []*s3.ReplicationRule{rule},
+		},
+	})
+
+	return translateError(err)
+}
+
+// gcpBucketRPO is the recovery point objective of a GCS bucket, missing from the storage client
+type gcpBucketRPO struct {
+	RPO string `json:"rpo,omitempty"`
+}
+
+// gcpGetBucketReplication reads the recovery point objective of the bucket through the JSON API
+func gcpGetBucketReplication(
+	ctx context.Context,
+	api *gcpFolders,
+) (*BucketReplication, error) {
+	var attrs gcpBucketRPO
+
+	path := fmt.Sprintf("b/%s?fields=rpo", url.PathEscape(api.bucketName))
+	if err := api.do(ctx, http.MethodGet, path, nil, &attrs); err != nil {
+		return nil, err
+	}
+
+	return &BucketReplication{Turbo: attrs.RPO == gcpRPOAsyncTurbo}, nil
+}
+
+// gcpSetBucketReplication sets the recovery point objective of the dual-region bucket through the JSON API,
+// the other fields being S3 only
+func gcpSetBucketReplication(
+	ctx context.Context,
+	api *gcpFolders,
+	replication *BucketReplication,
+) error {
+	if replication.DestinationBucket != "" {
+		return fmt.Errorf("%w: the GCS buckets are replicated within their location", ErrNotSupported)
+	}
+
+	rpo := gcpRPODefault
+	if replication.Turbo {
+		rpo = gcpRPOAsyncTurbo
+	}
+
+	path := fmt.Sprintf("b/%s?fields=rpo", url.PathEscape(api.bucketName))
+
+	return api.do(ctx, http.MethodPatch, path, gcpBucketRPO{RPO: rpo}, nil)
+}
@@ -0,0 +1,198 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSBucketReplication(t *testing.T) {
+	var (
+		configuration string
+		written       string
+		deleted       bool
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if configuration == "" {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`<Error><Code>ReplicationConfigurationNotFoundError</Code></Error>`))
+				return
+			}
+
+			w.Write([]byte(configuration))
+		case http.MethodPut:
+			body, _ := ioutil.ReadAll(r.Body)
+			written = string(body)
+		case http.MethodDelete:
+			deleted = true
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	awsSession, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(server.URL),
+		Region:           aws.String("us-east-1"),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	client := s3.New(awsSession)
+
+	replication, err := awsGetBucketReplication(ctx, client, "bucket")
+	require.NoError(t, err)
+	require.Equal(t, &BucketReplication{}, replication)
+
+	configuration = `<ReplicationConfiguration><Role>arn:aws:iam::123456789012:role/replication</Role>` +
+		`<Rule><ID>other</ID><Status>Disabled</Status></Rule>` +
+		`<Rule><ID>common-blob-go</ID><Status>Enabled</Status><Filter><Prefix>data/</Prefix></Filter>` +
+		`<DeleteMarkerReplication><Status>Enabled</Status></DeleteMarkerReplication>` +
+		`<Destination><Bucket>arn:aws:s3:::replica</Bucket><StorageClass>STANDARD_IA</StorageClass>` +
+		`<ReplicationTime><Status>Enabled</Status><Time><Minutes>15</Minutes></Time></ReplicationTime>` +
+		`</Destination></Rule></ReplicationConfiguration>`
+
+	replication, err = awsGetBucketReplication(ctx, client, "bucket")
+	require.NoError(t, err)
+	require.Equal(t, &BucketReplication{
+		DestinationBucket: "replica",
+		Role:              "arn:aws:iam::123456789012:role/replication",
+		Prefix:            "data/",
+		StorageClass:      "STANDARD_IA",
+		ReplicateDeletes:  true,
+		Turbo:             true,
+	}, replication)
+
+	require.NoError(t, awsSetBucketReplication(ctx, client, "bucket", &BucketReplication{
+		DestinationBucket: "replica",
+		Role:              "arn:aws:iam::123456789012:role/replication",
+	}))
+	require.Contains(t, written, "<Bucket>arn:aws:s3:::replica</Bucket>")
+	require.Contains(t, written, "<DeleteMarkerReplication><Status>Disabled</Status></DeleteMarkerReplication>")
+	require.NotContains(t, written, "ReplicationTime")
+
+	// the turbo replication enables the Replication Time Control and its metrics
+	require.NoError(t, awsSetBucketReplication(ctx, client, "bucket", &BucketReplication{
+		DestinationBucket: "arn:aws:s3:::replica",
+		Turbo:             true,
+	}))
+	require.Contains(t, written, "<Bucket>arn:aws:s3:::replica</Bucket>")
+
+	// the SDK doesn't keep the order of the elements, the configuration is decoded
+	var put struct {
+		Destination struct {
+			ReplicationTime struct {
+				Status  string
+				Minutes int `xml:"Time>Minutes"`
+			}
+			Metrics struct {
+				Status  string
+				Minutes int `xml:"EventThreshold>Minutes"`
+			}
+		} `xml:"Rule>Destination"`
+	}
+	require.NoError(t, xml.Unmarshal([]byte(written), &put))
+	require.Equal(t, "Enabled", put.Destination.ReplicationTime.Status)
+	require.Equal(t, 15, put.Destination.ReplicationTime.Minutes)
+	require.Equal(t, "Enabled", put.Destination.Metrics.Status)
+	require.Equal(t, 15, put.Destination.Metrics.Minutes)
+
+	// the replication is disabled without a destination bucket
+	require.NoError(t, awsSetBucketReplication(ctx, client, "bucket", &BucketReplication{}))
+	require.True(t, deleted)
+}
+
+func TestGCPBucketReplication(t *testing.T) {
+	rpo := ""
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/storage/v1/b/bucket", r.URL.EscapedPath())
+		require.Equal(t, "rpo", r.URL.Query().Get("fields"))
+
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(gcpBucketRPO{RPO: rpo})
+		case http.MethodPatch:
+			var body gcpBucketRPO
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			rpo = body.RPO
+			json.NewEncoder(w).Encode(body)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	endpoint := gcpFoldersEndpoint
+	gcpFoldersEndpoint = server.URL + "/storage/v1/"
+
+	defer func() {
+		gcpFoldersEndpoint = endpoint
+	}()
+
+	ctx := context.Background()
+	api := newGCPFolders(nil, "bucket", &CloudStorageOption{})
+
+	// the single-region buckets have no recovery point objective
+	replication, err := gcpGetBucketReplication(ctx, api)
+	require.NoError(t, err)
+	require.Equal(t, &BucketReplication{}, replication)
+
+	require.NoError(t, gcpSetBucketReplication(ctx, api, &BucketReplication{Turbo: true}))
+	require.Equal(t, "ASYNC_TURBO", rpo)
+
+	replication, err = gcpGetBucketReplication(ctx, api)
+	require.NoError(t, err)
+	require.Equal(t, &BucketReplication{Turbo: true}, replication)
+
+	require.NoError(t, gcpSetBucketReplication(ctx, api, &BucketReplication{}))
+	require.Equal(t, "DEFAULT", rpo)
+
+	err = gcpSetBucketReplication(ctx, api, &BucketReplication{DestinationBucket: "replica"})
+	require.ErrorIs(t, err, ErrNotSupported)
+}
+
+func TestFakeCloudStorageBucketReplication(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	replication, err := storage.GetBucketReplication(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &BucketReplication{}, replication)
+
+	require.NoError(t, storage.SetBucketReplication(ctx, &BucketReplication{DestinationBucket: "replica", Turbo: true}))
+
+	replication, err = storage.GetBucketReplication(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &BucketReplication{DestinationBucket: "replica", Turbo: true}, replication)
+}
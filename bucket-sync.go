@@ -0,0 +1,254 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultSyncConcurrency is the number of objects copied in parallel by Sync when SyncOption.Concurrency is not set
+const DefaultSyncConcurrency = 8
+
+// SyncOption configures Sync
+type SyncOption struct {
+	// Concurrency is the number of objects copied in parallel.
+	Concurrency int
+	// Delete removes the destination objects missing from the source.
+	Delete bool
+	// DryRun only fills the report, nothing is copied nor deleted.
+	DryRun bool
+}
+
+// SyncReport summarizes a Sync. The keys are relative to the prefixes.
+type SyncReport struct {
+	Copied  []string
+	Deleted []string
+	// Unchanged is the number of objects already up to date in the destination
+	Unchanged int
+	// Bytes is the size of the copied objects
+	Bytes int64
+}
+
+// Sync makes the objects under dstPrefix of dst match the objects under srcPrefix of src, like rsync.
+// The storages can be different providers. An object is copied when it's missing from the destination or its size
+// differs. When both listings have the MD5 the contents are compared, otherwise the source is copied if it's newer.
+// The first error stops the sync and is returned with the report of what was done until then.
+func Sync(
+	ctx context.Context,
+	src CloudStorage,
+	srcPrefix string,
+	dst CloudStorage,
+	dstPrefix string,
+	opts *SyncOption,
+) (*SyncReport, error) {
+	report := &SyncReport{}
+
+	srcObjects, err := listByName(ctx, src, srcPrefix)
+	if err != nil {
+		return report, err
+	}
+
+	dstObjects, err := listByName(ctx, dst, dstPrefix)
+	if err != nil {
+		return report, err
+	}
+
+	return syncObjects(ctx, src, srcObjects, dst, dstPrefix, dstObjects, opts)
+}
+
+// syncObjects copies the source objects differing from the known destination objects, and deletes the known objects
+// missing from the source when opts.Delete is set. The objects are indexed by their key without the prefix.
+// nolint:funlen
+func syncObjects(
+	ctx context.Context,
+	src CloudStorage,
+	srcObjects map[string]*ListObject,
+	dst CloudStorage,
+	dstPrefix string,
+	known map[string]*ListObject,
+	opts *SyncOption,
+) (*SyncReport, error) {
+	var options SyncOption
+	if opts != nil {
+		options = *opts
+	}
+
+	if options.Concurrency <= 0 {
+		options.Concurrency = DefaultSyncConcurrency
+	}
+
+	report := &SyncReport{}
+
+	syncCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		errOnce sync.Once
+		syncErr error
+	)
+
+	setErr := func(err error) {
+		errOnce.Do(func() {
+			syncErr = err
+
+			cancel()
+		})
+	}
+
+	semaphore := make(chan struct{}, options.Concurrency)
+
+	run := func(task func() error) {
+		semaphore <- struct{}{}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			if err := task(); err != nil {
+				setErr(err)
+			}
+		}()
+	}
+
+	for name, object := range srcObjects {
+		if syncCtx.Err() != nil {
+			break
+		}
+
+		if !objectChanged(object, known[name]) {
+			report.Unchanged++
+			continue
+		}
+
+		name, object := name, object
+
+		run(func() error {
+			if !options.DryRun {
+				if err := copyObject(syncCtx, src, object.Key, dst, dstPrefix+name); err != nil {
+					return err
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			report.Copied = append(report.Copied, name)
+			report.Bytes += object.Size
+
+			return nil
+		})
+	}
+
+	if options.Delete {
+		for name := range known {
+			if syncCtx.Err() != nil {
+				break
+			}
+
+			if _, ok := srcObjects[name]; ok {
+				continue
+			}
+
+			name := name
+
+			run(func() error {
+				if !options.DryRun {
+					if err := dst.Delete(syncCtx, dstPrefix+name); err != nil {
+						return err
+					}
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				report.Deleted = append(report.Deleted, name)
+
+				return nil
+			})
+		}
+	}
+
+	wg.Wait()
+
+	sort.Strings(report.Copied)
+	sort.Strings(report.Deleted)
+
+	if syncErr != nil {
+		return report, syncErr
+	}
+
+	return report, ctx.Err()
+}
+
+// listByName lists the objects under the prefix by their key without the prefix, skipping the folder placeholders
+func listByName(ctx context.Context, storage CloudStorage, prefix string) (map[string]*ListObject, error) {
+	objects := make(map[string]*ListObject)
+	iter := storage.List(ctx, prefix)
+
+	for {
+		object, err := iter.Next(ctx)
+		if err == io.EOF {
+			return objects, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimPrefix(object.Key, prefix)
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+
+		objects[name] = object
+	}
+}
+
+// objectChanged returns whether the source object has to be copied over the destination object
+func objectChanged(src *ListObject, dst *ListObject) bool {
+	if dst == nil || src.Size != dst.Size {
+		return true
+	}
+
+	if len(src.MD5) > 0 && len(dst.MD5) > 0 {
+		return !bytes.Equal(src.MD5, dst.MD5)
+	}
+
+	return src.ModTime.After(dst.ModTime)
+}
+
+// copyObject copies the object from src to dst with its attributes, with a server-side Copy when they're
+// the same storage
+func copyObject(ctx context.Context, src CloudStorage, srcKey string, dst CloudStorage, dstKey string) error {
+	if src == dst {
+		if err := src.Copy(ctx, srcKey, dstKey, nil); !errors.Is(err, ErrNotSupported) {
+			return err
+		}
+	}
+
+	return streamCopy(ctx, src, srcKey, dst, dstKey, nil)
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	src := NewFakeCloudStorage("src")
+	dst := NewFakeCloudStorage("dst")
+
+	contentType := "text/csv"
+	require.NoError(t, src.Write(ctx, "data/new.csv", []byte("a,b"), &contentType))
+	require.NoError(t, src.Write(ctx, "data/changed.txt", []byte("new"), nil))
+	require.NoError(t, src.Write(ctx, "data/same.txt", []byte("same"), nil))
+	require.NoError(t, src.Write(ctx, "other/skipped.txt", []byte("other"), nil))
+
+	require.NoError(t, dst.Write(ctx, "backup/changed.txt", []byte("old"), nil))
+	require.NoError(t, dst.Write(ctx, "backup/same.txt", []byte("same"), nil))
+	require.NoError(t, dst.Write(ctx, "backup/extra.txt", []byte("extra"), nil))
+
+	report, err := Sync(ctx, src, "data/", dst, "backup/", &SyncOption{DryRun: true, Delete: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"changed.txt", "new.csv"}, report.Copied)
+	require.Equal(t, []string{"extra.txt"}, report.Deleted)
+
+	body, err := dst.Get(ctx, "backup/changed.txt")
+	require.NoError(t, err)
+	require.Equal(t, []byte("old"), body)
+
+	report, err = Sync(ctx, src, "data/", dst, "backup/", nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"changed.txt", "new.csv"}, report.Copied)
+	require.Empty(t, report.Deleted)
+	require.Equal(t, 1, report.Unchanged)
+	require.Equal(t, int64(6), report.Bytes)
+
+	body, err = dst.Get(ctx, "backup/changed.txt")
+	require.NoError(t, err)
+	require.Equal(t, []byte("new"), body)
+
+	attrs, err := dst.Attributes(ctx, "backup/new.csv")
+	require.NoError(t, err)
+	require.Equal(t, "text/csv", attrs.ContentType)
+
+	_, err = dst.Get(ctx, "backup/extra.txt")
+	require.NoError(t, err)
+
+	report, err = Sync(ctx, src, "data/", dst, "backup/", &SyncOption{Delete: true, Concurrency: 1})
+	require.NoError(t, err)
+	require.Empty(t, report.Copied)
+	require.Equal(t, []string{"extra.txt"}, report.Deleted)
+	require.Equal(t, 3, report.Unchanged)
+
+	_, err = dst.Get(ctx, "backup/extra.txt")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	_, err = dst.Get(ctx, "backup/skipped.txt")
+	require.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestSyncError(t *testing.T) {
+	ctx := context.Background()
+	src := NewFakeCloudStorage("src")
+	dst := NewFaultInjectingCloudStorage(NewFakeCloudStorage("dst"), FaultInjectionOption{
+		Operations: map[string]Fault{"Upload": {ErrorRate: 1}},
+	})
+
+	require.NoError(t, src.Write(ctx, "a.txt", []byte("a"), nil))
+
+	report, err := Sync(ctx, src, "", dst, "", nil)
+	require.Error(t, err)
+	require.Empty(t, report.Copied)
+}
@@ -0,0 +1,520 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCacheMaxBytes       = 64 * 1024 * 1024
+	defaultCacheMaxObjectBytes = 1024 * 1024
+	defaultCacheTTL            = time.Minute
+)
+
+// CacheOption configures the read-through cache.
+type CacheOption struct {
+	// MaxBytes is the maximum total size of the cached objects. Defaults to 64MB.
+	MaxBytes int64
+	// MaxObjectBytes is the maximum size of a single cached object, larger objects are never cached. Defaults to 1MB.
+	MaxObjectBytes int64
+	// TTL is how long a cached object is served without revalidating it against the provider. Defaults to 1 minute.
+	// Once expired, the object version is checked with Attributes and downloaded again only if it has changed.
+	TTL time.Duration
+	// Directory stores the cached objects on the local disk instead of in memory.
+	Directory string
+	// Logger receives the cache failures. They are discarded when it's nil.
+	Logger Logger
+}
+
+func (o CacheOption) withDefaults() CacheOption {
+	if o.MaxBytes <= 0 {
+		o.MaxBytes = defaultCacheMaxBytes
+	}
+
+	if o.MaxObjectBytes <= 0 {
+		o.MaxObjectBytes = defaultCacheMaxObjectBytes
+	}
+
+	if o.TTL <= 0 {
+		o.TTL = defaultCacheTTL
+	}
+
+	o.Logger = loggerOrNoop(o.Logger)
+
+	return o
+}
+
+type cacheEntry struct {
+	key     string
+	version string
+	// file identifies the body in the cacheStore, every stored body gets a new one
+	file    string
+	attrs   *Attributes
+	size    int64
+	expires time.Time
+}
+
+// CachedCloudStorage serves Get, GetReader and GetWithAttributes from an LRU cache keyed by object key and version.
+// Writes and deletes made through it invalidate the cached object.
+// The mutex only guards the index, the bodies are read from and written to the store without holding it.
+type CachedCloudStorage struct {
+	CloudStorage
+
+	opts  CacheOption
+	store cacheStore
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	size    int64
+}
+
+// NewCachedCloudStorage wraps the storage with a read-through cache
+func NewCachedCloudStorage(storage CloudStorage, opts CacheOption) (*CachedCloudStorage, error) {
+	opts = opts.withDefaults()
+
+	var store cacheStore = &memoryCacheStore{bodies: make(map[string][]byte)}
+
+	if opts.Directory != "" {
+		if err := os.MkdirAll(opts.Directory, 0700); err != nil {
+			return nil, fmt.Errorf("unable to create cache directory: %v", err)
+		}
+
+		store = &diskCacheStore{directory: opts.Directory}
+	}
+
+	return &CachedCloudStorage{
+		CloudStorage: storage,
+		opts:         opts,
+		store:        store,
+		entries:      make(map[string]*list.Element),
+		lru:          list.New(),
+	}, nil
+}
+
+// objectVersion identifies the content of the object by its generation, or its ETag. The generation is preferred
+// since GCS doesn't return the ETag together with the body.
+func objectVersion(attrs *Attributes) string {
+	switch {
+	case attrs.Generation != "":
+		return "generation:" + attrs.Generation
+	case attrs.ETag != "":
+		return "etag:" + attrs.ETag
+	default:
+		// Last-Modified headers only have a precision of a second
+		return fmt.Sprintf("%d-%d", attrs.ModTime.Unix(), attrs.Size)
+	}
+}
+
+func (ts *CachedCloudStorage) Get(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) ([]byte, error) {
+	if body, _, ok := ts.lookup(ctx, key); ok {
+		return body, nil
+	}
+
+	reader, attrs, err := ts.CloudStorage.GetWithAttributes(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	ts.add(key, attrs, body)
+
+	return body, nil
+}
+
+func (ts *CachedCloudStorage) GetReader(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	reader, _, err := ts.GetWithAttributes(ctx, key, opts...)
+
+	return reader, err
+}
+
+func (ts *CachedCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	if body, attrs, ok := ts.lookup(ctx, key); ok {
+		return ioutil.NopCloser(bytes.NewReader(body)), attrs, nil
+	}
+
+	reader, attrs, err := ts.CloudStorage.GetWithAttributes(ctx, key, opts...)
+	if err != nil || attrs.Size > ts.opts.MaxObjectBytes {
+		return reader, attrs, err
+	}
+	defer reader.Close()
+
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ts.add(key, attrs, body)
+
+	return ioutil.NopCloser(bytes.NewReader(body)), attrs, nil
+}
+
+func (ts *CachedCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	defer ts.Invalidate(key)
+
+	return ts.CloudStorage.Write(ctx, key, body, contentType, opts...)
+}
+
+func (ts *CachedCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	ts.Invalidate(key)
+
+	return ts.CloudStorage.GetWriter(ctx, key, opts...)
+}
+
+func (ts *CachedCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	defer ts.Invalidate(key)
+
+	return ts.CloudStorage.Upload(ctx, key, reader, opts, writeOpts...)
+}
+
+func (ts *CachedCloudStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	defer ts.Invalidate(key)
+
+	return ts.CloudStorage.Delete(ctx, key)
+}
+
+func (ts *CachedCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	defer ts.Invalidate(key)
+
+	return ts.CloudStorage.WriteIf(ctx, key, body, contentType, condition)
+}
+
+func (ts *CachedCloudStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	defer ts.Invalidate(key)
+
+	return ts.CloudStorage.DeleteIf(ctx, key, generation)
+}
+
+// SetStorageClass invalidates the object, whose rewrite changes its version
+func (ts *CachedCloudStorage) SetStorageClass(
+	ctx context.Context,
+	key string,
+	storageClass string,
+) error {
+	defer ts.Invalidate(key)
+
+	return ts.CloudStorage.SetStorageClass(ctx, key, storageClass)
+}
+
+func (ts *CachedCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	defer ts.Invalidate(dstKey)
+
+	return ts.CloudStorage.Copy(ctx, srcKey, dstKey, opts)
+}
+
+// Invalidate removes the object from the cache
+func (ts *CachedCloudStorage) Invalidate(key string) {
+	ts.mu.Lock()
+
+	var files []string
+	if element, ok := ts.entries[key]; ok {
+		files = append(files, ts.removeElement(element))
+	}
+
+	ts.mu.Unlock()
+
+	ts.removeFiles(files)
+}
+
+// lookup returns the cached object, revalidating it with Attributes when the TTL has expired
+func (ts *CachedCloudStorage) lookup(ctx context.Context, key string) ([]byte, *Attributes, bool) {
+	ts.mu.Lock()
+
+	element, ok := ts.entries[key]
+	if !ok {
+		ts.mu.Unlock()
+		return nil, nil, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	fresh := time.Now().Before(entry.expires)
+	version := entry.version
+
+	ts.mu.Unlock()
+
+	if !fresh {
+		attrs, err := ts.CloudStorage.Attributes(ctx, key)
+		if err != nil {
+			return nil, nil, false
+		}
+
+		version = objectVersion(attrs)
+	}
+
+	return ts.load(key, version)
+}
+
+// load returns the cached object if it matches the version, and marks it as recently used
+func (ts *CachedCloudStorage) load(key, version string) ([]byte, *Attributes, bool) {
+	ts.mu.Lock()
+
+	element, ok := ts.entries[key]
+	if !ok {
+		ts.mu.Unlock()
+		return nil, nil, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if entry.version != version {
+		file := ts.removeElement(element)
+		ts.mu.Unlock()
+		ts.removeFiles([]string{file})
+
+		return nil, nil, false
+	}
+
+	file := entry.file
+	attrs := *entry.attrs
+
+	ts.mu.Unlock()
+
+	// the file of an entry is never rewritten, so a concurrent add or removal can only make the load miss
+	body, loaded := ts.store.load(file)
+
+	ts.mu.Lock()
+
+	var removed []string
+	if current, ok := ts.entries[key]; ok && current == element {
+		if loaded {
+			entry.expires = time.Now().Add(ts.opts.TTL)
+			ts.lru.MoveToFront(element)
+		} else {
+			removed = append(removed, ts.removeElement(element))
+		}
+	}
+
+	ts.mu.Unlock()
+
+	ts.removeFiles(removed)
+
+	if !loaded {
+		return nil, nil, false
+	}
+
+	return body, &attrs, true
+}
+
+func (ts *CachedCloudStorage) add(key string, attrs *Attributes, body []byte) {
+	size := int64(len(body))
+	if size > ts.opts.MaxObjectBytes {
+		return
+	}
+
+	file, err := ts.store.store(key, body)
+	if err != nil {
+		ts.opts.Logger.Warn("unable to cache object", Fields{"key": key, "error": err})
+		return
+	}
+
+	ts.mu.Lock()
+
+	var removed []string
+	if element, ok := ts.entries[key]; ok {
+		removed = append(removed, ts.removeElement(element))
+	}
+
+	ts.entries[key] = ts.lru.PushFront(&cacheEntry{
+		key:     key,
+		version: objectVersion(attrs),
+		file:    file,
+		attrs:   attrs,
+		size:    size,
+		expires: time.Now().Add(ts.opts.TTL),
+	})
+	ts.size += size
+
+	for ts.size > ts.opts.MaxBytes {
+		removed = append(removed, ts.removeElement(ts.lru.Back()))
+	}
+
+	ts.mu.Unlock()
+
+	ts.removeFiles(removed)
+}
+
+// removeElement must be called with the mutex held, it returns the file to remove once the mutex is released
+func (ts *CachedCloudStorage) removeElement(element *list.Element) string {
+	entry := element.Value.(*cacheEntry)
+
+	ts.lru.Remove(element)
+	delete(ts.entries, entry.key)
+	ts.size -= entry.size
+
+	return entry.file
+}
+
+func (ts *CachedCloudStorage) removeFiles(files []string) {
+	for _, file := range files {
+		ts.store.remove(file)
+	}
+}
+
+// Flush flushes the wrapped storage
+func (ts *CachedCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
+
+// cacheStore holds the cached bodies, it's called without the mutex of CachedCloudStorage held.
+// store returns a new file for every body, so a file is never rewritten while it's loaded.
+type cacheStore interface {
+	load(file string) ([]byte, bool)
+	store(key string, body []byte) (string, error)
+	remove(file string)
+}
+
+type memoryCacheStore struct {
+	mu     sync.Mutex
+	bodies map[string][]byte
+	next   int64
+}
+
+func (s *memoryCacheStore) load(file string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, ok := s.bodies[file]
+	if !ok {
+		return nil, false
+	}
+
+	// callers are free to modify the returned body
+	return append([]byte(nil), body...), true
+}
+
+func (s *memoryCacheStore) store(key string, body []byte) (string, error) {
+	body = append([]byte(nil), body...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.next++
+	file := fmt.Sprintf("%d-%s", s.next, key)
+	s.bodies[file] = body
+
+	return file, nil
+}
+
+func (s *memoryCacheStore) remove(file string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.bodies, file)
+}
+
+type diskCacheStore struct {
+	directory string
+}
+
+func (s *diskCacheStore) load(file string) ([]byte, bool) {
+	body, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+
+	return body, true
+}
+
+// store writes the body to a new file named after the hash of the key
+func (s *diskCacheStore) store(key string, body []byte) (string, error) {
+	hash := sha256.Sum256([]byte(key))
+
+	file, err := ioutil.TempFile(s.directory, hex.EncodeToString(hash[:])+"-")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := file.Write(body); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+
+		return "", err
+	}
+
+	if err := file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
+func (s *diskCacheStore) remove(file string) {
+	_ = os.Remove(file)
+}
@@ -0,0 +1,207 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type countingCloudStorage struct {
+	CloudStorage
+	body     []byte
+	modTime  time.Time
+	gets     int
+	attrsReq int
+}
+
+func (ts *countingCloudStorage) GetWithAttributes(ctx context.Context, key string, opts ...ReadOption) (io.ReadCloser, *Attributes, error) {
+	ts.gets++
+	return ioutil.NopCloser(bytes.NewReader(ts.body)), &Attributes{Size: int64(len(ts.body)), ModTime: ts.modTime}, nil
+}
+
+func (ts *countingCloudStorage) Attributes(ctx context.Context, key string, opts ...ReadOption) (*Attributes, error) {
+	ts.attrsReq++
+	return &Attributes{Size: int64(len(ts.body)), ModTime: ts.modTime}, nil
+}
+
+func (ts *countingCloudStorage) Write(ctx context.Context, key string, body []byte, contentType *string, opts ...WriteOption) error {
+	ts.body = body
+	ts.modTime = ts.modTime.Add(time.Second)
+
+	return nil
+}
+
+func TestCachedCloudStorage(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingCloudStorage{body: []byte("v1"), modTime: time.Now()}
+
+	storage, err := NewCachedCloudStorage(backend, CacheOption{TTL: time.Hour})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		body, err := storage.Get(ctx, "key")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), body)
+	}
+
+	require.Equal(t, 1, backend.gets)
+	require.Equal(t, 0, backend.attrsReq)
+
+	require.NoError(t, storage.Write(ctx, "key", []byte("v2"), nil))
+
+	body, err := storage.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), body)
+	require.Equal(t, 2, backend.gets)
+}
+
+func TestCachedCloudStorageRevalidatesExpiredObjects(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingCloudStorage{body: []byte("v1"), modTime: time.Now()}
+
+	directory, err := ioutil.TempDir("", "cache")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(directory)
+
+	storage, err := NewCachedCloudStorage(backend, CacheOption{TTL: time.Nanosecond, Directory: directory})
+	require.NoError(t, err)
+
+	_, err = storage.Get(ctx, "key")
+	require.NoError(t, err)
+
+	time.Sleep(time.Millisecond)
+
+	body, err := storage.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v1"), body)
+	require.Equal(t, 1, backend.gets)
+	require.Equal(t, 1, backend.attrsReq)
+}
+
+func TestCachedCloudStorageRevalidatesOverwrites(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+
+	storage, err := NewCachedCloudStorage(fake, CacheOption{TTL: time.Nanosecond})
+	require.NoError(t, err)
+
+	require.NoError(t, fake.Write(ctx, "key", []byte("v1"), nil))
+
+	_, err = storage.Get(ctx, "key")
+	require.NoError(t, err)
+
+	// the overwrite has the same size and likely the same second, but not the same generation
+	require.NoError(t, fake.Write(ctx, "key", []byte("v2"), nil))
+
+	time.Sleep(time.Millisecond)
+
+	body, err := storage.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), body)
+}
+
+type blockingCacheStore struct {
+	cacheStore
+	storing chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingCacheStore) store(key string, body []byte) (string, error) {
+	if key == "slow" {
+		close(s.storing)
+		<-s.release
+	}
+
+	return s.cacheStore.store(key, body)
+}
+
+func TestCachedCloudStorageLookupsDontWaitForStores(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	require.NoError(t, fake.Write(ctx, "fast", []byte("fast"), nil))
+	require.NoError(t, fake.Write(ctx, "slow", []byte("slow"), nil))
+
+	storage, err := NewCachedCloudStorage(fake, CacheOption{TTL: time.Hour})
+	require.NoError(t, err)
+
+	store := &blockingCacheStore{cacheStore: storage.store, storing: make(chan struct{}), release: make(chan struct{})}
+	storage.store = store
+
+	_, err = storage.Get(ctx, "fast")
+	require.NoError(t, err)
+
+	slowDone := make(chan error, 1)
+
+	go func() {
+		_, err := storage.Get(ctx, "slow")
+		slowDone <- err
+	}()
+
+	<-store.storing
+
+	fastDone := make(chan error, 1)
+
+	go func() {
+		_, err := storage.Get(ctx, "fast")
+		fastDone <- err
+	}()
+
+	select {
+	case err := <-fastDone:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("the lookup waited for the store of another object")
+	}
+
+	close(store.release)
+	require.NoError(t, <-slowDone)
+}
+
+func TestCachedCloudStorageRemovesInvalidatedFiles(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingCloudStorage{body: []byte("v1"), modTime: time.Now()}
+
+	directory, err := ioutil.TempDir("", "cache")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(directory)
+
+	storage, err := NewCachedCloudStorage(backend, CacheOption{TTL: time.Hour, Directory: directory})
+	require.NoError(t, err)
+
+	_, err = storage.Get(ctx, "key")
+	require.NoError(t, err)
+
+	files, err := ioutil.ReadDir(directory)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	storage.Invalidate("key")
+
+	files, err = ioutil.ReadDir(directory)
+	require.NoError(t, err)
+	require.Len(t, files, 0)
+}
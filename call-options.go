@@ -0,0 +1,340 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"go.opentelemetry.io/otel/attribute"
+	"gocloud.dev/blob"
+)
+
+// callOptions are the options of a single call, given as ReadOption, WriteOption or ListOption
+type callOptions struct {
+	timeout            time.Duration
+	traceAttributes    []attribute.KeyValue
+	metadata           map[string]string
+	storageClass       string
+	contentType        string
+	cacheControl       string
+	contentDisposition string
+	order              ListOrder
+	written            *writtenAttributes
+	idempotencyKey     string
+	resumeFrom         string
+	contentLength      int64
+	hasContentLength   bool
+}
+
+// ReadOption configures a single Get, GetReader, GetWithAttributes, GetRangeReader or Attributes call
+type ReadOption interface {
+	applyRead(o *callOptions)
+}
+
+// WriteOption configures a single Write, GetWriter or Upload call
+type WriteOption interface {
+	applyWrite(o *callOptions)
+}
+
+// ListOption configures a single List call
+type ListOption interface {
+	applyList(o *callOptions)
+}
+
+// CallOption is an option of every call, it's a ReadOption, a WriteOption and a ListOption
+type CallOption func(o *callOptions)
+
+func (f CallOption) applyRead(o *callOptions)  { f(o) }
+func (f CallOption) applyWrite(o *callOptions) { f(o) }
+func (f CallOption) applyList(o *callOptions)  { f(o) }
+
+// writeOption is an option of the writes only
+type writeOption func(o *callOptions)
+
+func (f writeOption) applyWrite(o *callOptions) { f(o) }
+
+// listOption is an option of List only
+type listOption func(o *callOptions)
+
+func (f listOption) applyList(o *callOptions) { f(o) }
+
+// WithTimeout cancels the call after the timeout. It covers the whole transfer of the readers and writers,
+// until they're closed, and the whole listing of List.
+// It's applied by the CloudStorage created with NewCloudStorage.
+func WithTimeout(timeout time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithTraceAttributes adds the attributes to the span of the call, with the TracerProvider option
+func WithTraceAttributes(attributes ...attribute.KeyValue) CallOption {
+	return func(o *callOptions) {
+		o.traceAttributes = append(o.traceAttributes, attributes...)
+	}
+}
+
+// WithMetadata stores the metadata with the written object, returned in Attributes.Metadata.
+// The metadata of several WithMetadata are merged, the last value of a key wins.
+func WithMetadata(metadata map[string]string) WriteOption {
+	return writeOption(func(o *callOptions) {
+		merged := make(map[string]string, len(o.metadata)+len(metadata))
+		for k, v := range o.metadata {
+			merged[k] = v
+		}
+
+		for k, v := range metadata {
+			merged[k] = v
+		}
+
+		o.metadata = merged
+	})
+}
+
+// WithContentType sets the content type of the written object, when it's not given to Write or Upload.
+// It's the only way to set the content type of GetWriter.
+func WithContentType(contentType string) WriteOption {
+	return writeOption(func(o *callOptions) {
+		o.contentType = contentType
+	})
+}
+
+// WithContentDisposition sets the Content-Disposition of the written object, e.g. AttachmentDisposition("report.csv")
+// so the browsers download it with this name
+func WithContentDisposition(contentDisposition string) WriteOption {
+	return writeOption(func(o *callOptions) {
+		o.contentDisposition = contentDisposition
+	})
+}
+
+// WithCacheControl sets the Cache-Control of the written object, e.g. "public, max-age=3600"
+func WithCacheControl(cacheControl string) WriteOption {
+	return writeOption(func(o *callOptions) {
+		o.cacheControl = cacheControl
+	})
+}
+
+// WithStorageClass stores the written object in the storage class, e.g. "STANDARD_IA" on AWS or "NEARLINE" on GCP
+func WithStorageClass(storageClass string) WriteOption {
+	return writeOption(func(o *callOptions) {
+		o.storageClass = storageClass
+	})
+}
+
+// WithWrittenAttributes fills attrs with the attributes of the object once the write succeeds, so its Generation,
+// ETag or CRC32C can be stored without an Attributes call. The writer of GetWriter fills them on Close.
+// The ModTime isn't returned by AWS and is left zero. With chunking, deduplication or transforms, they're the
+// attributes of the stored object.
+func WithWrittenAttributes(attrs *Attributes) WriteOption {
+	return writeOption(func(o *callOptions) {
+		o.written = &writtenAttributes{attrs: attrs}
+	})
+}
+
+// WithIdempotencyKey makes Write and Upload skip the write when a write of the key with the same token already
+// succeeded, e.g. the retry of a request, with the Idempotency option. See NewIdempotentCloudStorage.
+func WithIdempotencyKey(token string) WriteOption {
+	return writeOption(func(o *callOptions) {
+		o.idempotencyKey = token
+	})
+}
+
+// withContentLength gives the size of the content of GetWriter, for WriteFrom
+func withContentLength(size int64) WriteOption {
+	return writeOption(func(o *callOptions) {
+		o.contentLength = size
+		o.hasContentLength = true
+	})
+}
+
+// WithResumeFrom returns the objects of List after lastKey, e.g. the last key processed by a listing which failed,
+// so it isn't started over. It's applied to the lexicographic order of the keys, before WithListOrder.
+func WithResumeFrom(lastKey string) ListOption {
+	return listOption(func(o *callOptions) {
+		o.resumeFrom = lastKey
+	})
+}
+
+// WithListOrder returns the objects of List in the order, ListOrderAscending by default
+func WithListOrder(order ListOrder) ListOption {
+	return listOption(func(o *callOptions) {
+		o.order = order
+	})
+}
+
+func readOptions(opts []ReadOption) callOptions {
+	var options callOptions
+	for _, opt := range opts {
+		opt.applyRead(&options)
+	}
+
+	return options
+}
+
+func writeOptions(opts []WriteOption) callOptions {
+	var options callOptions
+	for _, opt := range opts {
+		opt.applyWrite(&options)
+	}
+
+	return options
+}
+
+func listOptions(opts []ListOption) callOptions {
+	var options callOptions
+	for _, opt := range opts {
+		opt.applyList(&options)
+	}
+
+	return options
+}
+
+// withTimeout returns ctx with the timeout of the options, if any
+func (o callOptions) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, o.timeout)
+}
+
+// cancelOnClose cancels the context of a reader or a writer when it's closed
+type cancelOnCloseReader struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelOnCloseReader) Close() error {
+	defer r.cancel()
+
+	return r.ReadCloser.Close()
+}
+
+type cancelOnCloseWriter struct {
+	io.WriteCloser
+	cancel context.CancelFunc
+}
+
+func (w *cancelOnCloseWriter) Close() error {
+	defer w.cancel()
+
+	return w.WriteCloser.Close()
+}
+
+// contentTypeOf returns contentType, else the content type of the options, else the one of the extension of the key.
+// It's nil when the content type must be sniffed.
+func (o callOptions) contentTypeOf(contentType *string, key string) *string {
+	if contentType != nil {
+		return contentType
+	}
+
+	if o.contentType != "" {
+		contentType := o.contentType
+		return &contentType
+	}
+
+	return extensionContentType(key)
+}
+
+// writerOptions returns the gocloud options of GetWriter, the provider sniffs the content type when it's not known
+func (o callOptions) writerOptions(key string) *blob.WriterOptions {
+	return o.blobWriterOptions(o.contentTypeOf(nil, key))
+}
+
+// writeAllOptions returns the gocloud options of a Write of body
+func (o callOptions) writeAllOptions(key string, body []byte, contentType *string) *blob.WriterOptions {
+	return o.blobWriterOptions(contentTypeOrDetect(o.contentTypeOf(contentType, key), key, body))
+}
+
+// blobWriterOptions returns the gocloud options of a write, the storage class is set on the request with BeforeWrite
+func (o callOptions) blobWriterOptions(contentType *string) *blob.WriterOptions {
+	options := &blob.WriterOptions{
+		Metadata:           o.metadata,
+		CacheControl:       o.cacheControl,
+		ContentDisposition: o.contentDisposition,
+	}
+	if contentType != nil {
+		options.ContentType = *contentType
+	}
+
+	if o.storageClass != "" || o.written != nil || o.hasContentLength {
+		storageClass, written := o.storageClass, o.written
+		contentLength, hasContentLength := o.contentLength, o.hasContentLength
+
+		options.BeforeWrite = func(as func(interface{}) bool) error {
+			var input *s3manager.UploadInput
+			if storageClass != "" && as(&input) {
+				input.StorageClass = aws.String(storageClass)
+			}
+
+			var writer *storage.Writer
+			if as(&writer) {
+				if storageClass != "" {
+					writer.StorageClass = storageClass
+				}
+
+				if written != nil {
+					written.gcsWriter = writer
+				}
+
+				// the objects of a single chunk are sent in one request, without buffering the chunk
+				if hasContentLength && contentLength <= int64(writer.ChunkSize) {
+					writer.ChunkSize = 0
+				}
+			}
+
+			return nil
+		}
+	}
+
+	return options
+}
+
+// applyUploadInput sets the metadata, the Cache-Control, the Content-Disposition and the storage class of an AWS upload
+func (o callOptions) applyUploadInput(input *s3manager.UploadInput) {
+	if len(o.metadata) > 0 {
+		input.Metadata = aws.StringMap(o.metadata)
+	}
+
+	if o.cacheControl != "" {
+		input.CacheControl = aws.String(o.cacheControl)
+	}
+
+	if o.contentDisposition != "" {
+		input.ContentDisposition = aws.String(o.contentDisposition)
+	}
+
+	if o.storageClass != "" {
+		input.StorageClass = aws.String(o.storageClass)
+	}
+}
+
+// objectAttrs returns the attributes of a GCP object written with the options
+func (o callOptions) objectAttrs(contentType string) storage.ObjectAttrs {
+	return storage.ObjectAttrs{
+		ContentType:        contentType,
+		CacheControl:       o.cacheControl,
+		ContentDisposition: o.contentDisposition,
+		Metadata:           o.metadata,
+		StorageClass:       o.storageClass,
+	}
+}
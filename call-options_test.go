@@ -0,0 +1,171 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestWithMetadata(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	metadata := WithMetadata(map[string]string{"Owner": "billing"})
+
+	require.NoError(t, fake.Write(ctx, "written", []byte("body"), nil, metadata))
+	require.NoError(t, fake.Upload(ctx, "uploaded", bytes.NewReader([]byte("body")), nil, metadata))
+
+	writer, err := fake.GetWriter(ctx, "streamed", metadata)
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("body"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	for _, key := range []string{"written", "uploaded", "streamed"} {
+		attrs, err := fake.Attributes(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"owner": "billing"}, attrs.Metadata, key)
+	}
+}
+
+func TestWithMetadataThroughWrappers(t *testing.T) {
+	ctx := context.Background()
+	metadata := WithMetadata(map[string]string{"owner": "billing"})
+
+	chunked := NewChunkedCloudStorage(NewFakeCloudStorage("bucket"), ChunkingOption{ChunkSize: 4})
+	require.NoError(t, chunked.Write(ctx, "key", []byte("chunked body"), nil, metadata))
+
+	attrs, err := chunked.Attributes(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "billing", attrs.Metadata["owner"])
+
+	// the blob is shared, each key keeps its own metadata
+	dedup := NewDedupCloudStorage(NewFakeCloudStorage("bucket"), DedupOption{})
+	require.NoError(t, dedup.Write(ctx, "first", []byte("body"), nil, metadata))
+	require.NoError(t, dedup.Write(ctx, "second", []byte("body"), nil))
+
+	_, attrs, err = dedup.GetWithAttributes(ctx, "first")
+	require.NoError(t, err)
+	require.Equal(t, "billing", attrs.Metadata["owner"])
+
+	_, attrs, err = dedup.GetWithAttributes(ctx, "second")
+	require.NoError(t, err)
+	require.Empty(t, attrs.Metadata)
+}
+
+func TestWithStorageClass(t *testing.T) {
+	options := writeOptions([]WriteOption{WithStorageClass("STANDARD_IA")})
+
+	input := &s3manager.UploadInput{}
+	options.applyUploadInput(input)
+	require.Equal(t, "STANDARD_IA", aws.StringValue(input.StorageClass))
+
+	// the gocloud writes set it on the request of the provider
+	input = &s3manager.UploadInput{}
+	err := options.writerOptions("report").BeforeWrite(func(i interface{}) bool {
+		p, ok := i.(**s3manager.UploadInput)
+		if ok {
+			*p = input
+		}
+
+		return ok
+	})
+	require.NoError(t, err)
+	require.Equal(t, "STANDARD_IA", aws.StringValue(input.StorageClass))
+
+	require.Equal(t, "NEARLINE", writeOptions([]WriteOption{WithStorageClass("NEARLINE")}).objectAttrs("").StorageClass)
+}
+
+// blockingStorage blocks the reads until their context is done
+type blockingStorage struct {
+	CloudStorage
+}
+
+func (ts *blockingStorage) Get(ctx context.Context, key string, opts ...ReadOption) ([]byte, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (ts *blockingStorage) GetReader(ctx context.Context, key string, opts ...ReadOption) (io.ReadCloser, error) {
+	return ioutil.NopCloser(&contextReader{ctx: ctx}), nil
+}
+
+// contextReader blocks until its context is done
+type contextReader struct {
+	ctx context.Context
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	<-r.ctx.Done()
+	return 0, r.ctx.Err()
+}
+
+func TestWithTimeout(t *testing.T) {
+	ctx := context.Background()
+	storage := newInterceptedCloudStorage(&blockingStorage{CloudStorage: NewFakeCloudStorage("bucket")}, "fake", "bucket")
+
+	_, err := storage.Get(ctx, "key", WithTimeout(10*time.Millisecond))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the timeout covers the reads of the stream, after GetReader returned
+	reader, err := storage.GetReader(ctx, "key", WithTimeout(10*time.Millisecond))
+	require.NoError(t, err)
+
+	_, err = reader.Read(make([]byte, 1))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NoError(t, reader.Close())
+}
+
+func TestWithTraceAttributes(t *testing.T) {
+	var attributes []attribute.KeyValue
+
+	storage := newInterceptedCloudStorage(NewFakeCloudStorage("bucket"), "fake", "bucket",
+		func(ctx context.Context, op *OperationInfo, next func(ctx context.Context) error) error {
+			attributes = op.TraceAttributes
+			return next(ctx)
+		})
+
+	err := storage.Write(context.Background(), "key", []byte("body"), nil,
+		WithTraceAttributes(attribute.String("tenant", "acme")), WithMetadata(map[string]string{"owner": "billing"}))
+	require.NoError(t, err)
+	require.Equal(t, []attribute.KeyValue{attribute.String("tenant", "acme")}, attributes)
+}
+
+func TestWithContentDisposition(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, fake.Write(ctx, "exports/1", []byte("id\n"), nil,
+		WithContentDisposition(AttachmentDisposition("export.csv"))))
+
+	attrs, err := fake.Attributes(ctx, "exports/1")
+	require.NoError(t, err)
+	require.Equal(t, `attachment; filename=export.csv`, attrs.ContentDisposition)
+
+	input := &s3manager.UploadInput{}
+	writeOptions([]WriteOption{WithContentDisposition("inline")}).applyUploadInput(input)
+	require.Equal(t, "inline", aws.StringValue(input.ContentDisposition))
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" // nolint:gosec
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudfront/sign"
+	"gocloud.dev/blob"
+)
+
+// CDNOption configures the URLs of CDNCloudStorage. At most one of the CloudFront and the Cloud CDN keys is set,
+// the URLs aren't signed without key.
+type CDNOption struct {
+	// BaseURL is the URL of the distribution serving the bucket, e.g. "https://d111111abcdef8.cloudfront.net/"
+	BaseURL string
+
+	// CloudFrontKeyPairID is the ID of the public key of the trusted key group of the distribution
+	CloudFrontKeyPairID string
+	// CloudFrontPrivateKey is the PEM RSA private key of the key pair
+	CloudFrontPrivateKey []byte
+
+	// CloudCDNKeyName is the name of the signing key of the backend bucket
+	CloudCDNKeyName string
+	// CloudCDNKey is the 16 bytes signing key, base64url decoded
+	CloudCDNKey []byte
+
+	// Clock returns the current time used to compute the expiry of the signed URLs, time.Now when it's nil
+	Clock func() time.Time
+}
+
+// CDNCloudStorage returns the URLs of the CDN instead of the bucket: GetPublicURL gives the URL of the object
+// through the distribution, and GetSignedURL signs the GET and HEAD URLs with the key of CloudFront or Cloud CDN.
+// The other methods and the GET URLs with a ContentDisposition are still signed by the bucket.
+type CDNCloudStorage struct {
+	CloudStorage
+	opts       CDNOption
+	baseURL    string
+	cloudFront *sign.URLSigner
+	cookies    *sign.CookieSigner
+}
+
+// NewCDNCloudStorage returns the storage giving the URLs of the CDN, it fails when a key is invalid
+func NewCDNCloudStorage(storage CloudStorage, opts CDNOption) (*CDNCloudStorage, error) {
+	if opts.BaseURL == "" {
+		return nil, errors.New("CDNOption.BaseURL is required")
+	}
+
+	if opts.CloudFrontKeyPairID != "" && opts.CloudCDNKeyName != "" {
+		return nil, errors.New("CDNOption can't have both a CloudFront and a Cloud CDN key")
+	}
+
+	opts.Clock = clockOrNow(opts.Clock)
+
+	ts := &CDNCloudStorage{
+		CloudStorage: storage,
+		opts:         opts,
+		baseURL:      strings.TrimSuffix(opts.BaseURL, "/") + "/",
+	}
+
+	if opts.CloudFrontKeyPairID != "" {
+		privateKey, err := parseRSAPrivateKey(opts.CloudFrontPrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CDNOption.CloudFrontPrivateKey: %w", err)
+		}
+
+		ts.cloudFront = sign.NewURLSigner(opts.CloudFrontKeyPairID, privateKey)
+		ts.cookies = sign.NewCookieSigner(opts.CloudFrontKeyPairID, privateKey)
+	}
+
+	if opts.CloudCDNKeyName != "" && len(opts.CloudCDNKey) != 16 {
+		return nil, fmt.Errorf("CDNOption.CloudCDNKey must have 16 bytes (%d)", len(opts.CloudCDNKey))
+	}
+
+	return ts, nil
+}
+
+// GetPublicURL returns the unsigned URL of the object through the CDN
+func (ts *CDNCloudStorage) GetPublicURL(key string) string {
+	return ts.baseURL + escapeKey(key)
+}
+
+func (ts *CDNCloudStorage) GetSignedURL(
+	ctx context.Context,
+	key string,
+	opts *SignedURLOption,
+) (string, error) {
+	method, err := signedURLMethod(opts)
+	if err != nil {
+		return "", err
+	}
+
+	if method != http.MethodGet && method != http.MethodHead || opts.ContentDisposition != "" {
+		return ts.CloudStorage.GetSignedURL(ctx, key, opts)
+	}
+
+	expiry := opts.Expiry
+	if expiry == 0 {
+		expiry = blob.DefaultSignedURLExpiry
+	}
+
+	if expiry < 0 {
+		return "", fmt.Errorf("SignedURLOption.Expiry must be >= 0 (%v)", opts.Expiry)
+	}
+
+	expires := ts.opts.Clock().Add(expiry)
+	objectURL := ts.GetPublicURL(key)
+
+	switch {
+	case ts.cloudFront != nil:
+		return ts.cloudFront.Sign(objectURL, expires)
+	case ts.opts.CloudCDNKeyName != "":
+		return cloudCDNSignedURL(objectURL, ts.opts.CloudCDNKeyName, ts.opts.CloudCDNKey, expires), nil
+	default:
+		return objectURL, nil
+	}
+}
+
+// GenerateSignedCookies returns the CloudFront cookies granting access to every object under the prefix until
+// the expiry, for the web clients fetching many objects. Their domain and path are the ones of BaseURL and
+// the prefix, they're HttpOnly and sent over HTTPS only when BaseURL is HTTPS.
+// It returns ErrNotSupported without CloudFront key.
+func (ts *CDNCloudStorage) GenerateSignedCookies(
+	ctx context.Context,
+	pathPrefix string,
+	expiry time.Duration,
+) ([]*http.Cookie, error) {
+	if ts.cookies == nil {
+		return nil, fmt.Errorf("%w: the signed cookies need a CloudFront key", ErrNotSupported)
+	}
+
+	if expiry <= 0 {
+		return nil, fmt.Errorf("the expiry of the signed cookies must be > 0 (%v)", expiry)
+	}
+
+	base, err := url.Parse(ts.baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// the cookies are sent with the requests of the objects of the prefix and of its siblings
+	cookiePath := base.Path + escapeKey(pathPrefix[:strings.LastIndex(pathPrefix, "/")+1])
+
+	return ts.cookies.Sign(ts.GetPublicURL(pathPrefix)+"*", ts.opts.Clock().Add(expiry), func(o *sign.CookieOptions) {
+		o.Path = cookiePath
+		o.Domain = base.Hostname()
+		o.Secure = base.Scheme == "https"
+	})
+}
+
+// cloudCDNSignedURL appends the expiry, the key name and the HMAC-SHA1 of the URL with them
+func cloudCDNSignedURL(objectURL string, keyName string, key []byte, expires time.Time) string {
+	signedURL := fmt.Sprintf("%s?Expires=%d&KeyName=%s", objectURL, expires.Unix(), keyName)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write([]byte(signedURL))
+
+	return signedURL + "&Signature=" + base64.URLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Flush flushes the wrapped storage
+func (ts *CDNCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
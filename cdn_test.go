@@ -0,0 +1,159 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCDNCloudStorageCloudFront(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	now := time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	storage, err := NewCDNCloudStorage(fake, CDNOption{
+		BaseURL:             "https://d111111abcdef8.cloudfront.net",
+		CloudFrontKeyPairID: "K2JCJMDEHXQW5F",
+		CloudFrontPrivateKey: pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+		}),
+		Clock: func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "https://d111111abcdef8.cloudfront.net/reports/2020%2006.pdf",
+		storage.GetPublicURL("reports/2020 06.pdf"))
+
+	signedURL, err := storage.GetSignedURL(ctx, "reports/a.pdf", &SignedURLOption{Expiry: time.Hour})
+	require.NoError(t, err)
+
+	parsed, err := url.Parse(signedURL)
+	require.NoError(t, err)
+	require.Equal(t, "d111111abcdef8.cloudfront.net", parsed.Host)
+	require.Equal(t, "/reports/a.pdf", parsed.Path)
+	require.Equal(t, "1583067600", parsed.Query().Get("Expires"))
+	require.Equal(t, "K2JCJMDEHXQW5F", parsed.Query().Get("Key-Pair-Id"))
+	require.NotEmpty(t, parsed.Query().Get("Signature"))
+
+	// the uploads are still signed by the bucket
+	signedURL, err = storage.GetSignedURL(ctx, "reports/a.pdf", &SignedURLOption{Method: http.MethodPut})
+	require.NoError(t, err)
+
+	expected, err := fake.GetSignedURL(ctx, "reports/a.pdf", &SignedURLOption{Method: http.MethodPut})
+	require.NoError(t, err)
+	require.Equal(t, expected, signedURL)
+}
+
+func TestCDNCloudStorageGenerateSignedCookies(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	storage, err := NewCDNCloudStorage(NewFakeCloudStorage("bucket"), CDNOption{
+		BaseURL:             "https://cdn.example.com/static/",
+		CloudFrontKeyPairID: "K2JCJMDEHXQW5F",
+		CloudFrontPrivateKey: pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+		}),
+		Clock: func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	cookies, err := storage.GenerateSignedCookies(ctx, "games/42/assets/", time.Hour)
+	require.NoError(t, err)
+	require.Len(t, cookies, 3)
+
+	values := make(map[string]string)
+
+	for _, cookie := range cookies {
+		require.Equal(t, "cdn.example.com", cookie.Domain)
+		require.Equal(t, "/static/games/42/assets/", cookie.Path)
+		require.True(t, cookie.Secure)
+		require.True(t, cookie.HttpOnly)
+
+		values[cookie.Name] = cookie.Value
+	}
+
+	require.Equal(t, "K2JCJMDEHXQW5F", values["CloudFront-Key-Pair-Id"])
+	require.NotEmpty(t, values["CloudFront-Signature"])
+
+	// the policy is base64 encoded with "-", "_" and "~" instead of "+", "=" and "/"
+	policy, err := base64.StdEncoding.DecodeString(strings.NewReplacer("-", "+", "_", "=", "~", "/").
+		Replace(values["CloudFront-Policy"]))
+	require.NoError(t, err)
+	require.Contains(t, string(policy), `"Resource":"https://cdn.example.com/static/games/42/assets/*"`)
+	require.Contains(t, string(policy), `"AWS:EpochTime":1583067600`)
+
+	withoutCloudFront, err := NewCDNCloudStorage(NewFakeCloudStorage("bucket"), CDNOption{BaseURL: "https://cdn.example.com/"})
+	require.NoError(t, err)
+
+	_, err = withoutCloudFront.GenerateSignedCookies(ctx, "games/", time.Hour)
+	require.True(t, errors.Is(err, ErrNotSupported))
+}
+
+func TestCDNCloudStorageCloudCDN(t *testing.T) {
+	now := time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	storage, err := NewCDNCloudStorage(NewFakeCloudStorage("bucket"), CDNOption{
+		BaseURL:         "https://cdn.example.com/",
+		CloudCDNKeyName: "my-key",
+		CloudCDNKey:     []byte("0123456789abcdef"),
+		Clock:           func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	signedURL, err := storage.GetSignedURL(context.Background(), "a.txt", &SignedURLOption{Expiry: time.Minute})
+	require.NoError(t, err)
+	require.Equal(t, cloudCDNSignedURL("https://cdn.example.com/a.txt", "my-key", []byte("0123456789abcdef"),
+		now.Add(time.Minute)), signedURL)
+	require.Regexp(t, `^https://cdn\.example\.com/a\.txt\?Expires=1583064060&KeyName=my-key&Signature=[\w-]+=*$`,
+		signedURL)
+
+	_, err = NewCDNCloudStorage(NewFakeCloudStorage("bucket"), CDNOption{
+		BaseURL:         "https://cdn.example.com/",
+		CloudCDNKeyName: "my-key",
+		CloudCDNKey:     []byte("short"),
+	})
+	require.Error(t, err)
+
+	_, err = NewCDNCloudStorage(NewFakeCloudStorage("bucket"), CDNOption{
+		BaseURL:              "https://cdn.example.com/",
+		CloudFrontKeyPairID:  "K2JCJMDEHXQW5F",
+		CloudFrontPrivateKey: []byte("invalid"),
+	})
+	require.Error(t, err)
+}
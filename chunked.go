@@ -0,0 +1,693 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultChunkPrefix is the prefix of the parts of the chunked objects when ChunkingOption.Prefix is not set
+	DefaultChunkPrefix = ".chunks/"
+
+	// DefaultChunkSize is the size of the parts when ChunkingOption.ChunkSize is not set
+	DefaultChunkSize = 1 << 30
+
+	defaultChunkConcurrency = 4
+
+	// chunkManifestContentType tells the manifests apart from the objects which aren't chunked
+	chunkManifestContentType = "application/vnd.commonblob.chunked+json"
+)
+
+// ChunkingOption configures the chunking of the large objects
+type ChunkingOption struct {
+	// ChunkSize is the size of the parts, the objects up to ChunkSize are written as is. Defaults to 1GB.
+	ChunkSize int64
+	// Concurrency is the number of parts written in parallel. Defaults to 4.
+	Concurrency int
+	// Prefix holds the parts under <key>/<upload ID>/<part number>. Defaults to DefaultChunkPrefix.
+	Prefix string
+	// Spool spools the parts being buffered to temporary files above its threshold, every part is held in memory
+	// when it's nil
+	Spool *SpoolOption
+}
+
+// chunkManifest is the content of the key of a chunked object
+type chunkManifest struct {
+	UploadID    string `json:"upload_id"`
+	Size        int64  `json:"size"`
+	ChunkSize   int64  `json:"chunk_size"`
+	Chunks      int    `json:"chunks"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// ChunkedCloudStorage splits the objects larger than the chunk size into part objects, written in parallel,
+// and writes a manifest of the parts at their key, so the objects can exceed the size limit of a single object
+// of the provider. The reads reassemble the parts in a single reader.
+//
+// List gives the manifests, and the objects written before the chunking was enabled are still read as is.
+// WriteIf and DeleteIf aren't chunked, and the chunked objects can't be signed.
+type ChunkedCloudStorage struct {
+	CloudStorage
+	opts ChunkingOption
+}
+
+// NewChunkedCloudStorage returns the storage chunking the large objects written to it
+func NewChunkedCloudStorage(storage CloudStorage, opts ChunkingOption) *ChunkedCloudStorage {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultChunkConcurrency
+	}
+
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultChunkPrefix
+	}
+
+	return &ChunkedCloudStorage{CloudStorage: storage, opts: opts}
+}
+
+func (ts *ChunkedCloudStorage) chunkKey(key string, uploadID string, chunk int) string {
+	return fmt.Sprintf("%s%s/%s/%06d", ts.opts.Prefix, key, uploadID, chunk)
+}
+
+// manifest returns the manifest at the key, or nil with the reader of the object when it isn't chunked
+func (ts *ChunkedCloudStorage) manifest(
+	ctx context.Context,
+	key string,
+) (*chunkManifest, io.ReadCloser, *Attributes, error) {
+	reader, attrs, err := ts.CloudStorage.GetWithAttributes(ctx, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if attrs.ContentType != chunkManifestContentType {
+		return nil, reader, attrs, nil
+	}
+	defer reader.Close()
+
+	var manifest chunkManifest
+	if err := json.NewDecoder(reader).Decode(&manifest); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return &manifest, nil, attrs, nil
+}
+
+// previousManifest returns the manifest at the key, if any
+func (ts *ChunkedCloudStorage) previousManifest(ctx context.Context, key string) (*chunkManifest, error) {
+	manifest, reader, _, err := ts.manifest(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if reader != nil {
+		reader.Close()
+	}
+
+	return manifest, nil
+}
+
+// deleteChunks deletes the parts of the manifest
+func (ts *ChunkedCloudStorage) deleteChunks(ctx context.Context, key string, manifest *chunkManifest) error {
+	for chunk := 0; chunk < manifest.Chunks; chunk++ {
+		err := ts.CloudStorage.Delete(ctx, ts.chunkKey(key, manifest.UploadID, chunk))
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// List skips the parts
+func (ts *ChunkedCloudStorage) List(
+	ctx context.Context,
+	prefix string,
+	opts ...ListOption,
+) *ListIterator {
+	iter := ts.CloudStorage.List(ctx, prefix, opts...)
+
+	return NewListIterator(ctx, func(ctx context.Context) (*ListObject, error) {
+		for {
+			object, err := iter.Next(ctx)
+			if err != nil || !strings.HasPrefix(object.Key, ts.opts.Prefix) {
+				return object, err
+			}
+		}
+	})
+}
+
+func (ts *ChunkedCloudStorage) Get(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) ([]byte, error) {
+	reader, err := ts.GetReader(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return ioutil.ReadAll(reader)
+}
+
+func (ts *ChunkedCloudStorage) GetReader(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	reader, _, err := ts.GetWithAttributes(ctx, key, opts...)
+
+	return reader, err
+}
+
+// GetWithAttributes returns the size and the content type of the chunked object, with the other attributes
+// of the manifest
+func (ts *ChunkedCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	manifest, reader, attrs, err := ts.manifest(ctx, key)
+	if err != nil || manifest == nil {
+		return reader, attrs, err
+	}
+
+	return ts.newChunkReader(ctx, key, manifest, 0, manifest.Size), chunkedAttributes(manifest, attrs), nil
+}
+
+func chunkedAttributes(manifest *chunkManifest, manifestAttrs *Attributes) *Attributes {
+	attrs := *manifestAttrs
+	attrs.ContentType = manifest.ContentType
+	attrs.Size = manifest.Size
+	attrs.MD5 = nil
+
+	return &attrs
+}
+
+func (ts *ChunkedCloudStorage) Attributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (*Attributes, error) {
+	attrs, err := ts.CloudStorage.Attributes(ctx, key, opts...)
+	if err != nil || attrs.ContentType != chunkManifestContentType {
+		return attrs, err
+	}
+
+	manifest, reader, attrs, err := ts.manifest(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest == nil {
+		// replaced meanwhile by an object which isn't chunked
+		reader.Close()
+		return attrs, nil
+	}
+
+	return chunkedAttributes(manifest, attrs), nil
+}
+
+func (ts *ChunkedCloudStorage) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset,
+	length int64,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	manifest, reader, _, err := ts.manifest(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest == nil {
+		reader.Close()
+		return ts.CloudStorage.GetRangeReader(ctx, key, offset, length, opts...)
+	}
+
+	if offset < 0 {
+		offset = max64(manifest.Size+offset, 0)
+	}
+
+	end := manifest.Size
+	if length >= 0 && offset+length < end {
+		end = offset + length
+	}
+
+	return ts.newChunkReader(ctx, key, manifest, offset, end), nil
+}
+
+// GetSignedURL returns ErrNotSupported for the chunked objects, which aren't a single object of the provider
+func (ts *ChunkedCloudStorage) GetSignedURL(
+	ctx context.Context,
+	key string,
+	opts *SignedURLOption,
+) (string, error) {
+	if opts != nil && opts.Method != "" && opts.Method != http.MethodGet && opts.Method != http.MethodHead {
+		return ts.CloudStorage.GetSignedURL(ctx, key, opts)
+	}
+
+	attrs, err := ts.CloudStorage.Attributes(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	if attrs.ContentType == chunkManifestContentType {
+		return "", fmt.Errorf("%w: '%s' is chunked", ErrNotSupported, key)
+	}
+
+	return ts.CloudStorage.GetSignedURL(ctx, key, opts)
+}
+
+// Query runs the SQL on the client side for the chunked objects
+func (ts *ChunkedCloudStorage) Query(
+	ctx context.Context,
+	key string,
+	sql string,
+	format QueryInputFormat,
+) (io.ReadCloser, error) {
+	attrs, err := ts.CloudStorage.Attributes(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if attrs.ContentType == chunkManifestContentType {
+		return queryObject(ctx, ts, key, sql, format)
+	}
+
+	return ts.CloudStorage.Query(ctx, key, sql, format)
+}
+
+// chunkReader reads the parts of a chunked object from offset to end, opening them one after the other
+type chunkReader struct {
+	ctx      context.Context
+	storage  *ChunkedCloudStorage
+	key      string
+	manifest *chunkManifest
+	offset   int64
+	end      int64
+	current  io.ReadCloser
+}
+
+func (ts *ChunkedCloudStorage) newChunkReader(
+	ctx context.Context,
+	key string,
+	manifest *chunkManifest,
+	offset int64,
+	end int64,
+) *chunkReader {
+	return &chunkReader{ctx: ctx, storage: ts, key: key, manifest: manifest, offset: offset, end: end}
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.offset >= r.end {
+			return 0, io.EOF
+		}
+
+		if r.current == nil {
+			chunk := r.offset / r.manifest.ChunkSize
+			within := r.offset % r.manifest.ChunkSize
+
+			length := r.manifest.ChunkSize - within
+			if r.end-r.offset < length {
+				length = r.end - r.offset
+			}
+
+			reader, err := r.storage.CloudStorage.GetRangeReader(r.ctx,
+				r.storage.chunkKey(r.key, r.manifest.UploadID, int(chunk)), within, length)
+			if err != nil {
+				return 0, err
+			}
+
+			r.current = reader
+		}
+
+		n, err := r.current.Read(p)
+		r.offset += int64(n)
+
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+
+			if n > 0 {
+				return n, nil
+			}
+
+			continue
+		}
+
+		return n, err
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.current != nil {
+		return r.current.Close()
+	}
+
+	return nil
+}
+
+func (ts *ChunkedCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	writer := ts.newChunkWriter(ctx, key, contentType, opts)
+
+	if _, err := writer.Write(body); err != nil {
+		writer.abort()
+		return err
+	}
+
+	return writer.Close()
+}
+
+func (ts *ChunkedCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	return ts.newChunkWriter(ctx, key, nil, opts), nil
+}
+
+func (ts *ChunkedCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	var contentType *string
+	if opts != nil && opts.ContentType != "" {
+		contentType = &opts.ContentType
+	}
+
+	writer := ts.newChunkWriter(ctx, key, contentType, writeOpts)
+
+	if _, err := io.Copy(writer, reader); err != nil {
+		writer.abort()
+		return err
+	}
+
+	return writer.Close()
+}
+
+// Delete removes the manifest, then the parts of a chunked object
+func (ts *ChunkedCloudStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	manifest, err := ts.previousManifest(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if err := ts.CloudStorage.Delete(ctx, key); err != nil {
+		return err
+	}
+
+	if manifest == nil {
+		return nil
+	}
+
+	return ts.deleteChunks(ctx, key, manifest)
+}
+
+// Copy reads and writes the object again, since the chunks are stored under the key of their manifest
+func (ts *ChunkedCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	return streamCopy(ctx, ts, srcKey, ts, dstKey, opts)
+}
+
+// chunkWriter buffers a part, and writes the full parts in the background. An object which fits in a single
+// part is written as is on Close. The options are given to the parts and the manifest.
+// The parts are spooled to temporary files with the Spool option, and uploaded from them.
+type chunkWriter struct {
+	ctx         context.Context
+	storage     *ChunkedCloudStorage
+	key         string
+	contentType *string
+	opts        []WriteOption
+	uploadID    string
+
+	part   *spool
+	chunks int
+	size   int64
+	// sniffed is the beginning of the object, to detect its content type
+	sniffed []byte
+
+	sem     chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	err     error
+	aborted bool
+}
+
+func (ts *ChunkedCloudStorage) newChunkWriter(
+	ctx context.Context,
+	key string,
+	contentType *string,
+	opts []WriteOption,
+) *chunkWriter {
+	if contentType == nil {
+		if options := writeOptions(opts); options.contentType != "" {
+			contentType = &options.contentType
+		}
+	}
+
+	return &chunkWriter{
+		ctx:         ctx,
+		storage:     ts,
+		key:         key,
+		contentType: contentType,
+		opts:        opts,
+		uploadID:    uuid.New().String(),
+		part:        newSpool(ts.opts.Spool),
+		sem:         make(chan struct{}, ts.opts.Concurrency),
+	}
+}
+
+func (w *chunkWriter) failure() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.err
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	if err := w.failure(); err != nil {
+		return 0, err
+	}
+
+	if n := 512 - len(w.sniffed); n > 0 {
+		if n > len(p) {
+			n = len(p)
+		}
+
+		w.sniffed = append(w.sniffed, p[:n]...)
+	}
+
+	written := 0
+
+	for len(p) > 0 {
+		// a full part is only written once more bytes follow, so a single part object is written as is
+		if w.part.Len() == w.storage.opts.ChunkSize {
+			if err := w.flush(); err != nil {
+				return written, err
+			}
+		}
+
+		n := int(w.storage.opts.ChunkSize - w.part.Len())
+		if n > len(p) {
+			n = len(p)
+		}
+
+		if _, err := w.part.Write(p[:n]); err != nil {
+			return written, err
+		}
+
+		p = p[n:]
+		written += n
+		w.size += int64(n)
+	}
+
+	return written, nil
+}
+
+// flush writes the buffered part in the background
+func (w *chunkWriter) flush() error {
+	select {
+	case w.sem <- struct{}{}:
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	}
+
+	if err := w.failure(); err != nil {
+		<-w.sem
+		return err
+	}
+
+	chunk, part := w.chunks, w.part
+	w.chunks++
+	w.part = newSpool(w.storage.opts.Spool)
+
+	w.wg.Add(1)
+
+	go func() {
+		defer func() {
+			part.Close()
+			<-w.sem
+			w.wg.Done()
+		}()
+
+		err := w.store(w.storage.chunkKey(w.key, w.uploadID, chunk), part, nil)
+		if err != nil {
+			w.mu.Lock()
+			if w.err == nil {
+				w.err = err
+			}
+			w.mu.Unlock()
+		}
+	}()
+
+	return nil
+}
+
+// store writes the part at the key, it's uploaded from its file when it was spooled
+func (w *chunkWriter) store(key string, part *spool, contentType *string) error {
+	if body, ok := part.Bytes(); ok {
+		return w.storage.CloudStorage.Write(w.ctx, key, body, contentType, w.opts...)
+	}
+
+	var opts UploadOption
+	if contentType != nil {
+		opts.ContentType = *contentType
+	}
+
+	writeOpts := append(append([]WriteOption(nil), w.opts...), withContentLength(part.Len()))
+
+	return w.storage.CloudStorage.Upload(w.ctx, key, part.Reader(), &opts, writeOpts...)
+}
+
+// abort deletes the written parts
+func (w *chunkWriter) abort() {
+	w.wg.Wait()
+	w.part.Close()
+
+	if w.aborted {
+		return
+	}
+
+	w.aborted = true
+
+	// the parts are deleted even when the context of the write is cancelled
+	_ = w.storage.deleteChunks(context.Background(), w.key, &chunkManifest{UploadID: w.uploadID, Chunks: w.chunks})
+}
+
+func (w *chunkWriter) Close() error {
+	previous, err := w.storage.previousManifest(w.ctx, w.key)
+	if err != nil {
+		w.abort()
+		return err
+	}
+
+	if w.chunks == 0 {
+		err := w.store(w.key, w.part, w.contentType)
+		w.part.Close()
+
+		if err != nil {
+			return err
+		}
+	} else if err := w.writeManifest(); err != nil {
+		w.abort()
+		return err
+	}
+
+	if previous != nil && previous.UploadID != w.uploadID {
+		return w.storage.deleteChunks(w.ctx, w.key, previous)
+	}
+
+	return nil
+}
+
+// writeManifest writes the last part, then the manifest once all the parts are written
+func (w *chunkWriter) writeManifest() error {
+	if w.part.Len() > 0 {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+
+	w.wg.Wait()
+
+	if err := w.failure(); err != nil {
+		return err
+	}
+
+	manifest := chunkManifest{
+		UploadID:  w.uploadID,
+		Size:      w.size,
+		ChunkSize: w.storage.opts.ChunkSize,
+		Chunks:    w.chunks,
+	}
+
+	if w.contentType != nil {
+		manifest.ContentType = *w.contentType
+	} else {
+		manifest.ContentType = detectContentType(w.key, w.sniffed)
+	}
+
+	body, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	contentType := chunkManifestContentType
+
+	return w.storage.CloudStorage.Write(w.ctx, w.key, body, &contentType, w.opts...)
+}
+
+// Flush flushes the wrapped storage
+func (ts *ChunkedCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
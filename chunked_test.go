@@ -0,0 +1,143 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkedCloudStorage(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	storage := NewChunkedCloudStorage(fake, ChunkingOption{ChunkSize: 4, Concurrency: 2})
+	contentType := "text/plain"
+
+	require.NoError(t, storage.Write(ctx, "small.txt", []byte("abcd"), nil))
+	require.NoError(t, storage.Write(ctx, "large.txt", []byte("abcdefghij"), &contentType))
+
+	parts, err := listByName(ctx, fake, DefaultChunkPrefix)
+	require.NoError(t, err)
+	require.Len(t, parts, 3)
+
+	body, err := storage.Get(ctx, "large.txt")
+	require.NoError(t, err)
+	require.Equal(t, "abcdefghij", string(body))
+
+	body, err = storage.Get(ctx, "small.txt")
+	require.NoError(t, err)
+	require.Equal(t, "abcd", string(body))
+
+	attrs, err := storage.Attributes(ctx, "large.txt")
+	require.NoError(t, err)
+	require.Equal(t, int64(10), attrs.Size)
+	require.Equal(t, "text/plain", attrs.ContentType)
+
+	reader, err := storage.GetRangeReader(ctx, "large.txt", 3, 6)
+	require.NoError(t, err)
+
+	body, err = ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, "defghi", string(body))
+
+	reader, err = storage.GetRangeReader(ctx, "large.txt", -4, 3)
+	require.NoError(t, err)
+
+	body, err = ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, "ghi", string(body))
+
+	_, err = storage.GetSignedURL(ctx, "large.txt", &SignedURLOption{})
+	require.True(t, errors.Is(err, ErrNotSupported))
+
+	// the parts aren't listed
+	var keys []string
+
+	iter := storage.List(ctx, "")
+
+	for {
+		object, err := iter.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+
+		keys = append(keys, object.Key)
+	}
+
+	require.ElementsMatch(t, []string{"small.txt", "large.txt"}, keys)
+
+	// the parts of the previous upload are deleted
+	require.NoError(t, storage.Upload(ctx, "large.txt", bytes.NewReader([]byte("0123456")), nil))
+
+	parts, err = listByName(ctx, fake, DefaultChunkPrefix)
+	require.NoError(t, err)
+	require.Len(t, parts, 2)
+
+	writer, err := storage.GetWriter(ctx, "large.txt")
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("xy"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	parts, err = listByName(ctx, fake, DefaultChunkPrefix)
+	require.NoError(t, err)
+	require.Empty(t, parts)
+
+	require.NoError(t, storage.Write(ctx, "large.txt", []byte("abcdefghij"), nil))
+	require.NoError(t, storage.Delete(ctx, "large.txt"))
+
+	parts, err = listByName(ctx, fake, DefaultChunkPrefix)
+	require.NoError(t, err)
+	require.Empty(t, parts)
+}
+
+// failingPartStorage fails the writes of the second part
+type failingPartStorage struct {
+	CloudStorage
+}
+
+func (ts *failingPartStorage) Write(ctx context.Context, key string, body []byte, contentType *string, opts ...WriteOption) error {
+	if strings.HasSuffix(key, "/000001") {
+		return ErrUnavailable
+	}
+
+	return ts.CloudStorage.Write(ctx, key, body, contentType)
+}
+
+func TestChunkedCloudStorageFailedPart(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	storage := NewChunkedCloudStorage(&failingPartStorage{CloudStorage: fake}, ChunkingOption{ChunkSize: 4})
+
+	err := storage.Write(ctx, "large.txt", []byte("abcdefghij"), nil)
+	require.True(t, errors.Is(err, ErrUnavailable))
+
+	// the written parts are deleted
+	objects, err := listByName(ctx, fake, "")
+	require.NoError(t, err)
+	require.Empty(t, objects)
+}
@@ -18,14 +18,19 @@ package commonblobgo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"time"
 
 	compMeta "cloud.google.com/go/compute/metadata"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ErrNotSupported is returned when an operation isn't available for the bucket provider
+var ErrNotSupported = errors.New("operation is not supported by the bucket provider")
+
 //nolint:funlen
 func NewCloudStorage(
 	ctx context.Context,
@@ -52,8 +57,219 @@ func NewCloudStorage(
 	})
 }
 
-//nolint:funlen
 func NewCloudStorageWithOption(ctx context.Context, isTesting bool, bucketProvider, bucketName string, cloudStorageOpts CloudStorageOption) (CloudStorage, error) {
+	if cloudStorageOpts.LazyInit {
+		lazy := newLazyCloudStorage(func(ctx context.Context) (CloudStorage, error) {
+			return newCloudStorage(ctx, isTesting, bucketProvider, bucketName, cloudStorageOpts)
+		}, cloudStorageOpts.Logger)
+		lazy.codec = cloudStorageOpts.Codec
+
+		return lazy, nil
+	}
+
+	return newCloudStorage(ctx, isTesting, bucketProvider, bucketName, cloudStorageOpts)
+}
+
+func newCloudStorage(ctx context.Context, isTesting bool, bucketProvider, bucketName string, cloudStorageOpts CloudStorageOption) (CloudStorage, error) {
+	storage, err := newProviderCloudStorage(ctx, isTesting, bucketProvider, bucketName, cloudStorageOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	if cloudStorageOpts.FaultInjection != nil {
+		storage = NewFaultInjectingCloudStorage(storage, *cloudStorageOpts.FaultInjection)
+	}
+
+	if cloudStorageOpts.Bandwidth != nil {
+		storage = newThrottledCloudStorage(storage, *cloudStorageOpts.Bandwidth)
+	}
+
+	if cloudStorageOpts.RetryPolicy != nil {
+		storage = newRetryCloudStorage(storage, *cloudStorageOpts.RetryPolicy)
+	}
+
+	// the idempotency markers aren't chunked, transformed, indexed or sent to the webhooks
+	markerStorage := storage
+
+	if cloudStorageOpts.CDN != nil {
+		cdnOpts := *cloudStorageOpts.CDN
+		if cdnOpts.Clock == nil {
+			cdnOpts.Clock = cloudStorageOpts.Clock
+		}
+
+		storage, err = NewCDNCloudStorage(storage, cdnOpts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cloudStorageOpts.Chunking != nil {
+		chunkingOpts := *cloudStorageOpts.Chunking
+		if chunkingOpts.Spool == nil {
+			chunkingOpts.Spool = cloudStorageOpts.Spool
+		}
+
+		storage = NewChunkedCloudStorage(storage, chunkingOpts)
+	}
+
+	if cloudStorageOpts.Dedup != nil {
+		dedupOpts := *cloudStorageOpts.Dedup
+		if dedupOpts.Spool == nil {
+			dedupOpts.Spool = cloudStorageOpts.Spool
+		}
+
+		storage = NewDedupCloudStorage(storage, dedupOpts)
+	}
+
+	if cloudStorageOpts.Webhook != nil {
+		webhookOpts := *cloudStorageOpts.Webhook
+		if webhookOpts.Logger == nil {
+			webhookOpts.Logger = cloudStorageOpts.Logger
+		}
+
+		storage = NewWebhookCloudStorage(storage, bucketName, webhookOpts)
+	}
+
+	if len(cloudStorageOpts.Transforms) > 0 {
+		storage = NewTransformingCloudStorage(storage, cloudStorageOpts.Transforms)
+	}
+
+	if cloudStorageOpts.SearchIndex != nil {
+		storage = NewIndexedCloudStorage(storage, *cloudStorageOpts.SearchIndex)
+	}
+
+	if cloudStorageOpts.Cache != nil {
+		cacheOpts := *cloudStorageOpts.Cache
+		if cacheOpts.Logger == nil {
+			cacheOpts.Logger = cloudStorageOpts.Logger
+		}
+
+		storage, err = NewCachedCloudStorage(storage, cacheOpts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cloudStorageOpts.AsyncWrite != nil {
+		asyncWriteOpts := *cloudStorageOpts.AsyncWrite
+		if asyncWriteOpts.Logger == nil {
+			asyncWriteOpts.Logger = cloudStorageOpts.Logger
+		}
+
+		storage = NewAsyncCloudStorage(storage, asyncWriteOpts)
+	}
+
+	if cloudStorageOpts.MaxObjectSize > 0 {
+		storage = newSizeLimitedCloudStorage(storage, cloudStorageOpts.MaxObjectSize)
+	}
+
+	if cloudStorageOpts.Quota != nil {
+		storage = NewQuotaCloudStorage(storage, *cloudStorageOpts.Quota)
+	}
+
+	if cloudStorageOpts.Trash != nil {
+		storage = NewTrashCloudStorage(storage, *cloudStorageOpts.Trash)
+	}
+
+	if cloudStorageOpts.WriteOnce != nil {
+		storage = NewWriteOnceCloudStorage(storage, *cloudStorageOpts.WriteOnce)
+	}
+
+	if cloudStorageOpts.SchemaValidation != nil {
+		storage, err = NewSchemaValidatingCloudStorage(storage, *cloudStorageOpts.SchemaValidation)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cloudStorageOpts.Idempotency != nil {
+		idempotencyOpts := *cloudStorageOpts.Idempotency
+		if idempotencyOpts.MarkerStorage == nil {
+			idempotencyOpts.MarkerStorage = markerStorage
+		}
+
+		if idempotencyOpts.Clock == nil {
+			idempotencyOpts.Clock = cloudStorageOpts.Clock
+		}
+
+		storage = NewIdempotentCloudStorage(storage, idempotencyOpts)
+	}
+
+	if cloudStorageOpts.Progress != nil {
+		storage = newProgressCloudStorage(storage, *cloudStorageOpts.Progress)
+	}
+
+	if cloudStorageOpts.TransferUsage != nil {
+		storage = newTransferUsageCloudStorage(storage, cloudStorageOpts.TransferUsage)
+	}
+
+	if cloudStorageOpts.WriteDefaults != nil {
+		storage = newWriteDefaultsCloudStorage(storage, *cloudStorageOpts.WriteDefaults)
+	}
+
+	if cloudStorageOpts.IgnoreMissingDeletes {
+		storage = newIgnoreMissingDeletesCloudStorage(storage)
+	}
+
+	if cloudStorageOpts.MaxSignedURLExpiry > 0 {
+		storage = newSignedURLPolicyCloudStorage(storage, cloudStorageOpts.MaxSignedURLExpiry)
+	}
+
+	var interceptors []Interceptor
+
+	if cloudStorageOpts.Anonymous {
+		interceptors = append(interceptors, newReadOnlyInterceptor())
+	}
+
+	if cloudStorageOpts.ValidateKeys {
+		interceptors = append(interceptors, newKeyValidationInterceptor())
+	}
+
+	if cloudStorageOpts.TracerProvider != nil {
+		interceptors = append(interceptors, newTracingInterceptor(cloudStorageOpts.TracerProvider))
+	}
+
+	if cloudStorageOpts.Metrics != nil {
+		interceptors = append(interceptors, newMetricsInterceptor(cloudStorageOpts.Metrics))
+	}
+
+	if cloudStorageOpts.Stats != nil {
+		interceptors = append(interceptors, newMetricsInterceptor(cloudStorageOpts.Stats))
+	}
+
+	if cloudStorageOpts.Costs != nil {
+		interceptors = append(interceptors, newCostInterceptor(cloudStorageOpts.Costs))
+	}
+
+	if cloudStorageOpts.SlowOperation != nil {
+		interceptors = append(interceptors, newSlowOperationInterceptor(
+			*cloudStorageOpts.SlowOperation,
+			loggerOrNoop(cloudStorageOpts.Logger),
+		))
+	}
+
+	if cloudStorageOpts.Audit != nil {
+		interceptors = append(interceptors, newAuditInterceptor(
+			*cloudStorageOpts.Audit,
+			storage,
+			loggerOrNoop(cloudStorageOpts.Logger),
+		))
+	}
+
+	interceptors = append(interceptors, cloudStorageOpts.Interceptors...)
+
+	intercepted := newInterceptedCloudStorage(storage, bucketProvider, bucketName, interceptors...)
+	intercepted.codec = cloudStorageOpts.Codec
+
+	if cloudStorageOpts.DefaultTimeouts != nil {
+		intercepted.timeouts = *cloudStorageOpts.DefaultTimeouts
+	}
+
+	return intercepted, nil
+}
+
+//nolint:funlen
+func newProviderCloudStorage(ctx context.Context, isTesting bool, bucketProvider, bucketName string, cloudStorageOpts CloudStorageOption) (CloudStorage, error) {
 	switch bucketProvider {
 	case "", "aws":
 		// 3-rd party library uses global variables
@@ -73,10 +289,10 @@ func NewCloudStorageWithOption(ctx context.Context, isTesting bool, bucketProvid
 		}
 
 		if isTesting {
-			return newAWSTestCloudStorage(ctx, cloudStorageOpts.AWSS3Endpoint, cloudStorageOpts.AWSS3Region, bucketProvider)
+			return newAWSTestCloudStorage(ctx, cloudStorageOpts.AWSS3Endpoint, cloudStorageOpts.AWSS3Region, bucketName, &cloudStorageOpts)
 		}
 
-		return newAWSCloudStorage(ctx, cloudStorageOpts.AWSS3Endpoint, cloudStorageOpts.AWSS3Region, bucketProvider, &cloudStorageOpts.AWSEnableS3Accelerate)
+		return newAWSCloudStorage(ctx, cloudStorageOpts.AWSS3Endpoint, cloudStorageOpts.AWSS3Region, bucketName, &cloudStorageOpts)
 
 	case "gcp":
 		if isTesting {
@@ -85,18 +301,21 @@ func NewCloudStorageWithOption(ctx context.Context, isTesting bool, bucketProvid
 				return nil, err
 			}
 
-			return newGCPTestCloudStorage(ctx, cloudStorageOpts.GCPCredentialsJSON, bucketName)
+			return newGCPTestCloudStorage(ctx, cloudStorageOpts.GCPCredentialsJSON, bucketName, &cloudStorageOpts)
 		}
 
 		// check that service has been started inside the GCP Kubernetes
 		isOnGCP := compMeta.OnGCE()
 
 		switch {
+		case cloudStorageOpts.Anonymous:
+			return newAnonymousGCPCloudStorage(ctx, bucketName, &cloudStorageOpts)
+
 		case cloudStorageOpts.GCPCredentialsJSON != "":
-			return newExplicitGCPCloudStorage(ctx, cloudStorageOpts.GCPCredentialsJSON, bucketName)
+			return newExplicitGCPCloudStorage(ctx, cloudStorageOpts.GCPCredentialsJSON, bucketName, &cloudStorageOpts)
 
 		case isOnGCP && cloudStorageOpts.GCPCredentialsJSON == "":
-			return newImplicitGCPCloudStorage(ctx, bucketName)
+			return newImplicitGCPCloudStorage(ctx, bucketName, &cloudStorageOpts)
 
 		default:
 			// don't support implicit external configuration
@@ -108,33 +327,82 @@ func NewCloudStorageWithOption(ctx context.Context, isTesting bool, bucketProvid
 	}
 }
 
+//go:generate mockery --name CloudStorage --output mocks --outpkg mocks --disable-version-string
 type CloudStorage interface {
-	List(ctx context.Context, prefix string) *ListIterator
-	Get(ctx context.Context, key string) ([]byte, error)
+	List(ctx context.Context, prefix string, opts ...ListOption) *ListIterator
+	Get(ctx context.Context, key string, opts ...ReadOption) ([]byte, error)
 	Delete(ctx context.Context, key string) error
 	CreateBucket(ctx context.Context, bucketPrefix string, expirationTimeDays int64) error
+	CreateBucketWithOptions(ctx context.Context, opts *CreateBucketOption) error
 	Close()
 	GetSignedURL(ctx context.Context, key string, opts *SignedURLOption) (string, error)
-	Write(ctx context.Context, key string, body []byte, contentType *string) error
-	Attributes(ctx context.Context, key string) (*Attributes, error)
-	GetReader(ctx context.Context, key string) (io.ReadCloser, error)
-	GetRangeReader(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
-	GetWriter(ctx context.Context, key string) (io.WriteCloser, error)
+	Write(ctx context.Context, key string, body []byte, contentType *string, opts ...WriteOption) error
+	Attributes(ctx context.Context, key string, opts ...ReadOption) (*Attributes, error)
+	GetReader(ctx context.Context, key string, opts ...ReadOption) (io.ReadCloser, error)
+	GetWithAttributes(ctx context.Context, key string, opts ...ReadOption) (io.ReadCloser, *Attributes, error)
+	GetRangeReader(ctx context.Context, key string, offset, length int64, opts ...ReadOption) (io.ReadCloser, error)
+	GetWriter(ctx context.Context, key string, opts ...WriteOption) (io.WriteCloser, error)
+	Upload(ctx context.Context, key string, reader io.Reader, opts *UploadOption, writeOpts ...WriteOption) error
+	SetObjectRetention(ctx context.Context, key string, retention *ObjectRetention) error
+	GetObjectRetention(ctx context.Context, key string) (*ObjectRetention, error)
+	SetLegalHold(ctx context.Context, key string, enabled bool) error
+	GetLegalHold(ctx context.Context, key string) (bool, error)
+	GetBucketPolicy(ctx context.Context) (*BucketPolicy, error)
+	SetBucketPolicy(ctx context.Context, policy *BucketPolicy) error
+	GetBucketEncryption(ctx context.Context) (*BucketEncryption, error)
+	SetBucketEncryption(ctx context.Context, encryption *BucketEncryption) error
+	GetBucketLogging(ctx context.Context) (*BucketLogging, error)
+	SetBucketLogging(ctx context.Context, logging *BucketLogging) error
+	GetBucketReplication(ctx context.Context) (*BucketReplication, error)
+	SetBucketReplication(ctx context.Context, replication *BucketReplication) error
+	GetPublicURL(key string) string
+	As(target interface{}) bool
+	ErrorAs(err error, target interface{}) bool
+	Ping(ctx context.Context) error
+	Subscribe(ctx context.Context, prefix string) (<-chan ObjectEvent, error)
+	ListIncompleteUploads(ctx context.Context, prefix string) ([]*IncompleteUpload, error)
+	AbortStaleUploads(ctx context.Context, olderThan time.Duration) ([]*IncompleteUpload, error)
+	Query(ctx context.Context, key string, sql string, format QueryInputFormat) (io.ReadCloser, error)
+	WriteIf(ctx context.Context, key string, body []byte, contentType *string, condition WriteCondition) (string, error)
+	DeleteIf(ctx context.Context, key string, generation string) error
+	BeginUpload(ctx context.Context, key string, opts *UploadOption) (*UploadSession, error)
+	ResumeUpload(ctx context.Context, state *UploadSessionState) (*UploadSession, error)
+	SetStorageClass(ctx context.Context, key string, storageClass string) error
+	Copy(ctx context.Context, srcKey string, dstKey string, opts *CopyOption) error
+	Undelete(ctx context.Context, key string) error
+	GetScopedCredentials(ctx context.Context, opts *ScopedCredentialsOption) (*ScopedCredentials, error)
+	CreateFolder(ctx context.Context, folder string) error
+	DeleteFolder(ctx context.Context, folder string) error
+	RenameFolder(ctx context.Context, folder string, newFolder string) error
 }
 
-func newListIterator(f func() (*ListObject, error)) *ListIterator {
+// NewListIterator returns an iterator calling f with the context given to Next, ctx is the context given to List.
+// It lets the CloudStorage implementations outside of the package, e.g. a gateway client, return a ListIterator.
+func NewListIterator(ctx context.Context, f func(ctx context.Context) (*ListObject, error)) *ListIterator {
 	return &ListIterator{
-		f: f,
+		ctx: ctx,
+		f:   f,
 	}
 }
 
 // ListIterator iterates over List results.
 type ListIterator struct {
-	f func() (*ListObject, error)
+	ctx context.Context
+	f   func(ctx context.Context) (*ListObject, error)
 }
 
+// Next returns the next object, or io.EOF once all the objects are returned.
+// It stops with the error of the context given to List or to Next once it's cancelled.
 func (i *ListIterator) Next(ctx context.Context) (*ListObject, error) {
-	return i.f()
+	if err := i.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return i.f(ctx)
 }
 
 // ListObject represents a single blob returned from List.
@@ -147,6 +415,11 @@ type ListObject struct {
 	Size int64
 	// MD5 is an MD5 hash of the blob contents or nil if not available.
 	MD5 []byte
+	// ETag is the entity tag of the blob without quotes, empty if not available.
+	ETag string
+	// StorageClass is the storage class of the blob as named by the provider, e.g. STANDARD_IA or NEARLINE.
+	// It's empty if not available.
+	StorageClass string
 }
 
 // Attributes contains attributes about a blob.
@@ -181,6 +454,16 @@ type Attributes struct {
 	Size int64
 	// MD5 is an MD5 hash of the blob contents or nil if not available.
 	MD5 []byte
+	// Generation identifies the content of the blob for WriteIf and DeleteIf.
+	// It's the ETag on AWS and the generation number on GCP.
+	Generation string
+	// ETag is the entity tag of the blob without quotes, empty if not available.
+	ETag string
+	// CRC32C is the big-endian CRC32C checksum of the blob contents or nil if not available.
+	// It's only computed by GCP.
+	CRC32C []byte
+	// Encryption is the server-side encryption of the blob, its Mode is EncryptionUnknown if not available.
+	Encryption ObjectEncryption
 }
 
 type SignedURLOption struct {
@@ -188,6 +471,15 @@ type SignedURLOption struct {
 	Expiry                   time.Duration
 	ContentType              string
 	EnforceAbsentContentType bool
+	// ContentDisposition overrides the Content-Disposition of the response of a GET URL,
+	// e.g. AttachmentDisposition("report.csv")
+	ContentDisposition string
+	// ContentMD5 is the MD5 digest the body of a PUT URL must have. The uploader sends it base64 encoded
+	// in the Content-MD5 header, and the provider rejects the bodies not matching it.
+	ContentMD5 []byte
+	// ContentSHA256 is the SHA-256 digest the body of a PUT URL must have, S3 only. The uploader sends it
+	// hex encoded in the x-amz-content-sha256 header, and S3 rejects the bodies not matching it.
+	ContentSHA256 []byte
 }
 
 type CloudStorageOption struct {
@@ -196,7 +488,110 @@ type CloudStorageOption struct {
 	AWSS3AccessKeyID      string
 	AWSS3SecretAccessKey  string
 	AWSEnableS3Accelerate bool
+	// AWSUseFIPSEndpoint sends the S3 and SQS requests to the FIPS 140-2 endpoints of the region,
+	// it's ignored with AWSS3Endpoint and can't be used with AWSEnableS3Accelerate
+	AWSUseFIPSEndpoint bool
+	// AWSRoleChain assumes a chain of roles, e.g. a bastion role then the role of the data account
+	AWSRoleChain *AWSRoleChainOption
 
 	GCPCredentialsJSON     string
 	GCPStorageEmulatorHost string
+	// GCPUniformBucketLevelAccess enables the uniform bucket-level access on the buckets created by CreateBucket,
+	// the access being then granted with the IAM bindings of SetBucketPolicy only
+	GCPUniformBucketLevelAccess bool
+	// GCPUserProject is the project billed for the GCS requests, required by the requester pays buckets
+	GCPUserProject string
+
+	// Anonymous reads a public bucket without credentials: the S3 requests aren't signed and the GCS requests
+	// are anonymous. The client is read-only, the writes, the deletes, the signed URLs and the other operations
+	// needing credentials fail with ErrPermissionDenied.
+	Anonymous bool
+
+	// RetryPolicy enables retries of the idempotent operations with exponential backoff and jitter
+	RetryPolicy *RetryPolicy
+	// Cache enables the read-through cache of Get, GetReader and GetWithAttributes
+	Cache *CacheOption
+	// AsyncWrite makes Write enqueue the objects to be uploaded by background workers
+	AsyncWrite *AsyncWriteOption
+	// HTTPTransport tunes the connection pool of the provider clients
+	HTTPTransport *HTTPTransportOption
+	// LazyInit defers the creation of the provider client until the first use, see LazyCloudStorage
+	LazyInit bool
+	// Bandwidth caps the upload and download bandwidth of the client
+	Bandwidth *BandwidthOption
+	// MaxObjectSize rejects the Write, GetWriter and Upload calls storing more bytes. Zero means unlimited.
+	MaxObjectSize int64
+	// ValidateKeys rejects the keys not passing ValidateKey before making any request
+	ValidateKeys bool
+	// TracerProvider enables the OpenTelemetry tracing of the operations, e.g. otel.GetTracerProvider()
+	TracerProvider trace.TracerProvider
+	// Metrics receives the count, latency, size and error of every operation
+	Metrics Metrics
+	// Stats collects the per-operation statistics served by its Stats method
+	Stats *StatsCollector
+	// Costs estimates the cost of every operation, aggregated by the labels of ContextWithCostLabels
+	Costs *CostAccountant
+	// TransferUsage counts the bytes uploaded and downloaded by key prefix
+	TransferUsage *TransferUsageTracker
+	// Logger receives the internal logs of the package. They are discarded when it's nil.
+	Logger Logger
+	// SlowOperation logs a warning with the Logger for the operations exceeding a threshold
+	SlowOperation *SlowOperationOption
+	// WireLog dumps the sanitized HTTP requests and responses with the Logger while it's enabled
+	WireLog *WireLog
+	// RequestAttribution adds a User-Agent suffix and headers to the provider requests, see ContextWithRequestHeaders
+	RequestAttribution *RequestAttributionOption
+	// Audit records every mutation with the actor taken from the context
+	Audit *AuditOption
+	// Interceptors wrap every operation, the first one being the outermost. They run inside the tracing and metrics.
+	Interceptors []Interceptor
+	// Clock returns the current time used to compute the expiry of the signed URLs, time.Now when it's nil
+	Clock func() time.Time
+	// MaxSignedURLExpiry rejects the GetSignedURL calls with a longer expiry with ErrSignedURLExpiryTooLong,
+	// including the CDN URLs. Zero means the limit of the provider, e.g. 7 days for the AWS Signature V4.
+	MaxSignedURLExpiry time.Duration
+	// FaultInjection injects latency, errors and partial reads into the provider calls, for chaos testing
+	FaultInjection *FaultInjectionOption
+	// NotificationQueue is the SQS queue, or the Pub/Sub topic and subscription, created by Subscribe.
+	// The replicas of a service share the events of a queue, so every service needs its own.
+	// Defaults to "<bucket>-notifications".
+	NotificationQueue string
+	// Webhook POSTs a signed JSON event to a URL after every successful mutation
+	Webhook *WebhookOption
+	// Transforms encodes the objects written under the prefixes of the rules, e.g. compressed and encrypted,
+	// and decodes them when they are read
+	Transforms []TransformRule
+	// Quota rejects the writes exceeding the quota of their prefix with ErrQuotaExceeded
+	Quota *QuotaOption
+	// Trash moves the deleted objects to a trash prefix instead of deleting them, see NewTrashCloudStorage
+	Trash *TrashOption
+	// WriteOnce rejects the overwrites and the deletes of the keys of its prefixes with ErrWriteOnce
+	WriteOnce *WriteOnceOption
+	// CDN gives the public and the signed GET URLs of the objects through CloudFront or Cloud CDN
+	CDN *CDNOption
+	// SchemaValidation rejects the JSON bodies violating the JSON Schema of their rule with ErrSchemaViolation
+	SchemaValidation *SchemaValidationOption
+	// Idempotency skips the writes given WithIdempotencyKey which already succeeded, see NewIdempotentCloudStorage
+	Idempotency *IdempotencyOption
+	// Dedup stores the identical objects once, under their content hash, with a pointer at their key
+	Dedup *DedupOption
+	// Chunking splits the objects larger than the chunk size into parts, reassembled on read
+	Chunking *ChunkingOption
+	// Spool spools the writes buffered by Chunking and Dedup to temporary files above its threshold, unless
+	// their own Spool option is set
+	Spool *SpoolOption
+	// SearchIndex records the attributes of the written objects in an IndexStore, see Search
+	SearchIndex *IndexOption
+	// Progress reports the bytes transferred by Get, GetReader, GetWithAttributes, GetRangeReader, Write,
+	// GetWriter and Upload, see also ContextWithProgress
+	Progress *ProgressOption
+	// WriteDefaults sets the Cache-Control, the content type and the metadata of the writes which don't set them
+	WriteDefaults *WriteDefaultsOption
+	// IgnoreMissingDeletes makes Delete succeed when the key doesn't exist. Every provider fails with ErrNotFound
+	// otherwise, see also DeleteIfExists.
+	IgnoreMissingDeletes bool
+	// Codec encodes the values of GetValue, PutValue and the Stores of the storage. Defaults to JSONCodec.
+	Codec Codec
+	// DefaultTimeouts are the timeouts of the calls made with a context without deadline, by class of operation
+	DefaultTimeouts *DefaultTimeouts
 }
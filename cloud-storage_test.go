@@ -17,16 +17,18 @@
 package commonblobgo
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -125,10 +127,6 @@ type Suite struct {
 }
 
 func (s *Suite) SetupSuite() {
-	logrus.SetOutput(os.Stdout)
-	logrus.SetLevel(logrus.DebugLevel)
-	logrus.SetReportCaller(true)
-
 	s.ctx = context.Background()
 	s.bucketPrefix = fmt.Sprintf("test_%s", uuid.New().String())
 
@@ -329,4 +327,182 @@ func (s *Suite) TestGetSignedURL() {
 	url, err := s.storage.GetSignedURL(s.ctx, fileName, options)
 	s.Require().NoError(err)
 	s.Require().NotEmpty(url)
+
+	for _, method := range []string{"HEAD", "DELETE"} {
+		url, err = s.storage.GetSignedURL(s.ctx, fileName, &SignedURLOption{Expiry: time.Hour, Method: method})
+		s.Require().NoError(err)
+		s.Require().NotEmpty(url)
+	}
+}
+
+func (s *Suite) TestGetPublicURL() {
+	fileName := fmt.Sprintf("%s/%s file.json", s.bucketPrefix, uuid.New().String())
+
+	url := s.storage.GetPublicURL(fileName)
+	s.Require().True(strings.HasPrefix(url, "http"))
+	s.Require().True(strings.HasSuffix(url, strings.Replace(fileName, " ", "%20", 1)))
+}
+
+func (s *Suite) TestGetWithAttributes() {
+	fileName := s.generateFileName()
+	body := []byte(`{"key": "value"}`)
+	contentType := "application/json"
+
+	err := s.storage.Write(s.ctx, fileName, body, &contentType)
+	s.Require().NoError(err)
+
+	reader, attrs, err := s.storage.GetWithAttributes(s.ctx, fileName)
+	s.Require().NoError(err)
+	s.Require().Equal(int64(len(body)), attrs.Size)
+	s.Require().Equal(contentType, attrs.ContentType)
+
+	storedBody, err := ioutil.ReadAll(reader)
+	s.Require().NoError(err)
+
+	err = reader.Close()
+	s.Require().NoError(err)
+
+	s.Require().JSONEq(string(body), string(storedBody))
+}
+
+func (s *Suite) TestUpload() {
+	fileName := s.generateFileName()
+	body := bytes.Repeat([]byte("0123456789"), 1024*1024)
+
+	err := s.storage.Upload(s.ctx, fileName, bytes.NewReader(body), &UploadOption{
+		PartSize:    5 * 1024 * 1024,
+		Concurrency: 2,
+	})
+	s.Require().NoError(err)
+
+	storedBody, err := s.storage.Get(s.ctx, fileName)
+	s.Require().NoError(err)
+	s.Require().Equal(body, storedBody)
+}
+
+func (s *Suite) TestWriteAndListStream() {
+	fileName := s.generateFileName()
+	body := []byte(`{"key": "value"}`)
+
+	err := s.storage.Write(s.ctx, fileName, body, nil)
+	s.Require().NoError(err)
+
+	var fileFound bool
+
+	items, errs := ListStream(s.ctx, s.storage, s.bucketPrefix)
+
+	for item := range items {
+		if item.Key == fileName {
+			fileFound = true
+		}
+	}
+
+	s.Require().NoError(<-errs)
+	s.Require().True(fileFound)
+}
+
+func (s *Suite) TestWriteAndGetInto() {
+	fileName := s.generateFileName()
+	body := []byte(`{"key": "value"}`)
+
+	err := s.storage.Write(s.ctx, fileName, body, nil)
+	s.Require().NoError(err)
+
+	buf := make([]byte, 64)
+
+	n, err := GetInto(s.ctx, s.storage, fileName, buf)
+	s.Require().NoError(err)
+	s.Require().JSONEq(string(body), string(buf[:n]))
+
+	_, err = GetInto(s.ctx, s.storage, fileName, buf[:4])
+	s.Require().Equal(ErrBufferTooSmall, err)
+}
+
+func (s *Suite) TestNotFound() {
+	fileName := s.generateFileName()
+
+	_, err := s.storage.Get(s.ctx, fileName)
+	s.Require().True(errors.Is(err, ErrNotFound))
+
+	_, err = s.storage.Attributes(s.ctx, fileName)
+	s.Require().True(errors.Is(err, ErrNotFound))
+
+	err = s.storage.Delete(s.ctx, fileName)
+	s.Require().True(errors.Is(err, ErrNotFound))
+}
+
+func (s *Suite) TestPing() {
+	s.Require().NoError(s.storage.Ping(s.ctx))
+}
+
+func (s *Suite) TestIncompleteUploads() {
+	_, err := s.storage.ListIncompleteUploads(s.ctx, s.bucketPrefix)
+	s.Require().NoError(err)
+
+	_, err = s.storage.AbortStaleUploads(s.ctx, 7*24*time.Hour)
+	s.Require().NoError(err)
+}
+
+func (s *Suite) TestQuery() {
+	fileName := fmt.Sprintf("%s/%s.csv", s.bucketPrefix, uuid.New().String())
+
+	err := s.storage.Write(s.ctx, fileName, []byte("name,country\nalice,FR\nbob,US\n"), nil)
+	s.Require().NoError(err)
+
+	reader, err := s.storage.Query(s.ctx, fileName, "SELECT s.name FROM S3Object s WHERE s.country = 'US'", QueryCSV)
+	s.Require().NoError(err)
+
+	defer reader.Close()
+
+	result, err := ioutil.ReadAll(reader)
+	s.Require().NoError(err)
+	s.Require().Equal(`{"name":"bob"}`+"\n", string(result))
+}
+
+func (s *Suite) TestWriteIf() {
+	fileName := fmt.Sprintf("%s/%s.json", s.bucketPrefix, uuid.New().String())
+
+	generation, err := s.storage.WriteIf(s.ctx, fileName, []byte("1"), nil, WriteCondition{DoesNotExist: true})
+	s.Require().NoError(err)
+
+	_, err = s.storage.WriteIf(s.ctx, fileName, []byte("2"), nil, WriteCondition{DoesNotExist: true})
+	s.Require().True(errors.Is(err, ErrPreconditionFailed))
+
+	attrs, err := s.storage.Attributes(s.ctx, fileName)
+	s.Require().NoError(err)
+	s.Require().Equal(generation, attrs.Generation)
+
+	updated, err := s.storage.WriteIf(s.ctx, fileName, []byte("2"), nil, WriteCondition{Generation: generation})
+	s.Require().NoError(err)
+
+	_, err = s.storage.WriteIf(s.ctx, fileName, []byte("3"), nil, WriteCondition{Generation: generation})
+	s.Require().True(errors.Is(err, ErrPreconditionFailed))
+
+	err = s.storage.DeleteIf(s.ctx, fileName, generation)
+	s.Require().True(errors.Is(err, ErrPreconditionFailed))
+
+	s.Require().NoError(s.storage.DeleteIf(s.ctx, fileName, updated))
+}
+
+func (s *Suite) TestUploadSession() {
+	fileName := fmt.Sprintf("%s/%s.bin", s.bucketPrefix, uuid.New().String())
+	source := bytes.Repeat([]byte("0123456789"), 600*1024)
+
+	session, err := s.storage.BeginUpload(s.ctx, fileName, &UploadOption{PartSize: 5 * 1024 * 1024})
+	s.Require().NoError(err)
+
+	s.Require().NoError(session.UploadPart(s.ctx, 1, source[:5*1024*1024]))
+
+	state := session.State()
+
+	resumed, err := s.storage.ResumeUpload(s.ctx, &state)
+	s.Require().NoError(err)
+	s.Require().Equal(int64(5*1024*1024), resumed.Offset())
+
+	s.Require().NoError(resumed.Upload(s.ctx, bytes.NewReader(source[resumed.Offset():]), nil))
+	s.Require().NoError(resumed.Complete(s.ctx))
+
+	body, err := s.storage.Get(s.ctx, fileName)
+	s.Require().NoError(err)
+	s.Require().Equal(source, body)
 }
@@ -0,0 +1,348 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	commonblobgo "github.com/AccelByte/common-blob-go"
+)
+
+const blobScheme = "blob://"
+
+type command func(ctx context.Context, storage commonblobgo.CloudStorage, args []string, stdout io.Writer) error
+
+var commands = map[string]command{
+	"ls":       ls,
+	"cp":       cp,
+	"rm":       rm,
+	"cat":      cat,
+	"stat":     stat,
+	"du":       du,
+	"sync":     sync,
+	"sign-url": signURL,
+}
+
+// parseRemote returns the key of a blob:// path
+func parseRemote(path string) (string, bool) {
+	if !strings.HasPrefix(path, blobScheme) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(path, blobScheme), true
+}
+
+func ls(ctx context.Context, storage commonblobgo.CloudStorage, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("ls", flag.ContinueOnError)
+	long := flags.Bool("l", false, "print the size and modification time")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	prefix := flags.Arg(0)
+	if key, ok := parseRemote(prefix); ok {
+		prefix = key
+	}
+
+	writer := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	iter := storage.List(ctx, prefix)
+
+	for {
+		object, err := iter.Next(ctx)
+		if err == io.EOF {
+			return writer.Flush()
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if *long {
+			fmt.Fprintf(writer, "%d\t%s\t%s\n", object.Size, object.ModTime.Format(time.RFC3339), object.Key)
+		} else {
+			fmt.Fprintln(writer, object.Key)
+		}
+	}
+}
+
+func cp(ctx context.Context, storage commonblobgo.CloudStorage, args []string, stdout io.Writer) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: cp <src> <dst>")
+	}
+
+	srcKey, srcRemote := parseRemote(args[0])
+	dstKey, dstRemote := parseRemote(args[1])
+
+	if !srcRemote && !dstRemote {
+		return fmt.Errorf("cp needs at least one blob:// path")
+	}
+
+	var (
+		reader      io.ReadCloser
+		contentType string
+		err         error
+	)
+
+	switch {
+	case srcRemote:
+		var attrs *commonblobgo.Attributes
+
+		reader, attrs, err = storage.GetWithAttributes(ctx, srcKey)
+		if err != nil {
+			return err
+		}
+
+		contentType = attrs.ContentType
+	case args[0] == "-":
+		reader = os.Stdin
+	default:
+		reader, err = os.Open(args[0])
+		if err != nil {
+			return err
+		}
+
+		contentType = mime.TypeByExtension(filepath.Ext(args[0]))
+	}
+	defer reader.Close()
+
+	if dstRemote {
+		// copying to a prefix keeps the name of the source
+		if dstKey == "" || strings.HasSuffix(dstKey, "/") {
+			dstKey += filepath.Base(filepath.FromSlash(args[0]))
+		}
+
+		return storage.Upload(ctx, dstKey, reader, &commonblobgo.UploadOption{ContentType: contentType})
+	}
+
+	if args[1] == "-" {
+		_, err = io.Copy(stdout, reader)
+		return err
+	}
+
+	return writeFile(args[1], reader)
+}
+
+func rm(ctx context.Context, storage commonblobgo.CloudStorage, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("rm", flag.ContinueOnError)
+	recursive := flags.Bool("r", false, "delete every object under the prefixes")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return fmt.Errorf("usage: rm [-r] <key>...")
+	}
+
+	for _, arg := range flags.Args() {
+		key := arg
+		if remote, ok := parseRemote(arg); ok {
+			key = remote
+		}
+
+		if !*recursive {
+			if err := storage.Delete(ctx, key); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		iter := storage.List(ctx, key)
+
+		for {
+			object, err := iter.Next(ctx)
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if err := storage.Delete(ctx, object.Key); err != nil {
+				return err
+			}
+
+			fmt.Fprintln(stdout, "deleted", object.Key)
+		}
+	}
+
+	return nil
+}
+
+func cat(ctx context.Context, storage commonblobgo.CloudStorage, args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: cat <key>")
+	}
+
+	key := args[0]
+	if remote, ok := parseRemote(key); ok {
+		key = remote
+	}
+
+	reader, err := storage.GetReader(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	_, err = io.Copy(stdout, reader)
+
+	return err
+}
+
+func du(ctx context.Context, storage commonblobgo.CloudStorage, args []string, stdout io.Writer) error {
+	prefix := ""
+	if len(args) > 0 {
+		prefix = args[0]
+	}
+
+	if key, ok := parseRemote(prefix); ok {
+		prefix = key
+	}
+
+	usage, err := commonblobgo.GetUsage(ctx, storage, prefix)
+	if err != nil {
+		return err
+	}
+
+	subPrefixes := make([]string, 0, len(usage.SubPrefixes))
+	for subPrefix := range usage.SubPrefixes {
+		subPrefixes = append(subPrefixes, subPrefix)
+	}
+
+	sort.Strings(subPrefixes)
+
+	writer := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+
+	for _, subPrefix := range subPrefixes {
+		subUsage := usage.SubPrefixes[subPrefix]
+		fmt.Fprintf(writer, "%d\t%d\t%s\n", subUsage.Bytes, subUsage.Objects, prefix+subPrefix)
+	}
+
+	fmt.Fprintf(writer, "%d\t%d\ttotal\n", usage.Bytes, usage.Objects)
+
+	return writer.Flush()
+}
+
+func stat(ctx context.Context, storage commonblobgo.CloudStorage, args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: stat <key>")
+	}
+
+	key := args[0]
+	if remote, ok := parseRemote(key); ok {
+		key = remote
+	}
+
+	attrs, err := storage.Attributes(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "Key:\t%s\n", key)
+	fmt.Fprintf(writer, "Size:\t%d\n", attrs.Size)
+	fmt.Fprintf(writer, "ModTime:\t%s\n", attrs.ModTime.Format(time.RFC3339))
+	fmt.Fprintf(writer, "ContentType:\t%s\n", attrs.ContentType)
+	fmt.Fprintf(writer, "MD5:\t%s\n", hex.EncodeToString(attrs.MD5))
+
+	for _, header := range []struct{ name, value string }{
+		{"CacheControl", attrs.CacheControl},
+		{"ContentDisposition", attrs.ContentDisposition},
+		{"ContentEncoding", attrs.ContentEncoding},
+		{"ContentLanguage", attrs.ContentLanguage},
+	} {
+		if header.value != "" {
+			fmt.Fprintf(writer, "%s:\t%s\n", header.name, header.value)
+		}
+	}
+
+	for name, value := range attrs.Metadata {
+		fmt.Fprintf(writer, "Metadata[%s]:\t%s\n", name, value)
+	}
+
+	return writer.Flush()
+}
+
+func signURL(ctx context.Context, storage commonblobgo.CloudStorage, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("sign-url", flag.ContinueOnError)
+	method := flags.String("method", http.MethodGet, "HTTP method allowed by the URL: GET, PUT or DELETE")
+	expiry := flags.Duration("expiry", 15*time.Minute, "validity of the URL")
+	contentType := flags.String("content-type", "", "content type required by a PUT URL")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: sign-url [-method GET] [-expiry 15m] <key>")
+	}
+
+	key := flags.Arg(0)
+	if remote, ok := parseRemote(key); ok {
+		key = remote
+	}
+
+	url, err := storage.GetSignedURL(ctx, key, &commonblobgo.SignedURLOption{
+		Method:      *method,
+		Expiry:      *expiry,
+		ContentType: *contentType,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, url)
+
+	return nil
+}
+
+// writeFile writes the reader to the path, creating the missing directories
+func writeFile(path string, reader io.Reader) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
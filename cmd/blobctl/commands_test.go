@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	commonblobgo "github.com/AccelByte/common-blob-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommands(t *testing.T) {
+	ctx := context.Background()
+	storage := commonblobgo.NewFakeCloudStorage("bucket")
+	dir, err := ioutil.TempDir("", "blobctl")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	local := filepath.Join(dir, "report.csv")
+	require.NoError(t, ioutil.WriteFile(local, []byte("a,b"), 0644))
+
+	var stdout bytes.Buffer
+
+	require.NoError(t, cp(ctx, storage, []string{local, "blob://reports/"}, &stdout))
+
+	attrs, err := storage.Attributes(ctx, "reports/report.csv")
+	require.NoError(t, err)
+	require.Equal(t, "text/csv; charset=utf-8", attrs.ContentType)
+
+	require.NoError(t, cat(ctx, storage, []string{"blob://reports/report.csv"}, &stdout))
+	require.Equal(t, "a,b", stdout.String())
+
+	stdout.Reset()
+	require.NoError(t, ls(ctx, storage, []string{"reports/"}, &stdout))
+	require.Equal(t, "reports/report.csv\n", stdout.String())
+
+	stdout.Reset()
+	require.NoError(t, du(ctx, storage, []string{"blob://reports/"}, &stdout))
+	require.Equal(t, "3  1  reports/\n3  1  total\n", stdout.String())
+
+	require.NoError(t, rm(ctx, storage, []string{"-r", "reports/"}, &stdout))
+
+	_, err = storage.Attributes(ctx, "reports/report.csv")
+	require.Error(t, err)
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	storage := commonblobgo.NewFakeCloudStorage("bucket")
+	dir, err := ioutil.TempDir("", "blobctl")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("b"), 0644))
+	require.NoError(t, storage.Write(ctx, "backup/extra.txt", []byte("extra"), nil))
+
+	var stdout bytes.Buffer
+
+	require.NoError(t, sync(ctx, storage, []string{"-delete", dir, "blob://backup"}, &stdout))
+
+	body, err := storage.Get(ctx, "backup/sub/b.txt")
+	require.NoError(t, err)
+	require.Equal(t, []byte("b"), body)
+
+	_, err = storage.Get(ctx, "backup/extra.txt")
+	require.Error(t, err)
+
+	// nothing changed, nothing is uploaded
+	stdout.Reset()
+	require.NoError(t, sync(ctx, storage, []string{dir, "blob://backup"}, &stdout))
+	require.Empty(t, stdout.String())
+
+	restored := filepath.Join(dir, "restored")
+	require.NoError(t, sync(ctx, storage, []string{"blob://backup/", restored}, &stdout))
+
+	content, err := ioutil.ReadFile(filepath.Join(restored, "sub", "b.txt"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("b"), content)
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+// Command blobctl inspects and modifies the objects of a bucket with the same provider configuration as the library.
+//
+//	blobctl [flags] <command> [arguments]
+//
+// The objects are referred to as blob://key, any other path is a local file and "-" is stdin or stdout.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	commonblobgo "github.com/AccelByte/common-blob-go"
+)
+
+const usage = `usage: blobctl [flags] <command> [arguments]
+
+commands:
+  ls [-l] [prefix]                              list the objects under the prefix
+  cp <src> <dst>                                copy between local files and objects
+  rm [-r] <key>...                              delete the objects, or every object under the prefixes with -r
+  cat <key>                                     write the object to stdout
+  stat <key>                                    print the attributes of the object
+  du [prefix]                                   print the bytes and objects of every sub-prefix of the prefix
+  sync [-delete] <src> <dst>                    copy the changed files between a local directory and a prefix
+  sign-url [-method GET] [-expiry 15m] <key>    print a signed URL of the object
+
+The objects are referred to as blob://key in cp and sync, any other path is local and "-" is stdin or stdout.
+
+flags:
+`
+
+type config struct {
+	isTesting bool
+	provider  string
+	bucket    string
+	opts      commonblobgo.CloudStorageOption
+}
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "blobctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdout io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	defer signal.Stop(interrupt)
+
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var cfg config
+
+	flags := flag.NewFlagSet("blobctl", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+
+	flags.BoolVar(&cfg.isTesting, "testing", false, "use the emulator clients, as isTesting of NewCloudStorage")
+	flags.StringVar(&cfg.provider, "provider", envOr("BUCKET_PROVIDER", "aws"), "bucket provider, aws or gcp ($BUCKET_PROVIDER)")
+	flags.StringVar(&cfg.bucket, "bucket", os.Getenv("BUCKET_NAME"), "bucket name ($BUCKET_NAME)")
+	flags.StringVar(&cfg.opts.AWSS3Endpoint, "aws-s3-endpoint", os.Getenv("AWS_S3_ENDPOINT"), "S3 endpoint ($AWS_S3_ENDPOINT)")
+	flags.StringVar(&cfg.opts.AWSS3Region, "aws-region", os.Getenv("AWS_REGION"), "S3 region ($AWS_REGION)")
+	flags.StringVar(&cfg.opts.AWSS3AccessKeyID, "aws-access-key-id", os.Getenv("AWS_ACCESS_KEY_ID"),
+		"S3 access key ($AWS_ACCESS_KEY_ID)")
+	flags.StringVar(&cfg.opts.AWSS3SecretAccessKey, "aws-secret-access-key", os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		"S3 secret key ($AWS_SECRET_ACCESS_KEY)")
+	flags.BoolVar(&cfg.opts.AWSEnableS3Accelerate, "aws-accelerate", false, "use the S3 accelerate endpoint")
+	flags.StringVar(&cfg.opts.GCPCredentialsJSON, "gcp-credentials-json", os.Getenv("GCP_CREDENTIAL_JSON"),
+		"GCP JSON credentials ($GCP_CREDENTIAL_JSON)")
+	flags.StringVar(&cfg.opts.GCPStorageEmulatorHost, "gcp-storage-emulator-host", os.Getenv("STORAGE_EMULATOR_HOST"),
+		"GCP storage emulator host ($STORAGE_EMULATOR_HOST)")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return flag.ErrHelp
+	}
+
+	command, ok := commands[flags.Arg(0)]
+	if !ok {
+		flags.Usage()
+		return fmt.Errorf("unknown command %q", flags.Arg(0))
+	}
+
+	if cfg.bucket == "" {
+		return fmt.Errorf("the bucket is required, set -bucket or $BUCKET_NAME")
+	}
+
+	storage, err := commonblobgo.NewCloudStorageWithOption(ctx, cfg.isTesting, cfg.provider, cfg.bucket, cfg.opts)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	return command(ctx, storage, flags.Args()[1:], stdout)
+}
+
+func envOr(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return fallback
+}
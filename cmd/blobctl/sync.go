@@ -0,0 +1,268 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" // nolint:gosec
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	commonblobgo "github.com/AccelByte/common-blob-go"
+)
+
+// sync copies the files whose size or MD5 differ between a local directory and a prefix, in either direction
+func sync(ctx context.Context, storage commonblobgo.CloudStorage, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+	deleteExtra := flags.Bool("delete", false, "delete the destination files missing from the source")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 2 {
+		return fmt.Errorf("usage: sync [-delete] <src> <dst>")
+	}
+
+	src, dst := flags.Arg(0), flags.Arg(1)
+	srcPrefix, srcRemote := parseRemote(src)
+	dstPrefix, dstRemote := parseRemote(dst)
+
+	if srcRemote == dstRemote {
+		return fmt.Errorf("sync needs a local directory and a blob:// prefix")
+	}
+
+	if srcRemote {
+		return syncDown(ctx, storage, prefixOf(srcPrefix), dst, *deleteExtra, stdout)
+	}
+
+	return syncUp(ctx, storage, src, prefixOf(dstPrefix), *deleteExtra, stdout)
+}
+
+// prefixOf makes the prefix a directory so sibling keys sharing its name aren't synced
+func prefixOf(prefix string) string {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	return prefix
+}
+
+func listObjects(ctx context.Context, storage commonblobgo.CloudStorage, prefix string) (map[string]*commonblobgo.ListObject, error) {
+	objects := make(map[string]*commonblobgo.ListObject)
+	iter := storage.List(ctx, prefix)
+
+	for {
+		object, err := iter.Next(ctx)
+		if err == io.EOF {
+			return objects, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		objects[strings.TrimPrefix(object.Key, prefix)] = object
+	}
+}
+
+func listFiles(dir string) (map[string]os.FileInfo, error) {
+	files := make(map[string]os.FileInfo)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) && path == dir {
+			return filepath.SkipDir
+		}
+
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		files[filepath.ToSlash(rel)] = info
+
+		return nil
+	})
+
+	return files, err
+}
+
+// changed returns whether the local file differs from the object, by size then by MD5 when the provider returns it
+func changed(path string, info os.FileInfo, object *commonblobgo.ListObject) (bool, error) {
+	if object == nil || info == nil || info.Size() != object.Size {
+		return true, nil
+	}
+
+	if len(object.MD5) == 0 {
+		return false, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	hash := md5.New() // nolint:gosec
+	if _, err := io.Copy(hash, file); err != nil {
+		return false, err
+	}
+
+	return !bytes.Equal(hash.Sum(nil), object.MD5), nil
+}
+
+func syncUp(
+	ctx context.Context,
+	storage commonblobgo.CloudStorage,
+	dir string,
+	prefix string,
+	deleteExtra bool,
+	stdout io.Writer,
+) error {
+	files, err := listFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	objects, err := listObjects(ctx, storage, prefix)
+	if err != nil {
+		return err
+	}
+
+	for name, info := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+
+		isChanged, err := changed(path, info, objects[name])
+		if err != nil {
+			return err
+		}
+
+		if !isChanged {
+			continue
+		}
+
+		if err := uploadFile(ctx, storage, path, prefix+name); err != nil {
+			return err
+		}
+
+		fmt.Fprintln(stdout, "uploaded", prefix+name)
+	}
+
+	if !deleteExtra {
+		return nil
+	}
+
+	for name := range objects {
+		if _, ok := files[name]; ok {
+			continue
+		}
+
+		if err := storage.Delete(ctx, prefix+name); err != nil {
+			return err
+		}
+
+		fmt.Fprintln(stdout, "deleted", prefix+name)
+	}
+
+	return nil
+}
+
+func syncDown(
+	ctx context.Context,
+	storage commonblobgo.CloudStorage,
+	prefix string,
+	dir string,
+	deleteExtra bool,
+	stdout io.Writer,
+) error {
+	objects, err := listObjects(ctx, storage, prefix)
+	if err != nil {
+		return err
+	}
+
+	files, err := listFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	for name, object := range objects {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+
+		isChanged, err := changed(path, files[name], object)
+		if err != nil {
+			return err
+		}
+
+		if !isChanged {
+			continue
+		}
+
+		reader, err := storage.GetReader(ctx, object.Key)
+		if err != nil {
+			return err
+		}
+
+		err = writeFile(path, reader)
+		reader.Close()
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(stdout, "downloaded", object.Key)
+	}
+
+	if !deleteExtra {
+		return nil
+	}
+
+	for name := range files {
+		if _, ok := objects[name]; ok {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			return err
+		}
+
+		fmt.Fprintln(stdout, "deleted", name)
+	}
+
+	return nil
+}
+
+func uploadFile(ctx context.Context, storage commonblobgo.CloudStorage, path string, key string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return storage.Upload(ctx, key, file, &commonblobgo.UploadOption{
+		ContentType: mime.TypeByExtension(filepath.Ext(path)),
+	})
+}
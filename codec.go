@@ -0,0 +1,151 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// Codec encodes the values of GetValue, PutValue and Store
+type Codec interface {
+	Marshal(value interface{}) ([]byte, error)
+	// Unmarshal decodes the data into the value, a pointer
+	Unmarshal(data []byte, value interface{}) error
+	ContentType() string
+}
+
+// JSONCodec encodes the values with encoding/json
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (JSONCodec) Unmarshal(data []byte, value interface{}) error {
+	return json.Unmarshal(data, value)
+}
+
+func (JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+// GobCodec encodes the values with encoding/gob
+type GobCodec struct{}
+
+func (GobCodec) Marshal(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Unmarshal(data []byte, value interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(value)
+}
+
+func (GobCodec) ContentType() string {
+	return "application/x-gob"
+}
+
+// ProtoCodec encodes the values with the protobuf wire format, they must be proto.Message
+type ProtoCodec struct{}
+
+func (ProtoCodec) Marshal(value interface{}) ([]byte, error) {
+	message, ok := value.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T isn't a proto.Message", value)
+	}
+
+	return proto.Marshal(message)
+}
+
+func (ProtoCodec) Unmarshal(data []byte, value interface{}) error {
+	message, ok := value.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T isn't a proto.Message", value)
+	}
+
+	return proto.Unmarshal(data, message)
+}
+
+func (ProtoCodec) ContentType() string {
+	return "application/x-protobuf"
+}
+
+// CodecProvider is implemented by the CloudStorage created with the Codec option
+type CodecProvider interface {
+	Codec() Codec
+}
+
+// codecOf returns the Codec of the storage, JSONCodec when it doesn't have one
+func codecOf(storage CloudStorage) Codec {
+	if provider, ok := storage.(CodecProvider); ok {
+		if codec := provider.Codec(); codec != nil {
+			return codec
+		}
+	}
+
+	return JSONCodec{}
+}
+
+// GetValue decodes the object into v, a pointer, with the Codec of the storage
+func GetValue(
+	ctx context.Context,
+	storage CloudStorage,
+	key string,
+	v interface{},
+	opts ...ReadOption,
+) error {
+	body, err := storage.Get(ctx, key, opts...)
+	if err != nil {
+		return err
+	}
+
+	if err := codecOf(storage).Unmarshal(body, v); err != nil {
+		return fmt.Errorf("unable to decode '%s': %w", key, err)
+	}
+
+	return nil
+}
+
+// PutValue encodes v with the Codec of the storage and writes it with the content type of the Codec
+func PutValue(
+	ctx context.Context,
+	storage CloudStorage,
+	key string,
+	v interface{},
+	opts ...WriteOption,
+) error {
+	codec := codecOf(storage)
+
+	body, err := codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("unable to encode '%s': %w", key, err)
+	}
+
+	contentType := codec.ContentType()
+
+	return storage.Write(ctx, key, body, &contentType, opts...)
+}
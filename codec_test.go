@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPutValue(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, PutValue(ctx, fake, "users/1", storeUser{Name: "alice"}))
+
+	attrs, err := fake.Attributes(ctx, "users/1")
+	require.NoError(t, err)
+	require.Equal(t, "application/json", attrs.ContentType)
+
+	var user storeUser
+	require.NoError(t, GetValue(ctx, fake, "users/1", &user))
+	require.Equal(t, "alice", user.Name)
+
+	require.True(t, errors.Is(GetValue(ctx, fake, "users/2", &user), ErrNotFound))
+}
+
+func TestCodecOption(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+
+	storage := newInterceptedCloudStorage(fake, "fake", "bucket")
+	storage.codec = ProtoCodec{}
+
+	require.NoError(t, PutValue(ctx, storage, "timeout", ptypes.DurationProto(time.Minute)))
+
+	attrs, err := fake.Attributes(ctx, "timeout")
+	require.NoError(t, err)
+	require.Equal(t, "application/x-protobuf", attrs.ContentType)
+
+	var timeout duration.Duration
+	require.NoError(t, GetValue(ctx, storage, "timeout", &timeout))
+	require.Equal(t, int64(60), timeout.Seconds)
+
+	// the stores of the storage use its codec unless they set one
+	require.NoError(t, NewStore(storage, StoreOption{}).Get(ctx, "timeout", &timeout))
+	require.Equal(t, int64(60), timeout.Seconds)
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// WriteCondition is the precondition of WriteIf, exactly one of its fields must be set
+type WriteCondition struct {
+	// DoesNotExist requires the key to be absent
+	DoesNotExist bool
+	// Generation requires the current content of the key to have this Attributes.Generation
+	Generation string
+}
+
+func (c WriteCondition) validate() error {
+	if c.DoesNotExist == (c.Generation != "") {
+		return errors.New("exactly one of DoesNotExist and Generation must be set")
+	}
+
+	return nil
+}
+
+// awsWriteIf sends the condition with the If-None-Match and If-Match headers, which the SDK doesn't expose
+func awsWriteIf(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	if err := condition.validate(); err != nil {
+		return "", err
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(bucketName),
+		Key:         aws.String(awsEscapeKey(key)),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String(detectContentType(key, body)),
+	}
+
+	if contentType != nil {
+		input.ContentType = contentType
+	}
+
+	request, output := client.PutObjectRequest(input)
+	request.SetContext(ctx)
+
+	if condition.DoesNotExist {
+		request.HTTPRequest.Header.Set("If-None-Match", "*")
+	} else {
+		request.HTTPRequest.Header.Set("If-Match", condition.Generation)
+	}
+
+	if err := request.Send(); err != nil {
+		return "", translateError(err)
+	}
+
+	return aws.StringValue(output.ETag), nil
+}
+
+func awsDeleteIf(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	key string,
+	generation string,
+) error {
+	request, _ := client.DeleteObjectRequest(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(awsEscapeKey(key)),
+	})
+	request.SetContext(ctx)
+	request.HTTPRequest.Header.Set("If-Match", generation)
+
+	return translateError(request.Send())
+}
+
+func gcpGeneration(generation string) (int64, error) {
+	value, err := strconv.ParseInt(generation, 10, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid GCS generation %q", generation)
+	}
+
+	return value, nil
+}
+
+func gcpWriteIf(
+	ctx context.Context,
+	client *storage.Client,
+	bucketName string,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	if err := condition.validate(); err != nil {
+		return "", err
+	}
+
+	conditions := storage.Conditions{DoesNotExist: condition.DoesNotExist}
+
+	if !condition.DoesNotExist {
+		generation, err := gcpGeneration(condition.Generation)
+		if err != nil {
+			return "", err
+		}
+
+		conditions.GenerationMatch = generation
+	}
+
+	// the upload is abandoned when the write fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	writer := client.Bucket(bucketName).Object(key).If(conditions).NewWriter(ctx)
+	writer.ContentType = detectContentType(key, body)
+
+	if contentType != nil {
+		writer.ContentType = *contentType
+	}
+
+	if _, err := writer.Write(body); err != nil {
+		return "", translateError(err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", translateError(err)
+	}
+
+	return strconv.FormatInt(writer.Attrs().Generation, 10), nil
+}
+
+func gcpDeleteIf(
+	ctx context.Context,
+	client *storage.Client,
+	bucketName string,
+	key string,
+	generation string,
+) error {
+	value, err := gcpGeneration(generation)
+	if err != nil {
+		return err
+	}
+
+	err = client.Bucket(bucketName).Object(key).If(storage.Conditions{GenerationMatch: value}).Delete(ctx)
+
+	return translateError(err)
+}
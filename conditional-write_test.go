@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSWriteIf(t *testing.T) {
+	var requests []string
+
+	client, server := newHTTPTestS3Client(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath()+" "+r.Header.Get("If-None-Match")+r.Header.Get("If-Match"))
+
+		if r.Header.Get("If-Match") == `"stale"` {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			w.Write([]byte(`<Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>`))
+
+			return
+		}
+
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+	})
+	defer server.Close()
+
+	ctx := context.Background()
+
+	// the keys are escaped like the keys of Get
+	generation, err := awsWriteIf(ctx, client, "bucket", "a//b", []byte("body"), nil, WriteCondition{DoesNotExist: true})
+	require.NoError(t, err)
+	require.Equal(t, `"etag"`, generation)
+
+	require.NoError(t, awsDeleteIf(ctx, client, "bucket", "a//b", generation))
+
+	err = awsDeleteIf(ctx, client, "bucket", "a//b", `"stale"`)
+	require.True(t, errors.Is(err, ErrPreconditionFailed), "%v", err)
+
+	require.Equal(t, []string{
+		`PUT /bucket/a/__0x2f__b *`,
+		`DELETE /bucket/a/__0x2f__b "etag"`,
+		`DELETE /bucket/a/__0x2f__b "stale"`,
+	}, requests)
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+)
+
+// sniffLen is the number of bytes read by http.DetectContentType
+const sniffLen = 512
+
+// detectContentType returns the content type of the extension of the key, or the content type sniffed from
+// the beginning of the object when the extension isn't known, e.g. "application/json" for "a.json", which
+// would only be sniffed as text
+func detectContentType(key string, head []byte) string {
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return contentType
+	}
+
+	return http.DetectContentType(head)
+}
+
+// extensionContentType returns the content type of the extension of the key, or nil when it isn't known
+// so the provider sniffs the object
+func extensionContentType(key string) *string {
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return &contentType
+	}
+
+	return nil
+}
+
+// sniffReader detects the content type of the object read by reader, and returns a reader of the whole object
+func sniffReader(key string, reader io.Reader) (string, io.Reader, error) {
+	if contentType := extensionContentType(key); contentType != nil {
+		return *contentType, reader, nil
+	}
+
+	head := make([]byte, sniffLen)
+
+	n, err := io.ReadFull(reader, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", nil, err
+	}
+
+	head = head[:n]
+
+	return http.DetectContentType(head), io.MultiReader(bytes.NewReader(head), reader), nil
+}
+
+// contentTypeOrDetect returns contentType, or the content type detected from the key and the body when it's nil
+func contentTypeOrDetect(contentType *string, key string, body []byte) *string {
+	if contentType != nil {
+		return contentType
+	}
+
+	detected := detectContentType(key, body)
+
+	return &detected
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectContentType(t *testing.T) {
+	// the extension wins over the sniffed content, which would be text/plain
+	require.Equal(t, "application/json", detectContentType("config.json", []byte(`{"limit": 10}`)))
+	require.Equal(t, "image/png", detectContentType("avatar", []byte("\x89PNG\r\n\x1a\n")))
+	require.Equal(t, "application/octet-stream", detectContentType("data", []byte{0, 1, 2}))
+
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.Write(ctx, "config.json", []byte(`{"limit": 10}`), nil))
+
+	attrs, err := storage.Attributes(ctx, "config.json")
+	require.NoError(t, err)
+	require.Equal(t, "application/json", attrs.ContentType)
+}
+
+func TestSniffReader(t *testing.T) {
+	body := append([]byte("<html><body>"), bytes.Repeat([]byte("a"), 1024)...)
+
+	contentType, reader, err := sniffReader("index", bytes.NewReader(body))
+	require.NoError(t, err)
+	require.Equal(t, "text/html; charset=utf-8", contentType)
+
+	// the sniffed bytes are read again
+	read, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, body, read)
+}
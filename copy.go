@@ -0,0 +1,223 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"net/url"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// MetadataDirective tells whether Copy keeps the attributes of the source object, like the S3 metadata directive
+type MetadataDirective int
+
+const (
+	// MetadataCopy gives the copy the content type, the Cache-Control, the Content-Disposition and the metadata
+	// of the source
+	MetadataCopy MetadataDirective = iota
+	// MetadataReplace gives the copy the attributes of the CopyOption instead, the ones left empty are cleared
+	MetadataReplace
+)
+
+// CopyOption configures Copy
+type CopyOption struct {
+	MetadataDirective MetadataDirective
+	// ContentType is the content type of the copy with MetadataReplace. It's detected from the extension of
+	// the destination key when it's empty, "application/octet-stream" for the unknown extensions.
+	ContentType string
+	// CacheControl is the Cache-Control of the copy with MetadataReplace
+	CacheControl string
+	// ContentDisposition is the Content-Disposition of the copy with MetadataReplace
+	ContentDisposition string
+	// Metadata is the metadata of the copy with MetadataReplace
+	Metadata map[string]string
+	// StorageClass is the storage class of the copy with both directives, e.g. "STANDARD_IA" on AWS or "NEARLINE"
+	// on GCP. The copy gets the default class of the bucket when it's empty, not the class of the source.
+	StorageClass string
+}
+
+// contentType returns the content type of a copy replacing the metadata
+func (o *CopyOption) contentType(dstKey string) string {
+	if o.ContentType != "" {
+		return o.ContentType
+	}
+
+	if contentType := extensionContentType(dstKey); contentType != nil {
+		return *contentType
+	}
+
+	return "application/octet-stream"
+}
+
+// writeOptions returns the options writing the copy of an object with the attributes, for the copies made by
+// reading and writing the object
+func (o *CopyOption) writeOptions(dstKey string, attrs *Attributes) (string, []WriteOption) {
+	var options CopyOption
+	if o != nil {
+		options = *o
+	}
+
+	var writeOpts []WriteOption
+
+	if options.StorageClass != "" {
+		writeOpts = append(writeOpts, WithStorageClass(options.StorageClass))
+	}
+
+	if options.MetadataDirective == MetadataReplace {
+		attrs = &Attributes{
+			CacheControl:       options.CacheControl,
+			ContentDisposition: options.ContentDisposition,
+			Metadata:           options.Metadata,
+		}
+	}
+
+	if attrs.CacheControl != "" {
+		writeOpts = append(writeOpts, WithCacheControl(attrs.CacheControl))
+	}
+
+	if attrs.ContentDisposition != "" {
+		writeOpts = append(writeOpts, WithContentDisposition(attrs.ContentDisposition))
+	}
+
+	if len(attrs.Metadata) > 0 {
+		writeOpts = append(writeOpts, WithMetadata(attrs.Metadata))
+	}
+
+	if options.MetadataDirective == MetadataReplace {
+		return options.contentType(dstKey), writeOpts
+	}
+
+	return attrs.ContentType, writeOpts
+}
+
+// streamCopy copies the object by reading it from src and writing it to dst, which can be different storages
+func streamCopy(
+	ctx context.Context,
+	src CloudStorage,
+	srcKey string,
+	dst CloudStorage,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	reader, attrs, err := src.GetWithAttributes(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	contentType, writeOpts := opts.writeOptions(dstKey, attrs)
+
+	return dst.Upload(ctx, dstKey, reader, &UploadOption{ContentType: contentType}, writeOpts...)
+}
+
+// awsCopy copies the object with a CopyObject, which is limited to 5GB
+func awsCopy(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	var options CopyOption
+	if opts != nil {
+		options = *opts
+	}
+
+	input := &s3.CopyObjectInput{
+		Bucket:            aws.String(bucketName),
+		Key:               aws.String(awsEscapeKey(dstKey)),
+		CopySource:        aws.String((&url.URL{Path: bucketName + "/" + awsEscapeKey(srcKey)}).EscapedPath()),
+		MetadataDirective: aws.String(s3.MetadataDirectiveCopy),
+	}
+
+	if options.StorageClass != "" {
+		input.StorageClass = aws.String(options.StorageClass)
+	}
+
+	if options.MetadataDirective == MetadataReplace {
+		input.MetadataDirective = aws.String(s3.MetadataDirectiveReplace)
+		input.ContentType = aws.String(options.contentType(dstKey))
+
+		if options.CacheControl != "" {
+			input.CacheControl = aws.String(options.CacheControl)
+		}
+
+		if options.ContentDisposition != "" {
+			input.ContentDisposition = aws.String(options.ContentDisposition)
+		}
+
+		if len(options.Metadata) > 0 {
+			input.Metadata = aws.StringMap(options.Metadata)
+		}
+	}
+
+	_, err := client.CopyObjectWithContext(ctx, input)
+
+	return translateError(err)
+}
+
+// gcpCopy copies the object with a rewrite. A rewrite setting some attributes doesn't copy the others, so the
+// attributes of the source are given again to change the storage class only.
+func gcpCopy(
+	ctx context.Context,
+	client *storage.Client,
+	bucketName string,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	var options CopyOption
+	if opts != nil {
+		options = *opts
+	}
+
+	bucket := client.Bucket(bucketName)
+	source := bucket.Object(srcKey)
+	copier := bucket.Object(dstKey).CopierFrom(source)
+
+	switch {
+	case options.MetadataDirective == MetadataReplace:
+		copier.ContentType = options.contentType(dstKey)
+		copier.CacheControl = options.CacheControl
+		copier.ContentDisposition = options.ContentDisposition
+		copier.Metadata = options.Metadata
+	case options.StorageClass != "":
+		attrs, err := source.Attrs(ctx)
+		if err != nil {
+			return translateError(err)
+		}
+
+		// the source can't change until the rewrite
+		copier = bucket.Object(dstKey).CopierFrom(source.Generation(attrs.Generation))
+		copier.ContentType = attrs.ContentType
+		copier.ContentEncoding = attrs.ContentEncoding
+		copier.ContentLanguage = attrs.ContentLanguage
+		copier.ContentDisposition = attrs.ContentDisposition
+		copier.CacheControl = attrs.CacheControl
+		copier.Metadata = attrs.Metadata
+	}
+
+	copier.StorageClass = options.StorageClass
+
+	_, err := copier.Run(ctx)
+
+	return translateError(err)
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeCloudStorageCopy(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	contentType := "text/csv"
+	require.NoError(t, storage.Write(ctx, "reports/a.csv", []byte("a,b"), &contentType,
+		WithCacheControl("no-cache"), WithMetadata(map[string]string{"owner": "alice"})))
+
+	// the metadata of the source is kept by default
+	require.NoError(t, storage.Copy(ctx, "reports/a.csv", "archive/a.dat", nil))
+
+	body, err := storage.Get(ctx, "archive/a.dat")
+	require.NoError(t, err)
+	require.Equal(t, "a,b", string(body))
+
+	attrs, err := storage.Attributes(ctx, "archive/a.dat")
+	require.NoError(t, err)
+	require.Equal(t, "text/csv", attrs.ContentType)
+	require.Equal(t, "no-cache", attrs.CacheControl)
+	require.Equal(t, map[string]string{"owner": "alice"}, attrs.Metadata)
+
+	// the replaced metadata clears the attributes left empty
+	require.NoError(t, storage.Copy(ctx, "reports/a.csv", "archive/a.json", &CopyOption{
+		MetadataDirective: MetadataReplace,
+		Metadata:          map[string]string{"archived": "true"},
+		StorageClass:      "STANDARD_IA",
+	}))
+
+	attrs, err = storage.Attributes(ctx, "archive/a.json")
+	require.NoError(t, err)
+	require.Equal(t, "application/json", attrs.ContentType)
+	require.Empty(t, attrs.CacheControl)
+	require.Equal(t, map[string]string{"archived": "true"}, attrs.Metadata)
+
+	object, err := storage.List(ctx, "archive/a.json").Next(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "STANDARD_IA", object.StorageClass)
+
+	require.NoError(t, storage.Copy(ctx, "reports/a.csv", "archive/a", &CopyOption{MetadataDirective: MetadataReplace}))
+
+	attrs, err = storage.Attributes(ctx, "archive/a")
+	require.NoError(t, err)
+	require.Equal(t, "application/octet-stream", attrs.ContentType)
+
+	require.True(t, errors.Is(storage.Copy(ctx, "missing", "archive/missing", nil), ErrNotFound))
+}
+
+func TestCopyObject(t *testing.T) {
+	ctx := context.Background()
+	src := NewFakeCloudStorage("src")
+	dst := NewFakeCloudStorage("dst")
+
+	contentType := "text/plain"
+	require.NoError(t, src.Write(ctx, "a", []byte("a"), &contentType,
+		WithContentDisposition("attachment"), WithMetadata(map[string]string{"owner": "alice"})))
+
+	// the objects streamed between storages keep their attributes
+	require.NoError(t, copyObject(ctx, src, "a", dst, "b"))
+	require.NoError(t, copyObject(ctx, src, "a", src, "c"))
+
+	for _, storage := range []CloudStorage{dst, src} {
+		key := "b"
+		if storage == src {
+			key = "c"
+		}
+
+		attrs, err := storage.Attributes(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "text/plain", attrs.ContentType)
+		require.Equal(t, "attachment", attrs.ContentDisposition)
+		require.Equal(t, map[string]string{"owner": "alice"}, attrs.Metadata)
+	}
+
+	// the storages without a server-side copy stream the object
+	transformed := NewTransformingCloudStorage(src, []TransformRule{{Prefix: "gz/", Transformers: []Transformer{GzipTransformer{}}}})
+	require.NoError(t, copyObject(ctx, transformed, "a", transformed, "gz/a"))
+
+	body, err := transformed.Get(ctx, "gz/a")
+	require.NoError(t, err)
+	require.Equal(t, "a", string(body))
+
+	stored, err := src.Get(ctx, "gz/a")
+	require.NoError(t, err)
+	require.NotEqual(t, "a", string(stored))
+}
+
+func TestAWSCopy(t *testing.T) {
+	var request *http.Request
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		request = r
+		w.Write([]byte(`<CopyObjectResult><ETag>"etag"</ETag></CopyObjectResult>`))
+	}))
+	defer server.Close()
+
+	awsSession, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(server.URL),
+		Region:           aws.String("us-east-1"),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	client := s3.New(awsSession)
+
+	require.NoError(t, awsCopy(ctx, client, "bucket", "reports/a b.csv", "archive/a.csv", nil))
+	require.Equal(t, http.MethodPut, request.Method)
+	require.Equal(t, "/bucket/archive/a.csv", request.URL.Path)
+	require.Equal(t, "bucket/reports/a%20b.csv", request.Header.Get("X-Amz-Copy-Source"))
+	require.Equal(t, "COPY", request.Header.Get("X-Amz-Metadata-Directive"))
+	require.Empty(t, request.Header.Get("X-Amz-Storage-Class"))
+
+	require.NoError(t, awsCopy(ctx, client, "bucket", "reports/a.csv", "archive/a.csv", &CopyOption{
+		MetadataDirective: MetadataReplace,
+		Metadata:          map[string]string{"archived": "true"},
+		StorageClass:      "GLACIER",
+	}))
+	require.Equal(t, "REPLACE", request.Header.Get("X-Amz-Metadata-Directive"))
+	require.Equal(t, "text/csv; charset=utf-8", request.Header.Get("Content-Type"))
+	require.Equal(t, "true", request.Header.Get("X-Amz-Meta-Archived"))
+	require.Equal(t, "GLACIER", request.Header.Get("X-Amz-Storage-Class"))
+}
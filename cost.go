@@ -0,0 +1,213 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// costClasses are the request classes of the billed operations, the operations missing from it are free.
+// The class A requests write or list the bucket, the class B requests read it.
+var costClasses = map[string]string{
+	"Write":                   "A",
+	"GetWriter":               "A",
+	"Upload":                  "A",
+	"WriteIf":                 "A",
+	"BeginUpload":             "A",
+	"CreateBucket":            "A",
+	"CreateBucketWithOptions": "A",
+	"SetObjectRetention":      "A",
+	"SetLegalHold":            "A",
+	"SetStorageClass":         "A",
+	"Copy":                    "A",
+	"Undelete":                "A",
+	"SetBucketPolicy":         "A",
+	"SetBucketEncryption":     "A",
+	"SetBucketLogging":        "A",
+	"SetBucketReplication":    "A",
+	"Ping":                    "A",
+	"ListIncompleteUploads":   "A",
+	"CreateFolder":            "A",
+	"RenameFolder":            "A",
+	"Get":                     "B",
+	"GetReader":               "B",
+	"GetWithAttributes":       "B",
+	"GetRangeReader":          "B",
+	"Attributes":              "B",
+	"GetObjectRetention":      "B",
+	"GetLegalHold":            "B",
+	"GetBucketPolicy":         "B",
+	"GetBucketEncryption":     "B",
+	"GetBucketLogging":        "B",
+	"GetBucketReplication":    "B",
+	"Query":                   "B",
+}
+
+// CostPrices are the unit prices of a provider, in any currency
+type CostPrices struct {
+	// ClassA is the price of a request writing or listing the bucket, e.g. a PUT of S3
+	ClassA float64
+	// ClassB is the price of a request reading the bucket, e.g. a GET of S3
+	ClassB float64
+	// EgressPerGB is the price of a GB read from the bucket
+	EgressPerGB float64
+	// IngressPerGB is the price of a GB written to the bucket
+	IngressPerGB float64
+}
+
+// DefaultCostPrices are the list prices in USD of the standard storage class of "aws" (us-east-1) and "gcp"
+// (multi-region), with the egress to the internet
+var DefaultCostPrices = map[string]CostPrices{
+	"aws": {ClassA: 0.005 / 1000, ClassB: 0.0004 / 1000, EgressPerGB: 0.09},
+	"gcp": {ClassA: 0.05 / 10000, ClassB: 0.004 / 10000, EgressPerGB: 0.12},
+}
+
+// Cost is the estimated cost of the operations made with the same labels
+type Cost struct {
+	Labels map[string]string
+	// ClassA and ClassB are the numbers of requests of each class
+	ClassA int64
+	ClassB int64
+	// EgressBytes and IngressBytes are the bytes read and written
+	EgressBytes  int64
+	IngressBytes int64
+	Total        float64
+}
+
+// CostAccountant estimates the cost of every operation with the prices of its provider, and aggregates them by
+// the labels of the context set with ContextWithCostLabels. The failed requests are billed too.
+// The bytes of the streamed reads aren't known, so only their request is accounted.
+type CostAccountant struct {
+	prices map[string]CostPrices
+	mu     sync.Mutex
+	costs  map[string]*Cost
+}
+
+// NewCostAccountant returns a CostAccountant using the prices by provider, DefaultCostPrices when it's nil
+func NewCostAccountant(prices map[string]CostPrices) *CostAccountant {
+	if prices == nil {
+		prices = DefaultCostPrices
+	}
+
+	return &CostAccountant{
+		prices: prices,
+		costs:  make(map[string]*Cost),
+	}
+}
+
+type costLabelsContextKey struct{}
+
+// ContextWithCostLabels returns a context whose operations are accounted under the labels, e.g. the feature
+// making them. They are added to the labels of the parent context.
+func ContextWithCostLabels(ctx context.Context, labels map[string]string) context.Context {
+	merged := make(map[string]string)
+
+	for name, value := range costLabelsFromContext(ctx) {
+		merged[name] = value
+	}
+
+	for name, value := range labels {
+		merged[name] = value
+	}
+
+	return context.WithValue(ctx, costLabelsContextKey{}, merged)
+}
+
+func costLabelsFromContext(ctx context.Context) map[string]string {
+	labels, _ := ctx.Value(costLabelsContextKey{}).(map[string]string)
+
+	return labels
+}
+
+// account adds the cost of an operation of the provider to the labels
+func (a *CostAccountant) account(labels map[string]string, provider string, operation string, bytes int64) {
+	class, billed := costClasses[operation]
+	if !billed {
+		return
+	}
+
+	// the default provider is AWS
+	if provider == "" {
+		provider = "aws"
+	}
+
+	prices := a.prices[provider]
+	key := costLabelsKey(labels)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cost, ok := a.costs[key]
+	if !ok {
+		cost = &Cost{Labels: labels}
+		a.costs[key] = cost
+	}
+
+	const gb = 1 << 30
+
+	if class == "A" {
+		cost.ClassA++
+		cost.IngressBytes += bytes
+		cost.Total += prices.ClassA + prices.IngressPerGB*float64(bytes)/gb
+	} else {
+		cost.ClassB++
+		cost.EgressBytes += bytes
+		cost.Total += prices.ClassB + prices.EgressPerGB*float64(bytes)/gb
+	}
+}
+
+// costLabelsKey returns the labels sorted by name, as the key of their aggregate
+func costLabelsKey(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+
+	for name, value := range labels {
+		pairs = append(pairs, name+"="+value)
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+// Costs returns the costs accounted since the accountant was created, by labels sorted by decreasing total
+func (a *CostAccountant) Costs() []Cost {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	costs := make([]Cost, 0, len(a.costs))
+
+	for _, cost := range a.costs {
+		costs = append(costs, *cost)
+	}
+
+	sort.Slice(costs, func(i, j int) bool { return costs[i].Total > costs[j].Total })
+
+	return costs
+}
+
+func newCostInterceptor(accountant *CostAccountant) Interceptor {
+	return func(ctx context.Context, op *OperationInfo, next func(ctx context.Context) error) error {
+		err := next(ctx)
+
+		accountant.account(costLabelsFromContext(ctx), op.Provider, op.Name, op.Bytes)
+
+		return err
+	}
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCostAccountant(t *testing.T) {
+	ctx := context.Background()
+	accountant := NewCostAccountant(map[string]CostPrices{
+		"gcp": {ClassA: 10, ClassB: 1, EgressPerGB: 1 << 30, IngressPerGB: 2 << 30},
+	})
+	storage := newInterceptedCloudStorage(NewFakeCloudStorage("bucket"), "gcp", "bucket", newCostInterceptor(accountant))
+
+	exportCtx := ContextWithCostLabels(ctx, map[string]string{"team": "data", "feature": "export"})
+	require.NoError(t, storage.Write(exportCtx, "export.csv", make([]byte, 100), nil))
+
+	_, err := storage.Get(exportCtx, "export.csv")
+	require.NoError(t, err)
+
+	// the labels are added to the ones of the parent context
+	importCtx := ContextWithCostLabels(exportCtx, map[string]string{"feature": "import"})
+	require.NoError(t, storage.Write(importCtx, "import.csv", make([]byte, 10), nil))
+
+	// the failed requests are billed and the deletes are free
+	_, err = storage.Get(importCtx, "missing.csv")
+	require.Error(t, err)
+	require.NoError(t, storage.Delete(importCtx, "import.csv"))
+
+	require.Equal(t, []Cost{
+		{
+			Labels:       map[string]string{"team": "data", "feature": "export"},
+			ClassA:       1,
+			ClassB:       1,
+			EgressBytes:  100,
+			IngressBytes: 100,
+			Total:        10 + 200 + 1 + 100,
+		},
+		{
+			Labels:       map[string]string{"team": "data", "feature": "import"},
+			ClassA:       1,
+			ClassB:       1,
+			IngressBytes: 10,
+			Total:        10 + 20 + 1,
+		},
+	}, accountant.Costs())
+}
+
+func TestCostAccountantDefaultProvider(t *testing.T) {
+	accountant := NewCostAccountant(nil)
+	storage := newInterceptedCloudStorage(NewFakeCloudStorage("bucket"), "", "bucket", newCostInterceptor(accountant))
+
+	require.NoError(t, storage.Write(context.Background(), "key", []byte("body"), nil))
+
+	costs := accountant.Costs()
+	require.Len(t, costs, 1)
+	require.Empty(t, costs[0].Labels)
+	require.InDelta(t, DefaultCostPrices["aws"].ClassA, costs[0].Total, 1e-12)
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"golang.org/x/oauth2/google"
+)
+
+// awsObjectOwnershipBucketOwnerEnforced disables the ACLs of a bucket, it's missing from the enum of the SDK
+const awsObjectOwnershipBucketOwnerEnforced = "BucketOwnerEnforced"
+
+// CreateBucketOption configures the bucket created by CreateBucketWithOptions
+type CreateBucketOption struct {
+	// Location is the region of the S3 bucket, the region of the client by default, or the location of the
+	// GCS bucket, "US" by default
+	Location string
+	// StorageClass is the default storage class of the objects of the GCS bucket.
+	// It isn't supported on AWS, S3 has no default storage class.
+	StorageClass string
+	// Versioning keeps the previous versions of the overwritten and deleted objects
+	Versioning bool
+	// UniformBucketLevelAccess disables the ACLs: the access is only granted by the IAM bindings of the GCS
+	// bucket, and the S3 bucket owns every object (BucketOwnerEnforced)
+	UniformBucketLevelAccess bool
+	// Labels are the labels of the GCS bucket, or the tags of the S3 bucket
+	Labels map[string]string
+	// KMSKeyName is the key encrypting the objects by default: the Cloud KMS key name on GCP, or the
+	// KMS key ID or ARN on AWS. The objects are encrypted with the keys of the provider when it's empty.
+	KMSKeyName string
+	// Logging is the destination of the access logs of the bucket, they're disabled when it's nil
+	Logging *BucketLogging
+	// GCPProjectID is the project of the GCS bucket, the project of the credentials by default
+	GCPProjectID string
+}
+
+func awsCreateBucket(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	opts *CreateBucketOption,
+) error {
+	if opts.StorageClass != "" {
+		return fmt.Errorf("%w: S3 has no default storage class", ErrNotSupported)
+	}
+
+	location := opts.Location
+	if location == "" {
+		location = aws.StringValue(client.Config.Region)
+	}
+
+	input := &s3.CreateBucketInput{Bucket: aws.String(bucketName)}
+
+	// us-east-1 is the default location, which can't be given as a constraint
+	if location != "" && location != "us-east-1" {
+		input.CreateBucketConfiguration = &s3.CreateBucketConfiguration{LocationConstraint: aws.String(location)}
+	}
+
+	if _, err := client.CreateBucketWithContext(ctx, input); err != nil {
+		return translateError(err)
+	}
+
+	if opts.Versioning {
+		_, err := client.PutBucketVersioningWithContext(ctx, &s3.PutBucketVersioningInput{
+			Bucket:                  aws.String(bucketName),
+			VersioningConfiguration: &s3.VersioningConfiguration{Status: aws.String(s3.BucketVersioningStatusEnabled)},
+		})
+		if err != nil {
+			return translateError(err)
+		}
+	}
+
+	if opts.UniformBucketLevelAccess {
+		_, err := client.PutBucketOwnershipControlsWithContext(ctx, &s3.PutBucketOwnershipControlsInput{
+			Bucket: aws.String(bucketName),
+			OwnershipControls: &s3.OwnershipControls{
+				Rules: []*s3.OwnershipControlsRule{{ObjectOwnership: aws.String(awsObjectOwnershipBucketOwnerEnforced)}},
+			},
+		})
+		if err != nil {
+			return translateError(err)
+		}
+	}
+
+	if len(opts.Labels) > 0 {
+		keys := make([]string, 0, len(opts.Labels))
+		for key := range opts.Labels {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		tags := make([]*s3.Tag, 0, len(keys))
+		for _, key := range keys {
+			tags = append(tags, &s3.Tag{Key: aws.String(key), Value: aws.String(opts.Labels[key])})
+		}
+
+		_, err := client.PutBucketTaggingWithContext(ctx, &s3.PutBucketTaggingInput{
+			Bucket:  aws.String(bucketName),
+			Tagging: &s3.Tagging{TagSet: tags},
+		})
+		if err != nil {
+			return translateError(err)
+		}
+	}
+
+	if opts.KMSKeyName != "" {
+		if err := awsSetBucketEncryption(ctx, client, bucketName, &BucketEncryption{KMSKeyName: opts.KMSKeyName}); err != nil {
+			return err
+		}
+	}
+
+	if opts.Logging != nil && opts.Logging.TargetBucket != "" {
+		return awsSetBucketLogging(ctx, client, bucketName, opts.Logging)
+	}
+
+	return nil
+}
+
+func gcpCreateBucket(
+	ctx context.Context,
+	client *storage.Client,
+	bucketName string,
+	projectID string,
+	opts *CreateBucketOption,
+) error {
+	attrs := &storage.BucketAttrs{
+		Location:                 opts.Location,
+		StorageClass:             opts.StorageClass,
+		VersioningEnabled:        opts.Versioning,
+		UniformBucketLevelAccess: storage.UniformBucketLevelAccess{Enabled: opts.UniformBucketLevelAccess},
+		Labels:                   opts.Labels,
+	}
+
+	if opts.KMSKeyName != "" {
+		attrs.Encryption = &storage.BucketEncryption{DefaultKMSKeyName: opts.KMSKeyName}
+	}
+
+	if opts.Logging != nil && opts.Logging.TargetBucket != "" {
+		attrs.Logging = gcpBucketLogging(opts.Logging)
+	}
+
+	return translateError(client.Bucket(bucketName).Create(ctx, projectID, attrs))
+}
+
+// gcpBucketProject returns the project of the new GCS bucket, the project of the credentials by default
+func gcpBucketProject(creds *google.Credentials, opts *CreateBucketOption) (string, error) {
+	if opts.GCPProjectID != "" {
+		return opts.GCPProjectID, nil
+	}
+
+	if creds == nil || creds.ProjectID == "" {
+		return "", errors.New("unable to create bucket: the GCP project of the credentials is unknown, set GCPProjectID")
+	}
+
+	return creds.ProjectID, nil
+}
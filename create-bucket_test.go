@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2/google"
+)
+
+func TestAWSCreateBucket(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+		bodies   []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	awsSession, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(server.URL),
+		Region:           aws.String("eu-west-1"),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	err = awsCreateBucket(context.Background(), s3.New(awsSession), "bucket", &CreateBucketOption{
+		Versioning:               true,
+		UniformBucketLevelAccess: true,
+		Labels:                   map[string]string{"team": "storage", "env": "prod"},
+		KMSKeyName:               "arn:aws:kms:eu-west-1:123456789012:key/abc",
+		Logging:                  &BucketLogging{TargetBucket: "logs", TargetPrefix: "bucket/"},
+	})
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Equal(t, []string{
+		"PUT /bucket?",
+		"PUT /bucket?versioning=",
+		"PUT /bucket?ownershipControls=",
+		"PUT /bucket?tagging=",
+		"PUT /bucket?encryption=",
+		"PUT /bucket?logging=",
+	}, requests)
+
+	// the region of the client is the location of the bucket
+	require.Contains(t, bodies[0], "<LocationConstraint>eu-west-1</LocationConstraint>")
+	require.Contains(t, bodies[2], "BucketOwnerEnforced")
+	require.True(t, strings.Index(bodies[3], "env") < strings.Index(bodies[3], "team"))
+	require.Contains(t, bodies[4], "arn:aws:kms:eu-west-1:123456789012:key/abc")
+
+	err = awsCreateBucket(context.Background(), s3.New(awsSession), "bucket", &CreateBucketOption{StorageClass: "GLACIER"})
+	require.ErrorIs(t, err, ErrNotSupported)
+	require.Len(t, requests, 6)
+}
+
+func TestGCPBucketProject(t *testing.T) {
+	projectID, err := gcpBucketProject(&google.Credentials{ProjectID: "creds"}, &CreateBucketOption{})
+	require.NoError(t, err)
+	require.Equal(t, "creds", projectID)
+
+	projectID, err = gcpBucketProject(&google.Credentials{ProjectID: "creds"}, &CreateBucketOption{GCPProjectID: "other"})
+	require.NoError(t, err)
+	require.Equal(t, "other", projectID)
+
+	_, err = gcpBucketProject(nil, &CreateBucketOption{})
+	require.Error(t, err)
+}
+
+func TestFakeCloudStorageCreateBucketWithOptions(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.CreateBucketWithOptions(ctx, &CreateBucketOption{
+		StorageClass:             "NEARLINE",
+		UniformBucketLevelAccess: true,
+	}))
+	require.ErrorIs(t, storage.CreateBucketWithOptions(ctx, &CreateBucketOption{}), ErrAlreadyExists)
+
+	policy, err := storage.GetBucketPolicy(ctx)
+	require.NoError(t, err)
+	require.True(t, *policy.UniformBucketLevelAccess)
+
+	// the objects get the default storage class of the bucket
+	require.NoError(t, storage.Write(ctx, "key", []byte("body"), nil))
+	require.NoError(t, storage.Write(ctx, "cold", []byte("body"), nil, WithStorageClass("ARCHIVE")))
+
+	iter := storage.List(ctx, "")
+
+	cold, err := iter.Next(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "ARCHIVE", cold.StorageClass)
+
+	key, err := iter.Next(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "NEARLINE", key.StorageClass)
+}
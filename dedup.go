@@ -0,0 +1,533 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultDedupPrefix is the prefix of the content-addressed blobs when DedupOption.Prefix is not set
+	DefaultDedupPrefix = ".cas/"
+
+	// dedupPointerContentType tells the pointer objects apart from the objects written without deduplication
+	dedupPointerContentType = "application/vnd.commonblob.pointer+json"
+)
+
+// DedupOption configures the deduplication of the objects
+type DedupOption struct {
+	// Prefix holds the blobs under blobs/<sha256> and their references under refs/<sha256>/<key>.
+	// Defaults to DefaultDedupPrefix.
+	Prefix string
+	// Spool spools the objects of GetWriter and Upload being hashed to temporary files above its threshold,
+	// they're held in memory when it's nil
+	Spool *SpoolOption
+}
+
+// dedupPointer is the content of the logical key of a deduplicated object
+type dedupPointer struct {
+	Hash        string `json:"hash"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// DedupCloudStorage stores the objects once per content, under their SHA-256, and writes a small pointer to the
+// blob at their key, so the identical uploads are only stored once. Every key referencing a blob has a reference
+// object, and the blob is deleted with its last reference.
+//
+// The written objects are buffered in memory to be hashed, or spooled to temporary files with the Spool option.
+// List gives the pointers, and the objects written before the deduplication was enabled are still read as is.
+// A blob may be lost when its last reference is deleted while the same content is being written to another key.
+// WriteIf and DeleteIf aren't deduplicated, they write and delete the object at the key as is.
+// The upload sessions aren't supported.
+type DedupCloudStorage struct {
+	CloudStorage
+	prefix string
+	spool  *SpoolOption
+}
+
+// NewDedupCloudStorage returns the storage deduplicating the objects written to it
+func NewDedupCloudStorage(storage CloudStorage, opts DedupOption) *DedupCloudStorage {
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultDedupPrefix
+	}
+
+	return &DedupCloudStorage{CloudStorage: storage, prefix: opts.Prefix, spool: opts.Spool}
+}
+
+func (ts *DedupCloudStorage) blobKey(hash string) string {
+	return ts.prefix + "blobs/" + hash
+}
+
+func (ts *DedupCloudStorage) refsPrefix(hash string) string {
+	return ts.prefix + "refs/" + hash + "/"
+}
+
+// pointer returns the pointer at the key, or nil with the reader of the object when it isn't deduplicated
+func (ts *DedupCloudStorage) pointer(ctx context.Context, key string) (*dedupPointer, io.ReadCloser, *Attributes, error) {
+	reader, attrs, err := ts.CloudStorage.GetWithAttributes(ctx, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if attrs.ContentType != dedupPointerContentType {
+		return nil, reader, attrs, nil
+	}
+	defer reader.Close()
+
+	var pointer dedupPointer
+	if err := json.NewDecoder(reader).Decode(&pointer); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return &pointer, nil, attrs, nil
+}
+
+// List skips the blobs and the references
+func (ts *DedupCloudStorage) List(
+	ctx context.Context,
+	prefix string,
+	opts ...ListOption,
+) *ListIterator {
+	iter := ts.CloudStorage.List(ctx, prefix, opts...)
+
+	return NewListIterator(ctx, func(ctx context.Context) (*ListObject, error) {
+		for {
+			object, err := iter.Next(ctx)
+			if err != nil || !strings.HasPrefix(object.Key, ts.prefix) {
+				return object, err
+			}
+		}
+	})
+}
+
+func (ts *DedupCloudStorage) Get(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) ([]byte, error) {
+	reader, err := ts.GetReader(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return ioutil.ReadAll(reader)
+}
+
+func (ts *DedupCloudStorage) GetReader(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	reader, _, err := ts.GetWithAttributes(ctx, key, opts...)
+
+	return reader, err
+}
+
+// GetWithAttributes returns the attributes of the blob, with the content type and the modification time of the key
+func (ts *DedupCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	pointer, reader, attrs, err := ts.pointer(ctx, key)
+	if err != nil || pointer == nil {
+		return reader, attrs, err
+	}
+
+	reader, blobAttrs, err := ts.CloudStorage.GetWithAttributes(ctx, ts.blobKey(pointer.Hash), opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return reader, dedupAttributes(pointer, attrs, blobAttrs), nil
+}
+
+func dedupAttributes(pointer *dedupPointer, pointerAttrs *Attributes, blobAttrs *Attributes) *Attributes {
+	attrs := *blobAttrs
+	attrs.ContentType = pointer.ContentType
+	attrs.ModTime = pointerAttrs.ModTime
+	// the blob is shared by the keys, the metadata is the one written with the key
+	attrs.Metadata = pointerAttrs.Metadata
+
+	return &attrs
+}
+
+func (ts *DedupCloudStorage) Attributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (*Attributes, error) {
+	attrs, err := ts.CloudStorage.Attributes(ctx, key, opts...)
+	if err != nil || attrs.ContentType != dedupPointerContentType {
+		return attrs, err
+	}
+
+	pointer, reader, attrs, err := ts.pointer(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if pointer == nil {
+		// replaced meanwhile by an object written without deduplication
+		reader.Close()
+		return attrs, nil
+	}
+
+	blobAttrs, err := ts.CloudStorage.Attributes(ctx, ts.blobKey(pointer.Hash), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return dedupAttributes(pointer, attrs, blobAttrs), nil
+}
+
+func (ts *DedupCloudStorage) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset,
+	length int64,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	pointer, reader, _, err := ts.pointer(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if pointer == nil {
+		reader.Close()
+		return ts.CloudStorage.GetRangeReader(ctx, key, offset, length, opts...)
+	}
+
+	return ts.CloudStorage.GetRangeReader(ctx, ts.blobKey(pointer.Hash), offset, length, opts...)
+}
+
+// GetSignedURL signs the blob of a deduplicated object for GET and HEAD, the other methods sign the key
+func (ts *DedupCloudStorage) GetSignedURL(
+	ctx context.Context,
+	key string,
+	opts *SignedURLOption,
+) (string, error) {
+	if opts != nil && opts.Method != "" && opts.Method != http.MethodGet && opts.Method != http.MethodHead {
+		return ts.CloudStorage.GetSignedURL(ctx, key, opts)
+	}
+
+	pointer, reader, _, err := ts.pointer(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	if pointer == nil {
+		reader.Close()
+		return ts.CloudStorage.GetSignedURL(ctx, key, opts)
+	}
+
+	return ts.CloudStorage.GetSignedURL(ctx, ts.blobKey(pointer.Hash), opts)
+}
+
+func (ts *DedupCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	sum := sha256.Sum256(body)
+
+	return ts.write(ctx, key, hex.EncodeToString(sum[:]), int64(len(body)), body, contentType, opts, func(blobKey string) error {
+		return ts.CloudStorage.Write(ctx, blobKey, body, contentType, opts...)
+	})
+}
+
+// write stores the object of the hash with writeBlob when the blob doesn't exist yet, and writes its pointer.
+// The content type is detected from the beginning of the object when it's not given.
+func (ts *DedupCloudStorage) write(
+	ctx context.Context,
+	key string,
+	hash string,
+	size int64,
+	beginning []byte,
+	contentType *string,
+	opts []WriteOption,
+	writeBlob func(blobKey string) error,
+) error {
+	previous, err := ts.previousHash(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	// the reference is added before the blob is written, so a concurrent delete keeps the blob
+	if err := ts.CloudStorage.Write(ctx, ts.refsPrefix(hash)+key, nil, nil); err != nil {
+		return err
+	}
+
+	if _, err := ts.CloudStorage.Attributes(ctx, ts.blobKey(hash)); errors.Is(err, ErrNotFound) {
+		if err := writeBlob(ts.blobKey(hash)); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	pointer := dedupPointer{Hash: hash, Size: size}
+	if contentType != nil {
+		pointer.ContentType = *contentType
+	} else if options := writeOptions(opts); options.contentType != "" {
+		pointer.ContentType = options.contentType
+	} else {
+		pointer.ContentType = detectContentType(key, beginning)
+	}
+
+	pointerBody, err := json.Marshal(pointer)
+	if err != nil {
+		return err
+	}
+
+	pointerContentType := dedupPointerContentType
+
+	if err := ts.CloudStorage.Write(ctx, key, pointerBody, &pointerContentType, opts...); err != nil {
+		return err
+	}
+
+	if previous != "" && previous != hash {
+		return ts.release(ctx, previous, key)
+	}
+
+	return nil
+}
+
+// previousHash returns the hash of the blob referenced by the key, if any
+func (ts *DedupCloudStorage) previousHash(ctx context.Context, key string) (string, error) {
+	pointer, reader, _, err := ts.pointer(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if pointer == nil {
+		reader.Close()
+		return "", nil
+	}
+
+	return pointer.Hash, nil
+}
+
+// release removes the reference of the key to the blob, and the blob when it was the last one
+func (ts *DedupCloudStorage) release(ctx context.Context, hash string, key string) error {
+	err := ts.CloudStorage.Delete(ctx, ts.refsPrefix(hash)+key)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	_, err = ts.CloudStorage.List(ctx, ts.refsPrefix(hash)).Next(ctx)
+	if err != io.EOF {
+		return err
+	}
+
+	err = ts.CloudStorage.Delete(ctx, ts.blobKey(hash))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// GetWriter buffers and hashes the object until the writer is closed
+func (ts *DedupCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	return ts.newDedupWriter(ctx, key, nil, opts), nil
+}
+
+// dedupWriter hashes the object while it's buffered, the blob is uploaded from the spool on Close
+type dedupWriter struct {
+	ctx         context.Context
+	storage     *DedupCloudStorage
+	key         string
+	contentType *string
+	opts        []WriteOption
+	spool       *spool
+	hash        hash.Hash
+	// beginning is the beginning of the object, to detect its content type
+	beginning []byte
+}
+
+func (ts *DedupCloudStorage) newDedupWriter(
+	ctx context.Context,
+	key string,
+	contentType *string,
+	opts []WriteOption,
+) *dedupWriter {
+	return &dedupWriter{
+		ctx:         ctx,
+		storage:     ts,
+		key:         key,
+		contentType: contentType,
+		opts:        opts,
+		spool:       newSpool(ts.spool),
+		hash:        sha256.New(),
+	}
+}
+
+func (w *dedupWriter) Write(p []byte) (int, error) {
+	if n := 512 - len(w.beginning); n > 0 {
+		if n > len(p) {
+			n = len(p)
+		}
+
+		w.beginning = append(w.beginning, p[:n]...)
+	}
+
+	n, err := w.spool.Write(p)
+	w.hash.Write(p[:n])
+
+	return n, err
+}
+
+func (w *dedupWriter) Close() error {
+	defer w.spool.Close()
+
+	size := w.spool.Len()
+	hash := hex.EncodeToString(w.hash.Sum(nil))
+
+	return w.storage.write(w.ctx, w.key, hash, size, w.beginning, w.contentType, w.opts, func(blobKey string) error {
+		if body, ok := w.spool.Bytes(); ok {
+			return w.storage.CloudStorage.Write(w.ctx, blobKey, body, w.contentType, w.opts...)
+		}
+
+		var opts UploadOption
+		if w.contentType != nil {
+			opts.ContentType = *w.contentType
+		}
+
+		writeOpts := append(append([]WriteOption(nil), w.opts...), withContentLength(size))
+
+		return w.storage.CloudStorage.Upload(w.ctx, blobKey, w.spool.Reader(), &opts, writeOpts...)
+	})
+}
+
+func (ts *DedupCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	var contentType *string
+	if opts != nil && opts.ContentType != "" {
+		contentType = &opts.ContentType
+	}
+
+	writer := ts.newDedupWriter(ctx, key, contentType, writeOpts)
+
+	if _, err := io.Copy(writer, reader); err != nil {
+		writer.spool.Close()
+		return err
+	}
+
+	return writer.Close()
+}
+
+// BeginUpload returns ErrNotSupported, the parts would be completed as is at the key, overwriting its pointer
+// without releasing its blob. Upload and GetWriter deduplicate the large objects.
+func (ts *DedupCloudStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	return nil, fmt.Errorf("%w: the upload sessions of '%s' can't be deduplicated", ErrNotSupported, key)
+}
+
+// ResumeUpload returns ErrNotSupported like BeginUpload
+func (ts *DedupCloudStorage) ResumeUpload(
+	ctx context.Context,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	return nil, fmt.Errorf("%w: the upload sessions of '%s' can't be deduplicated", ErrNotSupported, state.Key)
+}
+
+// Delete removes the pointer, then the blob when it was its last reference
+func (ts *DedupCloudStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	hash, err := ts.previousHash(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if err := ts.CloudStorage.Delete(ctx, key); err != nil {
+		return err
+	}
+
+	if hash == "" {
+		return nil
+	}
+
+	return ts.release(ctx, hash, key)
+}
+
+// Copy reads and writes the object again, so the copy is a pointer to the same content
+func (ts *DedupCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	return streamCopy(ctx, ts, srcKey, ts, dstKey, opts)
+}
+
+// Query runs the SQL on the blob of a deduplicated object
+func (ts *DedupCloudStorage) Query(
+	ctx context.Context,
+	key string,
+	sql string,
+	format QueryInputFormat,
+) (io.ReadCloser, error) {
+	pointer, reader, _, err := ts.pointer(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if pointer == nil {
+		reader.Close()
+		return ts.CloudStorage.Query(ctx, key, sql, format)
+	}
+
+	return ts.CloudStorage.Query(ctx, ts.blobKey(pointer.Hash), sql, format)
+}
+
+// Flush flushes the wrapped storage
+func (ts *DedupCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
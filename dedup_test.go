@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupCloudStorage(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	storage := NewDedupCloudStorage(fake, DedupOption{})
+	contentType := "text/csv"
+
+	require.NoError(t, fake.Write(ctx, "exports/legacy.csv", []byte("legacy"), nil))
+
+	require.NoError(t, storage.Write(ctx, "exports/a.csv", []byte("a,b"), &contentType))
+	require.NoError(t, storage.Upload(ctx, "exports/b.csv", bytes.NewReader([]byte("a,b")), nil))
+
+	writer, err := storage.GetWriter(ctx, "exports/c.csv")
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("c,d"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	blobs, err := listByName(ctx, fake, ".cas/blobs/")
+	require.NoError(t, err)
+	require.Len(t, blobs, 2)
+
+	body, err := storage.Get(ctx, "exports/b.csv")
+	require.NoError(t, err)
+	require.Equal(t, "a,b", string(body))
+
+	body, err = storage.Get(ctx, "exports/legacy.csv")
+	require.NoError(t, err)
+	require.Equal(t, "legacy", string(body))
+
+	attrs, err := storage.Attributes(ctx, "exports/a.csv")
+	require.NoError(t, err)
+	require.Equal(t, "text/csv", attrs.ContentType)
+	require.Equal(t, int64(3), attrs.Size)
+
+	reader, err := storage.GetRangeReader(ctx, "exports/c.csv", 2, 1)
+	require.NoError(t, err)
+
+	body, err = ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, "d", string(body))
+
+	// the blobs and the references aren't listed
+	var keys []string
+
+	iter := storage.List(ctx, "")
+
+	for {
+		object, err := iter.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+
+		keys = append(keys, object.Key)
+	}
+
+	require.Equal(t, []string{"exports/a.csv", "exports/b.csv", "exports/c.csv", "exports/legacy.csv"}, keys)
+
+	// the blob is kept until its last reference is deleted
+	require.NoError(t, storage.Delete(ctx, "exports/a.csv"))
+
+	body, err = storage.Get(ctx, "exports/b.csv")
+	require.NoError(t, err)
+	require.Equal(t, "a,b", string(body))
+
+	// overwriting releases the previous blob
+	require.NoError(t, storage.Write(ctx, "exports/b.csv", []byte("c,d"), nil))
+
+	blobs, err = listByName(ctx, fake, ".cas/blobs/")
+	require.NoError(t, err)
+	require.Len(t, blobs, 1)
+
+	require.NoError(t, storage.Delete(ctx, "exports/b.csv"))
+	require.NoError(t, storage.Delete(ctx, "exports/c.csv"))
+	require.NoError(t, storage.Delete(ctx, "exports/legacy.csv"))
+
+	objects, err := listByName(ctx, fake, "")
+	require.NoError(t, err)
+	require.Empty(t, objects)
+
+	err = storage.Delete(ctx, "exports/a.csv")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	_, err = storage.Get(ctx, "exports/a.csv")
+	require.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestDedupCloudStorageUploadSessions(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	storage := NewDedupCloudStorage(fake, DedupOption{})
+
+	require.NoError(t, storage.Write(ctx, "exports/a.csv", []byte("a,b"), nil))
+
+	// a session would overwrite the pointer without releasing the blob
+	_, err := storage.BeginUpload(ctx, "exports/a.csv", &UploadOption{PartSize: 4})
+	require.True(t, errors.Is(err, ErrNotSupported))
+
+	_, err = storage.ResumeUpload(ctx, &UploadSessionState{Key: "exports/a.csv", UploadID: "id", PartSize: 4})
+	require.True(t, errors.Is(err, ErrNotSupported))
+
+	body, err := storage.Get(ctx, "exports/a.csv")
+	require.NoError(t, err)
+	require.Equal(t, []byte("a,b"), body)
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultTimeouts are the timeouts of the calls made with a context without deadline, by class of operation,
+// so a forgotten context.WithTimeout can't block a worker forever. The timeout of WithTimeout wins.
+// Zero means no timeout. Like WithTimeout, the timeout of a reader or a writer covers its whole transfer,
+// until it's closed, and the one of List covers the whole listing.
+type DefaultTimeouts struct {
+	// Read is the timeout of the reads of the objects and their settings, Query and Ping
+	Read time.Duration
+	// Write is the timeout of the mutations of the objects and the bucket
+	Write time.Duration
+	// List is the timeout of List and ListIncompleteUploads
+	List time.Duration
+	// Sign is the timeout of GetSignedURL and GetScopedCredentials
+	Sign time.Duration
+}
+
+type timeoutClass int
+
+const (
+	noTimeout timeoutClass = iota
+	readTimeout
+	writeTimeout
+	listTimeout
+	signTimeout
+)
+
+// timeoutClasses are the classes of the operations given a default timeout. Subscribe and the resumable
+// uploads are missing since their context outlives the call.
+var timeoutClasses = map[string]timeoutClass{
+	"Get":                     readTimeout,
+	"GetReader":               readTimeout,
+	"GetWithAttributes":       readTimeout,
+	"GetRangeReader":          readTimeout,
+	"Attributes":              readTimeout,
+	"GetObjectRetention":      readTimeout,
+	"GetLegalHold":            readTimeout,
+	"GetBucketPolicy":         readTimeout,
+	"GetBucketEncryption":     readTimeout,
+	"GetBucketLogging":        readTimeout,
+	"GetBucketReplication":    readTimeout,
+	"Query":                   readTimeout,
+	"Ping":                    readTimeout,
+	"Write":                   writeTimeout,
+	"GetWriter":               writeTimeout,
+	"Upload":                  writeTimeout,
+	"Delete":                  writeTimeout,
+	"WriteIf":                 writeTimeout,
+	"DeleteIf":                writeTimeout,
+	"CreateBucket":            writeTimeout,
+	"CreateBucketWithOptions": writeTimeout,
+	"SetObjectRetention":      writeTimeout,
+	"SetLegalHold":            writeTimeout,
+	"SetStorageClass":         writeTimeout,
+	"Copy":                    writeTimeout,
+	"Undelete":                writeTimeout,
+	"SetBucketPolicy":         writeTimeout,
+	"SetBucketEncryption":     writeTimeout,
+	"SetBucketLogging":        writeTimeout,
+	"SetBucketReplication":    writeTimeout,
+	"AbortStaleUploads":       writeTimeout,
+	"CreateFolder":            writeTimeout,
+	"DeleteFolder":            writeTimeout,
+	"RenameFolder":            writeTimeout,
+	"List":                    listTimeout,
+	"ListIncompleteUploads":   listTimeout,
+	"GetSignedURL":            signTimeout,
+	"GetScopedCredentials":    signTimeout,
+}
+
+// timeout returns the default timeout of the operation
+func (t DefaultTimeouts) timeout(operation string) time.Duration {
+	switch timeoutClasses[operation] {
+	case readTimeout:
+		return t.Read
+	case writeTimeout:
+		return t.Write
+	case listTimeout:
+		return t.List
+	case signTimeout:
+		return t.Sign
+	default:
+		return 0
+	}
+}
+
+// withTimeout returns ctx with the timeout of the options, or with the default timeout of the operation when
+// ctx has no deadline
+func (t DefaultTimeouts) withTimeout(
+	ctx context.Context,
+	operation string,
+	options callOptions,
+) (context.Context, context.CancelFunc) {
+	if options.timeout <= 0 {
+		if _, ok := ctx.Deadline(); !ok {
+			options.timeout = t.timeout(operation)
+		}
+	}
+
+	return options.withTimeout(ctx)
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultTimeouts(t *testing.T) {
+	ctx := context.Background()
+	backend := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, backend.Write(ctx, "a", []byte("a"), nil))
+
+	deadlines := map[string]time.Duration{}
+	contexts := map[string]context.Context{}
+
+	record := func(ctx context.Context, op *OperationInfo, next func(ctx context.Context) error) error {
+		contexts[op.Name] = ctx
+		if deadline, ok := ctx.Deadline(); ok {
+			deadlines[op.Name] = time.Until(deadline)
+		}
+
+		return next(ctx)
+	}
+
+	storage := newInterceptedCloudStorage(backend, "aws", "bucket", record)
+	storage.timeouts = DefaultTimeouts{Read: time.Minute, Write: 2 * time.Minute, Sign: 3 * time.Minute}
+
+	_, err := storage.Get(ctx, "a")
+	require.NoError(t, err)
+	require.NoError(t, storage.Write(ctx, "b", []byte("b"), nil))
+	_, err = storage.GetSignedURL(ctx, "a", &SignedURLOption{})
+	require.NoError(t, err)
+	_, err = storage.ListIncompleteUploads(ctx, "")
+	require.NoError(t, err)
+
+	require.InDelta(t, time.Minute, deadlines["Get"], float64(time.Second))
+	require.InDelta(t, 2*time.Minute, deadlines["Write"], float64(time.Second))
+	require.InDelta(t, 3*time.Minute, deadlines["GetSignedURL"], float64(time.Second))
+	require.NotContains(t, deadlines, "ListIncompleteUploads")
+
+	// the deadline of the caller and WithTimeout win
+	callerCtx, cancel := context.WithTimeout(ctx, time.Hour)
+	defer cancel()
+
+	_, err = storage.Attributes(callerCtx, "a")
+	require.NoError(t, err)
+	require.InDelta(t, time.Hour, deadlines["Attributes"], float64(time.Second))
+
+	require.NoError(t, storage.Delete(callerCtx, "b"))
+	require.InDelta(t, time.Hour, deadlines["Delete"], float64(time.Second))
+
+	_, err = storage.Get(ctx, "a", WithTimeout(10*time.Second))
+	require.NoError(t, err)
+	require.InDelta(t, 10*time.Second, deadlines["Get"], float64(time.Second))
+
+	// the timeout of a reader lasts until it's closed
+	reader, err := storage.GetReader(ctx, "a")
+	require.NoError(t, err)
+	require.NoError(t, contexts["GetReader"].Err())
+	require.NoError(t, reader.Close())
+	require.Error(t, contexts["GetReader"].Err())
+}
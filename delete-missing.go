@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+)
+
+// DeleteIfExists deletes the object of the key, succeeding when it doesn't exist, e.g. for the cleanup jobs
+// which may run twice. The other errors of Delete are returned.
+func DeleteIfExists(ctx context.Context, storage CloudStorage, key string) error {
+	if err := storage.Delete(ctx, key); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// ignoreMissingDeletesCloudStorage makes Delete succeed for the missing keys, see
+// CloudStorageOption.IgnoreMissingDeletes
+type ignoreMissingDeletesCloudStorage struct {
+	CloudStorage
+}
+
+func newIgnoreMissingDeletesCloudStorage(storage CloudStorage) *ignoreMissingDeletesCloudStorage {
+	return &ignoreMissingDeletesCloudStorage{CloudStorage: storage}
+}
+
+func (ts *ignoreMissingDeletesCloudStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	return DeleteIfExists(ctx, ts.CloudStorage, key)
+}
+
+// Flush flushes the wrapped storage
+func (ts *ignoreMissingDeletesCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteIfExists(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.Write(ctx, "a", []byte("a"), nil))
+
+	require.True(t, errors.Is(storage.Delete(ctx, "missing"), ErrNotFound))
+	require.NoError(t, DeleteIfExists(ctx, storage, "missing"))
+	require.NoError(t, DeleteIfExists(ctx, storage, "a"))
+	require.NoError(t, DeleteIfExists(ctx, storage, "a"))
+
+	_, err := storage.Attributes(ctx, "a")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	// the other errors are returned
+	require.NoError(t, storage.Write(ctx, "held", []byte("held"), nil))
+	require.NoError(t, storage.SetLegalHold(ctx, "held", true))
+	require.True(t, errors.Is(DeleteIfExists(ctx, storage, "held"), ErrPermissionDenied))
+}
+
+func TestIgnoreMissingDeletes(t *testing.T) {
+	ctx := context.Background()
+	storage := newIgnoreMissingDeletesCloudStorage(NewFakeCloudStorage("bucket"))
+
+	require.NoError(t, storage.Write(ctx, "a", []byte("a"), nil))
+	require.NoError(t, storage.Delete(ctx, "a"))
+	require.NoError(t, storage.Delete(ctx, "a"))
+	require.NoError(t, storage.Delete(ctx, "missing"))
+
+	// the conditional deletes still fail for the missing keys
+	require.True(t, errors.Is(storage.DeleteIf(ctx, "a", "1"), ErrNotFound))
+}
@@ -0,0 +1,189 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+)
+
+// DiffKind is the difference reported by Diff for a key
+type DiffKind int
+
+const (
+	// DiffOnlyInSource is an object of the source missing from the destination
+	DiffOnlyInSource DiffKind = iota + 1
+	// DiffOnlyInDestination is an object of the destination missing from the source
+	DiffOnlyInDestination
+	// DiffChanged is an object of both prefixes whose size or MD5 differ
+	DiffChanged
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffOnlyInSource:
+		return "only in source"
+	case DiffOnlyInDestination:
+		return "only in destination"
+	case DiffChanged:
+		return "changed"
+	default:
+		return "unknown"
+	}
+}
+
+// DiffEntry is an object differing between the prefixes compared by Diff
+type DiffEntry struct {
+	// Name is the key relative to the prefixes
+	Name string
+	Kind DiffKind
+	// Source is the object of the source, nil for DiffOnlyInDestination
+	Source *ListObject
+	// Destination is the object of the destination, nil for DiffOnlyInSource
+	Destination *ListObject
+	// Checksum is what differs for DiffChanged, "size" or "MD5"
+	Checksum string
+}
+
+// DiffIterator iterates over the differences found by Diff
+type DiffIterator struct {
+	src       *ListIterator
+	srcPrefix string
+	srcNext   *ListObject
+	srcDone   bool
+
+	dst       *ListIterator
+	dstPrefix string
+	dstNext   *ListObject
+	dstDone   bool
+
+	unchanged int
+}
+
+// Diff compares the objects under srcPrefix of src with the objects under dstPrefix of dst, e.g. to audit the
+// replication between environments. The storages can be different providers.
+// Both listings are walked together in the lexicographic order of the keys, so only a page of each is in memory
+// whatever the number of objects. The objects of both prefixes are changed when their size differs, or their MD5
+// when both listings have it.
+func Diff(
+	ctx context.Context,
+	src CloudStorage,
+	srcPrefix string,
+	dst CloudStorage,
+	dstPrefix string,
+) *DiffIterator {
+	return &DiffIterator{
+		src:       src.List(ctx, srcPrefix),
+		srcPrefix: srcPrefix,
+		dst:       dst.List(ctx, dstPrefix),
+		dstPrefix: dstPrefix,
+	}
+}
+
+// Next returns the next difference, in the order of the names, or io.EOF once both listings are over
+func (i *DiffIterator) Next(ctx context.Context) (*DiffEntry, error) {
+	for {
+		if err := i.fill(ctx); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case i.srcNext == nil && i.dstNext == nil:
+			return nil, io.EOF
+		case i.dstNext == nil:
+			return i.onlyInSource(), nil
+		case i.srcNext == nil:
+			return i.onlyInDestination(), nil
+		}
+
+		srcName := strings.TrimPrefix(i.srcNext.Key, i.srcPrefix)
+		dstName := strings.TrimPrefix(i.dstNext.Key, i.dstPrefix)
+
+		switch {
+		case srcName < dstName:
+			return i.onlyInSource(), nil
+		case srcName > dstName:
+			return i.onlyInDestination(), nil
+		}
+
+		src, dst := i.srcNext, i.dstNext
+		i.srcNext, i.dstNext = nil, nil
+
+		if checksum := diffChecksum(src, dst); checksum != "" {
+			return &DiffEntry{Name: srcName, Kind: DiffChanged, Source: src, Destination: dst, Checksum: checksum}, nil
+		}
+
+		i.unchanged++
+	}
+}
+
+// Unchanged returns the number of identical objects skipped so far
+func (i *DiffIterator) Unchanged() int {
+	return i.unchanged
+}
+
+// fill lists the next object of the listings whose previous object was consumed
+func (i *DiffIterator) fill(ctx context.Context) error {
+	if i.srcNext == nil && !i.srcDone {
+		object, err := i.src.Next(ctx)
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		i.srcNext, i.srcDone = object, err == io.EOF
+	}
+
+	if i.dstNext == nil && !i.dstDone {
+		object, err := i.dst.Next(ctx)
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		i.dstNext, i.dstDone = object, err == io.EOF
+	}
+
+	return nil
+}
+
+func (i *DiffIterator) onlyInSource() *DiffEntry {
+	object := i.srcNext
+	i.srcNext = nil
+
+	return &DiffEntry{Name: strings.TrimPrefix(object.Key, i.srcPrefix), Kind: DiffOnlyInSource, Source: object}
+}
+
+func (i *DiffIterator) onlyInDestination() *DiffEntry {
+	object := i.dstNext
+	i.dstNext = nil
+
+	return &DiffEntry{Name: strings.TrimPrefix(object.Key, i.dstPrefix), Kind: DiffOnlyInDestination, Destination: object}
+}
+
+// diffChecksum returns what differs between the objects, empty when they are identical
+func diffChecksum(src *ListObject, dst *ListObject) string {
+	if src.Size != dst.Size {
+		return "size"
+	}
+
+	if len(src.MD5) > 0 && len(dst.MD5) > 0 && !bytes.Equal(src.MD5, dst.MD5) {
+		return "MD5"
+	}
+
+	return ""
+}
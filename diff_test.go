@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	ctx := context.Background()
+	src := NewFakeCloudStorage("src")
+	dst := NewFakeCloudStorage("dst")
+
+	for key, body := range map[string]string{
+		"prod/a":       "only in source",
+		"prod/b":       "same",
+		"prod/c":       "abc",
+		"prod/d":       "longer",
+		"prod/x/y":     "only in source",
+		"production/z": "outside of the prefix",
+	} {
+		require.NoError(t, src.Write(ctx, key, []byte(body), nil))
+	}
+
+	for key, body := range map[string]string{
+		"staging/b":  "same",
+		"staging/c":  "xyz",
+		"staging/d":  "short",
+		"staging/e":  "only in destination",
+		"staging/x0": "only in destination",
+	} {
+		require.NoError(t, dst.Write(ctx, key, []byte(body), nil))
+	}
+
+	iter := Diff(ctx, src, "prod/", dst, "staging/")
+
+	var entries []string
+
+	for {
+		entry, err := iter.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+
+		entries = append(entries, entry.Kind.String()+" "+entry.Name+" "+entry.Checksum)
+
+		switch entry.Kind {
+		case DiffOnlyInSource:
+			require.Nil(t, entry.Destination)
+			require.Equal(t, "prod/"+entry.Name, entry.Source.Key)
+		case DiffOnlyInDestination:
+			require.Nil(t, entry.Source)
+			require.Equal(t, "staging/"+entry.Name, entry.Destination.Key)
+		default:
+			require.NotNil(t, entry.Source)
+			require.NotNil(t, entry.Destination)
+		}
+	}
+
+	require.Equal(t, []string{
+		"only in source a ",
+		"changed c MD5",
+		"changed d size",
+		"only in destination e ",
+		"only in source x/y ",
+		"only in destination x0 ",
+	}, entries)
+	require.Equal(t, 1, iter.Unchanged())
+
+	_, err := iter.Next(ctx)
+	require.Equal(t, io.EOF, err)
+}
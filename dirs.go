@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io"
+	"strings"
+)
+
+// dirPrefix returns the prefix of the directory, ending with a slash unless it's the root
+func dirPrefix(dir string) string {
+	if dir == "" || strings.HasSuffix(dir, "/") {
+		return dir
+	}
+
+	return dir + "/"
+}
+
+// ListDirs returns the immediate subdirectories of dir, the common prefixes of the keys up to the next slash,
+// e.g. "photos/2020/" for "photos/2020/06/a.jpg" in "photos". The keys are flat, so the whole directory is listed.
+func ListDirs(ctx context.Context, storage CloudStorage, dir string) ([]string, error) {
+	dirs, _, err := listDir(ctx, storage, dir)
+
+	return dirs, err
+}
+
+// ListFiles returns the objects right in dir, without the objects of its subdirectories
+func ListFiles(ctx context.Context, storage CloudStorage, dir string) ([]*ListObject, error) {
+	_, files, err := listDir(ctx, storage, dir)
+
+	return files, err
+}
+
+func listDir(ctx context.Context, storage CloudStorage, dir string) ([]string, []*ListObject, error) {
+	prefix := dirPrefix(dir)
+	iter := storage.List(ctx, prefix)
+
+	var (
+		dirs  []string
+		files []*ListObject
+	)
+
+	for {
+		object, err := iter.Next(ctx)
+		if err == io.EOF {
+			return dirs, files, nil
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		rest := strings.TrimPrefix(object.Key, prefix)
+
+		slash := strings.Index(rest, "/")
+		if slash < 0 {
+			files = append(files, object)
+			continue
+		}
+
+		// the keys are listed in order, so the objects of a subdirectory follow each other
+		sub := prefix + rest[:slash+1]
+		if len(dirs) == 0 || dirs[len(dirs)-1] != sub {
+			dirs = append(dirs, sub)
+		}
+	}
+}
+
+// IsDirEmpty returns true when there is no object under dir, only the first object is listed
+func IsDirEmpty(ctx context.Context, storage CloudStorage, dir string) (bool, error) {
+	_, err := storage.List(ctx, dirPrefix(dir)).Next(ctx)
+	if err == io.EOF {
+		return true, nil
+	}
+
+	return false, err
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListDirs(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	for _, key := range []string{"photos/2020/06/a.jpg", "photos/2020/07/b.jpg", "photos/2021/c.jpg",
+		"photos/cover.jpg", "videos/d.mp4", "readme.txt"} {
+		require.NoError(t, storage.Write(ctx, key, []byte(key), nil))
+	}
+
+	dirs, err := ListDirs(ctx, storage, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"photos/", "videos/"}, dirs)
+
+	dirs, err = ListDirs(ctx, storage, "photos")
+	require.NoError(t, err)
+	require.Equal(t, []string{"photos/2020/", "photos/2021/"}, dirs)
+
+	files, err := ListFiles(ctx, storage, "photos/")
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, "photos/cover.jpg", files[0].Key)
+
+	empty, err := IsDirEmpty(ctx, storage, "photos/2020")
+	require.NoError(t, err)
+	require.False(t, empty)
+
+	// "photos/2" is a prefix of the keys, but not a directory
+	empty, err = IsDirEmpty(ctx, storage, "photos/2")
+	require.NoError(t, err)
+	require.True(t, empty)
+}
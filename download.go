@@ -0,0 +1,349 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" // nolint:gosec
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// DefaultDownloadPartSize is the size of the verified parts of DownloadFile when DownloadOption.PartSize is not set
+const DefaultDownloadPartSize = 16 * 1024 * 1024
+
+// DownloadOption configures DownloadFile
+type DownloadOption struct {
+	// PartSize is the size of the parts downloaded with a range read, checksummed and recorded in the checkpoint.
+	// Defaults to DefaultDownloadPartSize.
+	PartSize int64
+	// CheckpointPath is the file of the checkpoint. Defaults to the destination path with the ".checkpoint" suffix.
+	CheckpointPath string
+	// CheckpointStorage stores the checkpoint as the CheckpointKey object instead of a file, when both are set
+	CheckpointStorage CloudStorage
+	CheckpointKey     string
+	// Progress receives the progress of the download every DefaultProgressInterval, counting the resumed bytes.
+	// The callback of ContextWithProgress is called as well.
+	Progress ProgressFunc
+}
+
+// DownloadReport summarizes DownloadFile
+type DownloadReport struct {
+	Size int64
+	// Resumed is the number of bytes verified from a previous download, which weren't downloaded again
+	Resumed int64
+}
+
+// downloadCheckpoint records the parts written to the destination file
+type downloadCheckpoint struct {
+	Key        string         `json:"key"`
+	Size       int64          `json:"size"`
+	Generation string         `json:"generation,omitempty"`
+	PartSize   int64          `json:"part_size"`
+	Parts      []downloadPart `json:"parts"`
+}
+
+type downloadPart struct {
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// matches returns whether the checkpoint was recorded for the same content of the object
+func (c *downloadCheckpoint) matches(key string, attrs *Attributes, partSize int64) bool {
+	return c.Key == key && c.Size == attrs.Size && c.Generation == attrs.Generation && c.PartSize == partSize
+}
+
+// DownloadFile downloads the object to the file in parts, and records the checksum of every written part in
+// a checkpoint, so an interrupted download resumes from the last part verified against the file instead of
+// starting over, e.g. to pull large archives over flaky links. The download starts over when the object
+// changed. The checkpoint is deleted once the file is complete and matches the MD5 of the object, if known.
+// nolint:funlen
+func DownloadFile(
+	ctx context.Context,
+	storage CloudStorage,
+	key string,
+	path string,
+	opts *DownloadOption,
+) (*DownloadReport, error) {
+	var options DownloadOption
+	if opts != nil {
+		options = *opts
+	}
+
+	if options.PartSize <= 0 {
+		options.PartSize = DefaultDownloadPartSize
+	}
+
+	if options.CheckpointPath == "" {
+		options.CheckpointPath = path + ".checkpoint"
+	}
+
+	attrs, err := storage.Attributes(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint, err := loadDownloadCheckpoint(ctx, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	if checkpoint == nil || !checkpoint.matches(key, attrs, options.PartSize) {
+		checkpoint = &downloadCheckpoint{
+			Key:        key,
+			Size:       attrs.Size,
+			Generation: attrs.Generation,
+			PartSize:   options.PartSize,
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	report := &DownloadReport{Size: attrs.Size}
+
+	// the parts are verified against the file, which may have been written after the last checkpoint or damaged
+	verified, err := verifyDownloadParts(file, checkpoint.Parts)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint.Parts = checkpoint.Parts[:verified]
+
+	for _, part := range checkpoint.Parts {
+		report.Resumed += part.Size
+	}
+
+	if err := file.Truncate(report.Resumed); err != nil {
+		return nil, err
+	}
+
+	var tracker *progressTracker
+
+	if fn := progressFunc(ctx, options.Progress); fn != nil {
+		tracker = newProgressTracker(fn, DefaultProgressInterval, time.Now, "DownloadFile", key, attrs.Size)
+		tracker.resume(report.Resumed)
+	}
+
+	for offset := report.Resumed; offset < attrs.Size; offset += options.PartSize {
+		part, err := fetchDownloadPart(ctx, storage, key, file, offset, options.PartSize, tracker)
+		if err != nil {
+			tracker.finish(err)
+			return nil, err
+		}
+
+		checkpoint.Parts = append(checkpoint.Parts, *part)
+
+		if err := saveDownloadCheckpoint(ctx, &options, checkpoint); err != nil {
+			tracker.finish(err)
+			return nil, err
+		}
+	}
+
+	if err := file.Sync(); err != nil {
+		tracker.finish(err)
+		return nil, err
+	}
+
+	// the last report is sent once the file is verified
+	report, err = verifyDownload(ctx, &options, file, path, attrs, report)
+	tracker.finish(err)
+
+	return report, err
+}
+
+// verifyDownload checks the MD5 of the file, if known, and deletes the checkpoint of the complete download
+func verifyDownload(
+	ctx context.Context,
+	opts *DownloadOption,
+	file *os.File,
+	path string,
+	attrs *Attributes,
+	report *DownloadReport,
+) (*DownloadReport, error) {
+	if len(attrs.MD5) > 0 {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+
+		hash := md5.New() // nolint:gosec
+		if _, err := io.Copy(hash, file); err != nil {
+			return nil, err
+		}
+
+		if !bytes.Equal(hash.Sum(nil), attrs.MD5) {
+			// the next download starts over
+			_ = deleteDownloadCheckpoint(ctx, opts)
+			return nil, fmt.Errorf("%w: the MD5 of '%s' doesn't match the object", ErrChecksumMismatch, path)
+		}
+	}
+
+	return report, deleteDownloadCheckpoint(ctx, opts)
+}
+
+// fetchDownloadPart writes the range of the object at its offset in the file and returns its checksum
+func fetchDownloadPart(
+	ctx context.Context,
+	storage CloudStorage,
+	key string,
+	file *os.File,
+	offset int64,
+	length int64,
+	tracker *progressTracker,
+) (*downloadPart, error) {
+	reader, err := storage.GetRangeReader(ctx, key, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	hash := sha256.New()
+
+	var source io.Reader = io.TeeReader(reader, hash)
+	if tracker != nil {
+		source = &progressCounter{Reader: source, tracker: tracker}
+	}
+
+	n, err := io.Copy(&offsetWriter{file: file, offset: offset}, source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &downloadPart{Offset: offset, Size: n, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// offsetWriter writes to the file from the offset
+type offsetWriter struct {
+	file   *os.File
+	offset int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.file.WriteAt(p, w.offset)
+	w.offset += int64(n)
+
+	return n, err
+}
+
+// verifyDownloadParts returns the number of contiguous parts from the beginning matching the file
+func verifyDownloadParts(file *os.File, parts []downloadPart) (int, error) {
+	var offset int64
+
+	for i, part := range parts {
+		if part.Offset != offset {
+			return i, nil
+		}
+
+		hash := sha256.New()
+
+		n, err := io.Copy(hash, io.NewSectionReader(file, part.Offset, part.Size))
+		if err != nil {
+			return 0, err
+		}
+
+		if n != part.Size || hex.EncodeToString(hash.Sum(nil)) != part.SHA256 {
+			return i, nil
+		}
+
+		offset += part.Size
+	}
+
+	return len(parts), nil
+}
+
+func loadDownloadCheckpoint(ctx context.Context, opts *DownloadOption) (*downloadCheckpoint, error) {
+	var (
+		body []byte
+		err  error
+	)
+
+	if opts.CheckpointStorage != nil && opts.CheckpointKey != "" {
+		body, err = opts.CheckpointStorage.Get(ctx, opts.CheckpointKey)
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+	} else {
+		body, err = ioutil.ReadFile(opts.CheckpointPath)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint downloadCheckpoint
+	if err := json.Unmarshal(body, &checkpoint); err != nil {
+		// a checkpoint cut short by a crash is ignored
+		return nil, nil // nolint:nilerr
+	}
+
+	return &checkpoint, nil
+}
+
+func saveDownloadCheckpoint(ctx context.Context, opts *DownloadOption, checkpoint *downloadCheckpoint) error {
+	body, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	if opts.CheckpointStorage != nil && opts.CheckpointKey != "" {
+		contentType := "application/json"
+
+		return opts.CheckpointStorage.Write(ctx, opts.CheckpointKey, body, &contentType)
+	}
+
+	// the checkpoint is replaced atomically, so a crash never leaves it cut short
+	temporary := opts.CheckpointPath + ".tmp"
+
+	if err := ioutil.WriteFile(temporary, body, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(temporary, opts.CheckpointPath)
+}
+
+func deleteDownloadCheckpoint(ctx context.Context, opts *DownloadOption) error {
+	if opts.CheckpointStorage != nil && opts.CheckpointKey != "" {
+		err := opts.CheckpointStorage.Delete(ctx, opts.CheckpointKey)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	err := os.Remove(opts.CheckpointPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
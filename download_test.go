@@ -0,0 +1,143 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// flakyRangeStorage fails the range reads from the offset while failing is set
+type flakyRangeStorage struct {
+	CloudStorage
+	failing bool
+	from    int64
+}
+
+func (ts *flakyRangeStorage) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset,
+	length int64,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	if ts.failing && offset >= ts.from {
+		return nil, ErrUnavailable
+	}
+
+	return ts.CloudStorage.GetRangeReader(ctx, key, offset, length)
+}
+
+func TestDownloadFile(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "download")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	fake := NewFakeCloudStorage("bucket")
+	storage := &flakyRangeStorage{CloudStorage: fake, failing: true, from: 8}
+	path := filepath.Join(dir, "archive.tar")
+	opts := &DownloadOption{PartSize: 4}
+
+	require.NoError(t, fake.Write(ctx, "archive.tar", []byte("abcdefghij"), nil))
+
+	_, err = DownloadFile(ctx, storage, "archive.tar", path, opts)
+	require.True(t, errors.Is(err, ErrUnavailable))
+
+	_, err = os.Stat(path + ".checkpoint")
+	require.NoError(t, err)
+
+	storage.failing = false
+
+	report, err := DownloadFile(ctx, storage, "archive.tar", path, opts)
+	require.NoError(t, err)
+	require.Equal(t, int64(8), report.Resumed)
+
+	body, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "abcdefghij", string(body))
+
+	_, err = os.Stat(path + ".checkpoint")
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestDownloadFileVerifiesParts(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "download")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	fake := NewFakeCloudStorage("bucket")
+	storage := &flakyRangeStorage{CloudStorage: fake, failing: true, from: 8}
+	path := filepath.Join(dir, "archive.tar")
+	opts := &DownloadOption{PartSize: 4, CheckpointStorage: fake, CheckpointKey: "checkpoints/archive.json"}
+
+	require.NoError(t, fake.Write(ctx, "archive.tar", []byte("abcdefghij"), nil))
+
+	_, err = DownloadFile(ctx, storage, "archive.tar", path, opts)
+	require.Error(t, err)
+
+	// the second part is damaged, it's downloaded again
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	require.NoError(t, err)
+
+	_, err = file.WriteAt([]byte("X"), 5)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	storage.failing = false
+
+	report, err := DownloadFile(ctx, storage, "archive.tar", path, opts)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), report.Resumed)
+
+	body, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "abcdefghij", string(body))
+
+	_, err = fake.Attributes(ctx, "checkpoints/archive.json")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	// the download of a changed object starts over
+	storage.failing = true
+
+	require.NoError(t, fake.Write(ctx, "archive.tar", []byte("0123456789"), nil))
+
+	_, err = DownloadFile(ctx, storage, "archive.tar", path, opts)
+	require.Error(t, err)
+
+	require.NoError(t, fake.Write(ctx, "archive.tar", []byte("0123456789ab"), nil))
+
+	storage.failing = false
+
+	report, err = DownloadFile(ctx, storage, "archive.tar", path, opts)
+	require.NoError(t, err)
+	require.Zero(t, report.Resumed)
+
+	body, err = ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "0123456789ab", string(body))
+}
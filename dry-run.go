@@ -0,0 +1,405 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+// DryRunMutation is a mutation skipped by DryRunCloudStorage
+type DryRunMutation struct {
+	Operation string
+	Key       string
+	// Source is the source key of Copy and the folder of RenameFolder
+	Source string
+	// Size is the number of bytes which would be written, or deleted by Delete and DeleteIf. It's -1 when the
+	// operation doesn't write an object.
+	Size int64
+}
+
+// DryRunOption configures DryRunCloudStorage
+type DryRunOption struct {
+	// OnMutation is called synchronously with every skipped mutation
+	OnMutation func(mutation DryRunMutation)
+	// Logger receives every skipped mutation. They are discarded when it's nil.
+	Logger Logger
+}
+
+// DryRunCloudStorage performs the reads normally but only logs the mutations, so a destructive batch job can be
+// validated before the real run. Delete, DeleteIf, WriteIf and Copy read the objects they'd change, and fail like
+// the real call when they're missing or the condition doesn't hold. BeginUpload, ResumeUpload and Subscribe can't
+// be simulated, they return ErrNotSupported.
+type DryRunCloudStorage struct {
+	CloudStorage
+	opts DryRunOption
+
+	mu        sync.Mutex
+	mutations []DryRunMutation
+}
+
+// NewDryRunCloudStorage returns the storage skipping the mutations of the wrapped storage
+func NewDryRunCloudStorage(storage CloudStorage, opts DryRunOption) *DryRunCloudStorage {
+	opts.Logger = loggerOrNoop(opts.Logger)
+
+	return &DryRunCloudStorage{CloudStorage: storage, opts: opts}
+}
+
+// Mutations returns the mutations skipped so far, in order
+func (ts *DryRunCloudStorage) Mutations() []DryRunMutation {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return append([]DryRunMutation(nil), ts.mutations...)
+}
+
+func (ts *DryRunCloudStorage) skip(mutation DryRunMutation) {
+	ts.mu.Lock()
+	ts.mutations = append(ts.mutations, mutation)
+	ts.mu.Unlock()
+
+	fields := Fields{"operation": mutation.Operation, "key": mutation.Key}
+	if mutation.Source != "" {
+		fields["source"] = mutation.Source
+	}
+
+	if mutation.Size >= 0 {
+		fields["size"] = mutation.Size
+	}
+
+	ts.opts.Logger.Info("dry run: mutation skipped", fields)
+
+	if ts.opts.OnMutation != nil {
+		ts.opts.OnMutation(mutation)
+	}
+}
+
+func (ts *DryRunCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	ts.skip(DryRunMutation{Operation: "Write", Key: key, Size: int64(len(body))})
+
+	return nil
+}
+
+// GetWriter returns a writer discarding the object, the mutation is logged once it's closed
+func (ts *DryRunCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	return &dryRunWriter{storage: ts, key: key}, nil
+}
+
+type dryRunWriter struct {
+	storage *DryRunCloudStorage
+	key     string
+	size    int64
+}
+
+func (w *dryRunWriter) Write(p []byte) (int, error) {
+	w.size += int64(len(p))
+
+	return len(p), nil
+}
+
+func (w *dryRunWriter) Close() error {
+	w.storage.skip(DryRunMutation{Operation: "GetWriter", Key: w.key, Size: w.size})
+
+	return nil
+}
+
+// Upload reads the reader until the end to log the size of the object
+func (ts *DryRunCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	size, err := io.Copy(ioutil.Discard, reader)
+	if err != nil {
+		return err
+	}
+
+	ts.skip(DryRunMutation{Operation: "Upload", Key: key, Size: size})
+
+	return nil
+}
+
+func (ts *DryRunCloudStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	attrs, err := ts.CloudStorage.Attributes(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	ts.skip(DryRunMutation{Operation: "Delete", Key: key, Size: attrs.Size})
+
+	return nil
+}
+
+// WriteIf returns an empty generation, since the object isn't written
+func (ts *DryRunCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	if err := condition.validate(); err != nil {
+		return "", err
+	}
+
+	attrs, err := ts.CloudStorage.Attributes(ctx, key)
+
+	switch {
+	case err != nil && !errors.Is(err, ErrNotFound):
+		return "", err
+	case condition.DoesNotExist && err == nil,
+		condition.Generation != "" && (err != nil || attrs.Generation != condition.Generation):
+		return "", fmt.Errorf("%w: '%s'", ErrPreconditionFailed, key)
+	}
+
+	ts.skip(DryRunMutation{Operation: "WriteIf", Key: key, Size: int64(len(body))})
+
+	return "", nil
+}
+
+func (ts *DryRunCloudStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	attrs, err := ts.CloudStorage.Attributes(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if attrs.Generation != generation {
+		return fmt.Errorf("%w: '%s'", ErrPreconditionFailed, key)
+	}
+
+	ts.skip(DryRunMutation{Operation: "DeleteIf", Key: key, Size: attrs.Size})
+
+	return nil
+}
+
+func (ts *DryRunCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	attrs, err := ts.CloudStorage.Attributes(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+
+	ts.skip(DryRunMutation{Operation: "Copy", Key: dstKey, Source: srcKey, Size: attrs.Size})
+
+	return nil
+}
+
+// SetStorageClass logs the size of the object, which is rewritten
+func (ts *DryRunCloudStorage) SetStorageClass(
+	ctx context.Context,
+	key string,
+	storageClass string,
+) error {
+	attrs, err := ts.CloudStorage.Attributes(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	ts.skip(DryRunMutation{Operation: "SetStorageClass", Key: key, Size: attrs.Size})
+
+	return nil
+}
+
+func (ts *DryRunCloudStorage) SetObjectRetention(
+	ctx context.Context,
+	key string,
+	retention *ObjectRetention,
+) error {
+	ts.skip(DryRunMutation{Operation: "SetObjectRetention", Key: key, Size: -1})
+
+	return nil
+}
+
+func (ts *DryRunCloudStorage) SetLegalHold(
+	ctx context.Context,
+	key string,
+	enabled bool,
+) error {
+	ts.skip(DryRunMutation{Operation: "SetLegalHold", Key: key, Size: -1})
+
+	return nil
+}
+
+func (ts *DryRunCloudStorage) Undelete(
+	ctx context.Context,
+	key string,
+) error {
+	ts.skip(DryRunMutation{Operation: "Undelete", Key: key, Size: -1})
+
+	return nil
+}
+
+func (ts *DryRunCloudStorage) CreateBucket(
+	ctx context.Context,
+	bucketPrefix string,
+	expirationTimeDays int64,
+) error {
+	ts.skip(DryRunMutation{Operation: "CreateBucket", Size: -1})
+
+	return nil
+}
+
+func (ts *DryRunCloudStorage) CreateBucketWithOptions(
+	ctx context.Context,
+	opts *CreateBucketOption,
+) error {
+	ts.skip(DryRunMutation{Operation: "CreateBucketWithOptions", Size: -1})
+
+	return nil
+}
+
+func (ts *DryRunCloudStorage) SetBucketPolicy(
+	ctx context.Context,
+	policy *BucketPolicy,
+) error {
+	ts.skip(DryRunMutation{Operation: "SetBucketPolicy", Size: -1})
+
+	return nil
+}
+
+func (ts *DryRunCloudStorage) SetBucketEncryption(
+	ctx context.Context,
+	encryption *BucketEncryption,
+) error {
+	ts.skip(DryRunMutation{Operation: "SetBucketEncryption", Size: -1})
+
+	return nil
+}
+
+func (ts *DryRunCloudStorage) SetBucketLogging(
+	ctx context.Context,
+	logging *BucketLogging,
+) error {
+	ts.skip(DryRunMutation{Operation: "SetBucketLogging", Size: -1})
+
+	return nil
+}
+
+func (ts *DryRunCloudStorage) SetBucketReplication(
+	ctx context.Context,
+	replication *BucketReplication,
+) error {
+	ts.skip(DryRunMutation{Operation: "SetBucketReplication", Size: -1})
+
+	return nil
+}
+
+func (ts *DryRunCloudStorage) CreateFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	ts.skip(DryRunMutation{Operation: "CreateFolder", Key: folder, Size: -1})
+
+	return nil
+}
+
+func (ts *DryRunCloudStorage) DeleteFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	ts.skip(DryRunMutation{Operation: "DeleteFolder", Key: folder, Size: -1})
+
+	return nil
+}
+
+func (ts *DryRunCloudStorage) RenameFolder(
+	ctx context.Context,
+	folder string,
+	newFolder string,
+) error {
+	ts.skip(DryRunMutation{Operation: "RenameFolder", Key: newFolder, Source: folder, Size: -1})
+
+	return nil
+}
+
+// AbortStaleUploads returns the incomplete uploads which would be aborted, without aborting them
+func (ts *DryRunCloudStorage) AbortStaleUploads(
+	ctx context.Context,
+	olderThan time.Duration,
+) ([]*IncompleteUpload, error) {
+	uploads, err := ts.CloudStorage.ListIncompleteUploads(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []*IncompleteUpload
+
+	for _, upload := range uploads {
+		if time.Since(upload.Initiated) >= olderThan {
+			stale = append(stale, upload)
+			ts.skip(DryRunMutation{Operation: "AbortStaleUploads", Key: upload.Key, Size: -1})
+		}
+	}
+
+	return stale, nil
+}
+
+func (ts *DryRunCloudStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	return nil, fmt.Errorf("%w: the resumable uploads can't be dry run", ErrNotSupported)
+}
+
+func (ts *DryRunCloudStorage) ResumeUpload(
+	ctx context.Context,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	return nil, fmt.Errorf("%w: the resumable uploads can't be dry run", ErrNotSupported)
+}
+
+// Subscribe would add a notification to the bucket
+func (ts *DryRunCloudStorage) Subscribe(
+	ctx context.Context,
+	prefix string,
+) (<-chan ObjectEvent, error) {
+	return nil, fmt.Errorf("%w: Subscribe can't be dry run", ErrNotSupported)
+}
+
+// Flush flushes the wrapped storage
+func (ts *DryRunCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunCloudStorage(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, fake.Write(ctx, "a", []byte("abc"), nil))
+
+	var notified []DryRunMutation
+
+	storage := NewDryRunCloudStorage(fake, DryRunOption{OnMutation: func(mutation DryRunMutation) {
+		notified = append(notified, mutation)
+	}})
+
+	// the reads go through
+	body, err := storage.Get(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, "abc", string(body))
+
+	require.NoError(t, storage.Write(ctx, "b", []byte("bb"), nil))
+	require.NoError(t, storage.Upload(ctx, "c", bytes.NewReader([]byte("cccc")), nil))
+
+	writer, err := storage.GetWriter(ctx, "d")
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("ddddd"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	require.NoError(t, storage.Copy(ctx, "a", "e", nil))
+	require.NoError(t, storage.Delete(ctx, "a"))
+	require.NoError(t, storage.RenameFolder(ctx, "x/", "y/"))
+
+	// the mutations of the missing objects and the failed conditions fail like the real calls
+	require.True(t, errors.Is(storage.Delete(ctx, "missing"), ErrNotFound))
+	require.True(t, errors.Is(storage.Copy(ctx, "missing", "f", nil), ErrNotFound))
+
+	_, err = storage.WriteIf(ctx, "a", []byte("x"), nil, WriteCondition{DoesNotExist: true})
+	require.True(t, errors.Is(err, ErrPreconditionFailed))
+	require.True(t, errors.Is(storage.DeleteIf(ctx, "a", "unknown"), ErrPreconditionFailed))
+
+	_, err = storage.BeginUpload(ctx, "g", nil)
+	require.True(t, errors.Is(err, ErrNotSupported))
+
+	expected := []DryRunMutation{
+		{Operation: "Write", Key: "b", Size: 2},
+		{Operation: "Upload", Key: "c", Size: 4},
+		{Operation: "GetWriter", Key: "d", Size: 5},
+		{Operation: "Copy", Key: "e", Source: "a", Size: 3},
+		{Operation: "Delete", Key: "a", Size: 3},
+		{Operation: "RenameFolder", Key: "y/", Source: "x/", Size: -1},
+	}
+	require.Equal(t, expected, storage.Mutations())
+	require.Equal(t, expected, notified)
+
+	// nothing was changed
+	var keys []string
+
+	iter := fake.List(ctx, "")
+	for {
+		object, err := iter.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+
+		keys = append(keys, object.Key)
+	}
+
+	require.Equal(t, []string{"a"}, keys)
+}
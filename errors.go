@@ -0,0 +1,266 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"gocloud.dev/blob"
+	"gocloud.dev/gcerrors"
+	"google.golang.org/api/googleapi"
+)
+
+var (
+	// ErrNotFound is matched by errors.Is when the object or the bucket doesn't exist
+	ErrNotFound = errors.New("not found")
+	// ErrAlreadyExists is matched by errors.Is when the object or the bucket already exists
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrPermissionDenied is matched by errors.Is when the credentials aren't allowed to perform the operation
+	ErrPermissionDenied = errors.New("permission denied")
+	// ErrUnavailable is matched by errors.Is when the provider can't be reached or fails to handle the request
+	ErrUnavailable = errors.New("unavailable")
+	// ErrPreconditionFailed is matched by errors.Is when the condition of WriteIf or DeleteIf isn't met
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// OperationError is returned by the operations of the CloudStorage created by NewCloudStorage.
+// It gives the context of the failed operation and wraps the underlying error,
+// so errors.Is matches the sentinel errors and errors.As reaches the provider SDK error.
+type OperationError struct {
+	Operation string
+	Bucket    string
+	// Key is empty for the bucket-level operations
+	Key string
+	Err error
+}
+
+func (e *OperationError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("%s on bucket '%s': %v", e.Operation, e.Bucket, e.Err)
+	}
+
+	return fmt.Sprintf("%s '%s' in bucket '%s': %v", e.Operation, e.Key, e.Bucket, e.Err)
+}
+
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// ProviderError gives the details of a provider error, reachable with errors.As, e.g. to quote the request ID
+// in a support ticket. It matches the sentinel error of the provider error, and keeps the SDK error in Err.
+// The fields are empty when they aren't returned by the provider.
+type ProviderError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+	// Code is the error code of the provider, e.g. NoSuchKey on AWS or the reason of the first error on GCP
+	Code    string
+	Message string
+	// RequestID is the x-amz-request-id on AWS, the X-Goog-Request-Id or X-GUploader-UploadID on GCP
+	RequestID string
+	// HostID is the x-amz-id-2 on AWS
+	HostID string
+	Err    error
+
+	sentinel error
+}
+
+func (e *ProviderError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ProviderError) Is(target error) bool {
+	return e.sentinel != nil && target == e.sentinel
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// translateError wraps the errors of the SDKs or matching one of the sentinel errors in a *ProviderError,
+// the other errors are returned as is
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return err
+	}
+
+	details := &ProviderError{Err: err, sentinel: sentinelError(err)}
+
+	var (
+		requestFailure s3.RequestFailure
+		awsFailure     awserr.RequestFailure
+		awsErr         awserr.Error
+		apiErr         *googleapi.Error
+	)
+
+	switch {
+	case errors.As(err, &requestFailure):
+		details.HostID = requestFailure.HostID()
+		fillAWSErrorDetails(details, requestFailure)
+	case errors.As(err, &awsFailure):
+		fillAWSErrorDetails(details, awsFailure)
+	case errors.As(err, &awsErr):
+		details.Code = awsErr.Code()
+		details.Message = awsErr.Message()
+	case errors.As(err, &apiErr):
+		details.StatusCode = apiErr.Code
+		details.Message = apiErr.Message
+
+		if len(apiErr.Errors) > 0 {
+			details.Code = apiErr.Errors[0].Reason
+		}
+
+		details.RequestID = apiErr.Header.Get("X-Goog-Request-Id")
+		if details.RequestID == "" {
+			details.RequestID = apiErr.Header.Get("X-Guploader-Uploadid")
+		}
+	case details.sentinel == nil:
+		return err
+	}
+
+	return details
+}
+
+func fillAWSErrorDetails(details *ProviderError, failure awserr.RequestFailure) {
+	details.StatusCode = failure.StatusCode()
+	details.Code = failure.Code()
+	details.Message = failure.Message()
+	details.RequestID = failure.RequestID()
+}
+
+// bucketErrorAs finds the first error of the chain of err that the driver of the bucket converts to target,
+// e.g. an awserr.Error or a *googleapi.Error, the errors of the SDKs called directly being matched by errors.As
+func bucketErrorAs(bucket *blob.Bucket, err error, target interface{}) bool {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if bucket.ErrorAs(e, target) {
+			return true
+		}
+	}
+
+	return errors.As(err, target)
+}
+
+// errorAs is errors.As, also following the original error of the awserr.Error of the chain, which has no Unwrap
+func errorAs(err error, target interface{}) bool {
+	if errors.As(err, target) {
+		return true
+	}
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.OrigErr() != nil {
+		return errorAs(awsErr.OrigErr(), target)
+	}
+
+	return false
+}
+
+// isNetworkError returns whether the request failed before getting a response, e.g. a timeout or a reset connection
+func isNetworkError(err error) bool {
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		switch awsErr.Code() {
+		case request.ErrCodeRequestError, request.ErrCodeResponseTimeout:
+			return true
+		}
+	}
+
+	var netErr net.Error
+
+	return errorAs(err, &netErr)
+}
+
+func sentinelError(err error) error {
+	switch gcerrors.Code(err) {
+	case gcerrors.NotFound:
+		return ErrNotFound
+	case gcerrors.AlreadyExists:
+		return ErrAlreadyExists
+	case gcerrors.PermissionDenied:
+		return ErrPermissionDenied
+	case gcerrors.FailedPrecondition:
+		return ErrPreconditionFailed
+	}
+
+	if errors.Is(err, storage.ErrObjectNotExist) || errors.Is(err, storage.ErrBucketNotExist) {
+		return ErrNotFound
+	}
+
+	// S3 rejects the conditional writes racing with another write with a 409
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == "ConditionalRequestConflict" {
+		return ErrPreconditionFailed
+	}
+
+	var status int
+
+	var requestFailure awserr.RequestFailure
+	if errors.As(err, &requestFailure) {
+		status = requestFailure.StatusCode()
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		status = apiErr.Code
+	}
+
+	// the legacy ACL requests are rejected on the buckets with the uniform bucket-level access
+	if apiErr != nil && status == http.StatusBadRequest && strings.Contains(apiErr.Message, "uniform bucket-level access") {
+		return ErrNotSupported
+	}
+
+	switch {
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	case status == http.StatusPreconditionFailed:
+		return ErrPreconditionFailed
+	case status == http.StatusConflict:
+		return ErrAlreadyExists
+	case status == http.StatusForbidden || status == http.StatusUnauthorized:
+		return ErrPermissionDenied
+	case status >= http.StatusInternalServerError:
+		return ErrUnavailable
+	}
+
+	if isNetworkError(err) {
+		return ErrUnavailable
+	}
+
+	if errors.As(err, &awsErr) {
+		switch awsErr.Code() {
+		case s3.ErrCodeNoSuchKey, s3.ErrCodeNoSuchBucket, s3.ErrCodeNoSuchUpload, "NotFound":
+			return ErrNotFound
+		case s3.ErrCodeBucketAlreadyExists, s3.ErrCodeBucketAlreadyOwnedByYou:
+			return ErrAlreadyExists
+		case "AccessDenied":
+			return ErrPermissionDenied
+		}
+	}
+
+	return nil
+}
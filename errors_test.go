@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+)
+
+func TestTranslateError(t *testing.T) {
+	require.NoError(t, translateError(nil))
+
+	notFound := translateError(fmt.Errorf("wrapped: %w", storage.ErrObjectNotExist))
+	require.True(t, errors.Is(notFound, ErrNotFound))
+	require.True(t, errors.Is(notFound, storage.ErrObjectNotExist))
+	require.Equal(t, "wrapped: storage: object doesn't exist", notFound.Error())
+
+	forbidden := translateError(awserr.NewRequestFailure(awserr.New("AccessDenied", "denied", nil), 403, "id"))
+	require.True(t, errors.Is(forbidden, ErrPermissionDenied))
+
+	var requestFailure awserr.RequestFailure
+	require.True(t, errors.As(forbidden, &requestFailure))
+
+	require.True(t, errors.Is(translateError(&googleapi.Error{Code: 409}), ErrAlreadyExists))
+	require.True(t, errors.Is(translateError(awserr.New("NoSuchKey", "missing", nil)), ErrNotFound))
+
+	require.True(t, errors.Is(translateError(&googleapi.Error{Code: 412}), ErrPreconditionFailed))
+	require.True(t, errors.Is(translateError(awserr.NewRequestFailure(
+		awserr.New("ConditionalRequestConflict", "conflict", nil), 409, "id")), ErrPreconditionFailed))
+
+	require.True(t, errors.Is(translateError(&googleapi.Error{
+		Code:    400,
+		Message: "Cannot get legacy ACL for a bucket that has uniform bucket-level access enabled.",
+	}), ErrNotSupported))
+	require.False(t, errors.Is(translateError(&googleapi.Error{Code: 400}), ErrNotSupported))
+
+	require.True(t, errors.Is(translateError(&googleapi.Error{Code: 401}), ErrPermissionDenied))
+	require.True(t, errors.Is(translateError(&googleapi.Error{Code: 503}), ErrUnavailable))
+	require.True(t, errors.Is(translateError(&net.OpError{Op: "dial", Err: errors.New("refused")}), ErrUnavailable))
+
+	unknown := errors.New("unknown")
+	require.Equal(t, unknown, translateError(unknown))
+}
+
+func TestAs(t *testing.T) {
+	awsStorage, err := newAWSCloudStorage(context.Background(), "", "us-east-1", "bucket", &CloudStorageOption{})
+	require.NoError(t, err)
+
+	var client *s3.S3
+	require.True(t, awsStorage.As(&client))
+	require.NotNil(t, client)
+
+	var gcsClient *storage.Client
+	require.False(t, awsStorage.As(&gcsClient))
+
+	require.False(t, NewFakeCloudStorage("bucket").As(&client))
+}
+
+func TestErrorAs(t *testing.T) {
+	awsStorage, err := newAWSCloudStorage(context.Background(), "", "us-east-1", "bucket", &CloudStorageOption{})
+	require.NoError(t, err)
+
+	err = &OperationError{
+		Operation: "Get",
+		Bucket:    "bucket",
+		Key:       "key",
+		Err:       translateError(awserr.NewRequestFailure(awserr.New("AccessDenied", "denied", nil), 403, "id")),
+	}
+
+	var awsErr awserr.Error
+	require.True(t, awsStorage.ErrorAs(err, &awsErr))
+	require.Equal(t, "AccessDenied", awsErr.Code())
+
+	var apiErr *googleapi.Error
+	require.False(t, awsStorage.ErrorAs(err, &apiErr))
+
+	fake := NewFakeCloudStorage("bucket")
+	require.True(t, fake.ErrorAs(fmt.Errorf("wrapped: %w", &googleapi.Error{Code: 403}), &apiErr))
+	require.Equal(t, 403, apiErr.Code)
+	require.False(t, fake.ErrorAs(errors.New("unknown"), &apiErr))
+}
+
+type s3RequestFailure struct {
+	awserr.RequestFailure
+	hostID string
+}
+
+func (e *s3RequestFailure) HostID() string {
+	return e.hostID
+}
+
+func TestProviderErrorDetails(t *testing.T) {
+	err := &OperationError{
+		Operation: "Get",
+		Bucket:    "bucket",
+		Key:       "key",
+		Err: translateError(&s3RequestFailure{
+			RequestFailure: awserr.NewRequestFailure(awserr.New("AccessDenied", "Access Denied", nil), 403, "request-id"),
+			hostID:         "host-id",
+		}),
+	}
+
+	var details *ProviderError
+	require.True(t, errors.As(err, &details))
+	require.Equal(t, 403, details.StatusCode)
+	require.Equal(t, "AccessDenied", details.Code)
+	require.Equal(t, "Access Denied", details.Message)
+	require.Equal(t, "request-id", details.RequestID)
+	require.Equal(t, "host-id", details.HostID)
+	require.True(t, errors.Is(err, ErrPermissionDenied))
+
+	gcpErr := translateError(fmt.Errorf("wrapped: %w", &googleapi.Error{
+		Code:    429,
+		Message: "rate limited",
+		Errors:  []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}},
+		Header:  http.Header{"X-Guploader-Uploadid": []string{"upload-id"}},
+	}))
+	require.True(t, errors.As(gcpErr, &details))
+	require.Equal(t, 429, details.StatusCode)
+	require.Equal(t, "rateLimitExceeded", details.Code)
+	require.Equal(t, "upload-id", details.RequestID)
+	require.False(t, errors.Is(gcpErr, ErrNotFound))
+	require.Equal(t, gcpErr, translateError(gcpErr))
+
+	// the errors without details only match their sentinel
+	notFound := translateError(storage.ErrObjectNotExist)
+	require.True(t, errors.As(notFound, &details))
+	require.Zero(t, details.StatusCode)
+	require.True(t, errors.Is(notFound, ErrNotFound))
+}
@@ -0,0 +1,1024 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultFailureThreshold is the number of consecutive failures marking a backend unhealthy
+	DefaultFailureThreshold = 3
+	// DefaultHealthCheckInterval is the interval of the pings of the unhealthy backends
+	DefaultHealthCheckInterval = 30 * time.Second
+)
+
+// FailoverOption configures a FailoverStorage
+type FailoverOption struct {
+	// FailureThreshold is the number of consecutive failures marking a backend unhealthy.
+	// Defaults to DefaultFailureThreshold.
+	FailureThreshold int
+	// HealthCheckInterval is the interval of the pings of the unhealthy backends, which are healthy again
+	// once a ping succeeds. Defaults to DefaultHealthCheckInterval.
+	HealthCheckInterval time.Duration
+	// IsFailure classifies the errors counting as a failure of the backend, the operation is then retried on
+	// the next backend. Defaults to the retryable errors and ErrUnavailable.
+	IsFailure func(err error) bool
+	// ReplicateWrites replays the successful mutations on the other healthy backends, so they can serve
+	// the reads after a failover. The failed replays are logged, they aren't retried.
+	ReplicateWrites bool
+	// FallbackOnNotFound retries the reads of the missing objects on the next backends,
+	// e.g. for the objects written to a secondary while the primary was down
+	FallbackOnNotFound bool
+	// OnHealthChange is called when a backend, by its index, becomes unhealthy or healthy again
+	OnHealthChange func(backend int, healthy bool)
+	// Logger receives the failovers, the failbacks and the failed replays. They are discarded when it's nil.
+	Logger Logger
+}
+
+// isFailoverError is the default FailoverOption.IsFailure
+func isFailoverError(err error) bool {
+	return IsRetryableError(err) || errors.Is(err, ErrUnavailable)
+}
+
+type failoverBackend struct {
+	storage  CloudStorage
+	failures int
+	healthy  bool
+}
+
+// FailoverStorage routes the operations to the first healthy backend, the primary coming first, e.g. to
+// survive the outage of a region with a bucket replicated in another one. A backend is unhealthy after
+// FailureThreshold consecutive failures, and the operations are failed over to the next backend until
+// a health check succeeds, failing back to it.
+//
+// The writes missed by a backend while it was unhealthy aren't replayed, run a Replicator or Sync
+// to catch up. The upload sessions and the subscriptions stay on the backend which created them.
+type FailoverStorage struct {
+	opts FailoverOption
+
+	mu       sync.Mutex
+	backends []*failoverBackend
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+var _ CloudStorage = (*FailoverStorage)(nil)
+
+// NewFailoverStorage returns a FailoverStorage over the primary and the secondaries, in order of preference.
+// It checks the health of the unhealthy backends in background until Close.
+func NewFailoverStorage(primary CloudStorage, secondaries []CloudStorage, opts FailoverOption) *FailoverStorage {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = DefaultFailureThreshold
+	}
+
+	if opts.HealthCheckInterval <= 0 {
+		opts.HealthCheckInterval = DefaultHealthCheckInterval
+	}
+
+	if opts.IsFailure == nil {
+		opts.IsFailure = isFailoverError
+	}
+
+	opts.Logger = loggerOrNoop(opts.Logger)
+
+	ts := &FailoverStorage{
+		opts: opts,
+		stop: make(chan struct{}),
+	}
+
+	for _, storage := range append([]CloudStorage{primary}, secondaries...) {
+		ts.backends = append(ts.backends, &failoverBackend{storage: storage, healthy: true})
+	}
+
+	go ts.checkHealth()
+
+	return ts
+}
+
+// Health returns whether every backend, by index, is healthy
+func (ts *FailoverStorage) Health() []bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	health := make([]bool, len(ts.backends))
+	for i, backend := range ts.backends {
+		health[i] = backend.healthy
+	}
+
+	return health
+}
+
+// CheckHealth pings the unhealthy backends, failing back to them when they answer.
+// It runs every HealthCheckInterval in background.
+func (ts *FailoverStorage) CheckHealth(ctx context.Context) {
+	for i, healthy := range ts.Health() {
+		if healthy {
+			continue
+		}
+
+		if err := ts.backends[i].storage.Ping(ctx); err == nil {
+			ts.setHealth(i, true)
+		}
+	}
+}
+
+func (ts *FailoverStorage) checkHealth() {
+	ticker := time.NewTicker(ts.opts.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ts.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), ts.opts.HealthCheckInterval)
+			ts.CheckHealth(ctx)
+			cancel()
+		}
+	}
+}
+
+func (ts *FailoverStorage) setHealth(i int, healthy bool) {
+	ts.mu.Lock()
+	backend := ts.backends[i]
+	changed := backend.healthy != healthy
+	backend.healthy = healthy
+	backend.failures = 0
+	ts.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	if healthy {
+		ts.opts.Logger.Info("storage backend is healthy again", Fields{"backend": i})
+	} else {
+		ts.opts.Logger.Warn("storage backend is unhealthy, failing over", Fields{"backend": i})
+	}
+
+	if ts.opts.OnHealthChange != nil {
+		ts.opts.OnHealthChange(i, healthy)
+	}
+}
+
+// record counts the consecutive failures of the backend
+func (ts *FailoverStorage) record(i int, err error) {
+	failed := err != nil && ts.opts.IsFailure(err)
+
+	ts.mu.Lock()
+	backend := ts.backends[i]
+
+	if !failed {
+		backend.failures = 0
+		ts.mu.Unlock()
+
+		return
+	}
+
+	backend.failures++
+	unhealthy := backend.healthy && backend.failures >= ts.opts.FailureThreshold
+	ts.mu.Unlock()
+
+	if unhealthy {
+		ts.setHealth(i, false)
+	}
+}
+
+// candidates returns the healthy backends in order, or all of them when none is healthy
+func (ts *FailoverStorage) candidates() []int {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	var candidates []int
+
+	for i, backend := range ts.backends {
+		if backend.healthy {
+			candidates = append(candidates, i)
+		}
+	}
+
+	if len(candidates) == 0 {
+		for i := range ts.backends {
+			candidates = append(candidates, i)
+		}
+	}
+
+	return candidates
+}
+
+// route calls f with the backends in order until it succeeds or fails without a failure of the backend.
+// It returns the index of the last backend called.
+func (ts *FailoverStorage) route(f func(storage CloudStorage) error, read bool) (int, error) {
+	var (
+		i   int
+		err error
+	)
+
+	for _, i = range ts.candidates() {
+		err = f(ts.backends[i].storage)
+		ts.record(i, err)
+
+		if err == nil {
+			return i, nil
+		}
+
+		var aborted *failoverAbortedError
+		if errors.As(err, &aborted) {
+			return i, aborted.err
+		}
+
+		if !ts.opts.IsFailure(err) && !(read && ts.opts.FallbackOnNotFound && errors.Is(err, ErrNotFound)) {
+			return i, err
+		}
+	}
+
+	return i, err
+}
+
+func (ts *FailoverStorage) read(f func(storage CloudStorage) error) error {
+	_, err := ts.route(f, true)
+
+	return err
+}
+
+// write runs the mutation on the first healthy backend and replays it on the others with replay
+func (ts *FailoverStorage) write(
+	ctx context.Context,
+	operation string,
+	key string,
+	f func(storage CloudStorage) error,
+	replay func(from CloudStorage, to CloudStorage) error,
+) error {
+	from, err := ts.route(f, false)
+	if err != nil || !ts.opts.ReplicateWrites {
+		return err
+	}
+
+	ts.replicate(ctx, from, operation, key, replay)
+
+	return nil
+}
+
+func (ts *FailoverStorage) replicate(
+	ctx context.Context,
+	from int,
+	operation string,
+	key string,
+	replay func(from CloudStorage, to CloudStorage) error,
+) {
+	for i, healthy := range ts.Health() {
+		if i == from || !healthy {
+			continue
+		}
+
+		err := replay(ts.backends[from].storage, ts.backends[i].storage)
+		ts.record(i, err)
+
+		if err != nil {
+			ts.opts.Logger.Warn("unable to replicate the write", Fields{
+				"operation": operation,
+				"key":       key,
+				"backend":   i,
+				"error":     err,
+			})
+		}
+	}
+}
+
+// replayWrite is the replay of the mutations by body
+func replayWrite(f func(storage CloudStorage) error) func(from CloudStorage, to CloudStorage) error {
+	return func(from CloudStorage, to CloudStorage) error {
+		return f(to)
+	}
+}
+
+// replayCopy is the replay of the streamed writes, the object is copied from the backend storing it
+func replayCopy(ctx context.Context, key string) func(from CloudStorage, to CloudStorage) error {
+	return func(from CloudStorage, to CloudStorage) error {
+		return copyObject(ctx, from, key, to, key)
+	}
+}
+
+// replayDelete is the replay of the deletions, the objects missing from the other backends are ignored
+func replayDelete(ctx context.Context, key string) func(from CloudStorage, to CloudStorage) error {
+	return func(from CloudStorage, to CloudStorage) error {
+		if err := to.Delete(ctx, key); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+
+		return nil
+	}
+}
+
+func (ts *FailoverStorage) List(
+	ctx context.Context,
+	prefix string,
+	opts ...ListOption,
+) *ListIterator {
+	var (
+		iter  *ListIterator
+		first *ListObject
+	)
+
+	return NewListIterator(ctx, func(nextCtx context.Context) (*ListObject, error) {
+		if iter != nil {
+			return iter.Next(nextCtx)
+		}
+
+		// the listing fails over as long as no object has been returned
+		err := ts.read(func(storage CloudStorage) error {
+			candidate := storage.List(ctx, prefix, opts...)
+
+			object, err := candidate.Next(nextCtx)
+			if err != nil && err != io.EOF {
+				return err
+			}
+
+			iter = candidate
+			first = object
+
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return first, nil
+	})
+}
+
+func (ts *FailoverStorage) Get(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) ([]byte, error) {
+	var body []byte
+
+	err := ts.read(func(storage CloudStorage) (err error) {
+		body, err = storage.Get(ctx, key, opts...)
+		return err
+	})
+
+	return body, err
+}
+
+func (ts *FailoverStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	deleteObject := func(storage CloudStorage) error {
+		return storage.Delete(ctx, key)
+	}
+
+	return ts.write(ctx, "Delete", key, deleteObject, replayDelete(ctx, key))
+}
+
+// CreateBucket creates the bucket on every backend
+func (ts *FailoverStorage) CreateBucket(
+	ctx context.Context,
+	bucketPrefix string,
+	expirationTimeDays int64,
+) error {
+	for _, backend := range ts.backends {
+		if err := backend.storage.CreateBucket(ctx, bucketPrefix, expirationTimeDays); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CreateBucketWithOptions creates the bucket on every backend
+func (ts *FailoverStorage) CreateBucketWithOptions(
+	ctx context.Context,
+	opts *CreateBucketOption,
+) error {
+	for _, backend := range ts.backends {
+		if err := backend.storage.CreateBucketWithOptions(ctx, opts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close stops the health checks and closes the backends
+func (ts *FailoverStorage) Close() {
+	ts.closeOnce.Do(func() {
+		close(ts.stop)
+
+		for _, backend := range ts.backends {
+			backend.storage.Close()
+		}
+	})
+}
+
+func (ts *FailoverStorage) GetSignedURL(
+	ctx context.Context,
+	key string,
+	opts *SignedURLOption,
+) (string, error) {
+	var url string
+
+	err := ts.read(func(storage CloudStorage) (err error) {
+		url, err = storage.GetSignedURL(ctx, key, opts)
+		return err
+	})
+
+	return url, err
+}
+
+func (ts *FailoverStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	write := func(storage CloudStorage) error {
+		return storage.Write(ctx, key, body, contentType, opts...)
+	}
+
+	return ts.write(ctx, "Write", key, write, replayWrite(write))
+}
+
+func (ts *FailoverStorage) Attributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (*Attributes, error) {
+	var attrs *Attributes
+
+	err := ts.read(func(storage CloudStorage) (err error) {
+		attrs, err = storage.Attributes(ctx, key, opts...)
+		return err
+	})
+
+	return attrs, err
+}
+
+func (ts *FailoverStorage) GetReader(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	var reader io.ReadCloser
+
+	err := ts.read(func(storage CloudStorage) (err error) {
+		reader, err = storage.GetReader(ctx, key, opts...)
+		return err
+	})
+
+	return reader, err
+}
+
+func (ts *FailoverStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	var (
+		reader io.ReadCloser
+		attrs  *Attributes
+	)
+
+	err := ts.read(func(storage CloudStorage) (err error) {
+		reader, attrs, err = storage.GetWithAttributes(ctx, key, opts...)
+		return err
+	})
+
+	return reader, attrs, err
+}
+
+func (ts *FailoverStorage) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset,
+	length int64,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	var reader io.ReadCloser
+
+	err := ts.read(func(storage CloudStorage) (err error) {
+		reader, err = storage.GetRangeReader(ctx, key, offset, length, opts...)
+		return err
+	})
+
+	return reader, err
+}
+
+// GetWriter writes to the first healthy backend, the object is copied to the others on Close
+// with ReplicateWrites. The write doesn't fail over once the writer is returned.
+func (ts *FailoverStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	var writer io.WriteCloser
+
+	from, err := ts.route(func(storage CloudStorage) (err error) {
+		writer, err = storage.GetWriter(ctx, key, opts...)
+		return err
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &failoverWriter{WriteCloser: writer, ctx: ctx, key: key, from: from, storage: ts}, nil
+}
+
+// failoverWriter records the result of the write and replicates it on Close
+type failoverWriter struct {
+	io.WriteCloser
+	ctx     context.Context
+	key     string
+	from    int
+	storage *FailoverStorage
+}
+
+func (w *failoverWriter) Close() error {
+	err := w.WriteCloser.Close()
+	w.storage.record(w.from, err)
+
+	if err == nil && w.storage.opts.ReplicateWrites {
+		w.storage.replicate(w.ctx, w.from, "GetWriter", w.key, replayCopy(w.ctx, w.key))
+	}
+
+	return err
+}
+
+// Upload fails over as long as nothing has been read from the reader
+func (ts *FailoverStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	counter := &countingReader{Reader: reader}
+
+	upload := func(storage CloudStorage) error {
+		err := storage.Upload(ctx, key, counter, opts, writeOpts...)
+		if err != nil && counter.count > 0 {
+			// the next backend would store a truncated object
+			return &failoverAbortedError{err: err}
+		}
+
+		return err
+	}
+
+	return ts.write(ctx, "Upload", key, upload, replayCopy(ctx, key))
+}
+
+// failoverAbortedError stops the failover of an operation which can't be retried, and counts as a failure
+type failoverAbortedError struct {
+	err error
+}
+
+func (e *failoverAbortedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *failoverAbortedError) Unwrap() error {
+	return e.err
+}
+
+// SetObjectRetention sets the retention on every backend storing the object with ReplicateWrites
+func (ts *FailoverStorage) SetObjectRetention(
+	ctx context.Context,
+	key string,
+	retention *ObjectRetention,
+) error {
+	set := func(storage CloudStorage) error {
+		return storage.SetObjectRetention(ctx, key, retention)
+	}
+
+	return ts.write(ctx, "SetObjectRetention", key, set, replayWrite(set))
+}
+
+func (ts *FailoverStorage) GetObjectRetention(
+	ctx context.Context,
+	key string,
+) (*ObjectRetention, error) {
+	var retention *ObjectRetention
+
+	err := ts.read(func(storage CloudStorage) (err error) {
+		retention, err = storage.GetObjectRetention(ctx, key)
+		return err
+	})
+
+	return retention, err
+}
+
+func (ts *FailoverStorage) SetLegalHold(
+	ctx context.Context,
+	key string,
+	enabled bool,
+) error {
+	set := func(storage CloudStorage) error {
+		return storage.SetLegalHold(ctx, key, enabled)
+	}
+
+	return ts.write(ctx, "SetLegalHold", key, set, replayWrite(set))
+}
+
+// SetStorageClass rewrites the object with the storage class on every backend storing it with ReplicateWrites.
+// The storage class is named by the provider, so the backends should share it.
+func (ts *FailoverStorage) SetStorageClass(
+	ctx context.Context,
+	key string,
+	storageClass string,
+) error {
+	set := func(storage CloudStorage) error {
+		return storage.SetStorageClass(ctx, key, storageClass)
+	}
+
+	return ts.write(ctx, "SetStorageClass", key, set, replayWrite(set))
+}
+
+// Copy copies the object on every backend storing it with ReplicateWrites.
+func (ts *FailoverStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	copyTo := func(storage CloudStorage) error {
+		return storage.Copy(ctx, srcKey, dstKey, opts)
+	}
+
+	return ts.write(ctx, "Copy", dstKey, copyTo, replayCopy(ctx, dstKey))
+}
+
+// Undelete recovers the object on every backend storing it with ReplicateWrites
+func (ts *FailoverStorage) Undelete(
+	ctx context.Context,
+	key string,
+) error {
+	undelete := func(storage CloudStorage) error {
+		return storage.Undelete(ctx, key)
+	}
+
+	return ts.write(ctx, "Undelete", key, undelete, replayWrite(undelete))
+}
+
+func (ts *FailoverStorage) GetLegalHold(
+	ctx context.Context,
+	key string,
+) (bool, error) {
+	var enabled bool
+
+	err := ts.read(func(storage CloudStorage) (err error) {
+		enabled, err = storage.GetLegalHold(ctx, key)
+		return err
+	})
+
+	return enabled, err
+}
+
+func (ts *FailoverStorage) GetBucketPolicy(
+	ctx context.Context,
+) (*BucketPolicy, error) {
+	var policy *BucketPolicy
+
+	err := ts.read(func(storage CloudStorage) (err error) {
+		policy, err = storage.GetBucketPolicy(ctx)
+		return err
+	})
+
+	return policy, err
+}
+
+func (ts *FailoverStorage) GetScopedCredentials(
+	ctx context.Context,
+	opts *ScopedCredentialsOption,
+) (*ScopedCredentials, error) {
+	var credentials *ScopedCredentials
+
+	err := ts.read(func(storage CloudStorage) (err error) {
+		credentials, err = storage.GetScopedCredentials(ctx, opts)
+		return err
+	})
+
+	return credentials, err
+}
+
+func (ts *FailoverStorage) CreateFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	create := func(storage CloudStorage) error {
+		return storage.CreateFolder(ctx, folder)
+	}
+
+	return ts.write(ctx, "CreateFolder", folder, create, replayWrite(create))
+}
+
+func (ts *FailoverStorage) DeleteFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	remove := func(storage CloudStorage) error {
+		return storage.DeleteFolder(ctx, folder)
+	}
+
+	return ts.write(ctx, "DeleteFolder", folder, remove, replayWrite(remove))
+}
+
+func (ts *FailoverStorage) RenameFolder(
+	ctx context.Context,
+	folder string,
+	newFolder string,
+) error {
+	rename := func(storage CloudStorage) error {
+		return storage.RenameFolder(ctx, folder, newFolder)
+	}
+
+	return ts.write(ctx, "RenameFolder", folder, rename, replayWrite(rename))
+}
+
+func (ts *FailoverStorage) SetBucketPolicy(
+	ctx context.Context,
+	policy *BucketPolicy,
+) error {
+	set := func(storage CloudStorage) error {
+		return storage.SetBucketPolicy(ctx, policy)
+	}
+
+	return ts.write(ctx, "SetBucketPolicy", "", set, replayWrite(set))
+}
+
+func (ts *FailoverStorage) GetBucketEncryption(
+	ctx context.Context,
+) (*BucketEncryption, error) {
+	var encryption *BucketEncryption
+
+	err := ts.read(func(storage CloudStorage) (err error) {
+		encryption, err = storage.GetBucketEncryption(ctx)
+		return err
+	})
+
+	return encryption, err
+}
+
+func (ts *FailoverStorage) SetBucketEncryption(
+	ctx context.Context,
+	encryption *BucketEncryption,
+) error {
+	set := func(storage CloudStorage) error {
+		return storage.SetBucketEncryption(ctx, encryption)
+	}
+
+	return ts.write(ctx, "SetBucketEncryption", "", set, replayWrite(set))
+}
+
+func (ts *FailoverStorage) GetBucketLogging(
+	ctx context.Context,
+) (*BucketLogging, error) {
+	var logging *BucketLogging
+
+	err := ts.read(func(storage CloudStorage) (err error) {
+		logging, err = storage.GetBucketLogging(ctx)
+		return err
+	})
+
+	return logging, err
+}
+
+func (ts *FailoverStorage) SetBucketLogging(
+	ctx context.Context,
+	logging *BucketLogging,
+) error {
+	set := func(storage CloudStorage) error {
+		return storage.SetBucketLogging(ctx, logging)
+	}
+
+	return ts.write(ctx, "SetBucketLogging", "", set, replayWrite(set))
+}
+
+func (ts *FailoverStorage) GetBucketReplication(
+	ctx context.Context,
+) (*BucketReplication, error) {
+	var replication *BucketReplication
+
+	err := ts.read(func(storage CloudStorage) (err error) {
+		replication, err = storage.GetBucketReplication(ctx)
+		return err
+	})
+
+	return replication, err
+}
+
+func (ts *FailoverStorage) SetBucketReplication(
+	ctx context.Context,
+	replication *BucketReplication,
+) error {
+	set := func(storage CloudStorage) error {
+		return storage.SetBucketReplication(ctx, replication)
+	}
+
+	return ts.write(ctx, "SetBucketReplication", "", set, replayWrite(set))
+}
+
+// GetPublicURL returns the public URL of the object on the first healthy backend
+func (ts *FailoverStorage) GetPublicURL(key string) string {
+	return ts.backends[ts.candidates()[0]].storage.GetPublicURL(key)
+}
+
+// As sets target to the client of the first backend providing it, in the order of the backends
+func (ts *FailoverStorage) As(target interface{}) bool {
+	for _, backend := range ts.backends {
+		if backend.storage.As(target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ErrorAs sets target to the error of the first backend converting err, in the order of the backends
+func (ts *FailoverStorage) ErrorAs(err error, target interface{}) bool {
+	for _, backend := range ts.backends {
+		if backend.storage.ErrorAs(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Ping succeeds when a backend answers
+func (ts *FailoverStorage) Ping(ctx context.Context) error {
+	return ts.read(func(storage CloudStorage) error {
+		return storage.Ping(ctx)
+	})
+}
+
+// Subscribe subscribes to the first healthy backend, the subscription doesn't fail over
+func (ts *FailoverStorage) Subscribe(
+	ctx context.Context,
+	prefix string,
+) (<-chan ObjectEvent, error) {
+	var events <-chan ObjectEvent
+
+	err := ts.read(func(storage CloudStorage) (err error) {
+		events, err = storage.Subscribe(ctx, prefix)
+		return err
+	})
+
+	return events, err
+}
+
+func (ts *FailoverStorage) ListIncompleteUploads(
+	ctx context.Context,
+	prefix string,
+) ([]*IncompleteUpload, error) {
+	var uploads []*IncompleteUpload
+
+	err := ts.read(func(storage CloudStorage) (err error) {
+		uploads, err = storage.ListIncompleteUploads(ctx, prefix)
+		return err
+	})
+
+	return uploads, err
+}
+
+// AbortStaleUploads aborts the stale uploads of every backend, the unreachable ones are skipped
+func (ts *FailoverStorage) AbortStaleUploads(
+	ctx context.Context,
+	olderThan time.Duration,
+) ([]*IncompleteUpload, error) {
+	var (
+		aborted []*IncompleteUpload
+		lastErr error
+	)
+
+	for i, backend := range ts.backends {
+		uploads, err := backend.storage.AbortStaleUploads(ctx, olderThan)
+		ts.record(i, err)
+
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		aborted = append(aborted, uploads...)
+	}
+
+	return aborted, lastErr
+}
+
+func (ts *FailoverStorage) Query(
+	ctx context.Context,
+	key string,
+	sql string,
+	format QueryInputFormat,
+) (io.ReadCloser, error) {
+	var reader io.ReadCloser
+
+	err := ts.read(func(storage CloudStorage) (err error) {
+		reader, err = storage.Query(ctx, key, sql, format)
+		return err
+	})
+
+	return reader, err
+}
+
+// WriteIf writes to the first healthy backend, and replays the write without condition on the others
+// with ReplicateWrites, since the generations differ between backends
+func (ts *FailoverStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	var generation string
+
+	writeIf := func(storage CloudStorage) (err error) {
+		generation, err = storage.WriteIf(ctx, key, body, contentType, condition)
+		return err
+	}
+
+	err := ts.write(ctx, "WriteIf", key, writeIf, replayWrite(func(storage CloudStorage) error {
+		return storage.Write(ctx, key, body, contentType)
+	}))
+
+	return generation, err
+}
+
+// DeleteIf deletes from the first healthy backend, and replays the deletion without condition on the others
+// with ReplicateWrites
+func (ts *FailoverStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	deleteIf := func(storage CloudStorage) error {
+		return storage.DeleteIf(ctx, key, generation)
+	}
+
+	return ts.write(ctx, "DeleteIf", key, deleteIf, replayDelete(ctx, key))
+}
+
+// BeginUpload begins the session on the first healthy backend, the completed object isn't replicated
+func (ts *FailoverStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	var session *UploadSession
+
+	_, err := ts.route(func(storage CloudStorage) (err error) {
+		session, err = storage.BeginUpload(ctx, key, opts)
+		return err
+	}, false)
+
+	return session, err
+}
+
+// ResumeUpload resumes the session on the first healthy backend, it must be the one which began it
+func (ts *FailoverStorage) ResumeUpload(
+	ctx context.Context,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	var session *UploadSession
+
+	err := ts.read(func(storage CloudStorage) (err error) {
+		session, err = storage.ResumeUpload(ctx, state)
+		return err
+	})
+
+	return session, err
+}
+
+// Flush flushes the backends implementing Flusher
+func (ts *FailoverStorage) Flush(ctx context.Context) error {
+	for _, backend := range ts.backends {
+		if err := flushStorage(ctx, backend.storage); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
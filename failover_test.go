@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// outageStorage fails the operations with ErrUnavailable while it's down
+type outageStorage struct {
+	CloudStorage
+	down int32
+}
+
+func (ts *outageStorage) setDown(down bool) {
+	if down {
+		atomic.StoreInt32(&ts.down, 1)
+	} else {
+		atomic.StoreInt32(&ts.down, 0)
+	}
+}
+
+func (ts *outageStorage) err() error {
+	if atomic.LoadInt32(&ts.down) == 1 {
+		return ErrUnavailable
+	}
+
+	return nil
+}
+
+func (ts *outageStorage) Get(ctx context.Context, key string, opts ...ReadOption) ([]byte, error) {
+	if err := ts.err(); err != nil {
+		return nil, err
+	}
+
+	return ts.CloudStorage.Get(ctx, key)
+}
+
+func (ts *outageStorage) Write(ctx context.Context, key string, body []byte, contentType *string, opts ...WriteOption) error {
+	if err := ts.err(); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.Write(ctx, key, body, contentType)
+}
+
+func (ts *outageStorage) Upload(ctx context.Context, key string, reader io.Reader, opts *UploadOption, writeOpts ...WriteOption) error {
+	if err := ts.err(); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.Upload(ctx, key, reader, opts)
+}
+
+func (ts *outageStorage) List(ctx context.Context, prefix string, opts ...ListOption) *ListIterator {
+	if err := ts.err(); err != nil {
+		return NewListIterator(ctx, func(ctx context.Context) (*ListObject, error) {
+			return nil, err
+		})
+	}
+
+	return ts.CloudStorage.List(ctx, prefix)
+}
+
+func (ts *outageStorage) Ping(ctx context.Context) error {
+	return ts.err()
+}
+
+func TestFailoverStorage(t *testing.T) {
+	ctx := context.Background()
+	primary := &outageStorage{CloudStorage: NewFakeCloudStorage("primary")}
+	secondary := NewFakeCloudStorage("secondary")
+
+	var changes []bool
+
+	storage := NewFailoverStorage(primary, []CloudStorage{secondary}, FailoverOption{
+		FailureThreshold:   2,
+		ReplicateWrites:    true,
+		FallbackOnNotFound: true,
+		OnHealthChange: func(backend int, healthy bool) {
+			require.Equal(t, 0, backend)
+			changes = append(changes, healthy)
+		},
+	})
+	defer storage.Close()
+
+	// the writes are replicated to the secondary
+	require.NoError(t, storage.Write(ctx, "a.txt", []byte("a"), nil))
+
+	body, err := secondary.Get(ctx, "a.txt")
+	require.NoError(t, err)
+	require.Equal(t, "a", string(body))
+
+	// the operations fail over to the secondary during the outage of the primary
+	primary.setDown(true)
+
+	for i := 0; i < 2; i++ {
+		body, err = storage.Get(ctx, "a.txt")
+		require.NoError(t, err)
+		require.Equal(t, "a", string(body))
+	}
+
+	require.Equal(t, []bool{false, true}, storage.Health())
+	require.NoError(t, storage.Upload(ctx, "b.txt", strings.NewReader("b"), nil))
+
+	body, err = secondary.Get(ctx, "b.txt")
+	require.NoError(t, err)
+	require.Equal(t, "b", string(body))
+
+	objects, err := listByName(ctx, storage, "")
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+
+	// the health check fails back to the primary, the objects it missed are read from the secondary
+	storage.CheckHealth(ctx)
+	require.Equal(t, []bool{false, true}, storage.Health())
+
+	primary.setDown(false)
+	storage.CheckHealth(ctx)
+	require.Equal(t, []bool{true, true}, storage.Health())
+	require.Equal(t, []bool{false, true}, changes)
+
+	body, err = storage.Get(ctx, "b.txt")
+	require.NoError(t, err)
+	require.Equal(t, "b", string(body))
+
+	require.NoError(t, storage.Delete(ctx, "a.txt"))
+
+	_, err = secondary.Get(ctx, "a.txt")
+	require.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestFailoverStorageErrors(t *testing.T) {
+	ctx := context.Background()
+	primary := &outageStorage{CloudStorage: NewFakeCloudStorage("primary")}
+	secondary := &outageStorage{CloudStorage: NewFakeCloudStorage("secondary")}
+	storage := NewFailoverStorage(primary, []CloudStorage{secondary}, FailoverOption{})
+
+	defer storage.Close()
+
+	// the errors of the requests aren't failures of the backends
+	_, err := storage.Get(ctx, "missing.txt")
+	require.True(t, errors.Is(err, ErrNotFound))
+	require.Equal(t, []bool{true, true}, storage.Health())
+
+	// the error of the last backend is returned when they are all down
+	primary.setDown(true)
+	secondary.setDown(true)
+
+	err = storage.Write(ctx, "a.txt", []byte("a"), nil)
+	require.True(t, errors.Is(err, ErrUnavailable))
+	require.Error(t, storage.Ping(ctx))
+}
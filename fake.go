@@ -0,0 +1,1217 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" // nolint:gosec
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const fakeStorageHost = "fake-storage.local"
+
+// FakeCloudStorage is an in-memory CloudStorage for the unit tests of the consumers.
+// It's safe for concurrent use and returns the same sentinel errors as the providers.
+type FakeCloudStorage struct {
+	bucketName string
+	clock      func() time.Time
+
+	mu          sync.RWMutex
+	objects     map[string]*fakeObject
+	policy      BucketPolicy
+	encryption  BucketEncryption
+	logging     BucketLogging
+	replication BucketReplication
+	subscribers map[*fakeSubscriber]bool
+	uploads     map[*fakeWriter]*IncompleteUpload
+	generation  int64
+
+	// folders are the created folders, ending with a slash
+	folders map[string]bool
+
+	// bucketOptions are the options of CreateBucketWithOptions, nil until it's called
+	bucketOptions *CreateBucketOption
+
+	listVisibilityDelay time.Duration
+	deleted             map[string]*fakeTombstone
+
+	// noncurrent are the last deleted versions of the keys when the bucket is created with Versioning
+	noncurrent map[string]*fakeObject
+}
+
+type fakeObject struct {
+	body         []byte
+	attrs        Attributes
+	storageClass string
+	retention    ObjectRetention
+	legalHold    bool
+
+	// visibleAt is the time the object is listed from, the previous version being listed until then
+	visibleAt time.Time
+	previous  *fakeObject
+}
+
+// listed returns the version of the object listed at the time, or nil while a new object isn't visible yet
+func (o *fakeObject) listed(now time.Time) *fakeObject {
+	for o != nil && now.Before(o.visibleAt) {
+		o = o.previous
+	}
+
+	return o
+}
+
+// fakeTombstone keeps listing a deleted object until the deletion becomes visible
+type fakeTombstone struct {
+	object *fakeObject
+	until  time.Time
+}
+
+// NewFakeCloudStorage returns an empty FakeCloudStorage
+func NewFakeCloudStorage(bucketName string) *FakeCloudStorage {
+	return &FakeCloudStorage{
+		bucketName:  bucketName,
+		clock:       time.Now,
+		objects:     make(map[string]*fakeObject),
+		subscribers: make(map[*fakeSubscriber]bool),
+		uploads:     make(map[*fakeWriter]*IncompleteUpload),
+		deleted:     make(map[string]*fakeTombstone),
+		folders:     make(map[string]bool),
+		noncurrent:  make(map[string]*fakeObject),
+	}
+}
+
+// SetClock replaces time.Now for the modification times, the retention and the expiry of the signed URLs.
+// It must be called before using the storage.
+func (ts *FakeCloudStorage) SetClock(clock func() time.Time) {
+	ts.clock = clockOrNow(clock)
+}
+
+// FakeSimulationOption configures the behavior of a real bucket simulated by FakeCloudStorage,
+// so the load and resilience tests can run in-process
+type FakeSimulationOption struct {
+	// Faults injects latency, errors and partial reads into the operations, see FaultInjectingCloudStorage
+	Faults FaultInjectionOption
+	// ListVisibilityDelay delays the writes and the deletes in List, like an eventually consistent listing.
+	// The other operations see them immediately.
+	ListVisibilityDelay time.Duration
+}
+
+// Simulate sets the list visibility delay of the fake and returns it wrapped with the faults.
+// It must be called before using the storage.
+func (ts *FakeCloudStorage) Simulate(opts FakeSimulationOption) *FaultInjectingCloudStorage {
+	ts.listVisibilityDelay = opts.ListVisibilityDelay
+
+	return NewFaultInjectingCloudStorage(ts, opts.Faults)
+}
+
+// delayListing keeps listing the previous version of the key until the object becomes visible
+func (ts *FakeCloudStorage) delayListing(key string, object *fakeObject) {
+	now := ts.clock()
+	object.visibleAt = now.Add(ts.listVisibilityDelay)
+
+	if previous, ok := ts.objects[key]; ok {
+		object.previous = previous.listed(now)
+	} else if tombstone, ok := ts.deleted[key]; ok && now.Before(tombstone.until) {
+		object.previous = tombstone.object
+	}
+
+	// the older versions are never listed again
+	if object.previous != nil {
+		object.previous.previous = nil
+	}
+
+	delete(ts.deleted, key)
+}
+
+// delayDeletion keeps listing the deleted object until the deletion becomes visible
+func (ts *FakeCloudStorage) delayDeletion(key string, object *fakeObject) {
+	now := ts.clock()
+
+	for deletedKey, tombstone := range ts.deleted {
+		if !now.Before(tombstone.until) {
+			delete(ts.deleted, deletedKey)
+		}
+	}
+
+	if listed := object.listed(now); listed != nil {
+		ts.deleted[key] = &fakeTombstone{object: listed, until: now.Add(ts.listVisibilityDelay)}
+	}
+}
+
+func (ts *FakeCloudStorage) error(operation string, key string, err error) error {
+	return &OperationError{
+		Operation: operation,
+		Bucket:    ts.bucketName,
+		Key:       key,
+		Err:       err,
+	}
+}
+
+func (ts *FakeCloudStorage) object(operation string, key string) (*fakeObject, error) {
+	object, ok := ts.objects[key]
+	if !ok {
+		return nil, ts.error(operation, key, ErrNotFound)
+	}
+
+	return object, nil
+}
+
+// store stores the object with the attributes and the storage class of the options
+func (ts *FakeCloudStorage) store(key string, body []byte, contentType string, opts []WriteOption) {
+	_, _ = ts.storeIf("Write", key, body, contentType, writeOptions(opts), nil)
+}
+
+// storeIf stores the object when the condition is met, or unconditionally when it's nil, and returns its generation
+func (ts *FakeCloudStorage) storeIf(
+	operation string,
+	key string,
+	body []byte,
+	contentType string,
+	options callOptions,
+	condition *WriteCondition,
+) (string, error) {
+	if contentType == "" {
+		contentType = options.contentType
+	}
+
+	if contentType == "" {
+		contentType = detectContentType(key, body)
+	}
+
+	sum := md5.Sum(body) // nolint:gosec
+
+	storageClass := options.storageClass
+	if storageClass == "" && ts.bucketOptions != nil {
+		storageClass = ts.bucketOptions.StorageClass
+	}
+
+	if storageClass == "" {
+		storageClass = "STANDARD"
+	}
+
+	// the ETag is the MD5 like the single part uploads of S3, and the CRC32C is computed like GCS
+	object := &fakeObject{
+		body:         append([]byte(nil), body...),
+		storageClass: storageClass,
+		attrs: Attributes{
+			CacheControl:       options.cacheControl,
+			ContentDisposition: options.contentDisposition,
+			ContentType:        contentType,
+			ModTime:            ts.clock(),
+			Size:               int64(len(body)),
+			MD5:                sum[:],
+			ETag:               hex.EncodeToString(sum[:]),
+			CRC32C:             crc32cBytes(crc32.Checksum(body, crc32cTable)),
+		},
+	}
+
+	// the keys are lowercased like the providers
+	if len(options.metadata) > 0 {
+		object.attrs.Metadata = make(map[string]string, len(options.metadata))
+		for k, v := range options.metadata {
+			object.attrs.Metadata[strings.ToLower(k)] = v
+		}
+	}
+
+	ts.mu.Lock()
+
+	previous, exists := ts.objects[key]
+
+	if condition != nil && (condition.DoesNotExist && exists ||
+		!condition.DoesNotExist && (!exists || previous.attrs.Generation != condition.Generation)) {
+		ts.mu.Unlock()
+		return "", ts.error(operation, key, ErrPreconditionFailed)
+	}
+
+	eventType := ObjectCreated
+
+	if exists {
+		object.retention = previous.retention
+		object.legalHold = previous.legalHold
+		eventType = ObjectUpdated
+	}
+
+	ts.generation++
+	object.attrs.Generation = strconv.FormatInt(ts.generation, 10)
+	object.attrs.Encryption = ObjectEncryption{Mode: EncryptionProviderManaged}
+
+	if ts.encryption.KMSKeyName != "" {
+		object.attrs.Encryption = ObjectEncryption{Mode: EncryptionKMS, KeyID: ts.encryption.KMSKeyName}
+	}
+
+	if ts.listVisibilityDelay > 0 {
+		ts.delayListing(key, object)
+	}
+
+	ts.objects[key] = object
+	subscribers := ts.subscribersOf(key)
+	attrs := object.attrs
+
+	ts.mu.Unlock()
+
+	options.written.set(&attrs)
+
+	ts.publish(subscribers, ObjectEvent{Type: eventType, Key: key, Size: object.attrs.Size, Time: object.attrs.ModTime})
+
+	return object.attrs.Generation, nil
+}
+
+func fakeListObject(key string, object *fakeObject) *ListObject {
+	return &ListObject{
+		Key:          key,
+		ModTime:      object.attrs.ModTime,
+		Size:         object.attrs.Size,
+		MD5:          object.attrs.MD5,
+		ETag:         hex.EncodeToString(object.attrs.MD5),
+		StorageClass: object.storageClass,
+	}
+}
+
+// List lists the versions visible at the time, see FakeSimulationOption.ListVisibilityDelay
+func (ts *FakeCloudStorage) List(
+	ctx context.Context,
+	prefix string,
+	opts ...ListOption,
+) *ListIterator {
+	ts.mu.RLock()
+
+	now := ts.clock()
+	objects := make([]*ListObject, 0, len(ts.objects))
+	resumeFrom := listOptions(opts).resumeFrom
+
+	for key, object := range ts.objects {
+		if !strings.HasPrefix(key, prefix) || key <= resumeFrom {
+			continue
+		}
+
+		if listed := object.listed(now); listed != nil {
+			objects = append(objects, fakeListObject(key, listed))
+		}
+	}
+
+	for key, tombstone := range ts.deleted {
+		if strings.HasPrefix(key, prefix) && key > resumeFrom && now.Before(tombstone.until) {
+			objects = append(objects, fakeListObject(key, tombstone.object))
+		}
+	}
+
+	ts.mu.RUnlock()
+
+	sortObjects(objects, listOptions(opts).order)
+
+	return NewListIterator(ctx, func(ctx context.Context) (*ListObject, error) {
+		if len(objects) == 0 {
+			return nil, io.EOF
+		}
+
+		object := objects[0]
+		objects = objects[1:]
+
+		return object, nil
+	})
+}
+
+func (ts *FakeCloudStorage) Get(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) ([]byte, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	object, err := ts.object("Get", key)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(nil), object.body...), nil
+}
+
+// Delete fails with ErrPermissionDenied while the object is under legal hold or retention, as on S3
+func (ts *FakeCloudStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	return ts.deleteIf("Delete", key, "")
+}
+
+// deleteIf deletes the object when it has the generation, or unconditionally when it's empty
+func (ts *FakeCloudStorage) deleteIf(operation string, key string, generation string) error {
+	ts.mu.Lock()
+
+	object, err := ts.object(operation, key)
+	if err != nil {
+		ts.mu.Unlock()
+		return err
+	}
+
+	if generation != "" && object.attrs.Generation != generation {
+		ts.mu.Unlock()
+		return ts.error(operation, key, ErrPreconditionFailed)
+	}
+
+	if object.legalHold || ts.clock().Before(object.retention.RetainUntil) {
+		ts.mu.Unlock()
+		return ts.error(operation, key, ErrPermissionDenied)
+	}
+
+	delete(ts.objects, key)
+	subscribers := ts.subscribersOf(key)
+
+	if ts.bucketOptions != nil && ts.bucketOptions.Versioning {
+		ts.noncurrent[key] = object
+	}
+
+	if ts.listVisibilityDelay > 0 {
+		ts.delayDeletion(key, object)
+	}
+
+	ts.mu.Unlock()
+
+	ts.publish(subscribers, ObjectEvent{Type: ObjectDeleted, Key: key, Time: ts.clock()})
+
+	return nil
+}
+
+func (ts *FakeCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	if err := condition.validate(); err != nil {
+		return "", err
+	}
+
+	var value string
+	if contentType != nil {
+		value = *contentType
+	}
+
+	return ts.storeIf("WriteIf", key, body, value, callOptions{}, &condition)
+}
+
+func (ts *FakeCloudStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	if generation == "" {
+		return ts.error("DeleteIf", key, ErrPreconditionFailed)
+	}
+
+	return ts.deleteIf("DeleteIf", key, generation)
+}
+
+func (ts *FakeCloudStorage) CreateBucket(
+	ctx context.Context,
+	bucketPrefix string,
+	expirationTimeDays int64,
+) error {
+	return nil
+}
+
+// CreateBucketWithOptions records the options, the default storage class, the uniform bucket-level access, the
+// encryption and the logging applying to the fake bucket. It returns ErrAlreadyExists when it's called again.
+func (ts *FakeCloudStorage) CreateBucketWithOptions(
+	ctx context.Context,
+	opts *CreateBucketOption,
+) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.bucketOptions != nil {
+		return ts.error("CreateBucketWithOptions", "", ErrAlreadyExists)
+	}
+
+	created := *opts
+	ts.bucketOptions = &created
+	ts.encryption = BucketEncryption{KMSKeyName: opts.KMSKeyName}
+	if opts.Logging != nil {
+		ts.logging = *opts.Logging
+	}
+
+	if opts.UniformBucketLevelAccess {
+		enabled := true
+		ts.policy.UniformBucketLevelAccess = &enabled
+	}
+
+	return nil
+}
+
+func (ts *FakeCloudStorage) Close() {}
+
+// GetSignedURL returns a URL on a fake host, it can't be requested
+func (ts *FakeCloudStorage) GetSignedURL(
+	ctx context.Context,
+	key string,
+	opts *SignedURLOption,
+) (string, error) {
+	method, err := signedURLMethod(opts)
+	if err != nil {
+		return "", err
+	}
+
+	return withResponseContentDisposition(fmt.Sprintf("%s?method=%s&expires=%d",
+		ts.GetPublicURL(key), method, ts.clock().Add(opts.Expiry).Unix()), opts.ContentDisposition)
+}
+
+func (ts *FakeCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	var value string
+	if contentType != nil {
+		value = *contentType
+	}
+
+	ts.store(key, body, value, opts)
+
+	return nil
+}
+
+func (ts *FakeCloudStorage) Attributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (*Attributes, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	object, err := ts.object("Attributes", key)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := object.attrs
+
+	return &attrs, nil
+}
+
+func (ts *FakeCloudStorage) GetReader(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	return ts.GetRangeReader(ctx, key, 0, -1)
+}
+
+func (ts *FakeCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	object, err := ts.object("GetWithAttributes", key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attrs := object.attrs
+
+	return ioutil.NopCloser(bytes.NewReader(object.body)), &attrs, nil
+}
+
+// GetRangeReader reads up to length bytes from offset, a negative length reads until the end of the object
+// and a negative offset reads the last -offset bytes
+func (ts *FakeCloudStorage) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset,
+	length int64,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	object, err := ts.object("GetRangeReader", key)
+	if err != nil {
+		return nil, err
+	}
+
+	body := sliceRange(object.body, offset, length)
+
+	// the body is never modified in place, so it can be read without holding the lock
+	return ioutil.NopCloser(bytes.NewReader(body)), nil
+}
+
+// GetWriter stores the object when the writer is closed.
+// The writer is listed by ListIncompleteUploads until then.
+func (ts *FakeCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	writer := &fakeWriter{ctx: ctx, key: key, opts: opts, storage: ts}
+
+	ts.mu.Lock()
+	ts.uploads[writer] = &IncompleteUpload{Key: key, UploadID: uuid.New().String(), Initiated: ts.clock()}
+	ts.mu.Unlock()
+
+	return writer, nil
+}
+
+type fakeWriter struct {
+	ctx     context.Context
+	key     string
+	opts    []WriteOption
+	buf     bytes.Buffer
+	storage *FakeCloudStorage
+}
+
+func (w *fakeWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+// Close doesn't store the object when the context of the writer is done, like the providers
+func (w *fakeWriter) Close() error {
+	w.storage.mu.Lock()
+	_, pending := w.storage.uploads[w]
+	delete(w.storage.uploads, w)
+	w.storage.mu.Unlock()
+
+	if !pending {
+		return w.storage.error("GetWriter", w.key, ErrNotFound)
+	}
+
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+
+	w.storage.store(w.key, w.buf.Bytes(), "", w.opts)
+
+	return nil
+}
+
+func (ts *FakeCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return ts.error("Upload", key, err)
+	}
+
+	var contentType string
+	if opts != nil {
+		contentType = opts.ContentType
+	}
+
+	ts.store(key, body, contentType, writeOpts)
+
+	return nil
+}
+
+// BeginUpload uploads the parts as temporary objects, concatenated into the object on completion
+func (ts *FakeCloudStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	return newUploadSession(&objectUploadBackend{storage: ts}, newObjectUploadState(key, opts)), nil
+}
+
+func (ts *FakeCloudStorage) ResumeUpload(
+	ctx context.Context,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	return resumeUploadSession(ctx, &objectUploadBackend{storage: ts}, state)
+}
+
+func (ts *FakeCloudStorage) SetObjectRetention(
+	ctx context.Context,
+	key string,
+	retention *ObjectRetention,
+) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	object, err := ts.object("SetObjectRetention", key)
+	if err != nil {
+		return err
+	}
+
+	object.retention = *retention
+
+	return nil
+}
+
+func (ts *FakeCloudStorage) GetObjectRetention(
+	ctx context.Context,
+	key string,
+) (*ObjectRetention, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	object, err := ts.object("GetObjectRetention", key)
+	if err != nil {
+		return nil, err
+	}
+
+	retention := object.retention
+
+	return &retention, nil
+}
+
+func (ts *FakeCloudStorage) SetLegalHold(
+	ctx context.Context,
+	key string,
+	enabled bool,
+) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	object, err := ts.object("SetLegalHold", key)
+	if err != nil {
+		return err
+	}
+
+	object.legalHold = enabled
+
+	return nil
+}
+
+func (ts *FakeCloudStorage) GetLegalHold(
+	ctx context.Context,
+	key string,
+) (bool, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	object, err := ts.object("GetLegalHold", key)
+	if err != nil {
+		return false, err
+	}
+
+	return object.legalHold, nil
+}
+
+// SetStorageClass rewrites the object with the storage class, which updates its modification time and generation
+// like the providers
+func (ts *FakeCloudStorage) SetStorageClass(
+	ctx context.Context,
+	key string,
+	storageClass string,
+) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	object, err := ts.object("SetStorageClass", key)
+	if err != nil {
+		return err
+	}
+
+	ts.generation++
+
+	rewritten := *object
+	rewritten.storageClass = storageClass
+	rewritten.attrs.ModTime = ts.clock()
+	rewritten.attrs.Generation = strconv.FormatInt(ts.generation, 10)
+	ts.objects[key] = &rewritten
+
+	return nil
+}
+
+// Copy stores a copy of the object, with the attributes of the source or of the options
+func (ts *FakeCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	ts.mu.RLock()
+
+	object, err := ts.object("Copy", srcKey)
+	if err != nil {
+		ts.mu.RUnlock()
+		return err
+	}
+
+	body, attrs := object.body, object.attrs
+
+	ts.mu.RUnlock()
+
+	contentType, writeOpts := opts.writeOptions(dstKey, &attrs)
+	_, err = ts.storeIf("Copy", dstKey, body, contentType, writeOptions(writeOpts), nil)
+
+	return err
+}
+
+// Undelete brings back the last deleted version of the object, with its generation like the removal of a delete
+// marker of S3. The deleted versions are only kept when the bucket is created with Versioning.
+func (ts *FakeCloudStorage) Undelete(
+	ctx context.Context,
+	key string,
+) error {
+	ts.mu.Lock()
+
+	if _, exists := ts.objects[key]; exists {
+		ts.mu.Unlock()
+		return ts.error("Undelete", key, ErrAlreadyExists)
+	}
+
+	object, ok := ts.noncurrent[key]
+	if !ok {
+		ts.mu.Unlock()
+		return ts.error("Undelete", key, ErrNotFound)
+	}
+
+	delete(ts.noncurrent, key)
+
+	restored := *object
+	restored.previous = nil
+
+	if ts.listVisibilityDelay > 0 {
+		ts.delayListing(key, &restored)
+	}
+
+	ts.objects[key] = &restored
+	subscribers := ts.subscribersOf(key)
+
+	ts.mu.Unlock()
+
+	ts.publish(subscribers, ObjectEvent{Type: ObjectCreated, Key: key, Size: restored.attrs.Size, Time: ts.clock()})
+
+	return nil
+}
+
+func (ts *FakeCloudStorage) GetBucketPolicy(
+	ctx context.Context,
+) (*BucketPolicy, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	policy := BucketPolicy{
+		Policy:   ts.policy.Policy,
+		Bindings: make(map[string][]string, len(ts.policy.Bindings)),
+	}
+
+	if ts.policy.UniformBucketLevelAccess != nil {
+		enabled := *ts.policy.UniformBucketLevelAccess
+		policy.UniformBucketLevelAccess = &enabled
+	}
+
+	for role, members := range ts.policy.Bindings {
+		policy.Bindings[role] = append([]string(nil), members...)
+	}
+
+	return &policy, nil
+}
+
+// GetScopedCredentials returns fake credentials, whose AccessToken is "fake:<ro or rw>:<prefix>"
+func (ts *FakeCloudStorage) GetScopedCredentials(
+	ctx context.Context,
+	opts *ScopedCredentialsOption,
+) (*ScopedCredentials, error) {
+	access := "rw"
+	if opts.ReadOnly {
+		access = "ro"
+	}
+
+	duration := opts.Duration
+	if duration <= 0 {
+		duration = DefaultScopedCredentialsDuration
+	}
+
+	return &ScopedCredentials{
+		AccessKeyID:     "FAKE",
+		SecretAccessKey: "fake",
+		SessionToken:    "fake",
+		AccessToken:     "fake:" + access + ":" + opts.Prefix,
+		Expiration:      ts.clock().Add(duration),
+	}, nil
+}
+
+// folderExists returns whether the folder has been created or holds objects, like the folders created
+// implicitly by the writes in a bucket with hierarchical namespace
+func (ts *FakeCloudStorage) folderExists(folder string) bool {
+	if ts.folders[folder] {
+		return true
+	}
+
+	for key := range ts.objects {
+		if strings.HasPrefix(key, folder) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CreateFolder creates the folder and its missing parents
+func (ts *FakeCloudStorage) CreateFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	folder = folderName(folder)
+	if ts.folderExists(folder) {
+		return ts.error("CreateFolder", folder, ErrAlreadyExists)
+	}
+
+	segments := strings.Split(strings.TrimSuffix(folder, "/"), "/")
+	for i := range segments {
+		ts.folders[strings.Join(segments[:i+1], "/")+"/"] = true
+	}
+
+	return nil
+}
+
+// DeleteFolder deletes the folder, it fails with ErrPreconditionFailed if the folder isn't empty
+func (ts *FakeCloudStorage) DeleteFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	folder = folderName(folder)
+	if !ts.folderExists(folder) {
+		return ts.error("DeleteFolder", folder, ErrNotFound)
+	}
+
+	for key := range ts.objects {
+		if strings.HasPrefix(key, folder) {
+			return ts.error("DeleteFolder", folder, ErrPreconditionFailed)
+		}
+	}
+
+	for name := range ts.folders {
+		if name != folder && strings.HasPrefix(name, folder) {
+			return ts.error("DeleteFolder", folder, ErrPreconditionFailed)
+		}
+	}
+
+	delete(ts.folders, folder)
+
+	return nil
+}
+
+// RenameFolder moves the objects and the subfolders of the folder at once
+func (ts *FakeCloudStorage) RenameFolder(
+	ctx context.Context,
+	folder string,
+	newFolder string,
+) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	folder = folderName(folder)
+	newFolder = folderName(newFolder)
+
+	if !ts.folderExists(folder) {
+		return ts.error("RenameFolder", folder, ErrNotFound)
+	}
+
+	if ts.folderExists(newFolder) {
+		return ts.error("RenameFolder", newFolder, ErrAlreadyExists)
+	}
+
+	if strings.HasPrefix(newFolder, folder) {
+		return ts.error("RenameFolder", newFolder, ErrPreconditionFailed)
+	}
+
+	for key, object := range ts.objects {
+		if strings.HasPrefix(key, folder) {
+			delete(ts.objects, key)
+			ts.objects[newFolder+strings.TrimPrefix(key, folder)] = object
+		}
+	}
+
+	for name := range ts.folders {
+		if strings.HasPrefix(name, folder) {
+			delete(ts.folders, name)
+			ts.folders[newFolder+strings.TrimPrefix(name, folder)] = true
+		}
+	}
+
+	ts.folders[newFolder] = true
+
+	return nil
+}
+
+func (ts *FakeCloudStorage) SetBucketPolicy(
+	ctx context.Context,
+	policy *BucketPolicy,
+) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.policy.Policy = policy.Policy
+	if policy.UniformBucketLevelAccess != nil {
+		enabled := *policy.UniformBucketLevelAccess
+		ts.policy.UniformBucketLevelAccess = &enabled
+	}
+
+	if ts.policy.Bindings == nil {
+		ts.policy.Bindings = make(map[string][]string)
+	}
+
+	// only the listed roles are replaced, as on GCP
+	for role, members := range policy.Bindings {
+		ts.policy.Bindings[role] = append([]string(nil), members...)
+	}
+
+	return nil
+}
+
+func (ts *FakeCloudStorage) GetBucketEncryption(
+	ctx context.Context,
+) (*BucketEncryption, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	encryption := ts.encryption
+
+	return &encryption, nil
+}
+
+// SetBucketEncryption records the default encryption. The fake objects aren't encrypted, but the objects written
+// afterwards report it in their Encryption attribute.
+func (ts *FakeCloudStorage) SetBucketEncryption(
+	ctx context.Context,
+	encryption *BucketEncryption,
+) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.encryption = *encryption
+
+	return nil
+}
+
+func (ts *FakeCloudStorage) GetBucketLogging(
+	ctx context.Context,
+) (*BucketLogging, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	logging := ts.logging
+
+	return &logging, nil
+}
+
+// SetBucketLogging records the logging, no access log is written
+func (ts *FakeCloudStorage) SetBucketLogging(
+	ctx context.Context,
+	logging *BucketLogging,
+) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.logging = *logging
+
+	return nil
+}
+
+func (ts *FakeCloudStorage) GetBucketReplication(
+	ctx context.Context,
+) (*BucketReplication, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	replication := ts.replication
+
+	return &replication, nil
+}
+
+// SetBucketReplication records the replication, no object is replicated
+func (ts *FakeCloudStorage) SetBucketReplication(
+	ctx context.Context,
+	replication *BucketReplication,
+) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.replication = *replication
+
+	return nil
+}
+
+func (ts *FakeCloudStorage) GetPublicURL(
+	key string,
+) string {
+	return gcpPublicURL("https", fakeStorageHost, ts.bucketName, key)
+}
+
+// As returns false, the fake storage doesn't have an SDK client
+func (ts *FakeCloudStorage) As(
+	target interface{},
+) bool {
+	return false
+}
+
+func (ts *FakeCloudStorage) ErrorAs(
+	err error,
+	target interface{},
+) bool {
+	return errors.As(err, target)
+}
+
+func (ts *FakeCloudStorage) Ping(
+	ctx context.Context,
+) error {
+	return nil
+}
+
+// fakeSubscriber receives the events of the writes and deletes under its prefix until its context is done
+type fakeSubscriber struct {
+	ctx    context.Context
+	prefix string
+
+	mu     sync.Mutex
+	events chan ObjectEvent
+	closed bool
+}
+
+// Subscribe receives the events of the Write, WriteIf, GetWriter, Upload, Delete and DeleteIf calls.
+// The calls block while the channel is full.
+func (ts *FakeCloudStorage) Subscribe(
+	ctx context.Context,
+	prefix string,
+) (<-chan ObjectEvent, error) {
+	subscriber := &fakeSubscriber{
+		ctx:    ctx,
+		prefix: prefix,
+		events: make(chan ObjectEvent, notificationBufferSize),
+	}
+
+	ts.mu.Lock()
+	ts.subscribers[subscriber] = true
+	ts.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		ts.mu.Lock()
+		delete(ts.subscribers, subscriber)
+		ts.mu.Unlock()
+
+		subscriber.mu.Lock()
+		defer subscriber.mu.Unlock()
+
+		subscriber.closed = true
+		close(subscriber.events)
+	}()
+
+	return subscriber.events, nil
+}
+
+// subscribersOf returns the subscribers of the key, the caller holds ts.mu
+func (ts *FakeCloudStorage) subscribersOf(key string) []*fakeSubscriber {
+	var subscribers []*fakeSubscriber
+
+	for subscriber := range ts.subscribers {
+		if strings.HasPrefix(key, subscriber.prefix) {
+			subscribers = append(subscribers, subscriber)
+		}
+	}
+
+	return subscribers
+}
+
+func (ts *FakeCloudStorage) publish(subscribers []*fakeSubscriber, event ObjectEvent) {
+	for _, subscriber := range subscribers {
+		subscriber.mu.Lock()
+
+		if !subscriber.closed {
+			select {
+			case subscriber.events <- event:
+			case <-subscriber.ctx.Done():
+			}
+		}
+
+		subscriber.mu.Unlock()
+	}
+}
+
+// ListIncompleteUploads returns the writers of GetWriter which aren't closed yet
+func (ts *FakeCloudStorage) ListIncompleteUploads(
+	ctx context.Context,
+	prefix string,
+) ([]*IncompleteUpload, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	var uploads []*IncompleteUpload
+
+	for _, upload := range ts.uploads {
+		if strings.HasPrefix(upload.Key, prefix) {
+			uploads = append(uploads, upload)
+		}
+	}
+
+	sort.Slice(uploads, func(i, j int) bool { return uploads[i].Initiated.Before(uploads[j].Initiated) })
+
+	return uploads, nil
+}
+
+// AbortStaleUploads aborts the writers of GetWriter created before olderThan, their Close fails with ErrNotFound
+func (ts *FakeCloudStorage) AbortStaleUploads(
+	ctx context.Context,
+	olderThan time.Duration,
+) ([]*IncompleteUpload, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	var aborted []*IncompleteUpload
+
+	for writer, upload := range ts.uploads {
+		if ts.clock().Sub(upload.Initiated) >= olderThan {
+			aborted = append(aborted, upload)
+			delete(ts.uploads, writer)
+		}
+	}
+
+	sort.Slice(aborted, func(i, j int) bool { return aborted[i].Initiated.Before(aborted[j].Initiated) })
+
+	return aborted, nil
+}
+
+// Query runs the SQL on the client side, like on GCP
+func (ts *FakeCloudStorage) Query(
+	ctx context.Context,
+	key string,
+	sql string,
+	format QueryInputFormat,
+) (io.ReadCloser, error) {
+	return queryObject(ctx, ts, key, sql, format)
+}
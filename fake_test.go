@@ -0,0 +1,237 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeCloudStorage(t *testing.T) {
+	ctx := context.Background()
+
+	var storage CloudStorage = NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.Write(ctx, "folder/b", []byte("body b"), nil))
+	require.NoError(t, storage.Write(ctx, "folder/a", []byte(`{"key": "value"}`), nil))
+	require.NoError(t, storage.Write(ctx, "other", []byte("other"), nil))
+
+	body, err := storage.Get(ctx, "folder/b")
+	require.NoError(t, err)
+	require.Equal(t, []byte("body b"), body)
+
+	attrs, err := storage.Attributes(ctx, "folder/b")
+	require.NoError(t, err)
+	require.Equal(t, int64(6), attrs.Size)
+	require.Equal(t, "text/plain; charset=utf-8", attrs.ContentType)
+
+	reader, err := storage.GetRangeReader(ctx, "folder/b", 5, 10)
+	require.NoError(t, err)
+
+	body, err = ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, []byte("b"), body)
+
+	iter := storage.List(ctx, "folder/")
+
+	var keys []string
+
+	for {
+		object, err := iter.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+
+		keys = append(keys, object.Key)
+	}
+
+	require.Equal(t, []string{"folder/a", "folder/b"}, keys)
+
+	require.NoError(t, storage.Delete(ctx, "other"))
+
+	_, err = storage.Get(ctx, "other")
+	require.True(t, errors.Is(err, ErrNotFound))
+	require.True(t, errors.Is(storage.Delete(ctx, "other"), ErrNotFound))
+}
+
+func TestFakeCloudStorageWriter(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	writer, err := storage.GetWriter(ctx, "key")
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("body"))
+	require.NoError(t, err)
+
+	_, err = storage.Get(ctx, "key")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	require.NoError(t, writer.Close())
+
+	body, err := storage.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, []byte("body"), body)
+}
+
+func TestFakeCloudStorageIncompleteUploads(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+	now := time.Now()
+
+	storage.SetClock(func() time.Time { return now })
+
+	stale, err := storage.GetWriter(ctx, "uploads/stale.bin")
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Hour)
+
+	recent, err := storage.GetWriter(ctx, "uploads/recent.bin")
+	require.NoError(t, err)
+
+	uploads, err := storage.ListIncompleteUploads(ctx, "uploads/")
+	require.NoError(t, err)
+	require.Len(t, uploads, 2)
+	require.Equal(t, "uploads/stale.bin", uploads[0].Key)
+
+	aborted, err := storage.AbortStaleUploads(ctx, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, aborted, 1)
+	require.Equal(t, "uploads/stale.bin", aborted[0].Key)
+
+	require.True(t, errors.Is(stale.Close(), ErrNotFound))
+	require.NoError(t, recent.Close())
+
+	uploads, err = storage.ListIncompleteUploads(ctx, "")
+	require.NoError(t, err)
+	require.Empty(t, uploads)
+}
+
+func TestFakeCloudStorageObjectLock(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.Write(ctx, "key", []byte("body"), nil))
+	require.NoError(t, storage.SetLegalHold(ctx, "key", true))
+	require.True(t, errors.Is(storage.Delete(ctx, "key"), ErrPermissionDenied))
+
+	require.NoError(t, storage.SetLegalHold(ctx, "key", false))
+	require.NoError(t, storage.SetObjectRetention(ctx, "key", &ObjectRetention{
+		Mode:        RetentionModeGovernance,
+		RetainUntil: time.Now().Add(time.Hour),
+	}))
+	require.True(t, errors.Is(storage.Delete(ctx, "key"), ErrPermissionDenied))
+}
+
+func TestFakeCloudStorageBucketPolicy(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+	enabled := true
+
+	require.NoError(t, storage.SetBucketPolicy(ctx, &BucketPolicy{
+		Bindings:                 map[string][]string{"roles/storage.objectViewer": {"allUsers"}},
+		UniformBucketLevelAccess: &enabled,
+	}))
+
+	// the uniform bucket-level access is left untouched when it isn't set
+	require.NoError(t, storage.SetBucketPolicy(ctx, &BucketPolicy{}))
+
+	policy, err := storage.GetBucketPolicy(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"allUsers"}, policy.Bindings["roles/storage.objectViewer"])
+	require.True(t, *policy.UniformBucketLevelAccess)
+}
+
+func TestFakeCloudStorageListVisibilityDelay(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	fake := NewFakeCloudStorage("bucket")
+	fake.SetClock(func() time.Time { return now })
+	storage := fake.Simulate(FakeSimulationOption{ListVisibilityDelay: time.Minute})
+
+	require.NoError(t, storage.Write(ctx, "a", []byte("1"), nil))
+	require.NoError(t, storage.Write(ctx, "b", []byte("1"), nil))
+
+	// the new objects can be read but aren't listed yet
+	body, err := storage.Get(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, "1", string(body))
+	require.Empty(t, listedKeys(t, storage.List(ctx, "")))
+
+	now = now.Add(time.Minute)
+	require.Equal(t, []string{"a", "b"}, listedKeys(t, storage.List(ctx, "")))
+
+	// the previous version is listed until the overwrite is visible, the deleted object until the deletion is
+	require.NoError(t, storage.Write(ctx, "a", []byte("22"), nil))
+	require.NoError(t, storage.Delete(ctx, "b"))
+
+	_, err = storage.Get(ctx, "b")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	objects := listedObjects(t, storage.List(ctx, ""))
+	require.Len(t, objects, 2)
+	require.Equal(t, int64(1), objects[0].Size)
+	require.Equal(t, "b", objects[1].Key)
+
+	now = now.Add(time.Minute)
+
+	objects = listedObjects(t, storage.List(ctx, ""))
+	require.Len(t, objects, 1)
+	require.Equal(t, "a", objects[0].Key)
+	require.Equal(t, int64(2), objects[0].Size)
+}
+
+func TestFakeCloudStorageSimulatedFaults(t *testing.T) {
+	ctx := context.Background()
+
+	storage := NewFakeCloudStorage("bucket").Simulate(FakeSimulationOption{
+		Faults: FaultInjectionOption{
+			Operations: map[string]Fault{"Write": {ErrorRate: 1}},
+			Seed:       1,
+		},
+	})
+
+	var injected *InjectedFaultError
+	require.True(t, errors.As(storage.Write(ctx, "key", []byte("body"), nil), &injected))
+
+	_, err := storage.Get(ctx, "key")
+	require.True(t, errors.Is(err, ErrNotFound))
+}
+
+func listedObjects(t *testing.T, iter *ListIterator) []*ListObject {
+	var objects []*ListObject
+
+	for {
+		object, err := iter.Next(context.Background())
+		if err == io.EOF {
+			return objects
+		}
+
+		require.NoError(t, err)
+
+		objects = append(objects, object)
+	}
+}
@@ -0,0 +1,733 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Fault configures the misbehavior injected into an operation
+type Fault struct {
+	// Latency is added before the operation, plus a random duration up to Jitter
+	Latency time.Duration
+	Jitter  time.Duration
+	// Distribution adds a latency drawn from a distribution, e.g. LogNormalLatency for the long tail of a real bucket
+	Distribution LatencyDistribution
+	// ErrorRate is the probability, between 0 and 1, of failing the operation with Err without calling the storage
+	ErrorRate float64
+	// Err is the injected error, an *InjectedFaultError by default
+	Err error
+	// PartialReadRate is the probability, between 0 and 1, of the readers failing with io.ErrUnexpectedEOF
+	// after a random part of the object
+	PartialReadRate float64
+}
+
+// LatencyDistribution draws a latency with the random source of the storage
+type LatencyDistribution func(random *rand.Rand) time.Duration
+
+// NormalLatency draws the latencies from a normal distribution, the negative ones being 0
+func NormalLatency(mean, stdDev time.Duration) LatencyDistribution {
+	return func(random *rand.Rand) time.Duration {
+		return positiveDuration(float64(mean) + random.NormFloat64()*float64(stdDev))
+	}
+}
+
+// ExponentialLatency draws the latencies from an exponential distribution of the mean
+func ExponentialLatency(mean time.Duration) LatencyDistribution {
+	return func(random *rand.Rand) time.Duration {
+		return positiveDuration(random.ExpFloat64() * float64(mean))
+	}
+}
+
+// LogNormalLatency draws the latencies from a log-normal distribution of the median, sigma widening the tail,
+// e.g. a sigma of 1 gives a 99th percentile about 10 times the median
+func LogNormalLatency(median time.Duration, sigma float64) LatencyDistribution {
+	return func(random *rand.Rand) time.Duration {
+		return positiveDuration(float64(median) * math.Exp(random.NormFloat64()*sigma))
+	}
+}
+
+func positiveDuration(d float64) time.Duration {
+	if d < 0 {
+		return 0
+	}
+
+	return time.Duration(d)
+}
+
+// FaultInjectionOption configures the faults injected by FaultInjectingCloudStorage
+type FaultInjectionOption struct {
+	// Default applies to the operations missing from Operations
+	Default Fault
+	// Operations overrides the fault per operation name, e.g. "Get"
+	Operations map[string]Fault
+	// Seed makes the injected faults reproducible. A random seed is used when it's zero.
+	Seed int64
+}
+
+// InjectedFaultError is the error injected by default. It's retryable, see IsRetryableError.
+type InjectedFaultError struct {
+	Operation string
+	Key       string
+}
+
+func (e *InjectedFaultError) Error() string {
+	return fmt.Sprintf("injected fault in %s '%s'", e.Operation, e.Key)
+}
+
+// FaultInjectingCloudStorage injects latency, errors and partial reads into the operations of the wrapped CloudStorage,
+// to test the retry and fallback paths of its consumers.
+// Close and GetPublicURL aren't affected since they don't make any request.
+type FaultInjectingCloudStorage struct {
+	CloudStorage
+	opts FaultInjectionOption
+
+	mu     sync.Mutex
+	random *rand.Rand
+}
+
+// NewFaultInjectingCloudStorage wraps the storage with the fault injection
+func NewFaultInjectingCloudStorage(storage CloudStorage, opts FaultInjectionOption) *FaultInjectingCloudStorage {
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &FaultInjectingCloudStorage{
+		CloudStorage: storage,
+		opts:         opts,
+		random:       rand.New(rand.NewSource(seed)), // nolint:gosec
+	}
+}
+
+func (ts *FaultInjectingCloudStorage) fault(operation string) Fault {
+	if fault, ok := ts.opts.Operations[operation]; ok {
+		return fault
+	}
+
+	return ts.opts.Default
+}
+
+// float64 returns a random number in [0, 1), rand.Rand isn't safe for concurrent use
+func (ts *FaultInjectingCloudStorage) float64() float64 {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return ts.random.Float64()
+}
+
+// draw returns a latency of the distribution
+func (ts *FaultInjectingCloudStorage) draw(distribution LatencyDistribution) time.Duration {
+	if distribution == nil {
+		return 0
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return distribution(ts.random)
+}
+
+// inject waits for the latency then returns the injected error, if any
+func (ts *FaultInjectingCloudStorage) inject(ctx context.Context, operation string, key string) error {
+	fault := ts.fault(operation)
+
+	latency := fault.Latency + time.Duration(ts.float64()*float64(fault.Jitter)) + ts.draw(fault.Distribution)
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if fault.ErrorRate <= 0 || ts.float64() >= fault.ErrorRate {
+		return nil
+	}
+
+	if fault.Err != nil {
+		return fault.Err
+	}
+
+	return &InjectedFaultError{Operation: operation, Key: key}
+}
+
+// partialRead returns whether the read of the operation is cut short
+func (ts *FaultInjectingCloudStorage) partialRead(operation string) bool {
+	rate := ts.fault(operation).PartialReadRate
+
+	return rate > 0 && ts.float64() < rate
+}
+
+// cutReader fails the reader after a random part of the size bytes
+func (ts *FaultInjectingCloudStorage) cutReader(reader io.ReadCloser, size int64) io.ReadCloser {
+	return &partialReader{
+		ReadCloser: reader,
+		remaining:  int64(ts.float64() * float64(size)),
+	}
+}
+
+// partialReader fails with io.ErrUnexpectedEOF once the remaining bytes are read, like a dropped connection
+type partialReader struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (r *partialReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+
+	n, err := r.ReadCloser.Read(p)
+	r.remaining -= int64(n)
+
+	return n, err
+}
+
+// List injects the faults into every call of the iterator
+func (ts *FaultInjectingCloudStorage) List(
+	ctx context.Context,
+	prefix string,
+	opts ...ListOption,
+) *ListIterator {
+	iter := ts.CloudStorage.List(ctx, prefix, opts...)
+
+	return NewListIterator(ctx, func(ctx context.Context) (*ListObject, error) {
+		if err := ts.inject(ctx, "List", prefix); err != nil {
+			return nil, err
+		}
+
+		return iter.Next(ctx)
+	})
+}
+
+func (ts *FaultInjectingCloudStorage) Get(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) ([]byte, error) {
+	if err := ts.inject(ctx, "Get", key); err != nil {
+		return nil, err
+	}
+
+	body, err := ts.CloudStorage.Get(ctx, key, opts...)
+	if err == nil && ts.partialRead("Get") {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return body, err
+}
+
+func (ts *FaultInjectingCloudStorage) GetReader(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	if err := ts.inject(ctx, "GetReader", key); err != nil {
+		return nil, err
+	}
+
+	reader, err := ts.CloudStorage.GetReader(ctx, key, opts...)
+	if err != nil || !ts.partialRead("GetReader") {
+		return reader, err
+	}
+
+	// the size is only needed to cut the object at a random offset
+	var size int64
+	if attrs, err := ts.CloudStorage.Attributes(ctx, key); err == nil {
+		size = attrs.Size
+	}
+
+	return ts.cutReader(reader, size), nil
+}
+
+func (ts *FaultInjectingCloudStorage) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset,
+	length int64,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	if err := ts.inject(ctx, "GetRangeReader", key); err != nil {
+		return nil, err
+	}
+
+	reader, err := ts.CloudStorage.GetRangeReader(ctx, key, offset, length, opts...)
+	if err != nil || !ts.partialRead("GetRangeReader") {
+		return reader, err
+	}
+
+	size := length
+	if size < 0 {
+		if attrs, err := ts.CloudStorage.Attributes(ctx, key); err == nil {
+			size = attrs.Size - offset
+		}
+	}
+
+	return ts.cutReader(reader, size), nil
+}
+
+func (ts *FaultInjectingCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	if err := ts.inject(ctx, "GetWithAttributes", key); err != nil {
+		return nil, nil, err
+	}
+
+	reader, attrs, err := ts.CloudStorage.GetWithAttributes(ctx, key, opts...)
+	if err != nil || !ts.partialRead("GetWithAttributes") {
+		return reader, attrs, err
+	}
+
+	return ts.cutReader(reader, attrs.Size), attrs, nil
+}
+
+func (ts *FaultInjectingCloudStorage) Attributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (*Attributes, error) {
+	if err := ts.inject(ctx, "Attributes", key); err != nil {
+		return nil, err
+	}
+
+	return ts.CloudStorage.Attributes(ctx, key, opts...)
+}
+
+func (ts *FaultInjectingCloudStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	if err := ts.inject(ctx, "Delete", key); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.Delete(ctx, key)
+}
+
+func (ts *FaultInjectingCloudStorage) CreateBucket(
+	ctx context.Context,
+	bucketPrefix string,
+	expirationTimeDays int64,
+) error {
+	if err := ts.inject(ctx, "CreateBucket", ""); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.CreateBucket(ctx, bucketPrefix, expirationTimeDays)
+}
+
+func (ts *FaultInjectingCloudStorage) CreateBucketWithOptions(
+	ctx context.Context,
+	opts *CreateBucketOption,
+) error {
+	if err := ts.inject(ctx, "CreateBucketWithOptions", ""); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.CreateBucketWithOptions(ctx, opts)
+}
+
+func (ts *FaultInjectingCloudStorage) GetSignedURL(
+	ctx context.Context,
+	key string,
+	opts *SignedURLOption,
+) (string, error) {
+	if err := ts.inject(ctx, "GetSignedURL", key); err != nil {
+		return "", err
+	}
+
+	return ts.CloudStorage.GetSignedURL(ctx, key, opts)
+}
+
+func (ts *FaultInjectingCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	if err := ts.inject(ctx, "Write", key); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.Write(ctx, key, body, contentType, opts...)
+}
+
+func (ts *FaultInjectingCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	if err := ts.inject(ctx, "GetWriter", key); err != nil {
+		return nil, err
+	}
+
+	return ts.CloudStorage.GetWriter(ctx, key, opts...)
+}
+
+func (ts *FaultInjectingCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	if err := ts.inject(ctx, "Upload", key); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.Upload(ctx, key, reader, opts, writeOpts...)
+}
+
+func (ts *FaultInjectingCloudStorage) SetObjectRetention(
+	ctx context.Context,
+	key string,
+	retention *ObjectRetention,
+) error {
+	if err := ts.inject(ctx, "SetObjectRetention", key); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.SetObjectRetention(ctx, key, retention)
+}
+
+func (ts *FaultInjectingCloudStorage) GetObjectRetention(
+	ctx context.Context,
+	key string,
+) (*ObjectRetention, error) {
+	if err := ts.inject(ctx, "GetObjectRetention", key); err != nil {
+		return nil, err
+	}
+
+	return ts.CloudStorage.GetObjectRetention(ctx, key)
+}
+
+func (ts *FaultInjectingCloudStorage) SetLegalHold(
+	ctx context.Context,
+	key string,
+	enabled bool,
+) error {
+	if err := ts.inject(ctx, "SetLegalHold", key); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.SetLegalHold(ctx, key, enabled)
+}
+
+func (ts *FaultInjectingCloudStorage) GetLegalHold(
+	ctx context.Context,
+	key string,
+) (bool, error) {
+	if err := ts.inject(ctx, "GetLegalHold", key); err != nil {
+		return false, err
+	}
+
+	return ts.CloudStorage.GetLegalHold(ctx, key)
+}
+
+func (ts *FaultInjectingCloudStorage) SetStorageClass(
+	ctx context.Context,
+	key string,
+	storageClass string,
+) error {
+	if err := ts.inject(ctx, "SetStorageClass", key); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.SetStorageClass(ctx, key, storageClass)
+}
+
+func (ts *FaultInjectingCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	if err := ts.inject(ctx, "Copy", dstKey); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.Copy(ctx, srcKey, dstKey, opts)
+}
+
+func (ts *FaultInjectingCloudStorage) Undelete(
+	ctx context.Context,
+	key string,
+) error {
+	if err := ts.inject(ctx, "Undelete", key); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.Undelete(ctx, key)
+}
+
+func (ts *FaultInjectingCloudStorage) GetBucketPolicy(
+	ctx context.Context,
+) (*BucketPolicy, error) {
+	if err := ts.inject(ctx, "GetBucketPolicy", ""); err != nil {
+		return nil, err
+	}
+
+	return ts.CloudStorage.GetBucketPolicy(ctx)
+}
+
+func (ts *FaultInjectingCloudStorage) GetScopedCredentials(
+	ctx context.Context,
+	opts *ScopedCredentialsOption,
+) (*ScopedCredentials, error) {
+	if err := ts.inject(ctx, "GetScopedCredentials", ""); err != nil {
+		return nil, err
+	}
+
+	return ts.CloudStorage.GetScopedCredentials(ctx, opts)
+}
+
+func (ts *FaultInjectingCloudStorage) CreateFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	if err := ts.inject(ctx, "CreateFolder", folder); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.CreateFolder(ctx, folder)
+}
+
+func (ts *FaultInjectingCloudStorage) DeleteFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	if err := ts.inject(ctx, "DeleteFolder", folder); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.DeleteFolder(ctx, folder)
+}
+
+func (ts *FaultInjectingCloudStorage) RenameFolder(
+	ctx context.Context,
+	folder string,
+	newFolder string,
+) error {
+	if err := ts.inject(ctx, "RenameFolder", folder); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.RenameFolder(ctx, folder, newFolder)
+}
+
+func (ts *FaultInjectingCloudStorage) SetBucketPolicy(
+	ctx context.Context,
+	policy *BucketPolicy,
+) error {
+	if err := ts.inject(ctx, "SetBucketPolicy", ""); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.SetBucketPolicy(ctx, policy)
+}
+
+func (ts *FaultInjectingCloudStorage) GetBucketEncryption(
+	ctx context.Context,
+) (*BucketEncryption, error) {
+	if err := ts.inject(ctx, "GetBucketEncryption", ""); err != nil {
+		return nil, err
+	}
+
+	return ts.CloudStorage.GetBucketEncryption(ctx)
+}
+
+func (ts *FaultInjectingCloudStorage) SetBucketEncryption(
+	ctx context.Context,
+	encryption *BucketEncryption,
+) error {
+	if err := ts.inject(ctx, "SetBucketEncryption", ""); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.SetBucketEncryption(ctx, encryption)
+}
+
+func (ts *FaultInjectingCloudStorage) GetBucketLogging(
+	ctx context.Context,
+) (*BucketLogging, error) {
+	if err := ts.inject(ctx, "GetBucketLogging", ""); err != nil {
+		return nil, err
+	}
+
+	return ts.CloudStorage.GetBucketLogging(ctx)
+}
+
+func (ts *FaultInjectingCloudStorage) SetBucketLogging(
+	ctx context.Context,
+	logging *BucketLogging,
+) error {
+	if err := ts.inject(ctx, "SetBucketLogging", ""); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.SetBucketLogging(ctx, logging)
+}
+
+func (ts *FaultInjectingCloudStorage) GetBucketReplication(
+	ctx context.Context,
+) (*BucketReplication, error) {
+	if err := ts.inject(ctx, "GetBucketReplication", ""); err != nil {
+		return nil, err
+	}
+
+	return ts.CloudStorage.GetBucketReplication(ctx)
+}
+
+func (ts *FaultInjectingCloudStorage) SetBucketReplication(
+	ctx context.Context,
+	replication *BucketReplication,
+) error {
+	if err := ts.inject(ctx, "SetBucketReplication", ""); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.SetBucketReplication(ctx, replication)
+}
+
+func (ts *FaultInjectingCloudStorage) Ping(
+	ctx context.Context,
+) error {
+	if err := ts.inject(ctx, "Ping", ""); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.Ping(ctx)
+}
+
+func (ts *FaultInjectingCloudStorage) Subscribe(
+	ctx context.Context,
+	prefix string,
+) (<-chan ObjectEvent, error) {
+	if err := ts.inject(ctx, "Subscribe", prefix); err != nil {
+		return nil, err
+	}
+
+	return ts.CloudStorage.Subscribe(ctx, prefix)
+}
+
+func (ts *FaultInjectingCloudStorage) ListIncompleteUploads(
+	ctx context.Context,
+	prefix string,
+) ([]*IncompleteUpload, error) {
+	if err := ts.inject(ctx, "ListIncompleteUploads", prefix); err != nil {
+		return nil, err
+	}
+
+	return ts.CloudStorage.ListIncompleteUploads(ctx, prefix)
+}
+
+func (ts *FaultInjectingCloudStorage) Query(
+	ctx context.Context,
+	key string,
+	sql string,
+	format QueryInputFormat,
+) (io.ReadCloser, error) {
+	if err := ts.inject(ctx, "Query", key); err != nil {
+		return nil, err
+	}
+
+	return ts.CloudStorage.Query(ctx, key, sql, format)
+}
+
+func (ts *FaultInjectingCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	if err := ts.inject(ctx, "WriteIf", key); err != nil {
+		return "", err
+	}
+
+	return ts.CloudStorage.WriteIf(ctx, key, body, contentType, condition)
+}
+
+func (ts *FaultInjectingCloudStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	if err := ts.inject(ctx, "DeleteIf", key); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.DeleteIf(ctx, key, generation)
+}
+
+func (ts *FaultInjectingCloudStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	if err := ts.inject(ctx, "BeginUpload", key); err != nil {
+		return nil, err
+	}
+
+	return ts.CloudStorage.BeginUpload(ctx, key, opts)
+}
+
+func (ts *FaultInjectingCloudStorage) ResumeUpload(
+	ctx context.Context,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	if state != nil {
+		if err := ts.inject(ctx, "ResumeUpload", state.Key); err != nil {
+			return nil, err
+		}
+	}
+
+	return ts.CloudStorage.ResumeUpload(ctx, state)
+}
+
+func (ts *FaultInjectingCloudStorage) AbortStaleUploads(
+	ctx context.Context,
+	olderThan time.Duration,
+) ([]*IncompleteUpload, error) {
+	if err := ts.inject(ctx, "AbortStaleUploads", ""); err != nil {
+		return nil, err
+	}
+
+	return ts.CloudStorage.AbortStaleUploads(ctx, olderThan)
+}
+
+// Flush flushes the wrapped storage
+func (ts *FaultInjectingCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
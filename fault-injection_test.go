@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaultInjectionErrors(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	require.NoError(t, fake.Write(ctx, "key", []byte("body"), nil))
+
+	storage := NewFaultInjectingCloudStorage(fake, FaultInjectionOption{
+		Operations: map[string]Fault{
+			"Get":    {ErrorRate: 1},
+			"Delete": {ErrorRate: 1, Err: ErrPermissionDenied},
+		},
+	})
+
+	_, err := storage.Get(ctx, "key")
+
+	var faultErr *InjectedFaultError
+	require.True(t, errors.As(err, &faultErr))
+	require.True(t, IsRetryableError(err))
+
+	require.True(t, errors.Is(storage.Delete(ctx, "key"), ErrPermissionDenied))
+
+	attrs, err := storage.Attributes(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, int64(4), attrs.Size)
+}
+
+func TestFaultInjectionRetried(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	require.NoError(t, fake.Write(ctx, "key", []byte("body"), nil))
+
+	faulty := NewFaultInjectingCloudStorage(fake, FaultInjectionOption{
+		Default: Fault{ErrorRate: 0.5},
+		Seed:    1,
+	})
+	storage := newRetryCloudStorage(faulty, RetryPolicy{MaxAttempts: 20, BaseBackoff: time.Millisecond})
+
+	for i := 0; i < 10; i++ {
+		body, err := storage.Get(ctx, "key")
+		require.NoError(t, err)
+		require.Equal(t, []byte("body"), body)
+	}
+}
+
+func TestFaultInjectionLatency(t *testing.T) {
+	storage := NewFaultInjectingCloudStorage(NewFakeCloudStorage("bucket"), FaultInjectionOption{
+		Default: Fault{Latency: time.Hour},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	require.True(t, errors.Is(storage.Ping(ctx), context.DeadlineExceeded))
+}
+
+func TestFaultInjectionPartialRead(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	require.NoError(t, fake.Write(ctx, "key", []byte("0123456789"), nil))
+
+	storage := NewFaultInjectingCloudStorage(fake, FaultInjectionOption{
+		Default: Fault{PartialReadRate: 1},
+	})
+
+	reader, err := storage.GetReader(ctx, "key")
+	require.NoError(t, err)
+
+	body, err := ioutil.ReadAll(reader)
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+	require.Less(t, len(body), 10)
+
+	reader, _, err = storage.GetWithAttributes(ctx, "key")
+	require.NoError(t, err)
+
+	_, err = ioutil.ReadAll(reader)
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestLatencyDistributions(t *testing.T) {
+	random := rand.New(rand.NewSource(1)) // nolint:gosec
+
+	for _, distribution := range []LatencyDistribution{
+		NormalLatency(10*time.Millisecond, 20*time.Millisecond),
+		ExponentialLatency(10 * time.Millisecond),
+		LogNormalLatency(10*time.Millisecond, 1),
+	} {
+		var total time.Duration
+
+		for i := 0; i < 10000; i++ {
+			latency := distribution(random)
+			require.GreaterOrEqual(t, int64(latency), int64(0))
+
+			total += latency
+		}
+
+		// the means are between the median and a few times it
+		mean := total / 10000
+		require.Greater(t, int64(mean), int64(5*time.Millisecond))
+		require.Less(t, int64(mean), int64(30*time.Millisecond))
+	}
+}
+
+func TestFaultInjectionDistribution(t *testing.T) {
+	storage := NewFaultInjectingCloudStorage(NewFakeCloudStorage("bucket"), FaultInjectionOption{
+		Default: Fault{Distribution: NormalLatency(time.Hour, 0)},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	require.True(t, errors.Is(storage.Ping(ctx), context.DeadlineExceeded))
+}
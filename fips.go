@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+)
+
+// awsFIPSHost returns the FIPS 140-2 endpoint host of the service in the region.
+// The SQS endpoints of GovCloud are FIPS endpoints already.
+func awsFIPSHost(service string, region string) string {
+	if service == endpoints.SqsServiceID && strings.HasPrefix(region, "us-gov-") {
+		return fmt.Sprintf("sqs.%s.amazonaws.com", region)
+	}
+
+	return fmt.Sprintf("%s-fips.%s.amazonaws.com", service, region)
+}
+
+// awsFIPSResolver resolves S3 and SQS to their FIPS endpoints, the version of the SDK in use predating
+// the UseFIPSEndpoint setting and listing few of them. The other services are resolved by the default resolver.
+func awsFIPSResolver() endpoints.Resolver {
+	return endpoints.ResolverFunc(func(service, region string, opts ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
+		if service != endpoints.S3ServiceID && service != endpoints.SqsServiceID {
+			return endpoints.DefaultResolver().EndpointFor(service, region, opts...)
+		}
+
+		return endpoints.ResolvedEndpoint{
+			URL:           "https://" + awsFIPSHost(service, region),
+			PartitionID:   "aws",
+			SigningRegion: region,
+			SigningName:   service,
+			SigningMethod: "v4",
+		}, nil
+	})
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSFIPSResolver(t *testing.T) {
+	resolver := awsFIPSResolver()
+
+	endpoint, err := resolver.EndpointFor("s3", "us-east-1")
+	require.NoError(t, err)
+	require.Equal(t, "https://s3-fips.us-east-1.amazonaws.com", endpoint.URL)
+	require.Equal(t, "us-east-1", endpoint.SigningRegion)
+
+	endpoint, err = resolver.EndpointFor("sqs", "us-west-2")
+	require.NoError(t, err)
+	require.Equal(t, "https://sqs-fips.us-west-2.amazonaws.com", endpoint.URL)
+
+	endpoint, err = resolver.EndpointFor("sqs", "us-gov-west-1")
+	require.NoError(t, err)
+	require.Equal(t, "https://sqs.us-gov-west-1.amazonaws.com", endpoint.URL)
+
+	// the other services keep their default endpoints
+	endpoint, err = resolver.EndpointFor("sts", "us-east-1")
+	require.NoError(t, err)
+	require.Equal(t, "https://sts.amazonaws.com", endpoint.URL)
+
+	require.Equal(t, "https://bucket.s3-fips.us-east-1.amazonaws.com/a%20b.txt",
+		awsPublicURL("", "us-east-1", "bucket", false, true, "a b.txt"))
+	require.Equal(t, "https://s3-fips.us-east-1.amazonaws.com/my.bucket/a.txt",
+		awsPublicURL("", "us-east-1", "my.bucket", false, true, "a.txt"))
+}
+
+func TestAWSEndpointConflicts(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := newAWSCloudStorage(ctx, "", "us-east-1", "bucket", &CloudStorageOption{
+		AWSEnableS3Accelerate: true,
+		AWSUseFIPSEndpoint:    true,
+	})
+	require.Error(t, err)
+
+	_, err = newAWSCloudStorage(ctx, "", "us-east-1", "my.bucket", &CloudStorageOption{AWSEnableS3Accelerate: true})
+	require.Error(t, err)
+
+	storage, err := newAWSCloudStorage(ctx, "", "us-east-1", "bucket", &CloudStorageOption{AWSEnableS3Accelerate: true})
+	require.NoError(t, err)
+	require.Equal(t, "https://bucket.s3-accelerate.amazonaws.com/a.txt", storage.GetPublicURL("a.txt"))
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"crypto/md5" // nolint:gosec
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FixityOption configures VerifyPrefix
+type FixityOption struct {
+	// Concurrency is the number of objects verified in parallel, DefaultGetManyConcurrency when it isn't positive
+	Concurrency int
+	// SHA256MetadataKey is the metadata key of the hex SHA-256 stored by the application with the objects,
+	// which is verified as well when it's set
+	SHA256MetadataKey string
+}
+
+// FixityReport is the result of VerifyPrefix
+type FixityReport struct {
+	// Verified is the number of objects matching all their checksums
+	Verified int
+	// Mismatches are the objects whose content doesn't match one of their checksums, sorted by key
+	Mismatches []*IntegrityError
+	// Unverified are the keys of the objects without any checksum to compare, e.g. the S3 multipart uploads
+	// without the SHA-256 metadata, sorted
+	Unverified []string
+	// Errors are the errors of the objects which couldn't be read, by key
+	Errors map[string]error
+}
+
+// OK returns whether every object has been read and matches its checksums
+func (r *FixityReport) OK() bool {
+	return len(r.Mismatches) == 0 && len(r.Unverified) == 0 && len(r.Errors) == 0
+}
+
+// VerifyPrefix downloads every object under the prefix and compares its content with the checksums stored with
+// it, for the integrity audits of the archives. The MD5 of the provider is compared when it's available, i.e.
+// not for the S3 multipart uploads and the GCS composite objects, the CRC32C when it's available, and the
+// SHA-256 metadata of the options.
+// The error is only returned when the objects can't be listed, the failures of the objects are in the report.
+func VerifyPrefix(ctx context.Context, storage CloudStorage, prefix string, opts *FixityOption) (*FixityReport, error) {
+	options := FixityOption{}
+	if opts != nil {
+		options = *opts
+	}
+
+	var (
+		mu     sync.Mutex
+		report = &FixityReport{Errors: make(map[string]error)}
+	)
+
+	err := ForEachObject(ctx, storage, prefix, func(ctx context.Context, object *ListObject) error {
+		verified, err := verifyFixity(ctx, storage, object.Key, options.SHA256MetadataKey)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if verified {
+			report.Verified++
+		} else {
+			report.Unverified = append(report.Unverified, object.Key)
+		}
+
+		return nil
+	}, &ForEachOption{Concurrency: options.Concurrency, CollectErrors: true})
+
+	var forEachErr *ForEachError
+
+	switch {
+	case errors.As(err, &forEachErr):
+		for key, err := range forEachErr.Errors {
+			var integrityErr *IntegrityError
+			if errors.As(err, &integrityErr) {
+				report.Mismatches = append(report.Mismatches, integrityErr)
+			} else {
+				report.Errors[key] = err
+			}
+		}
+	case err != nil:
+		return nil, err
+	}
+
+	sort.Slice(report.Mismatches, func(i, j int) bool {
+		return report.Mismatches[i].Key < report.Mismatches[j].Key
+	})
+	sort.Strings(report.Unverified)
+
+	return report, nil
+}
+
+// fixityChecksum is a hex checksum of an object, the expected one being empty when it isn't available
+type fixityChecksum struct {
+	name     string
+	expected string
+	actual   string
+}
+
+// metadataValue returns the metadata value of the key, the providers returning lowercase keys
+func metadataValue(metadata map[string]string, key string) string {
+	if value, ok := metadata[key]; ok {
+		return value
+	}
+
+	return metadata[strings.ToLower(key)]
+}
+
+// verifyFixity reads the object and returns an *IntegrityError if it doesn't match one of its checksums,
+// or false if it has none
+func verifyFixity(ctx context.Context, storage CloudStorage, key string, sha256MetadataKey string) (bool, error) {
+	reader, attrs, err := storage.GetWithAttributes(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	defer reader.Close()
+
+	var (
+		md5Hash    = md5.New() // nolint:gosec
+		crc32cHash = crc32.New(crc32cTable)
+		sha256Hash = sha256.New()
+	)
+
+	if _, err := io.Copy(io.MultiWriter(md5Hash, crc32cHash, sha256Hash), reader); err != nil {
+		return false, err
+	}
+
+	checksums := []fixityChecksum{
+		{name: "MD5", expected: hex.EncodeToString(attrs.MD5), actual: hex.EncodeToString(md5Hash.Sum(nil))},
+		{name: "CRC32C", expected: hex.EncodeToString(attrs.CRC32C), actual: hex.EncodeToString(crc32cHash.Sum(nil))},
+	}
+
+	if stored := metadataValue(attrs.Metadata, sha256MetadataKey); sha256MetadataKey != "" && stored != "" {
+		checksums = append(checksums, fixityChecksum{
+			name:     "SHA-256",
+			expected: strings.ToLower(stored),
+			actual:   hex.EncodeToString(sha256Hash.Sum(nil)),
+		})
+	}
+
+	verified := false
+
+	for _, checksum := range checksums {
+		if checksum.expected == "" {
+			continue
+		}
+
+		if checksum.expected != checksum.actual {
+			return false, &IntegrityError{
+				Key:      key,
+				Checksum: checksum.name,
+				Expected: checksum.expected,
+				Actual:   checksum.actual,
+			}
+		}
+
+		verified = true
+	}
+
+	return verified, nil
+}
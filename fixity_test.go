@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyPrefix(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	sum := sha256.Sum256([]byte("intact"))
+	metadata := map[string]string{"Sha256": hex.EncodeToString(sum[:])}
+
+	require.NoError(t, storage.Write(ctx, "archive/intact", []byte("intact"), nil, WithMetadata(metadata)))
+	require.NoError(t, storage.Write(ctx, "archive/rotten", []byte("rotten"), nil))
+	require.NoError(t, storage.Write(ctx, "archive/tampered", []byte("tampered"), nil, WithMetadata(metadata)))
+	require.NoError(t, storage.Write(ctx, "archive/unchecked", []byte("unchecked"), nil))
+	require.NoError(t, storage.Write(ctx, "other", []byte("other"), nil))
+
+	storage.mu.Lock()
+	// the content changes without its checksums
+	storage.objects["archive/rotten"].body = []byte("r0tten")
+	// the checksums of the provider are missing, e.g. for a multipart upload
+	storage.objects["archive/unchecked"].attrs.MD5 = nil
+	storage.objects["archive/unchecked"].attrs.CRC32C = nil
+	storage.mu.Unlock()
+
+	report, err := VerifyPrefix(ctx, storage, "archive/", &FixityOption{SHA256MetadataKey: "Sha256"})
+	require.NoError(t, err)
+	require.False(t, report.OK())
+
+	require.Equal(t, 1, report.Verified)
+	require.Equal(t, []string{"archive/unchecked"}, report.Unverified)
+	require.Empty(t, report.Errors)
+
+	require.Len(t, report.Mismatches, 2)
+	require.Equal(t, "archive/rotten", report.Mismatches[0].Key)
+	require.Equal(t, "MD5", report.Mismatches[0].Checksum)
+	require.ErrorIs(t, report.Mismatches[0], ErrChecksumMismatch)
+
+	require.Equal(t, "archive/tampered", report.Mismatches[1].Key)
+	require.Equal(t, "SHA-256", report.Mismatches[1].Checksum)
+	require.Equal(t, hex.EncodeToString(sum[:]), report.Mismatches[1].Expected)
+
+	report, err = VerifyPrefix(ctx, storage, "other", nil)
+	require.NoError(t, err)
+	require.True(t, report.OK())
+	require.Equal(t, 1, report.Verified)
+}
+
+func TestVerifyPrefixReadErrors(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.Write(ctx, "archive/a", []byte("a"), nil))
+
+	faulty := NewFaultInjectingCloudStorage(storage, FaultInjectionOption{
+		Operations: map[string]Fault{"GetWithAttributes": {ErrorRate: 1, Err: ErrUnavailable}},
+	})
+
+	report, err := VerifyPrefix(ctx, faulty, "archive/", nil)
+	require.NoError(t, err)
+	require.False(t, report.OK())
+	require.ErrorIs(t, report.Errors["archive/a"], ErrUnavailable)
+}
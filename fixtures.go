@@ -0,0 +1,826 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrNoFixture is matched by errors.Is when FixtureReplayer has no recorded interaction for an operation
+var ErrNoFixture = errors.New("no recorded fixture")
+
+// fixtureErrors are the sentinel errors kept by the fixtures, so the replayed errors match them with errors.Is
+var fixtureErrors = []error{
+	ErrNotFound,
+	ErrAlreadyExists,
+	ErrPermissionDenied,
+	ErrUnavailable,
+	ErrPreconditionFailed,
+	ErrInvalidKey,
+	ErrObjectTooLarge,
+	ErrQuotaExceeded,
+	ErrNotSupported,
+	context.Canceled,
+	context.DeadlineExceeded,
+}
+
+// fixtureError is an error of a fixture, Sentinel being the message of the sentinel error it matches
+type fixtureError struct {
+	Message  string `json:"message"`
+	Sentinel string `json:"sentinel,omitempty"`
+}
+
+func newFixtureError(err error) *fixtureError {
+	if err == nil {
+		return nil
+	}
+
+	recorded := &fixtureError{Message: err.Error()}
+
+	for _, sentinel := range fixtureErrors {
+		if errors.Is(err, sentinel) {
+			recorded.Sentinel = sentinel.Error()
+			break
+		}
+	}
+
+	return recorded
+}
+
+func (e *fixtureError) err() error {
+	if e == nil {
+		return nil
+	}
+
+	for _, sentinel := range fixtureErrors {
+		if sentinel.Error() == e.Sentinel {
+			return fmt.Errorf("%w: %s", sentinel, e.Message)
+		}
+	}
+
+	return errors.New(e.Message)
+}
+
+// fixtureInteraction is a recorded operation and its response
+type fixtureInteraction struct {
+	Operation string `json:"operation"`
+	// Key is the key or the prefix of the operation
+	Key string `json:"key"`
+	// Args are the other arguments telling the responses apart, e.g. the range of GetRangeReader
+	Args       string        `json:"args,omitempty"`
+	Body       []byte        `json:"body,omitempty"`
+	Attributes *Attributes   `json:"attributes,omitempty"`
+	Objects    []*ListObject `json:"objects,omitempty"`
+	// Result is the URL of GetSignedURL or the generation of WriteIf
+	Result string        `json:"result,omitempty"`
+	Error  *fixtureError `json:"error,omitempty"`
+	// StreamError is the error ending the stream or the listing, or closing the writer
+	StreamError *fixtureError `json:"streamError,omitempty"`
+}
+
+func (i *fixtureInteraction) match() string {
+	return i.Operation + "\x00" + i.Key + "\x00" + i.Args
+}
+
+// fixtureFile is the content of a fixture file
+type fixtureFile struct {
+	Interactions []*fixtureInteraction `json:"interactions"`
+}
+
+// FixtureRecorder records the operations of the wrapped storage and their responses, saved to a fixture file by
+// Save, so FixtureReplayer can serve them back in the tests without the provider.
+// List, Get, GetReader, GetWithAttributes, GetRangeReader, Attributes, Write, GetWriter, Upload, Delete, WriteIf,
+// DeleteIf, GetSignedURL and Ping are recorded, the other operations are passed through. The streams are recorded
+// as they are read, and the listings as they are iterated.
+type FixtureRecorder struct {
+	CloudStorage
+	path string
+
+	mu           sync.Mutex
+	interactions []*fixtureInteraction
+}
+
+// NewFixtureRecorder returns the storage recording its operations to the fixture file of the path
+func NewFixtureRecorder(storage CloudStorage, path string) *FixtureRecorder {
+	return &FixtureRecorder{CloudStorage: storage, path: path}
+}
+
+func (ts *FixtureRecorder) record(interaction *fixtureInteraction) *fixtureInteraction {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.interactions = append(ts.interactions, interaction)
+
+	return interaction
+}
+
+// update changes a recorded interaction, e.g. while its stream is read
+func (ts *FixtureRecorder) update(f func()) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	f()
+}
+
+// Save writes the interactions recorded so far to the fixture file
+func (ts *FixtureRecorder) Save() error {
+	ts.mu.Lock()
+	body, err := json.MarshalIndent(fixtureFile{Interactions: ts.interactions}, "", "  ")
+	ts.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(ts.path, body, 0600)
+}
+
+func (ts *FixtureRecorder) List(
+	ctx context.Context,
+	prefix string,
+	opts ...ListOption,
+) *ListIterator {
+	interaction := ts.record(&fixtureInteraction{Operation: "List", Key: prefix})
+	iter := ts.CloudStorage.List(ctx, prefix, opts...)
+
+	return NewListIterator(ctx, func(ctx context.Context) (*ListObject, error) {
+		object, err := iter.Next(ctx)
+
+		ts.update(func() {
+			switch {
+			case err == nil:
+				interaction.Objects = append(interaction.Objects, object)
+			case err != io.EOF:
+				interaction.StreamError = newFixtureError(err)
+			}
+		})
+
+		return object, err
+	})
+}
+
+func (ts *FixtureRecorder) Get(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) ([]byte, error) {
+	body, err := ts.CloudStorage.Get(ctx, key, opts...)
+	ts.record(&fixtureInteraction{Operation: "Get", Key: key, Body: body, Error: newFixtureError(err)})
+
+	return body, err
+}
+
+// recordReader records the bytes of a stream as they are read, and its error
+func (ts *FixtureRecorder) recordReader(reader io.ReadCloser, interaction *fixtureInteraction) io.ReadCloser {
+	return &fixtureRecordingReader{ReadCloser: reader, recorder: ts, interaction: interaction}
+}
+
+type fixtureRecordingReader struct {
+	io.ReadCloser
+	recorder    *FixtureRecorder
+	interaction *fixtureInteraction
+}
+
+func (r *fixtureRecordingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+
+	r.recorder.update(func() {
+		r.interaction.Body = append(r.interaction.Body, p[:n]...)
+
+		if err != nil && err != io.EOF {
+			r.interaction.StreamError = newFixtureError(err)
+		}
+	})
+
+	return n, err
+}
+
+func (ts *FixtureRecorder) GetReader(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	reader, err := ts.CloudStorage.GetReader(ctx, key, opts...)
+	interaction := ts.record(&fixtureInteraction{Operation: "GetReader", Key: key, Error: newFixtureError(err)})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.recordReader(reader, interaction), nil
+}
+
+func (ts *FixtureRecorder) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	reader, attrs, err := ts.CloudStorage.GetWithAttributes(ctx, key, opts...)
+	interaction := ts.record(&fixtureInteraction{
+		Operation:  "GetWithAttributes",
+		Key:        key,
+		Attributes: attrs,
+		Error:      newFixtureError(err),
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ts.recordReader(reader, interaction), attrs, nil
+}
+
+func rangeArgs(offset, length int64) string {
+	return strconv.FormatInt(offset, 10) + "-" + strconv.FormatInt(length, 10)
+}
+
+func (ts *FixtureRecorder) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset,
+	length int64,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	reader, err := ts.CloudStorage.GetRangeReader(ctx, key, offset, length, opts...)
+	interaction := ts.record(&fixtureInteraction{
+		Operation: "GetRangeReader",
+		Key:       key,
+		Args:      rangeArgs(offset, length),
+		Error:     newFixtureError(err),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.recordReader(reader, interaction), nil
+}
+
+func (ts *FixtureRecorder) Attributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (*Attributes, error) {
+	attrs, err := ts.CloudStorage.Attributes(ctx, key, opts...)
+	ts.record(&fixtureInteraction{Operation: "Attributes", Key: key, Attributes: attrs, Error: newFixtureError(err)})
+
+	return attrs, err
+}
+
+func (ts *FixtureRecorder) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	err := ts.CloudStorage.Write(ctx, key, body, contentType, opts...)
+	ts.record(&fixtureInteraction{Operation: "Write", Key: key, Error: newFixtureError(err)})
+
+	return err
+}
+
+// fixtureRecordingWriter records the error of the upload on Close
+type fixtureRecordingWriter struct {
+	io.WriteCloser
+	recorder    *FixtureRecorder
+	interaction *fixtureInteraction
+}
+
+func (w *fixtureRecordingWriter) Close() error {
+	err := w.WriteCloser.Close()
+	w.recorder.update(func() {
+		w.interaction.StreamError = newFixtureError(err)
+	})
+
+	return err
+}
+
+func (ts *FixtureRecorder) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	writer, err := ts.CloudStorage.GetWriter(ctx, key, opts...)
+	interaction := ts.record(&fixtureInteraction{Operation: "GetWriter", Key: key, Error: newFixtureError(err)})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &fixtureRecordingWriter{WriteCloser: writer, recorder: ts, interaction: interaction}, nil
+}
+
+func (ts *FixtureRecorder) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	err := ts.CloudStorage.Upload(ctx, key, reader, opts, writeOpts...)
+	ts.record(&fixtureInteraction{Operation: "Upload", Key: key, Error: newFixtureError(err)})
+
+	return err
+}
+
+func (ts *FixtureRecorder) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	err := ts.CloudStorage.Delete(ctx, key)
+	ts.record(&fixtureInteraction{Operation: "Delete", Key: key, Error: newFixtureError(err)})
+
+	return err
+}
+
+func (ts *FixtureRecorder) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	generation, err := ts.CloudStorage.WriteIf(ctx, key, body, contentType, condition)
+	ts.record(&fixtureInteraction{Operation: "WriteIf", Key: key, Result: generation, Error: newFixtureError(err)})
+
+	return generation, err
+}
+
+func (ts *FixtureRecorder) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	err := ts.CloudStorage.DeleteIf(ctx, key, generation)
+	ts.record(&fixtureInteraction{Operation: "DeleteIf", Key: key, Args: generation, Error: newFixtureError(err)})
+
+	return err
+}
+
+func (ts *FixtureRecorder) GetSignedURL(
+	ctx context.Context,
+	key string,
+	opts *SignedURLOption,
+) (string, error) {
+	url, err := ts.CloudStorage.GetSignedURL(ctx, key, opts)
+	ts.record(&fixtureInteraction{Operation: "GetSignedURL", Key: key, Args: opts.Method, Result: url, Error: newFixtureError(err)})
+
+	return url, err
+}
+
+func (ts *FixtureRecorder) Ping(ctx context.Context) error {
+	err := ts.CloudStorage.Ping(ctx)
+	ts.record(&fixtureInteraction{Operation: "Ping", Error: newFixtureError(err)})
+
+	return err
+}
+
+// Flush flushes the wrapped storage
+func (ts *FixtureRecorder) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
+
+// FixtureReplayer serves the responses recorded by FixtureRecorder, without any request, e.g. for the tests of
+// the services depending on the storage in a CI without emulator or network.
+// The interactions of an operation, key and arguments are served in the order they were recorded, the last one
+// being served again once they are exhausted. The operations without interaction fail with ErrNoFixture,
+// and the operations which aren't recorded with ErrNotSupported.
+type FixtureReplayer struct {
+	mu           sync.Mutex
+	interactions map[string][]*fixtureInteraction
+	served       map[string]int
+}
+
+var _ CloudStorage = (*FixtureReplayer)(nil)
+
+// NewFixtureReplayer returns the storage replaying the fixture file of the path
+func NewFixtureReplayer(path string) (*FixtureReplayer, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures fixtureFile
+	if err := json.Unmarshal(body, &fixtures); err != nil {
+		return nil, fmt.Errorf("invalid fixture file '%s': %w", path, err)
+	}
+
+	ts := &FixtureReplayer{
+		interactions: make(map[string][]*fixtureInteraction),
+		served:       make(map[string]int),
+	}
+
+	for _, interaction := range fixtures.Interactions {
+		match := interaction.match()
+		ts.interactions[match] = append(ts.interactions[match], interaction)
+	}
+
+	return ts, nil
+}
+
+// replay returns the next interaction of the operation
+func (ts *FixtureReplayer) replay(operation, key, args string) (*fixtureInteraction, error) {
+	match := (&fixtureInteraction{Operation: operation, Key: key, Args: args}).match()
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	interactions := ts.interactions[match]
+	if len(interactions) == 0 {
+		return nil, fmt.Errorf("%w: %s '%s' %s", ErrNoFixture, operation, key, args)
+	}
+
+	served := ts.served[match]
+	if served < len(interactions)-1 {
+		ts.served[match] = served + 1
+	}
+
+	return interactions[served], nil
+}
+
+// replayError returns the error of the next interaction of the operation
+func (ts *FixtureReplayer) replayError(operation, key, args string) error {
+	interaction, err := ts.replay(operation, key, args)
+	if err != nil {
+		return err
+	}
+
+	return interaction.Error.err()
+}
+
+func (ts *FixtureReplayer) List(
+	ctx context.Context,
+	prefix string,
+	opts ...ListOption,
+) *ListIterator {
+	interaction, err := ts.replay("List", prefix, "")
+	next := 0
+
+	return NewListIterator(ctx, func(ctx context.Context) (*ListObject, error) {
+		switch {
+		case err != nil:
+			return nil, err
+		case next < len(interaction.Objects):
+			object := *interaction.Objects[next]
+			next++
+
+			return &object, nil
+		case interaction.Error != nil:
+			return nil, interaction.Error.err()
+		case interaction.StreamError != nil:
+			return nil, interaction.StreamError.err()
+		default:
+			return nil, io.EOF
+		}
+	})
+}
+
+func (ts *FixtureReplayer) Get(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) ([]byte, error) {
+	interaction, err := ts.replay("Get", key, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return interaction.Body, interaction.Error.err()
+}
+
+// replayReader returns the recorded bytes of a stream, followed by its error
+func replayReader(interaction *fixtureInteraction) io.ReadCloser {
+	reader := io.Reader(bytes.NewReader(interaction.Body))
+	if err := interaction.StreamError.err(); err != nil {
+		reader = io.MultiReader(reader, &errorReader{err: err})
+	}
+
+	return ioutil.NopCloser(reader)
+}
+
+type errorReader struct {
+	err error
+}
+
+func (r *errorReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// replayStream returns the stream of the next interaction of the operation, or its error if it failed to open
+func (ts *FixtureReplayer) replayStream(operation, key, args string) (io.ReadCloser, *Attributes, error) {
+	interaction, err := ts.replay(operation, key, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if interaction.Error != nil {
+		return nil, nil, interaction.Error.err()
+	}
+
+	return replayReader(interaction), interaction.Attributes, nil
+}
+
+func (ts *FixtureReplayer) GetReader(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	reader, _, err := ts.replayStream("GetReader", key, "")
+
+	return reader, err
+}
+
+func (ts *FixtureReplayer) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	return ts.replayStream("GetWithAttributes", key, "")
+}
+
+func (ts *FixtureReplayer) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset,
+	length int64,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	reader, _, err := ts.replayStream("GetRangeReader", key, rangeArgs(offset, length))
+
+	return reader, err
+}
+
+func (ts *FixtureReplayer) Attributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (*Attributes, error) {
+	interaction, err := ts.replay("Attributes", key, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return interaction.Attributes, interaction.Error.err()
+}
+
+func (ts *FixtureReplayer) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	return ts.replayError("Write", key, "")
+}
+
+// fixtureReplayWriter discards the bytes and returns the recorded error of the upload on Close
+type fixtureReplayWriter struct {
+	err error
+}
+
+func (w *fixtureReplayWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (w *fixtureReplayWriter) Close() error {
+	return w.err
+}
+
+func (ts *FixtureReplayer) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	interaction, err := ts.replay("GetWriter", key, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if interaction.Error != nil {
+		return nil, interaction.Error.err()
+	}
+
+	return &fixtureReplayWriter{err: interaction.StreamError.err()}, nil
+}
+
+// Upload drains the reader and returns the recorded error
+func (ts *FixtureReplayer) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	if err := ts.replayError("Upload", key, ""); err != nil {
+		return err
+	}
+
+	_, err := io.Copy(ioutil.Discard, reader)
+
+	return err
+}
+
+func (ts *FixtureReplayer) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	return ts.replayError("Delete", key, "")
+}
+
+func (ts *FixtureReplayer) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	interaction, err := ts.replay("WriteIf", key, "")
+	if err != nil {
+		return "", err
+	}
+
+	return interaction.Result, interaction.Error.err()
+}
+
+func (ts *FixtureReplayer) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	return ts.replayError("DeleteIf", key, generation)
+}
+
+func (ts *FixtureReplayer) GetSignedURL(
+	ctx context.Context,
+	key string,
+	opts *SignedURLOption,
+) (string, error) {
+	interaction, err := ts.replay("GetSignedURL", key, opts.Method)
+	if err != nil {
+		return "", err
+	}
+
+	return interaction.Result, interaction.Error.err()
+}
+
+func (ts *FixtureReplayer) Ping(ctx context.Context) error {
+	return ts.replayError("Ping", "", "")
+}
+
+// CreateBucket does nothing, the fixtures don't depend on the bucket
+func (ts *FixtureReplayer) CreateBucket(ctx context.Context, bucketPrefix string, expirationTimeDays int64) error {
+	return nil
+}
+
+func (ts *FixtureReplayer) CreateBucketWithOptions(ctx context.Context, opts *CreateBucketOption) error {
+	return nil
+}
+
+// Close does nothing
+func (ts *FixtureReplayer) Close() {}
+
+// GetPublicURL returns an empty string, the URL of the bucket isn't recorded
+func (ts *FixtureReplayer) GetPublicURL(key string) string {
+	return ""
+}
+
+// As returns false, the recorded responses don't have an SDK client
+func (ts *FixtureReplayer) As(target interface{}) bool {
+	return false
+}
+
+func (ts *FixtureReplayer) ErrorAs(err error, target interface{}) bool {
+	return errors.As(err, target)
+}
+
+func (ts *FixtureReplayer) SetObjectRetention(ctx context.Context, key string, retention *ObjectRetention) error {
+	return ErrNotSupported
+}
+
+func (ts *FixtureReplayer) GetObjectRetention(ctx context.Context, key string) (*ObjectRetention, error) {
+	return nil, ErrNotSupported
+}
+
+func (ts *FixtureReplayer) SetLegalHold(ctx context.Context, key string, enabled bool) error {
+	return ErrNotSupported
+}
+
+func (ts *FixtureReplayer) GetLegalHold(ctx context.Context, key string) (bool, error) {
+	return false, ErrNotSupported
+}
+
+func (ts *FixtureReplayer) SetStorageClass(ctx context.Context, key string, storageClass string) error {
+	return ErrNotSupported
+}
+
+func (ts *FixtureReplayer) Copy(ctx context.Context, srcKey string, dstKey string, opts *CopyOption) error {
+	return ErrNotSupported
+}
+
+func (ts *FixtureReplayer) Undelete(ctx context.Context, key string) error {
+	return ErrNotSupported
+}
+
+func (ts *FixtureReplayer) GetBucketPolicy(ctx context.Context) (*BucketPolicy, error) {
+	return nil, ErrNotSupported
+}
+
+func (ts *FixtureReplayer) SetBucketPolicy(ctx context.Context, policy *BucketPolicy) error {
+	return ErrNotSupported
+}
+
+func (ts *FixtureReplayer) GetBucketEncryption(ctx context.Context) (*BucketEncryption, error) {
+	return nil, ErrNotSupported
+}
+
+func (ts *FixtureReplayer) SetBucketEncryption(ctx context.Context, encryption *BucketEncryption) error {
+	return ErrNotSupported
+}
+
+func (ts *FixtureReplayer) GetBucketLogging(ctx context.Context) (*BucketLogging, error) {
+	return nil, ErrNotSupported
+}
+
+func (ts *FixtureReplayer) SetBucketLogging(ctx context.Context, logging *BucketLogging) error {
+	return ErrNotSupported
+}
+
+func (ts *FixtureReplayer) GetBucketReplication(ctx context.Context) (*BucketReplication, error) {
+	return nil, ErrNotSupported
+}
+
+func (ts *FixtureReplayer) SetBucketReplication(ctx context.Context, replication *BucketReplication) error {
+	return ErrNotSupported
+}
+
+func (ts *FixtureReplayer) GetScopedCredentials(
+	ctx context.Context,
+	opts *ScopedCredentialsOption,
+) (*ScopedCredentials, error) {
+	return nil, ErrNotSupported
+}
+
+func (ts *FixtureReplayer) CreateFolder(ctx context.Context, folder string) error {
+	return ErrNotSupported
+}
+
+func (ts *FixtureReplayer) DeleteFolder(ctx context.Context, folder string) error {
+	return ErrNotSupported
+}
+
+func (ts *FixtureReplayer) RenameFolder(ctx context.Context, folder string, newFolder string) error {
+	return ErrNotSupported
+}
+
+func (ts *FixtureReplayer) Subscribe(ctx context.Context, prefix string) (<-chan ObjectEvent, error) {
+	return nil, ErrNotSupported
+}
+
+func (ts *FixtureReplayer) ListIncompleteUploads(ctx context.Context, prefix string) ([]*IncompleteUpload, error) {
+	return nil, ErrNotSupported
+}
+
+func (ts *FixtureReplayer) AbortStaleUploads(ctx context.Context, olderThan time.Duration) ([]*IncompleteUpload, error) {
+	return nil, ErrNotSupported
+}
+
+func (ts *FixtureReplayer) Query(
+	ctx context.Context,
+	key string,
+	sql string,
+	format QueryInputFormat,
+) (io.ReadCloser, error) {
+	return nil, ErrNotSupported
+}
+
+func (ts *FixtureReplayer) BeginUpload(ctx context.Context, key string, opts *UploadOption) (*UploadSession, error) {
+	return nil, ErrNotSupported
+}
+
+func (ts *FixtureReplayer) ResumeUpload(ctx context.Context, state *UploadSessionState) (*UploadSession, error) {
+	return nil, ErrNotSupported
+}
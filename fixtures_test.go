@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixtureRecordReplay(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "fixtures")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "fixtures.json")
+
+	// record against the fake
+	recorder := NewFixtureRecorder(NewFakeCloudStorage("bucket"), path)
+
+	require.NoError(t, recorder.Write(ctx, "a/1", []byte("one"), nil))
+
+	writer, err := recorder.GetWriter(ctx, "a/2")
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("two"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	_, err = recorder.Get(ctx, "missing")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	body, err := recorder.Get(ctx, "a/1")
+	require.NoError(t, err)
+	require.Equal(t, "one", string(body))
+
+	reader, err := recorder.GetRangeReader(ctx, "a/2", 1, 2)
+	require.NoError(t, err)
+	body, err = ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	require.Equal(t, "wo", string(body))
+
+	require.Equal(t, []string{"a/1", "a/2"}, listedKeys(t, recorder.List(ctx, "a/")))
+
+	require.NoError(t, recorder.Delete(ctx, "a/1"))
+
+	_, err = recorder.Get(ctx, "a/1")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	require.NoError(t, recorder.Save())
+
+	// replay without the fake
+	replayer, err := NewFixtureReplayer(path)
+	require.NoError(t, err)
+
+	require.NoError(t, replayer.Write(ctx, "a/1", []byte("one"), nil))
+
+	writer, err = replayer.GetWriter(ctx, "a/2")
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("two"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	_, err = replayer.Get(ctx, "missing")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	// the responses of a key are served in order
+	body, err = replayer.Get(ctx, "a/1")
+	require.NoError(t, err)
+	require.Equal(t, "one", string(body))
+
+	reader, err = replayer.GetRangeReader(ctx, "a/2", 1, 2)
+	require.NoError(t, err)
+	body, err = ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, "wo", string(body))
+
+	require.Equal(t, []string{"a/1", "a/2"}, listedKeys(t, replayer.List(ctx, "a/")))
+
+	require.NoError(t, replayer.Delete(ctx, "a/1"))
+
+	_, err = replayer.Get(ctx, "a/1")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	// the last response is served again
+	_, err = replayer.Get(ctx, "a/1")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	_, err = replayer.Get(ctx, "a/3")
+	require.True(t, errors.Is(err, ErrNoFixture))
+
+	_, err = replayer.GetRangeReader(ctx, "a/2", 0, 2)
+	require.True(t, errors.Is(err, ErrNoFixture))
+
+	_, err = replayer.Subscribe(ctx, "a/")
+	require.Equal(t, ErrNotSupported, err)
+}
+
+func TestFixtureRecordPartialRead(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "fixtures")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "fixtures.json")
+
+	storage := NewFakeCloudStorage("bucket")
+	require.NoError(t, storage.Write(ctx, "key", []byte("body"), nil))
+
+	recorder := NewFixtureRecorder(storage, path)
+
+	reader, err := recorder.GetReader(ctx, "key")
+	require.NoError(t, err)
+
+	buf := make([]byte, 2)
+	_, err = io.ReadFull(reader, buf)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+
+	require.NoError(t, recorder.Save())
+
+	replayer, err := NewFixtureReplayer(path)
+	require.NoError(t, err)
+
+	// only the bytes read while recording are replayed
+	reader, err = replayer.GetReader(ctx, "key")
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, "bo", string(body))
+
+	// the sentinel errors survive the fixture file
+	recorded := newFixtureError(fmt.Errorf("%w: key", ErrPreconditionFailed))
+	require.True(t, errors.Is(recorded.err(), ErrPreconditionFailed))
+
+	recorded = newFixtureError(errors.New("unknown"))
+	require.Equal(t, "unknown", recorded.err().Error())
+}
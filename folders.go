@@ -0,0 +1,182 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi"
+)
+
+// gcpFoldersEndpoint is the JSON API of GCS, replaced by the tests
+var gcpFoldersEndpoint = "https://storage.googleapis.com/storage/v1/"
+
+// gcpFolderOperationInterval is the interval between the polls of a folder rename
+var gcpFolderOperationInterval = time.Second
+
+// folderName returns the name of the folder ending with a slash, like the folders of GCS
+func folderName(folder string) string {
+	if strings.HasSuffix(folder, "/") {
+		return folder
+	}
+
+	return folder + "/"
+}
+
+// gcpFolders manages the folders of a GCS bucket with the hierarchical namespace, through the JSON API
+// since the storage client doesn't support them
+type gcpFolders struct {
+	client     *http.Client
+	bucketName string
+}
+
+// newGCPFolders returns the folders of the bucket, requested with the credentials if any
+func newGCPFolders(creds *google.Credentials, bucketName string, cloudStorageOpts *CloudStorageOption) *gcpFolders {
+	transport := newGCPTransport(cloudStorageOpts)
+	if creds != nil {
+		transport = &oauth2.Transport{Base: transport, Source: creds.TokenSource}
+	}
+
+	return &gcpFolders{client: &http.Client{Transport: transport}, bucketName: bucketName}
+}
+
+// gcpOperation is the long-running operation of a folder rename
+type gcpOperation struct {
+	Name  string `json:"name"`
+	Done  bool   `json:"done"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// gcpOperationErrors maps the gRPC codes of the failed operations to the sentinel errors
+var gcpOperationErrors = map[int]error{
+	5:  ErrNotFound,
+	6:  ErrAlreadyExists,
+	7:  ErrPermissionDenied,
+	9:  ErrPreconditionFailed,
+	14: ErrUnavailable,
+}
+
+// do sends the request and decodes the response into out, if any
+func (f *gcpFolders) do(ctx context.Context, method string, path string, body interface{}, out interface{}) error {
+	var reader bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reader).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, gcpFoldersEndpoint+path, &reader)
+	if err != nil {
+		return err
+	}
+
+	req = req.WithContext(ctx)
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return translateError(err)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (f *gcpFolders) path(folder string) string {
+	return fmt.Sprintf("b/%s/folders/%s", url.PathEscape(f.bucketName), url.PathEscape(folderName(folder)))
+}
+
+// create creates the folder and its missing parents
+func (f *gcpFolders) create(ctx context.Context, folder string) error {
+	return f.do(ctx, http.MethodPost, fmt.Sprintf("b/%s/folders?recursive=true", url.PathEscape(f.bucketName)),
+		map[string]string{"name": folderName(folder)}, nil)
+}
+
+// delete deletes the empty folder, GCS rejecting the others with a 409
+func (f *gcpFolders) delete(ctx context.Context, folder string) error {
+	err := f.do(ctx, http.MethodDelete, f.path(folder), nil, nil)
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusConflict {
+		return fmt.Errorf("%w: folder '%s' isn't empty", ErrPreconditionFailed, folder)
+	}
+
+	return err
+}
+
+// rename renames the folder and waits for the operation to complete
+func (f *gcpFolders) rename(ctx context.Context, folder string, newFolder string) error {
+	var operation gcpOperation
+
+	path := fmt.Sprintf("%s/renameTo/folders/%s", f.path(folder), url.PathEscape(folderName(newFolder)))
+	if err := f.do(ctx, http.MethodPost, path, nil, &operation); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(gcpFolderOperationInterval)
+	defer ticker.Stop()
+
+	for !operation.Done {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		// the name is projects/_/buckets/<bucket>/operations/<id>
+		id := operation.Name[strings.LastIndex(operation.Name, "/")+1:]
+
+		path := fmt.Sprintf("b/%s/operations/%s", url.PathEscape(f.bucketName), url.PathEscape(id))
+		if err := f.do(ctx, http.MethodGet, path, nil, &operation); err != nil {
+			return err
+		}
+	}
+
+	if operation.Error != nil {
+		if sentinel, ok := gcpOperationErrors[operation.Error.Code]; ok {
+			return fmt.Errorf("%w: %s", sentinel, operation.Error.Message)
+		}
+
+		return fmt.Errorf("unable to rename folder '%s': %s", folder, operation.Error.Message)
+	}
+
+	return nil
+}
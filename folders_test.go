@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeCloudStorageFolders(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.CreateFolder(ctx, "a/b"))
+	require.ErrorIs(t, storage.CreateFolder(ctx, "a/b/"), ErrAlreadyExists)
+	require.ErrorIs(t, storage.CreateFolder(ctx, "a"), ErrAlreadyExists)
+
+	require.NoError(t, storage.Write(ctx, "a/b/c.txt", []byte("c"), nil))
+
+	// the folders holding objects or subfolders can't be deleted
+	require.ErrorIs(t, storage.DeleteFolder(ctx, "a/b"), ErrPreconditionFailed)
+	require.ErrorIs(t, storage.DeleteFolder(ctx, "a"), ErrPreconditionFailed)
+	require.ErrorIs(t, storage.DeleteFolder(ctx, "missing"), ErrNotFound)
+
+	require.ErrorIs(t, storage.RenameFolder(ctx, "missing", "other"), ErrNotFound)
+	require.ErrorIs(t, storage.RenameFolder(ctx, "a/b", "a"), ErrAlreadyExists)
+	require.ErrorIs(t, storage.RenameFolder(ctx, "a", "a/d"), ErrPreconditionFailed)
+
+	require.NoError(t, storage.RenameFolder(ctx, "a", "z"))
+	require.Equal(t, []string{"z/b/c.txt"}, listedKeys(t, storage.List(ctx, "")))
+
+	body, err := storage.Get(ctx, "z/b/c.txt")
+	require.NoError(t, err)
+	require.Equal(t, []byte("c"), body)
+
+	require.NoError(t, storage.Delete(ctx, "z/b/c.txt"))
+	require.NoError(t, storage.DeleteFolder(ctx, "z/b"))
+	require.NoError(t, storage.DeleteFolder(ctx, "z"))
+	require.ErrorIs(t, storage.DeleteFolder(ctx, "a/b"), ErrNotFound)
+}
+
+func TestGCPFolders(t *testing.T) {
+	polls := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.EscapedPath() {
+		case "POST /storage/v1/b/bucket/folders":
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			require.Equal(t, "a/b/", body["name"])
+			require.Equal(t, "true", r.URL.Query().Get("recursive"))
+			w.Write([]byte(`{"name": "a/b/"}`))
+		case "DELETE /storage/v1/b/bucket/folders/a%2Fb%2F":
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": {"code": 409, "message": "folder isn't empty"}}`))
+		case "DELETE /storage/v1/b/bucket/folders/missing%2F":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"code": 404, "message": "not found"}}`))
+		case "POST /storage/v1/b/bucket/folders/a%2F/renameTo/folders/z%2F":
+			w.Write([]byte(`{"name": "projects/_/buckets/bucket/operations/op1"}`))
+		case "GET /storage/v1/b/bucket/operations/op1":
+			polls++
+			w.Write([]byte(`{"name": "projects/_/buckets/bucket/operations/op1", "done": true}`))
+		case "POST /storage/v1/b/bucket/folders/a%2F/renameTo/folders/taken%2F":
+			w.Write([]byte(`{"name": "projects/_/buckets/bucket/operations/op2", "done": true,
+				"error": {"code": 6, "message": "the destination exists"}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	endpoint, interval := gcpFoldersEndpoint, gcpFolderOperationInterval
+	gcpFoldersEndpoint, gcpFolderOperationInterval = server.URL+"/storage/v1/", time.Millisecond
+
+	defer func() {
+		gcpFoldersEndpoint, gcpFolderOperationInterval = endpoint, interval
+	}()
+
+	ctx := context.Background()
+	folders := newGCPFolders(nil, "bucket", &CloudStorageOption{})
+
+	require.NoError(t, folders.create(ctx, "a/b"))
+	require.ErrorIs(t, folders.delete(ctx, "a/b"), ErrPreconditionFailed)
+	require.ErrorIs(t, folders.delete(ctx, "missing"), ErrNotFound)
+
+	require.NoError(t, folders.rename(ctx, "a", "z"))
+	require.Equal(t, 1, polls)
+
+	require.ErrorIs(t, folders.rename(ctx, "a", "taken"), ErrAlreadyExists)
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// ForEachOption configures ForEachObject
+type ForEachOption struct {
+	// Concurrency is the number of objects processed in parallel, DefaultGetManyConcurrency when it isn't positive
+	Concurrency int
+	// CollectErrors processes all the objects and returns a *ForEachError of the failed ones,
+	// instead of stopping on the first error
+	CollectErrors bool
+	// ListOptions are passed to List, e.g. WithListOrder
+	ListOptions []ListOption
+}
+
+// ForEachError is returned by ForEachObject collecting the errors, by key of the failed objects
+type ForEachError struct {
+	Errors map[string]error
+}
+
+func (e *ForEachError) Error() string {
+	keys := make([]string, 0, len(e.Errors))
+	for key := range e.Errors {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return fmt.Sprintf("%d objects failed, first '%s': %v", len(keys), keys[0], e.Errors[keys[0]])
+}
+
+// ForEachObject lists the objects under the prefix and calls fn for each of them, Concurrency at a time.
+// By default, the first error of fn cancels the context of the other calls, stops the listing and is returned.
+// With CollectErrors, the errors of fn are returned together once all the objects are processed.
+// The error of the listing is returned in both cases, after the calls in progress return.
+func ForEachObject(
+	ctx context.Context,
+	storage CloudStorage,
+	prefix string,
+	fn func(ctx context.Context, object *ListObject) error,
+	opts *ForEachOption,
+) error {
+	options := ForEachOption{}
+	if opts != nil {
+		options = *opts
+	}
+
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultGetManyConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		firstErr  error
+		listErr   error
+		errs      = make(map[string]error)
+		semaphore = make(chan struct{}, concurrency)
+	)
+
+	iter := storage.List(ctx, prefix, options.ListOptions...)
+
+	for listErr == nil {
+		object, err := iter.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			listErr = err
+			break
+		}
+
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		// the slot may be freed by the call cancelling the context
+		if err := ctx.Err(); err != nil {
+			listErr = err
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(object *ListObject) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			err := fn(ctx, object)
+			if err == nil {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case options.CollectErrors:
+				errs[object.Key] = err
+			case firstErr == nil:
+				firstErr = err
+				cancel()
+			}
+		}(object)
+	}
+
+	wg.Wait()
+
+	// the listing fails with context.Canceled once the first error cancels it
+	if firstErr != nil {
+		return firstErr
+	}
+
+	if listErr != nil {
+		return listErr
+	}
+
+	if len(errs) > 0 {
+		return &ForEachError{Errors: errs}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestForEachObject(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, storage.Write(ctx, "jobs/"+strconv.Itoa(i), []byte("body"), nil))
+	}
+
+	require.NoError(t, storage.Write(ctx, "other", []byte("body"), nil))
+
+	var (
+		mu      sync.Mutex
+		keys    []string
+		running int64
+		maxRun  int64
+	)
+
+	err := ForEachObject(ctx, storage, "jobs/", func(ctx context.Context, object *ListObject) error {
+		current := atomic.AddInt64(&running, 1)
+		defer atomic.AddInt64(&running, -1)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		keys = append(keys, object.Key)
+		if current > maxRun {
+			maxRun = current
+		}
+
+		return nil
+	}, &ForEachOption{Concurrency: 3})
+	require.NoError(t, err)
+	require.Len(t, keys, 20)
+	require.LessOrEqual(t, maxRun, int64(3))
+}
+
+func TestForEachObjectFailFast(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, storage.Write(ctx, "jobs/"+strconv.Itoa(i), []byte("body"), nil))
+	}
+
+	failure := errors.New("failure")
+
+	var calls int64
+
+	err := ForEachObject(ctx, storage, "jobs/", func(ctx context.Context, object *ListObject) error {
+		atomic.AddInt64(&calls, 1)
+		return failure
+	}, &ForEachOption{Concurrency: 1})
+	require.Equal(t, failure, err)
+	require.Equal(t, int64(1), atomic.LoadInt64(&calls))
+}
+
+func TestForEachObjectCollectErrors(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, storage.Write(ctx, "jobs/"+strconv.Itoa(i), []byte("body"), nil))
+	}
+
+	var calls int64
+
+	err := ForEachObject(ctx, storage, "jobs/", func(ctx context.Context, object *ListObject) error {
+		atomic.AddInt64(&calls, 1)
+
+		if object.Key == "jobs/3" || object.Key == "jobs/7" {
+			return errors.New("failure")
+		}
+
+		return nil
+	}, &ForEachOption{CollectErrors: true})
+
+	var forEachErr *ForEachError
+	require.True(t, errors.As(err, &forEachErr))
+	require.Len(t, forEachErr.Errors, 2)
+	require.Contains(t, forEachErr.Errors, "jobs/3")
+	require.Equal(t, "2 objects failed, first 'jobs/3': failure", err.Error())
+	require.Equal(t, int64(10), calls)
+}
+
+func TestForEachObjectListError(t *testing.T) {
+	storage := NewFaultInjectingCloudStorage(NewFakeCloudStorage("bucket"), FaultInjectionOption{
+		Operations: map[string]Fault{"List": {ErrorRate: 1}},
+	})
+
+	err := ForEachObject(context.Background(), storage, "", func(ctx context.Context, object *ListObject) error {
+		return nil
+	}, nil)
+
+	var injected *InjectedFaultError
+	require.True(t, errors.As(err, &injected))
+}
@@ -18,27 +18,47 @@ package commonblobgo
 
 import (
 	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
+	"sync"
 	"time"
 
+	"cloud.google.com/go/pubsub"
 	"cloud.google.com/go/storage"
-	"github.com/sirupsen/logrus"
 	"gocloud.dev/blob"
 	"gocloud.dev/blob/gcsblob"
 	"gocloud.dev/gcp"
 	"golang.org/x/oauth2/google"
-	"google.golang.org/api/option"
 )
 
 type ExplicitGCPCloudStorage struct {
-	client          *storage.Client
-	bucket          *blob.Bucket
-	bucketName      string
-	privateKey      []byte
-	googleAccessID  string
-	bucketCloseFunc func()
+	client            *storage.Client
+	bucket            *blob.Bucket
+	bucketName        string
+	privateKey        []byte
+	credentialsJSON   []byte
+	creds             *google.Credentials
+	notificationQueue string
+	userProject       string
+	folders           *gcpFolders
+	googleAccessID    string
+	logger            Logger
+	clock             func() time.Time
+	listRetry         RetryPolicy
+	bucketCloseFunc   func()
+
+	// signer is the private key parsed once for all the signed URLs
+	signerOnce sync.Once
+	signer     *rsa.PrivateKey
+	signerErr  error
 }
 
 type signature struct {
@@ -51,6 +71,7 @@ func newExplicitGCPCloudStorage(
 	ctx context.Context,
 	gcpCredentialJSON string,
 	bucketName string,
+	cloudStorageOpts *CloudStorageOption,
 ) (*ExplicitGCPCloudStorage, error) {
 	gcpCredentialJSONBytes := []byte(gcpCredentialJSON)
 
@@ -66,13 +87,13 @@ func newExplicitGCPCloudStorage(
 		return nil, fmt.Errorf("unable to unmarshal credentials: %v", err)
 	}
 
-	client, err := storage.NewClient(ctx, option.WithCredentials(creds))
+	client, err := storage.NewClient(ctx, newGCPClientOptions(creds, cloudStorageOpts)...)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create GCP client: %v", err)
 	}
 
 	bucketHTTPClient, err := gcp.NewHTTPClient(
-		gcp.DefaultTransport(),
+		newGCPTransport(cloudStorageOpts),
 		gcp.CredentialsTokenSource(creds),
 	)
 	if err != nil {
@@ -89,14 +110,23 @@ func newExplicitGCPCloudStorage(
 		return nil, err
 	}
 
-	logrus.Infof("explicit GCP CloudStorage created")
+	logger := loggerOrNoop(cloudStorageOpts.Logger)
+	logger.Info("explicit GCP CloudStorage created", Fields{"bucket": bucketName})
 
 	return &ExplicitGCPCloudStorage{
-		client:         client,
-		bucketName:     bucketName,
-		bucket:         bucket,
-		googleAccessID: sign.GoogleAccessID,
-		privateKey:     []byte(sign.PrivateKey),
+		client:            client,
+		bucketName:        bucketName,
+		bucket:            bucket,
+		googleAccessID:    sign.GoogleAccessID,
+		privateKey:        []byte(sign.PrivateKey),
+		credentialsJSON:   gcpCredentialJSONBytes,
+		creds:             creds,
+		notificationQueue: notificationQueueName(cloudStorageOpts, bucketName),
+		userProject:       cloudStorageOpts.GCPUserProject,
+		folders:           newGCPFolders(creds, bucketName, cloudStorageOpts),
+		logger:            logger,
+		clock:             clockOrNow(cloudStorageOpts.Clock),
+		listRetry:         listRetryPolicy(cloudStorageOpts),
 		bucketCloseFunc: func() {
 			bucket.Close()
 		},
@@ -106,56 +136,85 @@ func newExplicitGCPCloudStorage(
 func (ts *ExplicitGCPCloudStorage) List(
 	ctx context.Context,
 	prefix string,
+	opts ...ListOption,
 ) *ListIterator {
-	iter := ts.bucket.List(&blob.ListOptions{
-		Prefix: prefix,
-	})
+	return listBucket(ctx, ts.bucket, prefix, opts, ts.listRetry, gcpListObject)
+}
 
-	return newListIterator(func() (*ListObject, error) {
-		attrs, err := iter.Next(ctx)
-		if err != nil {
-			return nil, err
-		}
+// gcpListObject returns the listed object with the ETag and the storage class of the GCS object
+func gcpListObject(attrs *blob.ListObject) *ListObject {
+	object := &ListObject{
+		Key:     attrs.Key,
+		ModTime: attrs.ModTime,
+		Size:    attrs.Size,
+		MD5:     attrs.MD5,
+	}
 
-		return &ListObject{
-			Key:     attrs.Key,
-			ModTime: attrs.ModTime,
-			Size:    attrs.Size,
-			MD5:     attrs.MD5,
-		}, nil
-	})
+	var objectAttrs storage.ObjectAttrs
+	if attrs.As(&objectAttrs) {
+		object.ETag = objectAttrs.Etag
+		object.StorageClass = objectAttrs.StorageClass
+	}
+
+	return object
 }
 
 func (ts *ExplicitGCPCloudStorage) Get(
 	ctx context.Context,
 	key string,
+	opts ...ReadOption,
 ) ([]byte, error) {
 	body, err := ts.bucket.ReadAll(ctx, key)
 
-	return body, err
+	return body, translateError(err)
 }
 
 func (ts *ExplicitGCPCloudStorage) GetReader(
 	ctx context.Context,
 	key string,
+	opts ...ReadOption,
 ) (io.ReadCloser, error) {
-	return ts.bucket.NewReader(ctx, key, nil)
+	reader, err := ts.bucket.NewReader(ctx, key, nil)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return reader, nil
 }
 
+// GetRangeReader reads the last -offset bytes of the object when the offset is negative
 func (ts *ExplicitGCPCloudStorage) GetRangeReader(
 	ctx context.Context,
 	key string,
 	offset,
 	length int64,
+	opts ...ReadOption,
 ) (io.ReadCloser, error) {
-	return ts.bucket.NewRangeReader(ctx, key, offset, length, nil)
+	if offset < 0 {
+		return gcpTailReader(ctx, ts.client, ts.bucketName, key, -offset, length)
+	}
+
+	reader, err := ts.bucket.NewRangeReader(ctx, key, offset, length, nil)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return reader, nil
 }
 
 func (ts *ExplicitGCPCloudStorage) GetWriter(
 	ctx context.Context,
 	key string,
+	opts ...WriteOption,
 ) (io.WriteCloser, error) {
-	return ts.bucket.NewWriter(ctx, key, nil)
+	call := writeOptions(opts)
+
+	writer, err := ts.bucket.NewWriter(ctx, key, call.writerOptions(key))
+	if err != nil {
+		return nil, err
+	}
+
+	return gcsWriter(writer, call), nil
 }
 
 func (ts *ExplicitGCPCloudStorage) CreateBucket(
@@ -167,6 +226,18 @@ func (ts *ExplicitGCPCloudStorage) CreateBucket(
 	return nil
 }
 
+func (ts *ExplicitGCPCloudStorage) CreateBucketWithOptions(
+	ctx context.Context,
+	opts *CreateBucketOption,
+) error {
+	projectID, err := gcpBucketProject(ts.creds, opts)
+	if err != nil {
+		return err
+	}
+
+	return gcpCreateBucket(ctx, ts.client, ts.bucketName, projectID, opts)
+}
+
 func (ts *ExplicitGCPCloudStorage) Close() {
 	ts.bucketCloseFunc()
 }
@@ -176,12 +247,71 @@ func (ts *ExplicitGCPCloudStorage) GetSignedURL(
 	key string,
 	opts *SignedURLOption,
 ) (string, error) {
-	return storage.SignedURL(ts.bucketName, key, &storage.SignedURLOptions{
+	method, err := signedURLMethod(opts)
+	if err != nil {
+		return "", err
+	}
+
+	contentMD5, err := gcpSignedURLMD5(opts)
+	if err != nil {
+		return "", err
+	}
+
+	signedURL, err := storage.SignedURL(ts.bucketName, key, &storage.SignedURLOptions{
 		GoogleAccessID: ts.googleAccessID,
-		PrivateKey:     ts.privateKey,
-		Method:         opts.Method,
-		Expires:        time.Now().Add(opts.Expiry).UTC(),
+		SignBytes:      ts.signBytes,
+		Method:         method,
+		Expires:        ts.clock().Add(opts.Expiry).UTC(),
+		MD5:            contentMD5,
 	})
+	if err != nil {
+		return "", err
+	}
+
+	// the requester pays buckets need the project billed for the download
+	signedURL, err = withUnsignedParameter(signedURL, "userProject", ts.userProject)
+	if err != nil {
+		return "", err
+	}
+
+	return withResponseContentDisposition(signedURL, opts.ContentDisposition)
+}
+
+// signBytes signs like storage.SignedURL does with the PrivateKey option, without parsing the key every time
+func (ts *ExplicitGCPCloudStorage) signBytes(b []byte) ([]byte, error) {
+	ts.signerOnce.Do(func() {
+		ts.signer, ts.signerErr = parseRSAPrivateKey(ts.privateKey)
+	})
+
+	if ts.signerErr != nil {
+		return nil, ts.signerErr
+	}
+
+	sum := sha256.Sum256(b)
+
+	return rsa.SignPKCS1v15(rand.Reader, ts.signer, crypto.SHA256, sum[:])
+}
+
+// parseRSAPrivateKey parses a PEM or DER key, PKCS #8 or PKCS #1, like the storage package
+func parseRSAPrivateKey(key []byte) (*rsa.PrivateKey, error) {
+	if block, _ := pem.Decode(key); block != nil {
+		key = block.Bytes
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(key)
+	if err != nil {
+		parsed, err = x509.ParsePKCS1PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	privateKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("the private key isn't an RSA key")
+	}
+
+	return privateKey, nil
 }
 
 func (ts *ExplicitGCPCloudStorage) Write(
@@ -189,32 +319,56 @@ func (ts *ExplicitGCPCloudStorage) Write(
 	key string,
 	body []byte,
 	contentType *string,
+	opts ...WriteOption,
 ) error {
-	options := &blob.WriterOptions{}
-	if contentType != nil {
-		options.ContentType = *contentType
+	call := writeOptions(opts)
+
+	if err := ts.bucket.WriteAll(ctx, key, body, call.writeAllOptions(key, body, contentType)); err != nil {
+		return translateError(err)
 	}
 
-	return ts.bucket.WriteAll(ctx, key, body, options)
+	call.written.setFromGCSWriter()
+
+	return nil
 }
 
 func (ts *ExplicitGCPCloudStorage) Delete(
 	ctx context.Context,
 	key string,
 ) error {
-	return ts.client.Bucket(ts.bucketName).Object(key).Delete(ctx)
+	return translateError(ts.client.Bucket(ts.bucketName).Object(key).Delete(ctx))
+}
+
+// WriteIf writes the object when the condition is met, and returns its new generation
+func (ts *ExplicitGCPCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	return gcpWriteIf(ctx, ts.client, ts.bucketName, key, body, contentType, condition)
+}
+
+func (ts *ExplicitGCPCloudStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	return gcpDeleteIf(ctx, ts.client, ts.bucketName, key, generation)
 }
 
 func (ts *ExplicitGCPCloudStorage) Attributes(
 	ctx context.Context,
 	key string,
+	opts ...ReadOption,
 ) (*Attributes, error) {
 	attrs, err := ts.bucket.Attributes(ctx, key)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 
-	return &Attributes{
+	result := &Attributes{
 		CacheControl:       attrs.CacheControl,
 		ContentDisposition: attrs.ContentDisposition,
 		ContentEncoding:    attrs.ContentEncoding,
@@ -224,5 +378,243 @@ func (ts *ExplicitGCPCloudStorage) Attributes(
 		ModTime:            attrs.ModTime,
 		Size:               attrs.Size,
 		MD5:                attrs.MD5,
-	}, nil
+	}
+
+	result.setVersion(attrs)
+
+	return result, nil
+}
+
+func (ts *ExplicitGCPCloudStorage) SetObjectRetention(
+	ctx context.Context,
+	key string,
+	retention *ObjectRetention,
+) error {
+	// GCP retention is configured with the bucket retention policy
+	return ErrNotSupported
+}
+
+func (ts *ExplicitGCPCloudStorage) GetObjectRetention(
+	ctx context.Context,
+	key string,
+) (*ObjectRetention, error) {
+	return gcpGetObjectRetention(ctx, ts.client, ts.bucketName, key)
+}
+
+func (ts *ExplicitGCPCloudStorage) SetLegalHold(
+	ctx context.Context,
+	key string,
+	enabled bool,
+) error {
+	return gcpSetLegalHold(ctx, ts.client, ts.bucketName, key, enabled)
+}
+
+func (ts *ExplicitGCPCloudStorage) GetLegalHold(
+	ctx context.Context,
+	key string,
+) (bool, error) {
+	return gcpGetLegalHold(ctx, ts.client, ts.bucketName, key)
+}
+
+// SetStorageClass rewrites the object with the storage class
+func (ts *ExplicitGCPCloudStorage) SetStorageClass(
+	ctx context.Context,
+	key string,
+	storageClass string,
+) error {
+	return gcpSetStorageClass(ctx, ts.client, ts.bucketName, key, storageClass)
+}
+
+// Copy copies the object within the bucket with a rewrite
+func (ts *ExplicitGCPCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	return gcpCopy(ctx, ts.client, ts.bucketName, srcKey, dstKey, opts)
+}
+
+// Undelete copies the last noncurrent generation of the object back in a versioned bucket
+func (ts *ExplicitGCPCloudStorage) Undelete(
+	ctx context.Context,
+	key string,
+) error {
+	return gcpUndelete(ctx, ts.client, ts.bucketName, key)
+}
+
+func (ts *ExplicitGCPCloudStorage) GetScopedCredentials(
+	ctx context.Context,
+	opts *ScopedCredentialsOption,
+) (*ScopedCredentials, error) {
+	return gcpGetScopedCredentials(ctx, ts.creds.TokenSource, ts.bucketName, opts, ts.clock())
+}
+
+func (ts *ExplicitGCPCloudStorage) CreateFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	return ts.folders.create(ctx, folder)
+}
+
+func (ts *ExplicitGCPCloudStorage) DeleteFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	return ts.folders.delete(ctx, folder)
+}
+
+func (ts *ExplicitGCPCloudStorage) RenameFolder(
+	ctx context.Context,
+	folder string,
+	newFolder string,
+) error {
+	return ts.folders.rename(ctx, folder, newFolder)
+}
+
+func (ts *ExplicitGCPCloudStorage) GetBucketPolicy(
+	ctx context.Context,
+) (*BucketPolicy, error) {
+	return gcpGetBucketPolicy(ctx, ts.client, ts.bucketName)
+}
+
+func (ts *ExplicitGCPCloudStorage) SetBucketPolicy(
+	ctx context.Context,
+	policy *BucketPolicy,
+) error {
+	return gcpSetBucketPolicy(ctx, ts.client, ts.bucketName, policy)
+}
+
+func (ts *ExplicitGCPCloudStorage) GetBucketEncryption(
+	ctx context.Context,
+) (*BucketEncryption, error) {
+	return gcpGetBucketEncryption(ctx, ts.client, ts.bucketName)
+}
+
+func (ts *ExplicitGCPCloudStorage) SetBucketEncryption(
+	ctx context.Context,
+	encryption *BucketEncryption,
+) error {
+	return gcpSetBucketEncryption(ctx, ts.client, ts.bucketName, encryption)
+}
+
+func (ts *ExplicitGCPCloudStorage) GetBucketLogging(
+	ctx context.Context,
+) (*BucketLogging, error) {
+	return gcpGetBucketLogging(ctx, ts.client, ts.bucketName)
+}
+
+func (ts *ExplicitGCPCloudStorage) SetBucketLogging(
+	ctx context.Context,
+	logging *BucketLogging,
+) error {
+	return gcpSetBucketLogging(ctx, ts.client, ts.bucketName, logging)
+}
+
+func (ts *ExplicitGCPCloudStorage) GetBucketReplication(
+	ctx context.Context,
+) (*BucketReplication, error) {
+	return gcpGetBucketReplication(ctx, ts.folders)
+}
+
+func (ts *ExplicitGCPCloudStorage) SetBucketReplication(
+	ctx context.Context,
+	replication *BucketReplication,
+) error {
+	return gcpSetBucketReplication(ctx, ts.folders, replication)
+}
+
+func (ts *ExplicitGCPCloudStorage) GetPublicURL(
+	key string,
+) string {
+	return gcpPublicURL("https", gcpPublicHost, ts.bucketName, key)
+}
+
+// As sets target to the GCS client when it's a **storage.Client
+func (ts *ExplicitGCPCloudStorage) As(target interface{}) bool {
+	return ts.bucket.As(target)
+}
+
+// ErrorAs sets target to the GCS error wrapped by err when it's a **googleapi.Error
+func (ts *ExplicitGCPCloudStorage) ErrorAs(err error, target interface{}) bool {
+	return bucketErrorAs(ts.bucket, err, target)
+}
+
+func (ts *ExplicitGCPCloudStorage) Ping(
+	ctx context.Context,
+) error {
+	return gcpPing(ctx, ts.client, ts.bucketName)
+}
+
+func (ts *ExplicitGCPCloudStorage) Subscribe(
+	ctx context.Context,
+	prefix string,
+) (<-chan ObjectEvent, error) {
+	creds, err := google.CredentialsFromJSON(ctx, ts.credentialsJSON, pubsub.ScopePubSub, storage.ScopeFullControl)
+	if err != nil {
+		return nil, err
+	}
+
+	return gcpSubscribe(ctx, ts.client, creds, ts.bucketName, ts.notificationQueue, prefix, ts.logger)
+}
+
+// ListIncompleteUploads returns no upload, the resumable sessions of GCS can't be listed.
+// They aren't billed, and they expire a week after they are initiated.
+func (ts *ExplicitGCPCloudStorage) ListIncompleteUploads(
+	ctx context.Context,
+	prefix string,
+) ([]*IncompleteUpload, error) {
+	return nil, nil
+}
+
+// AbortStaleUploads does nothing, the resumable sessions of GCS expire a week after they are initiated
+func (ts *ExplicitGCPCloudStorage) AbortStaleUploads(
+	ctx context.Context,
+	olderThan time.Duration,
+) ([]*IncompleteUpload, error) {
+	return nil, nil
+}
+
+// Query runs the SQL on the client side while streaming the object, GCS has no equivalent of S3 Select
+func (ts *ExplicitGCPCloudStorage) Query(
+	ctx context.Context,
+	key string,
+	sql string,
+	format QueryInputFormat,
+) (io.ReadCloser, error) {
+	return queryObject(ctx, ts, key, sql, format)
+}
+
+func (ts *ExplicitGCPCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	return getWithAttributes(ctx, ts.bucket, key)
+}
+
+func (ts *ExplicitGCPCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	return gcpUpload(ctx, ts.client, ts.bucketName, key, reader, opts, writeOpts, ts.logger)
+}
+
+// BeginUpload uploads the parts as temporary objects, composed into the object on completion
+func (ts *ExplicitGCPCloudStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	return gcpBeginUpload(ts.client, ts.bucketName, key, opts), nil
+}
+
+func (ts *ExplicitGCPCloudStorage) ResumeUpload(
+	ctx context.Context,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	return gcpResumeUpload(ctx, ts.client, ts.bucketName, state)
 }
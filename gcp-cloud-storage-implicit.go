@@ -25,12 +25,10 @@ import (
 	compMeta "cloud.google.com/go/compute/metadata"
 	credentials "cloud.google.com/go/iam/credentials/apiv1"
 	"cloud.google.com/go/storage"
-	"github.com/sirupsen/logrus"
 	"gocloud.dev/blob"
 	"gocloud.dev/blob/gcsblob"
 	"gocloud.dev/gcp"
 	"golang.org/x/oauth2/google"
-	"google.golang.org/api/option"
 	credentialspb "google.golang.org/genproto/googleapis/iam/credentials/v1"
 )
 
@@ -39,7 +37,14 @@ type ImplicitGCPCloudStorage struct {
 	bucket               *blob.Bucket
 	bucketName           string
 	serviceAccountEmail  string
+	creds                *google.Credentials
+	notificationQueue    string
+	userProject          string
+	folders              *gcpFolders
 	iamCredentialsClient *credentials.IamCredentialsClient
+	logger               Logger
+	clock                func() time.Time
+	listRetry            RetryPolicy
 	bucketCloseFunc      func()
 }
 
@@ -47,6 +52,7 @@ type ImplicitGCPCloudStorage struct {
 func newImplicitGCPCloudStorage(
 	ctx context.Context,
 	bucketName string,
+	cloudStorageOpts *CloudStorageOption,
 ) (*ImplicitGCPCloudStorage, error) {
 	creds, err := gcp.DefaultCredentials(ctx)
 	if err != nil {
@@ -67,13 +73,13 @@ func newImplicitGCPCloudStorage(
 		return nil, err
 	}
 
-	client, err := storage.NewClient(ctx, option.WithCredentials(creds))
+	client, err := storage.NewClient(ctx, newGCPClientOptions(creds, cloudStorageOpts)...)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create GCP client: %v", err)
 	}
 
 	bucketHTTPClient, err := gcp.NewHTTPClient(
-		gcp.DefaultTransport(),
+		newGCPTransport(cloudStorageOpts),
 		gcp.CredentialsTokenSource(creds),
 	)
 	if err != nil {
@@ -90,13 +96,21 @@ func newImplicitGCPCloudStorage(
 		return nil, err
 	}
 
-	logrus.Infof("implicit GCP CloudStorage created")
+	logger := loggerOrNoop(cloudStorageOpts.Logger)
+	logger.Info("implicit GCP CloudStorage created", Fields{"bucket": bucketName})
 
 	return &ImplicitGCPCloudStorage{
 		client:              client,
 		bucketName:          bucketName,
 		bucket:              bucket,
 		serviceAccountEmail: serviceAccountID,
+		creds:               creds,
+		notificationQueue:   notificationQueueName(cloudStorageOpts, bucketName),
+		userProject:         cloudStorageOpts.GCPUserProject,
+		folders:             newGCPFolders(creds, bucketName, cloudStorageOpts),
+		logger:              logger,
+		clock:               clockOrNow(cloudStorageOpts.Clock),
+		listRetry:           listRetryPolicy(cloudStorageOpts),
 		bucketCloseFunc: func() {
 			bucket.Close()
 		},
@@ -107,56 +121,67 @@ func newImplicitGCPCloudStorage(
 func (ts *ImplicitGCPCloudStorage) List(
 	ctx context.Context,
 	prefix string,
+	opts ...ListOption,
 ) *ListIterator {
-	iter := ts.bucket.List(&blob.ListOptions{
-		Prefix: prefix,
-	})
-
-	return newListIterator(func() (*ListObject, error) {
-		attrs, err := iter.Next(ctx)
-		if err != nil {
-			return nil, err
-		}
-
-		return &ListObject{
-			Key:     attrs.Key,
-			ModTime: attrs.ModTime,
-			Size:    attrs.Size,
-			MD5:     attrs.MD5,
-		}, nil
-	})
+	return listBucket(ctx, ts.bucket, prefix, opts, ts.listRetry, gcpListObject)
 }
 
 func (ts *ImplicitGCPCloudStorage) Get(
 	ctx context.Context,
 	key string,
+	opts ...ReadOption,
 ) ([]byte, error) {
 	body, err := ts.bucket.ReadAll(ctx, key)
 
-	return body, err
+	return body, translateError(err)
 }
 
 func (ts *ImplicitGCPCloudStorage) GetReader(
 	ctx context.Context,
 	key string,
+	opts ...ReadOption,
 ) (io.ReadCloser, error) {
-	return ts.bucket.NewReader(ctx, key, nil)
+	reader, err := ts.bucket.NewReader(ctx, key, nil)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return reader, nil
 }
 
+// GetRangeReader reads the last -offset bytes of the object when the offset is negative
 func (ts *ImplicitGCPCloudStorage) GetRangeReader(
 	ctx context.Context,
 	key string,
 	offset,
 	length int64,
+	opts ...ReadOption,
 ) (io.ReadCloser, error) {
-	return ts.bucket.NewRangeReader(ctx, key, offset, length, nil)
+	if offset < 0 {
+		return gcpTailReader(ctx, ts.client, ts.bucketName, key, -offset, length)
+	}
+
+	reader, err := ts.bucket.NewRangeReader(ctx, key, offset, length, nil)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return reader, nil
 }
 
 func (ts *ImplicitGCPCloudStorage) GetWriter(
 	ctx context.Context,
 	key string,
+	opts ...WriteOption,
 ) (io.WriteCloser, error) {
-	return ts.bucket.NewWriter(ctx, key, nil)
+	call := writeOptions(opts)
+
+	writer, err := ts.bucket.NewWriter(ctx, key, call.writerOptions(key))
+	if err != nil {
+		return nil, err
+	}
+
+	return gcsWriter(writer, call), nil
 }
 
 func (ts *ImplicitGCPCloudStorage) CreateBucket(
@@ -168,6 +193,18 @@ func (ts *ImplicitGCPCloudStorage) CreateBucket(
 	return nil
 }
 
+func (ts *ImplicitGCPCloudStorage) CreateBucketWithOptions(
+	ctx context.Context,
+	opts *CreateBucketOption,
+) error {
+	projectID, err := gcpBucketProject(ts.creds, opts)
+	if err != nil {
+		return err
+	}
+
+	return gcpCreateBucket(ctx, ts.client, ts.bucketName, projectID, opts)
+}
+
 func (ts *ImplicitGCPCloudStorage) Close() {
 	ts.bucketCloseFunc()
 }
@@ -181,10 +218,21 @@ func (ts *ImplicitGCPCloudStorage) GetSignedURL(
 	// for details read https://github.com/googleapis/google-cloud-go/issues/1130#issuecomment-484236791
 	name := fmt.Sprintf("projects/-/serviceAccounts/%s", ts.serviceAccountEmail)
 
+	method, err := signedURLMethod(opts)
+	if err != nil {
+		return "", err
+	}
+
+	contentMD5, err := gcpSignedURLMD5(opts)
+	if err != nil {
+		return "", err
+	}
+
 	options := &storage.SignedURLOptions{
 		GoogleAccessID: ts.serviceAccountEmail,
-		Method:         opts.Method,
-		Expires:        time.Now().Add(opts.Expiry).UTC(),
+		Method:         method,
+		Expires:        ts.clock().Add(opts.Expiry).UTC(),
+		MD5:            contentMD5,
 		SignBytes: func(b []byte) ([]byte, error) {
 			req := &credentialspb.SignBlobRequest{
 				Payload: b,
@@ -200,7 +248,18 @@ func (ts *ImplicitGCPCloudStorage) GetSignedURL(
 		},
 	}
 
-	return storage.SignedURL(ts.bucketName, key, options)
+	signedURL, err := storage.SignedURL(ts.bucketName, key, options)
+	if err != nil {
+		return "", err
+	}
+
+	// the requester pays buckets need the project billed for the download
+	signedURL, err = withUnsignedParameter(signedURL, "userProject", ts.userProject)
+	if err != nil {
+		return "", err
+	}
+
+	return withResponseContentDisposition(signedURL, opts.ContentDisposition)
 }
 
 func (ts *ImplicitGCPCloudStorage) Write(
@@ -208,32 +267,56 @@ func (ts *ImplicitGCPCloudStorage) Write(
 	key string,
 	body []byte,
 	contentType *string,
+	opts ...WriteOption,
 ) error {
-	options := &blob.WriterOptions{}
-	if contentType != nil {
-		options.ContentType = *contentType
+	call := writeOptions(opts)
+
+	if err := ts.bucket.WriteAll(ctx, key, body, call.writeAllOptions(key, body, contentType)); err != nil {
+		return translateError(err)
 	}
 
-	return ts.bucket.WriteAll(ctx, key, body, options)
+	call.written.setFromGCSWriter()
+
+	return nil
 }
 
 func (ts *ImplicitGCPCloudStorage) Delete(
 	ctx context.Context,
 	key string,
 ) error {
-	return ts.client.Bucket(ts.bucketName).Object(key).Delete(ctx)
+	return translateError(ts.client.Bucket(ts.bucketName).Object(key).Delete(ctx))
+}
+
+// WriteIf writes the object when the condition is met, and returns its new generation
+func (ts *ImplicitGCPCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	return gcpWriteIf(ctx, ts.client, ts.bucketName, key, body, contentType, condition)
+}
+
+func (ts *ImplicitGCPCloudStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	return gcpDeleteIf(ctx, ts.client, ts.bucketName, key, generation)
 }
 
 func (ts *ImplicitGCPCloudStorage) Attributes(
 	ctx context.Context,
 	key string,
+	opts ...ReadOption,
 ) (*Attributes, error) {
 	attrs, err := ts.bucket.Attributes(ctx, key)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 
-	return &Attributes{
+	result := &Attributes{
 		CacheControl:       attrs.CacheControl,
 		ContentDisposition: attrs.ContentDisposition,
 		ContentEncoding:    attrs.ContentEncoding,
@@ -243,7 +326,240 @@ func (ts *ImplicitGCPCloudStorage) Attributes(
 		ModTime:            attrs.ModTime,
 		Size:               attrs.Size,
 		MD5:                attrs.MD5,
-	}, nil
+	}
+
+	result.setVersion(attrs)
+
+	return result, nil
+}
+
+func (ts *ImplicitGCPCloudStorage) SetObjectRetention(
+	ctx context.Context,
+	key string,
+	retention *ObjectRetention,
+) error {
+	// GCP retention is configured with the bucket retention policy
+	return ErrNotSupported
+}
+
+func (ts *ImplicitGCPCloudStorage) GetObjectRetention(
+	ctx context.Context,
+	key string,
+) (*ObjectRetention, error) {
+	return gcpGetObjectRetention(ctx, ts.client, ts.bucketName, key)
+}
+
+func (ts *ImplicitGCPCloudStorage) SetLegalHold(
+	ctx context.Context,
+	key string,
+	enabled bool,
+) error {
+	return gcpSetLegalHold(ctx, ts.client, ts.bucketName, key, enabled)
+}
+
+func (ts *ImplicitGCPCloudStorage) GetLegalHold(
+	ctx context.Context,
+	key string,
+) (bool, error) {
+	return gcpGetLegalHold(ctx, ts.client, ts.bucketName, key)
+}
+
+// SetStorageClass rewrites the object with the storage class
+func (ts *ImplicitGCPCloudStorage) SetStorageClass(
+	ctx context.Context,
+	key string,
+	storageClass string,
+) error {
+	return gcpSetStorageClass(ctx, ts.client, ts.bucketName, key, storageClass)
+}
+
+// Copy copies the object within the bucket with a rewrite
+func (ts *ImplicitGCPCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	return gcpCopy(ctx, ts.client, ts.bucketName, srcKey, dstKey, opts)
+}
+
+// Undelete copies the last noncurrent generation of the object back in a versioned bucket
+func (ts *ImplicitGCPCloudStorage) Undelete(
+	ctx context.Context,
+	key string,
+) error {
+	return gcpUndelete(ctx, ts.client, ts.bucketName, key)
+}
+
+func (ts *ImplicitGCPCloudStorage) GetScopedCredentials(
+	ctx context.Context,
+	opts *ScopedCredentialsOption,
+) (*ScopedCredentials, error) {
+	return gcpGetScopedCredentials(ctx, ts.creds.TokenSource, ts.bucketName, opts, ts.clock())
+}
+
+func (ts *ImplicitGCPCloudStorage) CreateFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	return ts.folders.create(ctx, folder)
+}
+
+func (ts *ImplicitGCPCloudStorage) DeleteFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	return ts.folders.delete(ctx, folder)
+}
+
+func (ts *ImplicitGCPCloudStorage) RenameFolder(
+	ctx context.Context,
+	folder string,
+	newFolder string,
+) error {
+	return ts.folders.rename(ctx, folder, newFolder)
+}
+
+func (ts *ImplicitGCPCloudStorage) GetBucketPolicy(
+	ctx context.Context,
+) (*BucketPolicy, error) {
+	return gcpGetBucketPolicy(ctx, ts.client, ts.bucketName)
+}
+
+func (ts *ImplicitGCPCloudStorage) SetBucketPolicy(
+	ctx context.Context,
+	policy *BucketPolicy,
+) error {
+	return gcpSetBucketPolicy(ctx, ts.client, ts.bucketName, policy)
+}
+
+func (ts *ImplicitGCPCloudStorage) GetBucketEncryption(
+	ctx context.Context,
+) (*BucketEncryption, error) {
+	return gcpGetBucketEncryption(ctx, ts.client, ts.bucketName)
+}
+
+func (ts *ImplicitGCPCloudStorage) SetBucketEncryption(
+	ctx context.Context,
+	encryption *BucketEncryption,
+) error {
+	return gcpSetBucketEncryption(ctx, ts.client, ts.bucketName, encryption)
+}
+
+func (ts *ImplicitGCPCloudStorage) GetBucketLogging(
+	ctx context.Context,
+) (*BucketLogging, error) {
+	return gcpGetBucketLogging(ctx, ts.client, ts.bucketName)
+}
+
+func (ts *ImplicitGCPCloudStorage) SetBucketLogging(
+	ctx context.Context,
+	logging *BucketLogging,
+) error {
+	return gcpSetBucketLogging(ctx, ts.client, ts.bucketName, logging)
+}
+
+func (ts *ImplicitGCPCloudStorage) GetBucketReplication(
+	ctx context.Context,
+) (*BucketReplication, error) {
+	return gcpGetBucketReplication(ctx, ts.folders)
+}
+
+func (ts *ImplicitGCPCloudStorage) SetBucketReplication(
+	ctx context.Context,
+	replication *BucketReplication,
+) error {
+	return gcpSetBucketReplication(ctx, ts.folders, replication)
+}
+
+func (ts *ImplicitGCPCloudStorage) GetPublicURL(
+	key string,
+) string {
+	return gcpPublicURL("https", gcpPublicHost, ts.bucketName, key)
+}
+
+// As sets target to the GCS client when it's a **storage.Client
+func (ts *ImplicitGCPCloudStorage) As(target interface{}) bool {
+	return ts.bucket.As(target)
+}
+
+// ErrorAs sets target to the GCS error wrapped by err when it's a **googleapi.Error
+func (ts *ImplicitGCPCloudStorage) ErrorAs(err error, target interface{}) bool {
+	return bucketErrorAs(ts.bucket, err, target)
+}
+
+func (ts *ImplicitGCPCloudStorage) Ping(
+	ctx context.Context,
+) error {
+	return gcpPing(ctx, ts.client, ts.bucketName)
+}
+
+func (ts *ImplicitGCPCloudStorage) Subscribe(
+	ctx context.Context,
+	prefix string,
+) (<-chan ObjectEvent, error) {
+	return gcpSubscribe(ctx, ts.client, ts.creds, ts.bucketName, ts.notificationQueue, prefix, ts.logger)
+}
+
+// ListIncompleteUploads returns no upload, the resumable sessions of GCS can't be listed.
+// They aren't billed, and they expire a week after they are initiated.
+func (ts *ImplicitGCPCloudStorage) ListIncompleteUploads(
+	ctx context.Context,
+	prefix string,
+) ([]*IncompleteUpload, error) {
+	return nil, nil
+}
+
+// AbortStaleUploads does nothing, the resumable sessions of GCS expire a week after they are initiated
+func (ts *ImplicitGCPCloudStorage) AbortStaleUploads(
+	ctx context.Context,
+	olderThan time.Duration,
+) ([]*IncompleteUpload, error) {
+	return nil, nil
+}
+
+// Query runs the SQL on the client side while streaming the object, GCS has no equivalent of S3 Select
+func (ts *ImplicitGCPCloudStorage) Query(
+	ctx context.Context,
+	key string,
+	sql string,
+	format QueryInputFormat,
+) (io.ReadCloser, error) {
+	return queryObject(ctx, ts, key, sql, format)
+}
+
+func (ts *ImplicitGCPCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	return getWithAttributes(ctx, ts.bucket, key)
+}
+
+func (ts *ImplicitGCPCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	return gcpUpload(ctx, ts.client, ts.bucketName, key, reader, opts, writeOpts, ts.logger)
+}
+
+// BeginUpload uploads the parts as temporary objects, composed into the object on completion
+func (ts *ImplicitGCPCloudStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	return gcpBeginUpload(ts.client, ts.bucketName, key, opts), nil
+}
+
+func (ts *ImplicitGCPCloudStorage) ResumeUpload(
+	ctx context.Context,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	return gcpResumeUpload(ctx, ts.client, ts.bucketName, state)
 }
 
 func getDefaultServiceAccountEmail(
@@ -26,7 +26,6 @@ import (
 	"time"
 
 	"cloud.google.com/go/storage"
-	"github.com/sirupsen/logrus"
 	"gocloud.dev/blob"
 	"gocloud.dev/blob/gcsblob"
 	"gocloud.dev/gcp"
@@ -36,11 +35,13 @@ import (
 )
 
 type GCPTestCloudStorage struct {
-	client          *storage.Client
-	bucket          *blob.Bucket
-	bucketName      string
-	host            string
-	bucketCloseFunc func()
+	client                   *storage.Client
+	bucket                   *blob.Bucket
+	bucketName               string
+	host                     string
+	uniformBucketLevelAccess bool
+	logger                   Logger
+	bucketCloseFunc          func()
 }
 
 // nolint:funlen
@@ -48,6 +49,7 @@ func newGCPTestCloudStorage(
 	ctx context.Context,
 	gcpCredentialJSON string,
 	bucketName string,
+	cloudStorageOpts *CloudStorageOption,
 ) (*GCPTestCloudStorage, error) {
 	// validation
 	host := os.Getenv("STORAGE_EMULATOR_HOST")
@@ -57,11 +59,17 @@ func newGCPTestCloudStorage(
 	}
 
 	// create vanilla GCP client
-	// nolint:gosec
-	transCfg := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // ignore expired SSL certificates
+	transCfg := &http.Transport{}
+	if cloudStorageOpts.HTTPTransport != nil {
+		transCfg = cloudStorageOpts.HTTPTransport.newTransport()
 	}
-	httpClient := &http.Client{Transport: transCfg}
+
+	// nolint:gosec
+	transCfg.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} // ignore expired SSL certificates
+	httpClient := &http.Client{Transport: withGCPUserProject(
+		withRequestAttribution(withWireLog(transCfg, cloudStorageOpts), cloudStorageOpts),
+		cloudStorageOpts,
+	)}
 
 	client, err := storage.NewClient(
 		context.TODO(),
@@ -81,7 +89,7 @@ func newGCPTestCloudStorage(
 	}
 
 	bucketHTTPClient, err := gcp.NewHTTPClient(
-		gcp.DefaultTransport(),
+		newGCPTransport(cloudStorageOpts),
 		gcp.CredentialsTokenSource(gcpCreds),
 	)
 	if err != nil {
@@ -98,13 +106,16 @@ func newGCPTestCloudStorage(
 		return nil, err
 	}
 
-	logrus.Infof("GCPTestCloudStorage created")
+	logger := loggerOrNoop(cloudStorageOpts.Logger)
+	logger.Info("GCPTestCloudStorage created", Fields{"bucket": bucketName})
 
 	return &GCPTestCloudStorage{
-		client:     client,
-		host:       host,
-		bucketName: bucketName,
-		bucket:     bucket,
+		client:                   client,
+		host:                     host,
+		bucketName:               bucketName,
+		bucket:                   bucket,
+		uniformBucketLevelAccess: cloudStorageOpts.GCPUniformBucketLevelAccess,
+		logger:                   logger,
 		bucketCloseFunc: func() {
 			bucket.Close()
 		},
@@ -114,12 +125,16 @@ func newGCPTestCloudStorage(
 func (ts *GCPTestCloudStorage) List(
 	ctx context.Context,
 	prefix string,
+	opts ...ListOption,
 ) *ListIterator {
 	iter := ts.client.Bucket(ts.bucketName).Objects(ctx, &storage.Query{
 		Prefix: prefix,
 	})
 
-	return newListIterator(func() (*ListObject, error) {
+	// the iterator of the client keeps returning its first error, so the pages aren't retried
+	noRetry := RetryPolicy{MaxAttempts: 1}.withDefaults()
+
+	return newResumableListIterator(ctx, listOptions(opts).resumeFrom, noRetry, func(ctx context.Context) (*ListObject, error) {
 		attrs, err := iter.Next()
 		if err == iterator.Done {
 			return nil, io.EOF
@@ -130,10 +145,12 @@ func (ts *GCPTestCloudStorage) List(
 		}
 
 		return &ListObject{
-			Key:     attrs.Name,
-			ModTime: attrs.Updated,
-			Size:    attrs.Size,
-			MD5:     attrs.MD5,
+			Key:          attrs.Name,
+			ModTime:      attrs.Updated,
+			Size:         attrs.Size,
+			MD5:          attrs.MD5,
+			ETag:         attrs.Etag,
+			StorageClass: attrs.StorageClass,
 		}, nil
 	})
 }
@@ -141,31 +158,59 @@ func (ts *GCPTestCloudStorage) List(
 func (ts *GCPTestCloudStorage) Get(
 	ctx context.Context,
 	key string,
+	opts ...ReadOption,
 ) ([]byte, error) {
-	return ts.bucket.ReadAll(ctx, key)
+	body, err := ts.bucket.ReadAll(ctx, key)
+
+	return body, translateError(err)
 }
 
 func (ts *GCPTestCloudStorage) GetReader(
 	ctx context.Context,
 	key string,
+	opts ...ReadOption,
 ) (io.ReadCloser, error) {
-	return ts.bucket.NewReader(ctx, key, nil)
+	reader, err := ts.bucket.NewReader(ctx, key, nil)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return reader, nil
 }
 
+// GetRangeReader reads the last -offset bytes of the object when the offset is negative
 func (ts *GCPTestCloudStorage) GetRangeReader(
 	ctx context.Context,
 	key string,
 	offset,
 	length int64,
+	opts ...ReadOption,
 ) (io.ReadCloser, error) {
-	return ts.bucket.NewRangeReader(ctx, key, offset, length, nil)
+	if offset < 0 {
+		return gcpTailReader(ctx, ts.client, ts.bucketName, key, -offset, length)
+	}
+
+	reader, err := ts.bucket.NewRangeReader(ctx, key, offset, length, nil)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return reader, nil
 }
 
 func (ts *GCPTestCloudStorage) GetWriter(
 	ctx context.Context,
 	key string,
+	opts ...WriteOption,
 ) (io.WriteCloser, error) {
-	return ts.bucket.NewWriter(ctx, key, nil)
+	call := writeOptions(opts)
+
+	writer, err := ts.bucket.NewWriter(ctx, key, call.writerOptions(key))
+	if err != nil {
+		return nil, err
+	}
+
+	return gcsWriter(writer, call), nil
 }
 
 func (ts *GCPTestCloudStorage) CreateBucket(
@@ -173,12 +218,13 @@ func (ts *GCPTestCloudStorage) CreateBucket(
 	bucketPrefix string,
 	expirationTimeDays int64,
 ) error {
-	logrus.Printf("CreateBucket. Name: %s, Prefix: %s, Exp Time: %v", ts.bucketName, bucketPrefix, expirationTimeDays)
+	ts.logger.Debug("CreateBucket", Fields{"bucket": ts.bucketName, "prefix": bucketPrefix, "expirationTimeDays": expirationTimeDays})
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*10) //nolint:gomnd
 	defer cancel()
 
 	if err := ts.client.Bucket(ts.bucketName).Create(ctx, "", &storage.BucketAttrs{
+		UniformBucketLevelAccess: storage.UniformBucketLevelAccess{Enabled: ts.uniformBucketLevelAccess},
 		Lifecycle: storage.Lifecycle{
 			Rules: []storage.LifecycleRule{
 				{
@@ -198,6 +244,14 @@ func (ts *GCPTestCloudStorage) CreateBucket(
 	return nil
 }
 
+// CreateBucketWithOptions creates the bucket in the emulator, which doesn't need a project
+func (ts *GCPTestCloudStorage) CreateBucketWithOptions(
+	ctx context.Context,
+	opts *CreateBucketOption,
+) error {
+	return gcpCreateBucket(ctx, ts.client, ts.bucketName, opts.GCPProjectID, opts)
+}
+
 func (ts *GCPTestCloudStorage) Close() {
 	ts.bucketCloseFunc()
 }
@@ -215,40 +269,290 @@ func (ts *GCPTestCloudStorage) Write(
 	key string,
 	body []byte,
 	contentType *string,
+	opts ...WriteOption,
 ) error {
-	options := &blob.WriterOptions{}
-	if contentType != nil {
-		options.ContentType = *contentType
+	call := writeOptions(opts)
+
+	if err := ts.bucket.WriteAll(ctx, key, body, call.writeAllOptions(key, body, contentType)); err != nil {
+		return translateError(err)
 	}
 
-	return ts.bucket.WriteAll(ctx, key, body, options)
+	call.written.setFromGCSWriter()
+
+	return nil
 }
 
 func (ts *GCPTestCloudStorage) Delete(
 	ctx context.Context,
 	key string,
 ) error {
-	return ts.client.Bucket(ts.bucketName).Object(key).Delete(ctx)
+	return translateError(ts.client.Bucket(ts.bucketName).Object(key).Delete(ctx))
+}
+
+// WriteIf writes the object when the condition is met, and returns its new generation
+func (ts *GCPTestCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	return gcpWriteIf(ctx, ts.client, ts.bucketName, key, body, contentType, condition)
+}
+
+func (ts *GCPTestCloudStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	return gcpDeleteIf(ctx, ts.client, ts.bucketName, key, generation)
 }
 
 func (ts *GCPTestCloudStorage) Attributes(
 	ctx context.Context,
 	key string,
+	opts ...ReadOption,
 ) (*Attributes, error) {
 	attrs, err := ts.client.Bucket(ts.bucketName).Object(key).Attrs(ctx)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 
-	return &Attributes{
-		CacheControl:       attrs.CacheControl,
-		ContentDisposition: attrs.ContentDisposition,
-		ContentEncoding:    attrs.ContentEncoding,
-		ContentLanguage:    attrs.ContentLanguage,
-		ContentType:        attrs.ContentType,
-		Metadata:           attrs.Metadata,
-		ModTime:            attrs.Updated,
-		Size:               attrs.Size,
-		MD5:                attrs.MD5,
-	}, nil
+	return gcsAttributes(attrs), nil
+}
+
+func (ts *GCPTestCloudStorage) SetObjectRetention(
+	ctx context.Context,
+	key string,
+	retention *ObjectRetention,
+) error {
+	// GCP retention is configured with the bucket retention policy
+	return ErrNotSupported
+}
+
+func (ts *GCPTestCloudStorage) GetObjectRetention(
+	ctx context.Context,
+	key string,
+) (*ObjectRetention, error) {
+	return gcpGetObjectRetention(ctx, ts.client, ts.bucketName, key)
+}
+
+func (ts *GCPTestCloudStorage) SetLegalHold(
+	ctx context.Context,
+	key string,
+	enabled bool,
+) error {
+	return gcpSetLegalHold(ctx, ts.client, ts.bucketName, key, enabled)
+}
+
+func (ts *GCPTestCloudStorage) GetLegalHold(
+	ctx context.Context,
+	key string,
+) (bool, error) {
+	return gcpGetLegalHold(ctx, ts.client, ts.bucketName, key)
+}
+
+// SetStorageClass rewrites the object with the storage class
+func (ts *GCPTestCloudStorage) SetStorageClass(
+	ctx context.Context,
+	key string,
+	storageClass string,
+) error {
+	return gcpSetStorageClass(ctx, ts.client, ts.bucketName, key, storageClass)
+}
+
+// Copy copies the object within the bucket with a rewrite
+func (ts *GCPTestCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	return gcpCopy(ctx, ts.client, ts.bucketName, srcKey, dstKey, opts)
+}
+
+// Undelete copies the last noncurrent generation of the object back in a versioned bucket
+func (ts *GCPTestCloudStorage) Undelete(
+	ctx context.Context,
+	key string,
+) error {
+	return gcpUndelete(ctx, ts.client, ts.bucketName, key)
+}
+
+// GetScopedCredentials isn't supported, the emulator has no token service
+func (ts *GCPTestCloudStorage) GetScopedCredentials(
+	ctx context.Context,
+	opts *ScopedCredentialsOption,
+) (*ScopedCredentials, error) {
+	return nil, ErrNotSupported
+}
+
+// CreateFolder isn't supported, the emulator has no hierarchical namespace
+func (ts *GCPTestCloudStorage) CreateFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	return ErrNotSupported
+}
+
+// DeleteFolder isn't supported, the emulator has no hierarchical namespace
+func (ts *GCPTestCloudStorage) DeleteFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	return ErrNotSupported
+}
+
+// RenameFolder isn't supported, the emulator has no hierarchical namespace
+func (ts *GCPTestCloudStorage) RenameFolder(
+	ctx context.Context,
+	folder string,
+	newFolder string,
+) error {
+	return ErrNotSupported
+}
+
+func (ts *GCPTestCloudStorage) GetBucketPolicy(
+	ctx context.Context,
+) (*BucketPolicy, error) {
+	return gcpGetBucketPolicy(ctx, ts.client, ts.bucketName)
+}
+
+func (ts *GCPTestCloudStorage) SetBucketPolicy(
+	ctx context.Context,
+	policy *BucketPolicy,
+) error {
+	return gcpSetBucketPolicy(ctx, ts.client, ts.bucketName, policy)
+}
+
+func (ts *GCPTestCloudStorage) GetBucketEncryption(
+	ctx context.Context,
+) (*BucketEncryption, error) {
+	return gcpGetBucketEncryption(ctx, ts.client, ts.bucketName)
+}
+
+func (ts *GCPTestCloudStorage) SetBucketEncryption(
+	ctx context.Context,
+	encryption *BucketEncryption,
+) error {
+	return gcpSetBucketEncryption(ctx, ts.client, ts.bucketName, encryption)
+}
+
+func (ts *GCPTestCloudStorage) GetBucketLogging(
+	ctx context.Context,
+) (*BucketLogging, error) {
+	return gcpGetBucketLogging(ctx, ts.client, ts.bucketName)
+}
+
+func (ts *GCPTestCloudStorage) SetBucketLogging(
+	ctx context.Context,
+	logging *BucketLogging,
+) error {
+	return gcpSetBucketLogging(ctx, ts.client, ts.bucketName, logging)
+}
+
+// GetBucketReplication isn't supported, the emulator has no replication
+func (ts *GCPTestCloudStorage) GetBucketReplication(
+	ctx context.Context,
+) (*BucketReplication, error) {
+	return nil, ErrNotSupported
+}
+
+// SetBucketReplication isn't supported, the emulator has no replication
+func (ts *GCPTestCloudStorage) SetBucketReplication(
+	ctx context.Context,
+	replication *BucketReplication,
+) error {
+	return ErrNotSupported
+}
+
+func (ts *GCPTestCloudStorage) GetPublicURL(
+	key string,
+) string {
+	return gcpPublicURL("http", ts.host, ts.bucketName, key)
+}
+
+// As sets target to the GCS client when it's a **storage.Client
+func (ts *GCPTestCloudStorage) As(target interface{}) bool {
+	return ts.bucket.As(target)
+}
+
+// ErrorAs sets target to the GCS error wrapped by err when it's a **googleapi.Error
+func (ts *GCPTestCloudStorage) ErrorAs(err error, target interface{}) bool {
+	return bucketErrorAs(ts.bucket, err, target)
+}
+
+func (ts *GCPTestCloudStorage) Ping(
+	ctx context.Context,
+) error {
+	return gcpPing(ctx, ts.client, ts.bucketName)
+}
+
+// Subscribe isn't supported, the GCS emulator doesn't send notifications
+func (ts *GCPTestCloudStorage) Subscribe(
+	ctx context.Context,
+	prefix string,
+) (<-chan ObjectEvent, error) {
+	return nil, ErrNotSupported
+}
+
+// ListIncompleteUploads returns no upload, the resumable sessions of GCS can't be listed.
+// They aren't billed, and they expire a week after they are initiated.
+func (ts *GCPTestCloudStorage) ListIncompleteUploads(
+	ctx context.Context,
+	prefix string,
+) ([]*IncompleteUpload, error) {
+	return nil, nil
+}
+
+// AbortStaleUploads does nothing, the resumable sessions of GCS expire a week after they are initiated
+func (ts *GCPTestCloudStorage) AbortStaleUploads(
+	ctx context.Context,
+	olderThan time.Duration,
+) ([]*IncompleteUpload, error) {
+	return nil, nil
+}
+
+// Query runs the SQL on the client side while streaming the object, GCS has no equivalent of S3 Select
+func (ts *GCPTestCloudStorage) Query(
+	ctx context.Context,
+	key string,
+	sql string,
+	format QueryInputFormat,
+) (io.ReadCloser, error) {
+	return queryObject(ctx, ts, key, sql, format)
+}
+
+func (ts *GCPTestCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	return getWithAttributes(ctx, ts.bucket, key)
+}
+
+func (ts *GCPTestCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	return gcpUpload(ctx, ts.client, ts.bucketName, key, reader, opts, writeOpts, ts.logger)
+}
+
+// BeginUpload uploads the parts as temporary objects, composed into the object on completion
+func (ts *GCPTestCloudStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	return gcpBeginUpload(ts.client, ts.bucketName, key, opts), nil
+}
+
+func (ts *GCPTestCloudStorage) ResumeUpload(
+	ctx context.Context,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	return gcpResumeUpload(ctx, ts.client, ts.bucketName, state)
 }
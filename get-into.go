@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrBufferTooSmall is returned by GetInto when the object doesn't fit into the buffer
+var ErrBufferTooSmall = errors.New("buffer is too small for the object")
+
+// GetInto reads the object into the caller-provided buffer and returns the number of bytes read,
+// so reading many small objects doesn't allocate a new body every time.
+// It returns ErrBufferTooSmall if the object is larger than the buffer.
+func GetInto(
+	ctx context.Context,
+	storage CloudStorage,
+	key string,
+	buf []byte,
+) (int, error) {
+	reader, err := storage.GetReader(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	// fail fast without downloading anything when the size is known
+	if sized, ok := reader.(interface{ Size() int64 }); ok && sized.Size() > int64(len(buf)) {
+		return 0, ErrBufferTooSmall
+	}
+
+	n, err := io.ReadFull(reader, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return n, nil
+	}
+
+	if err != nil {
+		return n, err
+	}
+
+	// the buffer is full, make sure nothing is left
+	var extra [1]byte
+
+	if _, err := io.ReadFull(reader, extra[:]); err != io.EOF {
+		if err != nil {
+			return n, err
+		}
+
+		return n, ErrBufferTooSmall
+	}
+
+	return n, nil
+}
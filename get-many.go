@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultGetManyConcurrency is the number of objects read in parallel by GetMany and AttributesMany
+// when concurrency isn't positive
+const DefaultGetManyConcurrency = 8
+
+// GetResult is the body of an object read by GetMany, or the error of its read
+type GetResult struct {
+	Body []byte
+	Err  error
+}
+
+// AttributesResult is the attributes of an object returned by AttributesMany, or the error of the request
+type AttributesResult struct {
+	Attributes *Attributes
+	Err        error
+}
+
+// GetMany reads the objects in parallel, concurrency at a time, and returns their results by key.
+// A failed read doesn't stop the others, e.g. the missing objects have an error matching ErrNotFound.
+// The reads not started yet when ctx is done fail with its error.
+func GetMany(
+	ctx context.Context,
+	storage CloudStorage,
+	keys []string,
+	concurrency int,
+	opts ...ReadOption,
+) map[string]*GetResult {
+	results := make(map[string]*GetResult, len(keys))
+	for _, key := range keys {
+		results[key] = &GetResult{}
+	}
+
+	skipped := forEachKey(ctx, keys, concurrency, func(key string) {
+		result := results[key]
+		result.Body, result.Err = storage.Get(ctx, key, opts...)
+	})
+
+	for key, err := range skipped {
+		results[key].Err = err
+	}
+
+	return results
+}
+
+// AttributesMany returns the attributes of the objects, requested in parallel concurrency at a time,
+// like GetMany
+func AttributesMany(
+	ctx context.Context,
+	storage CloudStorage,
+	keys []string,
+	concurrency int,
+	opts ...ReadOption,
+) map[string]*AttributesResult {
+	results := make(map[string]*AttributesResult, len(keys))
+	for _, key := range keys {
+		results[key] = &AttributesResult{}
+	}
+
+	skipped := forEachKey(ctx, keys, concurrency, func(key string) {
+		result := results[key]
+		result.Attributes, result.Err = storage.Attributes(ctx, key, opts...)
+	})
+
+	for key, err := range skipped {
+		results[key].Err = err
+	}
+
+	return results
+}
+
+// forEachKey calls f once per distinct key, concurrency at a time, and returns the keys skipped once ctx is done
+// with its error
+func forEachKey(ctx context.Context, keys []string, concurrency int, f func(key string)) map[string]error {
+	if concurrency <= 0 {
+		concurrency = DefaultGetManyConcurrency
+	}
+
+	var wg sync.WaitGroup
+
+	seen := make(map[string]bool, len(keys))
+	skipped := make(map[string]error)
+	semaphore := make(chan struct{}, concurrency)
+
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+
+		if err := ctx.Err(); err != nil {
+			skipped[key] = err
+			continue
+		}
+
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			skipped[key] = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(key string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			f(key)
+		}(key)
+	}
+
+	wg.Wait()
+
+	return skipped
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMany(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.Write(ctx, "a", []byte("body a"), nil))
+	require.NoError(t, storage.Write(ctx, "b", []byte("body b"), nil))
+
+	results := GetMany(ctx, storage, []string{"a", "b", "missing", "a"}, 2)
+	require.Len(t, results, 3)
+
+	require.NoError(t, results["a"].Err)
+	require.Equal(t, []byte("body a"), results["a"].Body)
+	require.NoError(t, results["b"].Err)
+	require.Equal(t, []byte("body b"), results["b"].Body)
+	require.True(t, errors.Is(results["missing"].Err, ErrNotFound))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	results = GetMany(cancelled, storage, []string{"a", "b"}, 1)
+	require.Error(t, results["a"].Err)
+	require.Error(t, results["b"].Err)
+}
+
+func TestAttributesMany(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.Write(ctx, "a", []byte("body a"), nil))
+
+	results := AttributesMany(ctx, storage, []string{"a", "missing"}, 0)
+	require.Len(t, results, 2)
+
+	require.NoError(t, results["a"].Err)
+	require.Equal(t, int64(6), results["a"].Attributes.Size)
+	require.True(t, errors.Is(results["missing"].Err, ErrNotFound))
+}
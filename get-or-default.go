@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// GetOrDefault returns the object, or def when it doesn't exist.
+// The other errors are returned as is.
+func GetOrDefault(
+	ctx context.Context,
+	storage CloudStorage,
+	key string,
+	def []byte,
+	opts ...ReadOption,
+) ([]byte, error) {
+	body, err := storage.Get(ctx, key, opts...)
+	if errors.Is(err, ErrNotFound) {
+		return def, nil
+	}
+
+	return body, err
+}
+
+// GetJSONOrDefault decodes the JSON object into v, a pointer, or sets v to def when the object doesn't exist.
+// def must be assignable to the value pointed by v, a nil def leaves v as is.
+func GetJSONOrDefault(
+	ctx context.Context,
+	storage CloudStorage,
+	key string,
+	v interface{},
+	def interface{},
+	opts ...ReadOption,
+) error {
+	body, err := storage.Get(ctx, key, opts...)
+	if errors.Is(err, ErrNotFound) {
+		return setDefault(v, def)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+func setDefault(v interface{}, def interface{}) error {
+	if def == nil {
+		return nil
+	}
+
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return fmt.Errorf("can't set the default into a %T, a non-nil pointer is expected", v)
+	}
+
+	value := reflect.ValueOf(def)
+	if !value.Type().AssignableTo(target.Elem().Type()) {
+		return fmt.Errorf("can't set the default %T into a %T", def, v)
+	}
+
+	target.Elem().Set(value)
+
+	return nil
+}
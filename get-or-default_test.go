@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOrDefault(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	body, err := GetOrDefault(ctx, storage, "missing", []byte("default"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("default"), body)
+
+	require.NoError(t, storage.Write(ctx, "key", []byte("body"), nil))
+
+	body, err = GetOrDefault(ctx, storage, "key", []byte("default"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("body"), body)
+
+	// the other errors aren't replaced by the default
+	failing := NewFaultInjectingCloudStorage(storage, FaultInjectionOption{Default: Fault{ErrorRate: 1}})
+
+	_, err = GetOrDefault(ctx, failing, "key", []byte("default"))
+	require.Error(t, err)
+	require.False(t, errors.Is(err, ErrNotFound))
+}
+
+func TestGetJSONOrDefault(t *testing.T) {
+	type config struct {
+		Limit int `json:"limit"`
+	}
+
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	var cfg config
+	require.NoError(t, GetJSONOrDefault(ctx, storage, "config.json", &cfg, config{Limit: 10}))
+	require.Equal(t, config{Limit: 10}, cfg)
+
+	require.NoError(t, storage.Write(ctx, "config.json", []byte(`{"limit": 25}`), nil))
+	require.NoError(t, GetJSONOrDefault(ctx, storage, "config.json", &cfg, config{Limit: 10}))
+	require.Equal(t, config{Limit: 25}, cfg)
+
+	require.Error(t, GetJSONOrDefault(ctx, storage, "missing", &cfg, "not a config"))
+}
@@ -4,13 +4,22 @@ go 1.13
 
 require (
 	cloud.google.com/go v0.58.0
+	cloud.google.com/go/pubsub v1.3.1
 	cloud.google.com/go/storage v1.9.0
 	github.com/aws/aws-sdk-go v1.40.50
+	github.com/golang/protobuf v1.4.2
 	github.com/google/uuid v1.1.1
-	github.com/sirupsen/logrus v1.6.0
-	github.com/stretchr/testify v1.5.1
+	github.com/spf13/afero v1.6.0
+	github.com/stretchr/testify v1.7.0
+	github.com/xeipuuv/gojsonschema v1.2.0
+	go.opentelemetry.io/otel v1.0.0
+	go.opentelemetry.io/otel/trace v1.0.0
 	gocloud.dev v0.20.0
+	golang.org/x/net v0.0.0-20210614182718-04defd469f4e
 	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
+	golang.org/x/time v0.0.0-20191024005414-555d28b269f0
 	google.golang.org/api v0.26.0
 	google.golang.org/genproto v0.0.0-20200608115520-7c474a2e3482
+	google.golang.org/grpc v1.29.1
+	google.golang.org/protobuf v1.24.0
 )
@@ -0,0 +1,569 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package grpcblob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"time"
+
+	commonblobgo "github.com/AccelByte/common-blob-go"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc"
+)
+
+// Client is a CloudStorage going through a BlobService gateway.
+// The errors match the sentinel errors of commonblobgo with errors.Is.
+// The metadata and storage class of the WriteOption aren't sent to the gateway, and the written attributes
+// aren't returned.
+// The operations without an RPC return commonblobgo.ErrNotSupported.
+type Client struct {
+	client BlobServiceClient
+}
+
+var _ commonblobgo.CloudStorage = (*Client)(nil)
+
+// NewClient returns a Client using the connection, which is left open by Close
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{client: NewBlobServiceClient(conn)}
+}
+
+func (c *Client) List(
+	ctx context.Context,
+	prefix string,
+	opts ...commonblobgo.ListOption,
+) *commonblobgo.ListIterator {
+	stream, err := c.client.List(ctx, &ListRequest{Prefix: prefix})
+
+	return commonblobgo.NewListIterator(ctx, func(ctx context.Context) (*commonblobgo.ListObject, error) {
+		if err != nil {
+			return nil, fromStatus(err)
+		}
+
+		response, err := stream.Recv()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+
+		if err != nil {
+			return nil, fromStatus(err)
+		}
+
+		modTime, err := ptypes.Timestamp(response.ModTime)
+		if err != nil {
+			return nil, err
+		}
+
+		return &commonblobgo.ListObject{
+			Key:     response.Key,
+			ModTime: modTime,
+			Size:    response.Size,
+			MD5:     response.Md5,
+		}, nil
+	})
+}
+
+func (c *Client) Get(
+	ctx context.Context,
+	key string,
+	opts ...commonblobgo.ReadOption,
+) ([]byte, error) {
+	reader, err := c.GetReader(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return ioutil.ReadAll(reader)
+}
+
+func (c *Client) GetReader(
+	ctx context.Context,
+	key string,
+	opts ...commonblobgo.ReadOption,
+) (io.ReadCloser, error) {
+	reader, _, err := c.GetWithAttributes(ctx, key)
+
+	return reader, err
+}
+
+func (c *Client) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...commonblobgo.ReadOption,
+) (io.ReadCloser, *commonblobgo.Attributes, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	stream, err := c.client.Get(ctx, &GetRequest{Key: key})
+	if err != nil {
+		cancel()
+		return nil, nil, fromStatus(err)
+	}
+
+	// the first message carries the attributes, and the error if the object can't be read
+	first, err := stream.Recv()
+	if err != nil {
+		cancel()
+		return nil, nil, fromStatus(err)
+	}
+
+	attrs := &commonblobgo.Attributes{}
+
+	if first.Attributes != nil {
+		modTime, err := ptypes.Timestamp(first.Attributes.ModTime)
+		if err != nil {
+			cancel()
+			return nil, nil, err
+		}
+
+		attrs = &commonblobgo.Attributes{
+			ContentType: first.Attributes.ContentType,
+			Metadata:    first.Attributes.Metadata,
+			ModTime:     modTime,
+			Size:        first.Attributes.Size,
+			MD5:         first.Attributes.Md5,
+		}
+	}
+
+	return &getReader{stream: stream, chunk: first.Chunk, cancel: cancel}, attrs, nil
+}
+
+// getReader reads the chunks of the Get stream, closing it cancels the stream
+type getReader struct {
+	stream BlobService_GetClient
+	chunk  []byte
+	cancel context.CancelFunc
+}
+
+func (r *getReader) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 {
+		response, err := r.stream.Recv()
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+
+		if err != nil {
+			return 0, fromStatus(err)
+		}
+
+		r.chunk = response.Chunk
+	}
+
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+
+	return n, nil
+}
+
+func (r *getReader) Close() error {
+	r.cancel()
+	return nil
+}
+
+func (c *Client) Attributes(
+	ctx context.Context,
+	key string,
+	opts ...commonblobgo.ReadOption,
+) (*commonblobgo.Attributes, error) {
+	reader, attrs, err := c.GetWithAttributes(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// only the first chunk has been received
+	reader.Close()
+
+	return attrs, nil
+}
+
+func (c *Client) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	_, err := c.client.Delete(ctx, &DeleteRequest{Key: key})
+
+	return fromStatus(err)
+}
+
+func (c *Client) GetSignedURL(
+	ctx context.Context,
+	key string,
+	opts *commonblobgo.SignedURLOption,
+) (string, error) {
+	response, err := c.client.SignURL(ctx, &SignURLRequest{
+		Key:         key,
+		Method:      opts.Method,
+		Expiry:      ptypes.DurationProto(opts.Expiry),
+		ContentType: opts.ContentType,
+	})
+	if err != nil {
+		return "", fromStatus(err)
+	}
+
+	return response.Url, nil
+}
+
+func (c *Client) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...commonblobgo.WriteOption,
+) error {
+	uploadOpts := &commonblobgo.UploadOption{}
+	if contentType != nil {
+		uploadOpts.ContentType = *contentType
+	}
+
+	return c.Upload(ctx, key, bytes.NewReader(body), uploadOpts, opts...)
+}
+
+func (c *Client) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...commonblobgo.WriteOption,
+) (io.WriteCloser, error) {
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+
+	go func() {
+		err := c.Upload(ctx, key, reader, nil, opts...)
+		reader.CloseWithError(err)
+		done <- err
+	}()
+
+	return &putWriter{PipeWriter: writer, done: done}, nil
+}
+
+// putWriter returns the result of the upload on Close
+type putWriter struct {
+	*io.PipeWriter
+	done chan error
+}
+
+func (w *putWriter) Close() error {
+	w.PipeWriter.Close()
+
+	return <-w.done
+}
+
+func (c *Client) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *commonblobgo.UploadOption,
+	writeOpts ...commonblobgo.WriteOption,
+) error {
+	// the stream is cancelled if the reader fails, so the server doesn't store a truncated object
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := c.client.Put(ctx)
+	if err != nil {
+		return fromStatus(err)
+	}
+
+	request := &PutRequest{Key: key}
+	if opts != nil {
+		request.ContentType = opts.ContentType
+	}
+
+	buf := make([]byte, chunkSize)
+
+	// the first message is sent even for an empty object, since it carries the key
+	for first := true; ; first = false {
+		n, err := io.ReadFull(reader, buf)
+		if n > 0 || first {
+			request.Chunk = buf[:n]
+
+			if err := stream.Send(request); err != nil {
+				// the error of the server is returned by CloseAndRecv
+				break
+			}
+
+			request = &PutRequest{}
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = stream.CloseAndRecv()
+
+	return fromStatus(err)
+}
+
+func (c *Client) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset,
+	length int64,
+	opts ...commonblobgo.ReadOption,
+) (io.ReadCloser, error) {
+	return nil, commonblobgo.ErrNotSupported
+}
+
+// CreateBucket isn't supported, the gateway serves a single bucket
+func (c *Client) CreateBucket(
+	ctx context.Context,
+	bucketPrefix string,
+	expirationTimeDays int64,
+) error {
+	return commonblobgo.ErrNotSupported
+}
+
+func (c *Client) CreateBucketWithOptions(
+	ctx context.Context,
+	opts *commonblobgo.CreateBucketOption,
+) error {
+	return commonblobgo.ErrNotSupported
+}
+
+// Close does nothing, the connection is owned by the caller
+func (c *Client) Close() {}
+
+func (c *Client) SetObjectRetention(
+	ctx context.Context,
+	key string,
+	retention *commonblobgo.ObjectRetention,
+) error {
+	return commonblobgo.ErrNotSupported
+}
+
+func (c *Client) GetObjectRetention(
+	ctx context.Context,
+	key string,
+) (*commonblobgo.ObjectRetention, error) {
+	return nil, commonblobgo.ErrNotSupported
+}
+
+func (c *Client) SetLegalHold(
+	ctx context.Context,
+	key string,
+	enabled bool,
+) error {
+	return commonblobgo.ErrNotSupported
+}
+
+func (c *Client) GetLegalHold(
+	ctx context.Context,
+	key string,
+) (bool, error) {
+	return false, commonblobgo.ErrNotSupported
+}
+
+func (c *Client) SetStorageClass(
+	ctx context.Context,
+	key string,
+	storageClass string,
+) error {
+	return commonblobgo.ErrNotSupported
+}
+
+func (c *Client) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *commonblobgo.CopyOption,
+) error {
+	return commonblobgo.ErrNotSupported
+}
+
+func (c *Client) Undelete(
+	ctx context.Context,
+	key string,
+) error {
+	return commonblobgo.ErrNotSupported
+}
+
+func (c *Client) GetBucketPolicy(
+	ctx context.Context,
+) (*commonblobgo.BucketPolicy, error) {
+	return nil, commonblobgo.ErrNotSupported
+}
+
+func (c *Client) GetScopedCredentials(
+	ctx context.Context,
+	opts *commonblobgo.ScopedCredentialsOption,
+) (*commonblobgo.ScopedCredentials, error) {
+	return nil, commonblobgo.ErrNotSupported
+}
+
+func (c *Client) CreateFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	return commonblobgo.ErrNotSupported
+}
+
+func (c *Client) DeleteFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	return commonblobgo.ErrNotSupported
+}
+
+func (c *Client) RenameFolder(
+	ctx context.Context,
+	folder string,
+	newFolder string,
+) error {
+	return commonblobgo.ErrNotSupported
+}
+
+func (c *Client) SetBucketPolicy(
+	ctx context.Context,
+	policy *commonblobgo.BucketPolicy,
+) error {
+	return commonblobgo.ErrNotSupported
+}
+
+func (c *Client) GetBucketEncryption(
+	ctx context.Context,
+) (*commonblobgo.BucketEncryption, error) {
+	return nil, commonblobgo.ErrNotSupported
+}
+
+func (c *Client) SetBucketEncryption(
+	ctx context.Context,
+	encryption *commonblobgo.BucketEncryption,
+) error {
+	return commonblobgo.ErrNotSupported
+}
+
+func (c *Client) GetBucketLogging(
+	ctx context.Context,
+) (*commonblobgo.BucketLogging, error) {
+	return nil, commonblobgo.ErrNotSupported
+}
+
+func (c *Client) SetBucketLogging(
+	ctx context.Context,
+	logging *commonblobgo.BucketLogging,
+) error {
+	return commonblobgo.ErrNotSupported
+}
+
+func (c *Client) GetBucketReplication(
+	ctx context.Context,
+) (*commonblobgo.BucketReplication, error) {
+	return nil, commonblobgo.ErrNotSupported
+}
+
+func (c *Client) SetBucketReplication(
+	ctx context.Context,
+	replication *commonblobgo.BucketReplication,
+) error {
+	return commonblobgo.ErrNotSupported
+}
+
+// GetPublicURL returns an empty string, the URL of the bucket isn't known by the gateway clients
+func (c *Client) GetPublicURL(key string) string {
+	return ""
+}
+
+// As returns false, the SDK clients are on the gateway side
+func (c *Client) As(target interface{}) bool {
+	return false
+}
+
+// ErrorAs only matches the errors of the chain of err, the SDK errors aren't sent by the gateway
+func (c *Client) ErrorAs(err error, target interface{}) bool {
+	return errors.As(err, target)
+}
+
+// Subscribe isn't supported, the BlobService doesn't stream the notifications
+func (c *Client) Subscribe(
+	ctx context.Context,
+	prefix string,
+) (<-chan commonblobgo.ObjectEvent, error) {
+	return nil, commonblobgo.ErrNotSupported
+}
+
+func (c *Client) ListIncompleteUploads(
+	ctx context.Context,
+	prefix string,
+) ([]*commonblobgo.IncompleteUpload, error) {
+	return nil, commonblobgo.ErrNotSupported
+}
+
+func (c *Client) Query(
+	ctx context.Context,
+	key string,
+	sql string,
+	format commonblobgo.QueryInputFormat,
+) (io.ReadCloser, error) {
+	return nil, commonblobgo.ErrNotSupported
+}
+
+func (c *Client) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition commonblobgo.WriteCondition,
+) (string, error) {
+	return "", commonblobgo.ErrNotSupported
+}
+
+func (c *Client) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	return commonblobgo.ErrNotSupported
+}
+
+func (c *Client) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *commonblobgo.UploadOption,
+) (*commonblobgo.UploadSession, error) {
+	return nil, commonblobgo.ErrNotSupported
+}
+
+func (c *Client) ResumeUpload(
+	ctx context.Context,
+	state *commonblobgo.UploadSessionState,
+) (*commonblobgo.UploadSession, error) {
+	return nil, commonblobgo.ErrNotSupported
+}
+
+func (c *Client) AbortStaleUploads(
+	ctx context.Context,
+	olderThan time.Duration,
+) ([]*commonblobgo.IncompleteUpload, error) {
+	return nil, commonblobgo.ErrNotSupported
+}
+
+// Ping checks the gateway by listing the first object of the bucket
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.List(ctx, "").Next(ctx)
+	if err == io.EOF {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package grpcblob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	commonblobgo "github.com/AccelByte/common-blob-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusCodes maps the sentinel errors of the package to the gRPC codes, in both directions
+var statusCodes = []struct {
+	err  error
+	code codes.Code
+}{
+	{commonblobgo.ErrNotFound, codes.NotFound},
+	{commonblobgo.ErrAlreadyExists, codes.AlreadyExists},
+	{commonblobgo.ErrPermissionDenied, codes.PermissionDenied},
+	{commonblobgo.ErrUnavailable, codes.Unavailable},
+	{commonblobgo.ErrInvalidKey, codes.InvalidArgument},
+	{commonblobgo.ErrObjectTooLarge, codes.ResourceExhausted},
+	{commonblobgo.ErrNotSupported, codes.Unimplemented},
+	{commonblobgo.ErrPreconditionFailed, codes.FailedPrecondition},
+	{context.Canceled, codes.Canceled},
+	{context.DeadlineExceeded, codes.DeadlineExceeded},
+}
+
+// toStatus returns the error with the gRPC code matching its sentinel error
+func toStatus(err error) error {
+	for _, statusCode := range statusCodes {
+		if errors.Is(err, statusCode.err) {
+			return status.Error(statusCode.code, err.Error())
+		}
+	}
+
+	return status.Error(codes.Unknown, err.Error())
+}
+
+// fromStatus returns an error matching the sentinel error of the gRPC code with errors.Is
+func fromStatus(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	for _, statusCode := range statusCodes {
+		if st.Code() == statusCode.code {
+			return fmt.Errorf("%w: %s", statusCode.err, st.Message())
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+// Package grpcblob serves a CloudStorage over gRPC, so the services without cloud credentials can go through
+// a central storage gateway. Client implements CloudStorage on top of the BlobService.
+package grpcblob
+
+//go:generate protoc --go_out=plugins=grpc,paths=source_relative:. blob.proto
+
+import (
+	"context"
+	"io"
+
+	commonblobgo "github.com/AccelByte/common-blob-go"
+	"github.com/golang/protobuf/ptypes"
+)
+
+// chunkSize is the size of the chunks of the streamed objects, well below the default 4MB message limit of gRPC
+const chunkSize = 64 * 1024
+
+// Server implements the BlobService with a CloudStorage
+type Server struct {
+	storage commonblobgo.CloudStorage
+}
+
+var _ BlobServiceServer = (*Server)(nil)
+
+// NewServer returns a Server over the storage, register it with RegisterBlobServiceServer
+func NewServer(storage commonblobgo.CloudStorage) *Server {
+	return &Server{storage: storage}
+}
+
+func (s *Server) Get(request *GetRequest, stream BlobService_GetServer) error {
+	reader, attrs, err := s.storage.GetWithAttributes(stream.Context(), request.Key)
+	if err != nil {
+		return toStatus(err)
+	}
+	defer reader.Close()
+
+	modTime, err := ptypes.TimestampProto(attrs.ModTime)
+	if err != nil {
+		return toStatus(err)
+	}
+
+	response := &GetResponse{
+		Attributes: &Attributes{
+			ContentType: attrs.ContentType,
+			Size:        attrs.Size,
+			ModTime:     modTime,
+			Md5:         attrs.MD5,
+			Metadata:    attrs.Metadata,
+		},
+	}
+
+	buf := make([]byte, chunkSize)
+
+	for {
+		n, err := io.ReadFull(reader, buf)
+		if n > 0 || response.Attributes != nil {
+			response.Chunk = buf[:n]
+
+			if err := stream.Send(response); err != nil {
+				return err
+			}
+
+			response = &GetResponse{}
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+
+		if err != nil {
+			return toStatus(err)
+		}
+	}
+}
+
+func (s *Server) Put(stream BlobService_PutServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	reader := &putReader{stream: stream, chunk: first.Chunk}
+
+	err = s.storage.Upload(stream.Context(), first.Key, reader, &commonblobgo.UploadOption{
+		ContentType: first.ContentType,
+	})
+	if err != nil {
+		return toStatus(err)
+	}
+
+	return stream.SendAndClose(&PutResponse{Size: reader.size})
+}
+
+// putReader reads the chunks of the Put stream
+type putReader struct {
+	stream BlobService_PutServer
+	chunk  []byte
+	size   int64
+}
+
+func (r *putReader) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 {
+		request, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+
+		r.chunk = request.Chunk
+	}
+
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	r.size += int64(n)
+
+	return n, nil
+}
+
+func (s *Server) List(request *ListRequest, stream BlobService_ListServer) error {
+	iter := s.storage.List(stream.Context(), request.Prefix)
+
+	for {
+		object, err := iter.Next(stream.Context())
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return toStatus(err)
+		}
+
+		modTime, err := ptypes.TimestampProto(object.ModTime)
+		if err != nil {
+			return toStatus(err)
+		}
+
+		err = stream.Send(&ListResponse{
+			Key:     object.Key,
+			Size:    object.Size,
+			ModTime: modTime,
+			Md5:     object.MD5,
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) Delete(ctx context.Context, request *DeleteRequest) (*DeleteResponse, error) {
+	if err := s.storage.Delete(ctx, request.Key); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &DeleteResponse{}, nil
+}
+
+func (s *Server) SignURL(ctx context.Context, request *SignURLRequest) (*SignURLResponse, error) {
+	opts := &commonblobgo.SignedURLOption{
+		Method:      request.Method,
+		ContentType: request.ContentType,
+	}
+
+	if request.Expiry != nil {
+		expiry, err := ptypes.Duration(request.Expiry)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+
+		opts.Expiry = expiry
+	}
+
+	url, err := s.storage.GetSignedURL(ctx, request.Key, opts)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &SignURLResponse{Url: url}, nil
+}
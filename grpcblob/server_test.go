@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package grpcblob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	commonblobgo "github.com/AccelByte/common-blob-go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient serves the storage in memory, the returned function stops the server
+func newTestClient(t *testing.T, storage commonblobgo.CloudStorage) (*Client, func()) {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	RegisterBlobServiceServer(server, NewServer(storage))
+
+	go server.Serve(listener) // nolint:errcheck
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+	)
+	require.NoError(t, err)
+
+	return NewClient(conn), func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func TestClientServer(t *testing.T) {
+	ctx := context.Background()
+	storage := commonblobgo.NewFakeCloudStorage("bucket")
+	client, stop := newTestClient(t, storage)
+	defer stop()
+
+	contentType := "text/plain"
+	require.NoError(t, client.Write(ctx, "reports/a.txt", []byte("hello"), &contentType))
+
+	body, err := storage.Get(ctx, "reports/a.txt")
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), body)
+
+	body, err = client.Get(ctx, "reports/a.txt")
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), body)
+
+	attrs, err := client.Attributes(ctx, "reports/a.txt")
+	require.NoError(t, err)
+	require.Equal(t, "text/plain", attrs.ContentType)
+	require.Equal(t, int64(5), attrs.Size)
+
+	// the object is larger than a chunk
+	large := bytes.Repeat([]byte("0123456789"), chunkSize/4)
+	require.NoError(t, client.Upload(ctx, "reports/large.bin", bytes.NewReader(large), nil))
+
+	reader, attrs, err := client.GetWithAttributes(ctx, "reports/large.bin")
+	require.NoError(t, err)
+	require.Equal(t, int64(len(large)), attrs.Size)
+
+	body, err = ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	require.Equal(t, large, body)
+
+	writer, err := client.GetWriter(ctx, "reports/b.txt")
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("streamed"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	body, err = storage.Get(ctx, "reports/b.txt")
+	require.NoError(t, err)
+	require.Equal(t, []byte("streamed"), body)
+
+	var keys []string
+
+	iter := client.List(ctx, "reports/")
+	for {
+		object, err := iter.Next(ctx)
+		if err != nil {
+			break
+		}
+
+		keys = append(keys, object.Key)
+	}
+
+	require.ElementsMatch(t, []string{"reports/a.txt", "reports/large.bin", "reports/b.txt"}, keys)
+
+	url, err := client.GetSignedURL(ctx, "reports/a.txt", &commonblobgo.SignedURLOption{Expiry: time.Minute})
+	require.NoError(t, err)
+	require.NotEmpty(t, url)
+
+	require.NoError(t, client.Delete(ctx, "reports/a.txt"))
+
+	_, err = client.Get(ctx, "reports/a.txt")
+	require.True(t, errors.Is(err, commonblobgo.ErrNotFound))
+
+	require.NoError(t, client.Ping(ctx))
+}
+
+func TestClientNotSupported(t *testing.T) {
+	client, stop := newTestClient(t, commonblobgo.NewFakeCloudStorage("bucket"))
+	defer stop()
+
+	_, err := client.GetRangeReader(context.Background(), "a.txt", 0, 1)
+	require.True(t, errors.Is(err, commonblobgo.ErrNotSupported))
+}
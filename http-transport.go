@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"gocloud.dev/gcp"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+)
+
+const defaultDialTimeout = 30 * time.Second
+
+// HTTPTransportOption tunes the connection pool of the HTTP transport used by the providers.
+// Zero values keep the defaults of http.DefaultTransport.
+type HTTPTransportOption struct {
+	// MaxIdleConns limits the number of idle connections across all hosts.
+	MaxIdleConns int
+	// MaxIdleConnsPerHost limits the number of idle connections kept per host.
+	// The Go default of 2 is usually too low for highly concurrent services.
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost limits the total number of connections per host.
+	MaxConnsPerHost int
+	// IdleConnTimeout is how long an idle connection stays in the pool.
+	IdleConnTimeout time.Duration
+	// KeepAlive is the interval of the TCP keep-alive probes.
+	KeepAlive time.Duration
+	// DisableKeepAlives disables the reuse of connections.
+	DisableKeepAlives bool
+}
+
+func (o *HTTPTransportOption) newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if o.KeepAlive != 0 {
+		transport.DialContext = (&net.Dialer{
+			Timeout:   defaultDialTimeout,
+			KeepAlive: o.KeepAlive,
+		}).DialContext
+	}
+
+	if o.MaxIdleConns != 0 {
+		transport.MaxIdleConns = o.MaxIdleConns
+	}
+
+	if o.MaxIdleConnsPerHost != 0 {
+		transport.MaxIdleConnsPerHost = o.MaxIdleConnsPerHost
+	}
+
+	if o.MaxConnsPerHost != 0 {
+		transport.MaxConnsPerHost = o.MaxConnsPerHost
+	}
+
+	if o.IdleConnTimeout != 0 {
+		transport.IdleConnTimeout = o.IdleConnTimeout
+	}
+
+	transport.DisableKeepAlives = o.DisableKeepAlives
+
+	return transport
+}
+
+// newAWSConfig returns the AWS configuration shared by the AWS clients
+func newAWSConfig(cloudStorageOpts *CloudStorageOption) aws.Config {
+	var awsConfig aws.Config
+
+	// the retry policy replaces the retries of the SDK
+	if cloudStorageOpts.RetryPolicy != nil {
+		awsConfig.MaxRetries = aws.Int(0)
+	}
+
+	// the requests of the anonymous clients aren't signed
+	if cloudStorageOpts.Anonymous {
+		awsConfig.Credentials = credentials.AnonymousCredentials
+	}
+
+	if hasCustomTransport(cloudStorageOpts) {
+		awsConfig.HTTPClient = &http.Client{Transport: newHTTPTransport(cloudStorageOpts, http.DefaultTransport)}
+	}
+
+	return awsConfig
+}
+
+// hasCustomTransport returns whether the default transport of the providers is replaced
+func hasCustomTransport(cloudStorageOpts *CloudStorageOption) bool {
+	return cloudStorageOpts.HTTPTransport != nil || cloudStorageOpts.WireLog != nil || cloudStorageOpts.RequestAttribution != nil
+}
+
+// newHTTPTransport returns the tuned transport if any, or base, wrapped with the wire logging and the request
+// attribution if they're configured
+func newHTTPTransport(cloudStorageOpts *CloudStorageOption, base http.RoundTripper) http.RoundTripper {
+	transport := base
+	if cloudStorageOpts.HTTPTransport != nil {
+		transport = cloudStorageOpts.HTTPTransport.newTransport()
+	}
+
+	// the attribution comes first, so the wire log shows its headers
+	return withRequestAttribution(withWireLog(transport, cloudStorageOpts), cloudStorageOpts)
+}
+
+// newGCPTransport returns the base transport of the GCP clients
+func newGCPTransport(cloudStorageOpts *CloudStorageOption) http.RoundTripper {
+	return withGCPUserProject(newHTTPTransport(cloudStorageOpts, gcp.DefaultTransport()), cloudStorageOpts)
+}
+
+// newGCPClientOptions returns the options of the GCP storage client
+func newGCPClientOptions(creds *google.Credentials, cloudStorageOpts *CloudStorageOption) []option.ClientOption {
+	if !hasCustomTransport(cloudStorageOpts) && cloudStorageOpts.GCPUserProject == "" {
+		return []option.ClientOption{option.WithCredentials(creds)}
+	}
+
+	return []option.ClientOption{option.WithHTTPClient(&http.Client{
+		Transport: &oauth2.Transport{
+			Base:   newGCPTransport(cloudStorageOpts),
+			Source: creds.TokenSource,
+		},
+	})}
+}
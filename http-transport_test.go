@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPTransportOption(t *testing.T) {
+	cloudStorageOpts := &CloudStorageOption{
+		HTTPTransport: &HTTPTransportOption{
+			MaxIdleConns:        200,
+			MaxIdleConnsPerHost: 50,
+			MaxConnsPerHost:     100,
+			IdleConnTimeout:     time.Minute,
+			KeepAlive:           15 * time.Second,
+			DisableKeepAlives:   true,
+		},
+	}
+
+	requireTuned := func(roundTripper http.RoundTripper) {
+		transport, ok := roundTripper.(*http.Transport)
+		require.True(t, ok, "got %T", roundTripper)
+
+		require.Equal(t, 200, transport.MaxIdleConns)
+		require.Equal(t, 50, transport.MaxIdleConnsPerHost)
+		require.Equal(t, 100, transport.MaxConnsPerHost)
+		require.Equal(t, time.Minute, transport.IdleConnTimeout)
+		require.True(t, transport.DisableKeepAlives)
+		require.NotNil(t, transport.DialContext)
+	}
+
+	// AWS
+	awsConfig := newAWSConfig(cloudStorageOpts)
+	require.NotNil(t, awsConfig.HTTPClient)
+	requireTuned(awsConfig.HTTPClient.Transport)
+
+	// GCP
+	requireTuned(newGCPTransport(cloudStorageOpts))
+}
+
+func TestHTTPTransportOptionDefaults(t *testing.T) {
+	defaults := http.DefaultTransport.(*http.Transport)
+
+	transport := (&HTTPTransportOption{MaxIdleConnsPerHost: 32}).newTransport()
+
+	require.Equal(t, 32, transport.MaxIdleConnsPerHost)
+	require.Equal(t, defaults.MaxIdleConns, transport.MaxIdleConns)
+	require.Equal(t, defaults.MaxConnsPerHost, transport.MaxConnsPerHost)
+	require.Equal(t, defaults.IdleConnTimeout, transport.IdleConnTimeout)
+	require.False(t, transport.DisableKeepAlives)
+
+	// without the option, the SDK keeps its own client
+	require.Nil(t, newAWSConfig(&CloudStorageOption{}).HTTPClient)
+}
@@ -0,0 +1,291 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultIdempotencyPrefix is the prefix of the markers when IdempotencyOption.Prefix isn't set
+	DefaultIdempotencyPrefix = ".idempotency/"
+	// DefaultIdempotencyExpiry is the time a write is remembered when IdempotencyOption.Expiry isn't set
+	DefaultIdempotencyExpiry = 24 * time.Hour
+	// DefaultIdempotencyPendingTimeout is the time after which an unfinished write is taken over
+	// when IdempotencyOption.PendingTimeout isn't set
+	DefaultIdempotencyPendingTimeout = time.Minute
+	// IdempotencyMetadataKey is the metadata key of the idempotency token stored with the written objects
+	IdempotencyMetadataKey = "idempotency-key"
+)
+
+// ErrWriteInProgress is matched by errors.Is when a write with the same idempotency token is still running
+var ErrWriteInProgress = errors.New("write in progress")
+
+// WriteInProgressError gives the write with the same idempotency token which is still running
+type WriteInProgressError struct {
+	Key       string
+	StartedAt time.Time
+}
+
+func (e *WriteInProgressError) Error() string {
+	return fmt.Sprintf("a write of '%s' with the same idempotency key is in progress since %s",
+		e.Key, e.StartedAt.Format(time.RFC3339))
+}
+
+func (e *WriteInProgressError) Is(target error) bool {
+	return target == ErrWriteInProgress
+}
+
+// IdempotencyOption configures an IdempotentCloudStorage
+type IdempotencyOption struct {
+	// Prefix holds the markers of the idempotency tokens. Defaults to DefaultIdempotencyPrefix.
+	Prefix string
+	// Expiry is the time a successful write is remembered. Defaults to DefaultIdempotencyExpiry.
+	// The expired markers are replaced by the next write, a lifecycle rule on the prefix deletes the others.
+	Expiry time.Duration
+	// PendingTimeout is the time after which a write which didn't finish, e.g. of a crashed process,
+	// is taken over by a retry. Defaults to DefaultIdempotencyPendingTimeout.
+	PendingTimeout time.Duration
+	// MarkerStorage stores the markers, it defaults to the wrapped storage.
+	// The storages of NewCloudStorageWithOption write them to the bucket without the other options.
+	MarkerStorage CloudStorage
+	// Clock returns the time of the markers, time.Now when it's nil
+	Clock func() time.Time
+}
+
+// idempotencyMarker records the state of the write of a key with a token
+type idempotencyMarker struct {
+	Key       string    `json:"key"`
+	Done      bool      `json:"done"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// IdempotentCloudStorage skips the Write and Upload calls given WithIdempotencyKey when a write of the key
+// with the same token already succeeded, so the retried requests, including after a restart of the process,
+// don't write the object again and don't trigger the object-created events again.
+// A marker object claimed with WriteIf records the token, and the token is stored in the IdempotencyMetadataKey
+// metadata of the object. A concurrent write with the same token fails with a *WriteInProgressError.
+// The markers are hidden from List.
+type IdempotentCloudStorage struct {
+	CloudStorage
+	opts IdempotencyOption
+}
+
+// NewIdempotentCloudStorage returns the storage deduplicating the writes given an idempotency key
+func NewIdempotentCloudStorage(storage CloudStorage, opts IdempotencyOption) *IdempotentCloudStorage {
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultIdempotencyPrefix
+	}
+
+	if opts.Expiry <= 0 {
+		opts.Expiry = DefaultIdempotencyExpiry
+	}
+
+	if opts.PendingTimeout <= 0 {
+		opts.PendingTimeout = DefaultIdempotencyPendingTimeout
+	}
+
+	if opts.MarkerStorage == nil {
+		opts.MarkerStorage = storage
+	}
+
+	opts.Clock = clockOrNow(opts.Clock)
+
+	return &IdempotentCloudStorage{CloudStorage: storage, opts: opts}
+}
+
+// List hides the markers
+func (ts *IdempotentCloudStorage) List(ctx context.Context, prefix string, opts ...ListOption) *ListIterator {
+	iter := ts.CloudStorage.List(ctx, prefix, opts...)
+
+	return NewListIterator(ctx, func(ctx context.Context) (*ListObject, error) {
+		for {
+			object, err := iter.Next(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			if !strings.HasPrefix(object.Key, ts.opts.Prefix) {
+				return object, nil
+			}
+		}
+	})
+}
+
+func (ts *IdempotentCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	return ts.write(ctx, key, opts, func(opts []WriteOption) error {
+		return ts.CloudStorage.Write(ctx, key, body, contentType, opts...)
+	})
+}
+
+func (ts *IdempotentCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	return ts.write(ctx, key, writeOpts, func(writeOpts []WriteOption) error {
+		return ts.CloudStorage.Upload(ctx, key, reader, opts, writeOpts...)
+	})
+}
+
+// GetWriter doesn't support the idempotency keys, the writer being created before the write is known to be needed
+func (ts *IdempotentCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	if writeOptions(opts).idempotencyKey != "" {
+		return nil, ErrNotSupported
+	}
+
+	return ts.CloudStorage.GetWriter(ctx, key, opts...)
+}
+
+func (ts *IdempotentCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
+
+// write claims the marker of the token, performs the write with the token in the metadata and marks it done
+func (ts *IdempotentCloudStorage) write(
+	ctx context.Context,
+	key string,
+	opts []WriteOption,
+	f func(opts []WriteOption) error,
+) error {
+	token := writeOptions(opts).idempotencyKey
+	if token == "" {
+		return f(opts)
+	}
+
+	markerKey := ts.markerKey(key, token)
+
+	done, err := ts.claim(ctx, key, markerKey)
+	if err != nil || done {
+		return err
+	}
+
+	opts = append(opts[:len(opts):len(opts)], WithMetadata(map[string]string{IdempotencyMetadataKey: token}))
+
+	if err := f(opts); err != nil {
+		// the retry can claim the token again
+		_ = ts.opts.MarkerStorage.Delete(ctx, markerKey)
+
+		return err
+	}
+
+	return ts.writeMarker(ctx, markerKey, &idempotencyMarker{Key: key, Done: true, UpdatedAt: ts.opts.Clock()})
+}
+
+// markerKey hashes the token with the key, so the same token can be used for several keys
+func (ts *IdempotentCloudStorage) markerKey(key string, token string) string {
+	sum := sha256.Sum256([]byte(key + "\n" + token))
+
+	return ts.opts.Prefix + hex.EncodeToString(sum[:])
+}
+
+// claim creates the pending marker, it returns true when the write already succeeded
+func (ts *IdempotentCloudStorage) claim(ctx context.Context, key string, markerKey string) (bool, error) {
+	for {
+		body, err := json.Marshal(&idempotencyMarker{Key: key, UpdatedAt: ts.opts.Clock()})
+		if err != nil {
+			return false, err
+		}
+
+		contentType := "application/json"
+
+		_, err = ts.opts.MarkerStorage.WriteIf(ctx, markerKey, body, &contentType, WriteCondition{DoesNotExist: true})
+		if err == nil {
+			return false, nil
+		}
+
+		if !errors.Is(err, ErrPreconditionFailed) && !errors.Is(err, ErrAlreadyExists) {
+			return false, err
+		}
+
+		marker, generation, err := ts.readMarker(ctx, markerKey)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		age := ts.opts.Clock().Sub(marker.UpdatedAt)
+
+		switch {
+		case marker.Done && age < ts.opts.Expiry:
+			return true, nil
+		case !marker.Done && age < ts.opts.PendingTimeout:
+			return false, &WriteInProgressError{Key: key, StartedAt: marker.UpdatedAt}
+		}
+
+		// the marker expired or its write was abandoned, it's replaced unless another retry did it first
+		err = ts.opts.MarkerStorage.DeleteIf(ctx, markerKey, generation)
+		if err != nil && !errors.Is(err, ErrPreconditionFailed) && !errors.Is(err, ErrNotFound) {
+			return false, err
+		}
+	}
+}
+
+func (ts *IdempotentCloudStorage) readMarker(ctx context.Context, markerKey string) (*idempotencyMarker, string, error) {
+	reader, attrs, err := ts.opts.MarkerStorage.GetWithAttributes(ctx, markerKey)
+	if err != nil {
+		return nil, "", err
+	}
+	defer reader.Close()
+
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var marker idempotencyMarker
+	if err := json.Unmarshal(body, &marker); err != nil {
+		return nil, "", fmt.Errorf("invalid idempotency marker '%s': %w", markerKey, err)
+	}
+
+	return &marker, attrs.Generation, nil
+}
+
+func (ts *IdempotentCloudStorage) writeMarker(ctx context.Context, markerKey string, marker *idempotencyMarker) error {
+	body, err := json.Marshal(marker)
+	if err != nil {
+		return err
+	}
+
+	contentType := "application/json"
+
+	return ts.opts.MarkerStorage.Write(ctx, markerKey, body, &contentType)
+}
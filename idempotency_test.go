@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type failingWriteCloudStorage struct {
+	CloudStorage
+	err error
+}
+
+func (ts *failingWriteCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	if ts.err != nil {
+		return ts.err
+	}
+
+	return ts.CloudStorage.Write(ctx, key, body, contentType, opts...)
+}
+
+func TestIdempotentCloudStorage(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	fake := NewFakeCloudStorage("bucket")
+	storage := NewIdempotentCloudStorage(fake, IdempotencyOption{Clock: func() time.Time { return now }})
+
+	require.NoError(t, storage.Write(ctx, "orders/1", []byte("first"), nil, WithIdempotencyKey("request-1")))
+
+	attrs, err := fake.Attributes(ctx, "orders/1")
+	require.NoError(t, err)
+	require.Equal(t, "request-1", attrs.Metadata[IdempotencyMetadataKey])
+
+	// the retry isn't written again, even by another process
+	restarted := NewIdempotentCloudStorage(fake, IdempotencyOption{Clock: func() time.Time { return now }})
+	require.NoError(t, restarted.Write(ctx, "orders/1", []byte("retry"), nil, WithIdempotencyKey("request-1")))
+	require.NoError(t, restarted.Upload(ctx, "orders/1", bytes.NewReader([]byte("retry")), nil,
+		WithIdempotencyKey("request-1")))
+
+	body, err := storage.Get(ctx, "orders/1")
+	require.NoError(t, err)
+	require.Equal(t, "first", string(body))
+
+	require.NoError(t, storage.Write(ctx, "orders/1", []byte("second"), nil, WithIdempotencyKey("request-2")))
+	require.NoError(t, storage.Write(ctx, "orders/2", []byte("other key"), nil, WithIdempotencyKey("request-1")))
+	require.NoError(t, storage.Write(ctx, "orders/3", []byte("no key"), nil))
+
+	body, err = storage.Get(ctx, "orders/1")
+	require.NoError(t, err)
+	require.Equal(t, "second", string(body))
+
+	// the markers are hidden
+	require.Equal(t, []string{"orders/1", "orders/2", "orders/3"}, listedKeys(t, storage.List(ctx, "")))
+	require.Len(t, listedKeys(t, fake.List(ctx, DefaultIdempotencyPrefix)), 3)
+
+	// the token is forgotten after the expiry
+	now = now.Add(DefaultIdempotencyExpiry)
+	require.NoError(t, storage.Write(ctx, "orders/1", []byte("expired"), nil, WithIdempotencyKey("request-1")))
+
+	body, err = storage.Get(ctx, "orders/1")
+	require.NoError(t, err)
+	require.Equal(t, "expired", string(body))
+
+	_, err = storage.GetWriter(ctx, "orders/1", WithIdempotencyKey("request-1"))
+	require.True(t, errors.Is(err, ErrNotSupported))
+}
+
+func TestIdempotentCloudStoragePendingWrites(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	fake := NewFakeCloudStorage("bucket")
+	failing := &failingWriteCloudStorage{CloudStorage: fake, err: errors.New("unavailable")}
+	storage := NewIdempotentCloudStorage(failing, IdempotencyOption{
+		MarkerStorage: fake,
+		Clock:         func() time.Time { return now },
+	})
+
+	// a failed write can be retried at once
+	err := storage.Write(ctx, "orders/1", []byte("body"), nil, WithIdempotencyKey("request-1"))
+	require.EqualError(t, err, "unavailable")
+	require.Empty(t, listedKeys(t, fake.List(ctx, DefaultIdempotencyPrefix)))
+
+	failing.err = nil
+	require.NoError(t, storage.Write(ctx, "orders/1", []byte("body"), nil, WithIdempotencyKey("request-1")))
+
+	// a write of a crashed process is taken over after the pending timeout
+	contentType := "application/json"
+	require.NoError(t, fake.Write(ctx, storage.markerKey("orders/2", "request-2"),
+		[]byte(`{"key": "orders/2", "done": false, "updatedAt": "2020-06-01T00:00:00Z"}`), &contentType))
+
+	err = storage.Write(ctx, "orders/2", []byte("body"), nil, WithIdempotencyKey("request-2"))
+	require.True(t, errors.Is(err, ErrWriteInProgress))
+
+	var inProgress *WriteInProgressError
+	require.True(t, errors.As(err, &inProgress))
+	require.Equal(t, "orders/2", inProgress.Key)
+
+	now = now.Add(DefaultIdempotencyPendingTimeout)
+	require.NoError(t, storage.Write(ctx, "orders/2", []byte("body"), nil, WithIdempotencyKey("request-2")))
+
+	body, err := fake.Get(ctx, "orders/2")
+	require.NoError(t, err)
+	require.Equal(t, "body", string(body))
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// IncompleteUpload is a multipart upload which was neither completed nor aborted.
+// Its parts are billed until it's aborted.
+type IncompleteUpload struct {
+	Key       string
+	UploadID  string
+	Initiated time.Time
+}
+
+func awsListIncompleteUploads(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	prefix string,
+) ([]*IncompleteUpload, error) {
+	var uploads []*IncompleteUpload
+
+	err := client.ListMultipartUploadsPagesWithContext(ctx, &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(awsEscapeKey(prefix)),
+	}, func(page *s3.ListMultipartUploadsOutput, lastPage bool) bool {
+		for _, upload := range page.Uploads {
+			uploads = append(uploads, &IncompleteUpload{
+				Key:       awsUnescapeKey(aws.StringValue(upload.Key)),
+				UploadID:  aws.StringValue(upload.UploadId),
+				Initiated: aws.TimeValue(upload.Initiated),
+			})
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return uploads, nil
+}
+
+// awsAbortStaleUploads aborts the uploads initiated before olderThan, and returns them.
+// The uploads aborted before an error are returned along with it.
+func awsAbortStaleUploads(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	olderThan time.Duration,
+	now time.Time,
+) ([]*IncompleteUpload, error) {
+	uploads, err := awsListIncompleteUploads(ctx, client, bucketName, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var aborted []*IncompleteUpload
+
+	for _, upload := range uploads {
+		if now.Sub(upload.Initiated) < olderThan {
+			continue
+		}
+
+		_, err := client.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(bucketName),
+			Key:      aws.String(awsEscapeKey(upload.Key)),
+			UploadId: aws.String(upload.UploadID),
+		})
+		// the upload may have been completed or aborted meanwhile
+		if err := translateError(err); err != nil && !errors.Is(err, ErrNotFound) {
+			return aborted, err
+		}
+
+		aborted = append(aborted, upload)
+	}
+
+	return aborted, nil
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSAbortStaleUploads(t *testing.T) {
+	var aborted []string
+
+	client, server := newHTTPTestS3Client(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`<ListMultipartUploadsResult><Bucket>bucket</Bucket>
+<Upload><Key>a/__0x2f__b</Key><UploadId>old</UploadId><Initiated>2020-01-01T00:00:00Z</Initiated></Upload>
+<Upload><Key>c</Key><UploadId>new</UploadId><Initiated>2020-01-02T00:00:00Z</Initiated></Upload>
+</ListMultipartUploadsResult>`))
+
+			return
+		}
+
+		aborted = append(aborted, r.URL.EscapedPath()+"?uploadId="+r.URL.Query().Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	defer server.Close()
+
+	ctx := context.Background()
+
+	// the listed keys are unescaped like the keys of List
+	uploads, err := awsListIncompleteUploads(ctx, client, "bucket", "")
+	require.NoError(t, err)
+	require.Len(t, uploads, 2)
+	require.Equal(t, "a//b", uploads[0].Key)
+
+	now := time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	stale, err := awsAbortStaleUploads(ctx, client, "bucket", 24*time.Hour, now)
+	require.NoError(t, err)
+	require.Len(t, stale, 1)
+	require.Equal(t, []string{"/bucket/a/__0x2f__b?uploadId=old"}, aborted)
+}
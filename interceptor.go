@@ -0,0 +1,846 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// OperationInfo describes a CloudStorage call passed through the interceptors
+type OperationInfo struct {
+	// Name is the name of the CloudStorage method, e.g. "Get"
+	Name     string
+	Provider string
+	Bucket   string
+	// Key is empty for the bucket-level operations.
+	// It can be rewritten by an interceptor before calling next, the call is then made with the new key.
+	Key string
+	// DestinationKey is the destination of Copy and the new folder of RenameFolder, Key being the source.
+	// It's empty for the other operations, and can be rewritten like Key.
+	DestinationKey string
+	// Bytes is the number of bytes read or written, when it's known once the operation completes
+	Bytes int64
+	// TraceAttributes are the attributes given to the call with WithTraceAttributes
+	TraceAttributes []attribute.KeyValue
+}
+
+// Interceptor wraps a CloudStorage call, e.g. to check permissions, log or rewrite keys.
+// next performs the call, an interceptor can also return an error without calling it.
+type Interceptor func(ctx context.Context, op *OperationInfo, next func(ctx context.Context) error) error
+
+// interceptedCloudStorage passes every operation of the wrapped CloudStorage through the interceptors
+// and returns the errors as *OperationError.
+// List is intercepted when the listing is opened, streams are intercepted while being opened.
+// Close and GetPublicURL aren't intercepted since they don't make any request.
+type interceptedCloudStorage struct {
+	CloudStorage
+	provider     string
+	bucketName   string
+	interceptors []Interceptor
+	codec        Codec
+	timeouts     DefaultTimeouts
+}
+
+func newInterceptedCloudStorage(
+	storage CloudStorage,
+	provider string,
+	bucketName string,
+	interceptors ...Interceptor,
+) *interceptedCloudStorage {
+	return &interceptedCloudStorage{
+		CloudStorage: storage,
+		provider:     provider,
+		bucketName:   bucketName,
+		interceptors: interceptors,
+	}
+}
+
+// run calls f through the interceptors, the first interceptor being the outermost one,
+// with the default timeout of the operation
+func (ts *interceptedCloudStorage) run(
+	ctx context.Context,
+	name string,
+	key string,
+	f func(ctx context.Context, op *OperationInfo) error,
+) error {
+	ctx, cancel := ts.timeouts.withTimeout(ctx, name, callOptions{})
+	defer cancel()
+
+	return ts.runWith(ctx, name, key, callOptions{}, f)
+}
+
+// runWithDestination runs the operations taking a source and a destination key
+func (ts *interceptedCloudStorage) runWithDestination(
+	ctx context.Context,
+	name string,
+	key string,
+	destinationKey string,
+	f func(ctx context.Context, op *OperationInfo) error,
+) error {
+	ctx, cancel := ts.timeouts.withTimeout(ctx, name, callOptions{})
+	defer cancel()
+
+	op := &OperationInfo{
+		Name:           name,
+		Provider:       ts.provider,
+		Bucket:         ts.bucketName,
+		Key:            key,
+		DestinationKey: destinationKey,
+	}
+
+	return ts.intercept(ctx, op, f)
+}
+
+// runWith is run for the calls given options, the timeout is applied by the caller since it can outlive the call
+func (ts *interceptedCloudStorage) runWith(
+	ctx context.Context,
+	name string,
+	key string,
+	options callOptions,
+	f func(ctx context.Context, op *OperationInfo) error,
+) error {
+	op := &OperationInfo{
+		Name:            name,
+		Provider:        ts.provider,
+		Bucket:          ts.bucketName,
+		Key:             key,
+		TraceAttributes: options.traceAttributes,
+	}
+
+	return ts.intercept(ctx, op, f)
+}
+
+// intercept passes the operation through the interceptors, the error is given the key of the caller
+func (ts *interceptedCloudStorage) intercept(
+	ctx context.Context,
+	op *OperationInfo,
+	f func(ctx context.Context, op *OperationInfo) error,
+) error {
+	key := op.Key
+
+	next := func(ctx context.Context) error {
+		return f(ctx, op)
+	}
+
+	for i := len(ts.interceptors) - 1; i >= 0; i-- {
+		intercept, inner := ts.interceptors[i], next
+		next = func(ctx context.Context) error {
+			return intercept(ctx, op, inner)
+		}
+	}
+
+	return ts.wrapError(op.Name, key, next(ctx))
+}
+
+// wrapError adds the context of the operation to err, the key is the one given by the caller
+func (ts *interceptedCloudStorage) wrapError(name string, key string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var operationErr *OperationError
+	if errors.As(err, &operationErr) {
+		return err
+	}
+
+	return &OperationError{
+		Operation: name,
+		Bucket:    ts.bucketName,
+		Key:       key,
+		Err:       err,
+	}
+}
+
+// List is intercepted on the first call to Next, with the prefix as the key, which opens the listing and fetches
+// its first object. A prefix prepended by an interceptor, e.g. a tenant, is removed from the listed keys so they
+// can be given back to the other operations. The timeout of the options covers the whole listing, the objects are
+// sorted in the order of the options.
+func (ts *interceptedCloudStorage) List(
+	ctx context.Context,
+	prefix string,
+	opts ...ListOption,
+) *ListIterator {
+	options := listOptions(opts)
+
+	var (
+		iter      *ListIterator
+		namespace string
+	)
+
+	timeout := options.timeout
+	if _, ok := ctx.Deadline(); !ok && timeout <= 0 {
+		timeout = ts.timeouts.List
+	}
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	return NewListIterator(ctx, func(callCtx context.Context) (*ListObject, error) {
+		if !deadline.IsZero() {
+			var cancel context.CancelFunc
+
+			callCtx, cancel = context.WithDeadline(callCtx, deadline)
+			defer cancel()
+		}
+
+		if iter == nil {
+			var object *ListObject
+
+			err := ts.runWith(callCtx, "List", prefix, options, func(nextCtx context.Context, op *OperationInfo) error {
+				if op.Key != prefix && strings.HasSuffix(op.Key, prefix) {
+					namespace = strings.TrimSuffix(op.Key, prefix)
+				}
+
+				listOpts := opts
+				if namespace != "" && options.resumeFrom != "" {
+					listOpts = append(append([]ListOption(nil), opts...), WithResumeFrom(namespace+options.resumeFrom))
+				}
+
+				// the later pages are fetched with the context of List, the one of the interceptors ends with the call
+				iter = orderedList(ctx, ts.CloudStorage.List(ctx, op.Key, listOpts...), options.order)
+
+				var err error
+
+				object, err = iter.Next(nextCtx)
+				if err == io.EOF {
+					return nil
+				}
+
+				return err
+			})
+			if err != nil {
+				iter = nil
+
+				return nil, err
+			}
+
+			if object == nil {
+				return nil, io.EOF
+			}
+
+			return ts.unscope(object, namespace), nil
+		}
+
+		object, err := iter.Next(callCtx)
+		if err != nil && err != io.EOF {
+			return nil, ts.wrapError("List", prefix, err)
+		}
+
+		return ts.unscope(object, namespace), err
+	})
+}
+
+// unscope removes the namespace prepended to the prefix of List from the key of the listed object
+func (ts *interceptedCloudStorage) unscope(object *ListObject, namespace string) *ListObject {
+	if object == nil || namespace == "" || !strings.HasPrefix(object.Key, namespace) {
+		return object
+	}
+
+	unscoped := *object
+	unscoped.Key = strings.TrimPrefix(object.Key, namespace)
+
+	return &unscoped
+}
+
+func (ts *interceptedCloudStorage) Get(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (body []byte, err error) {
+	options := readOptions(opts)
+
+	ctx, cancel := ts.timeouts.withTimeout(ctx, "Get", options)
+	defer cancel()
+
+	err = ts.runWith(ctx, "Get", key, options, func(ctx context.Context, op *OperationInfo) error {
+		body, err = ts.CloudStorage.Get(ctx, op.Key, opts...)
+		op.Bytes = int64(len(body))
+
+		return err
+	})
+
+	return body, err
+}
+
+func (ts *interceptedCloudStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	return ts.run(ctx, "Delete", key, func(ctx context.Context, op *OperationInfo) error {
+		return ts.CloudStorage.Delete(ctx, op.Key)
+	})
+}
+
+func (ts *interceptedCloudStorage) CreateBucket(
+	ctx context.Context,
+	bucketPrefix string,
+	expirationTimeDays int64,
+) error {
+	return ts.run(ctx, "CreateBucket", "", func(ctx context.Context, op *OperationInfo) error {
+		return ts.CloudStorage.CreateBucket(ctx, bucketPrefix, expirationTimeDays)
+	})
+}
+
+func (ts *interceptedCloudStorage) CreateBucketWithOptions(
+	ctx context.Context,
+	opts *CreateBucketOption,
+) error {
+	return ts.run(ctx, "CreateBucketWithOptions", "", func(ctx context.Context, op *OperationInfo) error {
+		return ts.CloudStorage.CreateBucketWithOptions(ctx, opts)
+	})
+}
+
+func (ts *interceptedCloudStorage) GetSignedURL(
+	ctx context.Context,
+	key string,
+	opts *SignedURLOption,
+) (url string, err error) {
+	err = ts.run(ctx, "GetSignedURL", key, func(ctx context.Context, op *OperationInfo) error {
+		url, err = ts.CloudStorage.GetSignedURL(ctx, op.Key, opts)
+		return err
+	})
+
+	return url, err
+}
+
+func (ts *interceptedCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	options := writeOptions(opts)
+
+	ctx, cancel := ts.timeouts.withTimeout(ctx, "Write", options)
+	defer cancel()
+
+	return ts.runWith(ctx, "Write", key, options, func(ctx context.Context, op *OperationInfo) error {
+		op.Bytes = int64(len(body))
+
+		return ts.CloudStorage.Write(ctx, op.Key, body, contentType, opts...)
+	})
+}
+
+func (ts *interceptedCloudStorage) Attributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (attrs *Attributes, err error) {
+	options := readOptions(opts)
+
+	ctx, cancel := ts.timeouts.withTimeout(ctx, "Attributes", options)
+	defer cancel()
+
+	err = ts.runWith(ctx, "Attributes", key, options, func(ctx context.Context, op *OperationInfo) error {
+		attrs, err = ts.CloudStorage.Attributes(ctx, op.Key, opts...)
+		return err
+	})
+
+	return attrs, err
+}
+
+func (ts *interceptedCloudStorage) GetReader(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (reader io.ReadCloser, err error) {
+	options := readOptions(opts)
+	ctx, cancel := ts.timeouts.withTimeout(ctx, "GetReader", options)
+
+	err = ts.runWith(ctx, "GetReader", key, options, func(ctx context.Context, op *OperationInfo) error {
+		reader, err = ts.CloudStorage.GetReader(ctx, op.Key, opts...)
+		return err
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &cancelOnCloseReader{ReadCloser: reader, cancel: cancel}, nil
+}
+
+func (ts *interceptedCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (reader io.ReadCloser, attrs *Attributes, err error) {
+	options := readOptions(opts)
+	ctx, cancel := ts.timeouts.withTimeout(ctx, "GetWithAttributes", options)
+
+	err = ts.runWith(ctx, "GetWithAttributes", key, options, func(ctx context.Context, op *OperationInfo) error {
+		reader, attrs, err = ts.CloudStorage.GetWithAttributes(ctx, op.Key, opts...)
+		if attrs != nil {
+			op.Bytes = attrs.Size
+		}
+
+		return err
+	})
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	return &cancelOnCloseReader{ReadCloser: reader, cancel: cancel}, attrs, nil
+}
+
+func (ts *interceptedCloudStorage) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset,
+	length int64,
+	opts ...ReadOption,
+) (reader io.ReadCloser, err error) {
+	options := readOptions(opts)
+	ctx, cancel := ts.timeouts.withTimeout(ctx, "GetRangeReader", options)
+
+	err = ts.runWith(ctx, "GetRangeReader", key, options, func(ctx context.Context, op *OperationInfo) error {
+		reader, err = ts.CloudStorage.GetRangeReader(ctx, op.Key, offset, length, opts...)
+		return err
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &cancelOnCloseReader{ReadCloser: reader, cancel: cancel}, nil
+}
+
+func (ts *interceptedCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (writer io.WriteCloser, err error) {
+	options := writeOptions(opts)
+	ctx, cancel := ts.timeouts.withTimeout(ctx, "GetWriter", options)
+
+	err = ts.runWith(ctx, "GetWriter", key, options, func(ctx context.Context, op *OperationInfo) error {
+		writer, err = ts.CloudStorage.GetWriter(ctx, op.Key, opts...)
+		return err
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &cancelOnCloseWriter{WriteCloser: writer, cancel: cancel}, nil
+}
+
+func (ts *interceptedCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	options := writeOptions(writeOpts)
+
+	ctx, cancel := ts.timeouts.withTimeout(ctx, "Upload", options)
+	defer cancel()
+
+	return ts.runWith(ctx, "Upload", key, options, func(ctx context.Context, op *OperationInfo) error {
+		counter := &countingReader{Reader: reader}
+		err := ts.CloudStorage.Upload(ctx, op.Key, counter, opts, writeOpts...)
+		op.Bytes = counter.count
+
+		return err
+	})
+}
+
+func (ts *interceptedCloudStorage) SetObjectRetention(
+	ctx context.Context,
+	key string,
+	retention *ObjectRetention,
+) error {
+	return ts.run(ctx, "SetObjectRetention", key, func(ctx context.Context, op *OperationInfo) error {
+		return ts.CloudStorage.SetObjectRetention(ctx, op.Key, retention)
+	})
+}
+
+func (ts *interceptedCloudStorage) GetObjectRetention(
+	ctx context.Context,
+	key string,
+) (retention *ObjectRetention, err error) {
+	err = ts.run(ctx, "GetObjectRetention", key, func(ctx context.Context, op *OperationInfo) error {
+		retention, err = ts.CloudStorage.GetObjectRetention(ctx, op.Key)
+		return err
+	})
+
+	return retention, err
+}
+
+func (ts *interceptedCloudStorage) SetLegalHold(
+	ctx context.Context,
+	key string,
+	enabled bool,
+) error {
+	return ts.run(ctx, "SetLegalHold", key, func(ctx context.Context, op *OperationInfo) error {
+		return ts.CloudStorage.SetLegalHold(ctx, op.Key, enabled)
+	})
+}
+
+func (ts *interceptedCloudStorage) GetLegalHold(
+	ctx context.Context,
+	key string,
+) (enabled bool, err error) {
+	err = ts.run(ctx, "GetLegalHold", key, func(ctx context.Context, op *OperationInfo) error {
+		enabled, err = ts.CloudStorage.GetLegalHold(ctx, op.Key)
+		return err
+	})
+
+	return enabled, err
+}
+
+func (ts *interceptedCloudStorage) SetStorageClass(
+	ctx context.Context,
+	key string,
+	storageClass string,
+) error {
+	return ts.run(ctx, "SetStorageClass", key, func(ctx context.Context, op *OperationInfo) error {
+		return ts.CloudStorage.SetStorageClass(ctx, op.Key, storageClass)
+	})
+}
+
+// Copy is intercepted with the source key and the destination key, which can both be rewritten
+func (ts *interceptedCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	return ts.runWithDestination(ctx, "Copy", srcKey, dstKey, func(ctx context.Context, op *OperationInfo) error {
+		return ts.CloudStorage.Copy(ctx, op.Key, op.DestinationKey, opts)
+	})
+}
+
+func (ts *interceptedCloudStorage) GetBucketPolicy(
+	ctx context.Context,
+) (policy *BucketPolicy, err error) {
+	err = ts.run(ctx, "GetBucketPolicy", "", func(ctx context.Context, op *OperationInfo) error {
+		policy, err = ts.CloudStorage.GetBucketPolicy(ctx)
+		return err
+	})
+
+	return policy, err
+}
+
+func (ts *interceptedCloudStorage) Undelete(
+	ctx context.Context,
+	key string,
+) error {
+	return ts.run(ctx, "Undelete", key, func(ctx context.Context, op *OperationInfo) error {
+		return ts.CloudStorage.Undelete(ctx, op.Key)
+	})
+}
+
+func (ts *interceptedCloudStorage) GetScopedCredentials(
+	ctx context.Context,
+	opts *ScopedCredentialsOption,
+) (credentials *ScopedCredentials, err error) {
+	err = ts.run(ctx, "GetScopedCredentials", "", func(ctx context.Context, op *OperationInfo) error {
+		credentials, err = ts.CloudStorage.GetScopedCredentials(ctx, opts)
+		return err
+	})
+
+	return credentials, err
+}
+
+func (ts *interceptedCloudStorage) CreateFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	return ts.run(ctx, "CreateFolder", folder, func(ctx context.Context, op *OperationInfo) error {
+		return ts.CloudStorage.CreateFolder(ctx, op.Key)
+	})
+}
+
+func (ts *interceptedCloudStorage) DeleteFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	return ts.run(ctx, "DeleteFolder", folder, func(ctx context.Context, op *OperationInfo) error {
+		return ts.CloudStorage.DeleteFolder(ctx, op.Key)
+	})
+}
+
+// RenameFolder is intercepted with the folder and the new folder as the destination key
+func (ts *interceptedCloudStorage) RenameFolder(
+	ctx context.Context,
+	folder string,
+	newFolder string,
+) error {
+	return ts.runWithDestination(ctx, "RenameFolder", folder, newFolder, func(ctx context.Context, op *OperationInfo) error {
+		return ts.CloudStorage.RenameFolder(ctx, op.Key, op.DestinationKey)
+	})
+}
+
+func (ts *interceptedCloudStorage) SetBucketPolicy(
+	ctx context.Context,
+	policy *BucketPolicy,
+) error {
+	return ts.run(ctx, "SetBucketPolicy", "", func(ctx context.Context, op *OperationInfo) error {
+		return ts.CloudStorage.SetBucketPolicy(ctx, policy)
+	})
+}
+
+func (ts *interceptedCloudStorage) GetBucketEncryption(
+	ctx context.Context,
+) (encryption *BucketEncryption, err error) {
+	err = ts.run(ctx, "GetBucketEncryption", "", func(ctx context.Context, op *OperationInfo) error {
+		encryption, err = ts.CloudStorage.GetBucketEncryption(ctx)
+		return err
+	})
+
+	return encryption, err
+}
+
+func (ts *interceptedCloudStorage) SetBucketEncryption(
+	ctx context.Context,
+	encryption *BucketEncryption,
+) error {
+	return ts.run(ctx, "SetBucketEncryption", "", func(ctx context.Context, op *OperationInfo) error {
+		return ts.CloudStorage.SetBucketEncryption(ctx, encryption)
+	})
+}
+
+func (ts *interceptedCloudStorage) GetBucketLogging(
+	ctx context.Context,
+) (logging *BucketLogging, err error) {
+	err = ts.run(ctx, "GetBucketLogging", "", func(ctx context.Context, op *OperationInfo) error {
+		logging, err = ts.CloudStorage.GetBucketLogging(ctx)
+		return err
+	})
+
+	return logging, err
+}
+
+func (ts *interceptedCloudStorage) SetBucketLogging(
+	ctx context.Context,
+	logging *BucketLogging,
+) error {
+	return ts.run(ctx, "SetBucketLogging", "", func(ctx context.Context, op *OperationInfo) error {
+		return ts.CloudStorage.SetBucketLogging(ctx, logging)
+	})
+}
+
+func (ts *interceptedCloudStorage) GetBucketReplication(
+	ctx context.Context,
+) (replication *BucketReplication, err error) {
+	err = ts.run(ctx, "GetBucketReplication", "", func(ctx context.Context, op *OperationInfo) error {
+		replication, err = ts.CloudStorage.GetBucketReplication(ctx)
+		return err
+	})
+
+	return replication, err
+}
+
+func (ts *interceptedCloudStorage) SetBucketReplication(
+	ctx context.Context,
+	replication *BucketReplication,
+) error {
+	return ts.run(ctx, "SetBucketReplication", "", func(ctx context.Context, op *OperationInfo) error {
+		return ts.CloudStorage.SetBucketReplication(ctx, replication)
+	})
+}
+
+func (ts *interceptedCloudStorage) Ping(
+	ctx context.Context,
+) error {
+	return ts.run(ctx, "Ping", "", func(ctx context.Context, op *OperationInfo) error {
+		return ts.CloudStorage.Ping(ctx)
+	})
+}
+
+// Subscribe is intercepted while the subscription is set up, the events aren't
+func (ts *interceptedCloudStorage) Subscribe(
+	ctx context.Context,
+	prefix string,
+) (<-chan ObjectEvent, error) {
+	var events <-chan ObjectEvent
+
+	err := ts.run(ctx, "Subscribe", prefix, func(ctx context.Context, op *OperationInfo) error {
+		var err error
+
+		events, err = ts.CloudStorage.Subscribe(ctx, op.Key)
+
+		return err
+	})
+
+	return events, err
+}
+
+func (ts *interceptedCloudStorage) ListIncompleteUploads(
+	ctx context.Context,
+	prefix string,
+) ([]*IncompleteUpload, error) {
+	var uploads []*IncompleteUpload
+
+	err := ts.run(ctx, "ListIncompleteUploads", prefix, func(ctx context.Context, op *OperationInfo) error {
+		var err error
+
+		uploads, err = ts.CloudStorage.ListIncompleteUploads(ctx, op.Key)
+
+		return err
+	})
+
+	return uploads, err
+}
+
+func (ts *interceptedCloudStorage) Query(
+	ctx context.Context,
+	key string,
+	sql string,
+	format QueryInputFormat,
+) (io.ReadCloser, error) {
+	var reader io.ReadCloser
+
+	// the default timeout covers the reading of the results
+	ctx, cancel := ts.timeouts.withTimeout(ctx, "Query", callOptions{})
+
+	err := ts.runWith(ctx, "Query", key, callOptions{}, func(ctx context.Context, op *OperationInfo) error {
+		var err error
+
+		reader, err = ts.CloudStorage.Query(ctx, op.Key, sql, format)
+
+		return err
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &cancelOnCloseReader{ReadCloser: reader, cancel: cancel}, nil
+}
+
+func (ts *interceptedCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	var generation string
+
+	err := ts.run(ctx, "WriteIf", key, func(ctx context.Context, op *OperationInfo) error {
+		op.Bytes = int64(len(body))
+
+		var err error
+
+		generation, err = ts.CloudStorage.WriteIf(ctx, op.Key, body, contentType, condition)
+
+		return err
+	})
+
+	return generation, err
+}
+
+func (ts *interceptedCloudStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	return ts.run(ctx, "DeleteIf", key, func(ctx context.Context, op *OperationInfo) error {
+		return ts.CloudStorage.DeleteIf(ctx, op.Key, generation)
+	})
+}
+
+func (ts *interceptedCloudStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	var session *UploadSession
+
+	err := ts.run(ctx, "BeginUpload", key, func(ctx context.Context, op *OperationInfo) error {
+		var err error
+
+		session, err = ts.CloudStorage.BeginUpload(ctx, op.Key, opts)
+
+		return err
+	})
+
+	return session, err
+}
+
+func (ts *interceptedCloudStorage) ResumeUpload(
+	ctx context.Context,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	var key string
+	if state != nil {
+		key = state.Key
+	}
+
+	var session *UploadSession
+
+	err := ts.run(ctx, "ResumeUpload", key, func(ctx context.Context, op *OperationInfo) error {
+		var err error
+
+		session, err = ts.CloudStorage.ResumeUpload(ctx, state)
+
+		return err
+	})
+
+	return session, err
+}
+
+func (ts *interceptedCloudStorage) AbortStaleUploads(
+	ctx context.Context,
+	olderThan time.Duration,
+) ([]*IncompleteUpload, error) {
+	var uploads []*IncompleteUpload
+
+	err := ts.run(ctx, "AbortStaleUploads", "", func(ctx context.Context, op *OperationInfo) error {
+		var err error
+
+		uploads, err = ts.CloudStorage.AbortStaleUploads(ctx, olderThan)
+
+		return err
+	})
+
+	return uploads, err
+}
+
+// Flush flushes the wrapped storage
+func (ts *interceptedCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
+
+// Codec returns the Codec option of the storage, nil when it isn't set
+func (ts *interceptedCloudStorage) Codec() Codec {
+	return ts.codec
+}
+
+type countingReader struct {
+	io.Reader
+	count int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.count += int64(n)
+
+	return n, err
+}
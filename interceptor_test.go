@@ -0,0 +1,242 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterceptedCloudStorage(t *testing.T) {
+	ctx := context.Background()
+	backend := &recordingCloudStorage{objects: make(map[string][]byte)}
+
+	var calls []string
+
+	record := func(name string) Interceptor {
+		return func(ctx context.Context, op *OperationInfo, next func(ctx context.Context) error) error {
+			calls = append(calls, name+" "+op.Name+" "+op.Key)
+			err := next(ctx)
+			calls = append(calls, fmt.Sprintf("%s done %d", name, op.Bytes))
+
+			return err
+		}
+	}
+
+	storage := newInterceptedCloudStorage(backend, "aws", "bucket", record("outer"), record("inner"))
+
+	require.NoError(t, storage.Write(ctx, "key", []byte("body"), nil))
+	require.Equal(t, []string{
+		"outer Write key",
+		"inner Write key",
+		"inner done 4",
+		"outer done 4",
+	}, calls)
+	require.Equal(t, []byte("body"), backend.objects["key"])
+}
+
+type recordingMetrics struct {
+	operations []string
+	bytes      int64
+	err        error
+}
+
+func (m *recordingMetrics) ObserveOperation(operation string, duration time.Duration, bytes int64, err error) {
+	m.operations = append(m.operations, operation)
+	m.bytes += bytes
+	m.err = err
+}
+
+func TestMetricsInterceptor(t *testing.T) {
+	ctx := context.Background()
+	backend := &recordingCloudStorage{objects: make(map[string][]byte)}
+	metrics := &recordingMetrics{}
+	storage := newInterceptedCloudStorage(backend, "aws", "bucket", newMetricsInterceptor(metrics))
+
+	require.NoError(t, storage.Write(ctx, "key", []byte("body"), nil))
+	require.Equal(t, []string{"Write"}, metrics.operations)
+	require.Equal(t, int64(4), metrics.bytes)
+	require.NoError(t, metrics.err)
+}
+
+func TestInterceptorRewritesKey(t *testing.T) {
+	ctx := context.Background()
+	backend := &recordingCloudStorage{objects: make(map[string][]byte)}
+
+	prefix := func(ctx context.Context, op *OperationInfo, next func(ctx context.Context) error) error {
+		op.Key = "tenant/" + op.Key
+		return next(ctx)
+	}
+
+	storage := newInterceptedCloudStorage(backend, "aws", "bucket", prefix)
+
+	require.NoError(t, storage.Write(ctx, "key", []byte("body"), nil))
+	require.Equal(t, []byte("body"), backend.objects["tenant/key"])
+}
+
+func TestInterceptorRewritesDestinationKey(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+
+	var operations []OperationInfo
+
+	prefix := func(ctx context.Context, op *OperationInfo, next func(ctx context.Context) error) error {
+		operations = append(operations, *op)
+
+		op.Key = "tenant/" + op.Key
+		if op.DestinationKey != "" {
+			op.DestinationKey = "tenant/" + op.DestinationKey
+		}
+
+		return next(ctx)
+	}
+
+	storage := newInterceptedCloudStorage(fake, "aws", "bucket", prefix)
+
+	require.NoError(t, storage.Write(ctx, "key", []byte("body"), nil))
+	require.NoError(t, storage.Copy(ctx, "key", "copy", nil))
+	require.NoError(t, storage.CreateFolder(ctx, "a/"))
+	require.NoError(t, storage.RenameFolder(ctx, "a/", "b/"))
+
+	require.Equal(t, "copy", operations[1].DestinationKey)
+	require.Equal(t, "b/", operations[3].DestinationKey)
+	require.Equal(t, []string{"tenant/copy", "tenant/key"}, listedKeys(t, fake.List(ctx, "")))
+}
+
+type warningLogger struct {
+	noopLogger
+	warnings []Fields
+}
+
+func (l *warningLogger) Warn(msg string, fields Fields) {
+	l.warnings = append(l.warnings, fields)
+}
+
+func TestSlowOperationInterceptor(t *testing.T) {
+	ctx := context.Background()
+	backend := &recordingCloudStorage{objects: make(map[string][]byte)}
+	logger := &warningLogger{}
+
+	slow := func(ctx context.Context, op *OperationInfo, next func(ctx context.Context) error) error {
+		if op.Key == "slow" {
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		return next(ctx)
+	}
+
+	storage := newInterceptedCloudStorage(backend, "aws", "bucket",
+		newSlowOperationInterceptor(SlowOperationOption{
+			Threshold:  time.Hour,
+			Thresholds: map[string]time.Duration{"Write": 10 * time.Millisecond},
+		}, logger),
+		slow,
+	)
+
+	require.NoError(t, storage.Write(ctx, "fast", []byte("body"), nil))
+	require.NoError(t, storage.Write(ctx, "slow", []byte("body"), nil))
+	require.Len(t, logger.warnings, 1)
+	require.Equal(t, "slow", logger.warnings[0]["key"])
+	require.Equal(t, int64(4), logger.warnings[0]["bytes"])
+}
+
+func TestInterceptedCloudStorageWrapsErrors(t *testing.T) {
+	flaky := &flakyCloudStorage{failures: 1, err: storage.ErrObjectNotExist}
+	intercepted := newInterceptedCloudStorage(flaky, "gcp", "bucket")
+
+	_, err := intercepted.Get(context.Background(), "key")
+	require.EqualError(t, err, "Get 'key' in bucket 'bucket': storage: object doesn't exist")
+	require.True(t, errors.Is(err, storage.ErrObjectNotExist))
+
+	var operationErr *OperationError
+	require.True(t, errors.As(err, &operationErr))
+	require.Equal(t, "Get", operationErr.Operation)
+	require.Equal(t, "key", operationErr.Key)
+}
+
+func TestInterceptedCloudStorageForwardsFlush(t *testing.T) {
+	backend := &recordingCloudStorage{objects: make(map[string][]byte)}
+	async := NewAsyncCloudStorage(backend, AsyncWriteOption{})
+	defer async.Close()
+
+	var intercepted CloudStorage = newInterceptedCloudStorage(async, "aws", "bucket")
+
+	require.NoError(t, intercepted.Write(context.Background(), "key", []byte("body"), nil))
+	require.NoError(t, intercepted.(Flusher).Flush(context.Background()))
+	require.Len(t, backend.objects, 1)
+}
+
+func TestInterceptedCloudStorageList(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+
+	for _, key := range []string{"a/1", "a/2", "b/1", "other/a/3"} {
+		require.NoError(t, fake.Write(ctx, key, []byte("body"), nil))
+	}
+
+	// the listing can be denied
+	denied := errors.New("denied")
+	deny := func(ctx context.Context, op *OperationInfo, next func(ctx context.Context) error) error {
+		if op.Name == "List" && op.Key == "b/" {
+			return denied
+		}
+
+		return next(ctx)
+	}
+
+	storage := newInterceptedCloudStorage(fake, "aws", "bucket", deny)
+	require.Equal(t, []string{"a/1", "a/2"}, listedKeys(t, storage.List(ctx, "a/")))
+
+	_, err := storage.List(ctx, "b/").Next(ctx)
+	require.ErrorIs(t, err, denied)
+
+	var operationErr *OperationError
+	require.ErrorAs(t, err, &operationErr)
+	require.Equal(t, "List", operationErr.Operation)
+	require.Equal(t, "b/", operationErr.Key)
+
+	// the prefix can be rewritten, the listed keys can be given back to the other operations
+	tenant := func(ctx context.Context, op *OperationInfo, next func(ctx context.Context) error) error {
+		op.Key = "other/" + op.Key
+		return next(ctx)
+	}
+
+	storage = newInterceptedCloudStorage(fake, "aws", "bucket", tenant)
+	require.Equal(t, []string{"a/3"}, listedKeys(t, storage.List(ctx, "a/")))
+	require.Equal(t, []string{"a/3"}, listedKeys(t, storage.List(ctx, "")))
+
+	body, err := storage.Get(ctx, "a/3")
+	require.NoError(t, err)
+	require.Equal(t, []byte("body"), body)
+
+	// the listing is observed once, when it's opened
+	metrics := &recordingMetrics{}
+	storage = newInterceptedCloudStorage(fake, "aws", "bucket", newMetricsInterceptor(metrics))
+	require.Len(t, listedKeys(t, storage.List(ctx, "")), 4)
+	require.Equal(t, []string{"List"}, metrics.operations)
+
+	// an empty listing
+	metrics.operations = nil
+	require.Empty(t, listedKeys(t, storage.List(ctx, "missing/")))
+	require.Equal(t, []string{"List"}, metrics.operations)
+}
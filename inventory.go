@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// InventoryFormat is the format of the inventory written by GenerateInventory
+type InventoryFormat string
+
+const (
+	// InventoryCSV writes a header and a line per object
+	InventoryCSV InventoryFormat = "csv"
+	// InventoryNDJSON writes an InventoryRecord JSON per line
+	InventoryNDJSON InventoryFormat = "ndjson"
+)
+
+// inventoryHeader is the header of the CSV inventories, matching the fields of InventoryRecord
+var inventoryHeader = []string{"key", "size", "last_modified", "etag", "storage_class"}
+
+// InventoryRecord is an object of an inventory
+type InventoryRecord struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+	ETag         string    `json:"etag,omitempty"`
+	StorageClass string    `json:"storage_class,omitempty"`
+}
+
+// InventoryReport sums the objects of an inventory
+type InventoryReport struct {
+	Objects int64
+	Bytes   int64
+}
+
+// GenerateInventory writes the listing of the objects under the prefix to dstKey, in the format.
+// The listing is streamed, so the inventory of a large bucket isn't buffered in memory, and the inventory
+// isn't written at all when the listing fails. The provider inventories (S3 Inventory, GCS Storage Insights)
+// are delivered daily to their own destination, so the objects are always listed.
+func GenerateInventory(
+	ctx context.Context,
+	storage CloudStorage,
+	prefix string,
+	dstKey string,
+	format InventoryFormat,
+) (*InventoryReport, error) {
+	var encode func(w io.Writer) (inventoryEncoder, error)
+
+	switch format {
+	case InventoryCSV:
+		encode = newCSVInventoryEncoder
+	case InventoryNDJSON:
+		encode = newNDJSONInventoryEncoder
+	default:
+		return nil, fmt.Errorf("unknown inventory format %q", format)
+	}
+
+	// the writer is aborted by cancelling its context before closing it
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	writer, err := storage.GetWriter(ctx, dstKey)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := writeInventory(ctx, storage, prefix, dstKey, writer, encode)
+	if err != nil {
+		cancel()
+		writer.Close()
+
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func writeInventory(
+	ctx context.Context,
+	storage CloudStorage,
+	prefix string,
+	dstKey string,
+	writer io.Writer,
+	encode func(w io.Writer) (inventoryEncoder, error),
+) (*InventoryReport, error) {
+	encoder, err := encode(writer)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &InventoryReport{}
+	items, errs := ListStream(ctx, storage, prefix)
+
+	for item := range items {
+		// the inventory being written may be listed on some providers
+		if item.Key == dstKey {
+			continue
+		}
+
+		err := encoder.Encode(&InventoryRecord{
+			Key:          item.Key,
+			Size:         item.Size,
+			LastModified: item.ModTime.UTC(),
+			ETag:         item.ETag,
+			StorageClass: item.StorageClass,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		report.Objects++
+		report.Bytes += item.Size
+	}
+
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+
+	return report, encoder.Flush()
+}
+
+type inventoryEncoder interface {
+	Encode(record *InventoryRecord) error
+	Flush() error
+}
+
+type csvInventoryEncoder struct {
+	writer *csv.Writer
+}
+
+func newCSVInventoryEncoder(w io.Writer) (inventoryEncoder, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(inventoryHeader); err != nil {
+		return nil, err
+	}
+
+	return &csvInventoryEncoder{writer: writer}, nil
+}
+
+func (e *csvInventoryEncoder) Encode(record *InventoryRecord) error {
+	return e.writer.Write([]string{
+		record.Key,
+		strconv.FormatInt(record.Size, 10),
+		record.LastModified.Format(time.RFC3339),
+		record.ETag,
+		record.StorageClass,
+	})
+}
+
+func (e *csvInventoryEncoder) Flush() error {
+	e.writer.Flush()
+
+	return e.writer.Error()
+}
+
+type ndjsonInventoryEncoder struct {
+	encoder *json.Encoder
+}
+
+func newNDJSONInventoryEncoder(w io.Writer) (inventoryEncoder, error) {
+	return &ndjsonInventoryEncoder{encoder: json.NewEncoder(w)}, nil
+}
+
+func (e *ndjsonInventoryEncoder) Encode(record *InventoryRecord) error {
+	return e.encoder.Encode(record)
+}
+
+func (e *ndjsonInventoryEncoder) Flush() error {
+	return nil
+}
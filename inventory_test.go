@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateInventory(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+	modTime := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	storage.SetClock(func() time.Time { return modTime })
+
+	require.NoError(t, storage.Write(ctx, "data/a.txt", []byte("a"), nil))
+	require.NoError(t, storage.Write(ctx, "data/b.txt", []byte("bb"), nil))
+	require.NoError(t, storage.Write(ctx, "other/c.txt", []byte("c"), nil))
+
+	report, err := GenerateInventory(ctx, storage, "data/", "data/inventory.csv", InventoryCSV)
+	require.NoError(t, err)
+	require.Equal(t, &InventoryReport{Objects: 2, Bytes: 3}, report)
+
+	body, err := storage.Get(ctx, "data/inventory.csv")
+	require.NoError(t, err)
+
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"key", "size", "last_modified", "etag", "storage_class"},
+		{"data/a.txt", "1", "2020-05-01T10:00:00Z", "0cc175b9c0f1b6a831c399e269772661", "STANDARD"},
+		{"data/b.txt", "2", "2020-05-01T10:00:00Z", "21ad0bd836b90d08f4cf640b4c298e7c", "STANDARD"},
+	}, records)
+
+	// the previous inventory is listed this time
+	report, err = GenerateInventory(ctx, storage, "", "inventory.ndjson", InventoryNDJSON)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), report.Objects)
+
+	body, err = storage.Get(ctx, "inventory.ndjson")
+	require.NoError(t, err)
+
+	var keys []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		var record InventoryRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+
+		keys = append(keys, record.Key)
+	}
+
+	require.Equal(t, []string{"data/a.txt", "data/b.txt", "data/inventory.csv", "other/c.txt"}, keys)
+
+	_, err = GenerateInventory(ctx, storage, "", "inventory.xml", InventoryFormat("xml"))
+	require.Error(t, err)
+}
+
+func TestGenerateInventoryListingFailure(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	errListing := errors.New("listing failed")
+
+	require.NoError(t, fake.Write(ctx, "data/a.txt", []byte("a"), nil))
+
+	storage := NewFaultInjectingCloudStorage(fake, FaultInjectionOption{
+		Operations: map[string]Fault{"List": {ErrorRate: 1, Err: errListing}},
+	})
+
+	_, err := GenerateInventory(ctx, storage, "data/", "inventory.csv", InventoryCSV)
+	require.True(t, errors.Is(err, errListing))
+
+	// no partial inventory is stored
+	_, err = fake.Get(ctx, "inventory.csv")
+	require.True(t, errors.Is(err, ErrNotFound))
+}
@@ -0,0 +1,313 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	kmspb "google.golang.org/genproto/googleapis/cloud/kms/v1"
+)
+
+// envelopeMagic starts the objects encrypted by the envelope transformer
+var envelopeMagic = []byte("ENV1")
+
+// dataKeySize is the size of the AES-256 data keys generated for every object
+const dataKeySize = 32
+
+// KeyProvider encrypts the data keys of the envelope encryption with a key encryption key, e.g. a KMS key.
+// The key ID is stored with every object, so the objects encrypted with the previous keys can still be decrypted
+// after a rotation, without being encrypted again.
+type KeyProvider interface {
+	// KeyID is the ID of the current key, which encrypts the data keys of the new objects
+	KeyID() string
+	// WrapKey encrypts the data key with the current key
+	WrapKey(ctx context.Context, dataKey []byte) ([]byte, error)
+	// UnwrapKey decrypts the data key encrypted with the key of the ID
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// localKeyProvider encrypts the data keys with AES-GCM keys held in memory
+type localKeyProvider struct {
+	keyID string
+	keys  map[string]cipher.AEAD
+}
+
+// NewLocalKeyProvider returns a KeyProvider encrypting the data keys with the local AES keys, by ID.
+// The key of currentKeyID encrypts the new objects, the others are kept to decrypt the objects encrypted before
+// the rotation. The keys are 16, 24 or 32 bytes long.
+func NewLocalKeyProvider(currentKeyID string, keys map[string][]byte) (KeyProvider, error) {
+	if _, ok := keys[currentKeyID]; !ok {
+		return nil, fmt.Errorf("current key '%s' isn't in the keys", currentKeyID)
+	}
+
+	provider := &localKeyProvider{keyID: currentKeyID, keys: make(map[string]cipher.AEAD, len(keys))}
+
+	for id, key := range keys {
+		aead, err := newAESGCM(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key '%s': %w", id, err)
+		}
+
+		provider.keys[id] = aead
+	}
+
+	return provider, nil
+}
+
+func (p *localKeyProvider) KeyID() string {
+	return p.keyID
+}
+
+func (p *localKeyProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	return sealWithNonce(p.keys[p.keyID], dataKey, []byte(p.keyID))
+}
+
+func (p *localKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key '%s'", ErrDecryptionFailed, keyID)
+	}
+
+	return openWithNonce(aead, wrapped, []byte(keyID))
+}
+
+// awsKMSKeyProvider encrypts the data keys with AWS KMS
+type awsKMSKeyProvider struct {
+	client kmsiface.KMSAPI
+	keyID  string
+}
+
+// NewAWSKMSKeyProvider returns a KeyProvider encrypting the data keys with the AWS KMS key, its ID, ARN or alias.
+// The objects encrypted with a previous key are decrypted with the key of their ID.
+func NewAWSKMSKeyProvider(client kmsiface.KMSAPI, keyID string) KeyProvider {
+	return &awsKMSKeyProvider{client: client, keyID: keyID}
+}
+
+func (p *awsKMSKeyProvider) KeyID() string {
+	return p.keyID
+}
+
+func (p *awsKMSKeyProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	output, err := p.client.EncryptWithContext(ctx, &kms.EncryptInput{
+		KeyId:     aws.String(p.keyID),
+		Plaintext: dataKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return output.CiphertextBlob, nil
+}
+
+func (p *awsKMSKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	output, err := p.client.DecryptWithContext(ctx, &kms.DecryptInput{
+		KeyId:          aws.String(keyID),
+		CiphertextBlob: wrapped,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return output.Plaintext, nil
+}
+
+// gcpKMSKeyProvider encrypts the data keys with GCP Cloud KMS
+type gcpKMSKeyProvider struct {
+	client  kmspb.KeyManagementServiceClient
+	keyName string
+}
+
+// NewGCPKMSKeyProvider returns a KeyProvider encrypting the data keys with the Cloud KMS key, named
+// "projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>". Cloud KMS keeps the rotated versions
+// of the key, and the objects encrypted with another key are decrypted with the key of their name.
+func NewGCPKMSKeyProvider(client kmspb.KeyManagementServiceClient, keyName string) KeyProvider {
+	return &gcpKMSKeyProvider{client: client, keyName: keyName}
+}
+
+func (p *gcpKMSKeyProvider) KeyID() string {
+	return p.keyName
+}
+
+func (p *gcpKMSKeyProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	response, err := p.client.Encrypt(ctx, &kmspb.EncryptRequest{Name: p.keyName, Plaintext: dataKey})
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Ciphertext, nil
+}
+
+func (p *gcpKMSKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	response, err := p.client.Decrypt(ctx, &kmspb.DecryptRequest{Name: keyID, Ciphertext: wrapped})
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Plaintext, nil
+}
+
+// envelopeTransformer encrypts every object with its own data key, stored encrypted by the KeyProvider
+type envelopeTransformer struct {
+	provider KeyProvider
+}
+
+// NewEnvelopeTransformer returns a Transformer encrypting every object with AES-256-GCM and a random data key.
+// The data key is encrypted by the provider and stored in the header of the object with the ID of the key,
+// followed by the nonce and the ciphertext. The object key and the header are authenticated along with the
+// content. The provider isn't called with the context of the operation, which the Transformer doesn't receive.
+func NewEnvelopeTransformer(provider KeyProvider) Transformer {
+	return &envelopeTransformer{provider: provider}
+}
+
+func (t *envelopeTransformer) Encode(key string, body []byte) ([]byte, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+
+	keyID := t.provider.KeyID()
+
+	wrapped, err := t.provider.WrapKey(context.Background(), dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encrypt the data key of '%s' with '%s': %w", key, keyID, err)
+	}
+
+	header := append([]byte(nil), envelopeMagic...)
+	header = appendField(header, []byte(keyID))
+	header = appendField(header, wrapped)
+
+	aead, err := newAESGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := sealWithNonce(aead, body, append([]byte(key), header...))
+	if err != nil {
+		return nil, err
+	}
+
+	return append(header, ciphertext...), nil
+}
+
+func (t *envelopeTransformer) Decode(key string, body []byte) ([]byte, error) {
+	keyID, wrapped, ciphertext, err := parseEnvelope(body)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := t.provider.UnwrapKey(context.Background(), keyID, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to decrypt the data key of '%s' with '%s': %v", ErrDecryptionFailed, key, keyID, err)
+	}
+
+	aead, err := newAESGCM(dataKey)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+
+	header := body[:len(body)-len(ciphertext)]
+
+	return openWithNonce(aead, ciphertext, append([]byte(key), header...))
+}
+
+// EnvelopeKeyID returns the ID of the key which encrypted the data key of an object written with the envelope
+// transformer, e.g. to find the objects to encrypt again before retiring a key
+func EnvelopeKeyID(body []byte) (string, error) {
+	keyID, _, _, err := parseEnvelope(body)
+
+	return keyID, err
+}
+
+func parseEnvelope(body []byte) (keyID string, wrapped []byte, ciphertext []byte, err error) {
+	if len(body) < len(envelopeMagic) || string(body[:len(envelopeMagic)]) != string(envelopeMagic) {
+		return "", nil, nil, fmt.Errorf("%w: the object isn't encrypted with a data key", ErrDecryptionFailed)
+	}
+
+	id, rest, ok := readField(body[len(envelopeMagic):])
+	if !ok {
+		return "", nil, nil, ErrDecryptionFailed
+	}
+
+	wrapped, ciphertext, ok = readField(rest)
+	if !ok {
+		return "", nil, nil, ErrDecryptionFailed
+	}
+
+	return string(id), wrapped, ciphertext, nil
+}
+
+// appendField appends the field prefixed by its 2 bytes big-endian length
+func appendField(b []byte, field []byte) []byte {
+	var size [2]byte
+	binary.BigEndian.PutUint16(size[:], uint16(len(field)))
+
+	return append(append(b, size[:]...), field...)
+}
+
+func readField(b []byte) (field []byte, rest []byte, ok bool) {
+	if len(b) < 2 {
+		return nil, nil, false
+	}
+
+	size := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+size {
+		return nil, nil, false
+	}
+
+	return b[2 : 2+size], b[2+size:], true
+}
+
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// sealWithNonce encrypts the plaintext with a random nonce, stored before the ciphertext
+func sealWithNonce(aead cipher.AEAD, plaintext []byte, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func openWithNonce(aead cipher.AEAD, sealed []byte, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrDecryptionFailed
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additionalData)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+
+	return plaintext, nil
+}
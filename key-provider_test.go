@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/stretchr/testify/require"
+	kmspb "google.golang.org/genproto/googleapis/cloud/kms/v1"
+	"google.golang.org/grpc"
+)
+
+func TestEnvelopeTransformerRotation(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+
+	before, err := NewLocalKeyProvider("v1", map[string][]byte{"v1": oldKey})
+	require.NoError(t, err)
+
+	encrypted, err := NewEnvelopeTransformer(before).Encode("pii/user", []byte("alice"))
+	require.NoError(t, err)
+	require.NotContains(t, string(encrypted), "alice")
+
+	keyID, err := EnvelopeKeyID(encrypted)
+	require.NoError(t, err)
+	require.Equal(t, "v1", keyID)
+
+	// the objects encrypted before the rotation are still decrypted with their key
+	after, err := NewLocalKeyProvider("v2", map[string][]byte{"v1": oldKey, "v2": newKey})
+	require.NoError(t, err)
+
+	transformer := NewEnvelopeTransformer(after)
+
+	decrypted, err := transformer.Decode("pii/user", encrypted)
+	require.NoError(t, err)
+	require.Equal(t, "alice", string(decrypted))
+
+	rotated, err := transformer.Encode("pii/user", decrypted)
+	require.NoError(t, err)
+
+	keyID, err = EnvelopeKeyID(rotated)
+	require.NoError(t, err)
+	require.Equal(t, "v2", keyID)
+
+	// the object key is authenticated
+	_, err = transformer.Decode("pii/other", rotated)
+	require.True(t, errors.Is(err, ErrDecryptionFailed))
+
+	retired, err := NewLocalKeyProvider("v2", map[string][]byte{"v2": newKey})
+	require.NoError(t, err)
+
+	_, err = NewEnvelopeTransformer(retired).Decode("pii/user", encrypted)
+	require.True(t, errors.Is(err, ErrDecryptionFailed))
+}
+
+func TestNewLocalKeyProvider(t *testing.T) {
+	_, err := NewLocalKeyProvider("v2", map[string][]byte{"v1": bytes.Repeat([]byte{1}, 32)})
+	require.Error(t, err)
+
+	_, err = NewLocalKeyProvider("v1", map[string][]byte{"v1": []byte("short")})
+	require.Error(t, err)
+}
+
+// xorKMS "encrypts" the data keys by xoring them with the last byte of the key ID
+func xorKMS(keyID string, data []byte) []byte {
+	result := make([]byte, len(data))
+	for i, b := range data {
+		result[i] = b ^ keyID[len(keyID)-1]
+	}
+
+	return result
+}
+
+type fakeAWSKMS struct {
+	kmsiface.KMSAPI
+}
+
+func (f *fakeAWSKMS) EncryptWithContext(
+	ctx aws.Context,
+	input *kms.EncryptInput,
+	opts ...request.Option,
+) (*kms.EncryptOutput, error) {
+	return &kms.EncryptOutput{CiphertextBlob: xorKMS(aws.StringValue(input.KeyId), input.Plaintext)}, nil
+}
+
+func (f *fakeAWSKMS) DecryptWithContext(
+	ctx aws.Context,
+	input *kms.DecryptInput,
+	opts ...request.Option,
+) (*kms.DecryptOutput, error) {
+	return &kms.DecryptOutput{Plaintext: xorKMS(aws.StringValue(input.KeyId), input.CiphertextBlob)}, nil
+}
+
+type fakeGCPKMS struct {
+	kmspb.KeyManagementServiceClient
+}
+
+func (f *fakeGCPKMS) Encrypt(
+	ctx context.Context,
+	request *kmspb.EncryptRequest,
+	opts ...grpc.CallOption,
+) (*kmspb.EncryptResponse, error) {
+	return &kmspb.EncryptResponse{Ciphertext: xorKMS(request.Name, request.Plaintext)}, nil
+}
+
+func (f *fakeGCPKMS) Decrypt(
+	ctx context.Context,
+	request *kmspb.DecryptRequest,
+	opts ...grpc.CallOption,
+) (*kmspb.DecryptResponse, error) {
+	return &kmspb.DecryptResponse{Plaintext: xorKMS(request.Name, request.Ciphertext)}, nil
+}
+
+func TestKMSKeyProviders(t *testing.T) {
+	for name, provider := range map[string]KeyProvider{
+		"aws": NewAWSKMSKeyProvider(&fakeAWSKMS{}, "alias/blob-1"),
+		"gcp": NewGCPKMSKeyProvider(&fakeGCPKMS{}, "projects/p/locations/global/keyRings/r/cryptoKeys/blob-1"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			transformer := NewEnvelopeTransformer(provider)
+
+			encrypted, err := transformer.Encode("pii/user", []byte("alice"))
+			require.NoError(t, err)
+
+			keyID, err := EnvelopeKeyID(encrypted)
+			require.NoError(t, err)
+			require.Equal(t, provider.KeyID(), keyID)
+
+			decrypted, err := transformer.Decode("pii/user", encrypted)
+			require.NoError(t, err)
+			require.Equal(t, "alice", string(decrypted))
+		})
+	}
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxKeyBytes is the maximum length of a key for both S3 and GCS
+const maxKeyBytes = 1024
+
+// ErrInvalidKey is matched by errors.Is when a key is rejected by ValidateKey
+var ErrInvalidKey = errors.New("invalid key")
+
+// InvalidKeyError gives the reason why a key is rejected
+type InvalidKeyError struct {
+	Key    string
+	Reason string
+}
+
+func (e *InvalidKeyError) Error() string {
+	return fmt.Sprintf("invalid key '%s': %s", e.Key, e.Reason)
+}
+
+func (e *InvalidKeyError) Is(target error) bool {
+	return target == ErrInvalidKey
+}
+
+// bucketOperations are the operations without a key or taking a prefix, they aren't validated
+var bucketOperations = map[string]bool{
+	"CreateBucket":            true,
+	"CreateBucketWithOptions": true,
+	"GetBucketPolicy":         true,
+	// GetScopedCredentials takes a prefix
+	"GetScopedCredentials": true,
+	"SetBucketPolicy":      true,
+	"GetBucketEncryption":  true,
+	"SetBucketEncryption":  true,
+	"GetBucketLogging":     true,
+	"SetBucketLogging":     true,
+	"GetBucketReplication": true,
+	"SetBucketReplication": true,
+	"Ping":                 true,
+	"Subscribe":            true,
+	// List and ListIncompleteUploads take a prefix
+	"List":                  true,
+	"ListIncompleteUploads": true,
+	"AbortStaleUploads":     true,
+}
+
+// ValidateKey returns an *InvalidKeyError if the key breaks the constraints of one of the providers,
+// or is likely to be misinterpreted as a path.
+func ValidateKey(key string) error {
+	reason := invalidKeyReason(key)
+	if reason == "" {
+		return nil
+	}
+
+	return &InvalidKeyError{Key: key, Reason: reason}
+}
+
+func invalidKeyReason(key string) string {
+	switch {
+	case key == "":
+		return "the key is empty"
+	case len(key) > maxKeyBytes:
+		return fmt.Sprintf("the key is longer than %d bytes", maxKeyBytes)
+	case !utf8.ValidString(key):
+		return "the key isn't valid UTF-8"
+	case strings.HasPrefix(key, "/"):
+		return "the key starts with a slash"
+	case strings.HasPrefix(key, ".well-known/acme-challenge/"):
+		return "the key is reserved by GCS"
+	}
+
+	for _, r := range key {
+		if r < 0x20 || r == 0x7f {
+			return "the key contains a control character"
+		}
+	}
+
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "." || segment == ".." {
+			return "the key contains a relative path segment"
+		}
+	}
+
+	return ""
+}
+
+func newKeyValidationInterceptor() Interceptor {
+	return func(ctx context.Context, op *OperationInfo, next func(ctx context.Context) error) error {
+		if !bucketOperations[op.Name] {
+			if err := ValidateKey(op.Key); err != nil {
+				return err
+			}
+		}
+
+		if op.DestinationKey != "" {
+			if err := ValidateKey(op.DestinationKey); err != nil {
+				return err
+			}
+		}
+
+		return next(ctx)
+	}
+}
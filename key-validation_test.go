@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateKey(t *testing.T) {
+	require.NoError(t, ValidateKey("folder/file.json"))
+	require.NoError(t, ValidateKey("folder/..file"))
+
+	for _, key := range []string{
+		"",
+		"/folder/file",
+		"folder/../file",
+		"./file",
+		"file\n",
+		"\x00",
+		"\xff",
+		".well-known/acme-challenge/token",
+		strings.Repeat("a", 1025),
+	} {
+		err := ValidateKey(key)
+		require.True(t, errors.Is(err, ErrInvalidKey), key)
+	}
+}
+
+func TestKeyValidationInterceptor(t *testing.T) {
+	backend := &recordingCloudStorage{objects: make(map[string][]byte)}
+	storage := newInterceptedCloudStorage(backend, "aws", "bucket", newKeyValidationInterceptor())
+
+	err := storage.Write(context.Background(), "../key", []byte("body"), nil)
+	require.True(t, errors.Is(err, ErrInvalidKey))
+	require.Empty(t, backend.objects)
+}
+
+func TestKeyValidationInterceptorDestinationKeys(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	storage := newInterceptedCloudStorage(fake, "aws", "bucket", newKeyValidationInterceptor())
+
+	require.NoError(t, fake.Write(ctx, "a/key", []byte("body"), nil))
+
+	require.True(t, errors.Is(storage.Copy(ctx, "../key", "a/copy", nil), ErrInvalidKey))
+	require.True(t, errors.Is(storage.Copy(ctx, "a/key", "../copy", nil), ErrInvalidKey))
+	require.True(t, errors.Is(storage.RenameFolder(ctx, "../a", "b"), ErrInvalidKey))
+	require.True(t, errors.Is(storage.RenameFolder(ctx, "a", "../b"), ErrInvalidKey))
+
+	require.NoError(t, storage.Copy(ctx, "a/key", "a/copy", nil))
+	require.Equal(t, []string{"a/copy", "a/key"}, listedKeys(t, fake.List(ctx, "")))
+}
@@ -0,0 +1,698 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrStorageClosed is returned by the calls made to a LazyCloudStorage after Close
+var ErrStorageClosed = errors.New("storage is closed")
+
+// LazyCloudStorage defers the creation of the provider client and the validation of the bucket until its first
+// use, so services can start even when the storage is temporarily unreachable.
+// A failed creation or validation is retried on the next call. CreateBucket and CreateBucketWithOptions don't
+// validate the bucket, which may not exist yet.
+type LazyCloudStorage struct {
+	connect func(ctx context.Context) (CloudStorage, error)
+	logger  Logger
+	codec   Codec
+
+	mu        sync.Mutex
+	storage   CloudStorage
+	validated bool
+	closed    bool
+}
+
+func newLazyCloudStorage(connect func(ctx context.Context) (CloudStorage, error), logger Logger) *LazyCloudStorage {
+	return &LazyCloudStorage{
+		connect: connect,
+		logger:  loggerOrNoop(logger),
+	}
+}
+
+// Connect creates the provider client and validates the bucket with Ping if it hasn't been done yet
+func (ts *LazyCloudStorage) Connect(ctx context.Context) error {
+	_, err := ts.get(ctx)
+
+	return err
+}
+
+// get returns the provider client once the bucket is validated
+func (ts *LazyCloudStorage) get(ctx context.Context) (CloudStorage, error) {
+	return ts.open(ctx, true)
+}
+
+// client returns the provider client without validating the bucket
+func (ts *LazyCloudStorage) client(ctx context.Context) (CloudStorage, error) {
+	return ts.open(ctx, false)
+}
+
+func (ts *LazyCloudStorage) open(ctx context.Context, validate bool) (CloudStorage, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.closed {
+		return nil, ErrStorageClosed
+	}
+
+	if ts.storage == nil {
+		storage, err := ts.connect(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		ts.storage = storage
+	}
+
+	if validate && !ts.validated {
+		if err := ts.storage.Ping(ctx); err != nil {
+			return nil, err
+		}
+
+		ts.validated = true
+	}
+
+	return ts.storage, nil
+}
+
+func (ts *LazyCloudStorage) List(
+	ctx context.Context,
+	prefix string,
+	opts ...ListOption,
+) *ListIterator {
+	var iter *ListIterator
+
+	return NewListIterator(ctx, func(nextCtx context.Context) (*ListObject, error) {
+		if iter == nil {
+			storage, err := ts.get(nextCtx)
+			if err != nil {
+				return nil, err
+			}
+
+			iter = storage.List(ctx, prefix, opts...)
+		}
+
+		return iter.Next(nextCtx)
+	})
+}
+
+func (ts *LazyCloudStorage) Get(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) ([]byte, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.Get(ctx, key, opts...)
+}
+
+func (ts *LazyCloudStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Delete(ctx, key)
+}
+
+func (ts *LazyCloudStorage) CreateBucket(
+	ctx context.Context,
+	bucketPrefix string,
+	expirationTimeDays int64,
+) error {
+	storage, err := ts.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.CreateBucket(ctx, bucketPrefix, expirationTimeDays)
+}
+
+func (ts *LazyCloudStorage) CreateBucketWithOptions(
+	ctx context.Context,
+	opts *CreateBucketOption,
+) error {
+	storage, err := ts.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.CreateBucketWithOptions(ctx, opts)
+}
+
+// Close closes the provider client, if it was created. The later calls fail with ErrStorageClosed.
+func (ts *LazyCloudStorage) Close() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.storage != nil && !ts.closed {
+		ts.storage.Close()
+	}
+
+	ts.closed = true
+}
+
+func (ts *LazyCloudStorage) GetSignedURL(
+	ctx context.Context,
+	key string,
+	opts *SignedURLOption,
+) (string, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return storage.GetSignedURL(ctx, key, opts)
+}
+
+func (ts *LazyCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Write(ctx, key, body, contentType, opts...)
+}
+
+func (ts *LazyCloudStorage) Attributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (*Attributes, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.Attributes(ctx, key, opts...)
+}
+
+func (ts *LazyCloudStorage) GetReader(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.GetReader(ctx, key, opts...)
+}
+
+func (ts *LazyCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return storage.GetWithAttributes(ctx, key, opts...)
+}
+
+func (ts *LazyCloudStorage) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset,
+	length int64,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.GetRangeReader(ctx, key, offset, length, opts...)
+}
+
+func (ts *LazyCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.GetWriter(ctx, key, opts...)
+}
+
+func (ts *LazyCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Upload(ctx, key, reader, opts, writeOpts...)
+}
+
+func (ts *LazyCloudStorage) SetObjectRetention(
+	ctx context.Context,
+	key string,
+	retention *ObjectRetention,
+) error {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.SetObjectRetention(ctx, key, retention)
+}
+
+func (ts *LazyCloudStorage) GetObjectRetention(
+	ctx context.Context,
+	key string,
+) (*ObjectRetention, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.GetObjectRetention(ctx, key)
+}
+
+func (ts *LazyCloudStorage) SetLegalHold(
+	ctx context.Context,
+	key string,
+	enabled bool,
+) error {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.SetLegalHold(ctx, key, enabled)
+}
+
+func (ts *LazyCloudStorage) GetLegalHold(
+	ctx context.Context,
+	key string,
+) (bool, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return storage.GetLegalHold(ctx, key)
+}
+
+func (ts *LazyCloudStorage) SetStorageClass(
+	ctx context.Context,
+	key string,
+	storageClass string,
+) error {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.SetStorageClass(ctx, key, storageClass)
+}
+
+func (ts *LazyCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Copy(ctx, srcKey, dstKey, opts)
+}
+
+func (ts *LazyCloudStorage) Undelete(
+	ctx context.Context,
+	key string,
+) error {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Undelete(ctx, key)
+}
+
+func (ts *LazyCloudStorage) GetBucketPolicy(
+	ctx context.Context,
+) (*BucketPolicy, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.GetBucketPolicy(ctx)
+}
+
+func (ts *LazyCloudStorage) GetScopedCredentials(
+	ctx context.Context,
+	opts *ScopedCredentialsOption,
+) (*ScopedCredentials, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.GetScopedCredentials(ctx, opts)
+}
+
+func (ts *LazyCloudStorage) CreateFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.CreateFolder(ctx, folder)
+}
+
+func (ts *LazyCloudStorage) DeleteFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.DeleteFolder(ctx, folder)
+}
+
+func (ts *LazyCloudStorage) RenameFolder(
+	ctx context.Context,
+	folder string,
+	newFolder string,
+) error {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.RenameFolder(ctx, folder, newFolder)
+}
+
+func (ts *LazyCloudStorage) SetBucketPolicy(
+	ctx context.Context,
+	policy *BucketPolicy,
+) error {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.SetBucketPolicy(ctx, policy)
+}
+
+func (ts *LazyCloudStorage) GetBucketEncryption(
+	ctx context.Context,
+) (*BucketEncryption, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.GetBucketEncryption(ctx)
+}
+
+func (ts *LazyCloudStorage) SetBucketEncryption(
+	ctx context.Context,
+	encryption *BucketEncryption,
+) error {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.SetBucketEncryption(ctx, encryption)
+}
+
+func (ts *LazyCloudStorage) GetBucketLogging(
+	ctx context.Context,
+) (*BucketLogging, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.GetBucketLogging(ctx)
+}
+
+func (ts *LazyCloudStorage) SetBucketLogging(
+	ctx context.Context,
+	logging *BucketLogging,
+) error {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.SetBucketLogging(ctx, logging)
+}
+
+func (ts *LazyCloudStorage) GetBucketReplication(
+	ctx context.Context,
+) (*BucketReplication, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.GetBucketReplication(ctx)
+}
+
+func (ts *LazyCloudStorage) SetBucketReplication(
+	ctx context.Context,
+	replication *BucketReplication,
+) error {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.SetBucketReplication(ctx, replication)
+}
+
+// GetPublicURL returns an empty string if the provider client can't be created
+func (ts *LazyCloudStorage) GetPublicURL(
+	key string,
+) string {
+	storage, err := ts.client(context.Background())
+	if err != nil {
+		ts.logger.Error("unable to create the cloud storage client", Fields{"error": err})
+		return ""
+	}
+
+	return storage.GetPublicURL(key)
+}
+
+// As returns false if the provider client can't be created
+func (ts *LazyCloudStorage) As(
+	target interface{},
+) bool {
+	storage, err := ts.client(context.Background())
+	if err != nil {
+		ts.logger.Error("unable to create the cloud storage client", Fields{"error": err})
+		return false
+	}
+
+	return storage.As(target)
+}
+
+// ErrorAs falls back to errors.As if the provider client can't be created
+func (ts *LazyCloudStorage) ErrorAs(
+	err error,
+	target interface{},
+) bool {
+	storage, getErr := ts.client(context.Background())
+	if getErr != nil {
+		return errors.As(err, target)
+	}
+
+	return storage.ErrorAs(err, target)
+}
+
+// Ping validates the bucket, the other calls don't validate it again once it succeeds
+func (ts *LazyCloudStorage) Ping(
+	ctx context.Context,
+) error {
+	storage, err := ts.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := storage.Ping(ctx); err != nil {
+		return err
+	}
+
+	ts.mu.Lock()
+	ts.validated = true
+	ts.mu.Unlock()
+
+	return nil
+}
+
+func (ts *LazyCloudStorage) Subscribe(
+	ctx context.Context,
+	prefix string,
+) (<-chan ObjectEvent, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.Subscribe(ctx, prefix)
+}
+
+func (ts *LazyCloudStorage) ListIncompleteUploads(
+	ctx context.Context,
+	prefix string,
+) ([]*IncompleteUpload, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.ListIncompleteUploads(ctx, prefix)
+}
+
+func (ts *LazyCloudStorage) Query(
+	ctx context.Context,
+	key string,
+	sql string,
+	format QueryInputFormat,
+) (io.ReadCloser, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.Query(ctx, key, sql, format)
+}
+
+func (ts *LazyCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return storage.WriteIf(ctx, key, body, contentType, condition)
+}
+
+func (ts *LazyCloudStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.DeleteIf(ctx, key, generation)
+}
+
+func (ts *LazyCloudStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.BeginUpload(ctx, key, opts)
+}
+
+func (ts *LazyCloudStorage) ResumeUpload(
+	ctx context.Context,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.ResumeUpload(ctx, state)
+}
+
+func (ts *LazyCloudStorage) AbortStaleUploads(
+	ctx context.Context,
+	olderThan time.Duration,
+) ([]*IncompleteUpload, error) {
+	storage, err := ts.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.AbortStaleUploads(ctx, olderThan)
+}
+
+// Codec returns the Codec option of the storage, it doesn't connect
+func (ts *LazyCloudStorage) Codec() Codec {
+	return ts.codec
+}
+
+// Flush flushes the provider client, it doesn't create it when it hasn't been created yet
+func (ts *LazyCloudStorage) Flush(ctx context.Context) error {
+	ts.mu.Lock()
+	storage := ts.storage
+	ts.mu.Unlock()
+
+	if storage == nil {
+		return nil
+	}
+
+	return flushStorage(ctx, storage)
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type pingingCloudStorage struct {
+	CloudStorage
+	pingErr error
+	pings   int
+	closes  int
+}
+
+func (ts *pingingCloudStorage) Ping(ctx context.Context) error {
+	ts.pings++
+
+	return ts.pingErr
+}
+
+func (ts *pingingCloudStorage) Close() {
+	ts.closes++
+}
+
+func TestLazyCloudStorageRetriesFailedConnections(t *testing.T) {
+	ctx := context.Background()
+	recording := &recordingCloudStorage{objects: make(map[string][]byte)}
+	backend := &pingingCloudStorage{CloudStorage: recording}
+	attempts := 0
+
+	storage := newLazyCloudStorage(func(ctx context.Context) (CloudStorage, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, errors.New("unreachable")
+		}
+
+		return backend, nil
+	}, nil)
+
+	require.Equal(t, 0, attempts)
+	require.Error(t, storage.Write(ctx, "key", []byte("body"), nil))
+	require.NoError(t, storage.Write(ctx, "key", []byte("body"), nil))
+	require.NoError(t, storage.Connect(ctx))
+	require.Equal(t, 2, attempts)
+	require.Equal(t, 1, backend.pings)
+	require.Equal(t, []byte("body"), recording.objects["key"])
+}
+
+func TestLazyCloudStorageValidatesTheBucket(t *testing.T) {
+	ctx := context.Background()
+	backend := &pingingCloudStorage{CloudStorage: NewFakeCloudStorage("bucket"), pingErr: ErrNotFound}
+	attempts := 0
+
+	storage := newLazyCloudStorage(func(ctx context.Context) (CloudStorage, error) {
+		attempts++
+
+		return backend, nil
+	}, nil)
+
+	require.True(t, errors.Is(storage.Connect(ctx), ErrNotFound))
+	require.True(t, errors.Is(storage.Write(ctx, "key", []byte("body"), nil), ErrNotFound))
+	require.Equal(t, 2, backend.pings)
+
+	// the bucket may not exist yet when it's created
+	require.NoError(t, storage.CreateBucket(ctx, "bucket", 1))
+	require.Equal(t, 2, backend.pings)
+
+	backend.pingErr = nil
+	require.NoError(t, storage.Connect(ctx))
+	require.NoError(t, storage.Write(ctx, "key", []byte("body"), nil))
+	require.Equal(t, 3, backend.pings)
+	require.Equal(t, 1, attempts)
+}
+
+func TestLazyCloudStorageStaysClosed(t *testing.T) {
+	ctx := context.Background()
+	backend := &pingingCloudStorage{CloudStorage: NewFakeCloudStorage("bucket")}
+	attempts := 0
+
+	storage := newLazyCloudStorage(func(ctx context.Context) (CloudStorage, error) {
+		attempts++
+
+		return backend, nil
+	}, nil)
+
+	require.NoError(t, storage.Connect(ctx))
+	storage.Close()
+	storage.Close()
+
+	require.Equal(t, 1, backend.closes)
+	require.True(t, errors.Is(storage.Connect(ctx), ErrStorageClosed))
+	require.True(t, errors.Is(storage.Write(ctx, "key", []byte("body"), nil), ErrStorageClosed))
+	require.True(t, errors.Is(storage.Ping(ctx), ErrStorageClosed))
+	require.Equal(t, 1, attempts)
+}
+
+type recordingLogger struct {
+	noopLogger
+	errors []string
+}
+
+func (l *recordingLogger) Error(msg string, fields Fields) {
+	l.errors = append(l.errors, fmt.Sprintf("%s: %v", msg, fields["error"]))
+}
+
+func TestLazyCloudStorageLogsConnectionErrors(t *testing.T) {
+	logger := &recordingLogger{}
+	storage := newLazyCloudStorage(func(ctx context.Context) (CloudStorage, error) {
+		return nil, errors.New("unreachable")
+	}, logger)
+
+	require.Equal(t, "", storage.GetPublicURL("key"))
+	require.Equal(t, []string{"unable to create the cloud storage client: unreachable"}, logger.errors)
+}
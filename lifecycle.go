@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+)
+
+// LifecycleRule is a provider-neutral lifecycle rule of a bucket, to be simulated with SimulateLifecycle
+type LifecycleRule struct {
+	Prefix string
+	// ExpirationDays deletes the objects this many days after their last modification, zero keeps them
+	ExpirationDays int
+	// Transitions move the objects to other storage classes
+	Transitions []LifecycleTransition
+}
+
+// LifecycleTransition moves the objects to the storage class this many days after their last modification
+type LifecycleTransition struct {
+	Days         int
+	StorageClass string
+}
+
+// LifecycleAction is what a rule set would do to an object
+type LifecycleAction struct {
+	Key  string
+	Size int64
+	// From is the current storage class of the object
+	From string
+	// To is the storage class the object would be moved to, empty when it would be expired
+	To string
+}
+
+// LifecycleSimulation reports what a rule set would do to the objects of a prefix
+type LifecycleSimulation struct {
+	// Objects and Bytes are the listed objects and their total size
+	Objects int64
+	Bytes   int64
+	Expired []LifecycleAction
+	// ExpiredBytes is the total size of the expired objects
+	ExpiredBytes int64
+	Transitioned []LifecycleAction
+	// TransitionedBytes is the total size of the transitioned objects by destination storage class
+	TransitionedBytes map[string]int64
+}
+
+// Savings returns the monthly saving of the simulated actions, with the prices of the storage classes
+// by GB and month. The storage classes without price cost nothing.
+func (s *LifecycleSimulation) Savings(prices map[string]float64) float64 {
+	const gb = 1 << 30
+
+	var savings float64
+
+	for _, action := range s.Expired {
+		savings += prices[action.From] * float64(action.Size) / gb
+	}
+
+	for _, action := range s.Transitioned {
+		savings += (prices[action.From] - prices[action.To]) * float64(action.Size) / gb
+	}
+
+	return savings
+}
+
+// SimulateLifecycle lists the objects under the prefix and reports the ones which would be expired or transitioned
+// by the rules today, without changing them, so the rules can be validated before being applied to a bucket.
+// Like the providers, all the rules matching a key apply: the expiration wins over the transitions, and the
+// transition of the most days wins over the others.
+func SimulateLifecycle(
+	ctx context.Context,
+	storage CloudStorage,
+	rules []LifecycleRule,
+	prefix string,
+) (*LifecycleSimulation, error) {
+	simulation := &LifecycleSimulation{TransitionedBytes: make(map[string]int64)}
+	now := time.Now()
+	iter := storage.List(ctx, prefix)
+
+	for {
+		object, err := iter.Next(ctx)
+		if err == io.EOF {
+			return simulation, nil
+		}
+
+		if err != nil {
+			return simulation, err
+		}
+
+		simulation.Objects++
+		simulation.Bytes += object.Size
+
+		action := LifecycleAction{Key: object.Key, Size: object.Size, From: object.StorageClass}
+		expired, storageClass := lifecycleOf(rules, object.Key, int(now.Sub(object.ModTime)/(24*time.Hour)))
+
+		switch {
+		case expired:
+			simulation.Expired = append(simulation.Expired, action)
+			simulation.ExpiredBytes += object.Size
+
+		case storageClass != "" && storageClass != object.StorageClass:
+			action.To = storageClass
+			simulation.Transitioned = append(simulation.Transitioned, action)
+			simulation.TransitionedBytes[storageClass] += object.Size
+		}
+	}
+}
+
+// lifecycleOf returns whether the rules expire an object of the age in days, or the storage class they move it to
+func lifecycleOf(rules []LifecycleRule, key string, age int) (expired bool, storageClass string) {
+	days := -1
+
+	for _, rule := range rules {
+		if !strings.HasPrefix(key, rule.Prefix) {
+			continue
+		}
+
+		if rule.ExpirationDays > 0 && age >= rule.ExpirationDays {
+			return true, ""
+		}
+
+		for _, transition := range rule.Transitions {
+			if age >= transition.Days && transition.Days > days {
+				days, storageClass = transition.Days, transition.StorageClass
+			}
+		}
+	}
+
+	return false, storageClass
+}
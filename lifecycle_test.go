@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulateLifecycle(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	write := func(key string, age time.Duration, size int) {
+		storage.SetClock(func() time.Time { return time.Now().Add(-age) })
+		require.NoError(t, storage.Write(ctx, key, make([]byte, size), nil))
+	}
+
+	write("logs/old.log", 400*24*time.Hour, 100)
+	write("logs/cold.log", 100*24*time.Hour, 200)
+	write("logs/warm.log", 40*24*time.Hour, 300)
+	write("logs/new.log", time.Hour, 400)
+	write("exports/old.csv", 400*24*time.Hour, 500)
+
+	rules := []LifecycleRule{
+		{
+			Prefix:         "logs/",
+			ExpirationDays: 365,
+			Transitions: []LifecycleTransition{
+				{Days: 30, StorageClass: "STANDARD_IA"},
+				{Days: 90, StorageClass: "GLACIER"},
+			},
+		},
+	}
+
+	simulation, err := SimulateLifecycle(ctx, storage, rules, "")
+	require.NoError(t, err)
+	require.Equal(t, int64(5), simulation.Objects)
+	require.Equal(t, int64(1500), simulation.Bytes)
+	require.Equal(t, []LifecycleAction{{Key: "logs/old.log", Size: 100, From: "STANDARD"}}, simulation.Expired)
+	require.Equal(t, int64(100), simulation.ExpiredBytes)
+	require.Equal(t, []LifecycleAction{
+		{Key: "logs/cold.log", Size: 200, From: "STANDARD", To: "GLACIER"},
+		{Key: "logs/warm.log", Size: 300, From: "STANDARD", To: "STANDARD_IA"},
+	}, simulation.Transitioned)
+	require.Equal(t, map[string]int64{"GLACIER": 200, "STANDARD_IA": 300}, simulation.TransitionedBytes)
+
+	savings := simulation.Savings(map[string]float64{"STANDARD": 1 << 30, "STANDARD_IA": 1 << 29})
+	require.Equal(t, float64(100+200+300/2), savings)
+}
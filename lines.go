@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bufio"
+	"context"
+	"io"
+)
+
+// DefaultMaxLineSize is the size of the longest line read by GetLines and LineIterator when
+// LineOption.MaxLineSize isn't set
+const DefaultMaxLineSize = 1024 * 1024
+
+// LineOption configures the reading of the lines of an object
+type LineOption struct {
+	// MaxLineSize is the size of the longest line, bufio.ErrTooLong is returned for a longer line.
+	// Defaults to DefaultMaxLineSize.
+	MaxLineSize int
+}
+
+// LineIterator reads the lines of an object as it's downloaded, without their line ending
+type LineIterator struct {
+	reader  io.ReadCloser
+	scanner *bufio.Scanner
+}
+
+// NewLineIterator opens the object to read its lines, the iterator must be closed
+func NewLineIterator(
+	ctx context.Context,
+	storage CloudStorage,
+	key string,
+	opts *LineOption,
+	readOpts ...ReadOption,
+) (*LineIterator, error) {
+	maxLineSize := DefaultMaxLineSize
+	if opts != nil && opts.MaxLineSize > 0 {
+		maxLineSize = opts.MaxLineSize
+	}
+
+	reader, err := storage.GetReader(ctx, key, readOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(reader)
+
+	initial := 64 * 1024
+	if initial > maxLineSize {
+		initial = maxLineSize
+	}
+
+	scanner.Buffer(make([]byte, 0, initial), maxLineSize)
+
+	return &LineIterator{reader: reader, scanner: scanner}, nil
+}
+
+// Next returns the next line, or io.EOF after the last one.
+// The line is only valid until the next call, it must be copied to be kept.
+func (i *LineIterator) Next() ([]byte, error) {
+	if i.scanner.Scan() {
+		return i.scanner.Bytes(), nil
+	}
+
+	if err := i.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
+
+// Close closes the reader of the object
+func (i *LineIterator) Close() error {
+	return i.reader.Close()
+}
+
+// GetLines calls fn with every line of the object as it's downloaded, e.g. to process NDJSON.
+// It stops at the first error of fn, which is returned. The line is only valid during the call.
+func GetLines(
+	ctx context.Context,
+	storage CloudStorage,
+	key string,
+	fn func(line []byte) error,
+	opts *LineOption,
+	readOpts ...ReadOption,
+) error {
+	iter, err := NewLineIterator(ctx, storage, key, opts, readOpts...)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	for {
+		line, err := iter.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetLines(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.Write(ctx, "events.ndjson", []byte("{\"id\":1}\n{\"id\":2}\r\n{\"id\":3}"), nil))
+
+	var lines []string
+
+	err := GetLines(ctx, storage, "events.ndjson", func(line []byte) error {
+		lines = append(lines, string(line))
+		return nil
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{`{"id":1}`, `{"id":2}`, `{"id":3}`}, lines)
+
+	// the error of fn stops the reading
+	stop := errors.New("stop")
+	calls := 0
+
+	err = GetLines(ctx, storage, "events.ndjson", func(line []byte) error {
+		calls++
+		return stop
+	}, nil)
+	require.Equal(t, stop, err)
+	require.Equal(t, 1, calls)
+
+	err = GetLines(ctx, storage, "events.ndjson", func(line []byte) error { return nil }, &LineOption{MaxLineSize: 4})
+	require.Equal(t, bufio.ErrTooLong, err)
+
+	_, err = NewLineIterator(ctx, storage, "missing", nil)
+	require.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestLineIterator(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.Write(ctx, "lines.txt", []byte("a\n\nb\n"), nil))
+
+	iter, err := NewLineIterator(ctx, storage, "lines.txt", nil)
+	require.NoError(t, err)
+	defer iter.Close()
+
+	for _, expected := range []string{"a", "", "b"} {
+		line, err := iter.Next()
+		require.NoError(t, err)
+		require.Equal(t, expected, string(line))
+	}
+
+	_, err = iter.Next()
+	require.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrTooManyObjects is matched by errors.Is when ListAll lists more objects than its limit
+var ErrTooManyObjects = errors.New("too many objects")
+
+// TooManyObjectsError gives the limit exceeded by ListAll
+type TooManyObjectsError struct {
+	Prefix string
+	Limit  int
+}
+
+func (e *TooManyObjectsError) Error() string {
+	return fmt.Sprintf("more than %d objects under '%s'", e.Limit, e.Prefix)
+}
+
+func (e *TooManyObjectsError) Is(target error) bool {
+	return target == ErrTooManyObjects
+}
+
+// ListAll returns the objects under the prefix, for the small prefixes not worth an iterator.
+// It fails with a *TooManyObjectsError as soon as more than limit objects are listed, so an unexpectedly large
+// prefix isn't loaded in memory. A limit that isn't positive lists everything.
+func ListAll(
+	ctx context.Context,
+	storage CloudStorage,
+	prefix string,
+	limit int,
+	opts ...ListOption,
+) ([]*ListObject, error) {
+	iter := storage.List(ctx, prefix, opts...)
+
+	var objects []*ListObject
+
+	for {
+		object, err := iter.Next(ctx)
+		if err == io.EOF {
+			return objects, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if limit > 0 && len(objects) == limit {
+			return nil, &TooManyObjectsError{Prefix: prefix, Limit: limit}
+		}
+
+		objects = append(objects, object)
+	}
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAll(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	for _, key := range []string{"a/1", "a/2", "a/3", "b/1"} {
+		require.NoError(t, storage.Write(ctx, key, []byte("body"), nil))
+	}
+
+	objects, err := ListAll(ctx, storage, "a/", 3)
+	require.NoError(t, err)
+	require.Len(t, objects, 3)
+	require.Equal(t, "a/1", objects[0].Key)
+
+	objects, err = ListAll(ctx, storage, "", 0)
+	require.NoError(t, err)
+	require.Len(t, objects, 4)
+
+	objects, err = ListAll(ctx, storage, "c/", 1)
+	require.NoError(t, err)
+	require.Empty(t, objects)
+
+	_, err = ListAll(ctx, storage, "a/", 2)
+	require.True(t, errors.Is(err, ErrTooManyObjects))
+
+	var tooMany *TooManyObjectsError
+	require.True(t, errors.As(err, &tooMany))
+	require.Equal(t, 2, tooMany.Limit)
+	require.Equal(t, "more than 2 objects under 'a/'", err.Error())
+}
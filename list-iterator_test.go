@@ -0,0 +1,50 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListIteratorHonorsCancellation(t *testing.T) {
+	calls := 0
+	next := func(ctx context.Context) (*ListObject, error) {
+		calls++
+		return &ListObject{Key: "key"}, nil
+	}
+
+	listCtx, cancelList := context.WithCancel(context.Background())
+	iter := NewListIterator(listCtx, next)
+
+	_, err := iter.Next(context.Background())
+	require.NoError(t, err)
+
+	cancelList()
+
+	_, err = iter.Next(context.Background())
+	require.Equal(t, context.Canceled, err)
+
+	nextCtx, cancelNext := context.WithCancel(context.Background())
+	cancelNext()
+
+	_, err = NewListIterator(context.Background(), next).Next(nextCtx)
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, 1, calls)
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io"
+	"sort"
+)
+
+// ListOrder is the order of the objects returned by List
+type ListOrder int
+
+const (
+	// ListOrderAscending is the lexicographic order of the keys, as bytes. It's the order of every provider,
+	// so the objects are streamed page by page.
+	ListOrderAscending ListOrder = iota
+	// ListOrderDescending is the reverse lexicographic order of the keys
+	ListOrderDescending
+	// ListOrderNewestFirst is the descending order of the modification times, then the ascending order of the keys
+	ListOrderNewestFirst
+)
+
+// orderedList returns the objects of iter in the order. The providers can't list in another order than
+// ListOrderAscending, so all the objects are listed on the first call to Next and sorted in memory.
+func orderedList(ctx context.Context, iter *ListIterator, order ListOrder) *ListIterator {
+	if order == ListOrderAscending {
+		return iter
+	}
+
+	var (
+		objects []*ListObject
+		listed  bool
+	)
+
+	return NewListIterator(ctx, func(ctx context.Context) (*ListObject, error) {
+		if !listed {
+			for {
+				object, err := iter.Next(ctx)
+				if err == io.EOF {
+					break
+				}
+
+				if err != nil {
+					return nil, err
+				}
+
+				objects = append(objects, object)
+			}
+
+			sortObjects(objects, order)
+
+			listed = true
+		}
+
+		if len(objects) == 0 {
+			return nil, io.EOF
+		}
+
+		object := objects[0]
+		objects = objects[1:]
+
+		return object, nil
+	})
+}
+
+func sortObjects(objects []*ListObject, order ListOrder) {
+	sort.SliceStable(objects, func(i, j int) bool {
+		switch order {
+		case ListOrderDescending:
+			return objects[i].Key > objects[j].Key
+		case ListOrderNewestFirst:
+			if !objects[i].ModTime.Equal(objects[j].ModTime) {
+				return objects[i].ModTime.After(objects[j].ModTime)
+			}
+		}
+
+		return objects[i].Key < objects[j].Key
+	})
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func listedKeys(t *testing.T, iter *ListIterator) []string {
+	var keys []string
+
+	for {
+		object, err := iter.Next(context.Background())
+		if err == io.EOF {
+			return keys
+		}
+
+		require.NoError(t, err)
+
+		keys = append(keys, object.Key)
+	}
+}
+
+func TestListOrder(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	fake := NewFakeCloudStorage("bucket")
+	fake.SetClock(func() time.Time { return now })
+
+	for _, key := range []string{"b", "a", "d", "c"} {
+		require.NoError(t, fake.Write(ctx, key, []byte(key), nil))
+
+		if key != "d" {
+			now = now.Add(time.Minute)
+		}
+	}
+
+	require.Equal(t, []string{"a", "b", "c", "d"}, listedKeys(t, fake.List(ctx, "")))
+	require.Equal(t, []string{"d", "c", "b", "a"}, listedKeys(t, fake.List(ctx, "", WithListOrder(ListOrderDescending))))
+	// c and d have the same modification time
+	require.Equal(t, []string{"c", "d", "a", "b"}, listedKeys(t, fake.List(ctx, "", WithListOrder(ListOrderNewestFirst))))
+
+	// the storage of NewCloudStorage sorts the objects of the providers
+	storage := newInterceptedCloudStorage(&ascendingStorage{CloudStorage: fake}, "fake", "bucket")
+	require.Equal(t, []string{"d", "c", "b", "a"}, listedKeys(t, storage.List(ctx, "", WithListOrder(ListOrderDescending))))
+}
+
+// ascendingStorage lists in the ascending order whatever the options, like the providers
+type ascendingStorage struct {
+	CloudStorage
+}
+
+func (ts *ascendingStorage) List(ctx context.Context, prefix string, opts ...ListOption) *ListIterator {
+	return ts.CloudStorage.List(ctx, prefix)
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"gocloud.dev/blob"
+)
+
+// listRetryPolicy returns the policy retrying the pages of List, the RetryPolicy option or the default policy
+func listRetryPolicy(cloudStorageOpts *CloudStorageOption) RetryPolicy {
+	if cloudStorageOpts.RetryPolicy != nil {
+		return cloudStorageOpts.RetryPolicy.withDefaults()
+	}
+
+	return RetryPolicy{}.withDefaults()
+}
+
+// listBucket lists the bucket after the key of WithResumeFrom, with StartAfter on S3 while the keys up to it are
+// skipped on GCS. The transient failures of a page are retried with the policy, the gocloud iterators fetching
+// the same page again.
+func listBucket(
+	ctx context.Context,
+	bucket *blob.Bucket,
+	prefix string,
+	opts []ListOption,
+	policy RetryPolicy,
+	convert func(attrs *blob.ListObject) *ListObject,
+) *ListIterator {
+	resumeFrom := listOptions(opts).resumeFrom
+
+	iter := bucket.List(&blob.ListOptions{
+		Prefix: prefix,
+		BeforeList: func(as func(interface{}) bool) error {
+			var input *s3.ListObjectsV2Input
+			if resumeFrom != "" && as(&input) {
+				// the keys are escaped by s3blob
+				input.StartAfter = aws.String(awsEscapeKey(resumeFrom))
+			}
+
+			return nil
+		},
+	})
+
+	return newResumableListIterator(ctx, resumeFrom, policy, func(ctx context.Context) (*ListObject, error) {
+		attrs, err := iter.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return convert(attrs), nil
+	})
+}
+
+// newResumableListIterator returns the objects of next after resumeFrom, retrying its failures with the policy
+func newResumableListIterator(
+	ctx context.Context,
+	resumeFrom string,
+	policy RetryPolicy,
+	next func(ctx context.Context) (*ListObject, error),
+) *ListIterator {
+	return NewListIterator(ctx, func(ctx context.Context) (*ListObject, error) {
+		for {
+			var object *ListObject
+
+			err := policy.do(ctx, func() error {
+				var err error
+
+				object, err = next(ctx)
+				if err == io.EOF {
+					return nil
+				}
+
+				return err
+			})
+
+			switch {
+			case err != nil:
+				return nil, err
+			case object == nil:
+				return nil, io.EOF
+			case resumeFrom == "" || object.Key > resumeFrom:
+				return object, nil
+			}
+		}
+	})
+}
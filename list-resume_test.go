@@ -0,0 +1,151 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/require"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/memblob"
+	"gocloud.dev/blob/s3blob"
+	"google.golang.org/api/googleapi"
+)
+
+func TestResumableListIteratorRetriesPages(t *testing.T) {
+	ctx := context.Background()
+	keys := []string{"a", "b", "c", "d"}
+	failures := map[int]int{2: 2}
+	calls := 0
+	next := 0
+
+	policy := RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond}.withDefaults()
+
+	iter := newResumableListIterator(ctx, "a", policy, func(ctx context.Context) (*ListObject, error) {
+		calls++
+		if failures[next] > 0 {
+			failures[next]--
+			return nil, &googleapi.Error{Code: 503}
+		}
+
+		if next == len(keys) {
+			return nil, io.EOF
+		}
+
+		next++
+
+		return &ListObject{Key: keys[next-1]}, nil
+	})
+
+	require.Equal(t, []string{"b", "c", "d"}, listedKeys(t, iter))
+	require.Equal(t, 7, calls)
+
+	// the errors which aren't transient or outlast the attempts are returned
+	iter = newResumableListIterator(ctx, "", policy, func(ctx context.Context) (*ListObject, error) {
+		return nil, &googleapi.Error{Code: 503}
+	})
+
+	_, err := iter.Next(ctx)
+	require.True(t, IsRetryableError(err))
+
+	calls = 0
+	iter = newResumableListIterator(ctx, "", policy, func(ctx context.Context) (*ListObject, error) {
+		calls++
+		return nil, &googleapi.Error{Code: 403}
+	})
+
+	_, err = iter.Next(ctx)
+	require.Error(t, err)
+	require.Equal(t, 1, calls)
+}
+
+func TestListResumeFrom(t *testing.T) {
+	ctx := context.Background()
+
+	bucket := memblob.OpenBucket(nil)
+	defer bucket.Close()
+
+	fake := NewFakeCloudStorage("bucket")
+
+	for _, key := range []string{"logs/1", "logs/2", "logs/3", "other"} {
+		require.NoError(t, bucket.WriteAll(ctx, key, []byte(key), nil))
+		require.NoError(t, fake.Write(ctx, key, []byte(key), nil))
+	}
+
+	convert := func(attrs *blob.ListObject) *ListObject {
+		return &ListObject{Key: attrs.Key}
+	}
+
+	policy := RetryPolicy{}.withDefaults()
+	require.Equal(t, []string{"logs/3"},
+		listedKeys(t, listBucket(ctx, bucket, "logs/", []ListOption{WithResumeFrom("logs/2")}, policy, convert)))
+	require.Equal(t, []string{"logs/1", "logs/2", "logs/3"},
+		listedKeys(t, listBucket(ctx, bucket, "logs/", nil, policy, convert)))
+
+	require.Equal(t, []string{"logs/3", "other"}, listedKeys(t, fake.List(ctx, "", WithResumeFrom("logs/2"))))
+	require.Equal(t, []string{"other", "logs/3"},
+		listedKeys(t, fake.List(ctx, "", WithResumeFrom("logs/2"), WithListOrder(ListOrderDescending))))
+
+	scoped, err := ScopedStorage(fake, "logs/")
+	require.NoError(t, err)
+	require.Equal(t, []string{"3"}, listedKeys(t, scoped.List(ctx, "", WithResumeFrom("2"))))
+}
+
+func TestAWSListResumeFrom(t *testing.T) {
+	var startAfter string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startAfter = r.URL.Query().Get("start-after")
+
+		w.Write([]byte(`<ListBucketResult><Name>bucket</Name><KeyCount>1</KeyCount>` +
+			`<Contents><Key>a/__0x2f__c</Key><LastModified>2020-01-01T00:00:00Z</LastModified><ETag>"etag"</ETag>` +
+			`<Size>1</Size></Contents></ListBucketResult>`))
+	}))
+	defer server.Close()
+
+	awsSession, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(server.URL),
+		Region:           aws.String("us-east-1"),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	bucket, err := s3blob.OpenBucket(ctx, awsSession, "bucket", nil)
+	require.NoError(t, err)
+
+	defer bucket.Close()
+
+	convert := func(attrs *blob.ListObject) *ListObject {
+		return &ListObject{Key: attrs.Key}
+	}
+
+	// the listing starts after the escaped key, and the listed keys are unescaped
+	iter := listBucket(ctx, bucket, "a/", []ListOption{WithResumeFrom("a//b")}, RetryPolicy{}.withDefaults(), convert)
+	require.Equal(t, []string{"a//c"}, listedKeys(t, iter))
+	require.Equal(t, "a/__0x2f__b", startAfter)
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io"
+)
+
+// listStreamBufferSize matches the page size of both providers, so a whole page can be prefetched
+const listStreamBufferSize = 1000
+
+// ListStream lists the objects under the prefix from a background goroutine, so listing the next pages
+// overlaps with the processing of the received items.
+// The items channel is closed once the listing is over; the error channel then receives
+// at most one error (nil is never sent) and is closed too.
+// Cancel ctx to stop the listing early.
+func ListStream(
+	ctx context.Context,
+	storage CloudStorage,
+	prefix string,
+) (<-chan *ListObject, <-chan error) {
+	items := make(chan *ListObject, listStreamBufferSize)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(items)
+
+		iter := storage.List(ctx, prefix)
+
+		for {
+			item, err := iter.Next(ctx)
+			if err == io.EOF {
+				return
+			}
+
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			select {
+			case items <- item:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return items, errs
+}
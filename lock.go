@@ -0,0 +1,265 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultLockPrefix is the prefix of the lock objects when LockerOption.Prefix is not set
+	DefaultLockPrefix = ".locks/"
+
+	// DefaultLockRetryInterval is the delay between two attempts of Lock when LockerOption.RetryInterval is not set
+	DefaultLockRetryInterval = time.Second
+)
+
+var (
+	// ErrLockHeld is matched by errors.Is when the lock is held by another owner
+	ErrLockHeld = errors.New("lock is held")
+	// ErrLockLost is matched by errors.Is when the lease expired and the lock was taken over, or released
+	ErrLockLost = errors.New("lock is lost")
+)
+
+// LockerOption configures a Locker
+type LockerOption struct {
+	// Prefix of the lock objects, followed by the name of the lock. Defaults to DefaultLockPrefix.
+	Prefix string
+	// Owner identifies the holder in the lock objects. Defaults to a random UUID.
+	Owner string
+	// RetryInterval is the delay between two attempts of Lock. Defaults to DefaultLockRetryInterval.
+	RetryInterval time.Duration
+	// Clock returns the current time used for the expiry of the leases, time.Now when it's nil
+	Clock func() time.Time
+}
+
+// lockRecord is the content of a lock object
+type lockRecord struct {
+	Owner  string    `json:"owner"`
+	Expiry time.Time `json:"expiry"`
+}
+
+// Locker acquires named locks stored as objects of the bucket, so the workers sharing a bucket can elect a leader
+// without a coordination service. The lock objects are created with WriteIf DoesNotExist, and renewed, taken over
+// and released on the condition of their generation, so a single owner holds a lock at a time.
+//
+// The expiry of a lease is compared with the clock of the other workers, so the ttl must be well above the clock
+// skew, and a holder must stop acting as the owner once its lease is expired.
+type Locker struct {
+	storage CloudStorage
+	opts    LockerOption
+}
+
+// NewLocker returns a Locker storing the locks in the storage
+func NewLocker(storage CloudStorage, opts LockerOption) *Locker {
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultLockPrefix
+	}
+
+	if opts.Owner == "" {
+		opts.Owner = uuid.New().String()
+	}
+
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = DefaultLockRetryInterval
+	}
+
+	opts.Clock = clockOrNow(opts.Clock)
+
+	return &Locker{storage: storage, opts: opts}
+}
+
+// Lock acquires the lock for the ttl, waiting while it's held by another owner, until the context is done
+func (l *Locker) Lock(ctx context.Context, name string, ttl time.Duration) (*Lease, error) {
+	for {
+		lease, err := l.TryLock(ctx, name, ttl)
+		if !errors.Is(err, ErrLockHeld) {
+			return lease, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(l.opts.RetryInterval):
+		}
+	}
+}
+
+// TryLock acquires the lock for the ttl, or returns ErrLockHeld when it's held by another owner.
+// An expired lock is taken over.
+func (l *Locker) TryLock(ctx context.Context, name string, ttl time.Duration) (*Lease, error) {
+	if ttl <= 0 {
+		return nil, errors.New("the ttl must be positive")
+	}
+
+	lease := &Lease{locker: l, key: l.opts.Prefix + name, ttl: ttl}
+
+	err := lease.write(ctx, WriteCondition{DoesNotExist: true})
+	if err == nil {
+		return lease, nil
+	}
+
+	if !errors.Is(err, ErrPreconditionFailed) {
+		return nil, err
+	}
+
+	record, generation, err := l.read(ctx, lease.key)
+	if errors.Is(err, ErrNotFound) {
+		// released meanwhile
+		return nil, fmt.Errorf("%w: '%s' is being released", ErrLockHeld, name)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if l.opts.Clock().Before(record.Expiry) {
+		return nil, fmt.Errorf("%w: '%s' is held by '%s' until %s",
+			ErrLockHeld, name, record.Owner, record.Expiry.Format(time.RFC3339))
+	}
+
+	err = lease.write(ctx, WriteCondition{Generation: generation})
+	if errors.Is(err, ErrPreconditionFailed) {
+		return nil, fmt.Errorf("%w: '%s' was taken over by another owner", ErrLockHeld, name)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return lease, nil
+}
+
+// read returns the record of the lock with its generation
+func (l *Locker) read(ctx context.Context, key string) (*lockRecord, string, error) {
+	reader, attrs, err := l.storage.GetWithAttributes(ctx, key)
+	if err != nil {
+		return nil, "", err
+	}
+	defer reader.Close()
+
+	var record lockRecord
+	if err := json.NewDecoder(reader).Decode(&record); err != nil {
+		return nil, "", fmt.Errorf("invalid lock object '%s': %w", key, err)
+	}
+
+	return &record, attrs.Generation, nil
+}
+
+// Lease is a held lock, valid until its expiry unless it's renewed
+type Lease struct {
+	locker *Locker
+	key    string
+	ttl    time.Duration
+
+	mu         sync.Mutex
+	generation string
+	expiry     time.Time
+}
+
+// write stores the record of the lease with its new expiry when the condition is met
+func (l *Lease) write(ctx context.Context, condition WriteCondition) error {
+	expiry := l.locker.opts.Clock().Add(l.ttl)
+
+	body, err := json.Marshal(lockRecord{Owner: l.locker.opts.Owner, Expiry: expiry})
+	if err != nil {
+		return err
+	}
+
+	contentType := "application/json"
+
+	generation, err := l.locker.storage.WriteIf(ctx, l.key, body, &contentType, condition)
+	if err != nil {
+		return err
+	}
+
+	l.generation = generation
+	l.expiry = expiry
+
+	return nil
+}
+
+// Expiry returns the time until which the lease is held, unless it's renewed
+func (l *Lease) Expiry() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.expiry
+}
+
+// Renew extends the lease by its ttl, or returns ErrLockLost when the lock was taken over or released
+func (l *Lease) Renew(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err := l.write(ctx, WriteCondition{Generation: l.generation})
+	if errors.Is(err, ErrPreconditionFailed) {
+		return fmt.Errorf("%w: '%s'", ErrLockLost, l.key)
+	}
+
+	return err
+}
+
+// KeepAlive renews the lease every third of its ttl until the context is done, or the renewal fails,
+// e.g. with ErrLockLost. The failed renewals are retried until the lease is expired.
+func (l *Lease) KeepAlive(ctx context.Context) error {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		err := l.Renew(ctx)
+		if err == nil || ctx.Err() != nil {
+			continue
+		}
+
+		if errors.Is(err, ErrLockLost) || !l.locker.opts.Clock().Before(l.Expiry()) {
+			return err
+		}
+	}
+}
+
+// Release deletes the lock, or returns ErrLockLost when it was taken over or released
+func (l *Lease) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err := l.locker.storage.DeleteIf(ctx, l.key, l.generation)
+	if errors.Is(err, ErrPreconditionFailed) || errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%w: '%s'", ErrLockLost, l.key)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	l.expiry = time.Time{}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocker(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+
+	var mu sync.Mutex
+
+	now := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return now
+	}
+
+	first := NewLocker(fake, LockerOption{Owner: "first", Clock: clock})
+	second := NewLocker(fake, LockerOption{Owner: "second", Clock: clock})
+
+	lease, err := first.TryLock(ctx, "leader", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(time.Minute), lease.Expiry())
+
+	_, err = second.TryLock(ctx, "leader", time.Minute)
+	require.True(t, errors.Is(err, ErrLockHeld))
+
+	mu.Lock()
+	now = now.Add(30 * time.Second)
+	mu.Unlock()
+
+	require.NoError(t, lease.Renew(ctx))
+	require.Equal(t, now.Add(time.Minute), lease.Expiry())
+
+	// the expired lease is taken over
+	mu.Lock()
+	now = now.Add(2 * time.Minute)
+	mu.Unlock()
+
+	takenOver, err := second.TryLock(ctx, "leader", time.Minute)
+	require.NoError(t, err)
+
+	require.True(t, errors.Is(lease.Renew(ctx), ErrLockLost))
+	require.True(t, errors.Is(lease.Release(ctx), ErrLockLost))
+
+	require.NoError(t, takenOver.Release(ctx))
+
+	_, err = fake.Attributes(ctx, DefaultLockPrefix+"leader")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	lease, err = first.TryLock(ctx, "leader", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, lease.Release(ctx))
+}
+
+func TestLockerLockWaits(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	first := NewLocker(fake, LockerOption{})
+	second := NewLocker(fake, LockerOption{RetryInterval: time.Millisecond})
+
+	lease, err := first.Lock(ctx, "leader", time.Minute)
+	require.NoError(t, err)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+
+	_, err = second.Lock(timeoutCtx, "leader", time.Minute)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		lease.Release(ctx) // nolint:errcheck
+	}()
+
+	lease, err = second.Lock(ctx, "leader", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, lease.Release(ctx))
+}
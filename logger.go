@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+// Fields are the structured context of a log entry
+type Fields map[string]interface{}
+
+// Logger receives the internal logs of the package, so they can be forwarded to any logging library.
+// Implementations must be safe for concurrent use.
+type Logger interface {
+	Debug(msg string, fields Fields)
+	Info(msg string, fields Fields)
+	Warn(msg string, fields Fields)
+	Error(msg string, fields Fields)
+}
+
+// noopLogger discards the logs, it's used when no Logger is set
+type noopLogger struct{}
+
+func (noopLogger) Debug(string, Fields) {}
+func (noopLogger) Info(string, Fields)  {}
+func (noopLogger) Warn(string, Fields)  {}
+func (noopLogger) Error(string, Fields) {}
+
+func loggerOrNoop(logger Logger) Logger {
+	if logger == nil {
+		return noopLogger{}
+	}
+
+	return logger
+}
@@ -0,0 +1,251 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"crypto/md5" // nolint:gosec
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidManifest is matched by errors.Is when the manifest given to ReconcileManifest can't be parsed
+var ErrInvalidManifest = errors.New("invalid manifest")
+
+// ObjectManifest is the expected content of a prefix, e.g. written by an export job next to its files
+type ObjectManifest struct {
+	Objects []ManifestObject `json:"objects"`
+}
+
+// ManifestObject is an object expected by an ObjectManifest
+type ManifestObject struct {
+	// Key is relative to the prefix given to ReconcileManifest
+	Key  string `json:"key"`
+	Size int64  `json:"size"`
+	// MD5 is the hex MD5 of the content, compared with the one of the provider when it's available
+	MD5 string `json:"md5,omitempty"`
+	// SHA256 is the hex SHA-256 of the content, the object is downloaded to compare it
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// ManifestOption configures ReconcileManifest
+type ManifestOption struct {
+	// Concurrency is the number of objects compared in parallel, DefaultGetManyConcurrency when it isn't positive
+	Concurrency int
+}
+
+// ManifestReport is the result of ReconcileManifest, the keys are sorted
+type ManifestReport struct {
+	// Matched is the number of objects matching their manifest entry
+	Matched int
+	// Missing are the keys of the manifest which aren't in the bucket
+	Missing []string
+	// Extra are the keys under the prefix which aren't in the manifest, except the manifest itself
+	Extra []string
+	// Mismatches are the objects whose size or checksums differ from the manifest, Checksum being "size",
+	// "MD5" or "SHA-256"
+	Mismatches []*IntegrityError
+	// Errors are the errors of the objects which couldn't be read, by key
+	Errors map[string]error
+}
+
+// OK returns whether the bucket matches the manifest
+func (r *ManifestReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0 && len(r.Mismatches) == 0 && len(r.Errors) == 0
+}
+
+// ReconcileManifest compares the objects under the prefix with the JSON ObjectManifest stored at manifestKey, for the
+// periodic validation of the export jobs. The sizes and the MD5 are compared from the listing, the objects are only
+// downloaded when the manifest has their SHA-256, or their MD5 isn't given by the provider.
+// The error is only returned when the manifest can't be read or the objects can't be listed,
+// the failures of the objects are in the report.
+func ReconcileManifest(
+	ctx context.Context,
+	storage CloudStorage,
+	manifestKey string,
+	prefix string,
+	opts *ManifestOption,
+) (*ManifestReport, error) {
+	options := ManifestOption{}
+	if opts != nil {
+		options = *opts
+	}
+
+	expected, err := readObjectManifest(ctx, storage, manifestKey, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mu     sync.Mutex
+		seen   = make(map[string]bool, len(expected))
+		report = &ManifestReport{Errors: make(map[string]error)}
+	)
+
+	err = ForEachObject(ctx, storage, prefix, func(ctx context.Context, object *ListObject) error {
+		if object.Key == manifestKey {
+			return nil
+		}
+
+		entry, ok := expected[object.Key]
+
+		mu.Lock()
+		if !ok {
+			report.Extra = append(report.Extra, object.Key)
+		}
+		seen[object.Key] = true
+		mu.Unlock()
+
+		if !ok {
+			return nil
+		}
+
+		if err := reconcileObject(ctx, storage, object, entry); err != nil {
+			return err
+		}
+
+		mu.Lock()
+		report.Matched++
+		mu.Unlock()
+
+		return nil
+	}, &ForEachOption{Concurrency: options.Concurrency, CollectErrors: true})
+
+	var forEachErr *ForEachError
+
+	switch {
+	case errors.As(err, &forEachErr):
+		for key, err := range forEachErr.Errors {
+			var integrityErr *IntegrityError
+			if errors.As(err, &integrityErr) {
+				report.Mismatches = append(report.Mismatches, integrityErr)
+			} else {
+				report.Errors[key] = err
+			}
+		}
+	case err != nil:
+		return nil, err
+	}
+
+	for key := range expected {
+		if !seen[key] {
+			report.Missing = append(report.Missing, key)
+		}
+	}
+
+	sort.Strings(report.Missing)
+	sort.Strings(report.Extra)
+	sort.Slice(report.Mismatches, func(i, j int) bool {
+		return report.Mismatches[i].Key < report.Mismatches[j].Key
+	})
+
+	return report, nil
+}
+
+// readObjectManifest returns the entries of the manifest by key
+func readObjectManifest(
+	ctx context.Context,
+	storage CloudStorage,
+	manifestKey string,
+	prefix string,
+) (map[string]ManifestObject, error) {
+	body, err := storage.Get(ctx, manifestKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest ObjectManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+	}
+
+	expected := make(map[string]ManifestObject, len(manifest.Objects))
+
+	for _, entry := range manifest.Objects {
+		key := prefix + entry.Key
+		if _, ok := expected[key]; ok {
+			return nil, fmt.Errorf("%w: duplicate key '%s'", ErrInvalidManifest, entry.Key)
+		}
+
+		entry.MD5 = strings.ToLower(entry.MD5)
+		entry.SHA256 = strings.ToLower(entry.SHA256)
+		expected[key] = entry
+	}
+
+	return expected, nil
+}
+
+// reconcileObject returns an *IntegrityError if the object differs from its manifest entry
+func reconcileObject(ctx context.Context, storage CloudStorage, object *ListObject, entry ManifestObject) error {
+	if object.Size != entry.Size {
+		return &IntegrityError{
+			Key:      object.Key,
+			Checksum: "size",
+			Expected: strconv.FormatInt(entry.Size, 10),
+			Actual:   strconv.FormatInt(object.Size, 10),
+		}
+	}
+
+	md5Listed := entry.MD5 != "" && len(object.MD5) > 0
+	if md5Listed && hex.EncodeToString(object.MD5) != entry.MD5 {
+		return &IntegrityError{Key: object.Key, Checksum: "MD5", Expected: entry.MD5, Actual: hex.EncodeToString(object.MD5)}
+	}
+
+	if entry.SHA256 == "" && (entry.MD5 == "" || md5Listed) {
+		return nil
+	}
+
+	reader, err := storage.GetReader(ctx, object.Key)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	var (
+		md5Hash    = md5.New() // nolint:gosec
+		sha256Hash = sha256.New()
+	)
+
+	if _, err := io.Copy(io.MultiWriter(md5Hash, sha256Hash), reader); err != nil {
+		return err
+	}
+
+	for _, checksum := range []struct {
+		name     string
+		expected string
+		hash     hash.Hash
+	}{
+		{name: "MD5", expected: entry.MD5, hash: md5Hash},
+		{name: "SHA-256", expected: entry.SHA256, hash: sha256Hash},
+	} {
+		actual := hex.EncodeToString(checksum.hash.Sum(nil))
+		if checksum.expected != "" && checksum.expected != actual {
+			return &IntegrityError{Key: object.Key, Checksum: checksum.name, Expected: checksum.expected, Actual: actual}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"crypto/md5" // nolint:gosec
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcileManifest(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	md5Of := func(body string) string {
+		sum := md5.Sum([]byte(body)) // nolint:gosec
+		return hex.EncodeToString(sum[:])
+	}
+
+	sha256Of := func(body string) string {
+		sum := sha256.Sum256([]byte(body))
+		return hex.EncodeToString(sum[:])
+	}
+
+	for key, body := range map[string]string{
+		"export/a.csv":     "a",
+		"export/b.csv":     "bb",
+		"export/c.csv":     "ccc",
+		"export/d.csv":     "dddd",
+		"export/e.csv":     "eeeee",
+		"export/extra.csv": "extra",
+	} {
+		require.NoError(t, storage.Write(ctx, key, []byte(body), nil))
+	}
+
+	manifest, err := json.Marshal(ObjectManifest{Objects: []ManifestObject{
+		{Key: "a.csv", Size: 1, MD5: md5Of("a")},
+		{Key: "b.csv", Size: 2, SHA256: sha256Of("bb")},
+		{Key: "c.csv", Size: 4},
+		{Key: "d.csv", Size: 4, MD5: md5Of("other")},
+		{Key: "e.csv", Size: 5, SHA256: sha256Of("other")},
+		{Key: "missing.csv", Size: 1},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, storage.Write(ctx, "export/MANIFEST.json", manifest, nil))
+
+	report, err := ReconcileManifest(ctx, storage, "export/MANIFEST.json", "export/", nil)
+	require.NoError(t, err)
+	require.False(t, report.OK())
+	require.Equal(t, 2, report.Matched)
+	require.Equal(t, []string{"export/missing.csv"}, report.Missing)
+	require.Equal(t, []string{"export/extra.csv"}, report.Extra)
+	require.Empty(t, report.Errors)
+	require.Equal(t, []*IntegrityError{
+		{Key: "export/c.csv", Checksum: "size", Expected: "4", Actual: "3"},
+		{Key: "export/d.csv", Checksum: "MD5", Expected: md5Of("other"), Actual: md5Of("dddd")},
+		{Key: "export/e.csv", Checksum: "SHA-256", Expected: sha256Of("other"), Actual: sha256Of("eeeee")},
+	}, report.Mismatches)
+
+	require.NoError(t, storage.Write(ctx, "manifests/export.json", []byte(`{"objects": [
+		{"key": "a.csv", "size": 1}, {"key": "b.csv", "size": 2, "md5": "`+md5Of("bb")+`"}
+	]}`), nil))
+
+	report, err = ReconcileManifest(ctx, storage, "manifests/export.json", "export/", &ManifestOption{Concurrency: 1})
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Matched)
+	require.Len(t, report.Extra, 5)
+
+	require.NoError(t, storage.Write(ctx, "manifests/invalid.json", []byte(`{"objects": [
+		{"key": "a.csv", "size": 1}, {"key": "a.csv", "size": 1}
+	]}`), nil))
+
+	_, err = ReconcileManifest(ctx, storage, "manifests/invalid.json", "export/", nil)
+	require.True(t, errors.Is(err, ErrInvalidManifest))
+
+	require.NoError(t, storage.Write(ctx, "manifests/invalid.json", []byte("not json"), nil))
+
+	_, err = ReconcileManifest(ctx, storage, "manifests/invalid.json", "export/", nil)
+	require.True(t, errors.Is(err, ErrInvalidManifest))
+
+	_, err = ReconcileManifest(ctx, storage, "manifests/missing.json", "export/", nil)
+	require.True(t, errors.Is(err, ErrNotFound))
+}
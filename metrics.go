@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"time"
+)
+
+// Metrics receives an observation for every operation of the client, e.g. to feed Prometheus collectors.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// ObserveOperation is called once the operation completes.
+	// bytes is the number of bytes read or written when it's known, err is nil on success.
+	ObserveOperation(operation string, duration time.Duration, bytes int64, err error)
+}
+
+func newMetricsInterceptor(metrics Metrics) Interceptor {
+	return func(ctx context.Context, op *OperationInfo, next func(ctx context.Context) error) error {
+		start := time.Now()
+		err := next(ctx)
+
+		metrics.ObserveOperation(op.Name, time.Since(start), op.Bytes, err)
+
+		return err
+	}
+}
@@ -0,0 +1,363 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" // nolint:gosec
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultMigrationCheckpointInterval is the number of migrated objects between two checkpoint saves
+const defaultMigrationCheckpointInterval = 100
+
+// ErrChecksumMismatch is reported when a migrated object doesn't match its source
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// MigrationOption configures Migrate
+type MigrationOption struct {
+	SourcePrefix      string
+	DestinationPrefix string
+	// Shards splits the objects between several workers by the hash of their key, each running Migrate
+	// with its own Shard between 0 and Shards-1. Defaults to a single shard.
+	Shards int
+	Shard  int
+	// Concurrency is the number of objects copied in parallel. Defaults to DefaultSyncConcurrency.
+	Concurrency int
+	// CheckpointPrefix is the destination prefix storing the checkpoint of every shard, so an interrupted migration
+	// resumes where it stopped. Nothing is stored when it's empty.
+	CheckpointPrefix string
+	// CheckpointInterval is the number of migrated objects between two checkpoint saves. Defaults to 100.
+	CheckpointInterval int
+	// Logger receives the progress of the migration. It's discarded when it's nil.
+	Logger Logger
+}
+
+// MigrationReport summarizes the migration of a shard
+type MigrationReport struct {
+	// Migrated is the number of objects copied and verified
+	Migrated int
+	// Skipped is the number of objects already migrated according to the checkpoint
+	Skipped int
+	// Bytes is the size of the migrated objects
+	Bytes int64
+	// Failed holds the error of every object which couldn't be migrated, by key without the prefix.
+	// They are retried by the next run.
+	Failed map[string]error
+}
+
+// migrationCheckpoint is the state of the migrated source objects, indexed by key without the prefix
+type migrationCheckpoint struct {
+	Objects map[string]*ListObject `json:"objects"`
+}
+
+// Migrate copies the objects of a shard from src to dst, typically between providers. Every copy is verified:
+// the MD5 of the streamed content must match the source listing and the destination attributes when they have one,
+// and the sizes must match. The objects failing are reported without stopping the migration.
+// An error is returned when the listing or the checkpoint fail.
+// Once all the shards are done, Reconcile compares the whole prefixes.
+// nolint:funlen
+func Migrate(ctx context.Context, src CloudStorage, dst CloudStorage, opts MigrationOption) (*MigrationReport, error) {
+	if opts.Shards <= 0 {
+		opts.Shards = 1
+	}
+
+	if opts.Shard < 0 || opts.Shard >= opts.Shards {
+		return nil, fmt.Errorf("shard %d out of %d shards", opts.Shard, opts.Shards)
+	}
+
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultSyncConcurrency
+	}
+
+	if opts.CheckpointInterval <= 0 {
+		opts.CheckpointInterval = defaultMigrationCheckpointInterval
+	}
+
+	logger := loggerOrNoop(opts.Logger)
+	report := &MigrationReport{Failed: make(map[string]error)}
+
+	checkpointKey := ""
+	if opts.CheckpointPrefix != "" {
+		checkpointKey = fmt.Sprintf("%sshard-%d-of-%d.json", opts.CheckpointPrefix, opts.Shard, opts.Shards)
+	}
+
+	checkpoint, err := loadMigrationCheckpoint(ctx, dst, checkpointKey)
+	if err != nil {
+		return report, err
+	}
+
+	srcObjects, err := listByName(ctx, src, opts.SourcePrefix)
+	if err != nil {
+		return report, err
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		unsaved  int
+		saveErr  error
+		progress = func() {
+			unsaved++
+			if unsaved < opts.CheckpointInterval {
+				return
+			}
+
+			unsaved = 0
+
+			if err := saveMigrationCheckpoint(ctx, dst, checkpointKey, checkpoint); err != nil {
+				saveErr = err
+			}
+
+			logger.Info("migration progress", Fields{
+				"shard":    opts.Shard,
+				"migrated": report.Migrated,
+				"failed":   len(report.Failed),
+			})
+		}
+	)
+
+	// the checkpoint is updated by the copies, the pending objects are selected beforehand
+	var pending []string
+
+	for _, name := range sortedNames(srcObjects) {
+		if migrationShard(name, opts.Shards) != opts.Shard {
+			continue
+		}
+
+		if !objectChanged(srcObjects[name], checkpoint.Objects[name]) {
+			report.Skipped++
+			continue
+		}
+
+		pending = append(pending, name)
+	}
+
+	semaphore := make(chan struct{}, opts.Concurrency)
+
+	for _, name := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+
+		name, object := name, srcObjects[name]
+
+		semaphore <- struct{}{}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			err := migrateObject(ctx, src, object, dst, opts.DestinationPrefix+name)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				report.Failed[name] = err
+				logger.Warn("unable to migrate object", Fields{"key": object.Key, "error": err})
+
+				return
+			}
+
+			checkpoint.Objects[name] = object
+			report.Migrated++
+			report.Bytes += object.Size
+
+			progress()
+		}()
+	}
+
+	wg.Wait()
+
+	if err := saveMigrationCheckpoint(ctx, dst, checkpointKey, checkpoint); err != nil {
+		return report, err
+	}
+
+	if saveErr != nil {
+		return report, saveErr
+	}
+
+	return report, ctx.Err()
+}
+
+// migrationShard returns the shard of the key
+func migrationShard(name string, shards int) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(name))
+
+	return int(hash.Sum32() % uint32(shards))
+}
+
+func sortedNames(objects map[string]*ListObject) []string {
+	names := make([]string, 0, len(objects))
+	for name := range objects {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// migrateObject copies the object and verifies the copy
+func migrateObject(ctx context.Context, src CloudStorage, object *ListObject, dst CloudStorage, dstKey string) error {
+	reader, attrs, err := src.GetWithAttributes(ctx, object.Key)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	hash := md5.New() // nolint:gosec
+	counter := &countingReader{Reader: io.TeeReader(reader, hash)}
+
+	err = dst.Upload(ctx, dstKey, counter, &UploadOption{ContentType: attrs.ContentType})
+	if err != nil {
+		return err
+	}
+
+	sum := hash.Sum(nil)
+
+	if counter.count != object.Size || (len(object.MD5) > 0 && !bytes.Equal(sum, object.MD5)) {
+		return fmt.Errorf("%w: read %d bytes from source of %d bytes", ErrChecksumMismatch, counter.count, object.Size)
+	}
+
+	dstAttrs, err := dst.Attributes(ctx, dstKey)
+	if err != nil {
+		return err
+	}
+
+	if dstAttrs.Size != object.Size || (len(dstAttrs.MD5) > 0 && !bytes.Equal(sum, dstAttrs.MD5)) {
+		return fmt.Errorf("%w: destination has %d bytes, source has %d bytes", ErrChecksumMismatch, dstAttrs.Size, object.Size)
+	}
+
+	return nil
+}
+
+func loadMigrationCheckpoint(ctx context.Context, dst CloudStorage, key string) (*migrationCheckpoint, error) {
+	checkpoint := &migrationCheckpoint{}
+
+	if key != "" {
+		body, err := dst.Get(ctx, key)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+
+		if err == nil {
+			if err := json.Unmarshal(body, checkpoint); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if checkpoint.Objects == nil {
+		checkpoint.Objects = make(map[string]*ListObject)
+	}
+
+	return checkpoint, nil
+}
+
+func saveMigrationCheckpoint(ctx context.Context, dst CloudStorage, key string, checkpoint *migrationCheckpoint) error {
+	if key == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	contentType := "application/json"
+
+	return dst.Write(ctx, key, body, &contentType)
+}
+
+// ReconciliationReport compares the source and destination prefixes after a migration.
+// The keys are relative to the prefixes.
+type ReconciliationReport struct {
+	// Matched is the number of objects with the same size and MD5 in both prefixes
+	Matched int
+	// Missing are the source objects missing from the destination
+	Missing []string
+	// Extra are the destination objects missing from the source
+	Extra []string
+	// Mismatched are the objects whose size or MD5 differ
+	Mismatched []string
+}
+
+// Reconcile lists both prefixes and reports the differences. The MD5 are only compared when both providers return
+// them, the modification times are ignored since the copies are always newer.
+// The keys under ignorePrefix in the destination, e.g. the checkpoints, are skipped.
+func Reconcile(
+	ctx context.Context,
+	src CloudStorage,
+	srcPrefix string,
+	dst CloudStorage,
+	dstPrefix string,
+	ignorePrefix string,
+) (*ReconciliationReport, error) {
+	srcObjects, err := listByName(ctx, src, srcPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	dstObjects, err := listByName(ctx, dst, dstPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReconciliationReport{}
+
+	for _, name := range sortedNames(srcObjects) {
+		object := srcObjects[name]
+		copied, ok := dstObjects[name]
+
+		switch {
+		case !ok:
+			report.Missing = append(report.Missing, name)
+		case object.Size != copied.Size ||
+			(len(object.MD5) > 0 && len(copied.MD5) > 0 && !bytes.Equal(object.MD5, copied.MD5)):
+			report.Mismatched = append(report.Mismatched, name)
+		default:
+			report.Matched++
+		}
+	}
+
+	for _, name := range sortedNames(dstObjects) {
+		if _, ok := srcObjects[name]; ok {
+			continue
+		}
+
+		if ignorePrefix != "" && strings.HasPrefix(dstObjects[name].Key, ignorePrefix) {
+			continue
+		}
+
+		report.Extra = append(report.Extra, name)
+	}
+
+	return report, nil
+}
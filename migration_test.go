@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	src := NewFakeCloudStorage("src")
+	dst := NewFakeCloudStorage("dst")
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, src.Write(ctx, fmt.Sprintf("data/%02d.txt", i), []byte(fmt.Sprintf("object %d", i)), nil))
+	}
+
+	opts := MigrationOption{
+		SourcePrefix:       "data/",
+		DestinationPrefix:  "migrated/",
+		Shards:             2,
+		CheckpointPrefix:   "migration/",
+		CheckpointInterval: 3,
+	}
+
+	var migrated int
+
+	for shard := 0; shard < opts.Shards; shard++ {
+		opts.Shard = shard
+
+		report, err := Migrate(ctx, src, dst, opts)
+		require.NoError(t, err)
+		require.Empty(t, report.Failed)
+		require.Zero(t, report.Skipped)
+
+		migrated += report.Migrated
+	}
+
+	require.Equal(t, 20, migrated)
+
+	// the checkpoints make the next runs skip the migrated objects
+	require.NoError(t, src.Write(ctx, "data/00.txt", []byte("updated"), nil))
+
+	var skipped int
+
+	for shard := 0; shard < opts.Shards; shard++ {
+		opts.Shard = shard
+
+		report, err := Migrate(ctx, src, dst, opts)
+		require.NoError(t, err)
+
+		migrated += report.Migrated
+		skipped += report.Skipped
+	}
+
+	require.Equal(t, 21, migrated)
+	require.Equal(t, 19, skipped)
+
+	report, err := Reconcile(ctx, src, "data/", dst, "migrated/", "migration/")
+	require.NoError(t, err)
+	require.Equal(t, &ReconciliationReport{Matched: 20}, report)
+}
+
+func TestMigrateFailures(t *testing.T) {
+	ctx := context.Background()
+	src := NewFakeCloudStorage("src")
+	dst := NewFakeCloudStorage("dst")
+
+	require.NoError(t, src.Write(ctx, "a.txt", []byte("a"), nil))
+	require.NoError(t, src.Write(ctx, "b.txt", []byte("b"), nil))
+
+	failing := NewFaultInjectingCloudStorage(dst, FaultInjectionOption{
+		Operations: map[string]Fault{"Upload": {ErrorRate: 1}},
+	})
+
+	report, err := Migrate(ctx, src, failing, MigrationOption{CheckpointPrefix: "checkpoints/"})
+	require.NoError(t, err)
+	require.Zero(t, report.Migrated)
+	require.Len(t, report.Failed, 2)
+
+	reconciliation, err := Reconcile(ctx, src, "", dst, "", "checkpoints/")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.txt", "b.txt"}, reconciliation.Missing)
+
+	report, err = Migrate(ctx, src, dst, MigrationOption{CheckpointPrefix: "checkpoints/"})
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Migrated)
+	require.Empty(t, report.Failed)
+
+	require.NoError(t, dst.Write(ctx, "a.txt", []byte("corrupted"), nil))
+	require.NoError(t, dst.Write(ctx, "c.txt", []byte("c"), nil))
+
+	reconciliation, err = Reconcile(ctx, src, "", dst, "", "checkpoints/")
+	require.NoError(t, err)
+	require.Equal(t, &ReconciliationReport{Matched: 1, Mismatched: []string{"a.txt"}, Extra: []string{"c.txt"}}, reconciliation)
+}
+
+func TestMigrateInvalidShard(t *testing.T) {
+	_, err := Migrate(context.Background(), NewFakeCloudStorage("src"), NewFakeCloudStorage("dst"), MigrationOption{
+		Shards: 2,
+		Shard:  2,
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,574 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMirrorJournalPrefix is the prefix of the journal of the failed mirror writes in the primary
+const DefaultMirrorJournalPrefix = ".mirror-journal/"
+
+// MirrorOption configures a MirrorStorage
+type MirrorOption struct {
+	// Async mirrors the mutations in background, the calls returning once the primary is written.
+	// Otherwise the calls return the error of the mirror after the primary is written.
+	Async bool
+	// QueueSize is the maximum number of pending mirror writes with Async, the calls block while the queue
+	// is full. Defaults to 1000.
+	QueueSize int
+	// Workers is the number of background workers of Async. Defaults to 4.
+	Workers int
+	// RetryPolicy is applied to the mirror writes with Async. The default policy is used when it's nil.
+	RetryPolicy *RetryPolicy
+	// JournalPrefix is the prefix of the primary storing the failed mirror writes, replayed by Replay.
+	// Defaults to DefaultMirrorJournalPrefix, it's hidden from List.
+	JournalPrefix string
+	// OnFailure is called with every failed mirror write, e.g. to raise an alert
+	OnFailure func(failure MirrorFailure)
+	// Logger receives the failed mirror writes and journal entries. They are discarded when it's nil.
+	Logger Logger
+}
+
+// MirrorFailure is an entry of the journal of a MirrorStorage
+type MirrorFailure struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Key       string    `json:"key"`
+	Error     string    `json:"error"`
+}
+
+// mirrorWrite is a mutation of the mirror
+type mirrorWrite struct {
+	operation string
+	key       string
+	apply     func(ctx context.Context) error
+}
+
+// MirrorStorage writes every object to a primary and a mirror, and reads from the primary, e.g. during the
+// migration to another provider. The failed mirror writes are recorded in a journal in the primary,
+// replayed by Replay from the current state of the primary.
+//
+// The upload sessions, the configuration of the bucket, and the folders aren't mirrored, run Sync to copy the
+// objects of the sessions and of the renamed folders.
+type MirrorStorage struct {
+	CloudStorage
+
+	mirror CloudStorage
+	opts   MirrorOption
+	policy RetryPolicy
+	queue  chan mirrorWrite
+
+	mu        sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+	pending   sync.WaitGroup
+	workers   sync.WaitGroup
+}
+
+var _ CloudStorage = (*MirrorStorage)(nil)
+
+// NewMirrorStorage returns a MirrorStorage writing to primary and mirror, it starts the workers of Async
+func NewMirrorStorage(primary CloudStorage, mirror CloudStorage, opts MirrorOption) *MirrorStorage {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultAsyncQueueSize
+	}
+
+	if opts.Workers <= 0 {
+		opts.Workers = defaultAsyncWorkers
+	}
+
+	if opts.JournalPrefix == "" {
+		opts.JournalPrefix = DefaultMirrorJournalPrefix
+	}
+
+	opts.Logger = loggerOrNoop(opts.Logger)
+
+	var policy RetryPolicy
+	if opts.RetryPolicy != nil {
+		policy = *opts.RetryPolicy
+	}
+
+	ts := &MirrorStorage{
+		CloudStorage: primary,
+		mirror:       mirror,
+		opts:         opts,
+		policy:       policy.withDefaults(),
+	}
+
+	if opts.Async {
+		ts.queue = make(chan mirrorWrite, opts.QueueSize)
+		ts.workers.Add(opts.Workers)
+
+		for i := 0; i < opts.Workers; i++ {
+			go ts.work()
+		}
+	}
+
+	return ts
+}
+
+func (ts *MirrorStorage) work() {
+	defer ts.workers.Done()
+
+	for write := range ts.queue {
+		write := write
+
+		// the context of the caller is usually gone by the time the write is mirrored
+		err := ts.policy.do(context.Background(), func() error {
+			return write.apply(context.Background())
+		})
+		if err != nil {
+			ts.journal(context.Background(), write, err)
+		}
+
+		ts.pending.Done()
+	}
+}
+
+// mirrorTo applies the mutation to the mirror, or enqueues it with Async
+func (ts *MirrorStorage) mirrorTo(ctx context.Context, write mirrorWrite) error {
+	if !ts.opts.Async {
+		err := write.apply(ctx)
+		if err != nil {
+			ts.journal(ctx, write, err)
+		}
+
+		return err
+	}
+
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	if ts.closed {
+		ts.journal(ctx, write, ErrAsyncWriterClosed)
+		return nil
+	}
+
+	ts.pending.Add(1)
+
+	select {
+	case ts.queue <- write:
+		return nil
+	case <-ctx.Done():
+		ts.pending.Done()
+		ts.journal(ctx, write, ctx.Err())
+
+		return nil
+	}
+}
+
+// journal records the failed mirror write in the primary
+func (ts *MirrorStorage) journal(ctx context.Context, write mirrorWrite, err error) {
+	failure := MirrorFailure{
+		Time:      time.Now().UTC(),
+		Operation: write.operation,
+		Key:       write.key,
+		Error:     err.Error(),
+	}
+
+	if ts.opts.OnFailure != nil {
+		ts.opts.OnFailure(failure)
+	}
+
+	ts.opts.Logger.Warn("unable to mirror the write", Fields{
+		"operation": write.operation,
+		"key":       write.key,
+		"error":     err,
+	})
+
+	body, err := json.Marshal(failure)
+	if err == nil {
+		contentType := "application/json"
+
+		// the entry is written even when the context of the caller is done
+		err = ts.CloudStorage.Write(context.Background(), auditKey(ts.opts.JournalPrefix, failure.Time), body, &contentType)
+	}
+
+	if err != nil {
+		ts.opts.Logger.Error("unable to journal the failed mirror write", Fields{"key": write.key, "error": err})
+	}
+}
+
+// Journal returns the failed mirror writes which haven't been replayed, oldest first
+func (ts *MirrorStorage) Journal(ctx context.Context) ([]MirrorFailure, error) {
+	entries, err := ts.journalEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	failures := make([]MirrorFailure, 0, len(entries))
+	for _, entry := range entries {
+		failures = append(failures, entry.failure)
+	}
+
+	return failures, nil
+}
+
+type mirrorJournalEntry struct {
+	key     string
+	failure MirrorFailure
+}
+
+func (ts *MirrorStorage) journalEntries(ctx context.Context) ([]mirrorJournalEntry, error) {
+	objects, err := listByName(ctx, ts.CloudStorage, ts.opts.JournalPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	names := sortedNames(objects)
+	entries := make([]mirrorJournalEntry, 0, len(names))
+
+	for _, name := range names {
+		body, err := ts.CloudStorage.Get(ctx, objects[name].Key)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := mirrorJournalEntry{key: objects[name].Key}
+		if err := json.Unmarshal(body, &entry.failure); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Replay mirrors the objects of the journal from their current state in the primary, copying them or deleting
+// them from the mirror when they're gone, and removes the replayed entries. It returns the number of replayed
+// entries, the replay stops at the first failure.
+func (ts *MirrorStorage) Replay(ctx context.Context) (int, error) {
+	entries, err := ts.journalEntries(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// the entries of a key are replayed once
+	replayed := map[string]bool{}
+
+	for i, entry := range entries {
+		if !replayed[entry.failure.Key] {
+			if err := ts.replayKey(ctx, entry.failure.Key); err != nil {
+				return i, err
+			}
+
+			replayed[entry.failure.Key] = true
+		}
+
+		if err := ts.CloudStorage.Delete(ctx, entry.key); err != nil && !errors.Is(err, ErrNotFound) {
+			return i, err
+		}
+	}
+
+	return len(entries), nil
+}
+
+func (ts *MirrorStorage) replayKey(ctx context.Context, key string) error {
+	err := copyObject(ctx, ts.CloudStorage, key, ts.mirror, key)
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	if err := ts.mirror.Delete(ctx, key); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// Flush waits until the mirror writes queued so far are applied or ctx is done
+func (ts *MirrorStorage) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+
+	go func() {
+		ts.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return flushStorage(ctx, ts.CloudStorage)
+}
+
+// Close drains the queue of Async and closes both storages
+func (ts *MirrorStorage) Close() {
+	ts.closeOnce.Do(func() {
+		if ts.queue != nil {
+			ts.mu.Lock()
+			ts.closed = true
+			close(ts.queue)
+			ts.mu.Unlock()
+
+			ts.workers.Wait()
+		}
+
+		ts.CloudStorage.Close()
+		ts.mirror.Close()
+	})
+}
+
+func (ts *MirrorStorage) List(
+	ctx context.Context,
+	prefix string,
+	opts ...ListOption,
+) *ListIterator {
+	iter := ts.CloudStorage.List(ctx, prefix, opts...)
+
+	return NewListIterator(ctx, func(ctx context.Context) (*ListObject, error) {
+		for {
+			object, err := iter.Next(ctx)
+			if err != nil || !strings.HasPrefix(object.Key, ts.opts.JournalPrefix) {
+				return object, err
+			}
+		}
+	})
+}
+
+// CreateBucket creates the bucket in both storages
+func (ts *MirrorStorage) CreateBucket(
+	ctx context.Context,
+	bucketPrefix string,
+	expirationTimeDays int64,
+) error {
+	if err := ts.CloudStorage.CreateBucket(ctx, bucketPrefix, expirationTimeDays); err != nil {
+		return err
+	}
+
+	return ts.mirror.CreateBucket(ctx, bucketPrefix, expirationTimeDays)
+}
+
+// CreateBucketWithOptions creates the bucket in both storages
+func (ts *MirrorStorage) CreateBucketWithOptions(
+	ctx context.Context,
+	opts *CreateBucketOption,
+) error {
+	if err := ts.CloudStorage.CreateBucketWithOptions(ctx, opts); err != nil {
+		return err
+	}
+
+	return ts.mirror.CreateBucketWithOptions(ctx, opts)
+}
+
+func (ts *MirrorStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	if err := ts.CloudStorage.Write(ctx, key, body, contentType, opts...); err != nil {
+		return err
+	}
+
+	if ts.opts.Async {
+		// the caller may reuse the body
+		body = append([]byte(nil), body...)
+	}
+
+	return ts.mirrorTo(ctx, mirrorWrite{operation: "Write", key: key, apply: func(ctx context.Context) error {
+		return ts.mirror.Write(ctx, key, body, contentType, opts...)
+	}})
+}
+
+// copyToMirror is the mirror write of the streamed objects, copied from the primary
+func (ts *MirrorStorage) copyToMirror(operation string, key string) mirrorWrite {
+	return mirrorWrite{operation: operation, key: key, apply: func(ctx context.Context) error {
+		return copyObject(ctx, ts.CloudStorage, key, ts.mirror, key)
+	}}
+}
+
+func (ts *MirrorStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	writer, err := ts.CloudStorage.GetWriter(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mirrorWriter{WriteCloser: writer, ctx: ctx, key: key, storage: ts}, nil
+}
+
+// mirrorWriter mirrors the object once it's written to the primary
+type mirrorWriter struct {
+	io.WriteCloser
+	ctx     context.Context
+	key     string
+	storage *MirrorStorage
+}
+
+func (w *mirrorWriter) Close() error {
+	if err := w.WriteCloser.Close(); err != nil {
+		return err
+	}
+
+	return w.storage.mirrorTo(w.ctx, w.storage.copyToMirror("GetWriter", w.key))
+}
+
+// Upload uploads to the primary, the object is then copied to the mirror
+func (ts *MirrorStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	if err := ts.CloudStorage.Upload(ctx, key, reader, opts, writeOpts...); err != nil {
+		return err
+	}
+
+	return ts.mirrorTo(ctx, ts.copyToMirror("Upload", key))
+}
+
+// deleteFromMirror is the mirror write of the deletions, the objects missing from the mirror are ignored
+func (ts *MirrorStorage) deleteFromMirror(operation string, key string) mirrorWrite {
+	return mirrorWrite{operation: operation, key: key, apply: func(ctx context.Context) error {
+		if err := ts.mirror.Delete(ctx, key); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+
+		return nil
+	}}
+}
+
+func (ts *MirrorStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	if err := ts.CloudStorage.Delete(ctx, key); err != nil {
+		return err
+	}
+
+	return ts.mirrorTo(ctx, ts.deleteFromMirror("Delete", key))
+}
+
+// WriteIf writes to the primary with the condition, the object is written to the mirror without condition
+// since the generations differ between the storages
+func (ts *MirrorStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	generation, err := ts.CloudStorage.WriteIf(ctx, key, body, contentType, condition)
+	if err != nil {
+		return "", err
+	}
+
+	if ts.opts.Async {
+		body = append([]byte(nil), body...)
+	}
+
+	return generation, ts.mirrorTo(ctx, mirrorWrite{operation: "WriteIf", key: key, apply: func(ctx context.Context) error {
+		return ts.mirror.Write(ctx, key, body, contentType)
+	}})
+}
+
+// DeleteIf deletes from the primary with the condition, the object is deleted from the mirror without condition
+func (ts *MirrorStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	if err := ts.CloudStorage.DeleteIf(ctx, key, generation); err != nil {
+		return err
+	}
+
+	return ts.mirrorTo(ctx, ts.deleteFromMirror("DeleteIf", key))
+}
+
+func (ts *MirrorStorage) SetObjectRetention(
+	ctx context.Context,
+	key string,
+	retention *ObjectRetention,
+) error {
+	if err := ts.CloudStorage.SetObjectRetention(ctx, key, retention); err != nil {
+		return err
+	}
+
+	return ts.mirrorTo(ctx, mirrorWrite{operation: "SetObjectRetention", key: key, apply: func(ctx context.Context) error {
+		return ts.mirror.SetObjectRetention(ctx, key, retention)
+	}})
+}
+
+func (ts *MirrorStorage) SetLegalHold(
+	ctx context.Context,
+	key string,
+	enabled bool,
+) error {
+	if err := ts.CloudStorage.SetLegalHold(ctx, key, enabled); err != nil {
+		return err
+	}
+
+	return ts.mirrorTo(ctx, mirrorWrite{operation: "SetLegalHold", key: key, apply: func(ctx context.Context) error {
+		return ts.mirror.SetLegalHold(ctx, key, enabled)
+	}})
+}
+
+func (ts *MirrorStorage) SetStorageClass(
+	ctx context.Context,
+	key string,
+	storageClass string,
+) error {
+	if err := ts.CloudStorage.SetStorageClass(ctx, key, storageClass); err != nil {
+		return err
+	}
+
+	return ts.mirrorTo(ctx, mirrorWrite{operation: "SetStorageClass", key: key, apply: func(ctx context.Context) error {
+		return ts.mirror.SetStorageClass(ctx, key, storageClass)
+	}})
+}
+
+func (ts *MirrorStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	if err := ts.CloudStorage.Copy(ctx, srcKey, dstKey, opts); err != nil {
+		return err
+	}
+
+	return ts.mirrorTo(ctx, ts.copyToMirror("Copy", dstKey))
+}
+
+func (ts *MirrorStorage) Undelete(
+	ctx context.Context,
+	key string,
+) error {
+	if err := ts.CloudStorage.Undelete(ctx, key); err != nil {
+		return err
+	}
+
+	return ts.mirrorTo(ctx, mirrorWrite{operation: "Undelete", key: key, apply: func(ctx context.Context) error {
+		return ts.mirror.Undelete(ctx, key)
+	}})
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirrorStorage(t *testing.T) {
+	ctx := context.Background()
+	primary := NewFakeCloudStorage("primary")
+	mirror := &outageStorage{CloudStorage: NewFakeCloudStorage("mirror")}
+
+	var failures []MirrorFailure
+
+	storage := NewMirrorStorage(primary, mirror, MirrorOption{
+		OnFailure: func(failure MirrorFailure) { failures = append(failures, failure) },
+	})
+	defer storage.Close()
+
+	require.NoError(t, storage.Write(ctx, "a.txt", []byte("a"), nil))
+	require.NoError(t, storage.Upload(ctx, "b.txt", strings.NewReader("b"), nil))
+
+	body, err := mirror.Get(ctx, "b.txt")
+	require.NoError(t, err)
+	require.Equal(t, "b", string(body))
+
+	// the failed mirror writes are returned and journaled, the primary being written
+	mirror.setDown(true)
+
+	err = storage.Write(ctx, "c.txt", []byte("c"), nil)
+	require.True(t, errors.Is(err, ErrUnavailable))
+	// outageStorage only fails the reads and writes
+	require.NoError(t, storage.Delete(ctx, "a.txt"))
+	require.Len(t, failures, 1)
+	require.Equal(t, "Write", failures[0].Operation)
+
+	body, err = storage.Get(ctx, "c.txt")
+	require.NoError(t, err)
+	require.Equal(t, "c", string(body))
+
+	journal, err := storage.Journal(ctx)
+	require.NoError(t, err)
+	require.Len(t, journal, 1)
+	require.Equal(t, "c.txt", journal[0].Key)
+
+	// the journal is hidden from List
+	objects, err := listByName(ctx, storage, "")
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+
+	mirror.setDown(false)
+
+	replayed, err := storage.Replay(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, replayed)
+
+	body, err = mirror.Get(ctx, "c.txt")
+	require.NoError(t, err)
+	require.Equal(t, "c", string(body))
+
+	journal, err = storage.Journal(ctx)
+	require.NoError(t, err)
+	require.Empty(t, journal)
+}
+
+func TestMirrorStorageAsync(t *testing.T) {
+	ctx := context.Background()
+	primary := NewFakeCloudStorage("primary")
+	mirror := &outageStorage{CloudStorage: NewFakeCloudStorage("mirror")}
+	storage := NewMirrorStorage(primary, mirror, MirrorOption{
+		Async:       true,
+		RetryPolicy: &RetryPolicy{MaxAttempts: 1},
+	})
+
+	require.NoError(t, storage.Write(ctx, "a.txt", []byte("a"), nil))
+	require.NoError(t, storage.Flush(ctx))
+
+	body, err := mirror.Get(ctx, "a.txt")
+	require.NoError(t, err)
+	require.Equal(t, "a", string(body))
+
+	// the asynchronous failures are only journaled
+	mirror.setDown(true)
+
+	require.NoError(t, storage.Write(ctx, "b.txt", []byte("b"), nil))
+	require.NoError(t, storage.Flush(ctx))
+
+	journal, err := storage.Journal(ctx)
+	require.NoError(t, err)
+	require.Len(t, journal, 1)
+	require.Equal(t, "b.txt", journal[0].Key)
+
+	mirror.setDown(false)
+
+	replayed, err := storage.Replay(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, replayed)
+
+	storage.Close()
+
+	body, err = mirror.Get(ctx, "b.txt")
+	require.NoError(t, err)
+	require.Equal(t, "b", string(body))
+}
@@ -0,0 +1,1151 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	commonblobgo "github.com/AccelByte/common-blob-go"
+
+	io "io"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// CloudStorage is an autogenerated mock type for the CloudStorage type
+type CloudStorage struct {
+	mock.Mock
+}
+
+// AbortStaleUploads provides a mock function with given fields: ctx, olderThan
+func (_m *CloudStorage) AbortStaleUploads(ctx context.Context, olderThan time.Duration) ([]*commonblobgo.IncompleteUpload, error) {
+	ret := _m.Called(ctx, olderThan)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AbortStaleUploads")
+	}
+
+	var r0 []*commonblobgo.IncompleteUpload
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Duration) ([]*commonblobgo.IncompleteUpload, error)); ok {
+		return rf(ctx, olderThan)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Duration) []*commonblobgo.IncompleteUpload); ok {
+		r0 = rf(ctx, olderThan)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*commonblobgo.IncompleteUpload)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Duration) error); ok {
+		r1 = rf(ctx, olderThan)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// As provides a mock function with given fields: target
+func (_m *CloudStorage) As(target interface{}) bool {
+	ret := _m.Called(target)
+
+	if len(ret) == 0 {
+		panic("no return value specified for As")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(interface{}) bool); ok {
+		r0 = rf(target)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// Attributes provides a mock function with given fields: ctx, key, opts
+func (_m *CloudStorage) Attributes(ctx context.Context, key string, opts ...commonblobgo.ReadOption) (*commonblobgo.Attributes, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Attributes")
+	}
+
+	var r0 *commonblobgo.Attributes
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...commonblobgo.ReadOption) (*commonblobgo.Attributes, error)); ok {
+		return rf(ctx, key, opts...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...commonblobgo.ReadOption) *commonblobgo.Attributes); ok {
+		r0 = rf(ctx, key, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*commonblobgo.Attributes)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...commonblobgo.ReadOption) error); ok {
+		r1 = rf(ctx, key, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BeginUpload provides a mock function with given fields: ctx, key, opts
+func (_m *CloudStorage) BeginUpload(ctx context.Context, key string, opts *commonblobgo.UploadOption) (*commonblobgo.UploadSession, error) {
+	ret := _m.Called(ctx, key, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BeginUpload")
+	}
+
+	var r0 *commonblobgo.UploadSession
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *commonblobgo.UploadOption) (*commonblobgo.UploadSession, error)); ok {
+		return rf(ctx, key, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *commonblobgo.UploadOption) *commonblobgo.UploadSession); ok {
+		r0 = rf(ctx, key, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*commonblobgo.UploadSession)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *commonblobgo.UploadOption) error); ok {
+		r1 = rf(ctx, key, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Close provides a mock function with no fields
+func (_m *CloudStorage) Close() {
+	_m.Called()
+}
+
+// Copy provides a mock function with given fields: ctx, srcKey, dstKey, opts
+func (_m *CloudStorage) Copy(ctx context.Context, srcKey string, dstKey string, opts *commonblobgo.CopyOption) error {
+	ret := _m.Called(ctx, srcKey, dstKey, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Copy")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *commonblobgo.CopyOption) error); ok {
+		r0 = rf(ctx, srcKey, dstKey, opts)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateBucket provides a mock function with given fields: ctx, bucketPrefix, expirationTimeDays
+func (_m *CloudStorage) CreateBucket(ctx context.Context, bucketPrefix string, expirationTimeDays int64) error {
+	ret := _m.Called(ctx, bucketPrefix, expirationTimeDays)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateBucket")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) error); ok {
+		r0 = rf(ctx, bucketPrefix, expirationTimeDays)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateBucketWithOptions provides a mock function with given fields: ctx, opts
+func (_m *CloudStorage) CreateBucketWithOptions(ctx context.Context, opts *commonblobgo.CreateBucketOption) error {
+	ret := _m.Called(ctx, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateBucketWithOptions")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *commonblobgo.CreateBucketOption) error); ok {
+		r0 = rf(ctx, opts)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateFolder provides a mock function with given fields: ctx, folder
+func (_m *CloudStorage) CreateFolder(ctx context.Context, folder string) error {
+	ret := _m.Called(ctx, folder)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateFolder")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, folder)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Delete provides a mock function with given fields: ctx, key
+func (_m *CloudStorage) Delete(ctx context.Context, key string) error {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteFolder provides a mock function with given fields: ctx, folder
+func (_m *CloudStorage) DeleteFolder(ctx context.Context, folder string) error {
+	ret := _m.Called(ctx, folder)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteFolder")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, folder)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteIf provides a mock function with given fields: ctx, key, generation
+func (_m *CloudStorage) DeleteIf(ctx context.Context, key string, generation string) error {
+	ret := _m.Called(ctx, key, generation)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteIf")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, key, generation)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ErrorAs provides a mock function with given fields: err, target
+func (_m *CloudStorage) ErrorAs(err error, target interface{}) bool {
+	ret := _m.Called(err, target)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ErrorAs")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(error, interface{}) bool); ok {
+		r0 = rf(err, target)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// Get provides a mock function with given fields: ctx, key, opts
+func (_m *CloudStorage) Get(ctx context.Context, key string, opts ...commonblobgo.ReadOption) ([]byte, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Get")
+	}
+
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...commonblobgo.ReadOption) ([]byte, error)); ok {
+		return rf(ctx, key, opts...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...commonblobgo.ReadOption) []byte); ok {
+		r0 = rf(ctx, key, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...commonblobgo.ReadOption) error); ok {
+		r1 = rf(ctx, key, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBucketEncryption provides a mock function with given fields: ctx
+func (_m *CloudStorage) GetBucketEncryption(ctx context.Context) (*commonblobgo.BucketEncryption, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBucketEncryption")
+	}
+
+	var r0 *commonblobgo.BucketEncryption
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*commonblobgo.BucketEncryption, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *commonblobgo.BucketEncryption); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*commonblobgo.BucketEncryption)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBucketLogging provides a mock function with given fields: ctx
+func (_m *CloudStorage) GetBucketLogging(ctx context.Context) (*commonblobgo.BucketLogging, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBucketLogging")
+	}
+
+	var r0 *commonblobgo.BucketLogging
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*commonblobgo.BucketLogging, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *commonblobgo.BucketLogging); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*commonblobgo.BucketLogging)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBucketPolicy provides a mock function with given fields: ctx
+func (_m *CloudStorage) GetBucketPolicy(ctx context.Context) (*commonblobgo.BucketPolicy, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBucketPolicy")
+	}
+
+	var r0 *commonblobgo.BucketPolicy
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*commonblobgo.BucketPolicy, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *commonblobgo.BucketPolicy); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*commonblobgo.BucketPolicy)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBucketReplication provides a mock function with given fields: ctx
+func (_m *CloudStorage) GetBucketReplication(ctx context.Context) (*commonblobgo.BucketReplication, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBucketReplication")
+	}
+
+	var r0 *commonblobgo.BucketReplication
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*commonblobgo.BucketReplication, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *commonblobgo.BucketReplication); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*commonblobgo.BucketReplication)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLegalHold provides a mock function with given fields: ctx, key
+func (_m *CloudStorage) GetLegalHold(ctx context.Context, key string) (bool, error) {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLegalHold")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(ctx, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetObjectRetention provides a mock function with given fields: ctx, key
+func (_m *CloudStorage) GetObjectRetention(ctx context.Context, key string) (*commonblobgo.ObjectRetention, error) {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetObjectRetention")
+	}
+
+	var r0 *commonblobgo.ObjectRetention
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*commonblobgo.ObjectRetention, error)); ok {
+		return rf(ctx, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *commonblobgo.ObjectRetention); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*commonblobgo.ObjectRetention)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPublicURL provides a mock function with given fields: key
+func (_m *CloudStorage) GetPublicURL(key string) string {
+	ret := _m.Called(key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPublicURL")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(key)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// GetRangeReader provides a mock function with given fields: ctx, key, offset, length, opts
+func (_m *CloudStorage) GetRangeReader(ctx context.Context, key string, offset int64, length int64, opts ...commonblobgo.ReadOption) (io.ReadCloser, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key, offset, length)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRangeReader")
+	}
+
+	var r0 io.ReadCloser
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64, int64, ...commonblobgo.ReadOption) (io.ReadCloser, error)); ok {
+		return rf(ctx, key, offset, length, opts...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64, int64, ...commonblobgo.ReadOption) io.ReadCloser); ok {
+		r0 = rf(ctx, key, offset, length, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(io.ReadCloser)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64, int64, ...commonblobgo.ReadOption) error); ok {
+		r1 = rf(ctx, key, offset, length, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetReader provides a mock function with given fields: ctx, key, opts
+func (_m *CloudStorage) GetReader(ctx context.Context, key string, opts ...commonblobgo.ReadOption) (io.ReadCloser, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetReader")
+	}
+
+	var r0 io.ReadCloser
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...commonblobgo.ReadOption) (io.ReadCloser, error)); ok {
+		return rf(ctx, key, opts...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...commonblobgo.ReadOption) io.ReadCloser); ok {
+		r0 = rf(ctx, key, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(io.ReadCloser)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...commonblobgo.ReadOption) error); ok {
+		r1 = rf(ctx, key, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetScopedCredentials provides a mock function with given fields: ctx, opts
+func (_m *CloudStorage) GetScopedCredentials(ctx context.Context, opts *commonblobgo.ScopedCredentialsOption) (*commonblobgo.ScopedCredentials, error) {
+	ret := _m.Called(ctx, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetScopedCredentials")
+	}
+
+	var r0 *commonblobgo.ScopedCredentials
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *commonblobgo.ScopedCredentialsOption) (*commonblobgo.ScopedCredentials, error)); ok {
+		return rf(ctx, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *commonblobgo.ScopedCredentialsOption) *commonblobgo.ScopedCredentials); ok {
+		r0 = rf(ctx, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*commonblobgo.ScopedCredentials)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *commonblobgo.ScopedCredentialsOption) error); ok {
+		r1 = rf(ctx, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSignedURL provides a mock function with given fields: ctx, key, opts
+func (_m *CloudStorage) GetSignedURL(ctx context.Context, key string, opts *commonblobgo.SignedURLOption) (string, error) {
+	ret := _m.Called(ctx, key, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSignedURL")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *commonblobgo.SignedURLOption) (string, error)); ok {
+		return rf(ctx, key, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *commonblobgo.SignedURLOption) string); ok {
+		r0 = rf(ctx, key, opts)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *commonblobgo.SignedURLOption) error); ok {
+		r1 = rf(ctx, key, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWithAttributes provides a mock function with given fields: ctx, key, opts
+func (_m *CloudStorage) GetWithAttributes(ctx context.Context, key string, opts ...commonblobgo.ReadOption) (io.ReadCloser, *commonblobgo.Attributes, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWithAttributes")
+	}
+
+	var r0 io.ReadCloser
+	var r1 *commonblobgo.Attributes
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...commonblobgo.ReadOption) (io.ReadCloser, *commonblobgo.Attributes, error)); ok {
+		return rf(ctx, key, opts...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...commonblobgo.ReadOption) io.ReadCloser); ok {
+		r0 = rf(ctx, key, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(io.ReadCloser)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...commonblobgo.ReadOption) *commonblobgo.Attributes); ok {
+		r1 = rf(ctx, key, opts...)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*commonblobgo.Attributes)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, ...commonblobgo.ReadOption) error); ok {
+		r2 = rf(ctx, key, opts...)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetWriter provides a mock function with given fields: ctx, key, opts
+func (_m *CloudStorage) GetWriter(ctx context.Context, key string, opts ...commonblobgo.WriteOption) (io.WriteCloser, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWriter")
+	}
+
+	var r0 io.WriteCloser
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...commonblobgo.WriteOption) (io.WriteCloser, error)); ok {
+		return rf(ctx, key, opts...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...commonblobgo.WriteOption) io.WriteCloser); ok {
+		r0 = rf(ctx, key, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(io.WriteCloser)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...commonblobgo.WriteOption) error); ok {
+		r1 = rf(ctx, key, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields: ctx, prefix, opts
+func (_m *CloudStorage) List(ctx context.Context, prefix string, opts ...commonblobgo.ListOption) *commonblobgo.ListIterator {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, prefix)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for List")
+	}
+
+	var r0 *commonblobgo.ListIterator
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...commonblobgo.ListOption) *commonblobgo.ListIterator); ok {
+		r0 = rf(ctx, prefix, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*commonblobgo.ListIterator)
+		}
+	}
+
+	return r0
+}
+
+// ListIncompleteUploads provides a mock function with given fields: ctx, prefix
+func (_m *CloudStorage) ListIncompleteUploads(ctx context.Context, prefix string) ([]*commonblobgo.IncompleteUpload, error) {
+	ret := _m.Called(ctx, prefix)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListIncompleteUploads")
+	}
+
+	var r0 []*commonblobgo.IncompleteUpload
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*commonblobgo.IncompleteUpload, error)); ok {
+		return rf(ctx, prefix)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*commonblobgo.IncompleteUpload); ok {
+		r0 = rf(ctx, prefix)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*commonblobgo.IncompleteUpload)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, prefix)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Ping provides a mock function with given fields: ctx
+func (_m *CloudStorage) Ping(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Ping")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Query provides a mock function with given fields: ctx, key, sql, format
+func (_m *CloudStorage) Query(ctx context.Context, key string, sql string, format commonblobgo.QueryInputFormat) (io.ReadCloser, error) {
+	ret := _m.Called(ctx, key, sql, format)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Query")
+	}
+
+	var r0 io.ReadCloser
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, commonblobgo.QueryInputFormat) (io.ReadCloser, error)); ok {
+		return rf(ctx, key, sql, format)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, commonblobgo.QueryInputFormat) io.ReadCloser); ok {
+		r0 = rf(ctx, key, sql, format)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(io.ReadCloser)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, commonblobgo.QueryInputFormat) error); ok {
+		r1 = rf(ctx, key, sql, format)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RenameFolder provides a mock function with given fields: ctx, folder, newFolder
+func (_m *CloudStorage) RenameFolder(ctx context.Context, folder string, newFolder string) error {
+	ret := _m.Called(ctx, folder, newFolder)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RenameFolder")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, folder, newFolder)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ResumeUpload provides a mock function with given fields: ctx, state
+func (_m *CloudStorage) ResumeUpload(ctx context.Context, state *commonblobgo.UploadSessionState) (*commonblobgo.UploadSession, error) {
+	ret := _m.Called(ctx, state)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResumeUpload")
+	}
+
+	var r0 *commonblobgo.UploadSession
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *commonblobgo.UploadSessionState) (*commonblobgo.UploadSession, error)); ok {
+		return rf(ctx, state)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *commonblobgo.UploadSessionState) *commonblobgo.UploadSession); ok {
+		r0 = rf(ctx, state)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*commonblobgo.UploadSession)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *commonblobgo.UploadSessionState) error); ok {
+		r1 = rf(ctx, state)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetBucketEncryption provides a mock function with given fields: ctx, encryption
+func (_m *CloudStorage) SetBucketEncryption(ctx context.Context, encryption *commonblobgo.BucketEncryption) error {
+	ret := _m.Called(ctx, encryption)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetBucketEncryption")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *commonblobgo.BucketEncryption) error); ok {
+		r0 = rf(ctx, encryption)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetBucketLogging provides a mock function with given fields: ctx, logging
+func (_m *CloudStorage) SetBucketLogging(ctx context.Context, logging *commonblobgo.BucketLogging) error {
+	ret := _m.Called(ctx, logging)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetBucketLogging")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *commonblobgo.BucketLogging) error); ok {
+		r0 = rf(ctx, logging)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetBucketPolicy provides a mock function with given fields: ctx, policy
+func (_m *CloudStorage) SetBucketPolicy(ctx context.Context, policy *commonblobgo.BucketPolicy) error {
+	ret := _m.Called(ctx, policy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetBucketPolicy")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *commonblobgo.BucketPolicy) error); ok {
+		r0 = rf(ctx, policy)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetBucketReplication provides a mock function with given fields: ctx, replication
+func (_m *CloudStorage) SetBucketReplication(ctx context.Context, replication *commonblobgo.BucketReplication) error {
+	ret := _m.Called(ctx, replication)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetBucketReplication")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *commonblobgo.BucketReplication) error); ok {
+		r0 = rf(ctx, replication)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetLegalHold provides a mock function with given fields: ctx, key, enabled
+func (_m *CloudStorage) SetLegalHold(ctx context.Context, key string, enabled bool) error {
+	ret := _m.Called(ctx, key, enabled)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetLegalHold")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) error); ok {
+		r0 = rf(ctx, key, enabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetObjectRetention provides a mock function with given fields: ctx, key, retention
+func (_m *CloudStorage) SetObjectRetention(ctx context.Context, key string, retention *commonblobgo.ObjectRetention) error {
+	ret := _m.Called(ctx, key, retention)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetObjectRetention")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *commonblobgo.ObjectRetention) error); ok {
+		r0 = rf(ctx, key, retention)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetStorageClass provides a mock function with given fields: ctx, key, storageClass
+func (_m *CloudStorage) SetStorageClass(ctx context.Context, key string, storageClass string) error {
+	ret := _m.Called(ctx, key, storageClass)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetStorageClass")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, key, storageClass)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Subscribe provides a mock function with given fields: ctx, prefix
+func (_m *CloudStorage) Subscribe(ctx context.Context, prefix string) (<-chan commonblobgo.ObjectEvent, error) {
+	ret := _m.Called(ctx, prefix)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Subscribe")
+	}
+
+	var r0 <-chan commonblobgo.ObjectEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (<-chan commonblobgo.ObjectEvent, error)); ok {
+		return rf(ctx, prefix)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) <-chan commonblobgo.ObjectEvent); ok {
+		r0 = rf(ctx, prefix)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan commonblobgo.ObjectEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, prefix)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Undelete provides a mock function with given fields: ctx, key
+func (_m *CloudStorage) Undelete(ctx context.Context, key string) error {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Undelete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Upload provides a mock function with given fields: ctx, key, reader, opts, writeOpts
+func (_m *CloudStorage) Upload(ctx context.Context, key string, reader io.Reader, opts *commonblobgo.UploadOption, writeOpts ...commonblobgo.WriteOption) error {
+	_va := make([]interface{}, len(writeOpts))
+	for _i := range writeOpts {
+		_va[_i] = writeOpts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key, reader, opts)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Upload")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, io.Reader, *commonblobgo.UploadOption, ...commonblobgo.WriteOption) error); ok {
+		r0 = rf(ctx, key, reader, opts, writeOpts...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Write provides a mock function with given fields: ctx, key, body, contentType, opts
+func (_m *CloudStorage) Write(ctx context.Context, key string, body []byte, contentType *string, opts ...commonblobgo.WriteOption) error {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key, body, contentType)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Write")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte, *string, ...commonblobgo.WriteOption) error); ok {
+		r0 = rf(ctx, key, body, contentType, opts...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// WriteIf provides a mock function with given fields: ctx, key, body, contentType, condition
+func (_m *CloudStorage) WriteIf(ctx context.Context, key string, body []byte, contentType *string, condition commonblobgo.WriteCondition) (string, error) {
+	ret := _m.Called(ctx, key, body, contentType, condition)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WriteIf")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte, *string, commonblobgo.WriteCondition) (string, error)); ok {
+		return rf(ctx, key, body, contentType, condition)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte, *string, commonblobgo.WriteCondition) string); ok {
+		r0 = rf(ctx, key, body, contentType, condition)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, []byte, *string, commonblobgo.WriteCondition) error); ok {
+		r1 = rf(ctx, key, body, contentType, condition)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewCloudStorage creates a new instance of CloudStorage. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewCloudStorage(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *CloudStorage {
+	mock := &CloudStorage{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,37 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package mocks
+
+import (
+	"context"
+	"testing"
+
+	commonblobgo "github.com/AccelByte/common-blob-go"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudStorage(t *testing.T) {
+	storage := NewCloudStorage(t)
+	storage.On("Get", mock.Anything, "key").Return([]byte("body"), nil)
+
+	var cloudStorage commonblobgo.CloudStorage = storage
+
+	body, err := cloudStorage.Get(context.Background(), "key")
+	require.NoError(t, err)
+	require.Equal(t, []byte("body"), body)
+}
@@ -0,0 +1,264 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+// DefaultMaxFormValuesSize is the default size of the form values read by UploadMultipart, as http.Request.ParseMultipartForm
+const DefaultMaxFormValuesSize = 10 << 20
+
+// ErrFormTooLarge is matched by errors.Is when a form exceeds a limit of MultipartUploadOption
+var ErrFormTooLarge = errors.New("form too large")
+
+// FormTooLargeError gives the limit of MultipartUploadOption exceeded by a form
+type FormTooLargeError struct {
+	// Limit is the name of the exceeded option, e.g. MaxFiles
+	Limit string
+	Value int64
+}
+
+func (e *FormTooLargeError) Error() string {
+	return fmt.Sprintf("form exceeds the %s limit of %d", e.Limit, e.Value)
+}
+
+func (e *FormTooLargeError) Is(target error) bool {
+	return target == ErrFormTooLarge
+}
+
+// MultipartUploadOption configures UploadMultipart
+type MultipartUploadOption struct {
+	// Prefix is prepended to the base name of the files by the default Key.
+	Prefix string
+	// Key returns the key of a file from the name of its form field and its file name, an empty key skipping the file.
+	// Defaults to Prefix followed by the base name of the file, the file name being chosen by the client.
+	Key func(fieldName string, fileName string) (string, error)
+	// MaxFileSize is the maximum size of a file in bytes, exceeding it fails with an *ObjectTooLargeError.
+	// No limit when zero.
+	MaxFileSize int64
+	// MaxTotalSize is the maximum size of all the files in bytes, exceeding it fails with a *FormTooLargeError.
+	// No limit when zero.
+	MaxTotalSize int64
+	// MaxFiles is the maximum number of files, exceeding it fails with a *FormTooLargeError. No limit when zero.
+	MaxFiles int
+	// MaxValuesSize is the maximum size of the form values in bytes, exceeding it fails with a *FormTooLargeError.
+	// Defaults to DefaultMaxFormValuesSize.
+	MaxValuesSize int64
+}
+
+// MultipartFile is a file uploaded by UploadMultipart
+type MultipartFile struct {
+	FieldName   string
+	FileName    string
+	Key         string
+	ContentType string
+	Size        int64
+}
+
+// UploadMultipartRequest uploads the files of the multipart/form-data body of r with UploadMultipart
+func UploadMultipartRequest(
+	r *http.Request,
+	storage CloudStorage,
+	opts *MultipartUploadOption,
+) ([]*MultipartFile, url.Values, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return UploadMultipart(r.Context(), storage, reader, opts)
+}
+
+// UploadMultipart streams each file part of the form into GetWriter, without buffering it in memory or on disk,
+// and returns the uploaded files with the other form values.
+// The content type of a file is the one sent by the client, or detected from its name and content when it's missing
+// or application/octet-stream. The files already uploaded are deleted when the form fails.
+func UploadMultipart(
+	ctx context.Context,
+	storage CloudStorage,
+	reader *multipart.Reader,
+	opts *MultipartUploadOption,
+) ([]*MultipartFile, url.Values, error) {
+	if opts == nil {
+		opts = &MultipartUploadOption{}
+	}
+
+	files, values, err := uploadParts(ctx, storage, reader, opts)
+	if err != nil {
+		for _, file := range files {
+			_ = storage.Delete(ctx, file.Key)
+		}
+
+		return nil, nil, err
+	}
+
+	return files, values, nil
+}
+
+func uploadParts(
+	ctx context.Context,
+	storage CloudStorage,
+	reader *multipart.Reader,
+	opts *MultipartUploadOption,
+) ([]*MultipartFile, url.Values, error) {
+	var files []*MultipartFile
+
+	values := url.Values{}
+
+	valuesRemaining := opts.MaxValuesSize
+	if valuesRemaining <= 0 {
+		valuesRemaining = DefaultMaxFormValuesSize
+	}
+
+	totalRemaining := opts.MaxTotalSize
+	if totalRemaining <= 0 {
+		totalRemaining = math.MaxInt64
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return files, values, nil
+		}
+
+		if err != nil {
+			return files, nil, err
+		}
+
+		fieldName := part.FormName()
+
+		if part.FileName() == "" {
+			value, err := ioutil.ReadAll(io.LimitReader(part, valuesRemaining+1))
+			part.Close()
+
+			if err != nil {
+				return files, nil, err
+			}
+
+			valuesRemaining -= int64(len(value))
+			if valuesRemaining < 0 {
+				return files, nil, &FormTooLargeError{Limit: "MaxValuesSize", Value: opts.MaxValuesSize}
+			}
+
+			values.Add(fieldName, string(value))
+
+			continue
+		}
+
+		if opts.MaxFiles > 0 && len(files) >= opts.MaxFiles {
+			part.Close()
+			return files, nil, &FormTooLargeError{Limit: "MaxFiles", Value: int64(opts.MaxFiles)}
+		}
+
+		file, err := uploadPart(ctx, storage, part, opts, totalRemaining)
+		part.Close()
+
+		if err != nil {
+			return files, nil, err
+		}
+
+		if file != nil {
+			totalRemaining -= file.Size
+			files = append(files, file)
+		}
+	}
+}
+
+// uploadPart uploads a file part, or returns nil when its key is empty
+func uploadPart(
+	ctx context.Context,
+	storage CloudStorage,
+	part *multipart.Part,
+	opts *MultipartUploadOption,
+	totalRemaining int64,
+) (*MultipartFile, error) {
+	file := &MultipartFile{
+		FieldName:   part.FormName(),
+		FileName:    part.FileName(),
+		ContentType: part.Header.Get("Content-Type"),
+	}
+
+	var err error
+	if opts.Key != nil {
+		file.Key, err = opts.Key(file.FieldName, file.FileName)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		file.Key = opts.Prefix + path.Base(file.FileName)
+	}
+
+	if file.Key == "" {
+		return nil, nil
+	}
+
+	var body io.Reader = part
+	if file.ContentType == "" || file.ContentType == "application/octet-stream" {
+		file.ContentType, body, err = sniffReader(file.FileName, part)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the writer is aborted by cancelling its context before closing it
+	ctx, cancel := context.WithCancel(ctx)
+
+	writer, err := storage.GetWriter(ctx, file.Key, WithContentType(file.ContentType))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	limit := totalRemaining
+	var limitErr error = &FormTooLargeError{Limit: "MaxTotalSize", Value: opts.MaxTotalSize}
+
+	if opts.MaxFileSize > 0 && opts.MaxFileSize <= limit {
+		limit = opts.MaxFileSize
+		limitErr = &ObjectTooLargeError{Key: file.Key, Limit: opts.MaxFileSize}
+	}
+
+	limited := &sizeLimitedWriter{
+		WriteCloser: writer,
+		cancel:      cancel,
+		err:         limitErr,
+		remaining:   limit,
+	}
+
+	file.Size, err = io.Copy(limited, body)
+	if err != nil {
+		cancel()
+		_ = limited.Close()
+
+		return nil, err
+	}
+
+	if err := limited.Close(); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type formPart struct {
+	field       string
+	fileName    string
+	contentType string
+	body        string
+}
+
+func newMultipartRequest(t *testing.T, parts ...formPart) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	for _, part := range parts {
+		header := textproto.MIMEHeader{}
+		if part.fileName != "" {
+			header.Set("Content-Disposition", `form-data; name="`+part.field+`"; filename="`+part.fileName+`"`)
+		} else {
+			header.Set("Content-Disposition", `form-data; name="`+part.field+`"`)
+		}
+
+		if part.contentType != "" {
+			header.Set("Content-Type", part.contentType)
+		}
+
+		w, err := writer.CreatePart(header)
+		require.NoError(t, err)
+
+		_, err = w.Write([]byte(part.body))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, writer.Close())
+
+	request := httptest.NewRequest(http.MethodPost, "/upload", body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return request
+}
+
+func TestUploadMultipart(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	files, values, err := UploadMultipartRequest(newMultipartRequest(t,
+		formPart{field: "title", body: "holidays"},
+		formPart{field: "photo", fileName: "beach.png", contentType: "image/png", body: "png"},
+		formPart{field: "notes", fileName: "notes.txt", contentType: "application/octet-stream", body: "hello"},
+	), storage, &MultipartUploadOption{Prefix: "uploads/"})
+	require.NoError(t, err)
+	require.Equal(t, "holidays", values.Get("title"))
+	require.Equal(t, []*MultipartFile{
+		{FieldName: "photo", FileName: "beach.png", Key: "uploads/beach.png", ContentType: "image/png", Size: 3},
+		{FieldName: "notes", FileName: "notes.txt", Key: "uploads/notes.txt", ContentType: "text/plain; charset=utf-8", Size: 5},
+	}, files)
+
+	attrs, err := storage.Attributes(ctx, "uploads/beach.png")
+	require.NoError(t, err)
+	require.Equal(t, "image/png", attrs.ContentType)
+
+	body, err := storage.Get(ctx, "uploads/notes.txt")
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(body))
+
+	attrs, err = storage.Attributes(ctx, "uploads/notes.txt")
+	require.NoError(t, err)
+	require.Equal(t, "text/plain; charset=utf-8", attrs.ContentType)
+
+	// the parts with an empty key are skipped
+	files, _, err = UploadMultipartRequest(newMultipartRequest(t,
+		formPart{field: "avatar", fileName: "me.png", body: "png"},
+		formPart{field: "other", fileName: "other.png", body: "png"},
+	), storage, &MultipartUploadOption{
+		Key: func(fieldName string, fileName string) (string, error) {
+			if fieldName != "avatar" {
+				return "", nil
+			}
+
+			return "avatars/user-1", nil
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, "avatars/user-1", files[0].Key)
+	require.Equal(t, "image/png", files[0].ContentType)
+}
+
+func TestUploadMultipartLimits(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	_, _, err := UploadMultipartRequest(newMultipartRequest(t,
+		formPart{field: "a", fileName: "a.txt", body: "small"},
+		formPart{field: "b", fileName: "b.txt", body: strings.Repeat("x", 100)},
+	), storage, &MultipartUploadOption{Prefix: "uploads/", MaxFileSize: 10})
+	require.True(t, errors.Is(err, ErrObjectTooLarge))
+
+	var tooLarge *ObjectTooLargeError
+	require.True(t, errors.As(err, &tooLarge))
+	require.Equal(t, "uploads/b.txt", tooLarge.Key)
+
+	// the files already uploaded are deleted
+	require.Empty(t, listedKeys(t, storage.List(ctx, "uploads/")))
+
+	_, _, err = UploadMultipartRequest(newMultipartRequest(t,
+		formPart{field: "a", fileName: "a.txt", body: "012345"},
+		formPart{field: "b", fileName: "b.txt", body: "012345"},
+	), storage, &MultipartUploadOption{Prefix: "uploads/", MaxTotalSize: 10})
+	require.True(t, errors.Is(err, ErrFormTooLarge))
+	require.Equal(t, "form exceeds the MaxTotalSize limit of 10", err.Error())
+	require.Empty(t, listedKeys(t, storage.List(ctx, "uploads/")))
+
+	_, _, err = UploadMultipartRequest(newMultipartRequest(t,
+		formPart{field: "a", fileName: "a.txt", body: "a"},
+		formPart{field: "b", fileName: "b.txt", body: "b"},
+	), storage, &MultipartUploadOption{Prefix: "uploads/", MaxFiles: 1})
+	require.True(t, errors.Is(err, ErrFormTooLarge))
+	require.Empty(t, listedKeys(t, storage.List(ctx, "uploads/")))
+
+	_, _, err = UploadMultipartRequest(newMultipartRequest(t,
+		formPart{field: "comment", body: strings.Repeat("x", 100)},
+	), storage, &MultipartUploadOption{MaxValuesSize: 10})
+	require.True(t, errors.Is(err, ErrFormTooLarge))
+
+	_, _, err = UploadMultipartRequest(httptest.NewRequest(http.MethodPost, "/upload", nil), storage, nil)
+	require.Error(t, err)
+}
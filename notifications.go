@@ -0,0 +1,437 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/iam"
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+)
+
+const (
+	// notificationBufferSize is the capacity of the channels returned by Subscribe
+	notificationBufferSize = 100
+	// notificationRetryDelay is the delay before receiving again after an error of the queue
+	notificationRetryDelay = 5 * time.Second
+)
+
+// ObjectEventType is the kind of change of an ObjectEvent
+type ObjectEventType string
+
+const (
+	ObjectCreated ObjectEventType = "created"
+	// ObjectUpdated is only sent by GCP, the overwritten objects are ObjectCreated on AWS
+	ObjectUpdated ObjectEventType = "updated"
+	ObjectDeleted ObjectEventType = "deleted"
+)
+
+// ObjectEvent is a change of an object received by Subscribe
+type ObjectEvent struct {
+	Type ObjectEventType
+	Key  string
+	// Size is zero for the deleted objects
+	Size int64
+	Time time.Time
+}
+
+func notificationQueueName(cloudStorageOpts *CloudStorageOption, bucketName string) string {
+	if cloudStorageOpts.NotificationQueue != "" {
+		return cloudStorageOpts.NotificationQueue
+	}
+
+	return bucketName + "-notifications"
+}
+
+// s3Event is the S3 event notification message
+type s3Event struct {
+	Records []struct {
+		EventName string    `json:"eventName"`
+		EventTime time.Time `json:"eventTime"`
+		S3        struct {
+			Object struct {
+				Key  string `json:"key"`
+				Size int64  `json:"size"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+}
+
+// awsSubscribe creates the SQS queue and the bucket notification if they don't exist yet,
+// then polls the queue until ctx is done
+// nolint:funlen
+func awsSubscribe(
+	ctx context.Context,
+	s3Client *s3.S3,
+	sqsClient *sqs.SQS,
+	bucketName string,
+	queueName string,
+	prefix string,
+	logger Logger,
+) (<-chan ObjectEvent, error) {
+	queue, err := sqsClient.CreateQueueWithContext(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String(queueName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create SQS queue: %w", err)
+	}
+
+	attributes, err := sqsClient.GetQueueAttributesWithContext(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       queue.QueueUrl,
+		AttributeNames: aws.StringSlice([]string{sqs.QueueAttributeNameQueueArn}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	queueArn := aws.StringValue(attributes.Attributes[sqs.QueueAttributeNameQueueArn])
+
+	// the bucket has to be allowed to send the notifications to the queue
+	policy, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{{
+			"Effect":    "Allow",
+			"Principal": map[string]string{"Service": "s3.amazonaws.com"},
+			"Action":    "sqs:SendMessage",
+			"Resource":  queueArn,
+			"Condition": map[string]interface{}{
+				"ArnLike": map[string]string{"aws:SourceArn": "arn:aws:s3:::" + bucketName},
+			},
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = sqsClient.SetQueueAttributesWithContext(ctx, &sqs.SetQueueAttributesInput{
+		QueueUrl:   queue.QueueUrl,
+		Attributes: map[string]*string{sqs.QueueAttributeNamePolicy: aws.String(string(policy))},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := awsAddQueueNotification(ctx, s3Client, bucketName, queueName, queueArn); err != nil {
+		return nil, err
+	}
+
+	events := make(chan ObjectEvent, notificationBufferSize)
+
+	go func() {
+		defer close(events)
+
+		for ctx.Err() == nil {
+			out, err := sqsClient.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+				QueueUrl:            queue.QueueUrl,
+				MaxNumberOfMessages: aws.Int64(10),
+				WaitTimeSeconds:     aws.Int64(20),
+			})
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Warn("unable to receive the bucket notifications", Fields{"queue": queueName, "error": err})
+					sleepContext(ctx, notificationRetryDelay)
+				}
+
+				continue
+			}
+
+			for _, message := range out.Messages {
+				if !sendEvents(ctx, events, awsObjectEvents(aws.StringValue(message.Body), prefix, logger)) {
+					return
+				}
+
+				_, err := sqsClient.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
+					QueueUrl:      queue.QueueUrl,
+					ReceiptHandle: message.ReceiptHandle,
+				})
+				if err != nil {
+					logger.Warn("unable to delete the bucket notification", Fields{"queue": queueName, "error": err})
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// awsAddQueueNotification adds the queue to the notification configuration of the bucket, keeping the others
+func awsAddQueueNotification(ctx context.Context, client *s3.S3, bucketName, queueName, queueArn string) error {
+	config, err := client.GetBucketNotificationConfigurationWithContext(ctx, &s3.GetBucketNotificationConfigurationRequest{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, queueConfig := range config.QueueConfigurations {
+		if aws.StringValue(queueConfig.QueueArn) == queueArn {
+			return nil
+		}
+	}
+
+	config.QueueConfigurations = append(config.QueueConfigurations, &s3.QueueConfiguration{
+		Id:       aws.String(queueName),
+		QueueArn: aws.String(queueArn),
+		Events:   aws.StringSlice([]string{s3.EventS3ObjectCreated, s3.EventS3ObjectRemoved}),
+	})
+
+	_, err = client.PutBucketNotificationConfigurationWithContext(ctx, &s3.PutBucketNotificationConfigurationInput{
+		Bucket:                    aws.String(bucketName),
+		NotificationConfiguration: config,
+	})
+
+	return err
+}
+
+// awsObjectEvents returns the events of an S3 notification message under the prefix
+func awsObjectEvents(body string, prefix string, logger Logger) []ObjectEvent {
+	var event s3Event
+	if err := json.Unmarshal([]byte(body), &event); err != nil {
+		logger.Warn("invalid bucket notification", Fields{"error": err})
+		return nil
+	}
+
+	var events []ObjectEvent
+
+	for _, record := range event.Records {
+		// the keys are URL-encoded in the notifications
+		key, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		objectEvent := ObjectEvent{Key: key, Size: record.S3.Object.Size, Time: record.EventTime}
+
+		switch {
+		case strings.HasPrefix(record.EventName, "ObjectCreated:"):
+			objectEvent.Type = ObjectCreated
+		case strings.HasPrefix(record.EventName, "ObjectRemoved:"):
+			objectEvent.Type = ObjectDeleted
+		default:
+			continue
+		}
+
+		events = append(events, objectEvent)
+	}
+
+	return events
+}
+
+// gcpSubscribe creates the Pub/Sub topic, the bucket notification and the subscription if they don't exist yet,
+// then receives the notifications until ctx is done
+// nolint:funlen
+func gcpSubscribe(
+	ctx context.Context,
+	client *storage.Client,
+	creds *google.Credentials,
+	bucketName string,
+	queueName string,
+	prefix string,
+	logger Logger,
+) (<-chan ObjectEvent, error) {
+	if creds.ProjectID == "" {
+		return nil, fmt.Errorf("unable to subscribe without the project ID of the GCP credentials")
+	}
+
+	pubsubClient, err := pubsub.NewClient(ctx, creds.ProjectID, option.WithCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create Pub/Sub client: %w", err)
+	}
+
+	topic := pubsubClient.Topic(queueName)
+
+	exists, err := topic.Exists(ctx)
+	if err != nil {
+		pubsubClient.Close()
+		return nil, err
+	}
+
+	if !exists {
+		topic, err = pubsubClient.CreateTopic(ctx, queueName)
+		if err != nil {
+			pubsubClient.Close()
+			return nil, fmt.Errorf("unable to create Pub/Sub topic: %w", err)
+		}
+	}
+
+	if err := gcpAddTopicNotification(ctx, client, topic, creds.ProjectID, bucketName, queueName); err != nil {
+		pubsubClient.Close()
+		return nil, err
+	}
+
+	subscription := pubsubClient.Subscription(queueName)
+
+	exists, err = subscription.Exists(ctx)
+	if err != nil {
+		pubsubClient.Close()
+		return nil, err
+	}
+
+	if !exists {
+		subscription, err = pubsubClient.CreateSubscription(ctx, queueName, pubsub.SubscriptionConfig{Topic: topic})
+		if err != nil {
+			pubsubClient.Close()
+			return nil, fmt.Errorf("unable to create Pub/Sub subscription: %w", err)
+		}
+	}
+
+	events := make(chan ObjectEvent, notificationBufferSize)
+
+	go func() {
+		defer close(events)
+		defer pubsubClient.Close()
+
+		for ctx.Err() == nil {
+			err := subscription.Receive(ctx, func(ctx context.Context, message *pubsub.Message) {
+				event, ok := gcpObjectEvent(message.Attributes, message.Data)
+				if ok && strings.HasPrefix(event.Key, prefix) && !sendEvents(ctx, events, []ObjectEvent{event}) {
+					message.Nack()
+					return
+				}
+
+				message.Ack()
+			})
+			if err != nil && ctx.Err() == nil {
+				logger.Warn("unable to receive the bucket notifications", Fields{"subscription": queueName, "error": err})
+				sleepContext(ctx, notificationRetryDelay)
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// gcpAddTopicNotification allows the storage service account to publish to the topic,
+// and adds the notification of the topic to the bucket
+func gcpAddTopicNotification(
+	ctx context.Context,
+	client *storage.Client,
+	topic *pubsub.Topic,
+	projectID string,
+	bucketName string,
+	queueName string,
+) error {
+	bucket := client.Bucket(bucketName)
+
+	notifications, err := bucket.Notifications(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, notification := range notifications {
+		if notification.TopicID == queueName && notification.TopicProjectID == projectID {
+			return nil
+		}
+	}
+
+	serviceAccount, err := client.ServiceAccount(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	policy, err := topic.IAM().Policy(ctx)
+	if err != nil {
+		return err
+	}
+
+	policy.Add("serviceAccount:"+serviceAccount, iam.RoleName("roles/pubsub.publisher"))
+
+	if err := topic.IAM().SetPolicy(ctx, policy); err != nil {
+		return err
+	}
+
+	_, err = bucket.AddNotification(ctx, &storage.Notification{
+		TopicID:        queueName,
+		TopicProjectID: projectID,
+		PayloadFormat:  storage.JSONPayload,
+	})
+
+	return err
+}
+
+// gcpObjectEvent returns the event of a GCS notification message, see
+// https://cloud.google.com/storage/docs/pubsub-notifications#attributes
+func gcpObjectEvent(attributes map[string]string, data []byte) (ObjectEvent, bool) {
+	event := ObjectEvent{Key: attributes["objectId"]}
+	event.Time, _ = time.Parse(time.RFC3339Nano, attributes["eventTime"])
+
+	switch attributes["eventType"] {
+	case "OBJECT_FINALIZE":
+		event.Type = ObjectCreated
+		if attributes["overwroteGeneration"] != "" {
+			event.Type = ObjectUpdated
+		}
+	case "OBJECT_METADATA_UPDATE":
+		event.Type = ObjectUpdated
+	case "OBJECT_DELETE":
+		// the overwritten objects are already notified by the OBJECT_FINALIZE of the new generation
+		if attributes["overwrittenByGeneration"] != "" {
+			return event, false
+		}
+
+		event.Type = ObjectDeleted
+	default:
+		return event, false
+	}
+
+	if event.Type != ObjectDeleted {
+		var payload struct {
+			Size string `json:"size"`
+		}
+
+		if err := json.Unmarshal(data, &payload); err == nil {
+			event.Size, _ = strconv.ParseInt(payload.Size, 10, 64)
+		}
+	}
+
+	return event, true
+}
+
+// sendEvents returns false if ctx is done before the events are sent
+func sendEvents(ctx context.Context, events chan<- ObjectEvent, batch []ObjectEvent) bool {
+	for _, event := range batch {
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	return true
+}
+
+func sleepContext(ctx context.Context, delay time.Duration) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSObjectEvents(t *testing.T) {
+	body := `{"Records": [
+		{"eventName": "ObjectCreated:Put", "eventTime": "2020-06-01T10:00:00.000Z",
+			"s3": {"object": {"key": "uploads/my+file%281%29.png", "size": 42}}},
+		{"eventName": "ObjectRemoved:Delete", "eventTime": "2020-06-01T10:01:00.000Z",
+			"s3": {"object": {"key": "uploads/old.png"}}},
+		{"eventName": "ObjectCreated:Put", "eventTime": "2020-06-01T10:02:00.000Z",
+			"s3": {"object": {"key": "other/file.png", "size": 1}}}
+	]}`
+
+	events := awsObjectEvents(body, "uploads/", noopLogger{})
+	require.Equal(t, []ObjectEvent{
+		{
+			Type: ObjectCreated,
+			Key:  "uploads/my file(1).png",
+			Size: 42,
+			Time: time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			Type: ObjectDeleted,
+			Key:  "uploads/old.png",
+			Time: time.Date(2020, 6, 1, 10, 1, 0, 0, time.UTC),
+		},
+	}, events)
+
+	// the test event sent when the notification is configured has no record
+	require.Empty(t, awsObjectEvents(`{"Event": "s3:TestEvent"}`, "", noopLogger{}))
+}
+
+func TestGCPObjectEvent(t *testing.T) {
+	event, ok := gcpObjectEvent(map[string]string{
+		"eventType":           "OBJECT_FINALIZE",
+		"objectId":            "uploads/a.png",
+		"eventTime":           "2020-06-01T10:00:00.5Z",
+		"overwroteGeneration": "1591005600000000",
+	}, []byte(`{"size": "42"}`))
+	require.True(t, ok)
+	require.Equal(t, ObjectEvent{
+		Type: ObjectUpdated,
+		Key:  "uploads/a.png",
+		Size: 42,
+		Time: time.Date(2020, 6, 1, 10, 0, 0, 500000000, time.UTC),
+	}, event)
+
+	event, ok = gcpObjectEvent(map[string]string{"eventType": "OBJECT_DELETE", "objectId": "uploads/a.png"}, nil)
+	require.True(t, ok)
+	require.Equal(t, ObjectDeleted, event.Type)
+
+	_, ok = gcpObjectEvent(map[string]string{
+		"eventType":               "OBJECT_DELETE",
+		"objectId":                "uploads/a.png",
+		"overwrittenByGeneration": "1591005600000001",
+	}, nil)
+	require.False(t, ok)
+}
+
+func TestFakeSubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	storage := NewFakeCloudStorage("bucket")
+
+	events, err := storage.Subscribe(ctx, "uploads/")
+	require.NoError(t, err)
+
+	require.NoError(t, storage.Write(ctx, "uploads/a.png", []byte("png"), nil))
+	require.NoError(t, storage.Write(ctx, "other/b.png", []byte("png"), nil))
+	require.NoError(t, storage.Write(ctx, "uploads/a.png", []byte("new png"), nil))
+	require.NoError(t, storage.Delete(ctx, "uploads/a.png"))
+
+	var received []ObjectEvent
+	for i := 0; i < 3; i++ {
+		event := <-events
+		event.Time = time.Time{}
+		received = append(received, event)
+	}
+
+	require.Equal(t, []ObjectEvent{
+		{Type: ObjectCreated, Key: "uploads/a.png", Size: 3},
+		{Type: ObjectUpdated, Key: "uploads/a.png", Size: 7},
+		{Type: ObjectDeleted, Key: "uploads/a.png"},
+	}, received)
+
+	cancel()
+
+	_, open := <-events
+	require.False(t, open)
+
+	// the writes don't block once the subscription is done
+	require.NoError(t, storage.Write(context.Background(), "uploads/c.png", []byte("png"), nil))
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// EncryptionMode is the server-side encryption protecting an object
+type EncryptionMode string
+
+const (
+	// EncryptionUnknown is the mode of the objects whose encryption isn't reported, e.g. by the S3 emulators
+	EncryptionUnknown EncryptionMode = ""
+	// EncryptionNone is the mode of the S3 objects stored without encryption
+	EncryptionNone EncryptionMode = "none"
+	// EncryptionProviderManaged is the encryption with the default keys of the provider: SSE-S3 on AWS and
+	// the Google-managed keys on GCP
+	EncryptionProviderManaged EncryptionMode = "provider-managed"
+	// EncryptionKMS is the encryption with a KMS key: SSE-KMS and DSSE-KMS on AWS, CMEK on GCP
+	EncryptionKMS EncryptionMode = "kms"
+	// EncryptionCustomerKey is the encryption with a key given with every request: SSE-C on AWS, CSEK on GCP
+	EncryptionCustomerKey EncryptionMode = "customer-key"
+)
+
+// ObjectEncryption is the server-side encryption of an object
+type ObjectEncryption struct {
+	Mode EncryptionMode
+	// KeyID identifies the key: the KMS key ARN on AWS and the Cloud KMS key version name on GCP for
+	// EncryptionKMS, the base64 MD5 (AWS) or SHA-256 (GCP) of the key for EncryptionCustomerKey.
+	// It's empty for the other modes. The ARN of the KMS key managed by AWS doesn't say it's the default one,
+	// GetBucketEncryption gives the key of the bucket to compare with.
+	KeyID string
+}
+
+// awsObjectEncryption returns the encryption of the headers of a HeadObject or a GetObject response
+func awsObjectEncryption(sse, kmsKeyID, customerAlgorithm, customerKeyMD5 *string) ObjectEncryption {
+	switch algorithm := aws.StringValue(sse); {
+	case aws.StringValue(customerAlgorithm) != "":
+		return ObjectEncryption{Mode: EncryptionCustomerKey, KeyID: aws.StringValue(customerKeyMD5)}
+	case strings.HasPrefix(algorithm, s3.ServerSideEncryptionAwsKms):
+		return ObjectEncryption{Mode: EncryptionKMS, KeyID: aws.StringValue(kmsKeyID)}
+	case algorithm == s3.ServerSideEncryptionAes256:
+		return ObjectEncryption{Mode: EncryptionProviderManaged}
+	default:
+		return ObjectEncryption{Mode: EncryptionNone}
+	}
+}
+
+// gcsObjectEncryption returns the encryption of a GCS object, which is always encrypted
+func gcsObjectEncryption(objectAttrs *storage.ObjectAttrs) ObjectEncryption {
+	switch {
+	case objectAttrs.CustomerKeySHA256 != "":
+		return ObjectEncryption{Mode: EncryptionCustomerKey, KeyID: objectAttrs.CustomerKeySHA256}
+	case objectAttrs.KMSKeyName != "":
+		return ObjectEncryption{Mode: EncryptionKMS, KeyID: objectAttrs.KMSKeyName}
+	default:
+		return ObjectEncryption{Mode: EncryptionProviderManaged}
+	}
+}
+
+// EncryptionReportOption configures ReportEncryption
+type EncryptionReportOption struct {
+	// Concurrency is the number of objects whose attributes are read in parallel, DefaultGetManyConcurrency
+	// when it isn't positive
+	Concurrency int
+}
+
+// EncryptionReport is the result of ReportEncryption
+type EncryptionReport struct {
+	// Objects is the encryption of the objects, by key
+	Objects map[string]ObjectEncryption
+	// Errors are the errors of the objects whose attributes couldn't be read, by key
+	Errors map[string]error
+}
+
+// WithMode returns the sorted keys of the objects encrypted with the mode
+func (r *EncryptionReport) WithMode(mode EncryptionMode) []string {
+	var keys []string
+
+	for key, encryption := range r.Objects {
+		if encryption.Mode == mode {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// WithKey returns the sorted keys of the objects encrypted with the key ID
+func (r *EncryptionReport) WithKey(keyID string) []string {
+	var keys []string
+
+	for key, encryption := range r.Objects {
+		if encryption.KeyID == keyID {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// ReportEncryption reads the attributes of every object under the prefix and reports their server-side
+// encryption, e.g. for the compliance scans looking for the objects still encrypted with the default keys of
+// the provider. The error is only returned when the objects can't be listed, the failures of the objects are in
+// the report.
+func ReportEncryption(
+	ctx context.Context,
+	storage CloudStorage,
+	prefix string,
+	opts *EncryptionReportOption,
+) (*EncryptionReport, error) {
+	options := EncryptionReportOption{}
+	if opts != nil {
+		options = *opts
+	}
+
+	var (
+		mu     sync.Mutex
+		report = &EncryptionReport{Objects: make(map[string]ObjectEncryption), Errors: make(map[string]error)}
+	)
+
+	err := ForEachObject(ctx, storage, prefix, func(ctx context.Context, object *ListObject) error {
+		attrs, err := storage.Attributes(ctx, object.Key)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		report.Objects[object.Key] = attrs.Encryption
+
+		return nil
+	}, &ForEachOption{Concurrency: options.Concurrency, CollectErrors: true})
+
+	var forEachErr *ForEachError
+
+	switch {
+	case errors.As(err, &forEachErr):
+		for key, err := range forEachErr.Errors {
+			report.Errors[key] = err
+		}
+	case err != nil:
+		return nil, err
+	}
+
+	return report, nil
+}
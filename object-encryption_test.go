@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSObjectEncryption(t *testing.T) {
+	testCases := []struct {
+		sse, kmsKeyID, customerAlgorithm, customerKeyMD5 *string
+		expected                                         ObjectEncryption
+	}{
+		{expected: ObjectEncryption{Mode: EncryptionNone}},
+		{sse: aws.String("AES256"), expected: ObjectEncryption{Mode: EncryptionProviderManaged}},
+		{
+			sse:      aws.String("aws:kms"),
+			kmsKeyID: aws.String("arn:aws:kms:us-east-1:123456789012:key/1234"),
+			expected: ObjectEncryption{Mode: EncryptionKMS, KeyID: "arn:aws:kms:us-east-1:123456789012:key/1234"},
+		},
+		{
+			sse:      aws.String("aws:kms:dsse"),
+			kmsKeyID: aws.String("arn:aws:kms:us-east-1:123456789012:key/5678"),
+			expected: ObjectEncryption{Mode: EncryptionKMS, KeyID: "arn:aws:kms:us-east-1:123456789012:key/5678"},
+		},
+		{
+			customerAlgorithm: aws.String("AES256"),
+			customerKeyMD5:    aws.String("ZmFrZQ=="),
+			expected:          ObjectEncryption{Mode: EncryptionCustomerKey, KeyID: "ZmFrZQ=="},
+		},
+	}
+
+	for _, testCase := range testCases {
+		require.Equal(t, testCase.expected, awsObjectEncryption(
+			testCase.sse,
+			testCase.kmsKeyID,
+			testCase.customerAlgorithm,
+			testCase.customerKeyMD5,
+		))
+	}
+}
+
+func TestGCSObjectEncryption(t *testing.T) {
+	require.Equal(t, ObjectEncryption{Mode: EncryptionProviderManaged}, gcsObjectEncryption(&storage.ObjectAttrs{}))
+
+	keyName := "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1"
+	require.Equal(t, ObjectEncryption{Mode: EncryptionKMS, KeyID: keyName},
+		gcsObjectEncryption(&storage.ObjectAttrs{KMSKeyName: keyName}))
+	require.Equal(t, ObjectEncryption{Mode: EncryptionCustomerKey, KeyID: "c2hhMjU2"},
+		gcsObjectEncryption(&storage.ObjectAttrs{CustomerKeySHA256: "c2hhMjU2"}))
+}
+
+func TestReportEncryption(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.Write(ctx, "exports/old", []byte("old"), nil))
+	require.NoError(t, storage.SetBucketEncryption(ctx, &BucketEncryption{KMSKeyName: "alias/exports"}))
+	require.NoError(t, storage.Write(ctx, "exports/new", []byte("new"), nil))
+	require.NoError(t, storage.Write(ctx, "other", []byte("other"), nil))
+
+	report, err := ReportEncryption(ctx, storage, "exports/", nil)
+	require.NoError(t, err)
+	require.Empty(t, report.Errors)
+	require.Equal(t, map[string]ObjectEncryption{
+		"exports/old": {Mode: EncryptionProviderManaged},
+		"exports/new": {Mode: EncryptionKMS, KeyID: "alias/exports"},
+	}, report.Objects)
+	require.Equal(t, []string{"exports/old"}, report.WithMode(EncryptionProviderManaged))
+	require.Equal(t, []string{"exports/new"}, report.WithKey("alias/exports"))
+	require.Empty(t, report.WithMode(EncryptionCustomerKey))
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	// RetentionModeGovernance allows users with special permissions to shorten or remove the retention.
+	RetentionModeGovernance = s3.ObjectLockRetentionModeGovernance
+	// RetentionModeCompliance prevents anyone from shortening or removing the retention.
+	RetentionModeCompliance = s3.ObjectLockRetentionModeCompliance
+)
+
+// ObjectRetention describes the WORM retention applied to a single object.
+type ObjectRetention struct {
+	// Mode is one of RetentionModeGovernance or RetentionModeCompliance.
+	// It is empty on GCP, where the retention comes from the bucket retention policy.
+	Mode string
+	// RetainUntil is the time until the object can't be overwritten or deleted.
+	RetainUntil time.Time
+}
+
+func awsSetObjectRetention(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	key string,
+	retention *ObjectRetention,
+) error {
+	_, err := client.PutObjectRetentionWithContext(ctx, &s3.PutObjectRetentionInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(awsEscapeKey(key)),
+		Retention: &s3.ObjectLockRetention{
+			Mode:            aws.String(retention.Mode),
+			RetainUntilDate: aws.Time(retention.RetainUntil),
+		},
+	})
+
+	return err
+}
+
+func awsGetObjectRetention(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	key string,
+) (*ObjectRetention, error) {
+	out, err := client.GetObjectRetentionWithContext(ctx, &s3.GetObjectRetentionInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(awsEscapeKey(key)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if out.Retention == nil {
+		return &ObjectRetention{}, nil
+	}
+
+	return &ObjectRetention{
+		Mode:        aws.StringValue(out.Retention.Mode),
+		RetainUntil: aws.TimeValue(out.Retention.RetainUntilDate),
+	}, nil
+}
+
+func awsSetLegalHold(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	key string,
+	enabled bool,
+) error {
+	status := s3.ObjectLockLegalHoldStatusOff
+	if enabled {
+		status = s3.ObjectLockLegalHoldStatusOn
+	}
+
+	_, err := client.PutObjectLegalHoldWithContext(ctx, &s3.PutObjectLegalHoldInput{
+		Bucket:    aws.String(bucketName),
+		Key:       aws.String(awsEscapeKey(key)),
+		LegalHold: &s3.ObjectLockLegalHold{Status: aws.String(status)},
+	})
+
+	return err
+}
+
+func awsGetLegalHold(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	key string,
+) (bool, error) {
+	out, err := client.GetObjectLegalHoldWithContext(ctx, &s3.GetObjectLegalHoldInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(awsEscapeKey(key)),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return out.LegalHold != nil && aws.StringValue(out.LegalHold.Status) == s3.ObjectLockLegalHoldStatusOn, nil
+}
+
+func gcpGetObjectRetention(
+	ctx context.Context,
+	client *storage.Client,
+	bucketName string,
+	key string,
+) (*ObjectRetention, error) {
+	attrs, err := client.Bucket(bucketName).Object(key).Attrs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ObjectRetention{
+		RetainUntil: attrs.RetentionExpirationTime,
+	}, nil
+}
+
+// GCP has no per-object retention mode, the closest equivalent of an S3 legal hold is a temporary hold
+func gcpSetLegalHold(
+	ctx context.Context,
+	client *storage.Client,
+	bucketName string,
+	key string,
+	enabled bool,
+) error {
+	_, err := client.Bucket(bucketName).Object(key).Update(ctx, storage.ObjectAttrsToUpdate{
+		TemporaryHold: enabled,
+	})
+
+	return err
+}
+
+func gcpGetLegalHold(
+	ctx context.Context,
+	client *storage.Client,
+	bucketName string,
+	key string,
+) (bool, error) {
+	attrs, err := client.Bucket(bucketName).Object(key).Attrs(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return attrs.TemporaryHold, nil
+}
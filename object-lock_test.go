@@ -0,0 +1,160 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+func TestAWSObjectLock(t *testing.T) {
+	var (
+		retention = `<Retention><Mode>COMPLIANCE</Mode><RetainUntilDate>2030-01-02T03:04:05Z</RetainUntilDate></Retention>`
+		legalHold = `<LegalHold><Status>ON</Status></LegalHold>`
+		written   = map[string]string{}
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the key is escaped like the keys of Get
+		require.Equal(t, "/bucket/a/__0x2f__b", r.URL.EscapedPath())
+
+		subresource := "retention"
+		if _, ok := r.URL.Query()["legal-hold"]; ok {
+			subresource = "legal-hold"
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			if subresource == "retention" {
+				w.Write([]byte(retention))
+			} else {
+				w.Write([]byte(legalHold))
+			}
+		case http.MethodPut:
+			body, _ := ioutil.ReadAll(r.Body)
+			written[subresource] = string(body)
+		}
+	}))
+	defer server.Close()
+
+	awsSession, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(server.URL),
+		Region:           aws.String("us-east-1"),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	client := s3.New(awsSession)
+
+	got, err := awsGetObjectRetention(ctx, client, "bucket", "a//b")
+	require.NoError(t, err)
+	require.Equal(t, RetentionModeCompliance, got.Mode)
+	require.True(t, time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC).Equal(got.RetainUntil))
+
+	err = awsSetObjectRetention(ctx, client, "bucket", "a//b", &ObjectRetention{
+		Mode:        RetentionModeGovernance,
+		RetainUntil: time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	require.Contains(t, written["retention"], "<Mode>GOVERNANCE</Mode>")
+	require.Contains(t, written["retention"], "<RetainUntilDate>2031-01-01T00:00:00Z</RetainUntilDate>")
+
+	held, err := awsGetLegalHold(ctx, client, "bucket", "a//b")
+	require.NoError(t, err)
+	require.True(t, held)
+
+	legalHold = `<LegalHold><Status>OFF</Status></LegalHold>`
+
+	held, err = awsGetLegalHold(ctx, client, "bucket", "a//b")
+	require.NoError(t, err)
+	require.False(t, held)
+
+	require.NoError(t, awsSetLegalHold(ctx, client, "bucket", "a//b", true))
+	require.Contains(t, written["legal-hold"], "<Status>ON</Status>")
+
+	require.NoError(t, awsSetLegalHold(ctx, client, "bucket", "a//b", false))
+	require.Contains(t, written["legal-hold"], "<Status>OFF</Status>")
+}
+
+func TestGCPObjectLock(t *testing.T) {
+	object := map[string]interface{}{
+		"bucket":                  "bucket",
+		"name":                    "key",
+		"retentionExpirationTime": "2030-01-02T03:04:05Z",
+		"temporaryHold":           true,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/storage/v1/b/bucket/o/key", r.URL.Path)
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPatch:
+			var update map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&update))
+			object["temporaryHold"] = update["temporaryHold"]
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+
+		json.NewEncoder(w).Encode(object)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	client, err := storage.NewClient(ctx, option.WithEndpoint(server.URL+"/storage/v1/"), option.WithoutAuthentication())
+	require.NoError(t, err)
+
+	defer client.Close()
+
+	// the retention comes from the retention policy of the bucket, it has no mode
+	retention, err := gcpGetObjectRetention(ctx, client, "bucket", "key")
+	require.NoError(t, err)
+	require.Equal(t, "", retention.Mode)
+	require.True(t, time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC).Equal(retention.RetainUntil))
+
+	// the legal hold is a temporary hold
+	held, err := gcpGetLegalHold(ctx, client, "bucket", "key")
+	require.NoError(t, err)
+	require.True(t, held)
+
+	require.NoError(t, gcpSetLegalHold(ctx, client, "bucket", "key", false))
+	require.Equal(t, false, object["temporaryHold"])
+
+	held, err = gcpGetLegalHold(ctx, client, "bucket", "key")
+	require.NoError(t, err)
+	require.False(t, held)
+
+	require.NoError(t, gcpSetLegalHold(ctx, client, "bucket", "key", true))
+	require.Equal(t, true, object["temporaryHold"])
+}
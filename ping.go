@@ -0,0 +1,49 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// awsPing checks the bucket with a HEAD request
+func awsPing(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+) error {
+	_, err := client.HeadBucketWithContext(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(bucketName),
+	})
+
+	return translateError(err)
+}
+
+// gcpPing checks the bucket by reading its metadata
+func gcpPing(
+	ctx context.Context,
+	client *storage.Client,
+	bucketName string,
+) error {
+	_, err := client.Bucket(bucketName).Attrs(ctx)
+
+	return translateError(err)
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSPing(t *testing.T) {
+	status := http.StatusOK
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodHead, r.Method)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	newClient := func(endpoint string) *s3.S3 {
+		awsSession, err := session.NewSession(&aws.Config{
+			Endpoint:         aws.String(endpoint),
+			Region:           aws.String("us-east-1"),
+			S3ForcePathStyle: aws.Bool(true),
+			Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+			HTTPClient:       &http.Client{Timeout: time.Second},
+			MaxRetries:       aws.Int(0),
+		})
+		require.NoError(t, err)
+
+		return s3.New(awsSession)
+	}
+
+	ctx := context.Background()
+	client := newClient(server.URL)
+
+	require.NoError(t, awsPing(ctx, client, "bucket"))
+
+	status = http.StatusForbidden
+	err := awsPing(ctx, client, "bucket")
+	require.True(t, errors.Is(err, ErrPermissionDenied))
+	require.False(t, errors.Is(err, ErrUnavailable))
+
+	status = http.StatusNotFound
+	require.True(t, errors.Is(awsPing(ctx, client, "bucket"), ErrNotFound))
+
+	// the network is down
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	err = awsPing(ctx, newClient(closed.URL), "bucket")
+	require.True(t, errors.Is(err, ErrUnavailable), "%v", err)
+}
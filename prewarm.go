@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// PrewarmOption selects the objects read by Prewarm
+type PrewarmOption struct {
+	// Keys are read as is, in addition to the objects listed under Prefix
+	Keys []string
+	// Prefix lists the objects to read when it's set
+	Prefix string
+	// MaxObjectBytes skips the listed objects larger than it, which the cache wouldn't keep, e.g. the
+	// CacheOption.MaxObjectBytes. Zero reads every object.
+	MaxObjectBytes int64
+	// Concurrency is the number of objects read in parallel. Defaults to DefaultGetManyConcurrency.
+	Concurrency int
+	// OnProgress is called after every read, from the reading goroutines but never concurrently
+	OnProgress func(progress PrewarmProgress)
+}
+
+// PrewarmProgress reports a read of Prewarm
+type PrewarmProgress struct {
+	Key string
+	// Err is the error of the read of the key
+	Err error
+	// Done is the number of objects read so far, out of Total
+	Done  int
+	Total int
+	// Bytes is the size of the objects read so far
+	Bytes int64
+}
+
+// PrewarmReport is the result of Prewarm
+type PrewarmReport struct {
+	Objects int
+	Bytes   int64
+	// Skipped are the listed objects larger than MaxObjectBytes
+	Skipped int
+	// Errors are the errors of the failed reads by key
+	Errors map[string]error
+}
+
+// Prewarm reads the objects into the read-through cache of the storage, see the Cache option, with bounded
+// concurrency, e.g. the configuration of a game before its launch. The bodies are discarded, so it only makes sense
+// on a cached storage. A failed read doesn't stop the others; the error is the one of the listing.
+func Prewarm(ctx context.Context, storage CloudStorage, opts PrewarmOption) (*PrewarmReport, error) {
+	report := &PrewarmReport{Errors: make(map[string]error)}
+	keys := append([]string(nil), opts.Keys...)
+
+	if opts.Prefix != "" {
+		iter := storage.List(ctx, opts.Prefix)
+
+		for {
+			object, err := iter.Next(ctx)
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				return report, err
+			}
+
+			if opts.MaxObjectBytes > 0 && object.Size > opts.MaxObjectBytes {
+				report.Skipped++
+				continue
+			}
+
+			keys = append(keys, object.Key)
+		}
+	}
+
+	var mu sync.Mutex
+
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		seen[key] = true
+	}
+
+	progress := PrewarmProgress{Total: len(seen)}
+
+	done := func(key string, size int64, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err != nil {
+			report.Errors[key] = err
+		} else {
+			report.Objects++
+			report.Bytes += size
+		}
+
+		progress.Key = key
+		progress.Err = err
+		progress.Done++
+		progress.Bytes = report.Bytes
+
+		if opts.OnProgress != nil {
+			opts.OnProgress(progress)
+		}
+	}
+
+	skipped := forEachKey(ctx, keys, opts.Concurrency, func(key string) {
+		body, err := storage.Get(ctx, key)
+		done(key, int64(len(body)), err)
+	})
+
+	for key, err := range skipped {
+		done(key, 0, err)
+	}
+
+	return report, nil
+}
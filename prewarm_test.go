@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// downloadCountingStorage counts the downloads reaching the storage
+type downloadCountingStorage struct {
+	CloudStorage
+	downloads int64
+}
+
+func (ts *downloadCountingStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	atomic.AddInt64(&ts.downloads, 1)
+
+	return ts.CloudStorage.GetWithAttributes(ctx, key, opts...)
+}
+
+func TestPrewarm(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	backend := &downloadCountingStorage{CloudStorage: fake}
+
+	storage, err := NewCachedCloudStorage(backend, CacheOption{MaxObjectBytes: 10})
+	require.NoError(t, err)
+
+	require.NoError(t, fake.Write(ctx, "config/a.json", []byte("{}"), nil))
+	require.NoError(t, fake.Write(ctx, "config/b.json", []byte("[]"), nil))
+	require.NoError(t, fake.Write(ctx, "config/large.bin", make([]byte, 100), nil))
+	require.NoError(t, fake.Write(ctx, "assets/x", []byte("x"), nil))
+
+	var progress []PrewarmProgress
+
+	report, err := Prewarm(ctx, storage, PrewarmOption{
+		Keys:           []string{"assets/x", "config/a.json", "missing"},
+		Prefix:         "config/",
+		MaxObjectBytes: 10,
+		Concurrency:    2,
+		OnProgress: func(p PrewarmProgress) {
+			progress = append(progress, p)
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, report.Objects)
+	require.Equal(t, int64(5), report.Bytes)
+	require.Equal(t, 1, report.Skipped)
+	require.Len(t, report.Errors, 1)
+	require.True(t, errors.Is(report.Errors["missing"], ErrNotFound))
+
+	require.Len(t, progress, 4)
+	require.Equal(t, 4, progress[3].Done)
+	require.Equal(t, 4, progress[3].Total)
+	require.Equal(t, int64(5), progress[3].Bytes)
+
+	downloads := atomic.LoadInt64(&backend.downloads)
+
+	for _, key := range []string{"assets/x", "config/a.json", "config/b.json"} {
+		_, err := storage.Get(ctx, key)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, downloads, atomic.LoadInt64(&backend.downloads))
+}
@@ -0,0 +1,414 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+// DefaultProgressInterval is the default minimum time between two progress reports of a transfer
+const DefaultProgressInterval = time.Second
+
+// Progress reports the state of a transfer
+type Progress struct {
+	// Operation is the name of the transferring operation, e.g. "GetReader" or "DownloadFile"
+	Operation string
+	Key       string
+	// Transferred is the number of bytes read or written so far
+	Transferred int64
+	// Total is the size of the transfer, -1 when it isn't known in advance, e.g. with GetWriter
+	Total int64
+	// BytesPerSecond is the average rate of the transfer since its start
+	BytesPerSecond float64
+	// Done is set in the last report of the transfer, Err being the error which ended it, if any
+	Done bool
+	Err  error
+}
+
+// ProgressFunc receives the progress reports, it's called synchronously by the transfer so it must be fast
+type ProgressFunc func(progress Progress)
+
+// ProgressOption reports the progress of the transfers of a client, e.g. to drive progress bars or log
+// the status of long transfers periodically
+type ProgressOption struct {
+	// OnProgress receives the reports of every transfer. The callback of ContextWithProgress is called as well.
+	OnProgress ProgressFunc
+	// Interval is the minimum time between two reports of a transfer, the last report is always sent.
+	// Defaults to DefaultProgressInterval.
+	Interval time.Duration
+	// Clock returns the current time used for the intervals and the rates, time.Now when it's nil
+	Clock func() time.Time
+}
+
+type progressContextKey struct{}
+
+// ContextWithProgress returns a context carrying a callback receiving the progress of the transfers made
+// with it, in addition to ProgressOption.OnProgress, e.g. for the progress bar of a single download
+func ContextWithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressContextKey{}, fn)
+}
+
+// progressFunc returns the callbacks of the option and of the context together, nil when there's none
+func progressFunc(ctx context.Context, fn ProgressFunc) ProgressFunc {
+	fromContext, _ := ctx.Value(progressContextKey{}).(ProgressFunc)
+
+	switch {
+	case fn == nil:
+		return fromContext
+	case fromContext == nil:
+		return fn
+	default:
+		return func(progress Progress) {
+			fn(progress)
+			fromContext(progress)
+		}
+	}
+}
+
+// progressTracker counts the bytes of a transfer and sends the reports at most once per interval
+type progressTracker struct {
+	mu       sync.Mutex
+	fn       ProgressFunc
+	interval time.Duration
+	clock    func() time.Time
+	progress Progress
+	start    time.Time
+	last     time.Time
+	// base is the number of bytes counted in Transferred which weren't transferred by this call
+	base int64
+}
+
+func newProgressTracker(
+	fn ProgressFunc,
+	interval time.Duration,
+	clock func() time.Time,
+	operation,
+	key string,
+	total int64,
+) *progressTracker {
+	now := clock()
+
+	return &progressTracker{
+		fn:       fn,
+		interval: interval,
+		clock:    clock,
+		progress: Progress{Operation: operation, Key: key, Total: total},
+		start:    now,
+		last:     now,
+	}
+}
+
+// resume counts the bytes already transferred by a previous call
+func (t *progressTracker) resume(n int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.progress.Transferred += n
+	t.base += n
+}
+
+func (t *progressTracker) add(n int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.progress.Done {
+		return
+	}
+
+	t.progress.Transferred += n
+
+	now := t.clock()
+	if now.Sub(t.last) < t.interval {
+		return
+	}
+
+	t.last = now
+	t.report(now)
+}
+
+// finish sends the last report, the following calls are ignored. It does nothing on a nil tracker.
+func (t *progressTracker) finish(err error) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.progress.Done {
+		return
+	}
+
+	t.progress.Done = true
+	t.progress.Err = err
+	t.report(t.clock())
+}
+
+func (t *progressTracker) report(now time.Time) {
+	if elapsed := now.Sub(t.start).Seconds(); elapsed > 0 {
+		t.progress.BytesPerSecond = float64(t.progress.Transferred-t.base) / elapsed
+	}
+
+	t.fn(t.progress)
+}
+
+// progressReader reports the bytes read, the transfer ends at EOF, on error or on Close
+type progressReader struct {
+	io.ReadCloser
+	tracker *progressTracker
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.tracker.add(int64(n))
+
+	if err == io.EOF {
+		r.tracker.finish(nil)
+	} else if err != nil {
+		r.tracker.finish(err)
+	}
+
+	return n, err
+}
+
+func (r *progressReader) Close() error {
+	err := r.ReadCloser.Close()
+	r.tracker.finish(err)
+
+	return err
+}
+
+// progressCounter reports the bytes read without ending the transfer
+type progressCounter struct {
+	io.Reader
+	tracker *progressTracker
+}
+
+func (r *progressCounter) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.tracker.add(int64(n))
+
+	return n, err
+}
+
+// progressWriter reports the bytes written, the transfer ends on Close with the result of the upload
+type progressWriter struct {
+	io.WriteCloser
+	tracker *progressTracker
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteCloser.Write(p)
+	w.tracker.add(int64(n))
+
+	if err != nil {
+		w.tracker.finish(err)
+	}
+
+	return n, err
+}
+
+func (w *progressWriter) Close() error {
+	err := w.WriteCloser.Close()
+	w.tracker.finish(err)
+
+	return err
+}
+
+// progressCloudStorage reports the progress of the transfers of the wrapped CloudStorage
+type progressCloudStorage struct {
+	CloudStorage
+	opts ProgressOption
+}
+
+func newProgressCloudStorage(storage CloudStorage, opts ProgressOption) *progressCloudStorage {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultProgressInterval
+	}
+
+	opts.Clock = clockOrNow(opts.Clock)
+
+	return &progressCloudStorage{CloudStorage: storage, opts: opts}
+}
+
+// track returns the tracker of a transfer, nil when nobody is listening
+func (ts *progressCloudStorage) track(ctx context.Context, operation, key string, total int64) *progressTracker {
+	fn := progressFunc(ctx, ts.opts.OnProgress)
+	if fn == nil {
+		return nil
+	}
+
+	return newProgressTracker(fn, ts.opts.Interval, ts.opts.Clock, operation, key, total)
+}
+
+func (ts *progressCloudStorage) Get(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) ([]byte, error) {
+	tracker := ts.track(ctx, "Get", key, -1)
+	if tracker == nil {
+		return ts.CloudStorage.Get(ctx, key, opts...)
+	}
+
+	// the object is streamed to report the progress of its download
+	reader, attrs, err := ts.CloudStorage.GetWithAttributes(ctx, key)
+	if err != nil {
+		tracker.finish(err)
+		return nil, err
+	}
+
+	tracker.progress.Total = attrs.Size
+
+	body, err := ioutil.ReadAll(&progressReader{ReadCloser: reader, tracker: tracker})
+	reader.Close()
+	tracker.finish(err)
+
+	return body, err
+}
+
+func (ts *progressCloudStorage) GetReader(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	reader, err := ts.CloudStorage.GetReader(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	tracker := ts.track(ctx, "GetReader", key, -1)
+	if tracker == nil {
+		return reader, nil
+	}
+
+	return &progressReader{ReadCloser: reader, tracker: tracker}, nil
+}
+
+func (ts *progressCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	reader, attrs, err := ts.CloudStorage.GetWithAttributes(ctx, key, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tracker := ts.track(ctx, "GetWithAttributes", key, attrs.Size)
+	if tracker == nil {
+		return reader, attrs, nil
+	}
+
+	return &progressReader{ReadCloser: reader, tracker: tracker}, attrs, nil
+}
+
+func (ts *progressCloudStorage) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset,
+	length int64,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	reader, err := ts.CloudStorage.GetRangeReader(ctx, key, offset, length, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	total := length
+	if offset < 0 && (total < 0 || total > -offset) {
+		// the last -offset bytes, fewer when the object is shorter
+		total = -offset
+	}
+
+	if total < 0 {
+		total = -1
+	}
+
+	tracker := ts.track(ctx, "GetRangeReader", key, total)
+	if tracker == nil {
+		return reader, nil
+	}
+
+	return &progressReader{ReadCloser: reader, tracker: tracker}, nil
+}
+
+func (ts *progressCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	// the body is sent by the SDK in one go, so only the end of the transfer is reported
+	err := ts.CloudStorage.Write(ctx, key, body, contentType, opts...)
+
+	if tracker := ts.track(ctx, "Write", key, int64(len(body))); tracker != nil {
+		if err == nil {
+			tracker.progress.Transferred = int64(len(body))
+		}
+
+		tracker.finish(err)
+	}
+
+	return err
+}
+
+func (ts *progressCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	writer, err := ts.CloudStorage.GetWriter(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	tracker := ts.track(ctx, "GetWriter", key, -1)
+	if tracker == nil {
+		return writer, nil
+	}
+
+	return &progressWriter{WriteCloser: writer, tracker: tracker}, nil
+}
+
+func (ts *progressCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	tracker := ts.track(ctx, "Upload", key, -1)
+	if tracker == nil {
+		return ts.CloudStorage.Upload(ctx, key, reader, opts, writeOpts...)
+	}
+
+	// the transfer ends when the upload completes, not when the reader is drained
+	err := ts.CloudStorage.Upload(ctx, key, &progressCounter{Reader: reader, tracker: tracker}, opts, writeOpts...)
+	tracker.finish(err)
+
+	return err
+}
+
+func (ts *progressCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
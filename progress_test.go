@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// progressRecorder records the reports in their order
+type progressRecorder struct {
+	mu      sync.Mutex
+	reports []Progress
+}
+
+func (r *progressRecorder) record(progress Progress) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reports = append(r.reports, progress)
+}
+
+func (r *progressRecorder) last() Progress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.reports[len(r.reports)-1]
+}
+
+func TestProgressCloudStorage(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	recorder := &progressRecorder{}
+	storage := newProgressCloudStorage(NewFakeCloudStorage("bucket"), ProgressOption{
+		OnProgress: recorder.record,
+		Interval:   time.Second,
+		Clock: func() time.Time {
+			// every read takes a second
+			now = now.Add(time.Second)
+			return now
+		},
+	})
+
+	writer, err := storage.GetWriter(ctx, "archive.tar")
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		_, err = writer.Write([]byte("abcd"))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, writer.Close())
+	require.Len(t, recorder.reports, 5)
+	require.Equal(t, Progress{Operation: "GetWriter", Key: "archive.tar", Transferred: 8, Total: -1, BytesPerSecond: 4},
+		recorder.reports[1])
+	require.Equal(t, Progress{
+		Operation: "GetWriter", Key: "archive.tar", Transferred: 16, Total: -1, BytesPerSecond: 3.2, Done: true,
+	}, recorder.last())
+
+	recorder.reports = nil
+
+	body, err := storage.Get(ctx, "archive.tar")
+	require.NoError(t, err)
+	require.Len(t, body, 16)
+
+	last := recorder.last()
+	require.Equal(t, "Get", last.Operation)
+	require.Equal(t, int64(16), last.Transferred)
+	require.Equal(t, int64(16), last.Total)
+	require.True(t, last.Done)
+
+	// the callback of the context is called along with the option
+	var fromContext []Progress
+
+	ctx = ContextWithProgress(ctx, func(progress Progress) { fromContext = append(fromContext, progress) })
+
+	require.NoError(t, storage.Upload(ctx, "upload.txt", strings.NewReader("abcdefgh"), nil))
+	require.Equal(t, recorder.last(), fromContext[len(fromContext)-1])
+	require.Equal(t, int64(8), fromContext[len(fromContext)-1].Transferred)
+	require.True(t, fromContext[len(fromContext)-1].Done)
+
+	_, err = storage.GetReader(ctx, "missing.txt")
+	require.Error(t, err)
+
+	err = storage.Write(ctx, "write.txt", []byte("abc"), nil)
+	require.NoError(t, err)
+	require.Equal(t, "Write", fromContext[len(fromContext)-1].Operation)
+	require.Equal(t, int64(3), fromContext[len(fromContext)-1].Transferred)
+}
+
+func TestDownloadFileProgress(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "download")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	fake := NewFakeCloudStorage("bucket")
+	storage := &flakyRangeStorage{CloudStorage: fake, failing: true, from: 8}
+	path := filepath.Join(dir, "archive.tar")
+	recorder := &progressRecorder{}
+	opts := &DownloadOption{PartSize: 4, Progress: recorder.record}
+
+	require.NoError(t, fake.Write(ctx, "archive.tar", []byte("abcdefghij"), nil))
+
+	_, err = DownloadFile(ctx, storage, "archive.tar", path, opts)
+	require.Error(t, err)
+	require.True(t, recorder.last().Done)
+	require.Equal(t, int64(8), recorder.last().Transferred)
+	require.Error(t, recorder.last().Err)
+
+	storage.failing = false
+	recorder.reports = nil
+
+	_, err = DownloadFile(ctx, storage, "archive.tar", path, opts)
+	require.NoError(t, err)
+
+	last := recorder.last()
+	require.Equal(t, "DownloadFile", last.Operation)
+	require.Equal(t, int64(10), last.Transferred)
+	require.Equal(t, int64(10), last.Total)
+	require.True(t, last.Done)
+	require.NoError(t, last.Err)
+}
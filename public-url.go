@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const gcpPublicHost = "storage.googleapis.com"
+
+// escapeKey escapes every segment of the key, keeping the "/" separators
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func awsPublicURL(
+	s3Endpoint string,
+	s3Region string,
+	bucketName string,
+	accelerate bool,
+	fips bool,
+	key string,
+) string {
+	// custom endpoints (localstack, minio, etc.) are always used path-style
+	if s3Endpoint != "" {
+		return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(s3Endpoint, "/"), bucketName, escapeKey(key))
+	}
+
+	host := fmt.Sprintf("s3.%s.amazonaws.com", s3Region)
+	if fips {
+		host = awsFIPSHost("s3", s3Region)
+	}
+
+	// virtual-hosted style doesn't work with TLS for bucket names containing dots
+	if strings.Contains(bucketName, ".") {
+		return fmt.Sprintf("https://%s/%s/%s", host, bucketName, escapeKey(key))
+	}
+
+	if accelerate {
+		return fmt.Sprintf("https://%s.s3-accelerate.amazonaws.com/%s", bucketName, escapeKey(key))
+	}
+
+	return fmt.Sprintf("https://%s.%s/%s", bucketName, host, escapeKey(key))
+}
+
+func gcpPublicURL(
+	scheme string,
+	host string,
+	bucketName string,
+	key string,
+) string {
+	return fmt.Sprintf("%s://%s/%s/%s", scheme, host, bucketName, escapeKey(key))
+}
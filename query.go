@@ -0,0 +1,830 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// QueryInputFormat is the format of the objects scanned by Query
+type QueryInputFormat string
+
+const (
+	// QueryCSV is a CSV object whose first line names the columns
+	QueryCSV QueryInputFormat = "csv"
+	// QueryJSON is a JSON Lines object, one JSON object per line
+	QueryJSON QueryInputFormat = "json"
+)
+
+// ErrInvalidQuery is matched by errors.Is when the SQL of Query can't be parsed
+var ErrInvalidQuery = errors.New("invalid query")
+
+func awsQuery(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	key string,
+	sql string,
+	format QueryInputFormat,
+) (io.ReadCloser, error) {
+	input := &s3.InputSerialization{}
+
+	switch format {
+	case QueryCSV:
+		input.CSV = &s3.CSVInput{FileHeaderInfo: aws.String(s3.FileHeaderInfoUse)}
+	case QueryJSON:
+		input.JSON = &s3.JSONInput{Type: aws.String(s3.JSONTypeLines)}
+	default:
+		return nil, fmt.Errorf("unknown query input format %q", format)
+	}
+
+	response, err := client.SelectObjectContentWithContext(ctx, &s3.SelectObjectContentInput{
+		Bucket:             aws.String(bucketName),
+		Key:                aws.String(awsEscapeKey(key)),
+		Expression:         aws.String(sql),
+		ExpressionType:     aws.String(s3.ExpressionTypeSql),
+		InputSerialization: input,
+		OutputSerialization: &s3.OutputSerialization{
+			JSON: &s3.JSONOutput{RecordDelimiter: aws.String("\n")},
+		},
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	stream := response.EventStream
+	reader, writer := io.Pipe()
+
+	go func() {
+		for event := range stream.Events() {
+			if records, ok := event.(*s3.RecordsEvent); ok {
+				if _, err := writer.Write(records.Payload); err != nil {
+					// the reader is closed
+					stream.Close()
+					return
+				}
+			}
+		}
+
+		writer.CloseWithError(translateError(stream.Err()))
+	}()
+
+	return &queryReader{PipeReader: reader, close: stream.Close}, nil
+}
+
+// queryReader stops the query when it's closed
+type queryReader struct {
+	*io.PipeReader
+	close func() error
+}
+
+func (r *queryReader) Close() error {
+	r.PipeReader.Close()
+
+	return r.close()
+}
+
+// queryObject runs the query on the client side, streaming the object, for the providers without S3 Select.
+// It supports SELECT with columns or *, FROM S3Object with an alias, WHERE with comparisons, IS [NOT] NULL,
+// AND, OR, NOT and parentheses, and LIMIT. The values are compared as numbers when both sides are numbers,
+// so the CSV columns don't need a CAST, as strings otherwise.
+func queryObject(
+	ctx context.Context,
+	storage CloudStorage,
+	key string,
+	sql string,
+	format QueryInputFormat,
+) (io.ReadCloser, error) {
+	query, err := parseQuery(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	var next func(reader io.Reader) (func() (queryRecord, error), error)
+
+	switch format {
+	case QueryCSV:
+		next = csvQueryRecords
+	case QueryJSON:
+		next = jsonQueryRecords
+	default:
+		return nil, fmt.Errorf("unknown query input format %q", format)
+	}
+
+	object, err := storage.GetReader(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, writer := io.Pipe()
+
+	go func() {
+		defer object.Close()
+
+		writer.CloseWithError(query.run(object, next, writer))
+	}()
+
+	return &queryReader{PipeReader: reader, close: object.Close}, nil
+}
+
+// queryField is a field of a record, the records keep the order of their fields
+type queryField struct {
+	name  string
+	value interface{}
+}
+
+type queryRecord []queryField
+
+// get returns the field named name, or at the position _1, _2...
+func (r queryRecord) get(name string) (interface{}, bool) {
+	for _, field := range r {
+		if field.name == name {
+			return field.value, true
+		}
+	}
+
+	if strings.HasPrefix(name, "_") {
+		if position, err := strconv.Atoi(name[1:]); err == nil && position >= 1 && position <= len(r) {
+			return r[position-1].value, true
+		}
+	}
+
+	return nil, false
+}
+
+func (r queryRecord) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+
+	for i, field := range r {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		name, err := json.Marshal(field.name)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// csvQueryRecords reads the records of a CSV object, named by the header
+func csvQueryRecords(reader io.Reader) (func() (queryRecord, error), error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return func() (queryRecord, error) { return nil, io.EOF }, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return func() (queryRecord, error) {
+		line, err := csvReader.Read()
+		if err != nil {
+			return nil, err
+		}
+
+		record := make(queryRecord, 0, len(line))
+
+		for i, value := range line {
+			name := "_" + strconv.Itoa(i+1)
+			if i < len(header) {
+				name = header[i]
+			}
+
+			record = append(record, queryField{name: name, value: value})
+		}
+
+		return record, nil
+	}, nil
+}
+
+// jsonQueryRecords reads the records of a JSON Lines object
+func jsonQueryRecords(reader io.Reader) (func() (queryRecord, error), error) {
+	decoder := json.NewDecoder(bufio.NewReader(reader))
+	decoder.UseNumber()
+
+	return func() (queryRecord, error) {
+		var object map[string]interface{}
+		if err := decoder.Decode(&object); err != nil {
+			return nil, err
+		}
+
+		record := make(queryRecord, 0, len(object))
+		for _, name := range sortedKeys(object) {
+			record = append(record, queryField{name: name, value: object[name]})
+		}
+
+		return record, nil
+	}, nil
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// query is a parsed SELECT
+type query struct {
+	// columns are the selected paths, nil for *
+	columns [][]string
+	where   queryExpr
+	limit   int64
+}
+
+func (q *query) run(
+	object io.Reader,
+	next func(reader io.Reader) (func() (queryRecord, error), error),
+	writer io.Writer,
+) error {
+	records, err := next(object)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(writer)
+
+	for count := int64(0); q.limit < 0 || count < q.limit; {
+		record, err := records()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if q.where != nil && q.where.eval(record) != true {
+			continue
+		}
+
+		if err := encoder.Encode(q.project(record)); err != nil {
+			return err
+		}
+
+		count++
+	}
+
+	return nil
+}
+
+func (q *query) project(record queryRecord) queryRecord {
+	if q.columns == nil {
+		return record
+	}
+
+	projected := make(queryRecord, 0, len(q.columns))
+
+	for _, path := range q.columns {
+		value := lookupPath(record, path)
+		projected = append(projected, queryField{name: path[len(path)-1], value: value})
+	}
+
+	return projected
+}
+
+// lookupPath returns the value of the path in the record, nil when it's missing
+func lookupPath(record queryRecord, path []string) interface{} {
+	value, ok := record.get(path[0])
+	if !ok {
+		return nil
+	}
+
+	for _, name := range path[1:] {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		value = object[name]
+	}
+
+	return value
+}
+
+// queryExpr is an expression of the WHERE clause
+type queryExpr interface {
+	eval(record queryRecord) interface{}
+}
+
+type queryLiteral struct {
+	value interface{}
+}
+
+func (e queryLiteral) eval(queryRecord) interface{} {
+	return e.value
+}
+
+type queryPath []string
+
+func (e queryPath) eval(record queryRecord) interface{} {
+	return lookupPath(record, e)
+}
+
+type queryLogical struct {
+	op          string
+	left, right queryExpr
+}
+
+func (e queryLogical) eval(record queryRecord) interface{} {
+	left := e.left.eval(record) == true
+
+	if e.op == "AND" {
+		return left && e.right.eval(record) == true
+	}
+
+	return left || e.right.eval(record) == true
+}
+
+type queryNot struct {
+	expr queryExpr
+}
+
+func (e queryNot) eval(record queryRecord) interface{} {
+	return e.expr.eval(record) != true
+}
+
+type queryIsNull struct {
+	expr   queryExpr
+	negate bool
+}
+
+func (e queryIsNull) eval(record queryRecord) interface{} {
+	return (e.expr.eval(record) == nil) != e.negate
+}
+
+type queryComparison struct {
+	op          string
+	left, right queryExpr
+}
+
+func (e queryComparison) eval(record queryRecord) interface{} {
+	left, right := e.left.eval(record), e.right.eval(record)
+	if left == nil || right == nil {
+		return false
+	}
+
+	var cmp int
+
+	leftNumber, leftOK := queryNumber(left)
+	rightNumber, rightOK := queryNumber(right)
+
+	switch {
+	case leftOK && rightOK:
+		switch {
+		case leftNumber < rightNumber:
+			cmp = -1
+		case leftNumber > rightNumber:
+			cmp = 1
+		}
+	default:
+		cmp = strings.Compare(fmt.Sprint(left), fmt.Sprint(right))
+	}
+
+	switch e.op {
+	case "=":
+		return cmp == 0
+	case "!=", "<>":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+// queryNumber returns the value as a number, if it's a number or a string holding one
+func queryNumber(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case json.Number:
+		number, err := value.Float64()
+		return number, err == nil
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return number, err == nil
+	}
+
+	return 0, false
+}
+
+// queryToken is a token of the SQL, kind is one of ident, string, number and symbol
+type queryToken struct {
+	kind  string
+	value string
+}
+
+func tokenizeQuery(sql string) ([]queryToken, error) {
+	var tokens []queryToken
+
+	runes := []rune(sql)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'':
+			var value strings.Builder
+
+			for i++; ; i++ {
+				if i >= len(runes) {
+					return nil, fmt.Errorf("%w: unterminated string", ErrInvalidQuery)
+				}
+
+				// a quote is escaped by doubling it
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						i++
+					} else {
+						break
+					}
+				}
+
+				value.WriteRune(runes[i])
+			}
+
+			tokens = append(tokens, queryToken{kind: "string", value: value.String()})
+			i++
+		case r == '"':
+			end := strings.IndexRune(string(runes[i+1:]), '"')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated identifier", ErrInvalidQuery)
+			}
+
+			name := string(runes[i+1:])[:end]
+			tokens = append(tokens, queryToken{kind: "quoted", value: name})
+			i += len([]rune(name)) + 2
+		case unicode.IsDigit(r) || r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			start := i
+
+			for i++; i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.'); i++ {
+			}
+
+			tokens = append(tokens, queryToken{kind: "number", value: string(runes[start:i])})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+
+			for i++; i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_'); i++ {
+			}
+
+			tokens = append(tokens, queryToken{kind: "ident", value: string(runes[start:i])})
+		default:
+			symbol := string(r)
+
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "!=", "<>", "<=", ">=":
+					symbol = two
+				}
+			}
+
+			if !strings.Contains("*,.()=<>!", symbol[:1]) || symbol == "!" {
+				return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidQuery, symbol)
+			}
+
+			tokens = append(tokens, queryToken{kind: "symbol", value: symbol})
+			i += len(symbol)
+		}
+	}
+
+	return tokens, nil
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+	alias  string
+}
+
+func (p *queryParser) peek() queryToken {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}
+	}
+
+	return p.tokens[p.pos]
+}
+
+// keyword consumes the next token if it's the keyword, case insensitively
+func (p *queryParser) keyword(keyword string) bool {
+	token := p.peek()
+	if token.kind == "ident" && strings.EqualFold(token.value, keyword) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *queryParser) symbol(symbol string) bool {
+	token := p.peek()
+	if token.kind == "symbol" && token.value == symbol {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidQuery, fmt.Sprintf(format, args...))
+}
+
+func parseQuery(sql string) (*query, error) {
+	tokens, err := tokenizeQuery(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+	q := &query{limit: -1}
+
+	if !p.keyword("SELECT") {
+		return nil, p.errorf("expected SELECT")
+	}
+
+	var columns [][]string
+
+	if !p.symbol("*") {
+		for {
+			path, err := p.path()
+			if err != nil {
+				return nil, err
+			}
+
+			columns = append(columns, path)
+
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+
+	if !p.keyword("FROM") || !p.keyword("S3Object") {
+		return nil, p.errorf("expected FROM S3Object")
+	}
+
+	p.keyword("AS")
+
+	if token := p.peek(); token.kind == "ident" && !isQueryKeyword(token.value) {
+		p.alias = token.value
+		p.pos++
+	}
+
+	// the columns are parsed before the alias is known
+	for i, path := range columns {
+		if len(path) > 1 && path[0] == p.alias {
+			columns[i] = path[1:]
+		}
+	}
+
+	q.columns = columns
+
+	if p.keyword("WHERE") {
+		if q.where, err = p.or(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.keyword("LIMIT") {
+		token := p.peek()
+
+		limit, err := strconv.ParseInt(token.value, 10, 64)
+		if token.kind != "number" || err != nil || limit < 0 {
+			return nil, p.errorf("invalid LIMIT %q", token.value)
+		}
+
+		q.limit = limit
+		p.pos++
+	}
+
+	if p.pos < len(p.tokens) {
+		return nil, p.errorf("unexpected %q", p.peek().value)
+	}
+
+	return q, nil
+}
+
+func isQueryKeyword(value string) bool {
+	switch strings.ToUpper(value) {
+	case "WHERE", "LIMIT", "AND", "OR", "NOT", "IS", "NULL", "TRUE", "FALSE", "AS":
+		return true
+	}
+
+	return false
+}
+
+// path parses a column, e.g. s.address.city or _1
+func (p *queryParser) path() ([]string, error) {
+	var path []string
+
+	for {
+		token := p.peek()
+		if token.kind != "ident" && token.kind != "quoted" {
+			return nil, p.errorf("expected a column, got %q", token.value)
+		}
+
+		path = append(path, token.value)
+		p.pos++
+
+		if !p.symbol(".") {
+			break
+		}
+	}
+
+	if p.alias != "" && len(path) > 1 && path[0] == p.alias {
+		path = path[1:]
+	}
+
+	return path, nil
+}
+
+func (p *queryParser) or() (queryExpr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("OR") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+
+		left = queryLogical{op: "OR", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) and() (queryExpr, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("AND") {
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+
+		left = queryLogical{op: "AND", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) not() (queryExpr, error) {
+	if p.keyword("NOT") {
+		expr, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+
+		return queryNot{expr: expr}, nil
+	}
+
+	return p.comparison()
+}
+
+func (p *queryParser) comparison() (queryExpr, error) {
+	if p.symbol("(") {
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.symbol(")") {
+			return nil, p.errorf("expected )")
+		}
+
+		return expr, nil
+	}
+
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.keyword("IS") {
+		negate := p.keyword("NOT")
+
+		if !p.keyword("NULL") {
+			return nil, p.errorf("expected NULL")
+		}
+
+		return queryIsNull{expr: left, negate: negate}, nil
+	}
+
+	token := p.peek()
+
+	switch token.value {
+	case "=", "!=", "<>", "<", "<=", ">", ">=":
+		if token.kind != "symbol" {
+			break
+		}
+
+		p.pos++
+
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+
+		return queryComparison{op: token.value, left: left, right: right}, nil
+	}
+
+	// a bare operand is a boolean, e.g. WHERE s.active
+	return left, nil
+}
+
+func (p *queryParser) operand() (queryExpr, error) {
+	token := p.peek()
+
+	switch {
+	case token.kind == "string":
+		p.pos++
+		return queryLiteral{value: token.value}, nil
+	case token.kind == "number":
+		number, err := strconv.ParseFloat(token.value, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", token.value)
+		}
+
+		p.pos++
+
+		return queryLiteral{value: number}, nil
+	case p.keyword("TRUE"):
+		return queryLiteral{value: true}, nil
+	case p.keyword("FALSE"):
+		return queryLiteral{value: false}, nil
+	case p.keyword("NULL"):
+		return queryLiteral{value: nil}, nil
+	}
+
+	path, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+
+	return queryPath(path), nil
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func runQuery(t *testing.T, storage CloudStorage, key string, sql string, format QueryInputFormat) string {
+	reader, err := storage.Query(context.Background(), key, sql, format)
+	require.NoError(t, err)
+
+	defer reader.Close()
+
+	result, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+
+	return string(result)
+}
+
+func TestQueryCSV(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.Write(ctx, "users.csv", []byte("name,age,country\nalice,34,FR\nbob,9,US\ncarol,41,US\n"), nil))
+
+	require.Equal(t,
+		`{"name":"alice","age":"34","country":"FR"}`+"\n"+`{"name":"bob","age":"9","country":"US"}`+"\n"+
+			`{"name":"carol","age":"41","country":"US"}`+"\n",
+		runQuery(t, storage, "users.csv", "SELECT * FROM S3Object", QueryCSV))
+
+	// the numbers are compared as numbers, not as strings
+	require.Equal(t,
+		`{"name":"alice"}`+"\n"+`{"name":"carol"}`+"\n",
+		runQuery(t, storage, "users.csv", "select s.name from s3object s where s.age > 10", QueryCSV))
+
+	require.Equal(t,
+		`{"_1":"carol","country":"US"}`+"\n",
+		runQuery(t, storage, "users.csv",
+			"SELECT s._1, s.country FROM S3Object AS s WHERE s.country = 'US' AND NOT (s.age < 18 OR s.name = 'dave')",
+			QueryCSV))
+
+	require.Equal(t,
+		`{"name":"alice"}`+"\n",
+		runQuery(t, storage, "users.csv", "SELECT name FROM S3Object LIMIT 1", QueryCSV))
+}
+
+func TestQueryJSON(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	body := `{"id":1,"active":true,"address":{"city":"Paris"}}
+{"id":2,"active":false,"address":{"city":"Lyon"},"note":"it's"}
+{"id":3,"active":true}
+`
+	require.NoError(t, storage.Write(ctx, "events.json", []byte(body), nil))
+
+	require.Equal(t,
+		`{"id":1,"city":"Paris"}`+"\n"+`{"id":3,"city":null}`+"\n",
+		runQuery(t, storage, "events.json", "SELECT e.id, e.address.city FROM S3Object e WHERE e.active", QueryJSON))
+
+	require.Equal(t,
+		`{"id":2}`+"\n",
+		runQuery(t, storage, "events.json", "SELECT id FROM S3Object WHERE note = 'it''s' AND address IS NOT NULL", QueryJSON))
+
+	require.Equal(t,
+		`{"id":3}`+"\n",
+		runQuery(t, storage, "events.json", "SELECT id FROM S3Object WHERE address IS NULL", QueryJSON))
+}
+
+func TestQueryErrors(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.Write(ctx, "a.csv", []byte("a\n1\n"), nil))
+
+	for _, sql := range []string{
+		"DELETE FROM S3Object",
+		"SELECT a FROM table",
+		"SELECT a FROM S3Object WHERE a = 'unterminated",
+		"SELECT a FROM S3Object WHERE (a = 1",
+		"SELECT a FROM S3Object LIMIT many",
+		"SELECT a FROM S3Object WHERE a ; 1",
+	} {
+		_, err := storage.Query(ctx, "a.csv", sql, QueryCSV)
+		require.True(t, errors.Is(err, ErrInvalidQuery), sql)
+	}
+
+	_, err := storage.Query(ctx, "a.csv", "SELECT * FROM S3Object", QueryInputFormat("parquet"))
+	require.Error(t, err)
+
+	_, err = storage.Query(ctx, "missing.csv", "SELECT * FROM S3Object", QueryCSV)
+	require.True(t, errors.Is(err, ErrNotFound))
+}
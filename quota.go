@@ -0,0 +1,590 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultQuotaReconcileInterval is the age of the usage of a prefix after which it's listed again,
+// when QuotaOption.ReconcileInterval is not set
+const DefaultQuotaReconcileInterval = 10 * time.Minute
+
+// ErrQuotaExceeded is matched by errors.Is when a write exceeds the quota of a prefix
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaExceededError gives the quota exceeded by a write
+type QuotaExceededError struct {
+	Key   string
+	Quota Quota
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("object '%s' exceeds the quota of '%s' (%d bytes, %d objects)",
+		e.Key, e.Quota.Prefix, e.Quota.MaxBytes, e.Quota.MaxObjects)
+}
+
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// Quota limits the objects under a prefix, e.g. the prefix of a tenant. A zero limit is unlimited.
+type Quota struct {
+	Prefix     string
+	MaxBytes   int64
+	MaxObjects int64
+}
+
+// QuotaUsage is the usage of a prefix
+type QuotaUsage struct {
+	Bytes   int64
+	Objects int64
+}
+
+// QuotaOption configures the quotas of the prefixes
+type QuotaOption struct {
+	// Quotas are all applied to the keys under their prefix, so nested prefixes can have their own quota
+	Quotas []Quota
+	// ReconcileInterval is the age of the usage of a prefix after which it's listed again, to account for
+	// the writes of the other replicas and the failed tracking. Defaults to DefaultQuotaReconcileInterval.
+	ReconcileInterval time.Duration
+	// Clock returns the current time used to age the usages, time.Now when it's nil
+	Clock func() time.Time
+}
+
+type quotaUsage struct {
+	QuotaUsage
+	reconciledAt time.Time
+}
+
+// QuotaCloudStorage rejects the writes exceeding the quota of their prefix with a *QuotaExceededError.
+// The usage of a prefix is listed on its first write, then maintained on every write and delete,
+// and listed again every ReconcileInterval. The usage is tracked per instance, so the writes made meanwhile by
+// the other replicas are only accounted for by the next listing. The streamed writes running concurrently
+// are checked against the usage at their start, so they can exceed the quota together. The parts of the upload
+// sessions exceeding the quota are rejected, and their size is reserved when the session is completed.
+type QuotaCloudStorage struct {
+	CloudStorage
+	opts QuotaOption
+
+	mu     sync.Mutex
+	usages map[string]*quotaUsage
+}
+
+// NewQuotaCloudStorage returns the storage enforcing the quotas
+func NewQuotaCloudStorage(storage CloudStorage, opts QuotaOption) *QuotaCloudStorage {
+	if opts.ReconcileInterval <= 0 {
+		opts.ReconcileInterval = DefaultQuotaReconcileInterval
+	}
+
+	if opts.Clock == nil {
+		opts.Clock = time.Now
+	}
+
+	return &QuotaCloudStorage{
+		CloudStorage: storage,
+		opts:         opts,
+		usages:       make(map[string]*quotaUsage),
+	}
+}
+
+// Usage returns the tracked usage of the prefix of a quota, it's listed when it's unknown or outdated
+func (ts *QuotaCloudStorage) Usage(ctx context.Context, prefix string) (QuotaUsage, error) {
+	for _, quota := range ts.opts.Quotas {
+		if quota.Prefix != prefix {
+			continue
+		}
+
+		if err := ts.reconcile(ctx, []Quota{quota}, false); err != nil {
+			return QuotaUsage{}, err
+		}
+
+		ts.mu.Lock()
+		defer ts.mu.Unlock()
+
+		return ts.usages[prefix].QuotaUsage, nil
+	}
+
+	return QuotaUsage{}, fmt.Errorf("no quota for the prefix '%s'", prefix)
+}
+
+// Reconcile lists the prefixes of all the quotas again
+func (ts *QuotaCloudStorage) Reconcile(ctx context.Context) error {
+	return ts.reconcile(ctx, ts.opts.Quotas, true)
+}
+
+// reconcile lists the prefixes of the quotas whose usage is unknown or outdated, or all of them with force
+func (ts *QuotaCloudStorage) reconcile(ctx context.Context, quotas []Quota, force bool) error {
+	for _, quota := range quotas {
+		now := ts.opts.Clock()
+
+		ts.mu.Lock()
+		usage, ok := ts.usages[quota.Prefix]
+		upToDate := ok && now.Sub(usage.reconciledAt) < ts.opts.ReconcileInterval
+		ts.mu.Unlock()
+
+		if upToDate && !force {
+			continue
+		}
+
+		objects, err := listByName(ctx, ts.CloudStorage, quota.Prefix)
+		if err != nil {
+			return err
+		}
+
+		usage = &quotaUsage{QuotaUsage: QuotaUsage{Objects: int64(len(objects))}, reconciledAt: now}
+		for _, object := range objects {
+			usage.Bytes += object.Size
+		}
+
+		ts.mu.Lock()
+		ts.usages[quota.Prefix] = usage
+		ts.mu.Unlock()
+	}
+
+	return nil
+}
+
+// quotas returns the quotas applied to the key
+func (ts *QuotaCloudStorage) quotas(key string) []Quota {
+	var quotas []Quota
+
+	for _, quota := range ts.opts.Quotas {
+		if strings.HasPrefix(key, quota.Prefix) {
+			quotas = append(quotas, quota)
+		}
+	}
+
+	return quotas
+}
+
+// objectSize returns the size of the object, or -1 when it doesn't exist
+func (ts *QuotaCloudStorage) objectSize(ctx context.Context, key string) (int64, error) {
+	attrs, err := ts.CloudStorage.Attributes(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		return -1, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return attrs.Size, nil
+}
+
+// reserve adds the delta to the usages of the quotas, unless it exceeds one of them
+func (ts *QuotaCloudStorage) reserve(key string, quotas []Quota, delta QuotaUsage) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	for _, quota := range quotas {
+		usage := ts.usages[quota.Prefix]
+
+		if delta.Bytes > 0 && quota.MaxBytes > 0 && usage.Bytes+delta.Bytes > quota.MaxBytes ||
+			delta.Objects > 0 && quota.MaxObjects > 0 && usage.Objects+delta.Objects > quota.MaxObjects {
+			return &QuotaExceededError{Key: key, Quota: quota}
+		}
+	}
+
+	for _, quota := range quotas {
+		ts.usages[quota.Prefix].Bytes += delta.Bytes
+		ts.usages[quota.Prefix].Objects += delta.Objects
+	}
+
+	return nil
+}
+
+// add adds the delta to the usages of the quotas without checking them
+func (ts *QuotaCloudStorage) add(quotas []Quota, delta QuotaUsage) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	for _, quota := range quotas {
+		ts.usages[quota.Prefix].Bytes += delta.Bytes
+		ts.usages[quota.Prefix].Objects += delta.Objects
+	}
+}
+
+// remaining returns the bytes which can be written to the key, whose previous size is given,
+// and the quota limiting them
+func (ts *QuotaCloudStorage) remaining(quotas []Quota, previousSize int64) (int64, Quota) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	remaining, limiting := int64(math.MaxInt64), quotas[0]
+
+	for _, quota := range quotas {
+		if quota.MaxBytes <= 0 {
+			continue
+		}
+
+		if left := quota.MaxBytes - ts.usages[quota.Prefix].Bytes + max64(previousSize, 0); left < remaining {
+			remaining, limiting = left, quota
+		}
+	}
+
+	return remaining, limiting
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+// prepare returns the quotas of the key with their usage up to date, and the current size of the object
+func (ts *QuotaCloudStorage) prepare(ctx context.Context, key string) ([]Quota, int64, error) {
+	quotas := ts.quotas(key)
+	if len(quotas) == 0 {
+		return nil, 0, nil
+	}
+
+	if err := ts.reconcile(ctx, quotas, false); err != nil {
+		return nil, 0, err
+	}
+
+	size, err := ts.objectSize(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return quotas, size, nil
+}
+
+// writeDelta returns the change of usage when an object of the previous size is replaced with the new size
+func writeDelta(previousSize, size int64) QuotaUsage {
+	if previousSize < 0 {
+		return QuotaUsage{Bytes: size, Objects: 1}
+	}
+
+	return QuotaUsage{Bytes: size - previousSize}
+}
+
+func (ts *QuotaCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	quotas, previousSize, err := ts.prepare(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	delta := writeDelta(previousSize, int64(len(body)))
+
+	if err := ts.reserve(key, quotas, delta); err != nil {
+		return err
+	}
+
+	if err := ts.CloudStorage.Write(ctx, key, body, contentType, opts...); err != nil {
+		ts.add(quotas, QuotaUsage{Bytes: -delta.Bytes, Objects: -delta.Objects})
+		return err
+	}
+
+	return nil
+}
+
+// GetWriter aborts the write as soon as the quota is exceeded, so no partial object is stored
+func (ts *QuotaCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	quotas, previousSize, err := ts.prepare(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(quotas) == 0 {
+		return ts.CloudStorage.GetWriter(ctx, key, opts...)
+	}
+
+	delta := writeDelta(previousSize, 0)
+
+	if err := ts.reserve(key, quotas, QuotaUsage{Objects: delta.Objects}); err != nil {
+		return nil, err
+	}
+
+	// the writer is aborted by cancelling its context before closing it
+	ctx, cancel := context.WithCancel(ctx)
+
+	writer, err := ts.CloudStorage.GetWriter(ctx, key, opts...)
+	if err != nil {
+		cancel()
+		ts.add(quotas, QuotaUsage{Objects: -delta.Objects})
+
+		return nil, err
+	}
+
+	remaining, limiting := ts.remaining(quotas, previousSize)
+
+	return &quotaWriter{
+		sizeLimitedWriter: sizeLimitedWriter{
+			WriteCloser: writer,
+			cancel:      cancel,
+			err:         &QuotaExceededError{Key: key, Quota: limiting},
+			remaining:   remaining,
+		},
+		storage:      ts,
+		quotas:       quotas,
+		limit:        remaining,
+		previousSize: previousSize,
+		objects:      delta.Objects,
+	}, nil
+}
+
+type quotaWriter struct {
+	sizeLimitedWriter
+	storage      *QuotaCloudStorage
+	quotas       []Quota
+	limit        int64
+	previousSize int64
+	objects      int64
+}
+
+func (w *quotaWriter) Close() error {
+	if err := w.sizeLimitedWriter.Close(); err != nil {
+		w.storage.add(w.quotas, QuotaUsage{Objects: -w.objects})
+		return err
+	}
+
+	w.storage.add(w.quotas, QuotaUsage{Bytes: writeDelta(w.previousSize, w.limit-w.remaining).Bytes})
+
+	return nil
+}
+
+// Upload aborts the upload as soon as the quota is exceeded, so no partial object is stored
+func (ts *QuotaCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	quotas, previousSize, err := ts.prepare(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if len(quotas) == 0 {
+		return ts.CloudStorage.Upload(ctx, key, reader, opts, writeOpts...)
+	}
+
+	delta := writeDelta(previousSize, 0)
+
+	if err := ts.reserve(key, quotas, QuotaUsage{Objects: delta.Objects}); err != nil {
+		return err
+	}
+
+	remaining, limiting := ts.remaining(quotas, previousSize)
+	limited := &sizeLimitedReader{
+		Reader:    reader,
+		err:       &QuotaExceededError{Key: key, Quota: limiting},
+		remaining: remaining,
+	}
+
+	if err := ts.CloudStorage.Upload(ctx, key, limited, opts, writeOpts...); err != nil {
+		ts.add(quotas, QuotaUsage{Objects: -delta.Objects})
+		return err
+	}
+
+	ts.add(quotas, QuotaUsage{Bytes: writeDelta(previousSize, remaining-limited.remaining).Bytes})
+
+	return nil
+}
+
+func (ts *QuotaCloudStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	session, err := ts.CloudStorage.BeginUpload(ctx, key, opts)
+	if err != nil || len(ts.quotas(key)) == 0 {
+		return session, err
+	}
+
+	return ts.guardUpload(session), nil
+}
+
+func (ts *QuotaCloudStorage) ResumeUpload(
+	ctx context.Context,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	session, err := ts.CloudStorage.ResumeUpload(ctx, state)
+	if err != nil || len(ts.quotas(state.Key)) == 0 {
+		return session, err
+	}
+
+	return ts.guardUpload(session), nil
+}
+
+// guardUpload rejects the parts exceeding the quotas, and reserves the size of the object when it's completed
+func (ts *QuotaCloudStorage) guardUpload(session *UploadSession) *UploadSession {
+	return guardUploadSession(session, func(ctx context.Context, state *UploadSessionState, number int, body []byte) error {
+		quotas, previousSize, err := ts.prepare(ctx, state.Key)
+		if err != nil {
+			return err
+		}
+
+		if remaining, limiting := ts.remaining(quotas, previousSize); uploadedSize(state, number, body) > remaining {
+			return &QuotaExceededError{Key: state.Key, Quota: limiting}
+		}
+
+		return nil
+	}, func(ctx context.Context, state *UploadSessionState, complete func() error) error {
+		quotas, previousSize, err := ts.prepare(ctx, state.Key)
+		if err != nil {
+			return err
+		}
+
+		delta := writeDelta(previousSize, uploadedSize(state, 0, nil))
+
+		if err := ts.reserve(state.Key, quotas, delta); err != nil {
+			return err
+		}
+
+		if err := complete(); err != nil {
+			ts.add(quotas, QuotaUsage{Bytes: -delta.Bytes, Objects: -delta.Objects})
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (ts *QuotaCloudStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	quotas, previousSize, err := ts.prepare(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if err := ts.CloudStorage.Delete(ctx, key); err != nil {
+		return err
+	}
+
+	if previousSize >= 0 {
+		ts.add(quotas, QuotaUsage{Bytes: -previousSize, Objects: -1})
+	}
+
+	return nil
+}
+
+func (ts *QuotaCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	quotas, previousSize, err := ts.prepare(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	delta := writeDelta(previousSize, int64(len(body)))
+
+	if err := ts.reserve(key, quotas, delta); err != nil {
+		return "", err
+	}
+
+	generation, err := ts.CloudStorage.WriteIf(ctx, key, body, contentType, condition)
+	if err != nil {
+		ts.add(quotas, QuotaUsage{Bytes: -delta.Bytes, Objects: -delta.Objects})
+		return "", err
+	}
+
+	return generation, nil
+}
+
+func (ts *QuotaCloudStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	quotas, previousSize, err := ts.prepare(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if err := ts.CloudStorage.DeleteIf(ctx, key, generation); err != nil {
+		return err
+	}
+
+	if previousSize >= 0 {
+		ts.add(quotas, QuotaUsage{Bytes: -previousSize, Objects: -1})
+	}
+
+	return nil
+}
+
+// Copy reserves the size of the source in the quotas of the destination
+func (ts *QuotaCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	quotas, previousSize, err := ts.prepare(ctx, dstKey)
+	if err != nil {
+		return err
+	}
+
+	var delta QuotaUsage
+
+	if len(quotas) > 0 {
+		size, err := ts.objectSize(ctx, srcKey)
+		if err != nil {
+			return err
+		}
+
+		if size < 0 {
+			return ErrNotFound
+		}
+
+		delta = writeDelta(previousSize, size)
+
+		if err := ts.reserve(dstKey, quotas, delta); err != nil {
+			return err
+		}
+	}
+
+	if err := ts.CloudStorage.Copy(ctx, srcKey, dstKey, opts); err != nil {
+		ts.add(quotas, QuotaUsage{Bytes: -delta.Bytes, Objects: -delta.Objects})
+		return err
+	}
+
+	return nil
+}
+
+// Flush flushes the wrapped storage
+func (ts *QuotaCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
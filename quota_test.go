@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaCloudStorage(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, fake.Write(ctx, "tenant-a/existing.txt", []byte("12345"), nil))
+
+	storage := NewQuotaCloudStorage(fake, QuotaOption{
+		Quotas: []Quota{
+			{Prefix: "tenant-a/", MaxBytes: 30, MaxObjects: 3},
+			{Prefix: "tenant-a/uploads/", MaxBytes: 8},
+		},
+	})
+
+	// the existing objects are listed on the first write
+	require.NoError(t, storage.Write(ctx, "tenant-a/b.txt", []byte("1234567890"), nil))
+
+	usage, err := storage.Usage(ctx, "tenant-a/")
+	require.NoError(t, err)
+	require.Equal(t, QuotaUsage{Bytes: 15, Objects: 2}, usage)
+
+	err = storage.Write(ctx, "tenant-a/c.txt", bytes.Repeat([]byte("c"), 16), nil)
+	require.True(t, errors.Is(err, ErrQuotaExceeded))
+
+	// an overwrite only accounts for the difference
+	require.NoError(t, storage.Write(ctx, "tenant-a/b.txt", []byte("12345678901"), nil))
+
+	// the nested quota is applied too
+	err = storage.Upload(ctx, "tenant-a/uploads/d.txt", bytes.NewReader([]byte("123456789")), nil)
+
+	var quotaErr *QuotaExceededError
+	require.True(t, errors.As(err, &quotaErr))
+	require.Equal(t, "tenant-a/uploads/", quotaErr.Quota.Prefix)
+
+	_, err = fake.Get(ctx, "tenant-a/uploads/d.txt")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	writer, err := storage.GetWriter(ctx, "tenant-a/uploads/d.txt")
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("1234"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	usage, err = storage.Usage(ctx, "tenant-a/")
+	require.NoError(t, err)
+	require.Equal(t, QuotaUsage{Bytes: 20, Objects: 3}, usage)
+
+	err = storage.Write(ctx, "tenant-a/e.txt", nil, nil)
+	require.True(t, errors.Is(err, ErrQuotaExceeded))
+
+	require.NoError(t, storage.Delete(ctx, "tenant-a/b.txt"))
+
+	usage, err = storage.Usage(ctx, "tenant-a/")
+	require.NoError(t, err)
+	require.Equal(t, QuotaUsage{Bytes: 9, Objects: 2}, usage)
+
+	// the other prefixes aren't limited
+	require.NoError(t, storage.Write(ctx, "tenant-b/a.txt", bytes.Repeat([]byte("a"), 100), nil))
+
+	_, err = storage.Usage(ctx, "tenant-b/")
+	require.Error(t, err)
+}
+
+func TestQuotaCloudStorageReconcile(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	now := time.Now()
+
+	storage := NewQuotaCloudStorage(fake, QuotaOption{
+		Quotas:            []Quota{{Prefix: "tenant/", MaxObjects: 2}},
+		ReconcileInterval: time.Minute,
+		Clock:             func() time.Time { return now },
+	})
+
+	require.NoError(t, storage.Write(ctx, "tenant/a.txt", []byte("a"), nil))
+
+	// written by another replica
+	require.NoError(t, fake.Write(ctx, "tenant/b.txt", []byte("b"), nil))
+
+	usage, err := storage.Usage(ctx, "tenant/")
+	require.NoError(t, err)
+	require.Equal(t, QuotaUsage{Bytes: 1, Objects: 1}, usage)
+
+	now = now.Add(2 * time.Minute)
+
+	err = storage.Write(ctx, "tenant/c.txt", []byte("c"), nil)
+	require.True(t, errors.Is(err, ErrQuotaExceeded))
+
+	require.NoError(t, fake.Delete(ctx, "tenant/b.txt"))
+	require.NoError(t, storage.Reconcile(ctx))
+
+	usage, err = storage.Usage(ctx, "tenant/")
+	require.NoError(t, err)
+	require.Equal(t, QuotaUsage{Bytes: 1, Objects: 1}, usage)
+}
+
+func TestQuotaCloudStorageUploadSessions(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	storage := NewQuotaCloudStorage(fake, QuotaOption{
+		Quotas: []Quota{{Prefix: "tenant/", MaxBytes: 8, MaxObjects: 2}},
+	})
+
+	session, err := storage.BeginUpload(ctx, "tenant/a", nil)
+	require.NoError(t, err)
+	require.NoError(t, session.UploadPart(ctx, 1, []byte("1234")))
+	require.NoError(t, session.UploadPart(ctx, 2, []byte("5678")))
+	require.True(t, errors.Is(session.UploadPart(ctx, 3, []byte("9")), ErrQuotaExceeded))
+	require.NoError(t, session.Complete(ctx))
+
+	usage, err := storage.Usage(ctx, "tenant/")
+	require.NoError(t, err)
+	require.Equal(t, QuotaUsage{Bytes: 8, Objects: 1}, usage)
+
+	session, err = storage.BeginUpload(ctx, "tenant/b", nil)
+	require.NoError(t, err)
+	require.True(t, errors.Is(session.UploadPart(ctx, 1, []byte("1")), ErrQuotaExceeded))
+
+	// the parts uploaded without the quota are checked when the session is completed
+	unlimited, err := fake.BeginUpload(ctx, "tenant/c", nil)
+	require.NoError(t, err)
+	require.NoError(t, unlimited.UploadPart(ctx, 1, []byte("123")))
+
+	state := unlimited.State()
+	session, err = storage.ResumeUpload(ctx, &state)
+	require.NoError(t, err)
+	require.True(t, errors.Is(session.Complete(ctx), ErrQuotaExceeded))
+
+	_, err = fake.Get(ctx, "tenant/c")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	usage, err = storage.Usage(ctx, "tenant/")
+	require.NoError(t, err)
+	require.Equal(t, QuotaUsage{Bytes: 8, Objects: 1}, usage)
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"gocloud.dev/blob"
+)
+
+// getWithAttributes opens a reader and fills the attributes from the same response,
+// so no additional HEAD request is required.
+func getWithAttributes(
+	ctx context.Context,
+	bucket *blob.Bucket,
+	key string,
+) (io.ReadCloser, *Attributes, error) {
+	reader, err := bucket.NewReader(ctx, key, nil)
+	if err != nil {
+		return nil, nil, translateError(err)
+	}
+
+	attrs := &Attributes{
+		ContentType: reader.ContentType(),
+		ModTime:     reader.ModTime(),
+		Size:        reader.Size(),
+	}
+
+	var s3Output s3.GetObjectOutput
+
+	var gcsReader *storage.Reader
+
+	switch {
+	case reader.As(&s3Output):
+		attrs.CacheControl = aws.StringValue(s3Output.CacheControl)
+		attrs.ContentDisposition = aws.StringValue(s3Output.ContentDisposition)
+		attrs.ContentEncoding = aws.StringValue(s3Output.ContentEncoding)
+		attrs.ContentLanguage = aws.StringValue(s3Output.ContentLanguage)
+		attrs.setS3Version(s3Output.ETag)
+		attrs.Encryption = awsObjectEncryption(
+			s3Output.ServerSideEncryption,
+			s3Output.SSEKMSKeyId,
+			s3Output.SSECustomerAlgorithm,
+			s3Output.SSECustomerKeyMD5,
+		)
+		attrs.Metadata = make(map[string]string, len(s3Output.Metadata))
+
+		for k, v := range s3Output.Metadata {
+			value := aws.StringValue(v)
+			if unescaped, err := url.PathUnescape(value); err == nil {
+				value = unescaped
+			}
+
+			attrs.Metadata[strings.ToLower(k)] = value
+		}
+
+	case reader.As(&gcsReader):
+		attrs.CacheControl = gcsReader.Attrs.CacheControl
+		attrs.ContentEncoding = gcsReader.Attrs.ContentEncoding
+		attrs.Generation = strconv.FormatInt(gcsReader.Attrs.Generation, 10)
+	}
+
+	return reader, attrs, nil
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+)
+
+// RecordWriterOption configures NewNDJSONWriter and NewCSVWriter
+type RecordWriterOption struct {
+	// Gzip compresses the object, the key should then end with ".gz"
+	Gzip bool
+}
+
+// recordStream is the writer of the object, through gzip when enabled
+type recordStream struct {
+	writer io.WriteCloser
+	gzip   *gzip.Writer
+	target io.Writer
+}
+
+func newRecordStream(
+	ctx context.Context,
+	storage CloudStorage,
+	key string,
+	opts *RecordWriterOption,
+	writeOpts []WriteOption,
+) (*recordStream, error) {
+	writer, err := storage.GetWriter(ctx, key, writeOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &recordStream{writer: writer, target: writer}
+
+	if opts != nil && opts.Gzip {
+		stream.gzip = gzip.NewWriter(writer)
+		stream.target = stream.gzip
+	}
+
+	return stream, nil
+}
+
+// close completes the object, the writer of the object is closed even if gzip fails
+func (s *recordStream) close(flushErr error) error {
+	err := flushErr
+
+	if s.gzip != nil {
+		if gzipErr := s.gzip.Close(); err == nil {
+			err = gzipErr
+		}
+	}
+
+	if closeErr := s.writer.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// NDJSONWriter writes values as JSON lines to an object, which is stored on Close
+type NDJSONWriter struct {
+	stream  *recordStream
+	buf     *bufio.Writer
+	encoder *json.Encoder
+}
+
+// NewNDJSONWriter returns a writer of the JSON lines of the object, it must be closed to store the object
+func NewNDJSONWriter(
+	ctx context.Context,
+	storage CloudStorage,
+	key string,
+	opts *RecordWriterOption,
+	writeOpts ...WriteOption,
+) (*NDJSONWriter, error) {
+	stream, err := newRecordStream(ctx, storage, key, opts, writeOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bufio.NewWriter(stream.target)
+
+	return &NDJSONWriter{stream: stream, buf: buf, encoder: json.NewEncoder(buf)}, nil
+}
+
+// Write encodes v as a JSON line
+func (w *NDJSONWriter) Write(v interface{}) error {
+	return w.encoder.Encode(v)
+}
+
+// Flush sends the buffered lines to the writer of the object
+func (w *NDJSONWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+// Close flushes the lines and stores the object
+func (w *NDJSONWriter) Close() error {
+	return w.stream.close(w.buf.Flush())
+}
+
+// CSVWriter writes records as CSV to an object, which is stored on Close
+type CSVWriter struct {
+	stream *recordStream
+	csv    *csv.Writer
+}
+
+// NewCSVWriter returns a writer of the CSV records of the object, it must be closed to store the object
+func NewCSVWriter(
+	ctx context.Context,
+	storage CloudStorage,
+	key string,
+	opts *RecordWriterOption,
+	writeOpts ...WriteOption,
+) (*CSVWriter, error) {
+	stream, err := newRecordStream(ctx, storage, key, opts, writeOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CSVWriter{stream: stream, csv: csv.NewWriter(stream.target)}, nil
+}
+
+// Write writes a record, the first one is usually the header
+func (w *CSVWriter) Write(record []string) error {
+	return w.csv.Write(record)
+}
+
+// Flush sends the buffered records to the writer of the object
+func (w *CSVWriter) Flush() error {
+	w.csv.Flush()
+
+	return w.csv.Error()
+}
+
+// Close flushes the records and stores the object
+func (w *CSVWriter) Close() error {
+	return w.stream.close(w.Flush())
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNDJSONWriter(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	writer, err := NewNDJSONWriter(ctx, storage, "events.ndjson", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Write(map[string]int{"id": 1}))
+	require.NoError(t, writer.Write(map[string]int{"id": 2}))
+
+	// the object is only stored on Close
+	_, err = storage.Get(ctx, "events.ndjson")
+	require.Error(t, err)
+
+	require.NoError(t, writer.Close())
+
+	body, err := storage.Get(ctx, "events.ndjson")
+	require.NoError(t, err)
+	require.Equal(t, "{\"id\":1}\n{\"id\":2}\n", string(body))
+}
+
+func TestCSVWriterGzip(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	writer, err := NewCSVWriter(ctx, storage, "export.csv.gz", &RecordWriterOption{Gzip: true})
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Write([]string{"id", "name"}))
+	require.NoError(t, writer.Write([]string{"1", "alice, bob"}))
+	require.NoError(t, writer.Close())
+
+	body, err := storage.Get(ctx, "export.csv.gz")
+	require.NoError(t, err)
+
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	require.NoError(t, err)
+
+	content, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, "id,name\n1,\"alice, bob\"\n", string(content))
+}
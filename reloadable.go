@@ -0,0 +1,714 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// ReloadConfig is the configuration of a ReloadableCloudStorage, as given to NewCloudStorageWithOption
+type ReloadConfig struct {
+	IsTesting      bool
+	BucketProvider string
+	BucketName     string
+	Option         CloudStorageOption
+}
+
+// ReloadableCloudStorage swaps its CloudStorage when the configuration changes, e.g. rotated credentials,
+// a new endpoint or new retry and limit settings, while the value is already shared across the application.
+// The calls started before UpdateConfig complete with the previous storage, which is closed once its readers,
+// writers and calls in flight are done. The upload sessions and the subscriptions stay on the storage
+// which created them.
+type ReloadableCloudStorage struct {
+	connect func(ctx context.Context, cfg ReloadConfig) (CloudStorage, error)
+
+	mu      sync.RWMutex
+	cfg     ReloadConfig
+	current *reloadableGeneration
+	closed  bool
+}
+
+var _ CloudStorage = (*ReloadableCloudStorage)(nil)
+
+// reloadableGeneration counts the uses of a storage, so it's closed after the last one once replaced
+type reloadableGeneration struct {
+	storage CloudStorage
+
+	mu      sync.Mutex
+	refs    int
+	retired bool
+}
+
+// NewReloadableCloudStorage creates the storage of cfg with NewCloudStorageWithOption
+func NewReloadableCloudStorage(ctx context.Context, cfg ReloadConfig) (*ReloadableCloudStorage, error) {
+	return newReloadableCloudStorage(ctx, cfg, func(ctx context.Context, cfg ReloadConfig) (CloudStorage, error) {
+		return NewCloudStorageWithOption(ctx, cfg.IsTesting, cfg.BucketProvider, cfg.BucketName, cfg.Option)
+	})
+}
+
+func newReloadableCloudStorage(
+	ctx context.Context,
+	cfg ReloadConfig,
+	connect func(ctx context.Context, cfg ReloadConfig) (CloudStorage, error),
+) (*ReloadableCloudStorage, error) {
+	storage, err := connect(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReloadableCloudStorage{
+		connect: connect,
+		cfg:     cfg,
+		current: &reloadableGeneration{storage: storage},
+	}, nil
+}
+
+// Config returns the configuration of the current storage
+func (ts *ReloadableCloudStorage) Config() ReloadConfig {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	return ts.cfg
+}
+
+// UpdateConfig creates the storage of cfg and makes the next calls use it.
+// The current storage is kept when the creation fails.
+func (ts *ReloadableCloudStorage) UpdateConfig(ctx context.Context, cfg ReloadConfig) error {
+	storage, err := ts.connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	ts.mu.Lock()
+
+	if ts.closed {
+		ts.mu.Unlock()
+		storage.Close()
+
+		return ErrShuttingDown
+	}
+
+	previous := ts.current
+	ts.cfg = cfg
+	ts.current = &reloadableGeneration{storage: storage}
+	ts.mu.Unlock()
+
+	previous.retire()
+
+	return nil
+}
+
+// WatchConfig applies the configurations received from updates until it's closed or ctx is done,
+// e.g. from a watcher of a secret or a config file. The errors of UpdateConfig are given to onError when it's not nil.
+func (ts *ReloadableCloudStorage) WatchConfig(
+	ctx context.Context,
+	updates <-chan ReloadConfig,
+	onError func(err error),
+) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case cfg, ok := <-updates:
+			if !ok {
+				return
+			}
+
+			if err := ts.UpdateConfig(ctx, cfg); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// acquire returns the current storage, release must be called once it's not used anymore
+func (ts *ReloadableCloudStorage) acquire() (CloudStorage, func()) {
+	ts.mu.RLock()
+	generation := ts.current
+	generation.acquire()
+	ts.mu.RUnlock()
+
+	var once sync.Once
+
+	return generation.storage, func() {
+		once.Do(generation.release)
+	}
+}
+
+func (g *reloadableGeneration) acquire() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.refs++
+}
+
+func (g *reloadableGeneration) release() {
+	g.mu.Lock()
+	g.refs--
+	closing := g.retired && g.refs == 0
+	g.mu.Unlock()
+
+	if closing {
+		g.storage.Close()
+	}
+}
+
+func (g *reloadableGeneration) retire() {
+	g.mu.Lock()
+	g.retired = true
+	closing := g.refs == 0
+	g.mu.Unlock()
+
+	if closing {
+		g.storage.Close()
+	}
+}
+
+// List keeps listing with the storage it started with, until the iterator returns an error or io.EOF
+func (ts *ReloadableCloudStorage) List(
+	ctx context.Context,
+	prefix string,
+	opts ...ListOption,
+) *ListIterator {
+	storage, release := ts.acquire()
+	iter := storage.List(ctx, prefix, opts...)
+
+	return NewListIterator(ctx, func(ctx context.Context) (*ListObject, error) {
+		object, err := iter.Next(ctx)
+		if err != nil {
+			release()
+		}
+
+		return object, err
+	})
+}
+
+// Close closes the current storage, the previous ones are closed once they're not used anymore
+func (ts *ReloadableCloudStorage) Close() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if !ts.closed {
+		ts.closed = true
+		ts.current.retire()
+	}
+}
+
+func (ts *ReloadableCloudStorage) GetReader(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	storage, release := ts.acquire()
+
+	reader, err := storage.GetReader(ctx, key, opts...)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	return &releaseOnCloseReader{ReadCloser: reader, release: release}, nil
+}
+
+func (ts *ReloadableCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	storage, release := ts.acquire()
+
+	reader, attrs, err := storage.GetWithAttributes(ctx, key, opts...)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+
+	return &releaseOnCloseReader{ReadCloser: reader, release: release}, attrs, nil
+}
+
+func (ts *ReloadableCloudStorage) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset, length int64,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	storage, release := ts.acquire()
+
+	reader, err := storage.GetRangeReader(ctx, key, offset, length, opts...)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	return &releaseOnCloseReader{ReadCloser: reader, release: release}, nil
+}
+
+func (ts *ReloadableCloudStorage) Query(
+	ctx context.Context,
+	key string,
+	sql string,
+	format QueryInputFormat,
+) (io.ReadCloser, error) {
+	storage, release := ts.acquire()
+
+	reader, err := storage.Query(ctx, key, sql, format)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	return &releaseOnCloseReader{ReadCloser: reader, release: release}, nil
+}
+
+func (ts *ReloadableCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	storage, release := ts.acquire()
+
+	writer, err := storage.GetWriter(ctx, key, opts...)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	return &releaseOnCloseWriter{WriteCloser: writer, release: release}, nil
+}
+
+func (ts *ReloadableCloudStorage) Get(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) ([]byte, error) {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.Get(ctx, key, opts...)
+}
+
+func (ts *ReloadableCloudStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.Delete(ctx, key)
+}
+
+func (ts *ReloadableCloudStorage) CreateBucket(
+	ctx context.Context,
+	bucketPrefix string,
+	expirationTimeDays int64,
+) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.CreateBucket(ctx, bucketPrefix, expirationTimeDays)
+}
+
+func (ts *ReloadableCloudStorage) CreateBucketWithOptions(
+	ctx context.Context,
+	opts *CreateBucketOption,
+) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.CreateBucketWithOptions(ctx, opts)
+}
+
+func (ts *ReloadableCloudStorage) GetSignedURL(
+	ctx context.Context,
+	key string,
+	opts *SignedURLOption,
+) (string, error) {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.GetSignedURL(ctx, key, opts)
+}
+
+func (ts *ReloadableCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.Write(ctx, key, body, contentType, opts...)
+}
+
+func (ts *ReloadableCloudStorage) Attributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (*Attributes, error) {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.Attributes(ctx, key, opts...)
+}
+
+func (ts *ReloadableCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.Upload(ctx, key, reader, opts, writeOpts...)
+}
+
+func (ts *ReloadableCloudStorage) SetObjectRetention(
+	ctx context.Context,
+	key string,
+	retention *ObjectRetention,
+) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.SetObjectRetention(ctx, key, retention)
+}
+
+func (ts *ReloadableCloudStorage) GetObjectRetention(
+	ctx context.Context,
+	key string,
+) (*ObjectRetention, error) {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.GetObjectRetention(ctx, key)
+}
+
+func (ts *ReloadableCloudStorage) SetLegalHold(
+	ctx context.Context,
+	key string,
+	enabled bool,
+) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.SetLegalHold(ctx, key, enabled)
+}
+
+func (ts *ReloadableCloudStorage) GetLegalHold(
+	ctx context.Context,
+	key string,
+) (bool, error) {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.GetLegalHold(ctx, key)
+}
+
+func (ts *ReloadableCloudStorage) GetBucketPolicy(
+	ctx context.Context,
+) (*BucketPolicy, error) {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.GetBucketPolicy(ctx)
+}
+
+func (ts *ReloadableCloudStorage) SetBucketPolicy(
+	ctx context.Context,
+	policy *BucketPolicy,
+) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.SetBucketPolicy(ctx, policy)
+}
+
+func (ts *ReloadableCloudStorage) GetBucketEncryption(
+	ctx context.Context,
+) (*BucketEncryption, error) {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.GetBucketEncryption(ctx)
+}
+
+func (ts *ReloadableCloudStorage) SetBucketEncryption(
+	ctx context.Context,
+	encryption *BucketEncryption,
+) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.SetBucketEncryption(ctx, encryption)
+}
+
+func (ts *ReloadableCloudStorage) GetBucketLogging(
+	ctx context.Context,
+) (*BucketLogging, error) {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.GetBucketLogging(ctx)
+}
+
+func (ts *ReloadableCloudStorage) SetBucketLogging(
+	ctx context.Context,
+	logging *BucketLogging,
+) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.SetBucketLogging(ctx, logging)
+}
+
+func (ts *ReloadableCloudStorage) GetBucketReplication(
+	ctx context.Context,
+) (*BucketReplication, error) {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.GetBucketReplication(ctx)
+}
+
+func (ts *ReloadableCloudStorage) SetBucketReplication(
+	ctx context.Context,
+	replication *BucketReplication,
+) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.SetBucketReplication(ctx, replication)
+}
+
+func (ts *ReloadableCloudStorage) GetPublicURL(key string) string {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.GetPublicURL(key)
+}
+
+func (ts *ReloadableCloudStorage) As(target interface{}) bool {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.As(target)
+}
+
+func (ts *ReloadableCloudStorage) ErrorAs(
+	err error,
+	target interface{},
+) bool {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.ErrorAs(err, target)
+}
+
+func (ts *ReloadableCloudStorage) Ping(
+	ctx context.Context,
+) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.Ping(ctx)
+}
+
+func (ts *ReloadableCloudStorage) Subscribe(
+	ctx context.Context,
+	prefix string,
+) (<-chan ObjectEvent, error) {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.Subscribe(ctx, prefix)
+}
+
+func (ts *ReloadableCloudStorage) ListIncompleteUploads(
+	ctx context.Context,
+	prefix string,
+) ([]*IncompleteUpload, error) {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.ListIncompleteUploads(ctx, prefix)
+}
+
+func (ts *ReloadableCloudStorage) AbortStaleUploads(
+	ctx context.Context,
+	olderThan time.Duration,
+) ([]*IncompleteUpload, error) {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.AbortStaleUploads(ctx, olderThan)
+}
+
+func (ts *ReloadableCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.WriteIf(ctx, key, body, contentType, condition)
+}
+
+func (ts *ReloadableCloudStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.DeleteIf(ctx, key, generation)
+}
+
+func (ts *ReloadableCloudStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.BeginUpload(ctx, key, opts)
+}
+
+func (ts *ReloadableCloudStorage) ResumeUpload(
+	ctx context.Context,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.ResumeUpload(ctx, state)
+}
+
+func (ts *ReloadableCloudStorage) SetStorageClass(
+	ctx context.Context,
+	key string,
+	storageClass string,
+) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.SetStorageClass(ctx, key, storageClass)
+}
+
+func (ts *ReloadableCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.Copy(ctx, srcKey, dstKey, opts)
+}
+
+func (ts *ReloadableCloudStorage) Undelete(
+	ctx context.Context,
+	key string,
+) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.Undelete(ctx, key)
+}
+
+func (ts *ReloadableCloudStorage) GetScopedCredentials(
+	ctx context.Context,
+	opts *ScopedCredentialsOption,
+) (*ScopedCredentials, error) {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.GetScopedCredentials(ctx, opts)
+}
+
+func (ts *ReloadableCloudStorage) CreateFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.CreateFolder(ctx, folder)
+}
+
+func (ts *ReloadableCloudStorage) DeleteFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.DeleteFolder(ctx, folder)
+}
+
+func (ts *ReloadableCloudStorage) RenameFolder(
+	ctx context.Context,
+	folder string,
+	newFolder string,
+) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return storage.RenameFolder(ctx, folder, newFolder)
+}
+
+// Flush flushes the current storage when it implements Flusher
+func (ts *ReloadableCloudStorage) Flush(ctx context.Context) error {
+	storage, release := ts.acquire()
+	defer release()
+
+	return flushStorage(ctx, storage)
+}
+
+type releaseOnCloseReader struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r *releaseOnCloseReader) Close() error {
+	defer r.release()
+
+	return r.ReadCloser.Close()
+}
+
+type releaseOnCloseWriter struct {
+	io.WriteCloser
+	release func()
+}
+
+func (w *releaseOnCloseWriter) Close() error {
+	defer w.release()
+
+	return w.WriteCloser.Close()
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type closeCountingStorage struct {
+	*FakeCloudStorage
+	closed int32
+}
+
+func (ts *closeCountingStorage) Close() {
+	atomic.AddInt32(&ts.closed, 1)
+}
+
+func TestReloadableCloudStorage(t *testing.T) {
+	ctx := context.Background()
+	storages := map[string]*closeCountingStorage{}
+
+	storage, err := newReloadableCloudStorage(ctx, ReloadConfig{BucketName: "first"},
+		func(ctx context.Context, cfg ReloadConfig) (CloudStorage, error) {
+			if cfg.BucketName == "" {
+				return nil, errors.New("missing bucket")
+			}
+
+			storages[cfg.BucketName] = &closeCountingStorage{FakeCloudStorage: NewFakeCloudStorage(cfg.BucketName)}
+
+			return storages[cfg.BucketName], nil
+		})
+	require.NoError(t, err)
+
+	require.NoError(t, storage.Write(ctx, "key", []byte("first"), nil))
+
+	writer, err := storage.GetWriter(ctx, "streamed")
+	require.NoError(t, err)
+
+	require.NoError(t, storage.UpdateConfig(ctx, ReloadConfig{BucketName: "second"}))
+	require.Equal(t, "second", storage.Config().BucketName)
+
+	// the new calls use the new storage, the previous one is closed once its writer is
+	_, err = storage.Get(ctx, "key")
+	require.True(t, errors.Is(err, ErrNotFound))
+	require.Equal(t, int32(0), atomic.LoadInt32(&storages["first"].closed))
+
+	_, err = writer.Write([]byte("body"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.Equal(t, int32(1), atomic.LoadInt32(&storages["first"].closed))
+
+	body, err := storages["first"].Get(ctx, "streamed")
+	require.NoError(t, err)
+	require.Equal(t, "body", string(body))
+
+	// the current storage is kept when the new one can't be created
+	require.Error(t, storage.UpdateConfig(ctx, ReloadConfig{}))
+	require.Equal(t, "second", storage.Config().BucketName)
+
+	updates := make(chan ReloadConfig, 2)
+	updates <- ReloadConfig{}
+	updates <- ReloadConfig{BucketName: "third"}
+	close(updates)
+
+	var watchErrs []error
+	storage.WatchConfig(ctx, updates, func(err error) {
+		watchErrs = append(watchErrs, err)
+	})
+	require.Len(t, watchErrs, 1)
+	require.Equal(t, "third", storage.Config().BucketName)
+	require.Equal(t, int32(1), atomic.LoadInt32(&storages["second"].closed))
+
+	storage.Close()
+	require.Equal(t, int32(1), atomic.LoadInt32(&storages["third"].closed))
+	require.True(t, errors.Is(storage.UpdateConfig(ctx, ReloadConfig{BucketName: "fourth"}), ErrShuttingDown))
+}
+
+func TestReloadableCloudStorageEndpoint(t *testing.T) {
+	ctx := context.Background()
+
+	storage, err := NewReloadableCloudStorage(ctx, ReloadConfig{
+		BucketProvider: "aws",
+		BucketName:     "bucket",
+		Option:         CloudStorageOption{AWSS3Region: "us-east-1", AWSS3Endpoint: "http://first:9000"},
+	})
+	require.NoError(t, err)
+
+	defer storage.Close()
+
+	require.Contains(t, storage.GetPublicURL("key"), "first:9000")
+
+	cfg := storage.Config()
+	cfg.Option.AWSS3Endpoint = "http://second:9000"
+	require.NoError(t, storage.UpdateConfig(ctx, cfg))
+	require.Contains(t, storage.GetPublicURL("key"), "second:9000")
+}
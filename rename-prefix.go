@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// RenamePrefixOption configures RenamePrefix
+type RenamePrefixOption struct {
+	// Concurrency is the number of objects moved in parallel, DefaultGetManyConcurrency when it isn't positive
+	Concurrency int
+	// OnProgress receives the progress after every object, it's called under a lock so it must be fast
+	OnProgress func(progress RenameProgress)
+}
+
+// RenameProgress is the state of a RenamePrefix
+type RenameProgress struct {
+	// Moved is the number of objects moved so far
+	Moved int
+	// Bytes is the size of the moved objects
+	Bytes int64
+	// Failed is the number of objects which failed to move
+	Failed int
+}
+
+// RenameReport is the result of RenamePrefix
+type RenameReport struct {
+	Moved int
+	Bytes int64
+	// Failed holds the error of every object left under the source prefix, they're moved by the next run
+	Failed map[string]error
+}
+
+// RenamePrefix moves every object under srcPrefix to dstPrefix, copying it then deleting the source, Concurrency
+// objects at a time. A partially completed rename is resumed by calling it again: only the objects left under
+// srcPrefix are moved, and those already copied with the same size and MD5 are only deleted.
+// The failed objects don't stop the rename, they're reported. An error is returned when the objects can't be
+// listed, or when dstPrefix is under srcPrefix.
+func RenamePrefix(
+	ctx context.Context,
+	storage CloudStorage,
+	srcPrefix string,
+	dstPrefix string,
+	opts *RenamePrefixOption,
+) (*RenameReport, error) {
+	options := RenamePrefixOption{}
+	if opts != nil {
+		options = *opts
+	}
+
+	// the moved objects would be listed again
+	if strings.HasPrefix(dstPrefix, srcPrefix) {
+		return nil, fmt.Errorf("unable to rename '%s' to '%s': the destination is under the source", srcPrefix, dstPrefix)
+	}
+
+	var (
+		mu       sync.Mutex
+		report   = &RenameReport{Failed: make(map[string]error)}
+		progress RenameProgress
+	)
+
+	err := ForEachObject(ctx, storage, srcPrefix, func(ctx context.Context, object *ListObject) error {
+		err := moveObject(ctx, storage, object, dstPrefix+strings.TrimPrefix(object.Key, srcPrefix))
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err != nil {
+			progress.Failed++
+		} else {
+			progress.Moved++
+			progress.Bytes += object.Size
+		}
+
+		if options.OnProgress != nil {
+			options.OnProgress(progress)
+		}
+
+		return err
+	}, &ForEachOption{Concurrency: options.Concurrency, CollectErrors: true})
+
+	report.Moved = progress.Moved
+	report.Bytes = progress.Bytes
+
+	var forEachErr *ForEachError
+
+	switch {
+	case errors.As(err, &forEachErr):
+		report.Failed = forEachErr.Errors
+	case err != nil:
+		return report, err
+	}
+
+	return report, nil
+}
+
+// moveObject copies the object to the destination key, unless it's already there, and deletes it
+func moveObject(ctx context.Context, storage CloudStorage, object *ListObject, dstKey string) error {
+	copied := false
+
+	// the object may have been copied by an interrupted rename
+	if len(object.MD5) > 0 {
+		attrs, err := storage.Attributes(ctx, dstKey)
+
+		switch {
+		case err == nil:
+			copied = attrs.Size == object.Size && bytes.Equal(attrs.MD5, object.MD5)
+		case !errors.Is(err, ErrNotFound):
+			return err
+		}
+	}
+
+	if !copied {
+		if err := copyObject(ctx, storage, object.Key, storage, dstKey); err != nil {
+			return err
+		}
+	}
+
+	return storage.Delete(ctx, object.Key)
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenamePrefix(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	for _, key := range []string{"old/a", "old/b/c", "old/d", "other/e"} {
+		require.NoError(t, storage.Write(ctx, key, []byte(key), nil))
+	}
+
+	var progress []RenameProgress
+
+	report, err := RenamePrefix(ctx, storage, "old/", "new/", &RenamePrefixOption{
+		Concurrency: 2,
+		OnProgress: func(p RenameProgress) {
+			progress = append(progress, p)
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, report.Moved)
+	require.Equal(t, int64(len("old/a")+len("old/b/c")+len("old/d")), report.Bytes)
+	require.Empty(t, report.Failed)
+
+	require.Len(t, progress, 3)
+	require.Equal(t, RenameProgress{Moved: 3, Bytes: report.Bytes}, progress[2])
+
+	require.Equal(t, []string{"new/a", "new/b/c", "new/d", "other/e"}, listedKeys(t, storage.List(ctx, "")))
+
+	body, err := storage.Get(ctx, "new/b/c")
+	require.NoError(t, err)
+	require.Equal(t, "old/b/c", string(body))
+
+	_, err = RenamePrefix(ctx, storage, "new/", "new/sub/", nil)
+	require.Error(t, err)
+}
+
+func TestRenamePrefixResume(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	for _, key := range []string{"old/a", "old/b"} {
+		require.NoError(t, storage.Write(ctx, key, []byte(key), nil))
+	}
+
+	// the objects are copied, but the sources can't be deleted
+	failingDeletes := NewFaultInjectingCloudStorage(storage, FaultInjectionOption{
+		Operations: map[string]Fault{"Delete": {ErrorRate: 1, Err: ErrUnavailable}},
+	})
+
+	report, err := RenamePrefix(ctx, failingDeletes, "old/", "new/", nil)
+	require.NoError(t, err)
+	require.Equal(t, 0, report.Moved)
+	require.Len(t, report.Failed, 2)
+	require.ErrorIs(t, report.Failed["old/a"], ErrUnavailable)
+
+	// the next run only deletes the copied objects
+	failingUploads := NewFaultInjectingCloudStorage(storage, FaultInjectionOption{
+		Operations: map[string]Fault{"Upload": {ErrorRate: 1, Err: ErrUnavailable}},
+	})
+
+	report, err = RenamePrefix(ctx, failingUploads, "old/", "new/", nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Moved)
+	require.Empty(t, report.Failed)
+
+	require.Equal(t, []string{"new/a", "new/b"}, listedKeys(t, storage.List(ctx, "")))
+}
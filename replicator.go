@@ -0,0 +1,237 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultReplicationInterval is the polling interval of Replicator when ReplicatorOption.Interval is not set
+const DefaultReplicationInterval = time.Minute
+
+// ReplicatorOption configures a Replicator
+type ReplicatorOption struct {
+	SourcePrefix      string
+	DestinationPrefix string
+	// Interval is the delay between two polls of the source. Defaults to DefaultReplicationInterval.
+	Interval time.Duration
+	// Delete removes the destination objects deleted from the source.
+	Delete bool
+	// Concurrency is the number of objects copied in parallel. Defaults to DefaultSyncConcurrency.
+	Concurrency int
+	// CheckpointKey is the destination key storing the replicated state, so a restarted Replicator only copies
+	// what changed in the meantime. The state is only kept in memory when it's empty.
+	CheckpointKey string
+	// OnReplicate is called after every pass, e.g. to export metrics. The failed passes are logged when it's nil.
+	OnReplicate func(report *SyncReport, err error)
+	// Logger receives the failed passes when OnReplicate is nil. They are discarded when it's nil.
+	Logger Logger
+}
+
+// replicationCheckpoint is the state of the source objects as of the last pass, indexed by key without the prefix
+type replicationCheckpoint struct {
+	Objects map[string]*ListObject `json:"objects"`
+}
+
+// Replicator keeps a destination prefix up to date with a source prefix, possibly on another provider.
+// The source is polled every Interval, and Notify triggers a pass right away, e.g. from a bucket notification.
+// The objects are compared with the checkpoint of the previous pass instead of listing the destination,
+// except for the first pass without a checkpoint.
+type Replicator struct {
+	src    CloudStorage
+	dst    CloudStorage
+	opts   ReplicatorOption
+	notify chan struct{}
+
+	mu         sync.Mutex
+	checkpoint map[string]*ListObject
+}
+
+// NewReplicator returns a Replicator from src to dst, call Run to start it
+func NewReplicator(src CloudStorage, dst CloudStorage, opts ReplicatorOption) *Replicator {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultReplicationInterval
+	}
+
+	opts.Logger = loggerOrNoop(opts.Logger)
+
+	return &Replicator{
+		src:    src,
+		dst:    dst,
+		opts:   opts,
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// Run replicates the source until ctx is done, then returns ctx.Err()
+func (r *Replicator) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := r.Replicate(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if r.opts.OnReplicate != nil {
+			r.opts.OnReplicate(report, err)
+		} else if err != nil {
+			r.opts.Logger.Error("unable to replicate", Fields{"prefix": r.opts.SourcePrefix, "error": err})
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-r.notify:
+		}
+	}
+}
+
+// Notify triggers a pass of Run without waiting for the next poll. It doesn't block.
+func (r *Replicator) Notify() {
+	select {
+	case r.notify <- struct{}{}:
+	default:
+		// a pass is already pending
+	}
+}
+
+// Replicate runs a single pass. The checkpoint is saved even when the pass fails,
+// so the objects copied so far aren't copied again.
+func (r *Replicator) Replicate(ctx context.Context) (*SyncReport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	known, err := r.known(ctx)
+	if err != nil {
+		return &SyncReport{}, err
+	}
+
+	srcObjects, err := listByName(ctx, r.src, r.opts.SourcePrefix)
+	if err != nil {
+		return &SyncReport{}, err
+	}
+
+	report, syncErr := syncObjects(ctx, r.src, srcObjects, r.dst, r.opts.DestinationPrefix, known, &SyncOption{
+		Concurrency: r.opts.Concurrency,
+		Delete:      r.opts.Delete,
+	})
+
+	r.checkpoint = nextCheckpoint(srcObjects, known, report, r.opts.Delete)
+
+	if err := r.saveCheckpoint(ctx); err != nil && syncErr == nil {
+		return report, err
+	}
+
+	return report, syncErr
+}
+
+// known returns the replicated objects, from the checkpoint or by listing the destination
+func (r *Replicator) known(ctx context.Context) (map[string]*ListObject, error) {
+	if r.checkpoint != nil {
+		return r.checkpoint, nil
+	}
+
+	if r.opts.CheckpointKey != "" {
+		body, err := r.dst.Get(ctx, r.opts.CheckpointKey)
+		if err == nil {
+			var checkpoint replicationCheckpoint
+			if err := json.Unmarshal(body, &checkpoint); err != nil {
+				return nil, err
+			}
+
+			if checkpoint.Objects != nil {
+				return checkpoint.Objects, nil
+			}
+		} else if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	objects, err := listByName(ctx, r.dst, r.opts.DestinationPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// the checkpoint isn't a replicated object, even when it's stored under the destination prefix
+	delete(objects, strings.TrimPrefix(r.opts.CheckpointKey, r.opts.DestinationPrefix))
+
+	return objects, nil
+}
+
+func (r *Replicator) saveCheckpoint(ctx context.Context) error {
+	if r.opts.CheckpointKey == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(replicationCheckpoint{Objects: r.checkpoint})
+	if err != nil {
+		return err
+	}
+
+	contentType := "application/json"
+
+	return r.dst.Write(ctx, r.opts.CheckpointKey, body, &contentType)
+}
+
+// nextCheckpoint returns the objects replicated after a pass, which may have been stopped by an error
+func nextCheckpoint(
+	srcObjects map[string]*ListObject,
+	known map[string]*ListObject,
+	report *SyncReport,
+	deleteExtra bool,
+) map[string]*ListObject {
+	copied := make(map[string]bool, len(report.Copied))
+	for _, name := range report.Copied {
+		copied[name] = true
+	}
+
+	deleted := make(map[string]bool, len(report.Deleted))
+	for _, name := range report.Deleted {
+		deleted[name] = true
+	}
+
+	checkpoint := make(map[string]*ListObject, len(srcObjects))
+
+	for name, object := range srcObjects {
+		switch {
+		case copied[name] || !objectChanged(object, known[name]):
+			checkpoint[name] = object
+		case known[name] != nil:
+			// the copy failed, the destination still has the previous version
+			checkpoint[name] = known[name]
+		}
+	}
+
+	if deleteExtra {
+		for name, object := range known {
+			if _, ok := srcObjects[name]; !ok && !deleted[name] {
+				// the deletion failed, it's retried on the next pass
+				checkpoint[name] = object
+			}
+		}
+	}
+
+	return checkpoint
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicatorCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	src := NewFakeCloudStorage("src")
+	dst := NewFakeCloudStorage("dst")
+
+	opts := ReplicatorOption{
+		SourcePrefix:      "data/",
+		DestinationPrefix: "replica/",
+		Delete:            true,
+		CheckpointKey:     "replica/.checkpoint.json",
+	}
+
+	require.NoError(t, src.Write(ctx, "data/a.txt", []byte("a"), nil))
+	require.NoError(t, src.Write(ctx, "data/b.txt", []byte("b"), nil))
+	require.NoError(t, dst.Write(ctx, "replica/b.txt", []byte("b"), nil))
+
+	report, err := NewReplicator(src, dst, opts).Replicate(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.txt"}, report.Copied)
+	require.Equal(t, 1, report.Unchanged)
+
+	_, err = dst.Get(ctx, "replica/.checkpoint.json")
+	require.NoError(t, err)
+
+	require.NoError(t, src.Write(ctx, "data/a.txt", []byte("updated"), nil))
+	require.NoError(t, src.Delete(ctx, "data/b.txt"))
+
+	// a restarted replicator resumes from the checkpoint
+	replicator := NewReplicator(src, dst, opts)
+
+	report, err = replicator.Replicate(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.txt"}, report.Copied)
+	require.Equal(t, []string{"b.txt"}, report.Deleted)
+
+	body, err := dst.Get(ctx, "replica/a.txt")
+	require.NoError(t, err)
+	require.Equal(t, []byte("updated"), body)
+
+	_, err = dst.Get(ctx, "replica/b.txt")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	report, err = replicator.Replicate(ctx)
+	require.NoError(t, err)
+	require.Empty(t, report.Copied)
+	require.Empty(t, report.Deleted)
+	require.Equal(t, 1, report.Unchanged)
+}
+
+func TestReplicatorNotify(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src := NewFakeCloudStorage("src")
+	dst := NewFakeCloudStorage("dst")
+	reports := make(chan *SyncReport, 1)
+
+	replicator := NewReplicator(src, dst, ReplicatorOption{
+		Interval: time.Hour,
+		OnReplicate: func(report *SyncReport, err error) {
+			require.NoError(t, err)
+			reports <- report
+		},
+	})
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- replicator.Run(ctx)
+	}()
+
+	require.Empty(t, (<-reports).Copied)
+
+	require.NoError(t, src.Write(ctx, "a.txt", []byte("a"), nil))
+	replicator.Notify()
+
+	require.Equal(t, []string{"a.txt"}, (<-reports).Copied)
+
+	cancel()
+	require.Equal(t, context.Canceled, <-done)
+}
@@ -0,0 +1,295 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"gocloud.dev/gcerrors"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	defaultRetryMaxAttempts = 3
+	defaultRetryBaseBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff  = 5 * time.Second
+)
+
+// RetryPolicy configures how failed operations are retried.
+// When it's set, the retries of the AWS SDK are disabled and replaced by the policy. The GCS client keeps its own
+// retries of the transient errors, the policy being applied on top of them.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one. Defaults to 3.
+	MaxAttempts int
+	// BaseBackoff is the backoff before the first retry, doubled on every following retry. Defaults to 100ms.
+	BaseBackoff time.Duration
+	// MaxBackoff caps the backoff between two attempts. Defaults to 5s.
+	MaxBackoff time.Duration
+	// IsRetryable classifies errors as transient. Defaults to IsRetryableError.
+	IsRetryable func(err error) bool
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultRetryMaxAttempts
+	}
+
+	if p.BaseBackoff <= 0 {
+		p.BaseBackoff = defaultRetryBaseBackoff
+	}
+
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = defaultRetryMaxBackoff
+	}
+
+	if p.IsRetryable == nil {
+		p.IsRetryable = IsRetryableError
+	}
+
+	return p
+}
+
+// backoff returns the exponential backoff with full jitter before the given retry (starting from 1)
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	backoff := p.MaxBackoff
+
+	// compared before shifting, a shifted BaseBackoff can overflow to a negative duration
+	if shift := uint(retry - 1); shift < 63 && p.BaseBackoff <= p.MaxBackoff>>shift {
+		backoff = p.BaseBackoff << shift
+	}
+
+	if backoff < 1 {
+		backoff = 1
+	}
+
+	// the upper bound is inclusive unless it would overflow
+	bound := int64(backoff)
+	if bound < math.MaxInt64 {
+		bound++
+	}
+
+	return time.Duration(rand.Int63n(bound)) // nolint:gosec
+}
+
+// do calls f until it succeeds, returns a non-retryable error or the attempts are exhausted
+func (p RetryPolicy) do(ctx context.Context, f func() error) error {
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		err = f()
+		if err == nil || attempt >= p.MaxAttempts || !p.IsRetryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(p.backoff(attempt))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// IsRetryableError reports whether err is a transient provider error:
+// a 429 or 5xx response, a throttling error, a network timeout or an injected fault.
+func IsRetryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	switch gcerrors.Code(err) {
+	case gcerrors.Internal, gcerrors.ResourceExhausted:
+		return true
+	}
+
+	var awsErr awserr.RequestFailure
+	if errors.As(err, &awsErr) {
+		return isRetryableStatusCode(awsErr.StatusCode())
+	}
+
+	var gcpErr *googleapi.Error
+	if errors.As(err, &gcpErr) {
+		return isRetryableStatusCode(gcpErr.Code)
+	}
+
+	// the AWS transport failures, the SDK retries being disabled when a policy is set
+	var transportErr awserr.Error
+	if errors.As(err, &transportErr) {
+		switch transportErr.Code() {
+		case request.ErrCodeRequestError, request.ErrCodeResponseTimeout:
+			return true
+		}
+	}
+
+	var netErr net.Error
+	if errorAs(err, &netErr) {
+		return netErr.Timeout()
+	}
+
+	var faultErr *InjectedFaultError
+	if errors.As(err, &faultErr) {
+		return true
+	}
+
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func isRetryableStatusCode(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// retryCloudStorage retries the idempotent operations of the wrapped CloudStorage.
+// Streams are only retried while being opened and Upload is never retried, since the reader can't be rewound.
+type retryCloudStorage struct {
+	CloudStorage
+	policy RetryPolicy
+}
+
+func newRetryCloudStorage(storage CloudStorage, policy RetryPolicy) *retryCloudStorage {
+	return &retryCloudStorage{
+		CloudStorage: storage,
+		policy:       policy.withDefaults(),
+	}
+}
+
+func (ts *retryCloudStorage) Get(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (body []byte, err error) {
+	err = ts.policy.do(ctx, func() error {
+		body, err = ts.CloudStorage.Get(ctx, key, opts...)
+		return err
+	})
+
+	return body, err
+}
+
+func (ts *retryCloudStorage) GetReader(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (reader io.ReadCloser, err error) {
+	err = ts.policy.do(ctx, func() error {
+		reader, err = ts.CloudStorage.GetReader(ctx, key, opts...)
+		return err
+	})
+
+	return reader, err
+}
+
+func (ts *retryCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (reader io.ReadCloser, attrs *Attributes, err error) {
+	err = ts.policy.do(ctx, func() error {
+		reader, attrs, err = ts.CloudStorage.GetWithAttributes(ctx, key, opts...)
+		return err
+	})
+
+	return reader, attrs, err
+}
+
+func (ts *retryCloudStorage) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset,
+	length int64,
+	opts ...ReadOption,
+) (reader io.ReadCloser, err error) {
+	err = ts.policy.do(ctx, func() error {
+		reader, err = ts.CloudStorage.GetRangeReader(ctx, key, offset, length, opts...)
+		return err
+	})
+
+	return reader, err
+}
+
+func (ts *retryCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	return ts.policy.do(ctx, func() error {
+		return ts.CloudStorage.Write(ctx, key, body, contentType, opts...)
+	})
+}
+
+func (ts *retryCloudStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	return ts.policy.do(ctx, func() error {
+		return ts.CloudStorage.Delete(ctx, key)
+	})
+}
+
+func (ts *retryCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	return ts.policy.do(ctx, func() error {
+		return ts.CloudStorage.Copy(ctx, srcKey, dstKey, opts)
+	})
+}
+
+func (ts *retryCloudStorage) Attributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (attrs *Attributes, err error) {
+	err = ts.policy.do(ctx, func() error {
+		attrs, err = ts.CloudStorage.Attributes(ctx, key, opts...)
+		return err
+	})
+
+	return attrs, err
+}
+
+func (ts *retryCloudStorage) GetSignedURL(
+	ctx context.Context,
+	key string,
+	opts *SignedURLOption,
+) (url string, err error) {
+	err = ts.policy.do(ctx, func() error {
+		url, err = ts.CloudStorage.GetSignedURL(ctx, key, opts)
+		return err
+	})
+
+	return url, err
+}
+
+// Flush flushes the wrapped storage
+func (ts *retryCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
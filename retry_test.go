@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+)
+
+type flakyCloudStorage struct {
+	CloudStorage
+	failures int
+	err      error
+	calls    int
+}
+
+func (ts *flakyCloudStorage) Get(ctx context.Context, key string, opts ...ReadOption) ([]byte, error) {
+	ts.calls++
+	if ts.calls <= ts.failures {
+		return nil, ts.err
+	}
+
+	return []byte(key), nil
+}
+
+func TestRetryCloudStorageRetriesTransientErrors(t *testing.T) {
+	flaky := &flakyCloudStorage{failures: 2, err: &googleapi.Error{Code: 503}}
+	storage := newRetryCloudStorage(flaky, RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond})
+
+	body, err := storage.Get(context.Background(), "key")
+	require.NoError(t, err)
+	require.Equal(t, []byte("key"), body)
+	require.Equal(t, 3, flaky.calls)
+}
+
+func TestRetryCloudStorageStopsOnPermanentErrors(t *testing.T) {
+	flaky := &flakyCloudStorage{failures: 2, err: &googleapi.Error{Code: 404}}
+	storage := newRetryCloudStorage(flaky, RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond})
+
+	_, err := storage.Get(context.Background(), "key")
+	require.Error(t, err)
+	require.Equal(t, 1, flaky.calls)
+}
+
+func TestIsRetryableError(t *testing.T) {
+	require.True(t, IsRetryableError(fmt.Errorf("wrapped: %w", &googleapi.Error{Code: 429})))
+	require.False(t, IsRetryableError(context.Canceled))
+	require.False(t, IsRetryableError(errors.New("unknown")))
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseBackoff: time.Hour, MaxBackoff: 1000 * time.Hour}
+
+	// the shifted base backoff would overflow from the 22nd retry on
+	for retry := 1; retry <= 100; retry++ {
+		backoff := policy.backoff(retry)
+		require.True(t, backoff >= 0 && backoff <= policy.MaxBackoff, "retry %d: %v", retry, backoff)
+	}
+
+	for retry := 1; retry <= 3; retry++ {
+		require.True(t, policy.backoff(retry) <= time.Hour<<uint(retry-1))
+	}
+
+	unbounded := RetryPolicy{BaseBackoff: time.Nanosecond, MaxBackoff: time.Duration(math.MaxInt64)}
+	require.NotPanics(t, func() {
+		for retry := 1; retry <= 100; retry++ {
+			require.True(t, unbounded.backoff(retry) >= 0)
+		}
+	})
+}
+
+func TestIsRetryableAWSTransportError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	newClient := func(endpoint string) *s3.S3 {
+		awsSession, err := session.NewSession(&aws.Config{
+			Endpoint:         aws.String(endpoint),
+			Region:           aws.String("us-east-1"),
+			S3ForcePathStyle: aws.Bool(true),
+			Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+			HTTPClient:       &http.Client{Timeout: 20 * time.Millisecond},
+			MaxRetries:       aws.Int(0),
+		})
+		require.NoError(t, err)
+
+		return s3.New(awsSession)
+	}
+
+	// the timeout is wrapped in an awserr.Error, which has no Unwrap
+	_, err := newClient(server.URL).GetObject(&s3.GetObjectInput{Bucket: aws.String("bucket"), Key: aws.String("key")})
+	require.Error(t, err)
+	require.True(t, IsRetryableError(err), "%#v", err)
+	require.True(t, IsRetryableError(fmt.Errorf("wrapped: %w", err)))
+
+	var netErr net.Error
+	require.True(t, errorAs(err, &netErr))
+	require.True(t, netErr.Timeout())
+
+	// a refused connection
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	_, err = newClient(closed.URL).GetObject(&s3.GetObjectInput{Bucket: aws.String("bucket"), Key: aws.String("key")})
+	require.Error(t, err)
+	require.True(t, IsRetryableError(err))
+}
@@ -0,0 +1,286 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// ErrSchemaViolation is matched by errors.Is when a write is rejected by the JSON Schema of its rule
+var ErrSchemaViolation = errors.New("schema violation")
+
+// SchemaViolationError gives the violations of the JSON Schema of a rejected write
+type SchemaViolationError struct {
+	Key    string
+	Schema string
+	// Violations are the fields and the descriptions of the violations, or the syntax error of the body
+	Violations []string
+}
+
+func (e *SchemaViolationError) Error() string {
+	return fmt.Sprintf("object '%s' violates the schema '%s': %s", e.Key, e.Schema, strings.Join(e.Violations, "; "))
+}
+
+func (e *SchemaViolationError) Is(target error) bool {
+	return target == ErrSchemaViolation
+}
+
+// SchemaRule validates the objects written under the prefix, with the content type when it's set, against
+// a JSON Schema
+type SchemaRule struct {
+	// Name identifies the schema in the errors
+	Name   string
+	Prefix string
+	// ContentType restricts the rule to a media type, e.g. "application/json", the parameters are ignored
+	ContentType string
+	// Schema is the JSON Schema document
+	Schema []byte
+}
+
+// SchemaValidationOption configures SchemaValidatingCloudStorage
+type SchemaValidationOption struct {
+	// Rules are all applied to the matching writes
+	Rules []SchemaRule
+}
+
+type schemaRule struct {
+	SchemaRule
+	schema *gojsonschema.Schema
+}
+
+// matches returns whether the rule applies to the key, the empty content type being unknown yet
+func (r *schemaRule) matches(key string, contentType string) bool {
+	if !strings.HasPrefix(key, r.Prefix) {
+		return false
+	}
+
+	return r.ContentType == "" || contentType == "" || mediaType(contentType) == mediaType(r.ContentType)
+}
+
+func mediaType(contentType string) string {
+	if value, _, err := mime.ParseMediaType(contentType); err == nil {
+		return value
+	}
+
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// SchemaValidatingCloudStorage rejects the writes of JSON bodies violating the schema of their rules with
+// a *SchemaViolationError, so the malformed payloads never land in the bucket. GetWriter and Upload buffer
+// the objects the rules may apply to, to validate them before they are written.
+type SchemaValidatingCloudStorage struct {
+	CloudStorage
+	rules []*schemaRule
+}
+
+// NewSchemaValidatingCloudStorage compiles the schemas of the rules, and fails when one is invalid
+func NewSchemaValidatingCloudStorage(
+	storage CloudStorage,
+	opts SchemaValidationOption,
+) (*SchemaValidatingCloudStorage, error) {
+	rules := make([]*schemaRule, 0, len(opts.Rules))
+
+	for _, rule := range opts.Rules {
+		schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(rule.Schema))
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema '%s': %w", rule.Name, err)
+		}
+
+		rules = append(rules, &schemaRule{SchemaRule: rule, schema: schema})
+	}
+
+	return &SchemaValidatingCloudStorage{CloudStorage: storage, rules: rules}, nil
+}
+
+// matches returns whether a rule may apply to the key, the empty content type being unknown yet
+func (ts *SchemaValidatingCloudStorage) matches(key string, contentType string) bool {
+	for _, rule := range ts.rules {
+		if rule.matches(key, contentType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validate validates the body against the rules of the key and the content type
+func (ts *SchemaValidatingCloudStorage) validate(key string, contentType string, body []byte) error {
+	for _, rule := range ts.rules {
+		if !rule.matches(key, contentType) {
+			continue
+		}
+
+		result, err := rule.schema.Validate(gojsonschema.NewBytesLoader(body))
+		if err != nil {
+			return &SchemaViolationError{Key: key, Schema: rule.Name, Violations: []string{err.Error()}}
+		}
+
+		if result.Valid() {
+			continue
+		}
+
+		violations := make([]string, 0, len(result.Errors()))
+		for _, violation := range result.Errors() {
+			violations = append(violations, violation.String())
+		}
+
+		return &SchemaViolationError{Key: key, Schema: rule.Name, Violations: violations}
+	}
+
+	return nil
+}
+
+func (ts *SchemaValidatingCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	value := writeOptions(opts).contentType
+	if contentType != nil {
+		value = *contentType
+	}
+
+	if value == "" {
+		value = detectContentType(key, body)
+	}
+
+	if err := ts.validate(key, value, body); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.Write(ctx, key, body, contentType, opts...)
+}
+
+func (ts *SchemaValidatingCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	value := detectContentType(key, body)
+	if contentType != nil {
+		value = *contentType
+	}
+
+	if err := ts.validate(key, value, body); err != nil {
+		return "", err
+	}
+
+	return ts.CloudStorage.WriteIf(ctx, key, body, contentType, condition)
+}
+
+// GetWriter buffers the object when a rule may apply to it, and writes it once validated on Close
+func (ts *SchemaValidatingCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	contentType := writeOptions(opts).contentType
+	if contentType == "" {
+		if extension := extensionContentType(key); extension != nil {
+			contentType = *extension
+		}
+	}
+
+	if !ts.matches(key, contentType) {
+		return ts.CloudStorage.GetWriter(ctx, key, opts...)
+	}
+
+	return &schemaValidatingWriter{ctx: ctx, storage: ts, key: key, opts: opts}, nil
+}
+
+type schemaValidatingWriter struct {
+	bytes.Buffer
+	ctx     context.Context
+	storage *SchemaValidatingCloudStorage
+	key     string
+	opts    []WriteOption
+}
+
+func (w *schemaValidatingWriter) Close() error {
+	return w.storage.Write(w.ctx, w.key, w.Bytes(), nil, w.opts...)
+}
+
+// Upload reads the object when a rule may apply to it, and uploads it once validated
+func (ts *SchemaValidatingCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	contentType := writeOptions(writeOpts).contentType
+	if opts != nil && opts.ContentType != "" {
+		contentType = opts.ContentType
+	}
+
+	if contentType == "" {
+		if extension := extensionContentType(key); extension != nil {
+			contentType = *extension
+		}
+	}
+
+	if !ts.matches(key, contentType) {
+		return ts.CloudStorage.Upload(ctx, key, reader, opts, writeOpts...)
+	}
+
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	if contentType == "" {
+		contentType = detectContentType(key, body)
+	}
+
+	if err := ts.validate(key, contentType, body); err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.Upload(ctx, key, bytes.NewReader(body), opts, writeOpts...)
+}
+
+// Copy reads and validates the object when a rule may apply to the destination
+func (ts *SchemaValidatingCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	if !ts.matches(dstKey, "") {
+		return ts.CloudStorage.Copy(ctx, srcKey, dstKey, opts)
+	}
+
+	return streamCopy(ctx, ts.CloudStorage, srcKey, ts, dstKey, opts)
+}
+
+// Flush flushes the wrapped storage
+func (ts *SchemaValidatingCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
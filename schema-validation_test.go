@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const consentSchema = `{
+	"type": "object",
+	"required": ["userId", "consent"],
+	"properties": {
+		"userId": {"type": "string"},
+		"consent": {"type": "boolean"}
+	}
+}`
+
+func TestSchemaValidatingCloudStorage(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+
+	storage, err := NewSchemaValidatingCloudStorage(fake, SchemaValidationOption{
+		Rules: []SchemaRule{{Name: "consent", Prefix: "gdpr/", ContentType: "application/json",
+			Schema: []byte(consentSchema)}},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, storage.Write(ctx, "gdpr/1.json", []byte(`{"userId": "1", "consent": true}`), nil))
+
+	err = storage.Write(ctx, "gdpr/2.json", []byte(`{"userId": 2}`), nil)
+
+	var violationErr *SchemaViolationError
+	require.True(t, errors.As(err, &violationErr))
+	require.Equal(t, "gdpr/2.json", violationErr.Key)
+	require.Equal(t, "consent", violationErr.Schema)
+	require.Len(t, violationErr.Violations, 2)
+
+	err = storage.Write(ctx, "gdpr/3.json", []byte(`{"userId"`), nil)
+	require.True(t, errors.Is(err, ErrSchemaViolation))
+
+	_, err = storage.WriteIf(ctx, "gdpr/2.json", []byte(`[]`), nil, WriteCondition{DoesNotExist: true})
+	require.True(t, errors.Is(err, ErrSchemaViolation))
+
+	err = storage.Upload(ctx, "gdpr/2.json", bytes.NewReader([]byte(`{"consent": false}`)), nil)
+	require.True(t, errors.Is(err, ErrSchemaViolation))
+
+	writer, err := storage.GetWriter(ctx, "gdpr/2.json")
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte(`{"userId": "2"}`))
+	require.NoError(t, err)
+	require.True(t, errors.Is(writer.Close(), ErrSchemaViolation))
+
+	_, err = fake.Get(ctx, "gdpr/2.json")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	require.NoError(t, storage.Upload(ctx, "gdpr/2.json", bytes.NewReader([]byte(`{"userId": "2", "consent": false}`)),
+		nil))
+
+	// the other content types and prefixes aren't validated
+	require.NoError(t, storage.Write(ctx, "gdpr/notes.txt", []byte("not JSON"), nil))
+	require.NoError(t, storage.Write(ctx, "other/1.json", []byte(`{}`), nil))
+
+	contentType := "application/json; charset=utf-8"
+	err = storage.Write(ctx, "gdpr/4", []byte(`{}`), &contentType)
+	require.True(t, errors.Is(err, ErrSchemaViolation))
+}
+
+func TestNewSchemaValidatingCloudStorageInvalidSchema(t *testing.T) {
+	_, err := NewSchemaValidatingCloudStorage(NewFakeCloudStorage("bucket"), SchemaValidationOption{
+		Rules: []SchemaRule{{Name: "broken", Schema: []byte(`{"type": 1}`)}},
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,261 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// DefaultScopedCredentialsDuration is the lifetime of the scoped credentials on AWS when
+	// ScopedCredentialsOption.Duration is not set
+	DefaultScopedCredentialsDuration = time.Hour
+
+	// defaultScopedCredentialsSessionName is the name of the AWS sessions when ScopedCredentialsOption.SessionName
+	// is not set
+	defaultScopedCredentialsSessionName = "common-blob-go"
+)
+
+// gcpSTSEndpoint exchanges the access tokens for the downscoped tokens
+var gcpSTSEndpoint = "https://sts.googleapis.com/v1/token"
+
+// ScopedCredentialsOption limits the credentials of GetScopedCredentials
+type ScopedCredentialsOption struct {
+	// Prefix is the prefix of the keys the credentials can access, empty for the whole bucket
+	Prefix string
+	// ReadOnly limits the credentials to listing and reading the objects, they can also write and delete them otherwise
+	ReadOnly bool
+	// Duration is the lifetime of the AWS credentials, from 15 minutes to the maximum session duration of the role.
+	// Defaults to DefaultScopedCredentialsDuration. The GCP tokens expire with the token of the client, within an hour.
+	Duration time.Duration
+	// AWSRoleARN is the role assumed with the session policy of the prefix. GetFederationToken is used when it's empty,
+	// which requires the client to use the long-term credentials of an IAM user.
+	AWSRoleARN string
+	// SessionName identifies the AWS session in CloudTrail, e.g. the process given the credentials.
+	// Defaults to "common-blob-go".
+	SessionName string
+}
+
+// ScopedCredentials are short-lived credentials limited to a prefix of the bucket, to give another process direct
+// access to the objects without sharing the credentials of the client
+type ScopedCredentials struct {
+	// AccessKeyID, SecretAccessKey and SessionToken are the AWS credentials
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// AccessToken is the GCP OAuth2 access token
+	AccessToken string
+	Expiration  time.Time
+}
+
+// awsScopedCredentialsPolicy returns the session policy allowing the access to the prefix of the bucket
+func awsScopedCredentialsPolicy(bucketName string, opts *ScopedCredentialsOption) (string, error) {
+	objectActions := []string{"s3:GetObject"}
+	if !opts.ReadOnly {
+		objectActions = append(objectActions,
+			"s3:PutObject", "s3:DeleteObject", "s3:AbortMultipartUpload", "s3:ListMultipartUploadParts")
+	}
+
+	bucketResource, objectsResource := awsPolicyResources(bucketName, opts.Prefix)
+
+	policy := map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{
+				"Effect":   "Allow",
+				"Action":   objectActions,
+				"Resource": objectsResource,
+			},
+			{
+				"Effect":   "Allow",
+				"Action":   []string{"s3:ListBucket"},
+				"Resource": bucketResource,
+				"Condition": map[string]interface{}{
+					"StringLike": map[string]string{"s3:prefix": opts.Prefix + "*"},
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(policy)
+
+	return string(body), err
+}
+
+func awsGetScopedCredentials(
+	ctx context.Context,
+	client *sts.STS,
+	bucketName string,
+	opts *ScopedCredentialsOption,
+) (*ScopedCredentials, error) {
+	policy, err := awsScopedCredentialsPolicy(bucketName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	duration := opts.Duration
+	if duration <= 0 {
+		duration = DefaultScopedCredentialsDuration
+	}
+
+	sessionName := opts.SessionName
+	if sessionName == "" {
+		sessionName = defaultScopedCredentialsSessionName
+	}
+
+	var credentials *sts.Credentials
+
+	if opts.AWSRoleARN != "" {
+		output, err := client.AssumeRoleWithContext(ctx, &sts.AssumeRoleInput{
+			RoleArn:         aws.String(opts.AWSRoleARN),
+			RoleSessionName: aws.String(sessionName),
+			Policy:          aws.String(policy),
+			DurationSeconds: aws.Int64(int64(duration / time.Second)),
+		})
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		credentials = output.Credentials
+	} else {
+		output, err := client.GetFederationTokenWithContext(ctx, &sts.GetFederationTokenInput{
+			Name:            aws.String(sessionName),
+			Policy:          aws.String(policy),
+			DurationSeconds: aws.Int64(int64(duration / time.Second)),
+		})
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		credentials = output.Credentials
+	}
+
+	return &ScopedCredentials{
+		AccessKeyID:     aws.StringValue(credentials.AccessKeyId),
+		SecretAccessKey: aws.StringValue(credentials.SecretAccessKey),
+		SessionToken:    aws.StringValue(credentials.SessionToken),
+		Expiration:      aws.TimeValue(credentials.Expiration),
+	}, nil
+}
+
+// gcpAccessBoundary returns the credential access boundary allowing the access to the prefix of the bucket
+func gcpAccessBoundary(bucketName string, opts *ScopedCredentialsOption) (string, error) {
+	role := "inRole:roles/storage.objectAdmin"
+	if opts.ReadOnly {
+		role = "inRole:roles/storage.objectViewer"
+	}
+
+	rule := map[string]interface{}{
+		"availablePermissions": []string{role},
+		"availableResource":    "//storage.googleapis.com/projects/_/buckets/" + bucketName,
+	}
+
+	if opts.Prefix != "" {
+		prefix := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(opts.Prefix)
+		rule["availabilityCondition"] = map[string]string{
+			"expression": fmt.Sprintf(
+				"resource.name.startsWith('projects/_/buckets/%s/objects/%s') || "+
+					"api.getAttribute('storage.googleapis.com/objectListPrefix', '').startsWith('%s')",
+				bucketName, prefix, prefix),
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"accessBoundary": map[string]interface{}{
+			"accessBoundaryRules": []interface{}{rule},
+		},
+	})
+
+	return string(body), err
+}
+
+// gcpGetScopedCredentials exchanges the token of the client for a downscoped token with the Security Token Service
+func gcpGetScopedCredentials(
+	ctx context.Context,
+	tokenSource oauth2.TokenSource,
+	bucketName string,
+	opts *ScopedCredentialsOption,
+	now time.Time,
+) (*ScopedCredentials, error) {
+	boundary, err := gcpAccessBoundary(bucketName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := tokenSource.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":           {"urn:ietf:params:oauth:grant-type:token-exchange"},
+		"subject_token_type":   {"urn:ietf:params:oauth:token-type:access_token"},
+		"requested_token_type": {"urn:ietf:params:oauth:token-type:access_token"},
+		"subject_token":        {token.AccessToken},
+		"options":              {boundary},
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, gcpSTSEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var exchanged struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&exchanged); err != nil {
+		return nil, fmt.Errorf("unable to decode the token exchange response (%s): %w", response.Status, err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		err := fmt.Errorf("unable to exchange the token: %s: %s", exchanged.Error, exchanged.ErrorDescription)
+		if response.StatusCode == http.StatusForbidden || response.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("%w: %v", ErrPermissionDenied, err)
+		}
+
+		return nil, err
+	}
+
+	expiration := token.Expiry
+	if exchanged.ExpiresIn > 0 {
+		expiration = now.Add(time.Duration(exchanged.ExpiresIn) * time.Second)
+	}
+
+	return &ScopedCredentials{AccessToken: exchanged.AccessToken, Expiration: expiration}, nil
+}
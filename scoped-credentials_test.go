@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestAWSGetScopedCredentials(t *testing.T) {
+	var form url.Values
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		form = r.PostForm
+
+		fmt.Fprint(w, `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleResult>
+    <Credentials>
+      <AccessKeyId>ASIA</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>token</SessionToken>
+      <Expiration>2020-06-01T01:00:00Z</Expiration>
+    </Credentials>
+  </AssumeRoleResult>
+</AssumeRoleResponse>`)
+	}))
+	defer server.Close()
+
+	awsSession, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(server.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	scoped, err := awsGetScopedCredentials(context.Background(), sts.New(awsSession), "bucket", &ScopedCredentialsOption{
+		Prefix:     "tenants/a/",
+		ReadOnly:   true,
+		AWSRoleARN: "arn:aws:iam::123456789012:role/uploader",
+	})
+	require.NoError(t, err)
+	require.Equal(t, &ScopedCredentials{
+		AccessKeyID:     "ASIA",
+		SecretAccessKey: "secret",
+		SessionToken:    "token",
+		Expiration:      time.Date(2020, 6, 1, 1, 0, 0, 0, time.UTC),
+	}, scoped)
+
+	require.Equal(t, "AssumeRole", form.Get("Action"))
+	require.Equal(t, "3600", form.Get("DurationSeconds"))
+	require.Equal(t, "common-blob-go", form.Get("RoleSessionName"))
+
+	var policy struct {
+		Statement []struct {
+			Action    []string
+			Resource  string
+			Condition map[string]map[string]string
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(form.Get("Policy")), &policy))
+	require.Len(t, policy.Statement, 2)
+	require.Equal(t, []string{"s3:GetObject"}, policy.Statement[0].Action)
+	require.Equal(t, "arn:aws:s3:::bucket/tenants/a/*", policy.Statement[0].Resource)
+	require.Equal(t, "tenants/a/*", policy.Statement[1].Condition["StringLike"]["s3:prefix"])
+}
+
+func TestGCPGetScopedCredentials(t *testing.T) {
+	var form url.Values
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		form = r.PostForm
+
+		if form.Get("subject_token") != "source" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": "invalid_grant", "error_description": "invalid token"}`)
+
+			return
+		}
+
+		fmt.Fprint(w, `{"access_token": "downscoped", "expires_in": 1800}`)
+	}))
+	defer server.Close()
+
+	endpoint := gcpSTSEndpoint
+	gcpSTSEndpoint = server.URL
+
+	defer func() { gcpSTSEndpoint = endpoint }()
+
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	source := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "source"})
+
+	scoped, err := gcpGetScopedCredentials(context.Background(), source, "bucket", &ScopedCredentialsOption{
+		Prefix: "tenants/a'/",
+	}, now)
+	require.NoError(t, err)
+	require.Equal(t, &ScopedCredentials{AccessToken: "downscoped", Expiration: now.Add(30 * time.Minute)}, scoped)
+
+	var boundary struct {
+		AccessBoundary struct {
+			AccessBoundaryRules []struct {
+				AvailablePermissions  []string
+				AvailableResource     string
+				AvailabilityCondition struct{ Expression string }
+			}
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(form.Get("options")), &boundary))
+
+	rule := boundary.AccessBoundary.AccessBoundaryRules[0]
+	require.Equal(t, []string{"inRole:roles/storage.objectAdmin"}, rule.AvailablePermissions)
+	require.Equal(t, "//storage.googleapis.com/projects/_/buckets/bucket", rule.AvailableResource)
+	require.Equal(t, `resource.name.startsWith('projects/_/buckets/bucket/objects/tenants/a\'/') || `+
+		`api.getAttribute('storage.googleapis.com/objectListPrefix', '').startsWith('tenants/a\'/')`,
+		rule.AvailabilityCondition.Expression)
+
+	_, err = gcpGetScopedCredentials(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "x"}),
+		"bucket", &ScopedCredentialsOption{}, now)
+	require.True(t, errors.Is(err, ErrPermissionDenied))
+}
+
+func TestScopedStorageGetScopedCredentials(t *testing.T) {
+	storage := NewFakeCloudStorage("bucket")
+
+	tenant, err := ScopedStorage(storage, "tenants/a/")
+	require.NoError(t, err)
+
+	scoped, err := tenant.GetScopedCredentials(context.Background(), &ScopedCredentialsOption{Prefix: "docs/", ReadOnly: true})
+	require.NoError(t, err)
+	require.Equal(t, "fake:ro:tenants/a/docs/", scoped.AccessToken)
+
+	_, err = tenant.GetScopedCredentials(context.Background(), &ScopedCredentialsOption{Prefix: "../"})
+	require.True(t, errors.Is(err, ErrInvalidKey))
+}
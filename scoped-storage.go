@@ -0,0 +1,656 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ScopeOption configures a ScopedCloudStorage
+type ScopeOption struct {
+	// MaxBytes and MaxObjects enforce a quota on the prefix, see QuotaCloudStorage. A zero limit is unlimited.
+	MaxBytes   int64
+	MaxObjects int64
+	// QuotaReconcileInterval is the age of the usage of the prefix after which it's listed again.
+	// Defaults to DefaultQuotaReconcileInterval.
+	QuotaReconcileInterval time.Duration
+}
+
+// ScopedCloudStorage namespaces every key under a prefix, e.g. the prefix of a tenant, so the business logic
+// can be given a handle unable to reach the objects of the other tenants. The keys can't escape the prefix:
+// the keys rejected by ValidateKey, e.g. with a ".." segment, fail with an *InvalidKeyError.
+//
+// List, Subscribe and ListIncompleteUploads give the keys relative to the prefix. The operations on the bucket
+// (CreateBucket, CreateBucketWithOptions, Get/SetBucketPolicy, Get/SetBucketEncryption, Get/SetBucketLogging,
+// Get/SetBucketReplication and AbortStaleUploads) return ErrPermissionDenied, and Close doesn't close the wrapped
+// storage, which is shared by the scopes. The state of the upload sessions has the full key, ResumeUpload rejects
+// the states of the other prefixes.
+type ScopedCloudStorage struct {
+	storage CloudStorage
+	prefix  string
+}
+
+var _ CloudStorage = (*ScopedCloudStorage)(nil)
+
+// ScopedStorage returns the storage namespacing every key under the prefix, "/" being appended to it if needed
+func ScopedStorage(storage CloudStorage, prefix string) (*ScopedCloudStorage, error) {
+	return ScopedStorageWithOption(storage, prefix, ScopeOption{})
+}
+
+// ScopedStorageWithOption returns the storage namespacing every key under the prefix, enforcing the quota of
+// the option. The usage of the quota is tracked per handle, so a handle should be kept per prefix.
+func ScopedStorageWithOption(storage CloudStorage, prefix string, opts ScopeOption) (*ScopedCloudStorage, error) {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	if err := ValidateKey(prefix); err != nil {
+		return nil, err
+	}
+
+	if opts.MaxBytes > 0 || opts.MaxObjects > 0 {
+		storage = NewQuotaCloudStorage(storage, QuotaOption{
+			Quotas:            []Quota{{Prefix: prefix, MaxBytes: opts.MaxBytes, MaxObjects: opts.MaxObjects}},
+			ReconcileInterval: opts.QuotaReconcileInterval,
+		})
+	}
+
+	return &ScopedCloudStorage{storage: storage, prefix: prefix}, nil
+}
+
+// Prefix returns the prefix of the keys, ending with "/"
+func (ts *ScopedCloudStorage) Prefix() string {
+	return ts.prefix
+}
+
+// key returns the full key of a key of the scope
+func (ts *ScopedCloudStorage) key(key string) (string, error) {
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+
+	return ts.prefix + key, nil
+}
+
+// listPrefix returns the full prefix of a prefix of the scope, which can be empty
+func (ts *ScopedCloudStorage) listPrefix(prefix string) (string, error) {
+	if prefix == "" {
+		return ts.prefix, nil
+	}
+
+	return ts.key(prefix)
+}
+
+// bucketOperation returns the error of the operations on the bucket, which can't be scoped
+func (ts *ScopedCloudStorage) bucketOperation(operation string) error {
+	return fmt.Errorf("%w: %s isn't allowed in the scope '%s'", ErrPermissionDenied, operation, ts.prefix)
+}
+
+func (ts *ScopedCloudStorage) List(
+	ctx context.Context,
+	prefix string,
+	opts ...ListOption,
+) *ListIterator {
+	fullPrefix, err := ts.listPrefix(prefix)
+	if err != nil {
+		return NewListIterator(ctx, func(ctx context.Context) (*ListObject, error) {
+			return nil, err
+		})
+	}
+
+	if resumeFrom := listOptions(opts).resumeFrom; resumeFrom != "" {
+		opts = append(opts[:len(opts):len(opts)], WithResumeFrom(ts.prefix+resumeFrom))
+	}
+
+	iter := ts.storage.List(ctx, fullPrefix, opts...)
+
+	return NewListIterator(ctx, func(ctx context.Context) (*ListObject, error) {
+		object, err := iter.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		scoped := *object
+		scoped.Key = strings.TrimPrefix(object.Key, ts.prefix)
+
+		return &scoped, nil
+	})
+}
+
+func (ts *ScopedCloudStorage) Get(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) ([]byte, error) {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.storage.Get(ctx, fullKey, opts...)
+}
+
+func (ts *ScopedCloudStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return err
+	}
+
+	return ts.storage.Delete(ctx, fullKey)
+}
+
+func (ts *ScopedCloudStorage) CreateBucket(
+	ctx context.Context,
+	bucketPrefix string,
+	expirationTimeDays int64,
+) error {
+	return ts.bucketOperation("CreateBucket")
+}
+
+func (ts *ScopedCloudStorage) CreateBucketWithOptions(
+	ctx context.Context,
+	opts *CreateBucketOption,
+) error {
+	return ts.bucketOperation("CreateBucketWithOptions")
+}
+
+// Close does nothing, the wrapped storage is shared by the scopes
+func (ts *ScopedCloudStorage) Close() {}
+
+func (ts *ScopedCloudStorage) GetSignedURL(
+	ctx context.Context,
+	key string,
+	opts *SignedURLOption,
+) (string, error) {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return "", err
+	}
+
+	return ts.storage.GetSignedURL(ctx, fullKey, opts)
+}
+
+func (ts *ScopedCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return err
+	}
+
+	return ts.storage.Write(ctx, fullKey, body, contentType, opts...)
+}
+
+func (ts *ScopedCloudStorage) Attributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (*Attributes, error) {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.storage.Attributes(ctx, fullKey, opts...)
+}
+
+func (ts *ScopedCloudStorage) GetReader(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.storage.GetReader(ctx, fullKey, opts...)
+}
+
+func (ts *ScopedCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ts.storage.GetWithAttributes(ctx, fullKey, opts...)
+}
+
+func (ts *ScopedCloudStorage) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset,
+	length int64,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.storage.GetRangeReader(ctx, fullKey, offset, length, opts...)
+}
+
+func (ts *ScopedCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.storage.GetWriter(ctx, fullKey, opts...)
+}
+
+func (ts *ScopedCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return err
+	}
+
+	return ts.storage.Upload(ctx, fullKey, reader, opts, writeOpts...)
+}
+
+func (ts *ScopedCloudStorage) SetObjectRetention(
+	ctx context.Context,
+	key string,
+	retention *ObjectRetention,
+) error {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return err
+	}
+
+	return ts.storage.SetObjectRetention(ctx, fullKey, retention)
+}
+
+func (ts *ScopedCloudStorage) GetObjectRetention(
+	ctx context.Context,
+	key string,
+) (*ObjectRetention, error) {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.storage.GetObjectRetention(ctx, fullKey)
+}
+
+func (ts *ScopedCloudStorage) SetLegalHold(
+	ctx context.Context,
+	key string,
+	enabled bool,
+) error {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return err
+	}
+
+	return ts.storage.SetLegalHold(ctx, fullKey, enabled)
+}
+
+func (ts *ScopedCloudStorage) GetLegalHold(
+	ctx context.Context,
+	key string,
+) (bool, error) {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return false, err
+	}
+
+	return ts.storage.GetLegalHold(ctx, fullKey)
+}
+
+func (ts *ScopedCloudStorage) SetStorageClass(
+	ctx context.Context,
+	key string,
+	storageClass string,
+) error {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return err
+	}
+
+	return ts.storage.SetStorageClass(ctx, fullKey, storageClass)
+}
+
+func (ts *ScopedCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	fullSrcKey, err := ts.key(srcKey)
+	if err != nil {
+		return err
+	}
+
+	fullDstKey, err := ts.key(dstKey)
+	if err != nil {
+		return err
+	}
+
+	return ts.storage.Copy(ctx, fullSrcKey, fullDstKey, opts)
+}
+
+func (ts *ScopedCloudStorage) Undelete(
+	ctx context.Context,
+	key string,
+) error {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return err
+	}
+
+	return ts.storage.Undelete(ctx, fullKey)
+}
+
+func (ts *ScopedCloudStorage) GetBucketPolicy(
+	ctx context.Context,
+) (*BucketPolicy, error) {
+	return nil, ts.bucketOperation("GetBucketPolicy")
+}
+
+// GetScopedCredentials returns the credentials of the prefix of the scope, under the prefix of the options
+func (ts *ScopedCloudStorage) GetScopedCredentials(
+	ctx context.Context,
+	opts *ScopedCredentialsOption,
+) (*ScopedCredentials, error) {
+	prefix, err := ts.listPrefix(opts.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	scoped := *opts
+	scoped.Prefix = prefix
+
+	return ts.storage.GetScopedCredentials(ctx, &scoped)
+}
+
+func (ts *ScopedCloudStorage) CreateFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	fullFolder, err := ts.key(folder)
+	if err != nil {
+		return err
+	}
+
+	return ts.storage.CreateFolder(ctx, fullFolder)
+}
+
+func (ts *ScopedCloudStorage) DeleteFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	fullFolder, err := ts.key(folder)
+	if err != nil {
+		return err
+	}
+
+	return ts.storage.DeleteFolder(ctx, fullFolder)
+}
+
+func (ts *ScopedCloudStorage) RenameFolder(
+	ctx context.Context,
+	folder string,
+	newFolder string,
+) error {
+	fullFolder, err := ts.key(folder)
+	if err != nil {
+		return err
+	}
+
+	fullNewFolder, err := ts.key(newFolder)
+	if err != nil {
+		return err
+	}
+
+	return ts.storage.RenameFolder(ctx, fullFolder, fullNewFolder)
+}
+
+func (ts *ScopedCloudStorage) SetBucketPolicy(
+	ctx context.Context,
+	policy *BucketPolicy,
+) error {
+	return ts.bucketOperation("SetBucketPolicy")
+}
+
+func (ts *ScopedCloudStorage) GetBucketEncryption(
+	ctx context.Context,
+) (*BucketEncryption, error) {
+	return nil, ts.bucketOperation("GetBucketEncryption")
+}
+
+func (ts *ScopedCloudStorage) SetBucketEncryption(
+	ctx context.Context,
+	encryption *BucketEncryption,
+) error {
+	return ts.bucketOperation("SetBucketEncryption")
+}
+
+func (ts *ScopedCloudStorage) GetBucketLogging(
+	ctx context.Context,
+) (*BucketLogging, error) {
+	return nil, ts.bucketOperation("GetBucketLogging")
+}
+
+func (ts *ScopedCloudStorage) SetBucketLogging(
+	ctx context.Context,
+	logging *BucketLogging,
+) error {
+	return ts.bucketOperation("SetBucketLogging")
+}
+
+func (ts *ScopedCloudStorage) GetBucketReplication(
+	ctx context.Context,
+) (*BucketReplication, error) {
+	return nil, ts.bucketOperation("GetBucketReplication")
+}
+
+func (ts *ScopedCloudStorage) SetBucketReplication(
+	ctx context.Context,
+	replication *BucketReplication,
+) error {
+	return ts.bucketOperation("SetBucketReplication")
+}
+
+// GetPublicURL returns an empty string for the keys escaping the prefix
+func (ts *ScopedCloudStorage) GetPublicURL(key string) string {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return ""
+	}
+
+	return ts.storage.GetPublicURL(fullKey)
+}
+
+// As sets target to the client of the underlying storage, which isn't restricted to the prefix
+func (ts *ScopedCloudStorage) As(target interface{}) bool {
+	return ts.storage.As(target)
+}
+
+func (ts *ScopedCloudStorage) ErrorAs(err error, target interface{}) bool {
+	return ts.storage.ErrorAs(err, target)
+}
+
+func (ts *ScopedCloudStorage) Ping(ctx context.Context) error {
+	return ts.storage.Ping(ctx)
+}
+
+func (ts *ScopedCloudStorage) Subscribe(
+	ctx context.Context,
+	prefix string,
+) (<-chan ObjectEvent, error) {
+	fullPrefix, err := ts.listPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := ts.storage.Subscribe(ctx, fullPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	scoped := make(chan ObjectEvent)
+
+	go func() {
+		defer close(scoped)
+
+		for event := range events {
+			event.Key = strings.TrimPrefix(event.Key, ts.prefix)
+
+			select {
+			case scoped <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return scoped, nil
+}
+
+func (ts *ScopedCloudStorage) ListIncompleteUploads(
+	ctx context.Context,
+	prefix string,
+) ([]*IncompleteUpload, error) {
+	fullPrefix, err := ts.listPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	uploads, err := ts.storage.ListIncompleteUploads(ctx, fullPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	scoped := make([]*IncompleteUpload, 0, len(uploads))
+
+	for _, upload := range uploads {
+		scopedUpload := *upload
+		scopedUpload.Key = strings.TrimPrefix(upload.Key, ts.prefix)
+		scoped = append(scoped, &scopedUpload)
+	}
+
+	return scoped, nil
+}
+
+func (ts *ScopedCloudStorage) AbortStaleUploads(
+	ctx context.Context,
+	olderThan time.Duration,
+) ([]*IncompleteUpload, error) {
+	return nil, ts.bucketOperation("AbortStaleUploads")
+}
+
+func (ts *ScopedCloudStorage) Query(
+	ctx context.Context,
+	key string,
+	sql string,
+	format QueryInputFormat,
+) (io.ReadCloser, error) {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.storage.Query(ctx, fullKey, sql, format)
+}
+
+func (ts *ScopedCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return "", err
+	}
+
+	return ts.storage.WriteIf(ctx, fullKey, body, contentType, condition)
+}
+
+func (ts *ScopedCloudStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return err
+	}
+
+	return ts.storage.DeleteIf(ctx, fullKey, generation)
+}
+
+func (ts *ScopedCloudStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	fullKey, err := ts.key(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.storage.BeginUpload(ctx, fullKey, opts)
+}
+
+func (ts *ScopedCloudStorage) ResumeUpload(
+	ctx context.Context,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	if state == nil || !strings.HasPrefix(state.Key, ts.prefix) {
+		return nil, ts.bucketOperation("resuming an upload of another prefix")
+	}
+
+	if _, err := ts.key(strings.TrimPrefix(state.Key, ts.prefix)); err != nil {
+		return nil, err
+	}
+
+	return ts.storage.ResumeUpload(ctx, state)
+}
+
+// Flush flushes the wrapped storage, including the writes made outside of the scope
+func (ts *ScopedCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.storage)
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopedStorage(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	tenantA, err := ScopedStorage(storage, "tenants/a")
+	require.NoError(t, err)
+	require.Equal(t, "tenants/a/", tenantA.Prefix())
+
+	tenantB, err := ScopedStorage(storage, "tenants/b/")
+	require.NoError(t, err)
+
+	require.NoError(t, tenantA.Write(ctx, "docs/x", []byte("a"), nil))
+	require.NoError(t, tenantB.Write(ctx, "docs/x", []byte("b"), nil))
+
+	body, err := storage.Get(ctx, "tenants/a/docs/x")
+	require.NoError(t, err)
+	require.Equal(t, []byte("a"), body)
+
+	body, err = tenantB.Get(ctx, "docs/x")
+	require.NoError(t, err)
+	require.Equal(t, []byte("b"), body)
+
+	objects, err := tenantA.List(ctx, "").Next(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "docs/x", objects.Key)
+
+	uploads, err := tenantA.ListIncompleteUploads(ctx, "")
+	require.NoError(t, err)
+	require.Empty(t, uploads)
+
+	require.NoError(t, tenantA.Delete(ctx, "docs/x"))
+
+	_, err = storage.Get(ctx, "tenants/b/docs/x")
+	require.NoError(t, err)
+}
+
+func TestScopedStorageRefusesToEscape(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	tenant, err := ScopedStorage(storage, "tenants/a/")
+	require.NoError(t, err)
+
+	for _, key := range []string{"../b/x", "x/../../b/x", "/x", ""} {
+		err := tenant.Write(ctx, key, []byte("body"), nil)
+		require.True(t, errors.Is(err, ErrInvalidKey), key)
+	}
+
+	_, err = tenant.List(ctx, "../").Next(ctx)
+	require.True(t, errors.Is(err, ErrInvalidKey))
+
+	require.True(t, errors.Is(tenant.CreateBucket(ctx, "bucket", 1), ErrPermissionDenied))
+
+	_, err = tenant.AbortStaleUploads(ctx, time.Hour)
+	require.True(t, errors.Is(err, ErrPermissionDenied))
+
+	_, err = tenant.ResumeUpload(ctx, &UploadSessionState{Key: "tenants/b/x", UploadID: "id", PartSize: 1})
+	require.True(t, errors.Is(err, ErrPermissionDenied))
+
+	_, err = ScopedStorage(storage, "../")
+	require.True(t, errors.Is(err, ErrInvalidKey))
+}
+
+func TestScopedStorageQuota(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	tenant, err := ScopedStorageWithOption(storage, "tenants/a/", ScopeOption{MaxObjects: 1})
+	require.NoError(t, err)
+
+	require.NoError(t, tenant.Write(ctx, "x", []byte("body"), nil))
+	require.True(t, errors.Is(tenant.Write(ctx, "y", []byte("body"), nil), ErrQuotaExceeded))
+
+	// the objects out of the scope don't count
+	require.NoError(t, storage.Write(ctx, "tenants/b/x", []byte("body"), nil))
+}
+
+func TestScopedStorageSubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storage := NewFakeCloudStorage("bucket")
+
+	tenant, err := ScopedStorage(storage, "tenants/a/")
+	require.NoError(t, err)
+
+	events, err := tenant.Subscribe(ctx, "")
+	require.NoError(t, err)
+
+	require.NoError(t, storage.Write(ctx, "tenants/b/x", []byte("body"), nil))
+	require.NoError(t, tenant.Write(ctx, "x", []byte("body"), nil))
+
+	select {
+	case event := <-events:
+		require.Equal(t, ObjectCreated, event.Type)
+		require.Equal(t, "x", event.Key)
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+	}
+}
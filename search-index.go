@@ -0,0 +1,471 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSearchIndexPrefix is the prefix of the index entries in the bucket when IndexOption.Store is not set
+const DefaultSearchIndexPrefix = ".index/"
+
+// IndexEntry is the indexed description of an object
+type IndexEntry struct {
+	Key         string            `json:"key"`
+	Size        int64             `json:"size"`
+	ModTime     time.Time         `json:"mod_time"`
+	ContentType string            `json:"content_type,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// SearchFilter selects the index entries. The zero fields don't filter.
+type SearchFilter struct {
+	// Prefix of the keys
+	Prefix string
+	// MinSize and MaxSize bound the size, inclusive
+	MinSize int64
+	MaxSize int64
+	// ModifiedAfter and ModifiedBefore bound the modification time, exclusive
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+	ContentType    string
+	// Metadata must all be set on the object with the same values
+	Metadata map[string]string
+	// Limit is the maximum number of entries returned
+	Limit int
+}
+
+// Match returns whether the entry matches all the filters
+func (f *SearchFilter) Match(entry *IndexEntry) bool {
+	switch {
+	case !strings.HasPrefix(entry.Key, f.Prefix):
+		return false
+	case entry.Size < f.MinSize:
+		return false
+	case f.MaxSize > 0 && entry.Size > f.MaxSize:
+		return false
+	case !f.ModifiedAfter.IsZero() && !entry.ModTime.After(f.ModifiedAfter):
+		return false
+	case !f.ModifiedBefore.IsZero() && !entry.ModTime.Before(f.ModifiedBefore):
+		return false
+	case f.ContentType != "" && entry.ContentType != f.ContentType:
+		return false
+	}
+
+	for name, value := range f.Metadata {
+		if actual, ok := entry.Metadata[name]; !ok || actual != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IndexStore holds the index entries, e.g. in the bucket with NewBucketIndexStore, or in a database
+type IndexStore interface {
+	Put(ctx context.Context, entry *IndexEntry) error
+	// Delete doesn't fail when the key isn't indexed
+	Delete(ctx context.Context, key string) error
+	// Scan calls fn with the entries of the keys under the prefix, in any order, until fn returns an error
+	Scan(ctx context.Context, prefix string, fn func(entry *IndexEntry) error) error
+}
+
+// Search returns the entries of the store matching the filter, sorted by key.
+// The limit is applied once sorted, so the whole prefix is scanned.
+func Search(ctx context.Context, store IndexStore, filter *SearchFilter) ([]*IndexEntry, error) {
+	if filter == nil {
+		filter = &SearchFilter{}
+	}
+
+	var entries []*IndexEntry
+
+	err := store.Scan(ctx, filter.Prefix, func(entry *IndexEntry) error {
+		if !filter.Match(entry) {
+			return nil
+		}
+
+		entries = append(entries, entry)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+
+	return entries, nil
+}
+
+// BucketIndexStore stores every entry as a JSON object under its prefix, followed by the key of the object,
+// so the scan of a key prefix only lists the entries under it
+type BucketIndexStore struct {
+	storage CloudStorage
+	prefix  string
+}
+
+// NewBucketIndexStore returns the store of the entries under the prefix of the storage
+func NewBucketIndexStore(storage CloudStorage, prefix string) *BucketIndexStore {
+	return &BucketIndexStore{storage: storage, prefix: prefix}
+}
+
+func (s *BucketIndexStore) Put(ctx context.Context, entry *IndexEntry) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	contentType := "application/json"
+
+	return s.storage.Write(ctx, s.prefix+entry.Key, body, &contentType)
+}
+
+func (s *BucketIndexStore) Delete(ctx context.Context, key string) error {
+	err := s.storage.Delete(ctx, s.prefix+key)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *BucketIndexStore) Scan(ctx context.Context, prefix string, fn func(entry *IndexEntry) error) error {
+	iter := s.storage.List(ctx, s.prefix+prefix)
+
+	for {
+		object, err := iter.Next(ctx)
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		body, err := s.storage.Get(ctx, object.Key)
+		if errors.Is(err, ErrNotFound) {
+			// deleted since it was listed
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var entry IndexEntry
+		if err := json.Unmarshal(body, &entry); err != nil {
+			return err
+		}
+
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+}
+
+// MemoryIndexStore keeps the entries in memory, for the tests and the single instance services
+// rebuilding the index with IndexedCloudStorage.Reindex on start
+type MemoryIndexStore struct {
+	mu      sync.RWMutex
+	entries map[string]*IndexEntry
+}
+
+// NewMemoryIndexStore returns an empty MemoryIndexStore
+func NewMemoryIndexStore() *MemoryIndexStore {
+	return &MemoryIndexStore{entries: make(map[string]*IndexEntry)}
+}
+
+func (s *MemoryIndexStore) Put(ctx context.Context, entry *IndexEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[entry.Key] = entry
+
+	return nil
+}
+
+func (s *MemoryIndexStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+
+	return nil
+}
+
+func (s *MemoryIndexStore) Scan(ctx context.Context, prefix string, fn func(entry *IndexEntry) error) error {
+	s.mu.RLock()
+
+	var entries []*IndexEntry
+
+	for key, entry := range s.entries {
+		if strings.HasPrefix(key, prefix) {
+			entries = append(entries, entry)
+		}
+	}
+
+	// fn may use the store
+	s.mu.RUnlock()
+
+	for _, entry := range entries {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IndexOption configures the search index
+type IndexOption struct {
+	// Store holds the entries. Defaults to a BucketIndexStore under Prefix in the wrapped storage.
+	Store IndexStore
+	// Prefix of the entries when Store is not set. Defaults to DefaultSearchIndexPrefix.
+	Prefix string
+}
+
+// IndexedCloudStorage records the key, the size, the modification time, the content type and the metadata of
+// every object written through it in an IndexStore, to Search them by more than their prefix.
+//
+// The entry is written after the object, from its attributes, and deleted after the object, so a failed
+// indexing leaves the index stale until Reindex, and the write returns the error of the index.
+type IndexedCloudStorage struct {
+	CloudStorage
+	store IndexStore
+	// hidden is the prefix of the entries in the wrapped storage, skipped by List
+	hidden string
+}
+
+// NewIndexedCloudStorage returns the storage indexing the objects written to it
+func NewIndexedCloudStorage(storage CloudStorage, opts IndexOption) *IndexedCloudStorage {
+	ts := &IndexedCloudStorage{CloudStorage: storage, store: opts.Store}
+
+	if ts.store == nil {
+		if opts.Prefix == "" {
+			opts.Prefix = DefaultSearchIndexPrefix
+		}
+
+		ts.store = NewBucketIndexStore(storage, opts.Prefix)
+		ts.hidden = opts.Prefix
+	}
+
+	return ts
+}
+
+// Search returns the indexed objects matching the filter, sorted by key
+func (ts *IndexedCloudStorage) Search(ctx context.Context, filter *SearchFilter) ([]*IndexEntry, error) {
+	return Search(ctx, ts.store, filter)
+}
+
+// Reindex indexes all the objects of the storage, and removes the entries of the objects which don't exist,
+// e.g. to index a bucket written before the index was enabled, or to repair it after failed indexings
+func (ts *IndexedCloudStorage) Reindex(ctx context.Context) error {
+	objects, err := listByName(ctx, ts, "")
+	if err != nil {
+		return err
+	}
+
+	for key := range objects {
+		if err := ts.index(ctx, key); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+
+	var stale []string
+
+	err = ts.store.Scan(ctx, "", func(entry *IndexEntry) error {
+		if _, ok := objects[entry.Key]; !ok {
+			stale = append(stale, entry.Key)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range stale {
+		if err := ts.store.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// index records the current attributes of the object
+func (ts *IndexedCloudStorage) index(ctx context.Context, key string) error {
+	attrs, err := ts.CloudStorage.Attributes(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	return ts.store.Put(ctx, &IndexEntry{
+		Key:         key,
+		Size:        attrs.Size,
+		ModTime:     attrs.ModTime,
+		ContentType: attrs.ContentType,
+		Metadata:    attrs.Metadata,
+	})
+}
+
+// List skips the entries of the index stored in the bucket
+func (ts *IndexedCloudStorage) List(
+	ctx context.Context,
+	prefix string,
+	opts ...ListOption,
+) *ListIterator {
+	iter := ts.CloudStorage.List(ctx, prefix, opts...)
+	if ts.hidden == "" {
+		return iter
+	}
+
+	return NewListIterator(ctx, func(ctx context.Context) (*ListObject, error) {
+		for {
+			object, err := iter.Next(ctx)
+			if err != nil || !strings.HasPrefix(object.Key, ts.hidden) {
+				return object, err
+			}
+		}
+	})
+}
+
+func (ts *IndexedCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	if err := ts.CloudStorage.Write(ctx, key, body, contentType, opts...); err != nil {
+		return err
+	}
+
+	return ts.index(ctx, key)
+}
+
+func (ts *IndexedCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	writer, err := ts.CloudStorage.GetWriter(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &indexWriter{WriteCloser: writer, ctx: ctx, storage: ts, key: key}, nil
+}
+
+// indexWriter indexes the object once it's written
+type indexWriter struct {
+	io.WriteCloser
+	ctx     context.Context
+	storage *IndexedCloudStorage
+	key     string
+}
+
+func (w *indexWriter) Close() error {
+	if err := w.WriteCloser.Close(); err != nil {
+		return err
+	}
+
+	return w.storage.index(w.ctx, w.key)
+}
+
+func (ts *IndexedCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	if err := ts.CloudStorage.Upload(ctx, key, reader, opts, writeOpts...); err != nil {
+		return err
+	}
+
+	return ts.index(ctx, key)
+}
+
+func (ts *IndexedCloudStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	if err := ts.CloudStorage.Delete(ctx, key); err != nil {
+		return err
+	}
+
+	return ts.store.Delete(ctx, key)
+}
+
+func (ts *IndexedCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	generation, err := ts.CloudStorage.WriteIf(ctx, key, body, contentType, condition)
+	if err != nil {
+		return "", err
+	}
+
+	return generation, ts.index(ctx, key)
+}
+
+func (ts *IndexedCloudStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	if err := ts.CloudStorage.DeleteIf(ctx, key, generation); err != nil {
+		return err
+	}
+
+	return ts.store.Delete(ctx, key)
+}
+
+func (ts *IndexedCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	if err := ts.CloudStorage.Copy(ctx, srcKey, dstKey, opts); err != nil {
+		return err
+	}
+
+	return ts.index(ctx, dstKey)
+}
+
+// Flush flushes the wrapped storage
+func (ts *IndexedCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
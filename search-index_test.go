@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func searchKeys(entries []*IndexEntry) []string {
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+	}
+
+	return keys
+}
+
+func TestIndexedCloudStorage(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	storage := NewIndexedCloudStorage(fake, IndexOption{})
+	contentType := "text/csv"
+
+	require.NoError(t, storage.Write(ctx, "exports/a.csv", []byte("a,b"), &contentType))
+	require.NoError(t, storage.Upload(ctx, "exports/b.json", bytes.NewReader([]byte(`{"a":1}`)),
+		&UploadOption{ContentType: "application/json"}))
+
+	writer, err := storage.GetWriter(ctx, "reports/c.csv")
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("a,b,c,d"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	entries, err := storage.Search(ctx, &SearchFilter{ContentType: "text/csv"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"exports/a.csv"}, searchKeys(entries))
+	require.Equal(t, int64(3), entries[0].Size)
+
+	entries, err = storage.Search(ctx, &SearchFilter{MinSize: 4})
+	require.NoError(t, err)
+	require.Equal(t, []string{"exports/b.json", "reports/c.csv"}, searchKeys(entries))
+
+	entries, err = storage.Search(ctx, &SearchFilter{Prefix: "exports/", Limit: 1})
+	require.NoError(t, err)
+	require.Equal(t, []string{"exports/a.csv"}, searchKeys(entries))
+
+	require.NoError(t, storage.Delete(ctx, "exports/a.csv"))
+
+	entries, err = storage.Search(ctx, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"exports/b.json", "reports/c.csv"}, searchKeys(entries))
+
+	// the entries aren't listed
+	iter := storage.List(ctx, "")
+
+	var keys []string
+
+	for {
+		object, err := iter.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+
+		keys = append(keys, object.Key)
+	}
+
+	require.ElementsMatch(t, []string{"exports/b.json", "reports/c.csv"}, keys)
+}
+
+func TestIndexedCloudStorageReindex(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	store := NewMemoryIndexStore()
+	storage := NewIndexedCloudStorage(fake, IndexOption{Store: store})
+
+	require.NoError(t, fake.Write(ctx, "legacy.txt", []byte("legacy"), nil))
+	require.NoError(t, store.Put(ctx, &IndexEntry{Key: "deleted.txt"}))
+
+	require.NoError(t, storage.Reindex(ctx))
+
+	entries, err := Search(ctx, store, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"legacy.txt"}, searchKeys(entries))
+	require.Equal(t, int64(6), entries[0].Size)
+}
+
+func TestSearchFilterMatch(t *testing.T) {
+	entry := &IndexEntry{
+		Key:      "exports/a.csv",
+		Size:     10,
+		ModTime:  time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC),
+		Metadata: map[string]string{"tenant": "a"},
+	}
+
+	require.True(t, (&SearchFilter{Metadata: map[string]string{"tenant": "a"}}).Match(entry))
+	require.False(t, (&SearchFilter{Metadata: map[string]string{"tenant": "b"}}).Match(entry))
+	require.False(t, (&SearchFilter{Metadata: map[string]string{"owner": "a"}}).Match(entry))
+	require.True(t, (&SearchFilter{MinSize: 10, MaxSize: 10}).Match(entry))
+	require.False(t, (&SearchFilter{MaxSize: 9}).Match(entry))
+	require.True(t, (&SearchFilter{ModifiedAfter: entry.ModTime.Add(-1)}).Match(entry))
+	require.False(t, (&SearchFilter{ModifiedBefore: entry.ModTime}).Match(entry))
+}
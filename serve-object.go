@@ -0,0 +1,256 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ServeObject streams the object in the response without buffering it.
+// The Range, If-Range, If-Match, If-None-Match, If-Modified-Since and If-Unmodified-Since headers are handled
+// by http.ServeContent, only the requested ranges are downloaded with GetRangeReader.
+func ServeObject(w http.ResponseWriter, r *http.Request, storage CloudStorage, key string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	attrs, err := storage.Attributes(r.Context(), key)
+	if err != nil {
+		status := errorStatusCode(err)
+		http.Error(w, http.StatusText(status), status)
+
+		return
+	}
+
+	header := w.Header()
+	setHeader(header, "Content-Type", attrs.ContentType)
+	setHeader(header, "Cache-Control", attrs.CacheControl)
+	setHeader(header, "Content-Disposition", attrs.ContentDisposition)
+	setHeader(header, "Content-Encoding", attrs.ContentEncoding)
+	setHeader(header, "Content-Language", attrs.ContentLanguage)
+
+	// the MD5 is missing on the multipart and composite objects, which still have an ETag
+	switch {
+	case attrs.ETag != "":
+		header.Set("Etag", `"`+attrs.ETag+`"`)
+	case len(attrs.MD5) > 0:
+		header.Set("Etag", `"`+hex.EncodeToString(attrs.MD5)+`"`)
+	}
+
+	content := &objectReadSeeker{
+		ctx:     r.Context(),
+		storage: storage,
+		key:     key,
+		size:    attrs.Size,
+		ranges:  requestedRanges(r.Header.Get("Range"), attrs.Size),
+	}
+	defer content.Close()
+
+	http.ServeContent(w, r, path.Base(key), attrs.ModTime, content)
+}
+
+// NewObjectHandler returns a handler serving the object named by the URL path with ServeObject.
+// The leading slash is removed from the path, use http.StripPrefix to serve the objects under a route.
+func NewObjectHandler(storage CloudStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeObject(w, r, storage, strings.TrimPrefix(r.URL.Path, "/"))
+	})
+}
+
+func setHeader(header http.Header, name string, value string) {
+	if value != "" {
+		header.Set(name, value)
+	}
+}
+
+func errorStatusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, ErrInvalidKey):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// requestedRanges returns the lengths of the ranges of a Range header by their start offset,
+// the invalid specs are skipped as the whole header is then rejected by http.ServeContent
+func requestedRanges(header string, size int64) map[int64]int64 {
+	if !strings.HasPrefix(header, "bytes=") {
+		return nil
+	}
+
+	ranges := make(map[int64]int64)
+
+	for _, spec := range strings.Split(strings.TrimPrefix(header, "bytes="), ",") {
+		spec = strings.TrimSpace(spec)
+
+		dash := strings.Index(spec, "-")
+		if dash < 0 {
+			continue
+		}
+
+		start, end := strings.TrimSpace(spec[:dash]), strings.TrimSpace(spec[dash+1:])
+
+		// a suffix range, the last bytes of the object
+		if start == "" {
+			length, err := strconv.ParseInt(end, 10, 64)
+			if err != nil || length <= 0 {
+				continue
+			}
+
+			if length > size {
+				length = size
+			}
+
+			ranges[size-length] = length
+
+			continue
+		}
+
+		first, err := strconv.ParseInt(start, 10, 64)
+		if err != nil || first < 0 || first >= size {
+			continue
+		}
+
+		last := size - 1
+		if end != "" {
+			last, err = strconv.ParseInt(end, 10, 64)
+			if err != nil || last < first {
+				continue
+			}
+
+			if last >= size {
+				last = size - 1
+			}
+		}
+
+		ranges[first] = last - first + 1
+	}
+
+	return ranges
+}
+
+// objectReadSeeker opens a range reader from the offset on the first Read after a Seek,
+// so http.ServeContent downloads only the ranges it sends
+type objectReadSeeker struct {
+	ctx     context.Context
+	storage CloudStorage
+	key     string
+	size    int64
+	// ranges are the lengths of the requested ranges by their start offset
+	ranges map[int64]int64
+
+	offset int64
+	reader io.ReadCloser
+	// limited is whether the reader stops at the end of a requested range
+	limited bool
+}
+
+func (s *objectReadSeeker) Read(p []byte) (int, error) {
+	if s.offset >= s.size {
+		return 0, io.EOF
+	}
+
+	if s.reader == nil {
+		length, ok := s.ranges[s.offset]
+		if !ok {
+			length = -1
+		}
+
+		reader, err := s.storage.GetRangeReader(s.ctx, s.key, s.offset, length)
+		if err != nil {
+			return 0, err
+		}
+
+		s.reader = reader
+		s.limited = ok
+	}
+
+	n, err := s.reader.Read(p)
+	s.offset += int64(n)
+
+	// reading past a requested range continues with a new reader
+	if err == io.EOF && s.limited && s.offset < s.size {
+		s.Close()
+
+		err = nil
+	}
+
+	return n, err
+}
+
+func (s *objectReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += s.offset
+	case io.SeekEnd:
+		offset += s.size
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	if offset != s.offset {
+		s.Close()
+	}
+
+	s.offset = offset
+
+	return offset, nil
+}
+
+// ReadAt keeps the stream open when the calls are sequential, as when reading a zip entry
+func (s *objectReadSeeker) ReadAt(p []byte, off int64) (int, error) {
+	if _, err := s.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	n, err := io.ReadFull(s, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+
+	return n, err
+}
+
+func (s *objectReadSeeker) Close() error {
+	if s.reader == nil {
+		return nil
+	}
+
+	err := s.reader.Close()
+	s.reader = nil
+
+	return err
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeObject(t *testing.T) {
+	storage := NewFakeCloudStorage("bucket")
+	contentType := "text/plain"
+	require.NoError(t, storage.Write(context.Background(), "folder/key", []byte("0123456789"), &contentType))
+
+	handler := NewObjectHandler(storage)
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/folder/key", nil))
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Equal(t, "0123456789", response.Body.String())
+	require.Equal(t, "text/plain", response.Header().Get("Content-Type"))
+	require.Equal(t, "bytes", response.Header().Get("Accept-Ranges"))
+
+	etag := response.Header().Get("Etag")
+	require.NotEmpty(t, etag)
+	require.NotEmpty(t, response.Header().Get("Last-Modified"))
+
+	request := httptest.NewRequest(http.MethodGet, "/folder/key", nil)
+	request.Header.Set("If-None-Match", etag)
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	require.Equal(t, http.StatusNotModified, response.Code)
+	require.Empty(t, response.Body.String())
+
+	request = httptest.NewRequest(http.MethodGet, "/folder/key", nil)
+	request.Header.Set("Range", "bytes=2-4")
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	require.Equal(t, http.StatusPartialContent, response.Code)
+	require.Equal(t, "234", response.Body.String())
+	require.Equal(t, "bytes 2-4/10", response.Header().Get("Content-Range"))
+
+	request = httptest.NewRequest(http.MethodGet, "/folder/key", nil)
+	request.Header.Set("Range", "bytes=20-")
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	require.Equal(t, http.StatusRequestedRangeNotSatisfiable, response.Code)
+
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	require.Equal(t, http.StatusNotFound, response.Code)
+
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/folder/key", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, response.Code)
+}
+
+type rangeRecordingCloudStorage struct {
+	CloudStorage
+	etag   string
+	ranges [][2]int64
+}
+
+func (ts *rangeRecordingCloudStorage) Attributes(ctx context.Context, key string, opts ...ReadOption) (*Attributes, error) {
+	attrs, err := ts.CloudStorage.Attributes(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs.ETag = ts.etag
+
+	return attrs, nil
+}
+
+func (ts *rangeRecordingCloudStorage) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset,
+	length int64,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	ts.ranges = append(ts.ranges, [2]int64{offset, length})
+
+	return ts.CloudStorage.GetRangeReader(ctx, key, offset, length, opts...)
+}
+
+func TestServeObjectRanges(t *testing.T) {
+	storage := &rangeRecordingCloudStorage{CloudStorage: NewFakeCloudStorage("bucket")}
+	contentType := "text/plain"
+	require.NoError(t, storage.Write(context.Background(), "key", []byte("0123456789"), &contentType))
+
+	handler := NewObjectHandler(storage)
+
+	serve := func(rangeHeader string) *httptest.ResponseRecorder {
+		storage.ranges = nil
+
+		request := httptest.NewRequest(http.MethodGet, "/key", nil)
+		request.Header.Set("Range", rangeHeader)
+
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+
+		return response
+	}
+
+	// only the requested bytes are downloaded
+	response := serve("bytes=2-4")
+	require.Equal(t, "234", response.Body.String())
+	require.Equal(t, [][2]int64{{2, 3}}, storage.ranges)
+
+	response = serve("bytes=-3")
+	require.Equal(t, "789", response.Body.String())
+	require.Equal(t, [][2]int64{{7, 3}}, storage.ranges)
+
+	response = serve("bytes=8-")
+	require.Equal(t, "89", response.Body.String())
+	require.Equal(t, [][2]int64{{8, 2}}, storage.ranges)
+
+	response = serve("bytes=0-1,5-6")
+	require.Equal(t, http.StatusPartialContent, response.Code)
+	require.Contains(t, response.Body.String(), "01")
+	require.Contains(t, response.Body.String(), "56")
+	require.Equal(t, [][2]int64{{0, 2}, {5, 2}}, storage.ranges)
+}
+
+func TestServeObjectETag(t *testing.T) {
+	storage := &rangeRecordingCloudStorage{CloudStorage: NewFakeCloudStorage("bucket"), etag: "d41d8cd98f00b204e9800998ecf8427e-2"}
+	require.NoError(t, storage.Write(context.Background(), "key", []byte("0123456789"), nil))
+
+	handler := NewObjectHandler(storage)
+
+	// the ETag of the provider is used, the MD5 of a multipart upload isn't the MD5 of the content
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/key", nil))
+	require.Equal(t, `"d41d8cd98f00b204e9800998ecf8427e-2"`, response.Header().Get("Etag"))
+
+	request := httptest.NewRequest(http.MethodGet, "/key", nil)
+	request.Header.Set("If-None-Match", `"d41d8cd98f00b204e9800998ecf8427e-2"`)
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	require.Equal(t, http.StatusNotModified, response.Code)
+
+	// the MD5 is the fallback
+	storage.etag = ""
+
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/key", nil))
+	require.Equal(t, `"781e5e245d69b566979b86e28d23f2c7"`, response.Header().Get("Etag"))
+}
@@ -0,0 +1,799 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrShuttingDown is matched by errors.Is when an operation is called after Shutdown
+var ErrShuttingDown = errors.New("shutting down")
+
+// InFlightOperation is an operation of a ShutdownCloudStorage that hasn't completed
+type InFlightOperation struct {
+	Operation string
+	// Key is empty for the bucket-level operations
+	Key       string
+	StartedAt time.Time
+}
+
+// ShutdownReport lists what Shutdown abandoned when its context was done first
+type ShutdownReport struct {
+	// Operations are the calls still running, the writers count until they're closed
+	Operations []*InFlightOperation
+	// Jobs are the names of the background jobs which haven't returned
+	Jobs []string
+	// FlushError is the error of flushing the queues of the wrapped storage, e.g. of the AsyncWrite option
+	FlushError error
+}
+
+// Abandoned tells if anything was still running when Shutdown returned
+func (r *ShutdownReport) Abandoned() bool {
+	return len(r.Operations) > 0 || len(r.Jobs) > 0 || r.FlushError != nil
+}
+
+// ShutdownCloudStorage drains the operations in flight before the process exits, e.g. on the SIGTERM of a pod.
+// After Shutdown, the new operations fail with ErrShuttingDown, except GetPublicURL, As and ErrorAs which don't make
+// any request. The readers returned before Shutdown are not waited for, only the writers until they're closed.
+type ShutdownCloudStorage struct {
+	CloudStorage
+
+	mu       sync.Mutex
+	closing  bool
+	nextID   uint64
+	inFlight map[uint64]*InFlightOperation
+	drained  chan struct{}
+
+	jobsCtx    context.Context
+	cancelJobs context.CancelFunc
+	jobs       map[uint64]string
+	jobsDone   sync.WaitGroup
+}
+
+// NewShutdownCloudStorage wraps storage, it should be the outermost wrapper so every call is drained
+func NewShutdownCloudStorage(storage CloudStorage) *ShutdownCloudStorage {
+	jobsCtx, cancelJobs := context.WithCancel(context.Background())
+
+	return &ShutdownCloudStorage{
+		CloudStorage: storage,
+		inFlight:     map[uint64]*InFlightOperation{},
+		drained:      make(chan struct{}),
+		jobsCtx:      jobsCtx,
+		cancelJobs:   cancelJobs,
+		jobs:         map[uint64]string{},
+	}
+}
+
+// Go runs a background job, e.g. the Run method of a Janitor or a Replicator, whose context is cancelled by Shutdown.
+// It returns ErrShuttingDown after Shutdown, the errors of the job other than the cancellation are given to onError
+// when it's not nil.
+func (ts *ShutdownCloudStorage) Go(name string, job func(ctx context.Context) error, onError func(err error)) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.closing {
+		return ErrShuttingDown
+	}
+
+	ts.nextID++
+	id := ts.nextID
+	ts.jobs[id] = name
+	ts.jobsDone.Add(1)
+
+	go func() {
+		defer ts.jobsDone.Done()
+
+		err := job(ts.jobsCtx)
+		if err != nil && !errors.Is(err, context.Canceled) && onError != nil {
+			onError(err)
+		}
+
+		ts.mu.Lock()
+		delete(ts.jobs, id)
+		ts.mu.Unlock()
+	}()
+
+	return nil
+}
+
+// Shutdown stops accepting operations, cancels the background jobs, flushes the queues of the wrapped storage
+// and waits for the operations in flight, until ctx is done. The report lists what was abandoned, the error is
+// the one of ctx when something was. The wrapped storage isn't closed.
+func (ts *ShutdownCloudStorage) Shutdown(ctx context.Context) (*ShutdownReport, error) {
+	ts.mu.Lock()
+	if !ts.closing {
+		ts.closing = true
+		ts.cancelJobs()
+
+		if len(ts.inFlight) == 0 {
+			close(ts.drained)
+		}
+	}
+	ts.mu.Unlock()
+
+	report := &ShutdownReport{}
+
+	report.FlushError = flushStorage(ctx, ts.CloudStorage)
+
+	jobsDone := make(chan struct{})
+
+	go func() {
+		ts.jobsDone.Wait()
+		close(jobsDone)
+	}()
+
+	for _, done := range []chan struct{}{ts.drained, jobsDone} {
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	for _, op := range ts.inFlight {
+		report.Operations = append(report.Operations, op)
+	}
+
+	sort.Slice(report.Operations, func(i, j int) bool {
+		return report.Operations[i].StartedAt.Before(report.Operations[j].StartedAt)
+	})
+
+	for _, name := range ts.jobs {
+		report.Jobs = append(report.Jobs, name)
+	}
+
+	sort.Strings(report.Jobs)
+
+	if report.Abandoned() {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+
+		return report, report.FlushError
+	}
+
+	return report, nil
+}
+
+// begin records an operation in flight, done must be called once it completes
+func (ts *ShutdownCloudStorage) begin(operation string, key string) (done func(), err error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.closing {
+		return nil, ErrShuttingDown
+	}
+
+	ts.nextID++
+	id := ts.nextID
+	ts.inFlight[id] = &InFlightOperation{
+		Operation: operation,
+		Key:       key,
+		StartedAt: time.Now(),
+	}
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			ts.mu.Lock()
+			defer ts.mu.Unlock()
+
+			delete(ts.inFlight, id)
+
+			if ts.closing && len(ts.inFlight) == 0 {
+				close(ts.drained)
+			}
+		})
+	}, nil
+}
+
+// List fails on the first Next after Shutdown
+func (ts *ShutdownCloudStorage) List(
+	ctx context.Context,
+	prefix string,
+	opts ...ListOption,
+) *ListIterator {
+	iter := ts.CloudStorage.List(ctx, prefix, opts...)
+
+	return NewListIterator(ctx, func(ctx context.Context) (*ListObject, error) {
+		done, err := ts.begin("List", prefix)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
+
+		return iter.Next(ctx)
+	})
+}
+
+// GetWriter keeps the operation in flight until the writer is closed
+func (ts *ShutdownCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	done, err := ts.begin("GetWriter", key)
+	if err != nil {
+		return nil, err
+	}
+
+	writer, err := ts.CloudStorage.GetWriter(ctx, key, opts...)
+	if err != nil {
+		done()
+		return nil, err
+	}
+
+	return &shutdownWriter{WriteCloser: writer, done: done}, nil
+}
+
+func (ts *ShutdownCloudStorage) Get(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) ([]byte, error) {
+	done, err := ts.begin("Get", key)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return ts.CloudStorage.Get(ctx, key, opts...)
+}
+
+func (ts *ShutdownCloudStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	done, err := ts.begin("Delete", key)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ts.CloudStorage.Delete(ctx, key)
+}
+
+func (ts *ShutdownCloudStorage) CreateBucket(
+	ctx context.Context,
+	bucketPrefix string,
+	expirationTimeDays int64,
+) error {
+	done, err := ts.begin("CreateBucket", "")
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ts.CloudStorage.CreateBucket(ctx, bucketPrefix, expirationTimeDays)
+}
+
+func (ts *ShutdownCloudStorage) CreateBucketWithOptions(
+	ctx context.Context,
+	opts *CreateBucketOption,
+) error {
+	done, err := ts.begin("CreateBucketWithOptions", "")
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ts.CloudStorage.CreateBucketWithOptions(ctx, opts)
+}
+
+func (ts *ShutdownCloudStorage) GetSignedURL(
+	ctx context.Context,
+	key string,
+	opts *SignedURLOption,
+) (string, error) {
+	done, err := ts.begin("GetSignedURL", key)
+	if err != nil {
+		return "", err
+	}
+	defer done()
+
+	return ts.CloudStorage.GetSignedURL(ctx, key, opts)
+}
+
+func (ts *ShutdownCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	done, err := ts.begin("Write", key)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ts.CloudStorage.Write(ctx, key, body, contentType, opts...)
+}
+
+func (ts *ShutdownCloudStorage) Attributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (*Attributes, error) {
+	done, err := ts.begin("Attributes", key)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return ts.CloudStorage.Attributes(ctx, key, opts...)
+}
+
+func (ts *ShutdownCloudStorage) GetReader(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	done, err := ts.begin("GetReader", key)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return ts.CloudStorage.GetReader(ctx, key, opts...)
+}
+
+func (ts *ShutdownCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	done, err := ts.begin("GetWithAttributes", key)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer done()
+
+	return ts.CloudStorage.GetWithAttributes(ctx, key, opts...)
+}
+
+func (ts *ShutdownCloudStorage) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset int64,
+	length int64,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	done, err := ts.begin("GetRangeReader", key)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return ts.CloudStorage.GetRangeReader(ctx, key, offset, length, opts...)
+}
+
+func (ts *ShutdownCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	done, err := ts.begin("Upload", key)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ts.CloudStorage.Upload(ctx, key, reader, opts, writeOpts...)
+}
+
+func (ts *ShutdownCloudStorage) SetObjectRetention(
+	ctx context.Context,
+	key string,
+	retention *ObjectRetention,
+) error {
+	done, err := ts.begin("SetObjectRetention", key)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ts.CloudStorage.SetObjectRetention(ctx, key, retention)
+}
+
+func (ts *ShutdownCloudStorage) GetObjectRetention(
+	ctx context.Context,
+	key string,
+) (*ObjectRetention, error) {
+	done, err := ts.begin("GetObjectRetention", key)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return ts.CloudStorage.GetObjectRetention(ctx, key)
+}
+
+func (ts *ShutdownCloudStorage) SetLegalHold(
+	ctx context.Context,
+	key string,
+	enabled bool,
+) error {
+	done, err := ts.begin("SetLegalHold", key)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ts.CloudStorage.SetLegalHold(ctx, key, enabled)
+}
+
+func (ts *ShutdownCloudStorage) GetLegalHold(
+	ctx context.Context,
+	key string,
+) (bool, error) {
+	done, err := ts.begin("GetLegalHold", key)
+	if err != nil {
+		return false, err
+	}
+	defer done()
+
+	return ts.CloudStorage.GetLegalHold(ctx, key)
+}
+
+func (ts *ShutdownCloudStorage) GetBucketPolicy(
+	ctx context.Context,
+) (*BucketPolicy, error) {
+	done, err := ts.begin("GetBucketPolicy", "")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return ts.CloudStorage.GetBucketPolicy(ctx)
+}
+
+func (ts *ShutdownCloudStorage) SetBucketPolicy(
+	ctx context.Context,
+	policy *BucketPolicy,
+) error {
+	done, err := ts.begin("SetBucketPolicy", "")
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ts.CloudStorage.SetBucketPolicy(ctx, policy)
+}
+
+func (ts *ShutdownCloudStorage) GetBucketEncryption(
+	ctx context.Context,
+) (*BucketEncryption, error) {
+	done, err := ts.begin("GetBucketEncryption", "")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return ts.CloudStorage.GetBucketEncryption(ctx)
+}
+
+func (ts *ShutdownCloudStorage) SetBucketEncryption(
+	ctx context.Context,
+	encryption *BucketEncryption,
+) error {
+	done, err := ts.begin("SetBucketEncryption", "")
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ts.CloudStorage.SetBucketEncryption(ctx, encryption)
+}
+
+func (ts *ShutdownCloudStorage) GetBucketLogging(
+	ctx context.Context,
+) (*BucketLogging, error) {
+	done, err := ts.begin("GetBucketLogging", "")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return ts.CloudStorage.GetBucketLogging(ctx)
+}
+
+func (ts *ShutdownCloudStorage) SetBucketLogging(
+	ctx context.Context,
+	logging *BucketLogging,
+) error {
+	done, err := ts.begin("SetBucketLogging", "")
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ts.CloudStorage.SetBucketLogging(ctx, logging)
+}
+
+func (ts *ShutdownCloudStorage) GetBucketReplication(
+	ctx context.Context,
+) (*BucketReplication, error) {
+	done, err := ts.begin("GetBucketReplication", "")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return ts.CloudStorage.GetBucketReplication(ctx)
+}
+
+func (ts *ShutdownCloudStorage) SetBucketReplication(
+	ctx context.Context,
+	replication *BucketReplication,
+) error {
+	done, err := ts.begin("SetBucketReplication", "")
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ts.CloudStorage.SetBucketReplication(ctx, replication)
+}
+
+func (ts *ShutdownCloudStorage) Ping(
+	ctx context.Context,
+) error {
+	done, err := ts.begin("Ping", "")
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ts.CloudStorage.Ping(ctx)
+}
+
+func (ts *ShutdownCloudStorage) Subscribe(
+	ctx context.Context,
+	prefix string,
+) (<-chan ObjectEvent, error) {
+	done, err := ts.begin("Subscribe", prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return ts.CloudStorage.Subscribe(ctx, prefix)
+}
+
+func (ts *ShutdownCloudStorage) ListIncompleteUploads(
+	ctx context.Context,
+	prefix string,
+) ([]*IncompleteUpload, error) {
+	done, err := ts.begin("ListIncompleteUploads", prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return ts.CloudStorage.ListIncompleteUploads(ctx, prefix)
+}
+
+func (ts *ShutdownCloudStorage) AbortStaleUploads(
+	ctx context.Context,
+	olderThan time.Duration,
+) ([]*IncompleteUpload, error) {
+	done, err := ts.begin("AbortStaleUploads", "")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return ts.CloudStorage.AbortStaleUploads(ctx, olderThan)
+}
+
+func (ts *ShutdownCloudStorage) Query(
+	ctx context.Context,
+	key string,
+	sql string,
+	format QueryInputFormat,
+) (io.ReadCloser, error) {
+	done, err := ts.begin("Query", key)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return ts.CloudStorage.Query(ctx, key, sql, format)
+}
+
+func (ts *ShutdownCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	done, err := ts.begin("WriteIf", key)
+	if err != nil {
+		return "", err
+	}
+	defer done()
+
+	return ts.CloudStorage.WriteIf(ctx, key, body, contentType, condition)
+}
+
+func (ts *ShutdownCloudStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	done, err := ts.begin("DeleteIf", key)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ts.CloudStorage.DeleteIf(ctx, key, generation)
+}
+
+func (ts *ShutdownCloudStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	done, err := ts.begin("BeginUpload", key)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return ts.CloudStorage.BeginUpload(ctx, key, opts)
+}
+
+func (ts *ShutdownCloudStorage) ResumeUpload(
+	ctx context.Context,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	key := ""
+	if state != nil {
+		key = state.Key
+	}
+
+	done, err := ts.begin("ResumeUpload", key)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return ts.CloudStorage.ResumeUpload(ctx, state)
+}
+
+func (ts *ShutdownCloudStorage) SetStorageClass(
+	ctx context.Context,
+	key string,
+	storageClass string,
+) error {
+	done, err := ts.begin("SetStorageClass", key)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ts.CloudStorage.SetStorageClass(ctx, key, storageClass)
+}
+
+func (ts *ShutdownCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	done, err := ts.begin("Copy", dstKey)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ts.CloudStorage.Copy(ctx, srcKey, dstKey, opts)
+}
+
+func (ts *ShutdownCloudStorage) Undelete(
+	ctx context.Context,
+	key string,
+) error {
+	done, err := ts.begin("Undelete", key)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ts.CloudStorage.Undelete(ctx, key)
+}
+
+func (ts *ShutdownCloudStorage) GetScopedCredentials(
+	ctx context.Context,
+	opts *ScopedCredentialsOption,
+) (*ScopedCredentials, error) {
+	done, err := ts.begin("GetScopedCredentials", "")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return ts.CloudStorage.GetScopedCredentials(ctx, opts)
+}
+
+func (ts *ShutdownCloudStorage) CreateFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	done, err := ts.begin("CreateFolder", folder)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ts.CloudStorage.CreateFolder(ctx, folder)
+}
+
+func (ts *ShutdownCloudStorage) DeleteFolder(
+	ctx context.Context,
+	folder string,
+) error {
+	done, err := ts.begin("DeleteFolder", folder)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ts.CloudStorage.DeleteFolder(ctx, folder)
+}
+
+func (ts *ShutdownCloudStorage) RenameFolder(
+	ctx context.Context,
+	folder string,
+	newFolder string,
+) error {
+	done, err := ts.begin("RenameFolder", folder)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ts.CloudStorage.RenameFolder(ctx, folder, newFolder)
+}
+
+func (ts *ShutdownCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
+
+type shutdownWriter struct {
+	io.WriteCloser
+	done func()
+}
+
+func (w *shutdownWriter) Close() error {
+	defer w.done()
+
+	return w.WriteCloser.Close()
+}
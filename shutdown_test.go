@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdownCloudStorage(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	storage := NewShutdownCloudStorage(NewAsyncCloudStorage(fake, AsyncWriteOption{}))
+
+	require.NoError(t, storage.Write(ctx, "queued", []byte("body"), nil))
+
+	writer, err := storage.GetWriter(ctx, "streamed")
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("body"))
+	require.NoError(t, err)
+
+	stopped := make(chan struct{})
+	require.NoError(t, storage.Go("janitor", func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stopped)
+
+		return ctx.Err()
+	}, func(err error) {
+		t.Errorf("unexpected error: %v", err)
+	}))
+
+	type result struct {
+		report *ShutdownReport
+		err    error
+	}
+
+	results := make(chan result)
+
+	go func() {
+		report, err := storage.Shutdown(ctx)
+		results <- result{report: report, err: err}
+	}()
+
+	<-stopped
+
+	// the new operations are rejected while the writer is drained
+	require.Eventually(t, func() bool {
+		_, err := storage.Get(ctx, "queued")
+		return errors.Is(err, ErrShuttingDown)
+	}, time.Second, time.Millisecond)
+
+	_, err = storage.List(ctx, "").Next(ctx)
+	require.True(t, errors.Is(err, ErrShuttingDown))
+	require.True(t, errors.Is(storage.Go("replicator", func(ctx context.Context) error { return nil }, nil), ErrShuttingDown))
+
+	require.NoError(t, writer.Close())
+
+	shutdown := <-results
+	require.NoError(t, shutdown.err)
+	require.False(t, shutdown.report.Abandoned())
+
+	for _, key := range []string{"queued", "streamed"} {
+		body, err := fake.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "body", string(body))
+	}
+}
+
+func TestShutdownCloudStorageAbandoned(t *testing.T) {
+	ctx := context.Background()
+	storage := NewShutdownCloudStorage(NewFakeCloudStorage("bucket"))
+
+	_, err := storage.GetWriter(ctx, "never-closed")
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	require.NoError(t, storage.Go("replicator", func(ctx context.Context) error {
+		<-release
+		return nil
+	}, nil))
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+
+	report, err := storage.Shutdown(shutdownCtx)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+	require.True(t, report.Abandoned())
+	require.Len(t, report.Operations, 1)
+	require.Equal(t, "GetWriter", report.Operations[0].Operation)
+	require.Equal(t, "never-closed", report.Operations[0].Key)
+	require.Equal(t, []string{"replicator"}, report.Jobs)
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gocloud.dev/blob"
+)
+
+var (
+	// ErrSignedURLExpiryTooLong is matched by errors.Is when a signed URL is requested with an expiry longer than
+	// CloudStorageOption.MaxSignedURLExpiry
+	ErrSignedURLExpiryTooLong = errors.New("signed URL expiry too long")
+	// ErrNoSignedURLExpiry is returned by SignedURLExpiry for the URLs without a known expiry parameter
+	ErrNoSignedURLExpiry = errors.New("no signed URL expiry")
+)
+
+// SignedURLExpiryError gives the expiry rejected by the maximum of the client
+type SignedURLExpiryError struct {
+	Key       string
+	Expiry    time.Duration
+	MaxExpiry time.Duration
+}
+
+func (e *SignedURLExpiryError) Error() string {
+	return fmt.Sprintf("the signed URL of '%s' can't expire in %v, the maximum is %v", e.Key, e.Expiry, e.MaxExpiry)
+}
+
+func (e *SignedURLExpiryError) Is(target error) bool {
+	return target == ErrSignedURLExpiryTooLong
+}
+
+// signedURLPolicyCloudStorage rejects the signed URLs expiring later than the maximum
+type signedURLPolicyCloudStorage struct {
+	CloudStorage
+	maxExpiry time.Duration
+}
+
+func newSignedURLPolicyCloudStorage(storage CloudStorage, maxExpiry time.Duration) *signedURLPolicyCloudStorage {
+	return &signedURLPolicyCloudStorage{CloudStorage: storage, maxExpiry: maxExpiry}
+}
+
+func (ts *signedURLPolicyCloudStorage) GetSignedURL(
+	ctx context.Context,
+	key string,
+	opts *SignedURLOption,
+) (string, error) {
+	expiry := opts.Expiry
+	if expiry == 0 {
+		expiry = blob.DefaultSignedURLExpiry
+	}
+
+	if expiry > ts.maxExpiry {
+		return "", &SignedURLExpiryError{Key: key, Expiry: expiry, MaxExpiry: ts.maxExpiry}
+	}
+
+	return ts.CloudStorage.GetSignedURL(ctx, key, opts)
+}
+
+// Flush flushes the wrapped storage
+func (ts *signedURLPolicyCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
+
+// cloudFrontPolicy is the custom policy of a CloudFront signed URL
+type cloudFrontPolicy struct {
+	Statement []struct {
+		Condition struct {
+			DateLessThan struct {
+				EpochTime int64 `json:"AWS:EpochTime"`
+			}
+		}
+	}
+}
+
+// cloudFrontBase64 reverts the characters replaced in the base64 of the CloudFront policies
+var cloudFrontBase64 = strings.NewReplacer("-", "+", "_", "=", "~", "/")
+
+// SignedURLExpiry returns the time a signed URL expires, read from its parameters, e.g. to check the URLs issued
+// by another service against a security policy. It understands the URLs signed by every provider: the AWS
+// Signature V4 and the GCS V4 URLs (their date plus their expiry), the GCS V2, Cloud CDN and CloudFront URLs
+// (their Expires time, or the policy of the CloudFront custom policies) and the FakeCloudStorage URLs.
+// The signature isn't verified. ErrNoSignedURLExpiry is returned when the URL has none of these parameters.
+func SignedURLExpiry(signedURL string) (time.Time, error) {
+	u, err := url.Parse(signedURL)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	query := u.Query()
+
+	for _, prefix := range []string{"X-Amz-", "X-Goog-"} {
+		if query.Get(prefix+"Date") == "" {
+			continue
+		}
+
+		date, err := time.Parse("20060102T150405Z", query.Get(prefix+"Date"))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %sDate: %w", prefix, err)
+		}
+
+		seconds, err := strconv.ParseInt(query.Get(prefix+"Expires"), 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %sExpires: %w", prefix, err)
+		}
+
+		return date.Add(time.Duration(seconds) * time.Second), nil
+	}
+
+	for _, name := range []string{"Expires", "expires"} {
+		if query.Get(name) == "" {
+			continue
+		}
+
+		seconds, err := strconv.ParseInt(query.Get(name), 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s: %w", name, err)
+		}
+
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+
+	if encoded := query.Get("Policy"); encoded != "" {
+		return cloudFrontPolicyExpiry(encoded)
+	}
+
+	return time.Time{}, ErrNoSignedURLExpiry
+}
+
+// cloudFrontPolicyExpiry returns the DateLessThan of the custom policy of a CloudFront URL
+func cloudFrontPolicyExpiry(encoded string) (time.Time, error) {
+	decoded, err := base64.StdEncoding.DecodeString(cloudFrontBase64.Replace(encoded))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid Policy: %w", err)
+	}
+
+	var policy cloudFrontPolicy
+	if err := json.Unmarshal(decoded, &policy); err != nil {
+		return time.Time{}, fmt.Errorf("invalid Policy: %w", err)
+	}
+
+	if len(policy.Statement) == 0 || policy.Statement[0].Condition.DateLessThan.EpochTime == 0 {
+		return time.Time{}, ErrNoSignedURLExpiry
+	}
+
+	return time.Unix(policy.Statement[0].Condition.DateLessThan.EpochTime, 0).UTC(), nil
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudfront/sign"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxSignedURLExpiry(t *testing.T) {
+	ctx := context.Background()
+	storage := newSignedURLPolicyCloudStorage(NewFakeCloudStorage("bucket"), 15*time.Minute)
+
+	_, err := storage.GetSignedURL(ctx, "reports/a.pdf", &SignedURLOption{Expiry: 15 * time.Minute})
+	require.NoError(t, err)
+
+	_, err = storage.GetSignedURL(ctx, "reports/a.pdf", &SignedURLOption{Expiry: 7 * 24 * time.Hour})
+	require.True(t, errors.Is(err, ErrSignedURLExpiryTooLong))
+
+	var expiryErr *SignedURLExpiryError
+	require.True(t, errors.As(err, &expiryErr))
+	require.Equal(t, SignedURLExpiryError{
+		Key:       "reports/a.pdf",
+		Expiry:    7 * 24 * time.Hour,
+		MaxExpiry: 15 * time.Minute,
+	}, *expiryErr)
+
+	// the expiry defaults to an hour
+	_, err = storage.GetSignedURL(ctx, "reports/a.pdf", &SignedURLOption{})
+	require.True(t, errors.Is(err, ErrSignedURLExpiryTooLong))
+}
+
+func TestSignedURLExpiry(t *testing.T) {
+	now := time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)
+	expires := now.Add(10 * time.Minute)
+
+	awsSession, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	awsURL, err := awsSignedURL(s3.New(awsSession), "bucket", "key", &SignedURLOption{Expiry: 10 * time.Minute},
+		func() time.Time { return now })
+	require.NoError(t, err)
+
+	gcsURLs := make([]string, 0, 2)
+
+	for _, scheme := range []storage.SigningScheme{storage.SigningSchemeV2, storage.SigningSchemeV4} {
+		gcsURL, err := storage.SignedURL("bucket", "key", &storage.SignedURLOptions{
+			GoogleAccessID: "signer@project.iam.gserviceaccount.com",
+			SignBytes:      func(b []byte) ([]byte, error) { return b, nil },
+			Method:         "GET",
+			Expires:        expires,
+			Scheme:         scheme,
+		})
+		require.NoError(t, err)
+
+		gcsURLs = append(gcsURLs, gcsURL)
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	signer := sign.NewURLSigner("K2JCJMDEHXQW5F", privateKey)
+
+	cannedURL, err := signer.Sign("https://d111111abcdef8.cloudfront.net/key", expires)
+	require.NoError(t, err)
+
+	policyURL, err := signer.SignWithPolicy("https://d111111abcdef8.cloudfront.net/key",
+		sign.NewCannedPolicy("https://d111111abcdef8.cloudfront.net/*", expires))
+	require.NoError(t, err)
+
+	fakeURL, err := NewFakeCloudStorage("bucket").GetSignedURL(context.Background(), "key",
+		&SignedURLOption{Expiry: time.Until(expires)})
+	require.NoError(t, err)
+
+	for _, signedURL := range append(gcsURLs,
+		awsURL,
+		cannedURL,
+		policyURL,
+		cloudCDNSignedURL("https://cdn.example.com/key", "key-name", make([]byte, 16), expires),
+	) {
+		expiry, err := SignedURLExpiry(signedURL)
+		require.NoError(t, err, signedURL)
+		require.True(t, expires.Equal(expiry), signedURL)
+	}
+
+	require.True(t, strings.Contains(policyURL, "Policy="))
+
+	expiry, err := SignedURLExpiry(fakeURL)
+	require.NoError(t, err)
+	require.WithinDuration(t, expires, expiry, 2*time.Second)
+
+	_, err = SignedURLExpiry("https://bucket.s3.amazonaws.com/key")
+	require.True(t, errors.Is(err, ErrNoSignedURLExpiry))
+
+	_, err = SignedURLExpiry("https://cdn.example.com/key?Policy=" + base64.StdEncoding.EncodeToString([]byte("{")))
+	require.Error(t, err)
+}
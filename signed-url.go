@@ -0,0 +1,274 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"crypto/md5" // nolint:gosec
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"gocloud.dev/blob"
+)
+
+func clockOrNow(clock func() time.Time) func() time.Time {
+	if clock == nil {
+		return time.Now
+	}
+
+	return clock
+}
+
+// signedURLMethods are the methods which can be signed on every provider
+var signedURLMethods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodHead:   true,
+	http.MethodPut:    true,
+	http.MethodDelete: true,
+}
+
+// signedURLMethod returns the method of the signed URL, GET when it's not set
+func signedURLMethod(opts *SignedURLOption) (string, error) {
+	method := opts.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	if !signedURLMethods[method] {
+		return "", fmt.Errorf("unsupported SignedURLOption.Method %q", method)
+	}
+
+	if opts.ContentDisposition != "" && method != http.MethodGet {
+		return "", fmt.Errorf("SignedURLOption.ContentDisposition must be empty for signing a %s URL", method)
+	}
+
+	if err := validateSignedURLChecksums(method, opts); err != nil {
+		return "", err
+	}
+
+	return method, nil
+}
+
+// validateSignedURLChecksums checks the digests of the body of a PUT URL
+func validateSignedURLChecksums(method string, opts *SignedURLOption) error {
+	if len(opts.ContentMD5) == 0 && len(opts.ContentSHA256) == 0 {
+		return nil
+	}
+
+	if method != http.MethodPut {
+		return fmt.Errorf("SignedURLOption.ContentMD5 and ContentSHA256 must be empty for signing a %s URL", method)
+	}
+
+	if len(opts.ContentMD5) != 0 && len(opts.ContentMD5) != md5.Size {
+		return fmt.Errorf("SignedURLOption.ContentMD5 must have %d bytes (%d)", md5.Size, len(opts.ContentMD5))
+	}
+
+	if len(opts.ContentSHA256) != 0 && len(opts.ContentSHA256) != sha256.Size {
+		return fmt.Errorf("SignedURLOption.ContentSHA256 must have %d bytes (%d)", sha256.Size, len(opts.ContentSHA256))
+	}
+
+	return nil
+}
+
+// gcpSignedURLMD5 returns the base64 MD5 signed in the GCP URLs, which can't sign a SHA-256
+func gcpSignedURLMD5(opts *SignedURLOption) (string, error) {
+	if len(opts.ContentSHA256) > 0 {
+		return "", fmt.Errorf("%w: SignedURLOption.ContentSHA256 on GCP", ErrNotSupported)
+	}
+
+	if len(opts.ContentMD5) == 0 {
+		return "", nil
+	}
+
+	return base64.StdEncoding.EncodeToString(opts.ContentMD5), nil
+}
+
+// AttachmentDisposition returns the Content-Disposition making the browsers download the object as filename,
+// the non-ASCII names being encoded as in RFC 6266
+func AttachmentDisposition(filename string) string {
+	if disposition := mime.FormatMediaType("attachment", map[string]string{"filename": filename}); disposition != "" {
+		return disposition
+	}
+
+	// the older versions of mime.FormatMediaType don't encode the non-ASCII values
+	return "attachment; filename*=utf-8''" + url.PathEscape(filename)
+}
+
+// withResponseContentDisposition adds the response-content-disposition parameter to a V2 signed URL of GCP,
+// which isn't part of the signature
+func withResponseContentDisposition(signedURL string, contentDisposition string) (string, error) {
+	return withUnsignedParameter(signedURL, "response-content-disposition", contentDisposition)
+}
+
+// withUnsignedParameter adds a parameter to a V2 signed URL of GCP, whose signature doesn't cover the parameters.
+// Nothing is added when the value is empty.
+func withUnsignedParameter(signedURL string, name string, value string) (string, error) {
+	if value == "" {
+		return signedURL, nil
+	}
+
+	u, err := url.Parse(signedURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set(name, value)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// awsSignedURL presigns the request the same way as blob.Bucket.SignedURL, but at the time given by now
+func awsSignedURL(
+	client *s3.S3,
+	bucketName string,
+	key string,
+	opts *SignedURLOption,
+	now func() time.Time,
+) (string, error) {
+	expiry := opts.Expiry
+	if expiry == 0 {
+		expiry = blob.DefaultSignedURLExpiry
+	}
+
+	if expiry < 0 {
+		return "", fmt.Errorf("SignedURLOption.Expiry must be >= 0 (%v)", opts.Expiry)
+	}
+
+	method, err := signedURLMethod(opts)
+	if err != nil {
+		return "", err
+	}
+
+	if method != http.MethodPut && (opts.ContentType != "" || opts.EnforceAbsentContentType) {
+		return "", fmt.Errorf("SignedURLOption.ContentType must be empty for signing a %s URL", method)
+	}
+
+	key = awsEscapeKey(key)
+
+	var req *request.Request
+
+	switch method {
+	case http.MethodGet:
+		input := &s3.GetObjectInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(key),
+		}
+
+		if opts.ContentDisposition != "" {
+			input.ResponseContentDisposition = aws.String(opts.ContentDisposition)
+		}
+
+		req, _ = client.GetObjectRequest(input)
+	case http.MethodHead:
+		req, _ = client.HeadObjectRequest(&s3.HeadObjectInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(key),
+		})
+	case http.MethodPut:
+		input := &s3.PutObjectInput{
+			Bucket:      aws.String(bucketName),
+			Key:         aws.String(key),
+			ContentType: aws.String(opts.ContentType),
+		}
+
+		// Content-MD5 is a signed header, the uploader must send the same value
+		if len(opts.ContentMD5) > 0 {
+			input.ContentMD5 = aws.String(base64.StdEncoding.EncodeToString(opts.ContentMD5))
+		}
+
+		req, _ = client.PutObjectRequest(input)
+
+		// the signature covers the SHA-256 of the payload instead of UNSIGNED-PAYLOAD
+		if len(opts.ContentSHA256) > 0 {
+			req.HTTPRequest.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(opts.ContentSHA256))
+		}
+	case http.MethodDelete:
+		req, _ = client.DeleteObjectRequest(&s3.DeleteObjectInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(key),
+		})
+	}
+
+	req.Handlers.Sign.Swap(v4.SignRequestHandler.Name, request.NamedHandler{
+		Name: v4.SignRequestHandler.Name,
+		Fn: func(req *request.Request) {
+			v4.SignSDKRequestWithCurrentTime(req, now)
+		},
+	})
+
+	return req.Presign(expiry)
+}
+
+// awsEscapeKey escapes the key like the s3blob driver does, so the URL points to the object written through the bucket
+func awsEscapeKey(key string) string {
+	runes := []rune(key)
+	escaped := make([]rune, 0, len(runes))
+
+	for i, r := range runes {
+		switch {
+		// S3 doesn't handle the control characters, and drops the trailing slash of "../" and "//"
+		case r < 32,
+			i > 1 && r == '/' && runes[i-1] == '.' && runes[i-2] == '.',
+			i > 0 && r == '/' && runes[i-1] == '/':
+			escaped = append(escaped, []rune(fmt.Sprintf("__%#x__", r))...)
+		default:
+			escaped = append(escaped, r)
+		}
+	}
+
+	return string(escaped)
+}
+
+// awsUnescapeKey reverts awsEscapeKey, for the keys listed with the S3 API
+func awsUnescapeKey(key string) string {
+	if !strings.Contains(key, "__0x") {
+		return key
+	}
+
+	var unescaped strings.Builder
+
+	for i := 0; i < len(key); {
+		if strings.HasPrefix(key[i:], "__0x") {
+			if end := strings.Index(key[i+4:], "__"); end > 0 {
+				if r, err := strconv.ParseInt(key[i+4:i+4+end], 16, 32); err == nil {
+					unescaped.WriteRune(rune(r))
+					i += 4 + end + 2
+
+					continue
+				}
+			}
+		}
+
+		unescaped.WriteByte(key[i])
+		i++
+	}
+
+	return unescaped.String()
+}
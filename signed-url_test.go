@@ -0,0 +1,246 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"crypto/md5" // nolint:gosec
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSSignedURLClock(t *testing.T) {
+	awsSession, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	client := s3.New(awsSession)
+	now := func() time.Time {
+		return time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)
+	}
+
+	signedURL, err := awsSignedURL(client, "bucket", "folder/key", &SignedURLOption{Expiry: 5 * time.Minute}, now)
+	require.NoError(t, err)
+
+	again, err := awsSignedURL(client, "bucket", "folder/key", &SignedURLOption{Expiry: 5 * time.Minute}, now)
+	require.NoError(t, err)
+	require.Equal(t, signedURL, again)
+
+	parsed, err := url.Parse(signedURL)
+	require.NoError(t, err)
+	require.Equal(t, "/folder/key", parsed.Path)
+	require.Equal(t, "20200301T120000Z", parsed.Query().Get("X-Amz-Date"))
+	require.Equal(t, "300", parsed.Query().Get("X-Amz-Expires"))
+
+	_, err = awsSignedURL(client, "bucket", "key", &SignedURLOption{Method: http.MethodPost}, now)
+	require.Error(t, err)
+}
+
+func TestAWSSignedURLMethods(t *testing.T) {
+	awsSession, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	client := s3.New(awsSession)
+	signatures := make(map[string]bool)
+
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete} {
+		signedURL, err := awsSignedURL(client, "bucket", "key", &SignedURLOption{Method: method}, time.Now)
+		require.NoError(t, err)
+
+		parsed, err := url.Parse(signedURL)
+		require.NoError(t, err)
+
+		signatures[parsed.Query().Get("X-Amz-Signature")] = true
+	}
+
+	// the method is signed
+	require.Len(t, signatures, 4)
+
+	_, err = awsSignedURL(client, "bucket", "key", &SignedURLOption{Method: http.MethodHead, ContentType: "text/plain"}, time.Now)
+	require.Error(t, err)
+}
+
+func TestGCPSignedURLMethods(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	storage := &ExplicitGCPCloudStorage{
+		bucketName:     "bucket",
+		googleAccessID: "signer@project.iam.gserviceaccount.com",
+		privateKey: pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+		}),
+		clock: time.Now,
+	}
+
+	signatures := make(map[string]bool)
+
+	for _, method := range []string{"", http.MethodHead, http.MethodPut, http.MethodDelete} {
+		signedURL, err := storage.GetSignedURL(context.Background(), "key", &SignedURLOption{Method: method, Expiry: time.Hour})
+		require.NoError(t, err)
+
+		parsed, err := url.Parse(signedURL)
+		require.NoError(t, err)
+
+		signatures[parsed.Query().Get("Signature")] = true
+	}
+
+	require.Len(t, signatures, 4)
+
+	_, err = storage.GetSignedURL(context.Background(), "key", &SignedURLOption{Method: http.MethodPost, Expiry: time.Hour})
+	require.Error(t, err)
+}
+
+func TestSignedURLContentDisposition(t *testing.T) {
+	awsSession, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	disposition := AttachmentDisposition("report 2020.csv")
+	require.Equal(t, `attachment; filename="report 2020.csv"`, disposition)
+
+	signedURL, err := awsSignedURL(s3.New(awsSession), "bucket", "key",
+		&SignedURLOption{ContentDisposition: disposition}, time.Now)
+	require.NoError(t, err)
+
+	parsed, err := url.Parse(signedURL)
+	require.NoError(t, err)
+	require.Equal(t, disposition, parsed.Query().Get("response-content-disposition"))
+
+	signedURL, err = NewFakeCloudStorage("bucket").GetSignedURL(context.Background(), "key",
+		&SignedURLOption{ContentDisposition: disposition})
+	require.NoError(t, err)
+
+	parsed, err = url.Parse(signedURL)
+	require.NoError(t, err)
+	require.Equal(t, disposition, parsed.Query().Get("response-content-disposition"))
+
+	// the other methods don't return the object
+	_, err = NewFakeCloudStorage("bucket").GetSignedURL(context.Background(), "key",
+		&SignedURLOption{Method: http.MethodPut, ContentDisposition: disposition})
+	require.Error(t, err)
+}
+
+func TestSignedURLChecksums(t *testing.T) {
+	awsSession, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	client := s3.New(awsSession)
+	body := []byte("hello")
+	md5Sum := md5.Sum(body) // nolint:gosec
+	sha256Sum := sha256.Sum256(body)
+
+	signedURL, err := awsSignedURL(client, "bucket", "key", &SignedURLOption{
+		Method:        http.MethodPut,
+		ContentMD5:    md5Sum[:],
+		ContentSHA256: sha256Sum[:],
+	}, time.Now)
+	require.NoError(t, err)
+
+	parsed, err := url.Parse(signedURL)
+	require.NoError(t, err)
+	require.Contains(t, parsed.Query().Get("X-Amz-SignedHeaders"), "content-md5")
+	require.Contains(t, parsed.Query().Get("X-Amz-SignedHeaders"), "x-amz-content-sha256")
+
+	_, err = awsSignedURL(client, "bucket", "key", &SignedURLOption{ContentMD5: md5Sum[:]}, time.Now)
+	require.Error(t, err)
+
+	_, err = awsSignedURL(client, "bucket", "key", &SignedURLOption{Method: http.MethodPut, ContentMD5: body}, time.Now)
+	require.Error(t, err)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	storage := &ExplicitGCPCloudStorage{
+		bucketName:     "bucket",
+		googleAccessID: "signer@project.iam.gserviceaccount.com",
+		privateKey: pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+		}),
+		clock: time.Now,
+	}
+
+	withoutMD5, err := storage.GetSignedURL(context.Background(), "key",
+		&SignedURLOption{Method: http.MethodPut, Expiry: time.Hour})
+	require.NoError(t, err)
+
+	withMD5, err := storage.GetSignedURL(context.Background(), "key",
+		&SignedURLOption{Method: http.MethodPut, Expiry: time.Hour, ContentMD5: md5Sum[:]})
+	require.NoError(t, err)
+	require.NotEqual(t, withoutMD5, withMD5)
+
+	_, err = storage.GetSignedURL(context.Background(), "key",
+		&SignedURLOption{Method: http.MethodPut, Expiry: time.Hour, ContentSHA256: sha256Sum[:]})
+	require.True(t, errors.Is(err, ErrNotSupported))
+}
+
+func TestAWSEscapeKey(t *testing.T) {
+	require.Equal(t, "folder/key", awsEscapeKey("folder/key"))
+	require.Equal(t, "a/__0x2f__b", awsEscapeKey("a//b"))
+	require.Equal(t, "..__0x2f__", awsEscapeKey("../"))
+	require.Equal(t, "a__0xa__b", awsEscapeKey("a\nb"))
+
+	for _, key := range []string{"folder/key", "a//b", "../", "a\nb", "__0x__", "a__0xzz__b"} {
+		require.Equal(t, key, awsUnescapeKey(awsEscapeKey(key)))
+	}
+}
+
+func TestFakeCloudStorageClock(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	storage := NewFakeCloudStorage("bucket")
+	storage.SetClock(func() time.Time {
+		return now
+	})
+
+	signedURL, err := storage.GetSignedURL(ctx, "key", &SignedURLOption{Expiry: time.Minute})
+	require.NoError(t, err)
+	require.Equal(t, "https://fake-storage.local/bucket/key?method=GET&expires=1583064060", signedURL)
+
+	require.NoError(t, storage.Write(ctx, "key", []byte("body"), nil))
+
+	attrs, err := storage.Attributes(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, now, attrs.ModTime)
+}
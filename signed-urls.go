@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"sync"
+)
+
+// GetSignedURLs signs the URLs of the keys with the same options in parallel, concurrency at a time
+// (DefaultGetManyConcurrency when it's not positive), and returns them by key. The options are validated once,
+// so invalid options fail before signing anything. The keys which couldn't be signed are given by
+// a *ForEachError, the URLs of the others being returned anyway.
+func GetSignedURLs(
+	ctx context.Context,
+	storage CloudStorage,
+	keys []string,
+	opts *SignedURLOption,
+	concurrency int,
+) (map[string]string, error) {
+	if _, err := signedURLMethod(opts); err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+
+	urls := make(map[string]string, len(keys))
+	errs := make(map[string]error)
+
+	skipped := forEachKey(ctx, keys, concurrency, func(key string) {
+		url, err := storage.GetSignedURL(ctx, key, opts)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err != nil {
+			errs[key] = err
+			return
+		}
+
+		urls[key] = url
+	})
+
+	for key, err := range skipped {
+		errs[key] = err
+	}
+
+	if len(errs) > 0 {
+		return urls, &ForEachError{Errors: errs}
+	}
+
+	return urls, nil
+}
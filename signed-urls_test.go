@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSignedURLs(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	keys := make([]string, 0, 100)
+
+	for i := 0; i < 100; i++ {
+		keys = append(keys, fmt.Sprintf("downloads/%d.pdf", i))
+	}
+
+	urls, err := GetSignedURLs(ctx, fake, keys, &SignedURLOption{Expiry: time.Hour}, 16)
+	require.NoError(t, err)
+	require.Len(t, urls, 100)
+
+	url, err := fake.GetSignedURL(ctx, "downloads/42.pdf", &SignedURLOption{Expiry: time.Hour})
+	require.NoError(t, err)
+	require.Equal(t, url, urls["downloads/42.pdf"])
+
+	// the options are validated before signing
+	_, err = GetSignedURLs(ctx, fake, keys, &SignedURLOption{Method: http.MethodPost}, 16)
+	require.Error(t, err)
+	require.False(t, errors.As(err, new(*ForEachError)))
+
+	faulty := NewFaultInjectingCloudStorage(fake, FaultInjectionOption{
+		Operations: map[string]Fault{"GetSignedURL": {ErrorRate: 1, Err: ErrUnavailable}},
+	})
+
+	urls, err = GetSignedURLs(ctx, faulty, keys[:3], &SignedURLOption{}, 0)
+	require.Empty(t, urls)
+
+	var forEachErr *ForEachError
+	require.True(t, errors.As(err, &forEachErr))
+	require.Len(t, forEachErr.Errors, 3)
+}
+
+func TestGCPSignedURLParsedKey(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	pemKey := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	})
+	now := time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	gcp := &ExplicitGCPCloudStorage{
+		bucketName:     "bucket",
+		googleAccessID: "signer@project.iam.gserviceaccount.com",
+		privateKey:     pemKey,
+		clock:          func() time.Time { return now },
+	}
+
+	signedURL, err := gcp.GetSignedURL(context.Background(), "key", &SignedURLOption{Expiry: time.Hour})
+	require.NoError(t, err)
+
+	// the URL is the one signed by the storage package with the key
+	expected, err := storage.SignedURL("bucket", "key", &storage.SignedURLOptions{
+		GoogleAccessID: "signer@project.iam.gserviceaccount.com",
+		PrivateKey:     pemKey,
+		Method:         http.MethodGet,
+		Expires:        now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, expected, signedURL)
+}
@@ -0,0 +1,224 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrObjectTooLarge is matched by errors.Is when a write exceeds the MaxObjectSize option
+var ErrObjectTooLarge = errors.New("object too large")
+
+// ObjectTooLargeError gives the limit exceeded by a write
+type ObjectTooLargeError struct {
+	Key   string
+	Limit int64
+}
+
+func (e *ObjectTooLargeError) Error() string {
+	return fmt.Sprintf("object '%s' exceeds the limit of %d bytes", e.Key, e.Limit)
+}
+
+func (e *ObjectTooLargeError) Is(target error) bool {
+	return target == ErrObjectTooLarge
+}
+
+// sizeLimitedCloudStorage rejects the writes larger than the limit.
+// Streamed writes are aborted as soon as the limit is exceeded, so no partial object is stored. The parts of the
+// upload sessions exceeding the limit are rejected, as well as the completion of the resumed sessions exceeding it.
+type sizeLimitedCloudStorage struct {
+	CloudStorage
+	limit int64
+}
+
+func newSizeLimitedCloudStorage(storage CloudStorage, limit int64) *sizeLimitedCloudStorage {
+	return &sizeLimitedCloudStorage{
+		CloudStorage: storage,
+		limit:        limit,
+	}
+}
+
+func (ts *sizeLimitedCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	if int64(len(body)) > ts.limit {
+		return &ObjectTooLargeError{Key: key, Limit: ts.limit}
+	}
+
+	return ts.CloudStorage.Write(ctx, key, body, contentType, opts...)
+}
+
+func (ts *sizeLimitedCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	if int64(len(body)) > ts.limit {
+		return "", &ObjectTooLargeError{Key: key, Limit: ts.limit}
+	}
+
+	return ts.CloudStorage.WriteIf(ctx, key, body, contentType, condition)
+}
+
+func (ts *sizeLimitedCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	// the writer is aborted by cancelling its context before closing it
+	ctx, cancel := context.WithCancel(ctx)
+
+	writer, err := ts.CloudStorage.GetWriter(ctx, key, opts...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &sizeLimitedWriter{
+		WriteCloser: writer,
+		cancel:      cancel,
+		err:         &ObjectTooLargeError{Key: key, Limit: ts.limit},
+		remaining:   ts.limit,
+	}, nil
+}
+
+func (ts *sizeLimitedCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	return ts.CloudStorage.Upload(ctx, key, &sizeLimitedReader{
+		Reader:    reader,
+		err:       &ObjectTooLargeError{Key: key, Limit: ts.limit},
+		remaining: ts.limit,
+	}, opts, writeOpts...)
+}
+
+// Flush flushes the wrapped storage
+func (ts *sizeLimitedCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
+
+func (ts *sizeLimitedCloudStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	session, err := ts.CloudStorage.BeginUpload(ctx, key, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.guardUpload(session), nil
+}
+
+func (ts *sizeLimitedCloudStorage) ResumeUpload(
+	ctx context.Context,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	session, err := ts.CloudStorage.ResumeUpload(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.guardUpload(session), nil
+}
+
+func (ts *sizeLimitedCloudStorage) guardUpload(session *UploadSession) *UploadSession {
+	return guardUploadSession(session, func(ctx context.Context, state *UploadSessionState, number int, body []byte) error {
+		if uploadedSize(state, number, body) > ts.limit {
+			return &ObjectTooLargeError{Key: state.Key, Limit: ts.limit}
+		}
+
+		return nil
+	}, func(ctx context.Context, state *UploadSessionState, complete func() error) error {
+		if uploadedSize(state, 0, nil) > ts.limit {
+			return &ObjectTooLargeError{Key: state.Key, Limit: ts.limit}
+		}
+
+		return complete()
+	})
+}
+
+type sizeLimitedWriter struct {
+	io.WriteCloser
+	cancel    context.CancelFunc
+	err       error
+	remaining int64
+	exceeded  bool
+}
+
+func (w *sizeLimitedWriter) Write(p []byte) (int, error) {
+	if w.exceeded {
+		return 0, w.err
+	}
+
+	if int64(len(p)) > w.remaining {
+		w.exceeded = true
+		w.cancel()
+
+		return 0, w.err
+	}
+
+	n, err := w.WriteCloser.Write(p)
+	w.remaining -= int64(n)
+
+	return n, err
+}
+
+func (w *sizeLimitedWriter) Close() error {
+	defer w.cancel()
+
+	err := w.WriteCloser.Close()
+	if w.exceeded {
+		return w.err
+	}
+
+	return err
+}
+
+type sizeLimitedReader struct {
+	io.Reader
+	err       error
+	remaining int64
+}
+
+func (r *sizeLimitedReader) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, r.err
+	}
+
+	n, err := r.Reader.Read(p)
+	r.remaining -= int64(n)
+
+	if r.remaining < 0 {
+		return 0, r.err
+	}
+
+	return n, err
+}
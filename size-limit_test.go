@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// streamingCloudStorage stores the streamed objects unless the context of the writer is cancelled before Close
+type streamingCloudStorage struct {
+	recordingCloudStorage
+}
+
+type streamingWriter struct {
+	ctx     context.Context
+	key     string
+	buf     bytes.Buffer
+	storage *streamingCloudStorage
+}
+
+func (w *streamingWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *streamingWriter) Close() error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+
+	return w.storage.Write(w.ctx, w.key, w.buf.Bytes(), nil)
+}
+
+func (ts *streamingCloudStorage) GetWriter(ctx context.Context, key string, opts ...WriteOption) (io.WriteCloser, error) {
+	return &streamingWriter{ctx: ctx, key: key, storage: ts}, nil
+}
+
+func (ts *streamingCloudStorage) Upload(ctx context.Context, key string, reader io.Reader, opts *UploadOption, writeOpts ...WriteOption) error {
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	return ts.Write(ctx, key, body, nil)
+}
+
+func TestSizeLimitedCloudStorage(t *testing.T) {
+	ctx := context.Background()
+	backend := &streamingCloudStorage{recordingCloudStorage{objects: make(map[string][]byte)}}
+	storage := newSizeLimitedCloudStorage(backend, 4)
+
+	require.NoError(t, storage.Write(ctx, "small", []byte("body"), nil))
+	require.True(t, errors.Is(storage.Write(ctx, "large", []byte("body!"), nil), ErrObjectTooLarge))
+
+	writer, err := storage.GetWriter(ctx, "streamed")
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("bod"))
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("y!"))
+	require.True(t, errors.Is(err, ErrObjectTooLarge))
+	require.True(t, errors.Is(writer.Close(), ErrObjectTooLarge))
+
+	err = storage.Upload(ctx, "uploaded", bytes.NewReader([]byte("body!")), nil)
+	require.True(t, errors.Is(err, ErrObjectTooLarge))
+
+	require.NoError(t, storage.Upload(ctx, "uploaded", bytes.NewReader([]byte("body")), nil))
+	require.Len(t, backend.objects, 2)
+}
+
+func TestSizeLimitedCloudStorageUploadSessions(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	storage := newSizeLimitedCloudStorage(fake, 4)
+
+	session, err := storage.BeginUpload(ctx, "uploaded", nil)
+	require.NoError(t, err)
+
+	require.True(t, errors.Is(session.UploadPart(ctx, 1, []byte("body!")), ErrObjectTooLarge))
+	require.NoError(t, session.UploadPart(ctx, 1, []byte("bo")))
+	require.NoError(t, session.UploadPart(ctx, 2, []byte("dy")))
+	require.True(t, errors.Is(session.UploadPart(ctx, 3, []byte("!")), ErrObjectTooLarge))
+
+	// a part can be replaced within the limit
+	require.NoError(t, session.UploadPart(ctx, 2, []byte("d")))
+	require.NoError(t, session.Complete(ctx))
+
+	body, err := fake.Get(ctx, "uploaded")
+	require.NoError(t, err)
+	require.Equal(t, "bod", string(body))
+
+	// the parts uploaded without the limit are checked when the session is completed
+	unlimited, err := fake.BeginUpload(ctx, "resumed", nil)
+	require.NoError(t, err)
+	require.NoError(t, unlimited.UploadPart(ctx, 1, []byte("body!")))
+
+	state := unlimited.State()
+	session, err = storage.ResumeUpload(ctx, &state)
+	require.NoError(t, err)
+	require.True(t, errors.Is(session.Complete(ctx), ErrObjectTooLarge))
+
+	_, err = fake.Get(ctx, "resumed")
+	require.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestSizeLimitedCloudStorageFlush(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	async := NewAsyncCloudStorage(fake, AsyncWriteOption{})
+	defer async.Close()
+
+	storage := newSizeLimitedCloudStorage(async, 4)
+
+	require.NoError(t, storage.Write(ctx, "small", []byte("body"), nil))
+	require.NoError(t, storage.Flush(ctx))
+
+	body, err := fake.Get(ctx, "small")
+	require.NoError(t, err)
+	require.Equal(t, "body", string(body))
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"time"
+)
+
+// SlowOperationOption logs a warning for the operations taking longer than their threshold
+type SlowOperationOption struct {
+	// Threshold applies to the operations without a threshold in Thresholds
+	Threshold time.Duration
+	// Thresholds overrides the threshold per operation name, e.g. {"Upload": time.Minute}
+	Thresholds map[string]time.Duration
+}
+
+func (o SlowOperationOption) threshold(operation string) time.Duration {
+	if threshold, ok := o.Thresholds[operation]; ok {
+		return threshold
+	}
+
+	return o.Threshold
+}
+
+func newSlowOperationInterceptor(opts SlowOperationOption, logger Logger) Interceptor {
+	return func(ctx context.Context, op *OperationInfo, next func(ctx context.Context) error) error {
+		start := time.Now()
+		err := next(ctx)
+		elapsed := time.Since(start)
+
+		if threshold := opts.threshold(op.Name); threshold > 0 && elapsed > threshold {
+			fields := Fields{
+				"operation": op.Name,
+				"bucket":    op.Bucket,
+				"key":       op.Key,
+				"bytes":     op.Bytes,
+				"elapsed":   elapsed,
+				"threshold": threshold,
+			}
+			if op.DestinationKey != "" {
+				fields["destination_key"] = op.DestinationKey
+			}
+
+			logger.Warn("slow cloud storage operation", fields)
+		}
+
+		return err
+	}
+}
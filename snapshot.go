@@ -0,0 +1,234 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"crypto/md5" // nolint:gosec
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultSnapshotPrefix is where the snapshots are stored when SnapshotOption.StorePrefix is not set
+const DefaultSnapshotPrefix = ".snapshots/"
+
+// SnapshotOption configures Snapshot and Restore
+type SnapshotOption struct {
+	// StorePrefix is where the manifests and the contents of the snapshots are stored, in the same storage.
+	// The contents are stored once by MD5, so the objects unchanged between two snapshots don't take more space.
+	// Defaults to DefaultSnapshotPrefix.
+	StorePrefix string
+}
+
+func (o *SnapshotOption) storePrefix() string {
+	if o == nil || o.StorePrefix == "" {
+		return DefaultSnapshotPrefix
+	}
+
+	return o.StorePrefix
+}
+
+// SnapshotManifest lists the objects captured by a snapshot
+type SnapshotManifest struct {
+	Name      string           `json:"name"`
+	Prefix    string           `json:"prefix"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Objects   []SnapshotObject `json:"objects"`
+}
+
+// SnapshotObject is an object captured by a snapshot
+type SnapshotObject struct {
+	// Key is relative to the prefix of the snapshot
+	Key  string `json:"key"`
+	Size int64  `json:"size"`
+	MD5  []byte `json:"md5"`
+	// Blob is the key of the captured content
+	Blob string `json:"blob"`
+}
+
+// Snapshot captures the objects under the prefix as the named snapshot, so the prefix can be rolled back
+// with Restore, e.g. after a bad batch job. The providers aren't required to support versioning: the contents
+// are copied under the store prefix. It returns ErrAlreadyExists when the name is already taken.
+func Snapshot(
+	ctx context.Context,
+	storage CloudStorage,
+	prefix string,
+	snapshotName string,
+	opts *SnapshotOption,
+) (*SnapshotManifest, error) {
+	if snapshotName == "" || strings.Contains(snapshotName, "/") {
+		return nil, fmt.Errorf("%w: invalid snapshot name '%s'", ErrInvalidKey, snapshotName)
+	}
+
+	storePrefix := opts.storePrefix()
+	manifestKey := storePrefix + "manifests/" + snapshotName + ".json"
+
+	if _, err := storage.Attributes(ctx, manifestKey); err == nil {
+		return nil, fmt.Errorf("%w: snapshot '%s'", ErrAlreadyExists, snapshotName)
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	objects, err := listByName(ctx, storage, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &SnapshotManifest{
+		Name:      snapshotName,
+		Prefix:    prefix,
+		CreatedAt: time.Now().UTC(),
+		Objects:   make([]SnapshotObject, 0, len(objects)),
+	}
+
+	for _, name := range sortedNames(objects) {
+		object := objects[name]
+
+		// the snapshots don't capture themselves when they're stored under the prefix
+		if strings.HasPrefix(object.Key, storePrefix) {
+			continue
+		}
+
+		captured, err := captureObject(ctx, storage, object, storePrefix+"blobs/")
+		if err != nil {
+			return nil, err
+		}
+
+		captured.Key = name
+		snapshot.Objects = append(snapshot.Objects, *captured)
+	}
+
+	body, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := "application/json"
+
+	if err := storage.Write(ctx, manifestKey, body, &contentType); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// captureObject copies the content of the object under the blob prefix, unless it's already there
+func captureObject(
+	ctx context.Context,
+	storage CloudStorage,
+	object *ListObject,
+	blobPrefix string,
+) (*SnapshotObject, error) {
+	var body []byte
+
+	sum := object.MD5
+	if len(sum) == 0 {
+		// the content is needed to name the blob when the provider doesn't return the MD5, e.g. multipart uploads
+		var err error
+
+		body, err = storage.Get(ctx, object.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		hash := md5.Sum(body) // nolint:gosec
+		sum = hash[:]
+	}
+
+	captured := &SnapshotObject{
+		Size: object.Size,
+		MD5:  sum,
+		Blob: blobPrefix + hex.EncodeToString(sum),
+	}
+
+	if attrs, err := storage.Attributes(ctx, captured.Blob); err == nil && attrs.Size == object.Size {
+		return captured, nil
+	} else if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	if body != nil {
+		return captured, storage.Write(ctx, captured.Blob, body, nil)
+	}
+
+	return captured, copyObject(ctx, storage, object.Key, storage, captured.Blob)
+}
+
+// GetSnapshot returns the manifest of the named snapshot
+func GetSnapshot(
+	ctx context.Context,
+	storage CloudStorage,
+	snapshotName string,
+	opts *SnapshotOption,
+) (*SnapshotManifest, error) {
+	body, err := storage.Get(ctx, opts.storePrefix()+"manifests/"+snapshotName+".json")
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &SnapshotManifest{}
+
+	return snapshot, json.Unmarshal(body, snapshot)
+}
+
+// Restore recreates the state of the named snapshot under dstPrefix, which is usually the prefix of the snapshot.
+// The objects missing or different from the snapshot are copied back, and the objects created since the snapshot
+// are deleted.
+func Restore(
+	ctx context.Context,
+	storage CloudStorage,
+	snapshotName string,
+	dstPrefix string,
+	opts *SnapshotOption,
+) (*SyncReport, error) {
+	snapshot, err := GetSnapshot(ctx, storage, snapshotName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// without MD5 in the destination listing the objects are always copied, since they look older than the snapshot
+	now := time.Now()
+
+	srcObjects := make(map[string]*ListObject, len(snapshot.Objects))
+	for _, object := range snapshot.Objects {
+		srcObjects[object.Key] = &ListObject{
+			Key:     object.Blob,
+			ModTime: now,
+			Size:    object.Size,
+			MD5:     object.MD5,
+		}
+	}
+
+	dstObjects, err := listByName(ctx, storage, dstPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	storePrefix := opts.storePrefix()
+
+	for name, object := range dstObjects {
+		if strings.HasPrefix(object.Key, storePrefix) {
+			delete(dstObjects, name)
+		}
+	}
+
+	return syncObjects(ctx, storage, srcObjects, storage, dstPrefix, dstObjects, &SyncOption{Delete: true})
+}
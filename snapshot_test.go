@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	contentType := "application/json"
+	require.NoError(t, storage.Write(ctx, "config/a.json", []byte(`{"a": 1}`), &contentType))
+	require.NoError(t, storage.Write(ctx, "config/b.json", []byte(`{"b": 1}`), nil))
+	require.NoError(t, storage.Write(ctx, "config/copy.json", []byte(`{"a": 1}`), nil))
+
+	snapshot, err := Snapshot(ctx, storage, "config/", "before-job", nil)
+	require.NoError(t, err)
+	require.Len(t, snapshot.Objects, 3)
+	require.Equal(t, "a.json", snapshot.Objects[0].Key)
+	// the identical contents are stored once
+	require.Equal(t, snapshot.Objects[0].Blob, snapshot.Objects[2].Blob)
+
+	_, err = Snapshot(ctx, storage, "config/", "before-job", nil)
+	require.True(t, errors.Is(err, ErrAlreadyExists))
+
+	// the bad batch job
+	require.NoError(t, storage.Write(ctx, "config/a.json", []byte(`{"a": 2}`), nil))
+	require.NoError(t, storage.Delete(ctx, "config/b.json"))
+	require.NoError(t, storage.Write(ctx, "config/c.json", []byte(`{"c": 1}`), nil))
+
+	report, err := Restore(ctx, storage, "before-job", "config/", nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.json", "b.json"}, report.Copied)
+	require.Equal(t, []string{"c.json"}, report.Deleted)
+	require.Equal(t, 1, report.Unchanged)
+
+	body, err := storage.Get(ctx, "config/a.json")
+	require.NoError(t, err)
+	require.Equal(t, `{"a": 1}`, string(body))
+
+	attrs, err := storage.Attributes(ctx, "config/a.json")
+	require.NoError(t, err)
+	require.Equal(t, "application/json", attrs.ContentType)
+
+	_, err = storage.Get(ctx, "config/c.json")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	// the snapshot can be restored elsewhere, e.g. to inspect it
+	_, err = Restore(ctx, storage, "before-job", "inspect/", nil)
+	require.NoError(t, err)
+
+	body, err = storage.Get(ctx, "inspect/b.json")
+	require.NoError(t, err)
+	require.Equal(t, `{"b": 1}`, string(body))
+}
+
+func TestSnapshotStoredUnderPrefix(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.Write(ctx, "a.txt", []byte("a"), nil))
+
+	_, err := Snapshot(ctx, storage, "", "first", nil)
+	require.NoError(t, err)
+
+	snapshot, err := Snapshot(ctx, storage, "", "second", nil)
+	require.NoError(t, err)
+	require.Len(t, snapshot.Objects, 1)
+
+	require.NoError(t, storage.Delete(ctx, "a.txt"))
+
+	_, err = Restore(ctx, storage, "second", "", nil)
+	require.NoError(t, err)
+
+	_, err = GetSnapshot(ctx, storage, "first", nil)
+	require.NoError(t, err)
+
+	_, err = storage.Get(ctx, "a.txt")
+	require.NoError(t, err)
+
+	_, err = Snapshot(ctx, storage, "", "invalid/name", nil)
+	require.True(t, errors.Is(err, ErrInvalidKey))
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// DefaultSpoolThreshold is the number of bytes a writer keeps in memory when SpoolOption.Threshold is not set
+const DefaultSpoolThreshold = 32 << 20
+
+// SpoolOption spools the bytes buffered by a writer to a temporary file above a threshold, so the writes of
+// multi-GB payloads don't hold them in memory
+type SpoolOption struct {
+	// Threshold is the number of bytes kept in memory, the whole buffer is moved to the file when it's exceeded.
+	// Defaults to DefaultSpoolThreshold.
+	Threshold int64
+	// Directory holds the temporary files, os.TempDir() when it's empty
+	Directory string
+}
+
+// spool buffers the written bytes in memory up to the threshold of its options, then in a temporary file.
+// Without options, it keeps everything in memory.
+type spool struct {
+	opts *SpoolOption
+	buf  []byte
+	file *os.File
+	size int64
+}
+
+func newSpool(opts *SpoolOption) *spool {
+	if opts != nil && opts.Threshold <= 0 {
+		withDefault := *opts
+		withDefault.Threshold = DefaultSpoolThreshold
+		opts = &withDefault
+	}
+
+	return &spool{opts: opts}
+}
+
+func (s *spool) Write(p []byte) (int, error) {
+	if s.file == nil && (s.opts == nil || s.size+int64(len(p)) <= s.opts.Threshold) {
+		s.buf = append(s.buf, p...)
+		s.size += int64(len(p))
+
+		return len(p), nil
+	}
+
+	if s.file == nil {
+		file, err := ioutil.TempFile(s.opts.Directory, "commonblob-spool-")
+		if err != nil {
+			return 0, err
+		}
+
+		s.file = file
+
+		if _, err := file.Write(s.buf); err != nil {
+			return 0, err
+		}
+
+		s.buf = nil
+	}
+
+	n, err := s.file.Write(p)
+	s.size += int64(n)
+
+	return n, err
+}
+
+// Len returns the number of bytes written
+func (s *spool) Len() int64 {
+	return s.size
+}
+
+// Bytes returns the written bytes when they are still in memory
+func (s *spool) Bytes() ([]byte, bool) {
+	return s.buf, s.file == nil
+}
+
+// Reader returns a reader of the written bytes from the beginning, valid until the spool is closed
+func (s *spool) Reader() io.Reader {
+	if s.file == nil {
+		return bytes.NewReader(s.buf)
+	}
+
+	return io.NewSectionReader(s.file, 0, s.size)
+}
+
+// Close removes the temporary file
+func (s *spool) Close() error {
+	s.buf = nil
+
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.file.Close()
+	if removeErr := os.Remove(s.file.Name()); err == nil {
+		err = removeErr
+	}
+
+	s.file = nil
+
+	return err
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpool(t *testing.T) {
+	directory, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	// without options everything stays in memory
+	memory := newSpool(nil)
+	_, err = memory.Write(bytes.Repeat([]byte("a"), 1<<20))
+	require.NoError(t, err)
+
+	_, inMemory := memory.Bytes()
+	require.True(t, inMemory)
+	require.NoError(t, memory.Close())
+
+	spooled := newSpool(&SpoolOption{Threshold: 4, Directory: directory})
+
+	_, err = spooled.Write([]byte("abc"))
+	require.NoError(t, err)
+
+	body, inMemory := spooled.Bytes()
+	require.True(t, inMemory)
+	require.Equal(t, "abc", string(body))
+
+	_, err = spooled.Write([]byte("defg"))
+	require.NoError(t, err)
+	require.Equal(t, int64(7), spooled.Len())
+
+	_, inMemory = spooled.Bytes()
+	require.False(t, inMemory)
+
+	files, err := ioutil.ReadDir(directory)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	// the reader starts from the beginning every time
+	for i := 0; i < 2; i++ {
+		body, err = ioutil.ReadAll(spooled.Reader())
+		require.NoError(t, err)
+		require.Equal(t, "abcdefg", string(body))
+	}
+
+	require.NoError(t, spooled.Close())
+
+	files, err = ioutil.ReadDir(directory)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestSpooledWriters(t *testing.T) {
+	ctx := context.Background()
+
+	directory, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	spool := &SpoolOption{Threshold: 2, Directory: directory}
+	fake := NewFakeCloudStorage("bucket")
+
+	chunked := NewChunkedCloudStorage(fake, ChunkingOption{ChunkSize: 4, Concurrency: 2, Spool: spool})
+	require.NoError(t, chunked.Upload(ctx, "chunked.txt", bytes.NewReader([]byte("abcdefghij")), nil))
+	require.NoError(t, chunked.Upload(ctx, "single.txt", bytes.NewReader([]byte("abc")), nil))
+
+	dedup := NewDedupCloudStorage(fake, DedupOption{Spool: spool})
+
+	writer, err := dedup.GetWriter(ctx, "dedup.json")
+	require.NoError(t, err)
+	_, err = writer.Write([]byte(`{"a":`))
+	require.NoError(t, err)
+	_, err = writer.Write([]byte(`1}`))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	require.NoError(t, dedup.Upload(ctx, "copy.json", bytes.NewReader([]byte(`{"a":1}`)), nil))
+
+	for key, expected := range map[string]string{"chunked.txt": "abcdefghij", "single.txt": "abc"} {
+		body, err := chunked.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, expected, string(body))
+	}
+
+	for _, key := range []string{"dedup.json", "copy.json"} {
+		body, err := dedup.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, `{"a":1}`, string(body))
+
+		attrs, err := dedup.Attributes(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "application/json", attrs.ContentType)
+	}
+
+	blobs, err := listByName(ctx, fake, DefaultDedupPrefix+"blobs/")
+	require.NoError(t, err)
+	require.Len(t, blobs, 1)
+
+	// the temporary files are removed
+	files, err := ioutil.ReadDir(directory)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// statsWindowSize is the number of latest latencies the percentiles are computed from
+const statsWindowSize = 1024
+
+// OperationStats are the statistics of an operation since the collector was created
+type OperationStats struct {
+	Count  int64
+	Errors int64
+	Bytes  int64
+	// P50, P95 and P99 are computed from the latest 1024 calls
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+}
+
+// StatsCollector keeps the per-operation statistics in memory, so they can be served by a debug endpoint.
+// It implements Metrics.
+type StatsCollector struct {
+	mu         sync.Mutex
+	operations map[string]*operationStats
+}
+
+type operationStats struct {
+	count     int64
+	errors    int64
+	bytes     int64
+	latencies []time.Duration
+	next      int
+}
+
+func NewStatsCollector() *StatsCollector {
+	return &StatsCollector{
+		operations: make(map[string]*operationStats),
+	}
+}
+
+func (c *StatsCollector) ObserveOperation(operation string, duration time.Duration, bytes int64, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats, ok := c.operations[operation]
+	if !ok {
+		stats = &operationStats{latencies: make([]time.Duration, 0, statsWindowSize)}
+		c.operations[operation] = stats
+	}
+
+	stats.count++
+	stats.bytes += bytes
+
+	if err != nil {
+		stats.errors++
+	}
+
+	if len(stats.latencies) < statsWindowSize {
+		stats.latencies = append(stats.latencies, duration)
+	} else {
+		stats.latencies[stats.next] = duration
+		stats.next = (stats.next + 1) % statsWindowSize
+	}
+}
+
+// Stats returns the statistics by operation name
+func (c *StatsCollector) Stats() map[string]OperationStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := make(map[string]OperationStats, len(c.operations))
+
+	for name, stats := range c.operations {
+		latencies := make([]time.Duration, len(stats.latencies))
+		copy(latencies, stats.latencies)
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+		result[name] = OperationStats{
+			Count:  stats.count,
+			Errors: stats.errors,
+			Bytes:  stats.bytes,
+			P50:    percentile(latencies, 50),
+			P95:    percentile(latencies, 95),
+			P99:    percentile(latencies, 99),
+		}
+	}
+
+	return result
+}
+
+// percentile uses the nearest-rank method on sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := (p*len(sorted) + 99) / 100 // nolint:gomnd
+
+	return sorted[rank-1]
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsCollector(t *testing.T) {
+	collector := NewStatsCollector()
+
+	for i := 1; i <= 100; i++ {
+		collector.ObserveOperation("Get", time.Duration(i)*time.Millisecond, 10, nil)
+	}
+
+	collector.ObserveOperation("Delete", time.Millisecond, 0, errors.New("failed"))
+
+	stats := collector.Stats()
+	require.Equal(t, OperationStats{
+		Count: 100,
+		Bytes: 1000,
+		P50:   50 * time.Millisecond,
+		P95:   95 * time.Millisecond,
+		P99:   99 * time.Millisecond,
+	}, stats["Get"])
+	require.Equal(t, int64(1), stats["Delete"].Errors)
+}
+
+func TestStatsCollectorKeepsLatestLatencies(t *testing.T) {
+	collector := NewStatsCollector()
+
+	for i := 0; i < statsWindowSize; i++ {
+		collector.ObserveOperation("Get", time.Hour, 0, nil)
+	}
+
+	for i := 0; i < statsWindowSize; i++ {
+		collector.ObserveOperation("Get", time.Millisecond, 0, nil)
+	}
+
+	stats := collector.Stats()["Get"]
+	require.Equal(t, int64(2*statsWindowSize), stats.Count)
+	require.Equal(t, time.Millisecond, stats.P99)
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"net/url"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// awsSetStorageClass copies the object onto itself with the storage class, keeping its metadata.
+// A single copy is limited to 5GB.
+func awsSetStorageClass(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	key string,
+	storageClass string,
+) error {
+	key = awsEscapeKey(key)
+	source := (&url.URL{Path: bucketName + "/" + key}).EscapedPath()
+
+	_, err := client.CopyObjectWithContext(ctx, &s3.CopyObjectInput{
+		Bucket:            aws.String(bucketName),
+		Key:               aws.String(key),
+		CopySource:        aws.String(source),
+		MetadataDirective: aws.String(s3.MetadataDirectiveCopy),
+		StorageClass:      aws.String(storageClass),
+	})
+
+	return translateError(err)
+}
+
+// gcpSetStorageClass rewrites the object onto itself with the storage class, on the condition that it wasn't
+// written meanwhile. The attributes of the object are given again, since they aren't copied by a rewrite setting
+// some of them.
+func gcpSetStorageClass(
+	ctx context.Context,
+	client *storage.Client,
+	bucketName string,
+	key string,
+	storageClass string,
+) error {
+	object := client.Bucket(bucketName).Object(key)
+
+	attrs, err := object.Attrs(ctx)
+	if err != nil {
+		return translateError(err)
+	}
+
+	copier := object.If(storage.Conditions{GenerationMatch: attrs.Generation}).CopierFrom(object)
+	copier.ContentType = attrs.ContentType
+	copier.ContentEncoding = attrs.ContentEncoding
+	copier.ContentLanguage = attrs.ContentLanguage
+	copier.ContentDisposition = attrs.ContentDisposition
+	copier.CacheControl = attrs.CacheControl
+	copier.Metadata = attrs.Metadata
+	copier.StorageClass = storageClass
+
+	_, err = copier.Run(ctx)
+
+	return translateError(err)
+}
@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSSetStorageClass(t *testing.T) {
+	var request *http.Request
+
+	client, server := newHTTPTestS3Client(t, func(w http.ResponseWriter, r *http.Request) {
+		request = r
+
+		w.Write([]byte(`<CopyObjectResult><ETag>"etag"</ETag></CopyObjectResult>`))
+	})
+	defer server.Close()
+
+	require.NoError(t, awsSetStorageClass(context.Background(), client, "bucket", "a//b", "STANDARD_IA"))
+
+	// the object is copied onto itself, with the key escaped like the keys of Get
+	require.Equal(t, http.MethodPut, request.Method)
+	require.Equal(t, "/bucket/a/__0x2f__b", request.URL.EscapedPath())
+	require.Equal(t, "bucket/a/__0x2f__b", request.Header.Get("X-Amz-Copy-Source"))
+	require.Equal(t, "COPY", request.Header.Get("X-Amz-Metadata-Directive"))
+	require.Equal(t, "STANDARD_IA", request.Header.Get("X-Amz-Storage-Class"))
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StoreOption configures a Store
+type StoreOption struct {
+	// Prefix is prepended to the keys of the values, e.g. "users/"
+	Prefix string
+	// Codec encodes the values. Defaults to the Codec of the storage, JSONCodec unless configured.
+	Codec Codec
+	// Compress gzips the encoded values
+	Compress bool
+}
+
+// Store keeps encoded values in a CloudStorage, as a simple document store.
+// The values are given as with encoding/json: any value to Put, and a pointer to Get and decode into.
+type Store struct {
+	storage CloudStorage
+	opts    StoreOption
+}
+
+// NewStore returns a Store of the values under the prefix of the storage
+func NewStore(storage CloudStorage, opts StoreOption) *Store {
+	if opts.Codec == nil {
+		opts.Codec = codecOf(storage)
+	}
+
+	return &Store{storage: storage, opts: opts}
+}
+
+func (s *Store) encode(key string, value interface{}) ([]byte, error) {
+	body, err := s.opts.Codec.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode '%s': %w", key, err)
+	}
+
+	if s.opts.Compress {
+		return GzipTransformer{}.Encode(key, body)
+	}
+
+	return body, nil
+}
+
+func (s *Store) decode(key string, body []byte, value interface{}) error {
+	if s.opts.Compress {
+		var err error
+		if body, err = (GzipTransformer{}).Decode(key, body); err != nil {
+			return err
+		}
+	}
+
+	if err := s.opts.Codec.Unmarshal(body, value); err != nil {
+		return fmt.Errorf("unable to decode '%s': %w", key, err)
+	}
+
+	return nil
+}
+
+// Get decodes the value of the key into value, or returns ErrNotFound
+func (s *Store) Get(ctx context.Context, key string, value interface{}) error {
+	body, err := s.storage.Get(ctx, s.opts.Prefix+key)
+	if err != nil {
+		return err
+	}
+
+	return s.decode(key, body, value)
+}
+
+// Put writes the value at the key
+func (s *Store) Put(ctx context.Context, key string, value interface{}) error {
+	body, err := s.encode(key, value)
+	if err != nil {
+		return err
+	}
+
+	contentType := s.opts.Codec.ContentType()
+
+	return s.storage.Write(ctx, s.opts.Prefix+key, body, &contentType)
+}
+
+// Update applies fn to the value of the key with Update, on the condition that it wasn't written meanwhile.
+// fn is called with the value decoded into a new value of newValue, or nil when the key doesn't exist,
+// and returns the value to write.
+func (s *Store) Update(
+	ctx context.Context,
+	key string,
+	newValue func() interface{},
+	fn func(value interface{}) (interface{}, error),
+) error {
+	return Update(ctx, s.storage, s.opts.Prefix+key, func(old []byte) ([]byte, error) {
+		var value interface{}
+
+		if old != nil {
+			value = newValue()
+			if err := s.decode(key, old, value); err != nil {
+				return nil, err
+			}
+		}
+
+		updated, err := fn(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.encode(key, updated)
+	})
+}
+
+// Delete deletes the value of the key, or returns ErrNotFound
+func (s *Store) Delete(ctx context.Context, key string) error {
+	return s.storage.Delete(ctx, s.opts.Prefix+key)
+}
+
+// List calls fn with the keys under the prefix, without the prefix of the store, until fn returns an error.
+// decode decodes the value of the key into a pointer, it's only read when it's called.
+func (s *Store) List(
+	ctx context.Context,
+	prefix string,
+	fn func(key string, decode func(value interface{}) error) error,
+) error {
+	iter := s.storage.List(ctx, s.opts.Prefix+prefix)
+
+	for {
+		object, err := iter.Next(ctx)
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		key := strings.TrimPrefix(object.Key, s.opts.Prefix)
+
+		err = fn(key, func(value interface{}) error {
+			return s.Get(ctx, key, value)
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/stretchr/testify/require"
+)
+
+type storeUser struct {
+	Name  string
+	Email string
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	store := NewStore(fake, StoreOption{Prefix: "users/"})
+
+	require.NoError(t, store.Put(ctx, "1", storeUser{Name: "alice", Email: "alice@example.com"}))
+	require.NoError(t, store.Put(ctx, "2", storeUser{Name: "bob"}))
+
+	var user storeUser
+	require.NoError(t, store.Get(ctx, "1", &user))
+	require.Equal(t, "alice", user.Name)
+
+	attrs, err := fake.Attributes(ctx, "users/1")
+	require.NoError(t, err)
+	require.Equal(t, "application/json", attrs.ContentType)
+
+	err = store.Update(ctx, "2", func() interface{} { return &storeUser{} }, func(value interface{}) (interface{}, error) {
+		user := value.(*storeUser)
+		user.Email = "bob@example.com"
+
+		return user, nil
+	})
+	require.NoError(t, err)
+
+	var names []string
+
+	err = store.List(ctx, "", func(key string, decode func(value interface{}) error) error {
+		var user storeUser
+		if err := decode(&user); err != nil {
+			return err
+		}
+
+		names = append(names, key+":"+user.Email)
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"1:alice@example.com", "2:bob@example.com"}, names)
+
+	require.NoError(t, store.Delete(ctx, "1"))
+	require.True(t, errors.Is(store.Get(ctx, "1", &user), ErrNotFound))
+}
+
+func TestStoreCodecs(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+
+	gobStore := NewStore(fake, StoreOption{Prefix: "gob/", Codec: GobCodec{}, Compress: true})
+	require.NoError(t, gobStore.Put(ctx, "user", storeUser{Name: "alice"}))
+
+	var user storeUser
+	require.NoError(t, gobStore.Get(ctx, "user", &user))
+	require.Equal(t, "alice", user.Name)
+
+	protoStore := NewStore(fake, StoreOption{Prefix: "proto/", Codec: ProtoCodec{}})
+	require.NoError(t, protoStore.Put(ctx, "timeout", ptypes.DurationProto(time.Minute)))
+	require.Error(t, protoStore.Put(ctx, "user", user))
+
+	var timeout duration.Duration
+	require.NoError(t, protoStore.Get(ctx, "timeout", &timeout))
+	require.Equal(t, int64(60), timeout.Seconds)
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// sliceRange returns the part of the body read by GetRangeReader: from offset, or the last -offset bytes when
+// it's negative, limited to length bytes unless it's negative
+func sliceRange(body []byte, offset, length int64) []byte {
+	size := int64(len(body))
+
+	switch {
+	case offset < 0 && -offset < size:
+		offset += size
+	case offset < 0:
+		offset = 0
+	case offset > size:
+		offset = size
+	}
+
+	body = body[offset:]
+	if length >= 0 && length < int64(len(body)) {
+		body = body[:length]
+	}
+
+	return body
+}
+
+// limitTail limits the tail read from a negative offset to length bytes, unless it's negative
+func limitTail(reader io.ReadCloser, length int64) io.ReadCloser {
+	if length < 0 {
+		return reader
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{Reader: io.LimitReader(reader, length), Closer: reader}
+}
+
+// rangeNotSatisfiable returns whether the provider rejected the range, which is the case of the suffix ranges
+// of the empty objects
+func rangeNotSatisfiable(err error) bool {
+	var providerErr *ProviderError
+
+	return errors.As(err, &providerErr) && providerErr.StatusCode == http.StatusRequestedRangeNotSatisfiable
+}
+
+// awsTailReader reads the last tail bytes of the object with a suffix range, which the s3blob driver doesn't send
+func awsTailReader(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	key string,
+	tail int64,
+	length int64,
+) (io.ReadCloser, error) {
+	output, err := client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(awsEscapeKey(key)),
+		Range:  aws.String(fmt.Sprintf("bytes=-%d", tail)),
+	})
+
+	err = translateError(err)
+	if rangeNotSatisfiable(err) {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return limitTail(output.Body, length), nil
+}
+
+// gcpTailReader reads the last tail bytes of the object with a suffix range
+func gcpTailReader(
+	ctx context.Context,
+	client *storage.Client,
+	bucketName string,
+	key string,
+	tail int64,
+	length int64,
+) (io.ReadCloser, error) {
+	reader, err := client.Bucket(bucketName).Object(key).NewRangeReader(ctx, -tail, -1)
+
+	err = translateError(err)
+	if rangeNotSatisfiable(err) {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return limitTail(reader, length), nil
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSliceRange(t *testing.T) {
+	body := []byte("0123456789")
+
+	for _, test := range []struct {
+		offset   int64
+		length   int64
+		expected string
+	}{
+		{offset: 0, length: -1, expected: "0123456789"},
+		{offset: 3, length: 4, expected: "3456"},
+		{offset: 8, length: 4, expected: "89"},
+		{offset: 12, length: -1, expected: ""},
+		{offset: -3, length: -1, expected: "789"},
+		{offset: -3, length: 2, expected: "78"},
+		{offset: -10, length: -1, expected: "0123456789"},
+		{offset: -20, length: -1, expected: "0123456789"},
+	} {
+		require.Equal(t, test.expected, string(sliceRange(body, test.offset, test.length)), "%d %d", test.offset, test.length)
+	}
+}
+
+func TestFakeCloudStorageTailRange(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.Write(ctx, "a.zip", []byte("entries|directory"), nil))
+	require.NoError(t, storage.Write(ctx, "empty", nil, nil))
+
+	reader, err := storage.GetRangeReader(ctx, "a.zip", -9, -1)
+	require.NoError(t, err)
+
+	body, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, "directory", string(body))
+
+	reader, err = storage.GetRangeReader(ctx, "empty", -9, -1)
+	require.NoError(t, err)
+
+	body, err = ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Empty(t, body)
+}
+
+func TestAWSTailReader(t *testing.T) {
+	var ranges []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+
+		if r.URL.Path == "/bucket/empty" {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			w.Write([]byte(`<Error><Code>InvalidRange</Code></Error>`))
+
+			return
+		}
+
+		w.Header().Set("Content-Range", "bytes 9-16/17")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("directory"))
+	}))
+	defer server.Close()
+
+	awsSession, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(server.URL),
+		Region:           aws.String("us-east-1"),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	client := s3.New(awsSession)
+
+	reader, err := awsTailReader(ctx, client, "bucket", "a.zip", 9, 3)
+	require.NoError(t, err)
+
+	body, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	require.Equal(t, "dir", string(body))
+
+	// the suffix ranges of the empty objects can't be satisfied
+	reader, err = awsTailReader(ctx, client, "bucket", "empty", 9, -1)
+	require.NoError(t, err)
+
+	body, err = ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Empty(t, body)
+
+	require.Equal(t, []string{"bytes=-9", "bytes=-9"}, ranges)
+}
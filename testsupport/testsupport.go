@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+// Package testsupport starts the storage emulators in Docker containers with testcontainers-go,
+// so the integration tests don't depend on services started beforehand at fixed ports.
+package testsupport
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	commonblobgo "github.com/AccelByte/common-blob-go"
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	localStackImage     = "localstack/localstack:0.11.2"
+	localStackPort      = "4572/tcp"
+	fakeGCSServerImage  = "fsouza/fake-gcs-server:v1.19.4"
+	fakeGCSServerPort   = "4443/tcp"
+	testRegion          = "us-west-2"
+	testCredentialsJSON = `{"type": "service_account", "project_id": "my-project-id"}`
+	bucketExpirationDay = 1
+)
+
+// Environment is a CloudStorage backed by an emulator running in a container
+type Environment struct {
+	Storage   commonblobgo.CloudStorage
+	container testcontainers.Container
+}
+
+// Terminate closes the storage and removes the container
+func (e *Environment) Terminate(ctx context.Context) error {
+	e.Storage.Close()
+
+	return e.container.Terminate(ctx)
+}
+
+// StartAWS starts LocalStack and returns a storage on a created bucket
+func StartAWS(ctx context.Context, bucketName string, opts commonblobgo.CloudStorageOption) (*Environment, error) {
+	container, endpoint, err := startContainer(ctx, testcontainers.ContainerRequest{
+		Image:        localStackImage,
+		ExposedPorts: []string{localStackPort},
+		Env:          map[string]string{"SERVICES": "s3"},
+		WaitingFor:   wait.ForLog("Ready."),
+	}, localStackPort)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.AWSS3Endpoint = "http://" + endpoint
+	opts.AWSS3Region = testRegion
+	opts.AWSS3AccessKeyID = "AWS_ACCESS_KEY_ID"
+	opts.AWSS3SecretAccessKey = "AWS_SECRET_ACCESS_KEY"
+
+	return newEnvironment(ctx, container, "aws", bucketName, opts)
+}
+
+// StartGCP starts fake-gcs-server and returns a storage on a created bucket
+func StartGCP(ctx context.Context, bucketName string, opts commonblobgo.CloudStorageOption) (*Environment, error) {
+	container, endpoint, err := startContainer(ctx, testcontainers.ContainerRequest{
+		Image:        fakeGCSServerImage,
+		ExposedPorts: []string{fakeGCSServerPort},
+		Entrypoint:   []string{"/bin/fake-gcs-server", "-data", "/data", "-scheme", "http", "-backend", "memory"},
+		WaitingFor:   wait.ForListeningPort(fakeGCSServerPort),
+	}, fakeGCSServerPort)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.GCPCredentialsJSON = testCredentialsJSON
+	opts.GCPStorageEmulatorHost = endpoint
+
+	return newEnvironment(ctx, container, "gcp", bucketName, opts)
+}
+
+// AWS starts LocalStack for the test, the container is removed when the test ends
+func AWS(t testing.TB, bucketName string) commonblobgo.CloudStorage {
+	return start(t, StartAWS, bucketName)
+}
+
+// GCP starts fake-gcs-server for the test, the container is removed when the test ends
+func GCP(t testing.TB, bucketName string) commonblobgo.CloudStorage {
+	return start(t, StartGCP, bucketName)
+}
+
+func start(
+	t testing.TB,
+	startEnvironment func(ctx context.Context, bucketName string, opts commonblobgo.CloudStorageOption) (*Environment, error),
+	bucketName string,
+) commonblobgo.CloudStorage {
+	t.Helper()
+
+	ctx := context.Background()
+
+	env, err := startEnvironment(ctx, bucketName, commonblobgo.CloudStorageOption{})
+	if err != nil {
+		t.Fatalf("unable to start the storage emulator: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := env.Terminate(ctx); err != nil {
+			t.Errorf("unable to terminate the storage emulator: %v", err)
+		}
+	})
+
+	return env.Storage
+}
+
+// startContainer returns the started container and the host:port of its mapped port
+func startContainer(
+	ctx context.Context,
+	request testcontainers.ContainerRequest,
+	port string,
+) (testcontainers.Container, string, error) {
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: request,
+		Started:          true,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to start %s: %v", request.Image, err)
+	}
+
+	endpoint, err := container.PortEndpoint(ctx, nat.Port(port), "")
+	if err != nil {
+		_ = container.Terminate(ctx)
+		return nil, "", fmt.Errorf("unable to get the endpoint of %s: %v", request.Image, err)
+	}
+
+	return container, endpoint, nil
+}
+
+func newEnvironment(
+	ctx context.Context,
+	container testcontainers.Container,
+	bucketProvider string,
+	bucketName string,
+	opts commonblobgo.CloudStorageOption,
+) (*Environment, error) {
+	storage, err := commonblobgo.NewCloudStorageWithOption(ctx, true, bucketProvider, bucketName, opts)
+	if err == nil {
+		err = storage.CreateBucket(ctx, "", bucketExpirationDay)
+	}
+
+	if err != nil {
+		_ = container.Terminate(ctx)
+		return nil, err
+	}
+
+	return &Environment{
+		Storage:   storage,
+		container: container,
+	}, nil
+}
@@ -0,0 +1,298 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+
+	"golang.org/x/time/rate"
+)
+
+// BandwidthOption caps the bandwidth used by a client. The caps are shared by all the operations of the client.
+type BandwidthOption struct {
+	// UploadBytesPerSecond caps Write, GetWriter, Upload and the parts of the upload sessions. Zero means unlimited.
+	UploadBytesPerSecond int64
+	// DownloadBytesPerSecond caps Get, GetReader, GetRangeReader and GetWithAttributes. Zero means unlimited.
+	DownloadBytesPerSecond int64
+}
+
+// bandwidthLimiter is a token bucket allowing bursts of one second worth of bytes
+type bandwidthLimiter struct {
+	limiter *rate.Limiter
+}
+
+func newBandwidthLimiter(bytesPerSecond int64) *bandwidthLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+
+	return &bandwidthLimiter{
+		limiter: rate.NewLimiter(rate.Limit(bytesPerSecond), int(bytesPerSecond)),
+	}
+}
+
+// wait blocks until n bytes can be transferred
+func (l *bandwidthLimiter) wait(ctx context.Context, n int) error {
+	for n > 0 {
+		chunk := n
+		if burst := l.limiter.Burst(); chunk > burst {
+			chunk = burst
+		}
+
+		if err := l.limiter.WaitN(ctx, chunk); err != nil {
+			return err
+		}
+
+		n -= chunk
+	}
+
+	return nil
+}
+
+type throttledReader struct {
+	io.ReadCloser
+	ctx     context.Context
+	limiter *bandwidthLimiter
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if burst := r.limiter.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+
+	n, err := r.ReadCloser.Read(p)
+	if waitErr := r.limiter.wait(r.ctx, n); waitErr != nil && err == nil {
+		err = waitErr
+	}
+
+	return n, err
+}
+
+type throttledWriter struct {
+	io.WriteCloser
+	ctx     context.Context
+	limiter *bandwidthLimiter
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+
+	for len(p) > 0 {
+		chunk := p
+		if burst := w.limiter.limiter.Burst(); len(chunk) > burst {
+			chunk = chunk[:burst]
+		}
+
+		if err := w.limiter.wait(w.ctx, len(chunk)); err != nil {
+			return written, err
+		}
+
+		n, err := w.WriteCloser.Write(chunk)
+		written += n
+
+		if err != nil {
+			return written, err
+		}
+
+		p = p[n:]
+	}
+
+	return written, nil
+}
+
+// throttledCloudStorage enforces the bandwidth caps in the readers and writers of the wrapped CloudStorage
+type throttledCloudStorage struct {
+	CloudStorage
+	upload   *bandwidthLimiter
+	download *bandwidthLimiter
+}
+
+func newThrottledCloudStorage(storage CloudStorage, opts BandwidthOption) *throttledCloudStorage {
+	return &throttledCloudStorage{
+		CloudStorage: storage,
+		upload:       newBandwidthLimiter(opts.UploadBytesPerSecond),
+		download:     newBandwidthLimiter(opts.DownloadBytesPerSecond),
+	}
+}
+
+func (ts *throttledCloudStorage) reader(ctx context.Context, reader io.ReadCloser) io.ReadCloser {
+	if ts.download == nil {
+		return reader
+	}
+
+	return &throttledReader{ReadCloser: reader, ctx: ctx, limiter: ts.download}
+}
+
+func (ts *throttledCloudStorage) Get(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) ([]byte, error) {
+	if ts.download == nil {
+		return ts.CloudStorage.Get(ctx, key, opts...)
+	}
+
+	reader, err := ts.GetReader(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return ioutil.ReadAll(reader)
+}
+
+func (ts *throttledCloudStorage) GetReader(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	reader, err := ts.CloudStorage.GetReader(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.reader(ctx, reader), nil
+}
+
+func (ts *throttledCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	reader, attrs, err := ts.CloudStorage.GetWithAttributes(ctx, key, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ts.reader(ctx, reader), attrs, nil
+}
+
+func (ts *throttledCloudStorage) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset,
+	length int64,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	reader, err := ts.CloudStorage.GetRangeReader(ctx, key, offset, length, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.reader(ctx, reader), nil
+}
+
+func (ts *throttledCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	// the body is sent by the SDK in one go, so the bandwidth is reserved before sending it
+	if ts.upload != nil {
+		if err := ts.upload.wait(ctx, len(body)); err != nil {
+			return err
+		}
+	}
+
+	return ts.CloudStorage.Write(ctx, key, body, contentType, opts...)
+}
+
+func (ts *throttledCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	if ts.upload != nil {
+		if err := ts.upload.wait(ctx, len(body)); err != nil {
+			return "", err
+		}
+	}
+
+	return ts.CloudStorage.WriteIf(ctx, key, body, contentType, condition)
+}
+
+func (ts *throttledCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	writer, err := ts.CloudStorage.GetWriter(ctx, key, opts...)
+	if err != nil || ts.upload == nil {
+		return writer, err
+	}
+
+	return &throttledWriter{WriteCloser: writer, ctx: ctx, limiter: ts.upload}, nil
+}
+
+func (ts *throttledCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	if ts.upload == nil {
+		return ts.CloudStorage.Upload(ctx, key, reader, opts, writeOpts...)
+	}
+
+	throttled := &throttledReader{ReadCloser: ioutil.NopCloser(reader), ctx: ctx, limiter: ts.upload}
+
+	return ts.CloudStorage.Upload(ctx, key, throttled, opts, writeOpts...)
+}
+
+func (ts *throttledCloudStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	session, err := ts.CloudStorage.BeginUpload(ctx, key, opts)
+	if err != nil || ts.upload == nil {
+		return session, err
+	}
+
+	return ts.throttleUpload(session), nil
+}
+
+func (ts *throttledCloudStorage) ResumeUpload(
+	ctx context.Context,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	session, err := ts.CloudStorage.ResumeUpload(ctx, state)
+	if err != nil || ts.upload == nil {
+		return session, err
+	}
+
+	return ts.throttleUpload(session), nil
+}
+
+// throttleUpload reserves the bandwidth of every part before it's sent, like the body of Write
+func (ts *throttledCloudStorage) throttleUpload(session *UploadSession) *UploadSession {
+	return guardUploadSession(session, func(ctx context.Context, state *UploadSessionState, number int, body []byte) error {
+		return ts.upload.wait(ctx, len(body))
+	}, nil)
+}
+
+// Flush flushes the wrapped storage
+func (ts *throttledCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
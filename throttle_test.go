@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottledReader(t *testing.T) {
+	limiter := newBandwidthLimiter(1000)
+	reader := &throttledReader{
+		ReadCloser: ioutil.NopCloser(bytes.NewReader(make([]byte, 1500))),
+		ctx:        context.Background(),
+		limiter:    limiter,
+	}
+
+	start := time.Now()
+
+	body, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Len(t, body, 1500)
+
+	// the first second worth of bytes is the burst, the remaining 500 bytes take half a second
+	require.True(t, time.Since(start) >= 400*time.Millisecond)
+}
+
+// readOptionsCloudStorage records the number of options of the readers
+type readOptionsCloudStorage struct {
+	CloudStorage
+	options int
+}
+
+func (ts *readOptionsCloudStorage) GetReader(ctx context.Context, key string, opts ...ReadOption) (io.ReadCloser, error) {
+	ts.options = len(opts)
+
+	return ts.CloudStorage.GetReader(ctx, key, opts...)
+}
+
+func TestThrottledCloudStorage(t *testing.T) {
+	ctx := context.Background()
+	backend := &readOptionsCloudStorage{CloudStorage: NewFakeCloudStorage("bucket")}
+	storage := newThrottledCloudStorage(backend, BandwidthOption{UploadBytesPerSecond: 100, DownloadBytesPerSecond: 1000})
+
+	require.NoError(t, storage.Write(ctx, "key", []byte("body"), nil))
+
+	// the options of Get are given to the reader
+	body, err := storage.Get(ctx, "key", WithTimeout(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, []byte("body"), body)
+	require.Equal(t, 1, backend.options)
+
+	session, err := storage.BeginUpload(ctx, "large.bin", &UploadOption{PartSize: 150})
+	require.NoError(t, err)
+
+	start := time.Now()
+
+	// the burst is the first 100 bytes, and the bandwidth was already used by Write
+	require.NoError(t, session.UploadPart(ctx, 1, make([]byte, 150)))
+	require.True(t, time.Since(start) >= 400*time.Millisecond)
+	require.NoError(t, session.Complete(ctx))
+
+	attrs, err := storage.Attributes(ctx, "large.bin")
+	require.NoError(t, err)
+	require.Equal(t, int64(150), attrs.Size)
+}
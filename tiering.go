@@ -0,0 +1,257 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+)
+
+// DefaultTieringInterval is the delay between two runs of Tiering when TieringOption.Interval is not set
+const DefaultTieringInterval = 24 * time.Hour
+
+// TieringRule moves the objects under the prefix to a colder storage class once they match its conditions
+type TieringRule struct {
+	Prefix string
+	// StorageClass is the storage class of the moved objects, as named by the provider, e.g. GLACIER or COLDLINE
+	StorageClass string
+	// MinAge is the minimum time since the last modification of the moved objects
+	MinAge time.Duration
+	// MinIdle is the minimum time since the last access of the moved objects, as given by TieringOption.LastAccess.
+	// It's ignored without LastAccess and for the objects whose last access isn't known.
+	MinIdle time.Duration
+	// MinSize skips the smaller objects, which the colder storage classes bill at a minimum size
+	MinSize int64
+}
+
+// TieringOption configures a Tiering
+type TieringOption struct {
+	// Rules are matched by the longest prefix of the keys. The rules of a prefix are listed from the warmest
+	// to the coldest storage class, the last one met wins.
+	Rules []TieringRule
+	// Interval is the delay between two runs. Defaults to DefaultTieringInterval.
+	Interval time.Duration
+	// Clock returns the current time compared with the modification and access times, time.Now when it's nil
+	Clock func() time.Time
+	// LastAccess returns the last access time of the object, false when it isn't known, e.g. from the access logs
+	LastAccess func(ctx context.Context, key string) (time.Time, bool)
+	// DryRun reports the transitions without rewriting the objects
+	DryRun bool
+	// OnRun is called after every run with its report, e.g. to export metrics
+	OnRun func(report *TieringReport, err error)
+	// Logger receives the transitions and the failed runs. They are discarded when it's nil.
+	Logger Logger
+}
+
+// TieringTransition is the move of an object to another storage class
+type TieringTransition struct {
+	Key  string
+	From string
+	To   string
+	Size int64
+	// Err is the error of the rewrite, the object is moved again by the next run
+	Err error
+}
+
+// TieringReport lists the transitions of a run
+type TieringReport struct {
+	Transitions []TieringTransition
+}
+
+// Tiering moves the objects to colder storage classes with per-prefix rules, e.g. per tenant, which the lifecycle
+// rules of the buckets can't express. The objects are rewritten server-side, which updates their modification time:
+// the MinAge of the next rule counts from the previous transition, and an object is only moved to the storage class
+// of a later rule than its current one.
+type Tiering struct {
+	storage CloudStorage
+	opts    TieringOption
+}
+
+// NewTiering returns a Tiering of the storage, call Run to start it
+func NewTiering(storage CloudStorage, opts TieringOption) *Tiering {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultTieringInterval
+	}
+
+	if opts.Clock == nil {
+		opts.Clock = time.Now
+	}
+
+	opts.Logger = loggerOrNoop(opts.Logger)
+
+	return &Tiering{storage: storage, opts: opts}
+}
+
+// Run applies the rules every Interval until ctx is done, then returns ctx.Err()
+func (t *Tiering) Run(ctx context.Context) error {
+	ticker := time.NewTicker(t.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := t.Apply(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if t.opts.OnRun != nil {
+			t.opts.OnRun(report, err)
+		}
+
+		if err != nil {
+			t.opts.Logger.Error("unable to apply the tiering rules", Fields{"error": err})
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Apply moves the objects matching the rules once. The failed rewrites are reported with their error
+// and don't stop the run.
+func (t *Tiering) Apply(ctx context.Context) (*TieringReport, error) {
+	report := &TieringReport{}
+	now := t.opts.Clock()
+
+	for _, prefix := range t.prefixes() {
+		iter := t.storage.List(ctx, prefix)
+
+		for {
+			object, err := iter.Next(ctx)
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				return report, err
+			}
+
+			rule := t.match(ctx, object, now)
+			if rule == nil {
+				continue
+			}
+
+			transition := TieringTransition{
+				Key:  object.Key,
+				From: object.StorageClass,
+				To:   rule.StorageClass,
+				Size: object.Size,
+			}
+
+			if !t.opts.DryRun {
+				transition.Err = t.storage.SetStorageClass(ctx, object.Key, rule.StorageClass)
+			}
+
+			fields := Fields{"key": transition.Key, "from": transition.From, "to": transition.To, "size": transition.Size}
+			if transition.Err != nil {
+				fields["error"] = transition.Err
+				t.opts.Logger.Warn("unable to move the object to the storage class", fields)
+			} else {
+				t.opts.Logger.Info("moved the object to the storage class", fields)
+			}
+
+			report.Transitions = append(report.Transitions, transition)
+		}
+	}
+
+	return report, nil
+}
+
+// prefixes returns the prefixes to list, without the ones under another prefix
+func (t *Tiering) prefixes() []string {
+	var prefixes []string
+
+	seen := make(map[string]bool)
+
+	for _, rule := range t.opts.Rules {
+		covered := seen[rule.Prefix]
+
+		for _, other := range t.opts.Rules {
+			if other.Prefix != rule.Prefix && strings.HasPrefix(rule.Prefix, other.Prefix) {
+				covered = true
+				break
+			}
+		}
+
+		if !covered {
+			prefixes = append(prefixes, rule.Prefix)
+		}
+
+		seen[rule.Prefix] = true
+	}
+
+	return prefixes
+}
+
+// match returns the last rule of the longest prefix of the key met by the object, after the rule of its current
+// storage class, or nil when it stays in its storage class
+func (t *Tiering) match(ctx context.Context, object *ListObject, now time.Time) *TieringRule {
+	var rules []*TieringRule
+
+	for i := range t.opts.Rules {
+		rule := &t.opts.Rules[i]
+		if !strings.HasPrefix(object.Key, rule.Prefix) {
+			continue
+		}
+
+		if len(rules) > 0 && len(rule.Prefix) < len(rules[0].Prefix) {
+			continue
+		}
+
+		if len(rules) > 0 && len(rule.Prefix) > len(rules[0].Prefix) {
+			rules = nil
+		}
+
+		rules = append(rules, rule)
+	}
+
+	// the rules up to the one of the current storage class would move the object back to a warmer class
+	for i, rule := range rules {
+		if rule.StorageClass == object.StorageClass {
+			rules = rules[i+1:]
+			break
+		}
+	}
+
+	var lastAccess time.Time
+
+	var accessKnown bool
+
+	if t.opts.LastAccess != nil && len(rules) > 0 {
+		lastAccess, accessKnown = t.opts.LastAccess(ctx, object.Key)
+	}
+
+	var match *TieringRule
+
+	for _, rule := range rules {
+		if now.Sub(object.ModTime) < rule.MinAge || object.Size < rule.MinSize {
+			continue
+		}
+
+		if accessKnown && now.Sub(lastAccess) < rule.MinIdle {
+			continue
+		}
+
+		match = rule
+	}
+
+	return match
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTieringApply(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	storage := NewFakeCloudStorage("bucket")
+	storage.SetClock(func() time.Time { return now })
+
+	require.NoError(t, storage.Write(ctx, "tenants/a/export.csv", []byte("a"), nil))
+	require.NoError(t, storage.Write(ctx, "tenants/b/export.csv", []byte("b"), nil))
+	require.NoError(t, storage.Write(ctx, "tenants/b/hot.csv", []byte("c"), nil))
+
+	tiering := NewTiering(storage, TieringOption{
+		Rules: []TieringRule{
+			{Prefix: "tenants/", StorageClass: "STANDARD_IA", MinAge: 30 * 24 * time.Hour},
+			{Prefix: "tenants/", StorageClass: "GLACIER", MinAge: 90 * 24 * time.Hour},
+			// tenant b keeps its objects warm for longer
+			{Prefix: "tenants/b/", StorageClass: "STANDARD_IA", MinAge: 60 * 24 * time.Hour, MinIdle: 7 * 24 * time.Hour},
+		},
+		Clock: func() time.Time { return now },
+		LastAccess: func(ctx context.Context, key string) (time.Time, bool) {
+			return now, key == "tenants/b/hot.csv"
+		},
+	})
+
+	report, err := tiering.Apply(ctx)
+	require.NoError(t, err)
+	require.Empty(t, report.Transitions)
+
+	now = now.Add(61 * 24 * time.Hour)
+
+	report, err = tiering.Apply(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []TieringTransition{
+		{Key: "tenants/a/export.csv", From: "STANDARD", To: "STANDARD_IA", Size: 1},
+		{Key: "tenants/b/export.csv", From: "STANDARD", To: "STANDARD_IA", Size: 1},
+	}, report.Transitions)
+
+	objects, err := listByName(ctx, storage, "tenants/")
+	require.NoError(t, err)
+	require.Equal(t, "STANDARD_IA", objects["a/export.csv"].StorageClass)
+	require.Equal(t, "STANDARD", objects["b/hot.csv"].StorageClass)
+
+	// the rewrite updated the modification time, the objects aren't moved back to a warmer class
+	now = now.Add(31 * 24 * time.Hour)
+
+	report, err = tiering.Apply(ctx)
+	require.NoError(t, err)
+	require.Empty(t, report.Transitions)
+
+	now = now.Add(60 * 24 * time.Hour)
+
+	report, err = tiering.Apply(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []TieringTransition{
+		{Key: "tenants/a/export.csv", From: "STANDARD_IA", To: "GLACIER", Size: 1},
+	}, report.Transitions)
+}
+
+func TestTieringDryRun(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.Write(ctx, "logs/2020-01-01.log", []byte("log"), nil))
+
+	tiering := NewTiering(storage, TieringOption{
+		Rules:  []TieringRule{{Prefix: "logs/", StorageClass: "COLDLINE"}},
+		DryRun: true,
+	})
+
+	report, err := tiering.Apply(ctx)
+	require.NoError(t, err)
+	require.Len(t, report.Transitions, 1)
+
+	objects, err := listByName(ctx, storage, "logs/")
+	require.NoError(t, err)
+	require.Equal(t, "STANDARD", objects["2020-01-01.log"].StorageClass)
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/AccelByte/common-blob-go"
+
+// newTracingInterceptor starts a client span around every operation, as a child of the span in the incoming ctx
+func newTracingInterceptor(provider trace.TracerProvider) Interceptor {
+	tracer := provider.Tracer(tracerName)
+
+	return func(ctx context.Context, op *OperationInfo, next func(ctx context.Context) error) error {
+		ctx, span := tracer.Start(ctx, "CloudStorage."+op.Name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("blob.provider", op.Provider),
+				attribute.String("blob.bucket", op.Bucket),
+				attribute.String("blob.key", op.Key),
+			),
+		)
+		defer span.End()
+
+		if op.DestinationKey != "" {
+			span.SetAttributes(attribute.String("blob.destination_key", op.DestinationKey))
+		}
+
+		span.SetAttributes(op.TraceAttributes...)
+
+		err := next(ctx)
+
+		span.SetAttributes(attribute.Int64("blob.bytes", op.Bytes))
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,364 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultUploadPartSize is the part size used by Upload when UploadOption.PartSize is not set
+	DefaultUploadPartSize = 16 * 1024 * 1024
+	// DefaultUploadConcurrency is the number of parallel part uploads used by Upload
+	// when UploadOption.Concurrency is not set
+	DefaultUploadConcurrency = 5
+
+	// GCP compose accepts up to 32 source objects per request
+	gcpMaxComposeSources = 32
+)
+
+// UploadOption configures a parallel multipart Upload.
+// Memory usage of an upload is bounded by PartSize * Concurrency.
+type UploadOption struct {
+	// PartSize is the size in bytes of every uploaded part.
+	// AWS requires at least 5MB for every part except the last one.
+	PartSize int64
+	// Concurrency is the number of parts uploaded in parallel.
+	Concurrency int
+	// ContentType is the MIME type of the resulting object.
+	ContentType string
+	// Verify checks the object uploaded by Upload against the checksum of the read content, the ETag on AWS and the
+	// CRC32C on GCP, and returns an IntegrityError when they differ. The GCP parts are also sent with their CRC32C.
+	// The ETag of the AWS objects encrypted with SSE-KMS or SSE-C isn't based on their content, don't verify them.
+	Verify bool
+}
+
+func (o *UploadOption) withDefaults() UploadOption {
+	var result UploadOption
+	if o != nil {
+		result = *o
+	}
+
+	if result.PartSize <= 0 {
+		result.PartSize = DefaultUploadPartSize
+	}
+
+	if result.Concurrency <= 0 {
+		result.Concurrency = DefaultUploadConcurrency
+	}
+
+	return result
+}
+
+func awsUpload(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts []WriteOption,
+) error {
+	options := opts.withDefaults()
+	call := writeOptions(writeOpts)
+
+	if options.ContentType == "" {
+		options.ContentType = call.contentType
+	}
+
+	if options.ContentType == "" {
+		contentType, sniffed, err := sniffReader(key, reader)
+		if err != nil {
+			return err
+		}
+
+		options.ContentType, reader = contentType, sniffed
+	}
+
+	uploader := s3manager.NewUploaderWithClient(client, func(u *s3manager.Uploader) {
+		u.PartSize = options.PartSize
+		u.Concurrency = options.Concurrency
+	})
+
+	var verifier *etagVerifier
+
+	if options.Verify {
+		verifier = newETagVerifier(options.PartSize)
+		reader = io.TeeReader(reader, verifier)
+	}
+
+	counter := &countingReader{Reader: reader}
+
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(awsEscapeKey(key)),
+		Body:   counter,
+	}
+	if options.ContentType != "" {
+		input.ContentType = aws.String(options.ContentType)
+	}
+
+	call.applyUploadInput(input)
+
+	output, err := uploader.UploadWithContext(ctx, input)
+	if err != nil {
+		return translateError(err)
+	}
+
+	if verifier != nil {
+		if err := verifier.verify(key, aws.StringValue(output.ETag)); err != nil {
+			return err
+		}
+	}
+
+	call.written.set(awsUploadAttributes(input, output, counter.count))
+
+	return nil
+}
+
+// gcpUpload uploads the parts as temporary objects in parallel and composes them into the final object.
+// Temporary objects are stored next to the key with the ".parts-<uuid>/" suffix and are removed afterwards.
+// nolint:funlen
+func gcpUpload(
+	ctx context.Context,
+	client *storage.Client,
+	bucketName string,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts []WriteOption,
+	logger Logger,
+) error {
+	options := opts.withDefaults()
+	call := writeOptions(writeOpts)
+
+	if options.ContentType == "" {
+		options.ContentType = call.contentType
+	}
+
+	if options.ContentType == "" {
+		contentType, sniffed, err := sniffReader(key, reader)
+		if err != nil {
+			return err
+		}
+
+		options.ContentType, reader = contentType, sniffed
+	}
+
+	checksum := crc32.New(crc32cTable)
+	if options.Verify {
+		reader = io.TeeReader(reader, checksum)
+	}
+
+	target := call.objectAttrs(options.ContentType)
+	bucket := client.Bucket(bucketName)
+	partPrefix := fmt.Sprintf("%s.parts-%s/", key, uuid.New().String())
+
+	var temporary []string
+
+	defer func() {
+		// the upload context could be already cancelled at this point
+		for _, name := range temporary {
+			if err := bucket.Object(name).Delete(context.Background()); err != nil {
+				logger.Warn("unable to delete temporary upload part", Fields{"key": name, "error": err})
+			}
+		}
+	}()
+
+	uploadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg        sync.WaitGroup
+		errOnce   sync.Once
+		uploadErr error
+	)
+
+	setErr := func(err error) {
+		errOnce.Do(func() {
+			uploadErr = err
+
+			cancel()
+		})
+	}
+
+	semaphore := make(chan struct{}, options.Concurrency)
+
+	for n := 0; uploadCtx.Err() == nil; n++ {
+		// the part is allocated once a slot is free, so at most Concurrency parts are held in memory
+		semaphore <- struct{}{}
+
+		buf := make([]byte, options.PartSize)
+
+		read, readErr := io.ReadFull(reader, buf)
+		if read == 0 {
+			<-semaphore
+		} else {
+			name := fmt.Sprintf("%s%05d", partPrefix, n)
+			temporary = append(temporary, name)
+
+			wg.Add(1)
+
+			go func(name string, body []byte) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+
+				writer := bucket.Object(name).NewWriter(uploadCtx)
+				// the part is already in memory, upload it with a single request
+				writer.ChunkSize = 0
+
+				if options.Verify {
+					writer.CRC32C = crc32.Checksum(body, crc32cTable)
+					writer.SendCRC32C = true
+				}
+
+				if _, err := writer.Write(body); err != nil {
+					_ = writer.Close()
+
+					setErr(err)
+
+					return
+				}
+
+				if err := writer.Close(); err != nil {
+					setErr(err)
+				}
+			}(name, buf[:read])
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+
+		if readErr != nil {
+			setErr(readErr)
+			break
+		}
+	}
+
+	wg.Wait()
+
+	if uploadErr != nil {
+		return uploadErr
+	}
+
+	if err := uploadCtx.Err(); err != nil {
+		return err
+	}
+
+	if len(temporary) == 0 {
+		writer := bucket.Object(key).NewWriter(ctx)
+		writer.ContentType = target.ContentType
+		writer.CacheControl = target.CacheControl
+		writer.ContentDisposition = target.ContentDisposition
+		writer.Metadata = target.Metadata
+		writer.StorageClass = target.StorageClass
+
+		if err := writer.Close(); err != nil {
+			return err
+		}
+
+		call.written.set(gcsAttributes(writer.Attrs()))
+
+		return nil
+	}
+
+	composed, attrs, err := gcpComposeParts(ctx, bucket, key, partPrefix, temporary, target)
+	temporary = append(temporary, composed...)
+
+	if err != nil {
+		return err
+	}
+
+	if options.Verify {
+		if err := verifyCRC32C(key, checksum.Sum32(), attrs.CRC32C); err != nil {
+			return err
+		}
+	}
+
+	call.written.set(gcsAttributes(attrs))
+
+	return nil
+}
+
+// gcpComposeParts composes the sources into the key with the target attributes, through intermediate objects
+// under the part prefix when there are more than 32 sources. It returns the intermediate objects, to be deleted,
+// and the attributes of the composed object.
+func gcpComposeParts(
+	ctx context.Context,
+	bucket *storage.BucketHandle,
+	key string,
+	partPrefix string,
+	sources []string,
+	target storage.ObjectAttrs,
+) ([]string, *storage.ObjectAttrs, error) {
+	var temporary []string
+
+	for level := 0; len(sources) > gcpMaxComposeSources; level++ {
+		var composed []string
+
+		for i := 0; i < len(sources); i += gcpMaxComposeSources {
+			end := i + gcpMaxComposeSources
+			if end > len(sources) {
+				end = len(sources)
+			}
+
+			name := fmt.Sprintf("%scompose-%d-%05d", partPrefix, level, i/gcpMaxComposeSources)
+			temporary = append(temporary, name)
+
+			if _, err := gcpCompose(ctx, bucket, name, sources[i:end], storage.ObjectAttrs{}); err != nil {
+				return temporary, nil, err
+			}
+
+			composed = append(composed, name)
+		}
+
+		sources = composed
+	}
+
+	attrs, err := gcpCompose(ctx, bucket, key, sources, target)
+
+	return temporary, attrs, err
+}
+
+func gcpCompose(
+	ctx context.Context,
+	bucket *storage.BucketHandle,
+	key string,
+	sources []string,
+	target storage.ObjectAttrs,
+) (*storage.ObjectAttrs, error) {
+	handles := make([]*storage.ObjectHandle, 0, len(sources))
+	for _, source := range sources {
+		handles = append(handles, bucket.Object(source))
+	}
+
+	composer := bucket.Object(key).ComposerFrom(handles...)
+	composer.ObjectAttrs = target
+
+	return composer.Run(ctx)
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+// newHTTPTestS3Client returns an S3 client sending the path-style requests to the handler, without retries
+func newHTTPTestS3Client(t *testing.T, handler http.HandlerFunc) (*s3.S3, *httptest.Server) {
+	server := httptest.NewServer(handler)
+
+	awsSession, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(server.URL),
+		Region:           aws.String("us-east-1"),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:       aws.Int(0),
+	})
+	require.NoError(t, err)
+
+	return s3.New(awsSession), server
+}
+
+func TestAWSUpload(t *testing.T) {
+	var paths []string
+
+	status := http.StatusOK
+
+	client, server := newHTTPTestS3Client(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+
+			return
+		}
+
+		w.Header().Set("ETag", `"etag"`)
+	})
+	defer server.Close()
+
+	ctx := context.Background()
+
+	// the key is escaped like the keys of Get, so the object can be read back
+	err := awsUpload(ctx, client, "bucket", "a//b", bytes.NewReader([]byte("body")), &UploadOption{ContentType: "text/plain"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/bucket/a/__0x2f__b"}, paths)
+
+	status = http.StatusForbidden
+
+	err = awsUpload(ctx, client, "bucket", "key", bytes.NewReader([]byte("body")), &UploadOption{ContentType: "text/plain"}, nil)
+	require.True(t, errors.Is(err, ErrPermissionDenied), "%v", err)
+}
+
+type readCountingReader struct {
+	io.Reader
+	mu   sync.Mutex
+	read int
+}
+
+func (r *readCountingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+
+	r.mu.Lock()
+	r.read += n
+	r.mu.Unlock()
+
+	return n, err
+}
+
+func (r *readCountingReader) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.read
+}
+
+func TestGCPUploadReadsAheadOnlyTheConcurrentParts(t *testing.T) {
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		<-release
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error": {"code": 400, "message": "rejected"}}`))
+	}))
+	defer server.Close()
+
+	var releaseOnce sync.Once
+	// the blocked uploads must be released before the server is closed, even when the test fails
+	defer releaseOnce.Do(func() { close(release) })
+
+	ctx := context.Background()
+
+	client, err := storage.NewClient(ctx, option.WithEndpoint(server.URL+"/storage/v1/"), option.WithoutAuthentication())
+	require.NoError(t, err)
+
+	defer client.Close()
+
+	reader := &readCountingReader{Reader: bytes.NewReader(make([]byte, 10*1024))}
+	done := make(chan error, 1)
+
+	go func() {
+		done <- gcpUpload(ctx, client, "bucket", "key", reader, &UploadOption{
+			PartSize:    1024,
+			Concurrency: 2,
+			ContentType: "application/octet-stream",
+		}, nil, noopLogger{})
+	}()
+
+	// the parts being uploaded are the only ones read
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, 2*1024, reader.count())
+
+	releaseOnce.Do(func() { close(release) })
+	require.Error(t, <-done)
+}
@@ -0,0 +1,320 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTransferUsageFlushInterval is the delay between two flushes of the usage report when
+// TransferUsageOption.FlushInterval is not set
+const DefaultTransferUsageFlushInterval = time.Minute
+
+// TransferUsageOption configures a TransferUsageTracker
+type TransferUsageOption struct {
+	// Depth is the number of segments of the key prefixes the bytes are tracked by, e.g. 2 tracks "tenants/a/".
+	// The keys with fewer segments are tracked under their own prefix. Defaults to 1.
+	Depth int
+	// FlushKey is the key Run writes the JSON report at, e.g. "stats/transfer-usage/<replica>.json".
+	// Its own transfers aren't tracked.
+	FlushKey string
+	// FlushInterval is the delay between two flushes of Run. Defaults to DefaultTransferUsageFlushInterval.
+	FlushInterval time.Duration
+	// Clock returns the current time of the reports, time.Now when it's nil
+	Clock func() time.Time
+	// Logger receives the errors of the flushes of Run
+	Logger Logger
+}
+
+// TransferUsage are the bytes transferred under a prefix
+type TransferUsage struct {
+	Uploaded   int64 `json:"uploaded"`
+	Downloaded int64 `json:"downloaded"`
+}
+
+// TransferUsageReport are the bytes transferred by prefix since the tracker was created
+type TransferUsageReport struct {
+	Since    time.Time                `json:"since"`
+	Time     time.Time                `json:"time"`
+	Prefixes map[string]TransferUsage `json:"prefixes"`
+}
+
+// TransferUsageTracker counts the bytes uploaded and downloaded by key prefix, e.g. to know which tenants drive
+// the egress without waiting for the bill of the provider. The bytes of the streams are counted as they are read
+// or written, including the ones of the failed transfers.
+type TransferUsageTracker struct {
+	opts     TransferUsageOption
+	since    time.Time
+	mu       sync.Mutex
+	prefixes map[string]*TransferUsage
+}
+
+func NewTransferUsageTracker(opts TransferUsageOption) *TransferUsageTracker {
+	if opts.Depth <= 0 {
+		opts.Depth = 1
+	}
+
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultTransferUsageFlushInterval
+	}
+
+	opts.Clock = clockOrNow(opts.Clock)
+	opts.Logger = loggerOrNoop(opts.Logger)
+
+	return &TransferUsageTracker{
+		opts:     opts,
+		since:    opts.Clock(),
+		prefixes: make(map[string]*TransferUsage),
+	}
+}
+
+// prefix returns the prefix of the key the bytes are tracked by
+func (t *TransferUsageTracker) prefix(key string) string {
+	end := 0
+
+	for i := 0; i < t.opts.Depth; i++ {
+		next := strings.Index(key[end:], "/")
+		if next < 0 {
+			return key
+		}
+
+		end += next + 1
+	}
+
+	return key[:end]
+}
+
+func (t *TransferUsageTracker) add(key string, uploaded, downloaded int64) {
+	if key == t.opts.FlushKey || uploaded == 0 && downloaded == 0 {
+		return
+	}
+
+	prefix := t.prefix(key)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	usage, ok := t.prefixes[prefix]
+	if !ok {
+		usage = &TransferUsage{}
+		t.prefixes[prefix] = usage
+	}
+
+	usage.Uploaded += uploaded
+	usage.Downloaded += downloaded
+}
+
+// Report returns the bytes transferred by prefix since the tracker was created
+func (t *TransferUsageTracker) Report() TransferUsageReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := TransferUsageReport{
+		Since:    t.since,
+		Time:     t.opts.Clock(),
+		Prefixes: make(map[string]TransferUsage, len(t.prefixes)),
+	}
+
+	for prefix, usage := range t.prefixes {
+		report.Prefixes[prefix] = *usage
+	}
+
+	return report
+}
+
+// Flush writes the report as JSON at the FlushKey of the storage
+func (t *TransferUsageTracker) Flush(ctx context.Context, storage CloudStorage) error {
+	body, err := json.Marshal(t.Report())
+	if err != nil {
+		return err
+	}
+
+	contentType := "application/json"
+
+	return storage.Write(ctx, t.opts.FlushKey, body, &contentType)
+}
+
+// Run flushes the report every FlushInterval until the context is done.
+// The replicas of a service share nothing, so every replica needs its own FlushKey.
+func (t *TransferUsageTracker) Run(ctx context.Context, storage CloudStorage) error {
+	ticker := time.NewTicker(t.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if err := t.Flush(ctx, storage); err != nil && ctx.Err() == nil {
+			t.opts.Logger.Error("unable to flush the transfer usage", Fields{"key": t.opts.FlushKey, "error": err})
+		}
+	}
+}
+
+// transferUsageReader counts the bytes read from a download
+type transferUsageReader struct {
+	io.ReadCloser
+	tracker *TransferUsageTracker
+	key     string
+}
+
+func (r *transferUsageReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.tracker.add(r.key, 0, int64(n))
+
+	return n, err
+}
+
+// transferUsageWriter counts the bytes written to an upload
+type transferUsageWriter struct {
+	io.WriteCloser
+	tracker *TransferUsageTracker
+	key     string
+}
+
+func (w *transferUsageWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteCloser.Write(p)
+	w.tracker.add(w.key, int64(n), 0)
+
+	return n, err
+}
+
+// transferUsageUploadReader counts the bytes read from the reader of an upload
+type transferUsageUploadReader struct {
+	io.Reader
+	tracker *TransferUsageTracker
+	key     string
+}
+
+func (r *transferUsageUploadReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.tracker.add(r.key, int64(n), 0)
+
+	return n, err
+}
+
+// transferUsageCloudStorage counts the bytes transferred by the wrapped CloudStorage
+type transferUsageCloudStorage struct {
+	CloudStorage
+	tracker *TransferUsageTracker
+}
+
+func newTransferUsageCloudStorage(storage CloudStorage, tracker *TransferUsageTracker) *transferUsageCloudStorage {
+	return &transferUsageCloudStorage{CloudStorage: storage, tracker: tracker}
+}
+
+func (ts *transferUsageCloudStorage) Get(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) ([]byte, error) {
+	body, err := ts.CloudStorage.Get(ctx, key, opts...)
+	ts.tracker.add(key, 0, int64(len(body)))
+
+	return body, err
+}
+
+func (ts *transferUsageCloudStorage) GetReader(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	reader, err := ts.CloudStorage.GetReader(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transferUsageReader{ReadCloser: reader, tracker: ts.tracker, key: key}, nil
+}
+
+func (ts *transferUsageCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	reader, attrs, err := ts.CloudStorage.GetWithAttributes(ctx, key, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &transferUsageReader{ReadCloser: reader, tracker: ts.tracker, key: key}, attrs, nil
+}
+
+func (ts *transferUsageCloudStorage) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset,
+	length int64,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	reader, err := ts.CloudStorage.GetRangeReader(ctx, key, offset, length, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transferUsageReader{ReadCloser: reader, tracker: ts.tracker, key: key}, nil
+}
+
+func (ts *transferUsageCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	err := ts.CloudStorage.Write(ctx, key, body, contentType, opts...)
+	ts.tracker.add(key, int64(len(body)), 0)
+
+	return err
+}
+
+func (ts *transferUsageCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	writer, err := ts.CloudStorage.GetWriter(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transferUsageWriter{WriteCloser: writer, tracker: ts.tracker, key: key}, nil
+}
+
+func (ts *transferUsageCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	reader = &transferUsageUploadReader{Reader: reader, tracker: ts.tracker, key: key}
+
+	return ts.CloudStorage.Upload(ctx, key, reader, opts, writeOpts...)
+}
+
+// Flush flushes the wrapped storage
+func (ts *transferUsageCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferUsageTracker(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	tracker := NewTransferUsageTracker(TransferUsageOption{Depth: 2, Clock: func() time.Time { return now }})
+	storage := newTransferUsageCloudStorage(NewFakeCloudStorage("bucket"), tracker)
+
+	require.NoError(t, storage.Write(ctx, "tenants/a/x", make([]byte, 10), nil))
+	require.NoError(t, storage.Upload(ctx, "tenants/a/y/z", bytes.NewReader(make([]byte, 20)), nil))
+
+	writer, err := storage.GetWriter(ctx, "tenants/b/x")
+	require.NoError(t, err)
+	_, err = writer.Write(make([]byte, 30))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	require.NoError(t, storage.Write(ctx, "root", make([]byte, 5), nil))
+
+	_, err = storage.Get(ctx, "tenants/a/x")
+	require.NoError(t, err)
+
+	reader, err := storage.GetRangeReader(ctx, "tenants/b/x", 0, 8)
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+
+	require.Equal(t, TransferUsageReport{
+		Since: now,
+		Time:  now,
+		Prefixes: map[string]TransferUsage{
+			"tenants/a/": {Uploaded: 30, Downloaded: 10},
+			"tenants/b/": {Uploaded: 30, Downloaded: 8},
+			"root":       {Uploaded: 5},
+		},
+	}, tracker.Report())
+}
+
+func TestTransferUsageTrackerFlush(t *testing.T) {
+	ctx := context.Background()
+	tracker := NewTransferUsageTracker(TransferUsageOption{FlushKey: "stats/usage.json"})
+	storage := newTransferUsageCloudStorage(NewFakeCloudStorage("bucket"), tracker)
+
+	require.NoError(t, storage.Write(ctx, "tenants/a/x", make([]byte, 10), nil))
+	require.NoError(t, tracker.Flush(ctx, storage))
+
+	body, err := storage.CloudStorage.Get(ctx, "stats/usage.json")
+	require.NoError(t, err)
+
+	var report TransferUsageReport
+	require.NoError(t, json.Unmarshal(body, &report))
+
+	// the flushes aren't tracked
+	require.Equal(t, map[string]TransferUsage{"tenants/": {Uploaded: 10}}, report.Prefixes)
+	require.NoError(t, tracker.Flush(ctx, storage))
+	require.Equal(t, report.Prefixes, tracker.Report().Prefixes)
+}
+
+func TestTransferUsageCloudStorageAsyncWrite(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	async := NewAsyncCloudStorage(fake, AsyncWriteOption{})
+	defer async.Close()
+
+	tracker := NewTransferUsageTracker(TransferUsageOption{})
+	storage := newTransferUsageCloudStorage(async, tracker)
+
+	require.NoError(t, storage.Write(ctx, "a.txt", []byte("body"), nil))
+	require.NoError(t, storage.Flush(ctx))
+
+	body, err := fake.Get(ctx, "a.txt")
+	require.NoError(t, err)
+	require.Equal(t, "body", string(body))
+	require.Equal(t, int64(4), tracker.Report().Prefixes["a.txt"].Uploaded)
+}
@@ -0,0 +1,415 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/cipher"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Transformer encodes the objects on the write path and decodes them on the read path
+type Transformer interface {
+	Encode(key string, body []byte) ([]byte, error)
+	Decode(key string, body []byte) ([]byte, error)
+}
+
+// TransformRule applies the transformers to the objects under the prefix.
+// They are encoded in order and decoded in the reverse order, e.g. compressed then encrypted.
+type TransformRule struct {
+	Prefix       string
+	Transformers []Transformer
+}
+
+// TransformingCloudStorage transforms the objects written and read under the prefixes of its rules,
+// so a policy like "everything under pii/ is compressed and encrypted" lives in one place.
+// The longest matching prefix wins. The transformed objects are buffered in memory,
+// and List, Attributes, GetSignedURL and GetPublicURL still give the stored objects.
+type TransformingCloudStorage struct {
+	CloudStorage
+	rules []TransformRule
+}
+
+// NewTransformingCloudStorage returns the storage transforming the objects with the rules
+func NewTransformingCloudStorage(storage CloudStorage, rules []TransformRule) *TransformingCloudStorage {
+	return &TransformingCloudStorage{CloudStorage: storage, rules: rules}
+}
+
+// transformers returns the transformers of the longest prefix matching the key
+func (ts *TransformingCloudStorage) transformers(key string) []Transformer {
+	var match *TransformRule
+
+	for i := range ts.rules {
+		rule := &ts.rules[i]
+		if strings.HasPrefix(key, rule.Prefix) && (match == nil || len(rule.Prefix) > len(match.Prefix)) {
+			match = rule
+		}
+	}
+
+	if match == nil {
+		return nil
+	}
+
+	return match.Transformers
+}
+
+func (ts *TransformingCloudStorage) encode(key string, body []byte) ([]byte, error) {
+	var err error
+
+	for _, transformer := range ts.transformers(key) {
+		if body, err = transformer.Encode(key, body); err != nil {
+			return nil, err
+		}
+	}
+
+	return body, nil
+}
+
+func (ts *TransformingCloudStorage) decode(key string, body []byte) ([]byte, error) {
+	transformers := ts.transformers(key)
+
+	var err error
+
+	for i := len(transformers) - 1; i >= 0; i-- {
+		if body, err = transformers[i].Decode(key, body); err != nil {
+			return nil, err
+		}
+	}
+
+	return body, nil
+}
+
+func (ts *TransformingCloudStorage) Get(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) ([]byte, error) {
+	if ts.transformers(key) == nil {
+		return ts.CloudStorage.Get(ctx, key, opts...)
+	}
+
+	body, err := ts.CloudStorage.Get(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.decode(key, body)
+}
+
+func (ts *TransformingCloudStorage) GetReader(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	reader, _, err := ts.GetWithAttributes(ctx, key, opts...)
+
+	return reader, err
+}
+
+func (ts *TransformingCloudStorage) GetWithAttributes(
+	ctx context.Context,
+	key string,
+	opts ...ReadOption,
+) (io.ReadCloser, *Attributes, error) {
+	reader, attrs, err := ts.CloudStorage.GetWithAttributes(ctx, key, opts...)
+	if err != nil || ts.transformers(key) == nil {
+		return reader, attrs, err
+	}
+	defer reader.Close()
+
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body, err = ts.decode(key, body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(body)), attrs, nil
+}
+
+// GetRangeReader decodes the whole object of a transformed key, since the ranges of the stored object don't match
+func (ts *TransformingCloudStorage) GetRangeReader(
+	ctx context.Context,
+	key string,
+	offset,
+	length int64,
+	opts ...ReadOption,
+) (io.ReadCloser, error) {
+	if ts.transformers(key) == nil {
+		return ts.CloudStorage.GetRangeReader(ctx, key, offset, length, opts...)
+	}
+
+	body, err := ts.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(sliceRange(body, offset, length))), nil
+}
+
+func (ts *TransformingCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	body, err := ts.encode(key, body)
+	if err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.Write(ctx, key, body, contentType, opts...)
+}
+
+func (ts *TransformingCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	body, err := ts.encode(key, body)
+	if err != nil {
+		return "", err
+	}
+
+	return ts.CloudStorage.WriteIf(ctx, key, body, contentType, condition)
+}
+
+// BeginUpload returns ErrNotSupported for the transformed keys, the parts are uploaded as is
+func (ts *TransformingCloudStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	if len(ts.transformers(key)) > 0 {
+		return nil, fmt.Errorf("%w: '%s' is transformed", ErrNotSupported, key)
+	}
+
+	return ts.CloudStorage.BeginUpload(ctx, key, opts)
+}
+
+// GetWriter buffers the object of a transformed key until the writer is closed
+func (ts *TransformingCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	if ts.transformers(key) == nil {
+		return ts.CloudStorage.GetWriter(ctx, key, opts...)
+	}
+
+	return &transformingWriter{ctx: ctx, storage: ts, key: key}, nil
+}
+
+type transformingWriter struct {
+	bytes.Buffer
+	ctx     context.Context
+	storage *TransformingCloudStorage
+	key     string
+}
+
+func (w *transformingWriter) Close() error {
+	return w.storage.Write(w.ctx, w.key, w.Bytes(), nil)
+}
+
+func (ts *TransformingCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	if ts.transformers(key) == nil {
+		return ts.CloudStorage.Upload(ctx, key, reader, opts, writeOpts...)
+	}
+
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	body, err = ts.encode(key, body)
+	if err != nil {
+		return err
+	}
+
+	return ts.CloudStorage.Upload(ctx, key, bytes.NewReader(body), opts, writeOpts...)
+}
+
+// Copy decodes and encodes the object again when one of the keys is transformed, since the encryption
+// authenticates the key
+func (ts *TransformingCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	if len(ts.transformers(srcKey)) == 0 && len(ts.transformers(dstKey)) == 0 {
+		return ts.CloudStorage.Copy(ctx, srcKey, dstKey, opts)
+	}
+
+	return streamCopy(ctx, ts, srcKey, ts, dstKey, opts)
+}
+
+// Query runs the SQL on the client side for the transformed keys, since the stored objects can't be scanned
+func (ts *TransformingCloudStorage) Query(
+	ctx context.Context,
+	key string,
+	sql string,
+	format QueryInputFormat,
+) (io.ReadCloser, error) {
+	if ts.transformers(key) == nil {
+		return ts.CloudStorage.Query(ctx, key, sql, format)
+	}
+
+	return queryObject(ctx, ts, key, sql, format)
+}
+
+// Flush flushes the wrapped storage
+func (ts *TransformingCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
+
+// GzipTransformer compresses the objects
+type GzipTransformer struct {
+	// Level is the compression level of compress/gzip, gzip.DefaultCompression when it's zero
+	Level int
+}
+
+func (t GzipTransformer) Encode(key string, body []byte) ([]byte, error) {
+	level := t.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	var buf bytes.Buffer
+
+	writer, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := writer.Write(body); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (t GzipTransformer) Decode(key string, body []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return ioutil.ReadAll(reader)
+}
+
+// ErrDecryptionFailed is returned when an object can't be decrypted, e.g. with the wrong key
+var ErrDecryptionFailed = errors.New("unable to decrypt the object")
+
+// aesGCMTransformer encrypts the objects with AES-GCM, the random nonce is stored before the ciphertext
+type aesGCMTransformer struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCMTransformer returns a Transformer encrypting the objects with AES-GCM.
+// The key is 16, 24 or 32 bytes long, for AES-128, AES-192 or AES-256. The object key is authenticated
+// along with the content, so an encrypted object copied to another key can't be decrypted.
+func NewAESGCMTransformer(key []byte) (Transformer, error) {
+	aead, err := newAESGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aesGCMTransformer{aead: aead}, nil
+}
+
+func (t *aesGCMTransformer) Encode(key string, body []byte) ([]byte, error) {
+	return sealWithNonce(t.aead, body, []byte(key))
+}
+
+func (t *aesGCMTransformer) Decode(key string, body []byte) ([]byte, error) {
+	return openWithNonce(t.aead, body, []byte(key))
+}
+
+// RedactJSONTransformer replaces the fields of the JSON objects before they are written, at any depth.
+// It can't be reversed, Decode returns the redacted object.
+type RedactJSONTransformer struct {
+	Fields []string
+	// Replacement is the value of the redacted fields, "[REDACTED]" when it's empty
+	Replacement string
+}
+
+func (t RedactJSONTransformer) Encode(key string, body []byte) ([]byte, error) {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil, err
+	}
+
+	replacement := t.Replacement
+	if replacement == "" {
+		replacement = "[REDACTED]"
+	}
+
+	fields := make(map[string]bool, len(t.Fields))
+	for _, field := range t.Fields {
+		fields[field] = true
+	}
+
+	return json.Marshal(redactJSON(value, fields, replacement))
+}
+
+func (t RedactJSONTransformer) Decode(key string, body []byte) ([]byte, error) {
+	return body, nil
+}
+
+func redactJSON(value interface{}, fields map[string]bool, replacement string) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for name, field := range value {
+			if fields[name] {
+				value[name] = replacement
+			} else {
+				value[name] = redactJSON(field, fields, replacement)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redactJSON(item, fields, replacement)
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformingCloudStorage(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+
+	encryption, err := NewAESGCMTransformer(bytes.Repeat([]byte("k"), 32))
+	require.NoError(t, err)
+
+	storage := NewTransformingCloudStorage(fake, []TransformRule{
+		{Prefix: "pii/", Transformers: []Transformer{GzipTransformer{}, encryption}},
+		{Prefix: "pii/public/", Transformers: []Transformer{GzipTransformer{}}},
+	})
+
+	body := bytes.Repeat([]byte("personal data "), 100)
+
+	require.NoError(t, storage.Write(ctx, "pii/a.txt", body, nil))
+	require.NoError(t, storage.Upload(ctx, "pii/public/b.txt", bytes.NewReader(body), nil))
+	require.NoError(t, storage.Write(ctx, "plain.txt", body, nil))
+
+	writer, err := storage.GetWriter(ctx, "pii/c.txt")
+	require.NoError(t, err)
+
+	_, err = writer.Write(body)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	for _, key := range []string{"pii/a.txt", "pii/public/b.txt", "pii/c.txt", "plain.txt"} {
+		result, err := storage.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, body, result, key)
+
+		reader, _, err := storage.GetWithAttributes(ctx, key)
+		require.NoError(t, err)
+
+		result, err = ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+		require.Equal(t, body, result, key)
+	}
+
+	// the objects are stored transformed
+	stored, err := fake.Get(ctx, "pii/a.txt")
+	require.NoError(t, err)
+	require.False(t, bytes.Contains(stored, []byte("personal")))
+
+	stored, err = fake.Get(ctx, "pii/public/b.txt")
+	require.NoError(t, err)
+
+	decoded, err := GzipTransformer{}.Decode("pii/public/b.txt", stored)
+	require.NoError(t, err)
+	require.Equal(t, body, decoded)
+
+	stored, err = fake.Get(ctx, "plain.txt")
+	require.NoError(t, err)
+	require.Equal(t, body, stored)
+
+	reader, err := storage.GetRangeReader(ctx, "pii/a.txt", 9, 4)
+	require.NoError(t, err)
+
+	result, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, "data", string(result))
+}
+
+func TestAESGCMTransformer(t *testing.T) {
+	_, err := NewAESGCMTransformer([]byte("short"))
+	require.Error(t, err)
+
+	transformer, err := NewAESGCMTransformer(bytes.Repeat([]byte("k"), 16))
+	require.NoError(t, err)
+
+	encrypted, err := transformer.Encode("a.txt", []byte("secret"))
+	require.NoError(t, err)
+
+	decrypted, err := transformer.Decode("a.txt", encrypted)
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(decrypted))
+
+	// the object key is authenticated
+	_, err = transformer.Decode("b.txt", encrypted)
+	require.True(t, errors.Is(err, ErrDecryptionFailed))
+
+	other, err := NewAESGCMTransformer(bytes.Repeat([]byte("o"), 16))
+	require.NoError(t, err)
+
+	_, err = other.Decode("a.txt", encrypted)
+	require.True(t, errors.Is(err, ErrDecryptionFailed))
+
+	_, err = transformer.Decode("a.txt", []byte("x"))
+	require.True(t, errors.Is(err, ErrDecryptionFailed))
+}
+
+func TestRedactJSONTransformer(t *testing.T) {
+	transformer := RedactJSONTransformer{Fields: []string{"email", "phone"}}
+
+	redacted, err := transformer.Encode("a.json", []byte(`{"id":1,"email":"a@b.c","contacts":[{"phone":"123","name":"x"}]}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":1,"email":"[REDACTED]","contacts":[{"phone":"[REDACTED]","name":"x"}]}`, string(redacted))
+
+	decoded, err := transformer.Decode("a.json", redacted)
+	require.NoError(t, err)
+	require.Equal(t, redacted, decoded)
+
+	_, err = transformer.Encode("a.json", []byte("not json"))
+	require.Error(t, err)
+}
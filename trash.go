@@ -0,0 +1,282 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultTrashPrefix is the prefix of the trash when TrashOption.Prefix isn't set
+const DefaultTrashPrefix = ".trash/"
+
+// TrashOption configures the soft deletes of a TrashCloudStorage
+type TrashOption struct {
+	// Prefix holds the deleted objects under objects/<key> and their tombstones under tombstones/<key>.
+	// Defaults to DefaultTrashPrefix.
+	Prefix string
+	// Clock returns the deletion time of the tombstones, time.Now when it's nil
+	Clock func() time.Time
+}
+
+// TrashEntry is the tombstone of a deleted object
+type TrashEntry struct {
+	Key         string    `json:"key"`
+	DeletedAt   time.Time `json:"deletedAt"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"contentType,omitempty"`
+	// Actor is the actor of the context of the Delete, see ContextWithActor
+	Actor string `json:"actor,omitempty"`
+}
+
+// TrashCloudStorage moves the deleted objects to a trash prefix with a tombstone, instead of deleting them,
+// protecting the buckets without versioning from accidental deletions. Restore puts them back, and PurgeTrash
+// deletes them for good once they're old enough. Only the last deleted version of a key is kept.
+// The trash is hidden from List, and the keys under the trash prefix are deleted for good.
+type TrashCloudStorage struct {
+	CloudStorage
+	opts TrashOption
+}
+
+// NewTrashCloudStorage returns the storage with the soft deletes. The storages of NewCloudStorageWithOption
+// with the Trash option can be wrapped again to call Restore and PurgeTrash, the trash being in the bucket.
+func NewTrashCloudStorage(storage CloudStorage, opts TrashOption) *TrashCloudStorage {
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultTrashPrefix
+	}
+
+	opts.Clock = clockOrNow(opts.Clock)
+
+	return &TrashCloudStorage{CloudStorage: storage, opts: opts}
+}
+
+func (ts *TrashCloudStorage) objectKey(key string) string {
+	return ts.opts.Prefix + "objects/" + key
+}
+
+func (ts *TrashCloudStorage) tombstoneKey(key string) string {
+	return ts.opts.Prefix + "tombstones/" + key
+}
+
+// List hides the trash
+func (ts *TrashCloudStorage) List(ctx context.Context, prefix string, opts ...ListOption) *ListIterator {
+	iter := ts.CloudStorage.List(ctx, prefix, opts...)
+
+	return NewListIterator(ctx, func(ctx context.Context) (*ListObject, error) {
+		for {
+			object, err := iter.Next(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			if !strings.HasPrefix(object.Key, ts.opts.Prefix) {
+				return object, nil
+			}
+		}
+	})
+}
+
+// Delete moves the object to the trash
+func (ts *TrashCloudStorage) Delete(ctx context.Context, key string) error {
+	return ts.trash(ctx, key, "")
+}
+
+// DeleteIf moves the object to the trash when it still has the generation
+func (ts *TrashCloudStorage) DeleteIf(ctx context.Context, key string, generation string) error {
+	return ts.trash(ctx, key, generation)
+}
+
+// trash copies the object and its tombstone to the trash then deletes it, the copy being removed when the
+// delete fails so a concurrent write isn't trashed
+func (ts *TrashCloudStorage) trash(ctx context.Context, key string, generation string) error {
+	if strings.HasPrefix(key, ts.opts.Prefix) {
+		if generation != "" {
+			return ts.CloudStorage.DeleteIf(ctx, key, generation)
+		}
+
+		return ts.CloudStorage.Delete(ctx, key)
+	}
+
+	attrs, err := copyObjectWithMetadata(ctx, ts.CloudStorage, key, ts.objectKey(key))
+	if err != nil {
+		return err
+	}
+
+	entry := &TrashEntry{
+		Key:         key,
+		DeletedAt:   ts.opts.Clock(),
+		Size:        attrs.Size,
+		ContentType: attrs.ContentType,
+		Actor:       ActorFromContext(ctx),
+	}
+
+	if err := ts.writeTombstone(ctx, entry); err != nil {
+		return err
+	}
+
+	if generation != "" {
+		err = ts.CloudStorage.DeleteIf(ctx, key, generation)
+	} else {
+		err = ts.CloudStorage.Delete(ctx, key)
+	}
+
+	if err != nil {
+		_ = ts.CloudStorage.Delete(ctx, ts.tombstoneKey(key))
+		_ = ts.CloudStorage.Delete(ctx, ts.objectKey(key))
+
+		return err
+	}
+
+	return nil
+}
+
+func (ts *TrashCloudStorage) writeTombstone(ctx context.Context, entry *TrashEntry) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	contentType := "application/json"
+
+	return ts.CloudStorage.Write(ctx, ts.tombstoneKey(entry.Key), body, &contentType)
+}
+
+func (ts *TrashCloudStorage) readTombstone(ctx context.Context, key string) (*TrashEntry, error) {
+	body, err := ts.CloudStorage.Get(ctx, ts.tombstoneKey(key))
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &TrashEntry{}
+	if err := json.Unmarshal(body, entry); err != nil {
+		return nil, fmt.Errorf("invalid tombstone of '%s': %w", key, err)
+	}
+
+	return entry, nil
+}
+
+// Restore puts the deleted object back to its key. It returns ErrNotFound when the key isn't in the trash, and
+// ErrAlreadyExists when the key has been written since its deletion.
+func (ts *TrashCloudStorage) Restore(ctx context.Context, key string) error {
+	if _, err := ts.readTombstone(ctx, key); err != nil {
+		return err
+	}
+
+	_, err := ts.CloudStorage.Attributes(ctx, key)
+
+	switch {
+	case err == nil:
+		return fmt.Errorf("%w: '%s' has been written since its deletion", ErrAlreadyExists, key)
+	case !errors.Is(err, ErrNotFound):
+		return err
+	}
+
+	if _, err := copyObjectWithMetadata(ctx, ts.CloudStorage, ts.objectKey(key), key); err != nil {
+		return err
+	}
+
+	return ts.purge(ctx, key)
+}
+
+// purge deletes the object and the tombstone of the key from the trash
+func (ts *TrashCloudStorage) purge(ctx context.Context, key string) error {
+	if err := ts.CloudStorage.Delete(ctx, ts.objectKey(key)); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	return ts.CloudStorage.Delete(ctx, ts.tombstoneKey(key))
+}
+
+// ListTrash returns the tombstones of the deleted objects under the prefix, sorted by key
+func (ts *TrashCloudStorage) ListTrash(ctx context.Context, prefix string) ([]*TrashEntry, error) {
+	tombstones, err := listByName(ctx, ts.CloudStorage, ts.tombstoneKey(prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*TrashEntry, 0, len(tombstones))
+
+	for tombstoneKey := range tombstones {
+		entry, err := ts.readTombstone(ctx, strings.TrimPrefix(tombstoneKey, ts.tombstoneKey("")))
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+
+	return entries, nil
+}
+
+// PurgeTrash deletes for good the objects deleted more than olderThan ago, and returns their tombstones
+func (ts *TrashCloudStorage) PurgeTrash(ctx context.Context, olderThan time.Duration) ([]*TrashEntry, error) {
+	entries, err := ts.ListTrash(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := ts.opts.Clock().Add(-olderThan)
+
+	var purged []*TrashEntry
+
+	for _, entry := range entries {
+		if !entry.DeletedAt.Before(cutoff) {
+			continue
+		}
+
+		if err := ts.purge(ctx, entry.Key); err != nil {
+			return purged, err
+		}
+
+		purged = append(purged, entry)
+	}
+
+	return purged, nil
+}
+
+// Flush flushes the wrapped storage
+func (ts *TrashCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
+
+// copyObjectWithMetadata copies the object with its content type and metadata, and returns its attributes
+func copyObjectWithMetadata(ctx context.Context, storage CloudStorage, srcKey string, dstKey string) (*Attributes, error) {
+	reader, attrs, err := storage.GetWithAttributes(ctx, srcKey)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	err = storage.Upload(ctx, dstKey, io.Reader(reader), &UploadOption{ContentType: attrs.ContentType},
+		WithMetadata(attrs.Metadata))
+
+	return attrs, err
+}
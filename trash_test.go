@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrashCloudStorage(t *testing.T) {
+	ctx := ContextWithActor(context.Background(), "alice")
+	fake := NewFakeCloudStorage("bucket")
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	storage := NewTrashCloudStorage(fake, TrashOption{Clock: func() time.Time { return now }})
+
+	require.NoError(t, storage.Upload(ctx, "a.txt", strings.NewReader("hello"), &UploadOption{ContentType: "text/plain"},
+		WithMetadata(map[string]string{"owner": "bob"})))
+	require.NoError(t, storage.Write(ctx, "b.txt", []byte("world!"), nil))
+
+	require.NoError(t, storage.Delete(ctx, "a.txt"))
+
+	_, err := storage.Get(ctx, "a.txt")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	// the trash is hidden from List
+	require.Equal(t, []string{"b.txt"}, listedKeys(t, storage.List(ctx, "")))
+
+	entries, err := storage.ListTrash(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, []*TrashEntry{{Key: "a.txt", DeletedAt: now, Size: 5, ContentType: "text/plain", Actor: "alice"}},
+		entries)
+
+	require.NoError(t, storage.Restore(ctx, "a.txt"))
+
+	body, attrs, err := storage.GetWithAttributes(ctx, "a.txt")
+	require.NoError(t, err)
+	defer body.Close()
+	require.Equal(t, "text/plain", attrs.ContentType)
+	require.Equal(t, "bob", attrs.Metadata["owner"])
+
+	entries, err = storage.ListTrash(ctx, "")
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	err = storage.Restore(ctx, "a.txt")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	// a restore doesn't overwrite the objects written since the deletion
+	require.NoError(t, storage.Delete(ctx, "b.txt"))
+	require.NoError(t, storage.Write(ctx, "b.txt", []byte("new"), nil))
+
+	err = storage.Restore(ctx, "b.txt")
+	require.True(t, errors.Is(err, ErrAlreadyExists))
+
+	// only what was deleted before the cutoff is purged
+	now = now.Add(48 * time.Hour)
+	require.NoError(t, storage.Delete(ctx, "a.txt"))
+
+	purged, err := storage.PurgeTrash(ctx, 24*time.Hour)
+	require.NoError(t, err)
+	require.Len(t, purged, 1)
+	require.Equal(t, "b.txt", purged[0].Key)
+
+	require.Equal(t, []string{".trash/objects/a.txt", ".trash/tombstones/a.txt", "b.txt"},
+		listedKeys(t, fake.List(ctx, "")))
+}
+
+func TestTrashCloudStorageDeleteIf(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	storage := NewTrashCloudStorage(fake, TrashOption{Prefix: "trash/"})
+
+	require.NoError(t, storage.Write(ctx, "a.txt", []byte("hello"), nil))
+
+	// the copy is removed when the precondition fails
+	err := storage.DeleteIf(ctx, "a.txt", "0")
+	require.True(t, errors.Is(err, ErrPreconditionFailed))
+	require.Equal(t, []string{"a.txt"}, listedKeys(t, fake.List(ctx, "")))
+
+	// the keys of the trash are deleted for good
+	require.NoError(t, fake.Write(ctx, "trash/objects/b.txt", []byte("hello"), nil))
+	require.NoError(t, storage.Delete(ctx, "trash/objects/b.txt"))
+	require.Equal(t, []string{"a.txt"}, listedKeys(t, fake.List(ctx, "")))
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// TTLIndexPrefix is the prefix of the expiry index written by WriteWithTTL and read by Janitor
+	TTLIndexPrefix = ".ttl/"
+
+	// DefaultJanitorInterval is the delay between two sweeps of Janitor when JanitorOption.Interval is not set
+	DefaultJanitorInterval = time.Hour
+)
+
+// ttlIndexKey returns the index entry of the key, starting with its zero-padded expiry,
+// so the listing of the index is sorted by expiry
+func ttlIndexKey(key string, expiry time.Time) string {
+	return fmt.Sprintf("%s%020d/%s", TTLIndexPrefix, expiry.Unix(), key)
+}
+
+// parseTTLIndexKey returns the key and the expiry of an index entry
+func parseTTLIndexKey(indexKey string) (string, time.Time, error) {
+	parts := strings.SplitN(strings.TrimPrefix(indexKey, TTLIndexPrefix), "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", time.Time{}, fmt.Errorf("invalid TTL index entry %q", indexKey)
+	}
+
+	seconds, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid TTL index entry %q: %w", indexKey, err)
+	}
+
+	return parts[1], time.Unix(seconds, 0), nil
+}
+
+// WriteWithTTL writes the object and records its expiry under TTLIndexPrefix, so a Janitor deletes it once
+// the ttl is elapsed. It's meant for the per-object retention the lifecycle rules of the bucket can't express.
+// The index entry is written first, so an object is never left without expiry.
+// A key rewritten with another ttl expires at the earliest of its expiries.
+func WriteWithTTL(
+	ctx context.Context,
+	storage CloudStorage,
+	key string,
+	body []byte,
+	contentType *string,
+	ttl time.Duration,
+) error {
+	if ttl <= 0 {
+		return errors.New("the ttl must be positive")
+	}
+
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	if err := storage.Write(ctx, ttlIndexKey(key, time.Now().Add(ttl)), nil, nil); err != nil {
+		return err
+	}
+
+	return storage.Write(ctx, key, body, contentType)
+}
+
+// JanitorOption configures a Janitor
+type JanitorOption struct {
+	// Interval is the delay between two sweeps. Defaults to DefaultJanitorInterval.
+	Interval time.Duration
+	// Clock returns the current time compared with the expiries, time.Now when it's nil
+	Clock func() time.Time
+	// OnSweep is called after every sweep, e.g. to export metrics. The failed sweeps are logged when it's nil.
+	OnSweep func(report *JanitorReport, err error)
+	// Logger receives the failed sweeps when OnSweep is nil. They are discarded when it's nil.
+	Logger Logger
+}
+
+// JanitorReport lists the keys deleted by a sweep
+type JanitorReport struct {
+	Deleted []string
+}
+
+// Janitor deletes the objects written by WriteWithTTL once they are expired
+type Janitor struct {
+	storage CloudStorage
+	opts    JanitorOption
+}
+
+// NewJanitor returns a Janitor of the storage, call Run to start it
+func NewJanitor(storage CloudStorage, opts JanitorOption) *Janitor {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultJanitorInterval
+	}
+
+	if opts.Clock == nil {
+		opts.Clock = time.Now
+	}
+
+	opts.Logger = loggerOrNoop(opts.Logger)
+
+	return &Janitor{storage: storage, opts: opts}
+}
+
+// Run sweeps the storage every Interval until ctx is done, then returns ctx.Err()
+func (j *Janitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(j.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := j.Sweep(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if j.opts.OnSweep != nil {
+			j.opts.OnSweep(report, err)
+		} else if err != nil {
+			j.opts.Logger.Error("unable to delete the expired objects", Fields{"error": err})
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep deletes the expired objects and their index entries. The index entry of an object is only deleted
+// after the object, so an object failing to be deleted is retried by the next sweep.
+func (j *Janitor) Sweep(ctx context.Context) (*JanitorReport, error) {
+	report := &JanitorReport{}
+	now := j.opts.Clock()
+	iter := j.storage.List(ctx, TTLIndexPrefix)
+
+	for {
+		entry, err := iter.Next(ctx)
+		if err == io.EOF {
+			return report, nil
+		}
+
+		if err != nil {
+			return report, err
+		}
+
+		key, expiry, err := parseTTLIndexKey(entry.Key)
+		if err != nil {
+			j.opts.Logger.Warn("skipping the TTL index entry", Fields{"key": entry.Key, "error": err})
+			continue
+		}
+
+		if expiry.After(now) {
+			continue
+		}
+
+		// the object may already be deleted, by a previous sweep which failed to delete the entry
+		if err := j.storage.Delete(ctx, key); err != nil && !errors.Is(err, ErrNotFound) {
+			return report, err
+		}
+
+		if err := j.storage.Delete(ctx, entry.Key); err != nil && !errors.Is(err, ErrNotFound) {
+			return report, err
+		}
+
+		report.Deleted = append(report.Deleted, key)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJanitorSweep(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, WriteWithTTL(ctx, storage, "gdpr/a.json", []byte("a"), nil, time.Hour))
+	require.NoError(t, WriteWithTTL(ctx, storage, "gdpr/b.json", []byte("b"), nil, 48*time.Hour))
+	require.NoError(t, storage.Write(ctx, "gdpr/c.json", []byte("c"), nil))
+	require.Error(t, WriteWithTTL(ctx, storage, "gdpr/d.json", []byte("d"), nil, 0))
+
+	now := time.Now()
+	janitor := NewJanitor(storage, JanitorOption{Clock: func() time.Time { return now }})
+
+	report, err := janitor.Sweep(ctx)
+	require.NoError(t, err)
+	require.Empty(t, report.Deleted)
+
+	now = now.Add(2 * time.Hour)
+
+	report, err = janitor.Sweep(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"gdpr/a.json"}, report.Deleted)
+
+	_, err = storage.Get(ctx, "gdpr/a.json")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	objects, err := listByName(ctx, storage, TTLIndexPrefix)
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+
+	now = now.Add(72 * time.Hour)
+
+	// an object already deleted doesn't fail the sweep
+	require.NoError(t, storage.Delete(ctx, "gdpr/b.json"))
+
+	report, err = janitor.Sweep(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"gdpr/b.json"}, report.Deleted)
+
+	// the objects without ttl are kept
+	_, err = storage.Get(ctx, "gdpr/c.json")
+	require.NoError(t, err)
+
+	objects, err = listByName(ctx, storage, TTLIndexPrefix)
+	require.NoError(t, err)
+	require.Empty(t, objects)
+}
+
+func TestParseTTLIndexKey(t *testing.T) {
+	expiry := time.Unix(1600000000, 0)
+
+	key, parsed, err := parseTTLIndexKey(ttlIndexKey("a/b.json", expiry))
+	require.NoError(t, err)
+	require.Equal(t, "a/b.json", key)
+	require.True(t, expiry.Equal(parsed))
+
+	_, _, err = parseTTLIndexKey(TTLIndexPrefix + "soon/a.json")
+	require.Error(t, err)
+
+	_, _, err = parseTTLIndexKey(TTLIndexPrefix + "1600000000")
+	require.Error(t, err)
+}
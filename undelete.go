@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"google.golang.org/api/iterator"
+)
+
+// S3 keeps the deleted object as a noncurrent version hidden by a delete marker, removing the marker brings the
+// version back as it was
+func awsUndelete(ctx context.Context, client *s3.S3, bucketName string, key string) error {
+	var (
+		marker *s3.DeleteMarkerEntry
+		live   bool
+	)
+
+	// the versions are listed with the key escaped like the keys of Get
+	escaped := awsEscapeKey(key)
+
+	err := client.ListObjectVersionsPagesWithContext(ctx, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(escaped),
+	}, func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+		for _, version := range page.Versions {
+			if aws.StringValue(version.Key) == escaped && aws.BoolValue(version.IsLatest) {
+				live = true
+			}
+		}
+
+		for _, deleteMarker := range page.DeleteMarkers {
+			if aws.StringValue(deleteMarker.Key) == escaped && aws.BoolValue(deleteMarker.IsLatest) {
+				marker = deleteMarker
+			}
+		}
+
+		return !live && marker == nil
+	})
+	if err != nil {
+		return translateError(err)
+	}
+
+	switch {
+	case live:
+		return fmt.Errorf("%w: '%s' isn't deleted", ErrAlreadyExists, key)
+	case marker == nil:
+		return fmt.Errorf("%w: no delete marker of '%s'", ErrNotFound, key)
+	}
+
+	_, err = client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket:    aws.String(bucketName),
+		Key:       aws.String(escaped),
+		VersionId: marker.VersionId,
+	})
+
+	return translateError(err)
+}
+
+// GCS has no delete markers, the last noncurrent generation is copied back as a new generation
+func gcpUndelete(ctx context.Context, client *storage.Client, bucketName string, key string) error {
+	bucket := client.Bucket(bucketName)
+
+	var last *storage.ObjectAttrs
+
+	iter := bucket.Objects(ctx, &storage.Query{Prefix: key, Versions: true})
+
+	for {
+		attrs, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+
+		if err != nil {
+			return translateError(err)
+		}
+
+		if attrs.Name != key {
+			continue
+		}
+
+		if attrs.Deleted.IsZero() {
+			return fmt.Errorf("%w: '%s' isn't deleted", ErrAlreadyExists, key)
+		}
+
+		if last == nil || attrs.Generation > last.Generation {
+			last = attrs
+		}
+	}
+
+	if last == nil {
+		return fmt.Errorf("%w: no noncurrent generation of '%s'", ErrNotFound, key)
+	}
+
+	// the precondition fails when the key is written concurrently
+	dst := bucket.Object(key).If(storage.Conditions{DoesNotExist: true})
+
+	_, err := dst.CopierFrom(bucket.Object(key).Generation(last.Generation)).Run(ctx)
+
+	return translateError(err)
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeCloudStorageUndelete(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.CreateBucketWithOptions(ctx, &CreateBucketOption{Versioning: true}))
+	require.NoError(t, storage.Write(ctx, "a.txt", []byte("first"), nil))
+	require.NoError(t, storage.Write(ctx, "a.txt", []byte("second"), nil))
+
+	attrs, err := storage.Attributes(ctx, "a.txt")
+	require.NoError(t, err)
+
+	err = storage.Undelete(ctx, "a.txt")
+	require.True(t, errors.Is(err, ErrAlreadyExists))
+
+	require.NoError(t, storage.Delete(ctx, "a.txt"))
+	require.NoError(t, storage.Undelete(ctx, "a.txt"))
+
+	// the last version is back with its generation
+	body, err := storage.Get(ctx, "a.txt")
+	require.NoError(t, err)
+	require.Equal(t, "second", string(body))
+
+	restored, err := storage.Attributes(ctx, "a.txt")
+	require.NoError(t, err)
+	require.Equal(t, attrs.Generation, restored.Generation)
+
+	err = storage.Undelete(ctx, "b.txt")
+	require.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestFakeCloudStorageUndeleteWithoutVersioning(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.Write(ctx, "a.txt", []byte("hello"), nil))
+	require.NoError(t, storage.Delete(ctx, "a.txt"))
+
+	err := storage.Undelete(ctx, "a.txt")
+	require.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestAWSUndelete(t *testing.T) {
+	var deleted string
+
+	client, server := newHTTPTestS3Client(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			// the listing is made with the escaped key, whose versions have the escaped key
+			require.Equal(t, "a/__0x2f__b", r.URL.Query().Get("prefix"))
+
+			w.Write([]byte(`<ListVersionsResult>
+<DeleteMarker><Key>a/__0x2f__b</Key><VersionId>marker</VersionId><IsLatest>true</IsLatest></DeleteMarker>
+<Version><Key>a/__0x2f__b</Key><VersionId>v1</VersionId><IsLatest>false</IsLatest></Version>
+</ListVersionsResult>`))
+
+			return
+		}
+
+		deleted = r.URL.EscapedPath() + "?versionId=" + r.URL.Query().Get("versionId")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	defer server.Close()
+
+	require.NoError(t, awsUndelete(context.Background(), client, "bucket", "a//b"))
+	require.Equal(t, "/bucket/a/__0x2f__b?versionId=marker", deleted)
+}
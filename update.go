@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+const (
+	defaultUpdateMaxAttempts = 10
+	defaultUpdateBaseBackoff = 10 * time.Millisecond
+	defaultUpdateMaxBackoff  = time.Second
+)
+
+// ErrSkipUpdate is returned by the function given to Update to leave the object unchanged, Update returns nil
+var ErrSkipUpdate = errors.New("skip update")
+
+// Update reads the object, calls fn with its content, nil when it doesn't exist, and writes the result back
+// with WriteIf, on the condition that the object wasn't written meanwhile. The whole read-modify-write is retried
+// with a backoff when it was, so fn may be called several times and must not have side effects.
+// The errors of fn are returned as is, and the content type of the object is kept.
+func Update(
+	ctx context.Context,
+	storage CloudStorage,
+	key string,
+	fn func(old []byte) ([]byte, error),
+) error {
+	var fnErr error
+
+	policy := RetryPolicy{
+		MaxAttempts: defaultUpdateMaxAttempts,
+		BaseBackoff: defaultUpdateBaseBackoff,
+		MaxBackoff:  defaultUpdateMaxBackoff,
+		IsRetryable: func(err error) bool {
+			return fnErr == nil && errors.Is(err, ErrPreconditionFailed)
+		},
+	}
+
+	err := policy.do(ctx, func() error {
+		old, condition, contentType, err := readForUpdate(ctx, storage, key)
+		if err != nil {
+			return err
+		}
+
+		body, err := fn(old)
+		if err != nil {
+			fnErr = err
+			return err
+		}
+
+		_, err = storage.WriteIf(ctx, key, body, contentType, condition)
+
+		return err
+	})
+
+	switch {
+	case errors.Is(fnErr, ErrSkipUpdate):
+		return nil
+	case fnErr == nil && errors.Is(err, ErrPreconditionFailed):
+		return fmt.Errorf("unable to update '%s' after %d attempts: %w", key, policy.MaxAttempts, err)
+	}
+
+	return err
+}
+
+// readForUpdate returns the content of the object with the condition of its update
+func readForUpdate(
+	ctx context.Context,
+	storage CloudStorage,
+	key string,
+) ([]byte, WriteCondition, *string, error) {
+	reader, attrs, err := storage.GetWithAttributes(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		return nil, WriteCondition{DoesNotExist: true}, nil, nil
+	}
+
+	if err != nil {
+		return nil, WriteCondition{}, nil, err
+	}
+	defer reader.Close()
+
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, WriteCondition{}, nil, err
+	}
+
+	return body, WriteCondition{Generation: attrs.Generation}, &attrs.ContentType, nil
+}
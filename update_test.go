@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	increment := func(old []byte) ([]byte, error) {
+		count := 0
+
+		if old != nil {
+			var err error
+			if count, err = strconv.Atoi(string(old)); err != nil {
+				return nil, err
+			}
+		}
+
+		return []byte(strconv.Itoa(count + 1)), nil
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			require.NoError(t, Update(ctx, storage, "counter", increment))
+		}()
+	}
+
+	wg.Wait()
+
+	body, err := storage.Get(ctx, "counter")
+	require.NoError(t, err)
+	require.Equal(t, "10", string(body))
+
+	contentType := "application/json"
+	require.NoError(t, storage.Write(ctx, "config.json", []byte(`{}`), &contentType))
+
+	err = Update(ctx, storage, "config.json", func(old []byte) ([]byte, error) {
+		return []byte(`{"enabled":true}`), nil
+	})
+	require.NoError(t, err)
+
+	attrs, err := storage.Attributes(ctx, "config.json")
+	require.NoError(t, err)
+	require.Equal(t, "application/json", attrs.ContentType)
+
+	// the errors of the function stop the update
+	failure := errors.New("failure")
+
+	err = Update(ctx, storage, "config.json", func(old []byte) ([]byte, error) {
+		return nil, failure
+	})
+	require.Equal(t, failure, err)
+
+	err = Update(ctx, storage, "config.json", func(old []byte) ([]byte, error) {
+		return nil, ErrSkipUpdate
+	})
+	require.NoError(t, err)
+
+	body, err = storage.Get(ctx, "config.json")
+	require.NoError(t, err)
+	require.Equal(t, `{"enabled":true}`, string(body))
+}
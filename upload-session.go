@@ -0,0 +1,672 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
+)
+
+// UploadSessionState is the serializable state of an UploadSession, to resume it with ResumeUpload,
+// e.g. from another process
+type UploadSessionState struct {
+	Key         string `json:"key"`
+	UploadID    string `json:"upload_id"`
+	PartSize    int64  `json:"part_size"`
+	ContentType string `json:"content_type,omitempty"`
+	// Parts are the uploaded parts, sorted by number
+	Parts []UploadedPart `json:"parts"`
+}
+
+// UploadedPart is a part of an UploadSession
+type UploadedPart struct {
+	// Number starts from 1
+	Number int    `json:"number"`
+	ETag   string `json:"etag,omitempty"`
+	Size   int64  `json:"size"`
+}
+
+// uploadBackend stores the parts of the upload sessions of a provider
+type uploadBackend interface {
+	uploadPart(ctx context.Context, state *UploadSessionState, number int, body []byte) (string, error)
+	// parts returns the parts already uploaded, sorted by number
+	parts(ctx context.Context, state *UploadSessionState) ([]UploadedPart, error)
+	complete(ctx context.Context, state *UploadSessionState) error
+	abort(ctx context.Context, state *UploadSessionState) error
+}
+
+// UploadSession is a multipart upload whose state can be saved, so a multi-GB upload interrupted by a crash
+// is resumed from its last uploaded part by any process with ResumeUpload, instead of starting over.
+// The parts are uploaded sequentially and must all have the part size, except the last one.
+//
+// The sessions write directly to the provider, so the objects aren't transformed, deduplicated or chunked,
+// and don't count towards the quotas, the search index or the webhooks.
+type UploadSession struct {
+	backend uploadBackend
+
+	mu    sync.Mutex
+	state UploadSessionState
+}
+
+func newUploadSession(backend uploadBackend, state UploadSessionState) *UploadSession {
+	return &UploadSession{backend: backend, state: state}
+}
+
+// guardedUploadBackend runs the checks of a wrapper before a part is uploaded, and around the completion of the
+// session, which is done by calling complete
+type guardedUploadBackend struct {
+	uploadBackend
+	beforePart     func(ctx context.Context, state *UploadSessionState, number int, body []byte) error
+	aroundComplete func(ctx context.Context, state *UploadSessionState, complete func() error) error
+}
+
+// guardUploadSession returns the session running the checks, which may be nil
+func guardUploadSession(
+	session *UploadSession,
+	beforePart func(ctx context.Context, state *UploadSessionState, number int, body []byte) error,
+	aroundComplete func(ctx context.Context, state *UploadSessionState, complete func() error) error,
+) *UploadSession {
+	backend := &guardedUploadBackend{
+		uploadBackend:  session.backend,
+		beforePart:     beforePart,
+		aroundComplete: aroundComplete,
+	}
+
+	return newUploadSession(backend, session.State())
+}
+
+// uploadedSize returns the size of the parts of the session, the part of the number being replaced by the body
+func uploadedSize(state *UploadSessionState, number int, body []byte) int64 {
+	size := int64(len(body))
+
+	for _, part := range state.Parts {
+		if part.Number != number {
+			size += part.Size
+		}
+	}
+
+	return size
+}
+
+func (b *guardedUploadBackend) uploadPart(
+	ctx context.Context,
+	state *UploadSessionState,
+	number int,
+	body []byte,
+) (string, error) {
+	if b.beforePart != nil {
+		if err := b.beforePart(ctx, state, number, body); err != nil {
+			return "", err
+		}
+	}
+
+	return b.uploadBackend.uploadPart(ctx, state, number, body)
+}
+
+func (b *guardedUploadBackend) complete(ctx context.Context, state *UploadSessionState) error {
+	if b.aroundComplete == nil {
+		return b.uploadBackend.complete(ctx, state)
+	}
+
+	return b.aroundComplete(ctx, state, func() error {
+		return b.uploadBackend.complete(ctx, state)
+	})
+}
+
+// resumeUploadSession returns the session with the parts uploaded since its state was saved
+func resumeUploadSession(
+	ctx context.Context,
+	backend uploadBackend,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	if state == nil || state.Key == "" || state.UploadID == "" || state.PartSize <= 0 {
+		return nil, errors.New("invalid upload session state")
+	}
+
+	resumed := *state
+
+	parts, err := backend.parts(ctx, &resumed)
+	if err != nil {
+		return nil, err
+	}
+
+	resumed.Parts = parts
+
+	return newUploadSession(backend, resumed), nil
+}
+
+// State returns a copy of the state of the session, to be saved
+func (s *UploadSession) State() UploadSessionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.state
+	state.Parts = append([]UploadedPart(nil), s.state.Parts...)
+
+	return state
+}
+
+// Offset returns the number of bytes of the contiguous parts uploaded from the beginning of the object,
+// where the source must be read from to resume the upload
+func (s *UploadSession) Offset() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var offset int64
+
+	for i, part := range s.state.Parts {
+		if part.Number != i+1 {
+			break
+		}
+
+		offset += part.Size
+	}
+
+	return offset
+}
+
+// nextPart returns the number of the first part missing from the beginning of the object
+func (s *UploadSession) nextPart() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, part := range s.state.Parts {
+		if part.Number != i+1 {
+			return i + 1
+		}
+	}
+
+	return len(s.state.Parts) + 1
+}
+
+// UploadPart uploads the part, replacing the part with the same number, if any
+func (s *UploadSession) UploadPart(ctx context.Context, number int, body []byte) error {
+	if number < 1 {
+		return fmt.Errorf("invalid part number %d", number)
+	}
+
+	state := s.State()
+
+	etag, err := s.backend.uploadPart(ctx, &state, number, body)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parts := s.state.Parts[:0:0]
+
+	for _, part := range s.state.Parts {
+		if part.Number != number {
+			parts = append(parts, part)
+		}
+	}
+
+	parts = append(parts, UploadedPart{Number: number, ETag: etag, Size: int64(len(body))})
+
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].Number < parts[j].Number
+	})
+
+	s.state.Parts = parts
+
+	return nil
+}
+
+// Upload uploads the reader in parts from the next missing part, so the reader must start at Offset.
+// checkpoint is called with the state after every part, e.g. to save it. It may be nil.
+func (s *UploadSession) Upload(
+	ctx context.Context,
+	reader io.Reader,
+	checkpoint func(state UploadSessionState) error,
+) error {
+	buf := make([]byte, s.state.PartSize)
+
+	for number := s.nextPart(); ; number++ {
+		n, err := io.ReadFull(reader, buf)
+		if n > 0 {
+			if err := s.UploadPart(ctx, number, buf[:n]); err != nil {
+				return err
+			}
+
+			if checkpoint != nil {
+				if err := checkpoint(s.State()); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Complete assembles the parts into the object
+func (s *UploadSession) Complete(ctx context.Context) error {
+	state := s.State()
+
+	for i, part := range state.Parts {
+		if part.Number != i+1 {
+			return fmt.Errorf("part %d of '%s' is missing", i+1, state.Key)
+		}
+	}
+
+	return s.backend.complete(ctx, &state)
+}
+
+// Abort deletes the uploaded parts
+func (s *UploadSession) Abort(ctx context.Context) error {
+	state := s.State()
+
+	return s.backend.abort(ctx, &state)
+}
+
+// awsUploadBackend uses the multipart uploads of S3
+type awsUploadBackend struct {
+	client     *s3.S3
+	bucketName string
+}
+
+func awsBeginUpload(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	options := opts.withDefaults()
+
+	input := &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(awsEscapeKey(key)),
+	}
+
+	if options.ContentType != "" {
+		input.ContentType = aws.String(options.ContentType)
+	}
+
+	out, err := client.CreateMultipartUploadWithContext(ctx, input)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return newUploadSession(&awsUploadBackend{client: client, bucketName: bucketName}, UploadSessionState{
+		Key:         key,
+		UploadID:    aws.StringValue(out.UploadId),
+		PartSize:    options.PartSize,
+		ContentType: options.ContentType,
+	}), nil
+}
+
+func awsResumeUpload(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	return resumeUploadSession(ctx, &awsUploadBackend{client: client, bucketName: bucketName}, state)
+}
+
+func (b *awsUploadBackend) uploadPart(
+	ctx context.Context,
+	state *UploadSessionState,
+	number int,
+	body []byte,
+) (string, error) {
+	out, err := b.client.UploadPartWithContext(ctx, &s3.UploadPartInput{
+		Bucket:     aws.String(b.bucketName),
+		Key:        aws.String(awsEscapeKey(state.Key)),
+		UploadId:   aws.String(state.UploadID),
+		PartNumber: aws.Int64(int64(number)),
+		Body:       bytes.NewReader(body),
+	})
+	if err != nil {
+		return "", translateError(err)
+	}
+
+	return aws.StringValue(out.ETag), nil
+}
+
+func (b *awsUploadBackend) parts(ctx context.Context, state *UploadSessionState) ([]UploadedPart, error) {
+	var parts []UploadedPart
+
+	err := b.client.ListPartsPagesWithContext(ctx, &s3.ListPartsInput{
+		Bucket:   aws.String(b.bucketName),
+		Key:      aws.String(awsEscapeKey(state.Key)),
+		UploadId: aws.String(state.UploadID),
+	}, func(page *s3.ListPartsOutput, lastPage bool) bool {
+		for _, part := range page.Parts {
+			parts = append(parts, UploadedPart{
+				Number: int(aws.Int64Value(part.PartNumber)),
+				ETag:   aws.StringValue(part.ETag),
+				Size:   aws.Int64Value(part.Size),
+			})
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return parts, nil
+}
+
+func (b *awsUploadBackend) complete(ctx context.Context, state *UploadSessionState) error {
+	parts := make([]*s3.CompletedPart, 0, len(state.Parts))
+	for _, part := range state.Parts {
+		parts = append(parts, &s3.CompletedPart{
+			ETag:       aws.String(part.ETag),
+			PartNumber: aws.Int64(int64(part.Number)),
+		})
+	}
+
+	_, err := b.client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(b.bucketName),
+		Key:             aws.String(awsEscapeKey(state.Key)),
+		UploadId:        aws.String(state.UploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	})
+
+	return translateError(err)
+}
+
+func (b *awsUploadBackend) abort(ctx context.Context, state *UploadSessionState) error {
+	_, err := b.client.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(b.bucketName),
+		Key:      aws.String(awsEscapeKey(state.Key)),
+		UploadId: aws.String(state.UploadID),
+	})
+
+	return translateError(err)
+}
+
+// uploadPartPrefix is the prefix of the temporary part objects of the sessions without native multipart uploads,
+// the same as the parts of gcpUpload
+func uploadPartPrefix(state *UploadSessionState) string {
+	return fmt.Sprintf("%s.parts-%s/", state.Key, state.UploadID)
+}
+
+func uploadPartKey(state *UploadSessionState, number int) string {
+	return fmt.Sprintf("%s%05d", uploadPartPrefix(state), number)
+}
+
+// parseUploadPartKey returns the number of the part object, or zero for the intermediate objects
+func parseUploadPartKey(state *UploadSessionState, key string) int {
+	number, err := strconv.Atoi(strings.TrimPrefix(key, uploadPartPrefix(state)))
+	if err != nil {
+		return 0
+	}
+
+	return number
+}
+
+// gcpUploadBackend uploads the parts as temporary objects, composed into the object on completion
+type gcpUploadBackend struct {
+	client     *storage.Client
+	bucketName string
+}
+
+func gcpBeginUpload(client *storage.Client, bucketName string, key string, opts *UploadOption) *UploadSession {
+	return newUploadSession(&gcpUploadBackend{client: client, bucketName: bucketName}, newObjectUploadState(key, opts))
+}
+
+func newObjectUploadState(key string, opts *UploadOption) UploadSessionState {
+	options := opts.withDefaults()
+
+	return UploadSessionState{
+		Key:         key,
+		UploadID:    uuid.New().String(),
+		PartSize:    options.PartSize,
+		ContentType: options.ContentType,
+	}
+}
+
+func gcpResumeUpload(
+	ctx context.Context,
+	client *storage.Client,
+	bucketName string,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	return resumeUploadSession(ctx, &gcpUploadBackend{client: client, bucketName: bucketName}, state)
+}
+
+func (b *gcpUploadBackend) uploadPart(
+	ctx context.Context,
+	state *UploadSessionState,
+	number int,
+	body []byte,
+) (string, error) {
+	writer := b.client.Bucket(b.bucketName).Object(uploadPartKey(state, number)).NewWriter(ctx)
+	// the part is already in memory, upload it with a single request
+	writer.ChunkSize = 0
+
+	if _, err := writer.Write(body); err != nil {
+		_ = writer.Close()
+		return "", translateError(err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", translateError(err)
+	}
+
+	return writer.Attrs().Etag, nil
+}
+
+func (b *gcpUploadBackend) objects(ctx context.Context, state *UploadSessionState) ([]*storage.ObjectAttrs, error) {
+	var objects []*storage.ObjectAttrs
+
+	iter := b.client.Bucket(b.bucketName).Objects(ctx, &storage.Query{Prefix: uploadPartPrefix(state)})
+
+	for {
+		attrs, err := iter.Next()
+		if err == iterator.Done {
+			return objects, nil
+		}
+
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		objects = append(objects, attrs)
+	}
+}
+
+func (b *gcpUploadBackend) parts(ctx context.Context, state *UploadSessionState) ([]UploadedPart, error) {
+	objects, err := b.objects(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []UploadedPart
+
+	for _, attrs := range objects {
+		if number := parseUploadPartKey(state, attrs.Name); number > 0 {
+			parts = append(parts, UploadedPart{Number: number, ETag: attrs.Etag, Size: attrs.Size})
+		}
+	}
+
+	return parts, nil
+}
+
+func (b *gcpUploadBackend) complete(ctx context.Context, state *UploadSessionState) error {
+	bucket := b.client.Bucket(b.bucketName)
+
+	sources := make([]string, 0, len(state.Parts))
+	for _, part := range state.Parts {
+		sources = append(sources, uploadPartKey(state, part.Number))
+	}
+
+	if len(sources) == 0 {
+		writer := bucket.Object(state.Key).NewWriter(ctx)
+		writer.ContentType = state.ContentType
+
+		if err := writer.Close(); err != nil {
+			return translateError(err)
+		}
+	} else if _, _, err := gcpComposeParts(ctx, bucket, state.Key, uploadPartPrefix(state), sources,
+		storage.ObjectAttrs{ContentType: state.ContentType}); err != nil {
+		return translateError(err)
+	}
+
+	return b.abort(ctx, state)
+}
+
+// abort deletes the parts and the intermediate objects of the composition
+func (b *gcpUploadBackend) abort(ctx context.Context, state *UploadSessionState) error {
+	objects, err := b.objects(ctx, state)
+	if err != nil {
+		return err
+	}
+
+	for _, attrs := range objects {
+		err := b.client.Bucket(b.bucketName).Object(attrs.Name).Delete(ctx)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			return translateError(err)
+		}
+	}
+
+	return nil
+}
+
+// objectUploadBackend uploads the parts as temporary objects of a CloudStorage, concatenated into the object
+// with Upload on completion, for the storages without multipart uploads
+type objectUploadBackend struct {
+	storage CloudStorage
+}
+
+func (b *objectUploadBackend) uploadPart(
+	ctx context.Context,
+	state *UploadSessionState,
+	number int,
+	body []byte,
+) (string, error) {
+	if err := b.storage.Write(ctx, uploadPartKey(state, number), body, nil); err != nil {
+		return "", err
+	}
+
+	return "", nil
+}
+
+func (b *objectUploadBackend) parts(ctx context.Context, state *UploadSessionState) ([]UploadedPart, error) {
+	objects, err := listByName(ctx, b.storage, uploadPartPrefix(state))
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []UploadedPart
+
+	for name, object := range objects {
+		if number, err := strconv.Atoi(name); err == nil && number > 0 {
+			parts = append(parts, UploadedPart{Number: number, Size: object.Size})
+		}
+	}
+
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].Number < parts[j].Number
+	})
+
+	return parts, nil
+}
+
+func (b *objectUploadBackend) complete(ctx context.Context, state *UploadSessionState) error {
+	err := b.storage.Upload(ctx, state.Key, &partsReader{ctx: ctx, backend: b, state: state}, &UploadOption{
+		PartSize:    state.PartSize,
+		ContentType: state.ContentType,
+	})
+	if err != nil {
+		return err
+	}
+
+	return b.abort(ctx, state)
+}
+
+// partsReader reads the part objects one after the other
+type partsReader struct {
+	ctx     context.Context
+	backend *objectUploadBackend
+	state   *UploadSessionState
+	next    int
+	current io.ReadCloser
+}
+
+func (r *partsReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if r.next >= len(r.state.Parts) {
+				return 0, io.EOF
+			}
+
+			reader, err := r.backend.storage.GetReader(r.ctx, uploadPartKey(r.state, r.state.Parts[r.next].Number))
+			if err != nil {
+				return 0, err
+			}
+
+			r.current = reader
+			r.next++
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+
+			if n > 0 {
+				return n, nil
+			}
+
+			continue
+		}
+
+		return n, err
+	}
+}
+
+func (b *objectUploadBackend) abort(ctx context.Context, state *UploadSessionState) error {
+	objects, err := listByName(ctx, b.storage, uploadPartPrefix(state))
+	if err != nil {
+		return err
+	}
+
+	for name := range objects {
+		err := b.storage.Delete(ctx, uploadPartPrefix(state)+name)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadSession(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+	source := []byte("abcdefghij")
+
+	session, err := storage.BeginUpload(ctx, "archives/large.bin", &UploadOption{PartSize: 4, ContentType: "text/plain"})
+	require.NoError(t, err)
+
+	var saved []byte
+
+	// the worker crashes after the first part
+	require.NoError(t, session.UploadPart(ctx, 1, source[:4]))
+
+	saved, err = json.Marshal(session.State())
+	require.NoError(t, err)
+
+	// the second part is uploaded after the state was saved
+	require.NoError(t, session.UploadPart(ctx, 2, source[4:8]))
+
+	var state UploadSessionState
+	require.NoError(t, json.Unmarshal(saved, &state))
+
+	resumed, err := storage.ResumeUpload(ctx, &state)
+	require.NoError(t, err)
+	require.Equal(t, int64(8), resumed.Offset())
+
+	checkpoints := 0
+
+	err = resumed.Upload(ctx, bytes.NewReader(source[resumed.Offset():]), func(state UploadSessionState) error {
+		checkpoints++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, checkpoints)
+	require.Len(t, resumed.State().Parts, 3)
+
+	require.NoError(t, resumed.Complete(ctx))
+
+	body, err := storage.Get(ctx, "archives/large.bin")
+	require.NoError(t, err)
+	require.Equal(t, source, body)
+
+	attrs, err := storage.Attributes(ctx, "archives/large.bin")
+	require.NoError(t, err)
+	require.Equal(t, "text/plain", attrs.ContentType)
+
+	// the parts are deleted
+	objects, err := listByName(ctx, storage, "")
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+}
+
+func TestUploadSessionAbort(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	session, err := storage.BeginUpload(ctx, "large.bin", &UploadOption{PartSize: 4})
+	require.NoError(t, err)
+
+	require.NoError(t, session.UploadPart(ctx, 2, []byte("efgh")))
+	require.Error(t, session.Complete(ctx))
+	require.NoError(t, session.Abort(ctx))
+
+	objects, err := listByName(ctx, storage, "")
+	require.NoError(t, err)
+	require.Empty(t, objects)
+}
+
+func TestAWSUploadSession(t *testing.T) {
+	var requests []string
+
+	client, server := newHTTPTestS3Client(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath())
+
+		query := r.URL.Query()
+
+		switch {
+		case r.Method == http.MethodPost && query.Get("uploadId") == "":
+			w.Write([]byte(`<InitiateMultipartUploadResult><UploadId>id</UploadId></InitiateMultipartUploadResult>`))
+		case r.Method == http.MethodPut:
+			w.Header().Set("ETag", `"part"`)
+		case r.Method == http.MethodPost:
+			w.Write([]byte(`<CompleteMultipartUploadResult><ETag>"etag"</ETag></CompleteMultipartUploadResult>`))
+		}
+	})
+	defer server.Close()
+
+	ctx := context.Background()
+
+	// the keys are escaped like the keys of Get
+	session, err := awsBeginUpload(ctx, client, "bucket", "a//b", &UploadOption{PartSize: 4})
+	require.NoError(t, err)
+	require.Equal(t, "a//b", session.State().Key)
+
+	require.NoError(t, session.UploadPart(ctx, 1, []byte("body")))
+	require.NoError(t, session.Complete(ctx))
+
+	require.Equal(t, []string{
+		"POST /bucket/a/__0x2f__b",
+		"PUT /bucket/a/__0x2f__b",
+		"POST /bucket/a/__0x2f__b",
+	}, requests)
+}
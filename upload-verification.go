@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"crypto/md5" // nolint:gosec
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"strconv"
+	"strings"
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// IntegrityError is returned by Upload with UploadOption.Verify when the uploaded object doesn't match the
+// checksum of the read content, and reported by VerifyPrefix for the stored objects not matching their checksums.
+// It matches ErrChecksumMismatch with errors.Is.
+type IntegrityError struct {
+	Key string
+	// Checksum is the compared checksum, ETag on AWS and CRC32C on GCP for the uploads
+	Checksum string
+	Expected string
+	Actual   string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("object '%s' has the %s %s instead of %s", e.Key, e.Checksum, e.Actual, e.Expected)
+}
+
+func (e *IntegrityError) Is(target error) bool {
+	return target == ErrChecksumMismatch
+}
+
+// etagVerifier computes the ETag of an S3 upload from its content: the MD5 of the object for a single part upload,
+// or the MD5 of the concatenated MD5 of the parts followed by the number of parts for a multipart upload.
+type etagVerifier struct {
+	partSize int64
+	object   hash.Hash
+	part     hash.Hash
+	partLen  int64
+	parts    []byte
+	count    int
+}
+
+func newETagVerifier(partSize int64) *etagVerifier {
+	return &etagVerifier{
+		partSize: partSize,
+		object:   md5.New(), // nolint:gosec
+		part:     md5.New(), // nolint:gosec
+	}
+}
+
+func (v *etagVerifier) Write(p []byte) (int, error) {
+	_, _ = v.object.Write(p)
+
+	for rest := p; len(rest) > 0; {
+		n := v.partSize - v.partLen
+		if n > int64(len(rest)) {
+			n = int64(len(rest))
+		}
+
+		_, _ = v.part.Write(rest[:n])
+		v.partLen += n
+		rest = rest[n:]
+
+		if v.partLen == v.partSize {
+			v.endPart()
+		}
+	}
+
+	return len(p), nil
+}
+
+func (v *etagVerifier) endPart() {
+	v.parts = v.part.Sum(v.parts)
+	v.count++
+	v.part.Reset()
+	v.partLen = 0
+}
+
+// verify compares the ETag of the uploaded object, the multipart ETag is recognized by its "-<parts>" suffix
+func (v *etagVerifier) verify(key string, etag string) error {
+	etag = strings.Trim(etag, `"`)
+	expected := hex.EncodeToString(v.object.Sum(nil))
+
+	if strings.Contains(etag, "-") {
+		if v.partLen > 0 {
+			v.endPart()
+		}
+
+		sum := md5.Sum(v.parts) // nolint:gosec
+		expected = hex.EncodeToString(sum[:]) + "-" + strconv.Itoa(v.count)
+	}
+
+	if etag != expected {
+		return &IntegrityError{Key: key, Checksum: "ETag", Expected: expected, Actual: etag}
+	}
+
+	return nil
+}
+
+// verifyCRC32C compares the CRC32C of the uploaded GCS object, the composed objects have the CRC32C of their whole
+// content
+func verifyCRC32C(key string, expected uint32, actual uint32) error {
+	if expected != actual {
+		return &IntegrityError{
+			Key:      key,
+			Checksum: "CRC32C",
+			Expected: strconv.FormatUint(uint64(expected), 16),
+			Actual:   strconv.FormatUint(uint64(actual), 16),
+		}
+	}
+
+	return nil
+}
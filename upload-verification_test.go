@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"crypto/md5" // nolint:gosec
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestETagVerifier(t *testing.T) {
+	body := []byte("0123456789")
+
+	verifier := newETagVerifier(4)
+	_, err := verifier.Write(body[:3])
+	require.NoError(t, err)
+	_, err = verifier.Write(body[3:])
+	require.NoError(t, err)
+
+	sum := md5.Sum(body) // nolint:gosec
+	require.NoError(t, verifier.verify("key", `"`+hex.EncodeToString(sum[:])+`"`))
+
+	var parts []byte
+	for _, part := range []string{"0123", "4567", "89"} {
+		sum := md5.Sum([]byte(part)) // nolint:gosec
+		parts = append(parts, sum[:]...)
+	}
+
+	sum = md5.Sum(parts) // nolint:gosec
+	require.NoError(t, verifier.verify("key", `"`+hex.EncodeToString(sum[:])+`-3"`))
+
+	err = verifier.verify("key", `"`+hex.EncodeToString(sum[:])+`-2"`)
+	require.True(t, errors.Is(err, ErrChecksumMismatch))
+
+	var integrityErr *IntegrityError
+	require.True(t, errors.As(err, &integrityErr))
+	require.Equal(t, "ETag", integrityErr.Checksum)
+	require.Equal(t, hex.EncodeToString(sum[:])+"-3", integrityErr.Expected)
+}
+
+func TestVerifyCRC32C(t *testing.T) {
+	checksum := crc32.Checksum([]byte("data"), crc32cTable)
+
+	require.NoError(t, verifyCRC32C("key", checksum, checksum))
+	require.True(t, errors.Is(verifyCRC32C("key", checksum, checksum+1), ErrChecksumMismatch))
+}
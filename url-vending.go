@@ -0,0 +1,311 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultURLTokenTTL is the lifetime of the tokens when URLVendorOption.TokenTTL is not set
+	DefaultURLTokenTTL = 15 * time.Minute
+
+	// DefaultVendedURLExpiry is the expiry of the signed URLs the tokens are redirected to,
+	// when URLVendorOption.SignedURLExpiry is not set
+	DefaultVendedURLExpiry = time.Minute
+
+	// urlTokenBytes is the number of random bytes of a token
+	urlTokenBytes = 32
+)
+
+// URLToken is an issued token, redirecting to a signed URL of its key until it expires or is revoked
+type URLToken struct {
+	// ID identifies the token in the logs and the store, it's the hex SHA-256 of the token, which isn't stored
+	ID                 string    `json:"id"`
+	Key                string    `json:"key"`
+	Method             string    `json:"method"`
+	ContentType        string    `json:"content_type,omitempty"`
+	ContentDisposition string    `json:"content_disposition,omitempty"`
+	Actor              string    `json:"actor,omitempty"`
+	IssuedAt           time.Time `json:"issued_at"`
+	ExpiresAt          time.Time `json:"expires_at"`
+}
+
+// URLTokenStore stores the issued tokens by ID. Load returns an error matching ErrNotFound for the unknown IDs.
+// Implementations must be safe for concurrent use.
+type URLTokenStore interface {
+	Save(ctx context.Context, token *URLToken) error
+	Load(ctx context.Context, id string) (*URLToken, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// URLVendorOption configures a URLVendor
+type URLVendorOption struct {
+	// BaseURL is the URL the vendor is served at, the tokens being appended to it, e.g. "https://api.example.com/files/"
+	BaseURL string
+	// Store stores the issued tokens. Defaults to a MemoryURLTokenStore, whose tokens are only known by the replica
+	// issuing them.
+	Store URLTokenStore
+	// TokenTTL is the lifetime of the tokens. Defaults to DefaultURLTokenTTL.
+	TokenTTL time.Duration
+	// SignedURLExpiry is the expiry of the signed URLs the tokens are redirected to, the delay for a revocation
+	// to apply to the URLs already redirected. Defaults to DefaultVendedURLExpiry.
+	SignedURLExpiry time.Duration
+	// Clock returns the current time used for the expiry of the tokens, time.Now when it's nil
+	Clock func() time.Time
+	// Logger receives the issuance and the revocation of the tokens
+	Logger Logger
+}
+
+// URLVendor issues URLs carrying a token instead of the signed URLs, plain signed URLs can't be revoked once leaked.
+// The vendor serves the URLs as a http.Handler: every request of a valid token is redirected to a new signed URL
+// of SignedURLExpiry, so the tokens can be revoked at any time.
+type URLVendor struct {
+	storage CloudStorage
+	opts    URLVendorOption
+}
+
+// NewURLVendor returns the vendor of the signed URLs of the storage
+func NewURLVendor(storage CloudStorage, opts URLVendorOption) *URLVendor {
+	if opts.Store == nil {
+		opts.Store = NewMemoryURLTokenStore()
+	}
+
+	if opts.TokenTTL <= 0 {
+		opts.TokenTTL = DefaultURLTokenTTL
+	}
+
+	if opts.SignedURLExpiry <= 0 {
+		opts.SignedURLExpiry = DefaultVendedURLExpiry
+	}
+
+	opts.Clock = clockOrNow(opts.Clock)
+	opts.Logger = loggerOrNoop(opts.Logger)
+
+	return &URLVendor{storage: storage, opts: opts}
+}
+
+// urlTokenID returns the ID of a token
+func urlTokenID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue returns the URL of a new token for the key and its ID, to revoke it. The Expiry of the options is ignored,
+// the token expires after TokenTTL. The actor of the context is recorded, see ContextWithActor.
+func (v *URLVendor) Issue(ctx context.Context, key string, opts *SignedURLOption) (url string, id string, err error) {
+	if opts == nil {
+		opts = &SignedURLOption{}
+	}
+
+	method, err := signedURLMethod(opts)
+	if err != nil {
+		return "", "", err
+	}
+
+	random := make([]byte, urlTokenBytes)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", err
+	}
+
+	secret := base64.RawURLEncoding.EncodeToString(random)
+	now := v.opts.Clock()
+	token := &URLToken{
+		ID:                 urlTokenID(secret),
+		Key:                key,
+		Method:             method,
+		ContentType:        opts.ContentType,
+		ContentDisposition: opts.ContentDisposition,
+		Actor:              ActorFromContext(ctx),
+		IssuedAt:           now,
+		ExpiresAt:          now.Add(v.opts.TokenTTL),
+	}
+
+	if err := v.opts.Store.Save(ctx, token); err != nil {
+		return "", "", err
+	}
+
+	v.opts.Logger.Info("issued a signed URL token", Fields{
+		"token":      token.ID,
+		"key":        key,
+		"method":     method,
+		"actor":      token.Actor,
+		"expires_at": token.ExpiresAt,
+	})
+
+	return v.opts.BaseURL + secret, token.ID, nil
+}
+
+// Revoke revokes the token of the ID, the signed URLs it was already redirected to stay valid until they expire
+func (v *URLVendor) Revoke(ctx context.Context, id string) error {
+	if err := v.opts.Store.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	v.opts.Logger.Info("revoked a signed URL token", Fields{"token": id, "actor": ActorFromContext(ctx)})
+
+	return nil
+}
+
+// ServeHTTP redirects the requests of a valid token, the last segment of the URL path, to a new signed URL.
+// The method of the request must be the method of the token, or HEAD for a GET token.
+func (v *URLVendor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	secret := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+	token, err := v.opts.Store.Load(r.Context(), urlTokenID(secret))
+	if err == nil && !v.opts.Clock().Before(token.ExpiresAt) {
+		err = fmt.Errorf("%w: the token has expired", ErrNotFound)
+	}
+
+	if err != nil {
+		status := errorStatusCode(err)
+		http.Error(w, http.StatusText(status), status)
+
+		return
+	}
+
+	if r.Method != token.Method && !(r.Method == http.MethodHead && token.Method == http.MethodGet) {
+		w.Header().Set("Allow", token.Method)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	signedURL, err := v.storage.GetSignedURL(r.Context(), token.Key, &SignedURLOption{
+		Method:             token.Method,
+		Expiry:             v.opts.SignedURLExpiry,
+		ContentType:        token.ContentType,
+		ContentDisposition: token.ContentDisposition,
+	})
+	if err != nil {
+		status := errorStatusCode(err)
+		http.Error(w, http.StatusText(status), status)
+
+		return
+	}
+
+	// 307 keeps the method and the body of the uploads
+	http.Redirect(w, r, signedURL, http.StatusTemporaryRedirect)
+}
+
+// MemoryURLTokenStore keeps the tokens in memory, the expired tokens are dropped by the next Save
+type MemoryURLTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]URLToken
+}
+
+func NewMemoryURLTokenStore() *MemoryURLTokenStore {
+	return &MemoryURLTokenStore{tokens: make(map[string]URLToken)}
+}
+
+func (s *MemoryURLTokenStore) Save(ctx context.Context, token *URLToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, saved := range s.tokens {
+		if !token.IssuedAt.Before(saved.ExpiresAt) {
+			delete(s.tokens, id)
+		}
+	}
+
+	s.tokens[token.ID] = *token
+
+	return nil
+}
+
+func (s *MemoryURLTokenStore) Load(ctx context.Context, id string) (*URLToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown token", ErrNotFound)
+	}
+
+	return &token, nil
+}
+
+func (s *MemoryURLTokenStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tokens, id)
+
+	return nil
+}
+
+// BucketURLTokenStore stores the tokens as JSON objects "<prefix><ID>.json" of a bucket, so they are shared by
+// the replicas. The expired tokens are left in the bucket, a lifecycle rule on the prefix should delete them.
+type BucketURLTokenStore struct {
+	storage CloudStorage
+	prefix  string
+}
+
+func NewBucketURLTokenStore(storage CloudStorage, prefix string) *BucketURLTokenStore {
+	return &BucketURLTokenStore{storage: storage, prefix: prefix}
+}
+
+func (s *BucketURLTokenStore) key(id string) string {
+	return s.prefix + id + ".json"
+}
+
+func (s *BucketURLTokenStore) Save(ctx context.Context, token *URLToken) error {
+	body, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	contentType := "application/json"
+
+	return s.storage.Write(ctx, s.key(token.ID), body, &contentType)
+}
+
+func (s *BucketURLTokenStore) Load(ctx context.Context, id string) (*URLToken, error) {
+	body, err := s.storage.Get(ctx, s.key(id))
+	if err != nil {
+		return nil, err
+	}
+
+	token := &URLToken{}
+	if err := json.Unmarshal(body, token); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// Delete doesn't fail for the unknown tokens
+func (s *BucketURLTokenStore) Delete(ctx context.Context, id string) error {
+	err := s.storage.Delete(ctx, s.key(id))
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type infoLogger struct {
+	noopLogger
+	infos []Fields
+}
+
+func (l *infoLogger) Info(msg string, fields Fields) {
+	l.infos = append(l.infos, fields)
+}
+
+func vendedURL(vendor *URLVendor, method string, url string) (int, string) {
+	recorder := httptest.NewRecorder()
+	vendor.ServeHTTP(recorder, httptest.NewRequest(method, url, nil))
+
+	return recorder.Code, recorder.Header().Get("Location")
+}
+
+func TestURLVendor(t *testing.T) {
+	ctx := ContextWithActor(context.Background(), "alice")
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	storage := NewFakeCloudStorage("bucket")
+	storage.SetClock(func() time.Time { return now })
+
+	logger := &infoLogger{}
+	vendor := NewURLVendor(storage, URLVendorOption{
+		BaseURL:  "https://api.example.com/files/",
+		TokenTTL: time.Hour,
+		Clock:    func() time.Time { return now },
+		Logger:   logger,
+	})
+
+	url, id, err := vendor.Issue(ctx, "reports/x.csv", nil)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(url, "https://api.example.com/files/"))
+	require.NotContains(t, url, id)
+	require.Len(t, logger.infos, 1)
+	require.Equal(t, id, logger.infos[0]["token"])
+	require.Equal(t, "alice", logger.infos[0]["actor"])
+
+	token, err := vendor.opts.Store.Load(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "reports/x.csv", token.Key)
+	require.Equal(t, http.MethodGet, token.Method)
+	require.Equal(t, now.Add(time.Hour), token.ExpiresAt)
+
+	status, location := vendedURL(vendor, http.MethodGet, url)
+	require.Equal(t, http.StatusTemporaryRedirect, status)
+	require.Equal(t, fmt.Sprintf("%s?method=GET&expires=%d",
+		storage.GetPublicURL("reports/x.csv"), now.Add(DefaultVendedURLExpiry).Unix()), location)
+
+	status, _ = vendedURL(vendor, http.MethodHead, url)
+	require.Equal(t, http.StatusTemporaryRedirect, status)
+
+	status, _ = vendedURL(vendor, http.MethodPut, url)
+	require.Equal(t, http.StatusMethodNotAllowed, status)
+
+	status, _ = vendedURL(vendor, http.MethodGet, "https://api.example.com/files/unknown")
+	require.Equal(t, http.StatusNotFound, status)
+
+	require.NoError(t, vendor.Revoke(ctx, id))
+	require.Len(t, logger.infos, 2)
+
+	status, _ = vendedURL(vendor, http.MethodGet, url)
+	require.Equal(t, http.StatusNotFound, status)
+}
+
+func TestURLVendorExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	storage := NewFakeCloudStorage("bucket")
+	vendor := NewURLVendor(storage, URLVendorOption{
+		BaseURL: "/files/",
+		Store:   NewBucketURLTokenStore(storage, ".url-tokens/"),
+		Clock:   func() time.Time { return now },
+	})
+
+	url, id, err := vendor.Issue(ctx, "x", &SignedURLOption{Method: http.MethodPut, ContentType: "text/plain"})
+	require.NoError(t, err)
+
+	_, err = storage.Attributes(ctx, ".url-tokens/"+id+".json")
+	require.NoError(t, err)
+
+	status, location := vendedURL(vendor, http.MethodPut, url)
+	require.Equal(t, http.StatusTemporaryRedirect, status)
+	require.Contains(t, location, "method=PUT")
+
+	now = now.Add(DefaultURLTokenTTL)
+
+	status, _ = vendedURL(vendor, http.MethodPut, url)
+	require.Equal(t, http.StatusNotFound, status)
+
+	// revoking an unknown token doesn't fail
+	require.NoError(t, vendor.Revoke(ctx, id))
+	require.NoError(t, vendor.Revoke(ctx, id))
+}
+
+func TestMemoryURLTokenStoreDropsExpiredTokens(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryURLTokenStore()
+
+	require.NoError(t, store.Save(ctx, &URLToken{ID: "a", IssuedAt: now, ExpiresAt: now.Add(time.Minute)}))
+	require.NoError(t, store.Save(ctx, &URLToken{ID: "b", IssuedAt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour)}))
+
+	_, err := store.Load(ctx, "a")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	_, err = store.Load(ctx, "b")
+	require.NoError(t, err)
+}
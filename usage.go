@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"strings"
+)
+
+// BucketUsage is the size of the objects under a prefix
+type BucketUsage struct {
+	Bytes   int64
+	Objects int64
+	// SubPrefixes breaks the usage down by top-level sub-prefix, e.g. "tenant-a/" under "tenants/".
+	// The objects right under the prefix are counted under "". It's nil for the sub-prefixes themselves.
+	SubPrefixes map[string]*BucketUsage
+}
+
+// GetUsage walks the objects under the prefix, e.g. to know how big is the data of a tenant.
+// The providers only list the keys in order, so the next pages are listed in the background
+// while the received ones are counted.
+func GetUsage(ctx context.Context, storage CloudStorage, prefix string) (*BucketUsage, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	usage := &BucketUsage{SubPrefixes: make(map[string]*BucketUsage)}
+	items, errs := ListStream(ctx, storage, prefix)
+
+	for item := range items {
+		name := strings.TrimPrefix(item.Key, prefix)
+
+		// the directory markers aren't objects
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+
+		subPrefix := ""
+		if i := strings.Index(name, "/"); i >= 0 {
+			subPrefix = name[:i+1]
+		}
+
+		subUsage, ok := usage.SubPrefixes[subPrefix]
+		if !ok {
+			subUsage = &BucketUsage{}
+			usage.SubPrefixes[subPrefix] = subUsage
+		}
+
+		subUsage.Bytes += item.Size
+		subUsage.Objects++
+		usage.Bytes += item.Size
+		usage.Objects++
+	}
+
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
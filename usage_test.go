@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetUsage(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.Write(ctx, "tenants/a/1.txt", []byte("12345"), nil))
+	require.NoError(t, storage.Write(ctx, "tenants/a/sub/2.txt", []byte("123"), nil))
+	require.NoError(t, storage.Write(ctx, "tenants/b/1.txt", []byte("1"), nil))
+	require.NoError(t, storage.Write(ctx, "tenants/b/", nil, nil))
+	require.NoError(t, storage.Write(ctx, "tenants/index.json", []byte("{}"), nil))
+	require.NoError(t, storage.Write(ctx, "other/1.txt", []byte("123456789"), nil))
+
+	usage, err := GetUsage(ctx, storage, "tenants/")
+	require.NoError(t, err)
+	require.Equal(t, &BucketUsage{
+		Bytes:   11,
+		Objects: 4,
+		SubPrefixes: map[string]*BucketUsage{
+			"a/": {Bytes: 8, Objects: 2},
+			"b/": {Bytes: 1, Objects: 1},
+			"":   {Bytes: 2, Objects: 1},
+		},
+	}, usage)
+
+	usage, err = GetUsage(ctx, storage, "missing/")
+	require.NoError(t, err)
+	require.Equal(t, &BucketUsage{SubPrefixes: map[string]*BucketUsage{}}, usage)
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"net/http"
+)
+
+// userProjectTransport bills the GCS requests to a project, for the requester pays buckets.
+// The JSON API takes it as the userProject parameter, and the XML API used for the reads as the x-goog-user-project
+// header, so both are set.
+type userProjectTransport struct {
+	base    http.RoundTripper
+	project string
+}
+
+// withGCPUserProject wraps the transport of the GCP clients with the GCPUserProject option, if any
+func withGCPUserProject(transport http.RoundTripper, cloudStorageOpts *CloudStorageOption) http.RoundTripper {
+	if cloudStorageOpts.GCPUserProject == "" {
+		return transport
+	}
+
+	return &userProjectTransport{base: transport, project: cloudStorageOpts.GCPUserProject}
+}
+
+func (t *userProjectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+
+	query := req.URL.Query()
+	if query.Get("userProject") == "" {
+		query.Set("userProject", t.project)
+		req.URL.RawQuery = query.Encode()
+	}
+
+	req.Header.Set("X-Goog-User-Project", t.project)
+
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGCPUserProjectTransport(t *testing.T) {
+	var received *http.Request
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+	}))
+	defer server.Close()
+
+	opts := &CloudStorageOption{GCPUserProject: "billing"}
+	client := &http.Client{Transport: withGCPUserProject(http.DefaultTransport, opts)}
+
+	response, err := client.Get(server.URL + "/storage/v1/b/bucket/o?prefix=a")
+	require.NoError(t, err)
+	response.Body.Close()
+
+	require.Equal(t, "billing", received.URL.Query().Get("userProject"))
+	require.Equal(t, "a", received.URL.Query().Get("prefix"))
+	require.Equal(t, "billing", received.Header.Get("X-Goog-User-Project"))
+
+	// the project set by the client is kept
+	response, err = client.Get(server.URL + "/storage/v1/b/bucket/o?userProject=other")
+	require.NoError(t, err)
+	response.Body.Close()
+
+	require.Equal(t, "other", received.URL.Query().Get("userProject"))
+
+	require.Equal(t, http.DefaultTransport, withGCPUserProject(http.DefaultTransport, &CloudStorageOption{}))
+}
+
+func TestGCPUserProjectSignedURL(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	storage := &ExplicitGCPCloudStorage{
+		bucketName:     "bucket",
+		googleAccessID: "signer@project.iam.gserviceaccount.com",
+		privateKey: pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+		}),
+		userProject: "billing",
+		clock:       time.Now,
+	}
+
+	signedURL, err := storage.GetSignedURL(context.Background(), "key", &SignedURLOption{Expiry: time.Hour})
+	require.NoError(t, err)
+
+	parsed, err := url.Parse(signedURL)
+	require.NoError(t, err)
+	require.Equal(t, "billing", parsed.Query().Get("userProject"))
+	require.NotEmpty(t, parsed.Query().Get("Signature"))
+}
@@ -0,0 +1,333 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+)
+
+// ExportManifestName is the name of the manifest, the last entry of the archives of ExportVerified
+const ExportManifestName = "MANIFEST.json"
+
+// ErrInvalidExport is matched by errors.Is when VerifyExport finds an incomplete, altered or unsigned export
+var ErrInvalidExport = errors.New("invalid export")
+
+// ExportOption configures ExportVerified
+type ExportOption struct {
+	// Format is ArchiveTar or ArchiveTarGz, ArchiveTar when it's empty
+	Format ArchiveFormat
+	// Secret signs the summary of the manifest with HMAC-SHA256, it isn't signed when it's empty
+	Secret []byte
+	// Clock gives the creation time of the manifest, time.Now when it's nil
+	Clock func() time.Time
+}
+
+// ExportFile is a file of the export
+type ExportFile struct {
+	Name   string `json:"name"`
+	Key    string `json:"key"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ExportSummary sums up the export, its SHA256 is the SHA-256 of the "<sha256>  <name>\n" lines of the files
+// in order, like the output of sha256sum
+type ExportSummary struct {
+	Prefix    string    `json:"prefix"`
+	CreatedAt time.Time `json:"createdAt"`
+	Files     int       `json:"files"`
+	Bytes     int64     `json:"bytes"`
+	SHA256    string    `json:"sha256"`
+}
+
+// ExportManifest lists the files of the export with their checksums
+type ExportManifest struct {
+	Files   []ExportFile  `json:"files"`
+	Summary ExportSummary `json:"summary"`
+	// Signature is the hex HMAC-SHA256 of the JSON of the summary, see SignExportSummary
+	Signature string `json:"signature,omitempty"`
+}
+
+// SignExportSummary returns the signature of the summary of a manifest
+func SignExportSummary(secret []byte, summary ExportSummary) (string, error) {
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// exportDigest returns the SHA-256 of the sha256sum lines of the files
+func exportDigest(files []ExportFile) string {
+	digest := sha256.New()
+
+	for _, file := range files {
+		fmt.Fprintf(digest, "%s  %s\n", file.SHA256, file.Name)
+	}
+
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+// ExportVerified streams the objects under the prefix to w as a tar archive like ArchivePrefix, followed by an
+// ExportManifestName entry with the SHA-256 of every file and a signed summary, so the recipients can check
+// the completeness and the integrity of the export with VerifyExport. The checksums are computed while streaming,
+// nothing is buffered besides the manifest.
+func ExportVerified(
+	ctx context.Context,
+	storage CloudStorage,
+	prefix string,
+	w io.Writer,
+	opts *ExportOption,
+) (*ExportManifest, error) {
+	if opts == nil {
+		opts = &ExportOption{}
+	}
+
+	format := opts.Format
+	if format == "" {
+		format = ArchiveTar
+	}
+
+	if format != ArchiveTar && format != ArchiveTarGz {
+		return nil, fmt.Errorf("%w: exports are tar archives, not %s", ErrNotSupported, format)
+	}
+
+	archive, err := newArchiveWriter(w, format)
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := &checksumArchiveWriter{archiveWriter: archive}
+
+	if err := archivePrefix(ctx, storage, prefix, checksums); err != nil {
+		return nil, err
+	}
+
+	manifest, err := checksums.manifest(prefix, clockOrNow(opts.Clock)(), opts.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := archive.add(&ListObject{Size: int64(len(body)), ModTime: manifest.Summary.CreatedAt},
+		ExportManifestName)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := entry.Write(body); err != nil {
+		return nil, err
+	}
+
+	return manifest, archive.Close()
+}
+
+// checksumArchiveWriter computes the SHA-256 of the entries added to the archive
+type checksumArchiveWriter struct {
+	archiveWriter
+	files  []ExportFile
+	hashes []hash.Hash
+}
+
+func (a *checksumArchiveWriter) add(object *ListObject, name string) (io.Writer, error) {
+	if name == ExportManifestName {
+		return nil, fmt.Errorf("object '%s' has the name of the manifest", object.Key)
+	}
+
+	entry, err := a.archiveWriter.add(object, name)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.New()
+	a.files = append(a.files, ExportFile{Name: name, Key: object.Key, Size: object.Size})
+	a.hashes = append(a.hashes, digest)
+
+	return io.MultiWriter(entry, digest), nil
+}
+
+func (a *checksumArchiveWriter) manifest(prefix string, createdAt time.Time, secret []byte) (*ExportManifest, error) {
+	manifest := &ExportManifest{
+		Files: make([]ExportFile, 0, len(a.files)),
+		Summary: ExportSummary{
+			Prefix:    prefix,
+			CreatedAt: createdAt.UTC(),
+			Files:     len(a.files),
+		},
+	}
+
+	for i, file := range a.files {
+		file.SHA256 = hex.EncodeToString(a.hashes[i].Sum(nil))
+		manifest.Files = append(manifest.Files, file)
+		manifest.Summary.Bytes += file.Size
+	}
+
+	manifest.Summary.SHA256 = exportDigest(manifest.Files)
+
+	if len(secret) > 0 {
+		signature, err := SignExportSummary(secret, manifest.Summary)
+		if err != nil {
+			return nil, err
+		}
+
+		manifest.Signature = signature
+	}
+
+	return manifest, nil
+}
+
+// VerifyExport reads a tar or tar.gz archive of ExportVerified and returns its manifest. It fails with
+// ErrInvalidExport when a file is missing, unexpected or altered, when the summary doesn't match the files,
+// or when the signature doesn't match the secret. The signature isn't checked when the secret is empty.
+func VerifyExport(r io.Reader, secret []byte) (*ExportManifest, error) {
+	buffered := bufio.NewReader(r)
+
+	var reader io.Reader = buffered
+
+	magic, err := buffered.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(magic, []byte{0x1f, 0x8b}) {
+		gzipReader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+
+		reader = gzipReader
+	}
+
+	archive := tar.NewReader(reader)
+	files := make(map[string]ExportFile)
+	names := []string{}
+
+	var manifest *ExportManifest
+
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		if manifest != nil {
+			return nil, fmt.Errorf("%w: '%s' follows the manifest", ErrInvalidExport, header.Name)
+		}
+
+		if header.Name == ExportManifestName {
+			manifest = &ExportManifest{}
+			if err := json.NewDecoder(archive).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("%w: invalid manifest: %v", ErrInvalidExport, err)
+			}
+
+			continue
+		}
+
+		digest := sha256.New()
+
+		size, err := io.Copy(digest, archive)
+		if err != nil {
+			return nil, err
+		}
+
+		names = append(names, header.Name)
+		files[header.Name] = ExportFile{Name: header.Name, Size: size, SHA256: hex.EncodeToString(digest.Sum(nil))}
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("%w: no %s", ErrInvalidExport, ExportManifestName)
+	}
+
+	if err := verifyExportManifest(manifest, names, files, secret); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// verifyExportManifest checks the files read from the archive, in the order of their names, against the manifest
+func verifyExportManifest(manifest *ExportManifest, names []string, files map[string]ExportFile, secret []byte) error {
+	if len(secret) > 0 {
+		signature, err := SignExportSummary(secret, manifest.Summary)
+		if err != nil {
+			return err
+		}
+
+		if !hmac.Equal([]byte(signature), []byte(manifest.Signature)) {
+			return fmt.Errorf("%w: the signature of the summary doesn't match", ErrInvalidExport)
+		}
+	}
+
+	var size int64
+
+	for _, expected := range manifest.Files {
+		file, ok := files[expected.Name]
+		if !ok {
+			return fmt.Errorf("%w: '%s' is missing", ErrInvalidExport, expected.Name)
+		}
+
+		if file.Size != expected.Size || file.SHA256 != expected.SHA256 {
+			return fmt.Errorf("%w: '%s' has the SHA-256 %s instead of %s", ErrInvalidExport,
+				expected.Name, file.SHA256, expected.SHA256)
+		}
+
+		delete(files, expected.Name)
+		size += expected.Size
+	}
+
+	for _, name := range names {
+		if _, ok := files[name]; ok {
+			return fmt.Errorf("%w: '%s' isn't in the manifest", ErrInvalidExport, name)
+		}
+	}
+
+	summary := manifest.Summary
+	if summary.Files != len(manifest.Files) || summary.Bytes != size || summary.SHA256 != exportDigest(manifest.Files) {
+		return fmt.Errorf("%w: the summary doesn't match the files", ErrInvalidExport)
+	}
+
+	return nil
+}
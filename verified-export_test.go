@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// rewriteExport rewrites the entries of the tar with edit, the entries it returns nil for are dropped
+func rewriteExport(t *testing.T, export []byte, edit func(name string, body []byte) []byte) []byte {
+	var rewritten bytes.Buffer
+
+	reader := tar.NewReader(bytes.NewReader(export))
+	writer := tar.NewWriter(&rewritten)
+
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+
+		body, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+
+		body = edit(header.Name, body)
+		if body == nil {
+			continue
+		}
+
+		header.Size = int64(len(body))
+		require.NoError(t, writer.WriteHeader(header))
+
+		_, err = writer.Write(body)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, writer.Close())
+
+	return rewritten.Bytes()
+}
+
+func TestExportVerified(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+	secret := []byte("secret")
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, storage.Write(ctx, "users/1/a.txt", []byte("hello"), nil))
+	require.NoError(t, storage.Write(ctx, "users/1/b/c.txt", []byte("world!"), nil))
+	require.NoError(t, storage.Write(ctx, "users/2/d.txt", []byte("other"), nil))
+
+	var export bytes.Buffer
+
+	manifest, err := ExportVerified(ctx, storage, "users/1/", &export, &ExportOption{
+		Secret: secret,
+		Clock:  func() time.Time { return now },
+	})
+	require.NoError(t, err)
+	require.Equal(t, []ExportFile{
+		{Name: "a.txt", Key: "users/1/a.txt", Size: 5,
+			SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		{Name: "b/c.txt", Key: "users/1/b/c.txt", Size: 6,
+			SHA256: "711e9609339e92b03ddc0a211827dba421f38f9ed8b9d806e1ffdd8c15ffa03d"},
+	}, manifest.Files)
+	require.Equal(t, ExportSummary{Prefix: "users/1/", CreatedAt: now, Files: 2, Bytes: 11,
+		SHA256: exportDigest(manifest.Files)}, manifest.Summary)
+	require.NotEmpty(t, manifest.Signature)
+
+	verified, err := VerifyExport(bytes.NewReader(export.Bytes()), secret)
+	require.NoError(t, err)
+	require.Equal(t, manifest, verified)
+
+	_, err = VerifyExport(bytes.NewReader(export.Bytes()), []byte("other"))
+	require.True(t, errors.Is(err, ErrInvalidExport))
+
+	altered := rewriteExport(t, export.Bytes(), func(name string, body []byte) []byte {
+		if name == "a.txt" {
+			return []byte("HELLO")
+		}
+
+		return body
+	})
+
+	_, err = VerifyExport(bytes.NewReader(altered), secret)
+	require.True(t, errors.Is(err, ErrInvalidExport))
+
+	incomplete := rewriteExport(t, export.Bytes(), func(name string, body []byte) []byte {
+		if name == "b/c.txt" {
+			return nil
+		}
+
+		return body
+	})
+
+	_, err = VerifyExport(bytes.NewReader(incomplete), secret)
+	require.True(t, errors.Is(err, ErrInvalidExport))
+
+	withoutManifest := rewriteExport(t, export.Bytes(), func(name string, body []byte) []byte {
+		if name == ExportManifestName {
+			return nil
+		}
+
+		return body
+	})
+
+	_, err = VerifyExport(bytes.NewReader(withoutManifest), nil)
+	require.True(t, errors.Is(err, ErrInvalidExport))
+}
+
+func TestExportVerifiedTarGz(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, storage.Write(ctx, "exports/a.txt", []byte("hello"), nil))
+
+	var export bytes.Buffer
+
+	manifest, err := ExportVerified(ctx, storage, "exports/", &export, &ExportOption{Format: ArchiveTarGz})
+	require.NoError(t, err)
+	require.Empty(t, manifest.Signature)
+
+	verified, err := VerifyExport(&export, nil)
+	require.NoError(t, err)
+	require.Equal(t, manifest, verified)
+
+	_, err = ExportVerified(ctx, storage, "exports/", ioutil.Discard, &ExportOption{Format: ArchiveZip})
+	require.True(t, errors.Is(err, ErrNotSupported))
+}
@@ -0,0 +1,201 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// WatchOption configures Watch
+type WatchOption struct {
+	// CheckpointKey stores the listing of the last poll, so a restarted watcher sends the changes made while it
+	// was stopped. The listing is only kept in memory when it's empty.
+	CheckpointKey string
+	// EmitExisting sends an ObjectCreated event for every object of the first poll without checkpoint.
+	// Otherwise the prefix is listed by Watch as the reference.
+	EmitExisting bool
+	// Logger receives the failed polls, which are retried at the next interval. They are discarded when it's nil.
+	Logger Logger
+}
+
+// Watch polls the prefix every interval and sends the differences between two listings as ObjectEvent,
+// for the environments where Subscribe can't be used. The channel is closed once ctx is done.
+// The changes made and reverted between two polls aren't seen, and the event times of the deleted objects are
+// the times of the polls.
+func Watch(
+	ctx context.Context,
+	storage CloudStorage,
+	prefix string,
+	interval time.Duration,
+	opts *WatchOption,
+) (<-chan ObjectEvent, error) {
+	var options WatchOption
+	if opts != nil {
+		options = *opts
+	}
+
+	logger := loggerOrNoop(options.Logger)
+
+	previous, err := loadWatchCheckpoint(ctx, storage, options.CheckpointKey)
+	if err != nil {
+		return nil, err
+	}
+
+	polled := false
+
+	switch {
+	case previous != nil:
+	case options.EmitExisting:
+		previous = make(map[string]*ListObject)
+	default:
+		// the reference is listed before returning, so the changes made after the call are all sent
+		previous, err = listByName(ctx, storage, prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		delete(previous, trimPrefix(options.CheckpointKey, prefix))
+
+		if err := saveWatchCheckpoint(ctx, storage, options.CheckpointKey, previous); err != nil {
+			return nil, err
+		}
+
+		polled = true
+	}
+
+	events := make(chan ObjectEvent, notificationBufferSize)
+
+	go func() {
+		defer close(events)
+
+		if polled {
+			sleepContext(ctx, interval)
+		}
+
+		for ctx.Err() == nil {
+			current, err := listByName(ctx, storage, prefix)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Warn("unable to list the watched prefix", Fields{"prefix": prefix, "error": err})
+				}
+			} else {
+				delete(current, trimPrefix(options.CheckpointKey, prefix))
+
+				changes := diffListings(prefix, previous, current)
+				if !sendEvents(ctx, events, changes) {
+					return
+				}
+
+				// the checkpoint is only written when the listing changed
+				if len(changes) > 0 {
+					err := saveWatchCheckpoint(ctx, storage, options.CheckpointKey, current)
+					if err != nil {
+						logger.Warn("unable to save the watch checkpoint", Fields{"key": options.CheckpointKey, "error": err})
+					}
+				}
+
+				previous = current
+			}
+
+			sleepContext(ctx, interval)
+		}
+	}()
+
+	return events, nil
+}
+
+// diffListings returns the events turning the previous listing into the current one, sorted by key
+func diffListings(prefix string, previous map[string]*ListObject, current map[string]*ListObject) []ObjectEvent {
+	var events []ObjectEvent
+
+	for _, name := range sortedNames(current) {
+		object := current[name]
+		event := ObjectEvent{Key: prefix + name, Size: object.Size, Time: object.ModTime}
+
+		old, ok := previous[name]
+
+		switch {
+		case !ok:
+			event.Type = ObjectCreated
+		case old.Size != object.Size || !old.ModTime.Equal(object.ModTime) ||
+			(len(old.MD5) > 0 && len(object.MD5) > 0 && !bytes.Equal(old.MD5, object.MD5)):
+			event.Type = ObjectUpdated
+		default:
+			continue
+		}
+
+		events = append(events, event)
+	}
+
+	now := time.Now()
+
+	for _, name := range sortedNames(previous) {
+		if _, ok := current[name]; !ok {
+			events = append(events, ObjectEvent{Type: ObjectDeleted, Key: prefix + name, Time: now})
+		}
+	}
+
+	return events
+}
+
+// trimPrefix returns the key without the prefix, or an empty string if the key isn't under the prefix
+func trimPrefix(key string, prefix string) string {
+	if len(key) < len(prefix) || key[:len(prefix)] != prefix {
+		return ""
+	}
+
+	return key[len(prefix):]
+}
+
+// loadWatchCheckpoint returns nil when there's no checkpoint
+func loadWatchCheckpoint(ctx context.Context, storage CloudStorage, key string) (map[string]*ListObject, error) {
+	if key == "" {
+		return nil, nil
+	}
+
+	body, err := storage.Get(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var objects map[string]*ListObject
+
+	return objects, json.Unmarshal(body, &objects)
+}
+
+func saveWatchCheckpoint(ctx context.Context, storage CloudStorage, key string, objects map[string]*ListObject) error {
+	if key == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(objects)
+	if err != nil {
+		return err
+	}
+
+	contentType := "application/json"
+
+	return storage.Write(ctx, key, body, &contentType)
+}
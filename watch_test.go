@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// nextEvents receives n events, without their time
+func nextEvents(t *testing.T, events <-chan ObjectEvent, n int) []ObjectEvent {
+	t.Helper()
+
+	var received []ObjectEvent
+
+	for i := 0; i < n; i++ {
+		select {
+		case event := <-events:
+			event.Time = time.Time{}
+			received = append(received, event)
+		case <-time.After(time.Second):
+			t.Fatalf("received %d events out of %d", i, n)
+		}
+	}
+
+	return received
+}
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storage := NewFakeCloudStorage("bucket")
+	require.NoError(t, storage.Write(ctx, "watched/existing.txt", []byte("a"), nil))
+
+	events, err := Watch(ctx, storage, "watched/", 10*time.Millisecond, &WatchOption{EmitExisting: true})
+	require.NoError(t, err)
+
+	require.Equal(t, []ObjectEvent{
+		{Type: ObjectCreated, Key: "watched/existing.txt", Size: 1},
+	}, nextEvents(t, events, 1))
+
+	require.NoError(t, storage.Write(ctx, "watched/new.txt", []byte("new"), nil))
+	require.Equal(t, []ObjectEvent{
+		{Type: ObjectCreated, Key: "watched/new.txt", Size: 3},
+	}, nextEvents(t, events, 1))
+
+	require.NoError(t, storage.Write(ctx, "watched/existing.txt", []byte("changed"), nil))
+	require.NoError(t, storage.Write(ctx, "other/ignored.txt", []byte("ignored"), nil))
+	require.Equal(t, []ObjectEvent{
+		{Type: ObjectUpdated, Key: "watched/existing.txt", Size: 7},
+	}, nextEvents(t, events, 1))
+
+	require.NoError(t, storage.Delete(ctx, "watched/new.txt"))
+	require.Equal(t, []ObjectEvent{
+		{Type: ObjectDeleted, Key: "watched/new.txt"},
+	}, nextEvents(t, events, 1))
+
+	cancel()
+
+	for range events {
+	}
+}
+
+func TestWatchCheckpoint(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	storage := NewFakeCloudStorage("bucket")
+	opts := &WatchOption{CheckpointKey: "watched/.checkpoint"}
+
+	require.NoError(t, storage.Write(ctx, "watched/a.txt", []byte("a"), nil))
+
+	events, err := Watch(ctx, storage, "watched/", 10*time.Millisecond, opts)
+	require.NoError(t, err)
+
+	// the first listing is the reference
+	require.NoError(t, storage.Write(ctx, "watched/b.txt", []byte("b"), nil))
+	require.Equal(t, []ObjectEvent{
+		{Type: ObjectCreated, Key: "watched/b.txt", Size: 1},
+	}, nextEvents(t, events, 1))
+
+	cancel()
+
+	for range events {
+	}
+
+	// the changes made while the watcher is stopped are sent on restart
+	require.NoError(t, storage.Delete(context.Background(), "watched/a.txt"))
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err = Watch(ctx, storage, "watched/", 10*time.Millisecond, opts)
+	require.NoError(t, err)
+
+	require.Equal(t, []ObjectEvent{
+		{Type: ObjectDeleted, Key: "watched/a.txt"},
+	}, nextEvents(t, events, 1))
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+// Package webdavblob exposes a CloudStorage bucket as a WebDAV server, to browse it with a file explorer.
+//
+// The files are read and written through aferoblob: the directories only exist as long as they contain a file,
+// and the uploaded files are buffered in memory until they are closed.
+package webdavblob
+
+import (
+	"context"
+	"os"
+
+	commonblobgo "github.com/AccelByte/common-blob-go"
+	"github.com/AccelByte/common-blob-go/aferoblob"
+	"golang.org/x/net/webdav"
+)
+
+// FileSystem is a webdav.FileSystem over the objects of a CloudStorage
+type FileSystem struct {
+	storage commonblobgo.CloudStorage
+}
+
+var _ webdav.FileSystem = (*FileSystem)(nil)
+
+// NewFileSystem returns a FileSystem over the storage
+func NewFileSystem(storage commonblobgo.CloudStorage) *FileSystem {
+	return &FileSystem{storage: storage}
+}
+
+// NewHandler returns a WebDAV handler serving the storage with in-memory locks.
+// prefix is the URL path the handler is mounted on, e.g. "/dav".
+func NewHandler(storage commonblobgo.CloudStorage, prefix string) *webdav.Handler {
+	return &webdav.Handler{
+		Prefix:     prefix,
+		FileSystem: NewFileSystem(storage),
+		LockSystem: webdav.NewMemLS(),
+	}
+}
+
+// fs returns the afero adapter making its requests with the ctx of the WebDAV request
+func (fs *FileSystem) fs(ctx context.Context) *aferoblob.Fs {
+	return aferoblob.New(ctx, fs.storage)
+}
+
+func (fs *FileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return fs.fs(ctx).Mkdir(name, perm)
+}
+
+func (fs *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	return fs.fs(ctx).OpenFile(name, flag, perm)
+}
+
+func (fs *FileSystem) RemoveAll(ctx context.Context, name string) error {
+	return fs.fs(ctx).RemoveAll(name)
+}
+
+func (fs *FileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	return fs.fs(ctx).Rename(oldName, newName)
+}
+
+func (fs *FileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	return fs.fs(ctx).Stat(name)
+}
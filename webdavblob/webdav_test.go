@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package webdavblob
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	commonblobgo "github.com/AccelByte/common-blob-go"
+	"github.com/stretchr/testify/require"
+)
+
+func do(t *testing.T, handler http.Handler, method string, target string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	if method == "PROPFIND" {
+		request.Header.Set("Depth", "1")
+	}
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+
+	return response
+}
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	storage := commonblobgo.NewFakeCloudStorage("bucket")
+	handler := NewHandler(storage, "/dav")
+
+	response := do(t, handler, http.MethodPut, "/dav/ingest/report.csv", "a,b")
+	require.Equal(t, http.StatusCreated, response.Code)
+
+	body, err := storage.Get(ctx, "ingest/report.csv")
+	require.NoError(t, err)
+	require.Equal(t, []byte("a,b"), body)
+
+	response = do(t, handler, http.MethodGet, "/dav/ingest/report.csv", "")
+	require.Equal(t, http.StatusOK, response.Code)
+
+	content, err := ioutil.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, "a,b", string(content))
+
+	response = do(t, handler, "PROPFIND", "/dav/ingest/", "")
+	require.Equal(t, http.StatusMultiStatus, response.Code)
+	require.Contains(t, response.Body.String(), "/dav/ingest/report.csv")
+
+	response = do(t, handler, http.MethodDelete, "/dav/ingest/report.csv", "")
+	require.Equal(t, http.StatusNoContent, response.Code)
+
+	_, err = storage.Get(ctx, "ingest/report.csv")
+	require.True(t, errors.Is(err, commonblobgo.ErrNotFound))
+}
@@ -0,0 +1,425 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultWebhookQueueSize = 1000
+	defaultWebhookTimeout   = 10 * time.Second
+
+	// WebhookSignatureHeader holds the hex HMAC-SHA256 of "<timestamp>.<body>" with the secret, see SignWebhookPayload
+	WebhookSignatureHeader = "X-Blob-Signature"
+	// WebhookTimestampHeader holds the Unix time of the delivery, to reject the replayed deliveries
+	WebhookTimestampHeader = "X-Blob-Timestamp"
+)
+
+// ErrWebhookOutboxFull is reported to WebhookOption.OnError for the events dropped while the outbox is full
+var ErrWebhookOutboxFull = errors.New("webhook outbox is full")
+
+// WebhookOption configures the webhook notified of the mutations
+type WebhookOption struct {
+	// URL receives a POST of a WebhookEvent JSON after every successful Write, WriteIf, GetWriter, Upload, Delete
+	// and DeleteIf
+	URL string
+	// Secret signs the payloads, see SignWebhookPayload. They aren't signed when it's empty.
+	Secret []byte
+	// Client sends the requests. Defaults to an http.Client with a 10s timeout.
+	Client *http.Client
+	// QueueSize is the number of events waiting to be sent. The events are dropped while it's full,
+	// so the mutations are never slowed down by the webhook. Defaults to 1000.
+	QueueSize int
+	// RetryPolicy is applied to every delivery. The network errors, 429 and 5xx responses are retried by default.
+	RetryPolicy *RetryPolicy
+	// OnError is called with the events which couldn't be delivered. They are logged when it's nil.
+	OnError func(event WebhookEvent, err error)
+	// Logger receives the failed deliveries when OnError is nil. They are discarded when it's nil.
+	Logger Logger
+}
+
+// WebhookEvent is the payload sent to the webhook
+type WebhookEvent struct {
+	// ID is unique per event, so the receivers can ignore the retried deliveries
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Bucket    string    `json:"bucket"`
+	Key       string    `json:"key"`
+	// Size is the size of the written object, zero for Delete
+	Size int64 `json:"size,omitempty"`
+}
+
+// WebhookStatusError is returned for the deliveries answered with a non-2xx status
+type WebhookStatusError struct {
+	StatusCode int
+}
+
+func (e *WebhookStatusError) Error() string {
+	return fmt.Sprintf("webhook responded with status %d", e.StatusCode)
+}
+
+// isRetryableWebhookError retries the network errors, and the 429 and 5xx responses
+func isRetryableWebhookError(err error) bool {
+	var statusErr *WebhookStatusError
+	if errors.As(err, &statusErr) {
+		return isRetryableStatusCode(statusErr.StatusCode)
+	}
+
+	return true
+}
+
+// SignWebhookPayload returns the signature of the payload, for the receivers to compare with hmac.Equal
+// to the WebhookSignatureHeader
+func SignWebhookPayload(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookCloudStorage notifies a webhook of the successful mutations of the wrapped CloudStorage.
+// The events are sent in order by a background worker through a bounded outbox.
+type WebhookCloudStorage struct {
+	CloudStorage
+
+	bucketName string
+	opts       WebhookOption
+	policy     RetryPolicy
+	outbox     chan WebhookEvent
+
+	mu        sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+	pending   sync.WaitGroup
+	done      chan struct{}
+}
+
+// NewWebhookCloudStorage wraps the storage with the webhook notifications and starts the delivery worker
+func NewWebhookCloudStorage(storage CloudStorage, bucketName string, opts WebhookOption) *WebhookCloudStorage {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultWebhookQueueSize
+	}
+
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: defaultWebhookTimeout}
+	}
+
+	opts.Logger = loggerOrNoop(opts.Logger)
+
+	var policy RetryPolicy
+	if opts.RetryPolicy != nil {
+		policy = *opts.RetryPolicy
+	}
+
+	if policy.IsRetryable == nil {
+		policy.IsRetryable = isRetryableWebhookError
+	}
+
+	ts := &WebhookCloudStorage{
+		CloudStorage: storage,
+		bucketName:   bucketName,
+		opts:         opts,
+		policy:       policy.withDefaults(),
+		outbox:       make(chan WebhookEvent, opts.QueueSize),
+		done:         make(chan struct{}),
+	}
+
+	go ts.work()
+
+	return ts
+}
+
+func (ts *WebhookCloudStorage) work() {
+	defer close(ts.done)
+
+	for event := range ts.outbox {
+		event := event
+
+		// the context of the mutation is usually gone by the time the event is sent
+		err := ts.policy.do(context.Background(), func() error {
+			return ts.deliver(event)
+		})
+		if err != nil {
+			if ts.opts.OnError != nil {
+				ts.opts.OnError(event, err)
+			} else {
+				ts.opts.Logger.Error("unable to deliver webhook event", Fields{"key": event.Key, "error": err})
+			}
+		}
+
+		ts.pending.Done()
+	}
+}
+
+func (ts *WebhookCloudStorage) deliver(event WebhookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, ts.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	if len(ts.opts.Secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		request.Header.Set(WebhookTimestampHeader, timestamp)
+		request.Header.Set(WebhookSignatureHeader, SignWebhookPayload(ts.opts.Secret, timestamp, body))
+	}
+
+	response, err := ts.opts.Client.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return &WebhookStatusError{StatusCode: response.StatusCode}
+	}
+
+	return nil
+}
+
+// notify enqueues the event, or drops it if the outbox is full
+func (ts *WebhookCloudStorage) notify(operation string, key string, size int64) {
+	event := WebhookEvent{
+		ID:        uuid.New().String(),
+		Time:      time.Now().UTC(),
+		Operation: operation,
+		Bucket:    ts.bucketName,
+		Key:       key,
+		Size:      size,
+	}
+
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	if ts.closed {
+		return
+	}
+
+	ts.pending.Add(1)
+
+	select {
+	case ts.outbox <- event:
+	default:
+		ts.pending.Done()
+
+		if ts.opts.OnError != nil {
+			ts.opts.OnError(event, ErrWebhookOutboxFull)
+		} else {
+			ts.opts.Logger.Error("webhook event dropped", Fields{"key": key, "error": ErrWebhookOutboxFull})
+		}
+	}
+}
+
+func (ts *WebhookCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	if err := ts.CloudStorage.Write(ctx, key, body, contentType, opts...); err != nil {
+		return err
+	}
+
+	ts.notify("Write", key, int64(len(body)))
+
+	return nil
+}
+
+// GetWriter notifies the webhook once the writer is closed successfully
+func (ts *WebhookCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	writer, err := ts.CloudStorage.GetWriter(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webhookWriter{WriteCloser: writer, storage: ts, key: key}, nil
+}
+
+type webhookWriter struct {
+	io.WriteCloser
+	storage *WebhookCloudStorage
+	key     string
+	size    int64
+}
+
+func (w *webhookWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteCloser.Write(p)
+	w.size += int64(n)
+
+	return n, err
+}
+
+func (w *webhookWriter) Close() error {
+	if err := w.WriteCloser.Close(); err != nil {
+		return err
+	}
+
+	w.storage.notify("GetWriter", w.key, w.size)
+
+	return nil
+}
+
+func (ts *WebhookCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	counter := &countingReader{Reader: reader}
+
+	if err := ts.CloudStorage.Upload(ctx, key, counter, opts, writeOpts...); err != nil {
+		return err
+	}
+
+	ts.notify("Upload", key, counter.count)
+
+	return nil
+}
+
+func (ts *WebhookCloudStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	if err := ts.CloudStorage.Delete(ctx, key); err != nil {
+		return err
+	}
+
+	ts.notify("Delete", key, 0)
+
+	return nil
+}
+
+func (ts *WebhookCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	generation, err := ts.CloudStorage.WriteIf(ctx, key, body, contentType, condition)
+	if err != nil {
+		return "", err
+	}
+
+	ts.notify("WriteIf", key, int64(len(body)))
+
+	return generation, nil
+}
+
+func (ts *WebhookCloudStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	if err := ts.CloudStorage.DeleteIf(ctx, key, generation); err != nil {
+		return err
+	}
+
+	ts.notify("DeleteIf", key, 0)
+
+	return nil
+}
+
+// Copy notifies the webhook with the size of the copy, which is read once it's copied
+func (ts *WebhookCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	if err := ts.CloudStorage.Copy(ctx, srcKey, dstKey, opts); err != nil {
+		return err
+	}
+
+	var size int64
+	if attrs, err := ts.CloudStorage.Attributes(ctx, dstKey); err == nil {
+		size = attrs.Size
+	}
+
+	ts.notify("Copy", dstKey, size)
+
+	return nil
+}
+
+// Flush waits until the events of the mutations made so far are delivered or ctx is done,
+// then forwards to the wrapped storage when it queues the writes
+func (ts *WebhookCloudStorage) Flush(ctx context.Context) error {
+	if err := flushStorage(ctx, ts.CloudStorage); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		ts.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the notifications, delivers the queued events and closes the wrapped storage
+func (ts *WebhookCloudStorage) Close() {
+	ts.closeOnce.Do(func() {
+		ts.mu.Lock()
+		ts.closed = true
+		close(ts.outbox)
+		ts.mu.Unlock()
+
+		<-ts.done
+		ts.CloudStorage.Close()
+	})
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// webhookReceiver records the events posted to it, answering with the given statuses first
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	events   []WebhookEvent
+	requests int
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests++
+
+	body, _ := ioutil.ReadAll(request.Body)
+
+	signature := SignWebhookPayload([]byte("secret"), request.Header.Get(WebhookTimestampHeader), body)
+	if !hmac.Equal([]byte(signature), []byte(request.Header.Get(WebhookSignatureHeader))) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if len(r.statuses) > 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	r.events = append(r.events, event)
+}
+
+func TestWebhookCloudStorage(t *testing.T) {
+	ctx := context.Background()
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+
+	defer server.Close()
+
+	storage := NewWebhookCloudStorage(NewFakeCloudStorage("bucket"), "bucket", WebhookOption{
+		URL:    server.URL,
+		Secret: []byte("secret"),
+	})
+
+	require.NoError(t, storage.Write(ctx, "a.txt", []byte("abc"), nil))
+
+	writer, err := storage.GetWriter(ctx, "b.txt")
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	require.NoError(t, storage.Upload(ctx, "c.txt", bytes.NewReader([]byte("uploaded")), nil))
+	require.NoError(t, storage.Delete(ctx, "a.txt"))
+	// the failed mutations aren't notified
+	require.Error(t, storage.Delete(ctx, "missing.txt"))
+
+	require.NoError(t, storage.Flush(ctx))
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	require.Len(t, receiver.events, 4)
+
+	var operations []string
+
+	for _, event := range receiver.events {
+		require.Equal(t, "bucket", event.Bucket)
+		require.NotEmpty(t, event.ID)
+
+		operations = append(operations, event.Operation+" "+event.Key)
+	}
+
+	require.Equal(t, []string{"Write a.txt", "GetWriter b.txt", "Upload c.txt", "Delete a.txt"}, operations)
+	require.Equal(t, int64(5), receiver.events[1].Size)
+	require.Equal(t, int64(8), receiver.events[2].Size)
+}
+
+func TestWebhookCloudStorageRetries(t *testing.T) {
+	ctx := context.Background()
+	receiver := &webhookReceiver{statuses: []int{http.StatusServiceUnavailable, http.StatusOK, http.StatusBadRequest}}
+	server := httptest.NewServer(receiver)
+
+	defer server.Close()
+
+	var (
+		mu     sync.Mutex
+		failed []error
+	)
+
+	storage := NewWebhookCloudStorage(NewFakeCloudStorage("bucket"), "bucket", WebhookOption{
+		URL:         server.URL,
+		Secret:      []byte("secret"),
+		RetryPolicy: &RetryPolicy{BaseBackoff: time.Millisecond},
+		OnError: func(event WebhookEvent, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			failed = append(failed, err)
+		},
+	})
+
+	// delivered on the second attempt
+	require.NoError(t, storage.Write(ctx, "a.txt", []byte("a"), nil))
+	// the 400 isn't retried
+	require.NoError(t, storage.Write(ctx, "b.txt", []byte("b"), nil))
+
+	storage.Close()
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	require.Equal(t, 3, receiver.requests)
+	require.Len(t, receiver.events, 1)
+	require.Equal(t, "a.txt", receiver.events[0].Key)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Len(t, failed, 1)
+	require.Equal(t, &WebhookStatusError{StatusCode: http.StatusBadRequest}, failed[0])
+}
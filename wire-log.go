@@ -0,0 +1,147 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// sensitiveHeaders are never logged
+var sensitiveHeaders = map[string]bool{
+	"Authorization":        true,
+	"Proxy-Authorization":  true,
+	"Cookie":               true,
+	"Set-Cookie":           true,
+	"X-Amz-Security-Token": true,
+	"X-Goog-Signature":     true,
+}
+
+// sensitiveQueryParameters are redacted from the logged URLs, e.g. the signatures of signed URLs
+var sensitiveQueryParameters = map[string]bool{
+	"x-amz-signature":      true,
+	"x-amz-credential":     true,
+	"x-amz-security-token": true,
+	"x-goog-signature":     true,
+	"x-goog-credential":    true,
+	"signature":            true,
+	"access_token":         true,
+}
+
+// WireLog dumps the sanitized HTTP requests and responses of the providers with the Logger at the debug level.
+// It can be enabled and disabled at runtime, it's disabled by default.
+type WireLog struct {
+	enabled int32
+}
+
+// SetEnabled enables or disables the wire logging
+func (w *WireLog) SetEnabled(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+
+	atomic.StoreInt32(&w.enabled, value)
+}
+
+// Enabled returns whether the wire logging is enabled
+func (w *WireLog) Enabled() bool {
+	return atomic.LoadInt32(&w.enabled) == 1
+}
+
+type wireLogTransport struct {
+	base    http.RoundTripper
+	wireLog *WireLog
+	logger  Logger
+}
+
+// withWireLog wraps the transport when the wire logging is configured
+func withWireLog(transport http.RoundTripper, cloudStorageOpts *CloudStorageOption) http.RoundTripper {
+	if cloudStorageOpts.WireLog == nil {
+		return transport
+	}
+
+	return &wireLogTransport{
+		base:    transport,
+		wireLog: cloudStorageOpts.WireLog,
+		logger:  loggerOrNoop(cloudStorageOpts.Logger),
+	}
+}
+
+func (t *wireLogTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.wireLog.Enabled() {
+		return t.base.RoundTrip(req)
+	}
+
+	t.logger.Debug("HTTP request", Fields{
+		"method":  req.Method,
+		"url":     sanitizeURL(req.URL),
+		"headers": sanitizeHeaders(req.Header),
+	})
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.logger.Debug("HTTP request failed", Fields{
+			"method": req.Method,
+			"url":    sanitizeURL(req.URL),
+			"error":  err,
+		})
+
+		return nil, err
+	}
+
+	t.logger.Debug("HTTP response", Fields{
+		"method":  req.Method,
+		"url":     sanitizeURL(req.URL),
+		"status":  resp.StatusCode,
+		"headers": sanitizeHeaders(resp.Header),
+	})
+
+	return resp, nil
+}
+
+func sanitizeURL(u *url.URL) string {
+	sanitized := *u
+	sanitized.User = nil
+
+	query := sanitized.Query()
+	for name := range query {
+		if sensitiveQueryParameters[strings.ToLower(name)] {
+			query.Set(name, "REDACTED")
+		}
+	}
+
+	sanitized.RawQuery = query.Encode()
+
+	return sanitized.String()
+}
+
+func sanitizeHeaders(headers http.Header) map[string]string {
+	sanitized := make(map[string]string, len(headers))
+
+	for name, values := range headers {
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+
+		sanitized[name] = strings.Join(values, ", ")
+	}
+
+	return sanitized
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type debugLogger struct {
+	noopLogger
+	entries []Fields
+}
+
+func (l *debugLogger) Debug(msg string, fields Fields) {
+	l.entries = append(l.entries, fields)
+}
+
+func TestWireLogTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	logger := &debugLogger{}
+	wireLog := &WireLog{}
+	client := &http.Client{Transport: withWireLog(http.DefaultTransport, &CloudStorageOption{
+		WireLog: wireLog,
+		Logger:  logger,
+	})}
+
+	request := func() {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/bucket/key?X-Amz-Signature=secret&prefix=a", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret")
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	request()
+	require.Empty(t, logger.entries)
+
+	wireLog.SetEnabled(true)
+	request()
+	require.Len(t, logger.entries, 2)
+	require.Equal(t, server.URL+"/bucket/key?X-Amz-Signature=REDACTED&prefix=a", logger.entries[0]["url"])
+	require.NotContains(t, logger.entries[0]["headers"], "Authorization")
+	require.Equal(t, http.StatusNotFound, logger.entries[1]["status"])
+	require.NotContains(t, logger.entries[1]["headers"], "Set-Cookie")
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io"
+)
+
+// WriteDefaultsOption configures the attributes given to every Write, GetWriter and Upload, unless the call
+// sets them, so the policies of an organization don't depend on every call site
+type WriteDefaultsOption struct {
+	// CacheControl is the Cache-Control of the objects written without WithCacheControl
+	CacheControl string
+	// ContentType is the content type of the objects written without a content type whose key has no known
+	// extension, instead of sniffing their content
+	ContentType string
+	// Metadata is merged with the metadata of WithMetadata, which wins for the same key
+	Metadata map[string]string
+}
+
+// writeDefaultsCloudStorage prepends the default options to the options of the writes
+type writeDefaultsCloudStorage struct {
+	CloudStorage
+	opts WriteDefaultsOption
+}
+
+func newWriteDefaultsCloudStorage(storage CloudStorage, opts WriteDefaultsOption) *writeDefaultsCloudStorage {
+	return &writeDefaultsCloudStorage{CloudStorage: storage, opts: opts}
+}
+
+// options returns the default options followed by the options of the call, which override them
+func (ts *writeDefaultsCloudStorage) options(key string, opts []WriteOption) []WriteOption {
+	defaults := make([]WriteOption, 0, 3+len(opts))
+
+	if ts.opts.CacheControl != "" {
+		defaults = append(defaults, WithCacheControl(ts.opts.CacheControl))
+	}
+
+	if ts.opts.ContentType != "" && extensionContentType(key) == nil {
+		defaults = append(defaults, WithContentType(ts.opts.ContentType))
+	}
+
+	if len(ts.opts.Metadata) > 0 {
+		defaults = append(defaults, WithMetadata(ts.opts.Metadata))
+	}
+
+	return append(defaults, opts...)
+}
+
+func (ts *writeDefaultsCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	return ts.CloudStorage.Write(ctx, key, body, contentType, ts.options(key, opts)...)
+}
+
+func (ts *writeDefaultsCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	return ts.CloudStorage.GetWriter(ctx, key, ts.options(key, opts)...)
+}
+
+func (ts *writeDefaultsCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	return ts.CloudStorage.Upload(ctx, key, reader, opts, ts.options(key, writeOpts)...)
+}
+
+// Flush flushes the wrapped storage
+func (ts *writeDefaultsCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteDefaults(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	storage := newWriteDefaultsCloudStorage(fake, WriteDefaultsOption{
+		CacheControl: "private, max-age=60",
+		ContentType:  "application/x-ndjson",
+		Metadata:     map[string]string{"service": "billing", "version": "1.4.2"},
+	})
+
+	require.NoError(t, storage.Write(ctx, "events", []byte(`{"id":1}`), nil))
+
+	attrs, err := fake.Attributes(ctx, "events")
+	require.NoError(t, err)
+	require.Equal(t, "private, max-age=60", attrs.CacheControl)
+	require.Equal(t, "application/x-ndjson", attrs.ContentType)
+	require.Equal(t, map[string]string{"service": "billing", "version": "1.4.2"}, attrs.Metadata)
+
+	// the options of the call and the known extensions win over the defaults
+	require.NoError(t, storage.Upload(ctx, "config.json", bytes.NewReader([]byte(`{}`)), nil,
+		WithCacheControl("no-store"), WithMetadata(map[string]string{"version": "1.5.0"})))
+
+	attrs, err = fake.Attributes(ctx, "config.json")
+	require.NoError(t, err)
+	require.Equal(t, "no-store", attrs.CacheControl)
+	require.Equal(t, "application/json", attrs.ContentType)
+	require.Equal(t, map[string]string{"service": "billing", "version": "1.5.0"}, attrs.Metadata)
+
+	writer, err := storage.GetWriter(ctx, "report", WithContentType("text/csv"))
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("id,name\n"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	attrs, err = fake.Attributes(ctx, "report")
+	require.NoError(t, err)
+	require.Equal(t, "text/csv", attrs.ContentType)
+}
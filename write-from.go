@@ -0,0 +1,177 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"io"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// awsMaxPutObjectSize is the largest object S3 accepts in a single PutObject, the larger ones need a multipart upload
+const awsMaxPutObjectSize = 5 << 30
+
+// WriteFrom streams the content of reader to the object of the key, without reading it in memory first like Write.
+// When size isn't negative, the reader must return exactly size bytes, otherwise the write is aborted with
+// an *IntegrityError of the "size". The size lets the providers upload the object in a single request without
+// buffering it: a PutObject with its Content-Length on AWS, up to 5GB, and a single request upload on GCP for the
+// objects smaller than a chunk. When it's -1, the object is uploaded like with GetWriter, in parts or chunks.
+func WriteFrom(
+	ctx context.Context,
+	storage CloudStorage,
+	key string,
+	reader io.Reader,
+	size int64,
+	opts ...WriteOption,
+) error {
+	if size >= 0 {
+		opts = append(opts, withContentLength(size))
+		reader = &exactSizeReader{Reader: reader, key: key, remaining: size, size: size}
+	}
+
+	// the writer is aborted by cancelling its context before closing it
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	writer, err := storage.GetWriter(ctx, key, opts...)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(writer, reader); err != nil {
+		cancel()
+		_ = writer.Close()
+
+		return err
+	}
+
+	return writer.Close()
+}
+
+// exactSizeReader fails with an IntegrityError when its reader doesn't return exactly size bytes
+type exactSizeReader struct {
+	io.Reader
+	key       string
+	size      int64
+	remaining int64
+}
+
+func (r *exactSizeReader) Read(p []byte) (int, error) {
+	if r.remaining == 0 {
+		// a byte more than the size means the reader is longer
+		n, err := r.Reader.Read(make([]byte, 1))
+		if n > 0 {
+			return 0, r.mismatch(">" + strconv.FormatInt(r.size, 10))
+		}
+
+		return 0, err
+	}
+
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+
+	n, err := r.Reader.Read(p)
+	r.remaining -= int64(n)
+
+	if err == io.EOF && r.remaining > 0 {
+		return n, r.mismatch(strconv.FormatInt(r.size-r.remaining, 10))
+	}
+
+	return n, err
+}
+
+func (r *exactSizeReader) mismatch(actual string) error {
+	return &IntegrityError{Key: r.key, Checksum: "size", Expected: strconv.FormatInt(r.size, 10), Actual: actual}
+}
+
+// awsPutWriter returns a writer streaming the object to a single PutObject request with its Content-Length
+func awsPutWriter(
+	ctx context.Context,
+	s3Client *s3.S3,
+	bucketName string,
+	key string,
+	size int64,
+	writeOpts []WriteOption,
+) io.WriteCloser {
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+
+	go func() {
+		err := awsPutObject(ctx, s3Client, bucketName, key, reader, size, writeOpts)
+		reader.CloseWithError(err)
+		done <- err
+	}()
+
+	return &uploadWriter{PipeWriter: writer, done: done}
+}
+
+// awsPutObject uploads the object with a PutObject of size bytes. The payload isn't signed, so the body doesn't have
+// to be read twice to be hashed, and the request isn't retried since the body can't be read again.
+func awsPutObject(
+	ctx context.Context,
+	s3Client *s3.S3,
+	bucketName string,
+	key string,
+	reader io.Reader,
+	size int64,
+	writeOpts []WriteOption,
+) error {
+	call := writeOptions(writeOpts)
+
+	contentType := call.contentTypeOf(nil, key)
+	if contentType == nil {
+		detected, sniffed, err := sniffReader(key, reader)
+		if err != nil {
+			return err
+		}
+
+		contentType, reader = &detected, sniffed
+	}
+
+	upload := &s3manager.UploadInput{ContentType: contentType}
+	call.applyUploadInput(upload)
+
+	req, output := s3Client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket:             aws.String(bucketName),
+		Key:                aws.String(awsEscapeKey(key)),
+		Body:               aws.ReadSeekCloser(reader),
+		ContentLength:      aws.Int64(size),
+		ContentType:        upload.ContentType,
+		CacheControl:       upload.CacheControl,
+		ContentDisposition: upload.ContentDisposition,
+		Metadata:           upload.Metadata,
+		StorageClass:       upload.StorageClass,
+	})
+	req.SetContext(ctx)
+	req.Retryer = client.NoOpRetryer{}
+	req.Handlers.Sign.Swap(v4.SignRequestHandler.Name, v4.BuildNamedHandler(v4.SignRequestHandler.Name, v4.WithUnsignedPayload))
+
+	if err := req.Send(); err != nil {
+		return translateError(err)
+	}
+
+	call.written.set(awsUploadAttributes(upload, &s3manager.UploadOutput{ETag: output.ETag}, size))
+
+	return nil
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+func TestWriteFrom(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+
+	require.NoError(t, WriteFrom(ctx, fake, "known.txt", strings.NewReader("hello world"), 11))
+	require.NoError(t, WriteFrom(ctx, fake, "unknown.txt", strings.NewReader("hello"), -1,
+		WithCacheControl("no-cache")))
+
+	body, err := fake.Get(ctx, "known.txt")
+	require.NoError(t, err)
+	require.Equal(t, "hello world", string(body))
+
+	attrs, err := fake.Attributes(ctx, "unknown.txt")
+	require.NoError(t, err)
+	require.Equal(t, int64(5), attrs.Size)
+	require.Equal(t, "no-cache", attrs.CacheControl)
+
+	// the readers not matching the size don't store anything
+	for body, expected := range map[string]string{"short": "5", "too long": ">6"} {
+		err = WriteFrom(ctx, fake, "mismatch.txt", strings.NewReader(body), 6)
+		require.True(t, errors.Is(err, ErrChecksumMismatch))
+
+		var integrityErr *IntegrityError
+		require.True(t, errors.As(err, &integrityErr))
+		require.Equal(t, IntegrityError{Key: "mismatch.txt", Checksum: "size", Expected: "6", Actual: expected},
+			*integrityErr)
+
+		_, err = fake.Attributes(ctx, "mismatch.txt")
+		require.True(t, errors.Is(err, ErrNotFound))
+	}
+}
+
+func TestAWSPutObject(t *testing.T) {
+	var requests []*http.Request
+
+	var bodies []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+
+		w.Header().Set("ETag", `"5eb63bbbe01eeed093cb22bb8f5acdc3"`)
+	}))
+	defer server.Close()
+
+	awsSession, err := session.NewSession(&aws.Config{
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String(server.URL),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	var attrs Attributes
+
+	writer := awsPutWriter(context.Background(), s3.New(awsSession), "bucket", "reports/a.txt", 11,
+		[]WriteOption{WithMetadata(map[string]string{"Owner": "reports"}), WithWrittenAttributes(&attrs)})
+
+	_, err = writer.Write([]byte("hello "))
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("world"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	require.Len(t, requests, 1)
+	require.Equal(t, http.MethodPut, requests[0].Method)
+	require.Equal(t, "/bucket/reports/a.txt", requests[0].URL.Path)
+	require.Equal(t, int64(11), requests[0].ContentLength)
+	require.Equal(t, "UNSIGNED-PAYLOAD", requests[0].Header.Get("X-Amz-Content-Sha256"))
+	require.Equal(t, "text/plain; charset=utf-8", requests[0].Header.Get("Content-Type"))
+	require.Equal(t, "reports", requests[0].Header.Get("X-Amz-Meta-Owner"))
+	require.Equal(t, "hello world", bodies[0])
+
+	require.Equal(t, int64(11), attrs.Size)
+	require.Equal(t, "5eb63bbbe01eeed093cb22bb8f5acdc3", attrs.ETag)
+}
+
+func TestGCSWriteFromChunkSize(t *testing.T) {
+	client, err := storage.NewClient(context.Background(), option.WithoutAuthentication())
+	require.NoError(t, err)
+
+	defer client.Close()
+
+	for size, chunkSize := range map[int64]int{1 << 20: 0, 1 << 30: 16 << 20} {
+		writer := client.Bucket("bucket").Object("key").NewWriter(context.Background())
+		writer.ChunkSize = 16 << 20
+
+		options := writeOptions([]WriteOption{withContentLength(size)}).writerOptions("key")
+		require.NoError(t, options.BeforeWrite(func(as interface{}) bool {
+			if p, ok := as.(**storage.Writer); ok {
+				*p = writer
+				return true
+			}
+
+			return false
+		}))
+		require.Equal(t, chunkSize, writer.ChunkSize)
+	}
+
+	require.Nil(t, writeOptions(nil).writerOptions("key").BeforeWrite)
+}
@@ -0,0 +1,316 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrWriteOnce is matched by errors.Is when an overwrite or a delete of a write-once object is rejected
+var ErrWriteOnce = errors.New("write-once object")
+
+// WriteOnceError gives the rejected operation on a write-once object
+type WriteOnceError struct {
+	Operation string
+	Key       string
+}
+
+func (e *WriteOnceError) Error() string {
+	return fmt.Sprintf("%s of the write-once object '%s' is rejected", e.Operation, e.Key)
+}
+
+func (e *WriteOnceError) Is(target error) bool {
+	return target == ErrWriteOnce
+}
+
+// WriteOnceOption configures the prefixes of WriteOnceCloudStorage
+type WriteOnceOption struct {
+	// Prefixes are the write-once prefixes, e.g. the append-only audit logs. Every key is write-once when it's empty.
+	Prefixes []string
+}
+
+// WriteOnceCloudStorage rejects the overwrites and the deletes of the write-once objects with a *WriteOnceError,
+// for the buckets where the Object Lock of the provider isn't available. Write and WriteIf are conditional writes
+// failing when the key exists. The Write calls with options, GetWriter, Upload and Copy can't be conditional: the
+// key is checked to be absent first, so a concurrent write of the same key can still go through. The same goes
+// for the upload sessions, checked when they begin and when they're completed, and for Undelete.
+type WriteOnceCloudStorage struct {
+	CloudStorage
+	opts WriteOnceOption
+}
+
+// NewWriteOnceCloudStorage returns the storage rejecting the overwrites and deletes of the keys of the prefixes
+func NewWriteOnceCloudStorage(storage CloudStorage, opts WriteOnceOption) *WriteOnceCloudStorage {
+	return &WriteOnceCloudStorage{CloudStorage: storage, opts: opts}
+}
+
+func (ts *WriteOnceCloudStorage) protected(key string) bool {
+	if len(ts.opts.Prefixes) == 0 {
+		return true
+	}
+
+	for _, prefix := range ts.opts.Prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkAbsent rejects the write when the key exists
+func (ts *WriteOnceCloudStorage) checkAbsent(ctx context.Context, operation string, key string) error {
+	_, err := ts.CloudStorage.Attributes(ctx, key)
+
+	switch {
+	case err == nil:
+		return &WriteOnceError{Operation: operation, Key: key}
+	case errors.Is(err, ErrNotFound):
+		return nil
+	default:
+		return err
+	}
+}
+
+func (ts *WriteOnceCloudStorage) Write(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	opts ...WriteOption,
+) error {
+	if !ts.protected(key) {
+		return ts.CloudStorage.Write(ctx, key, body, contentType, opts...)
+	}
+
+	// WriteIf takes no options
+	if len(opts) > 0 {
+		if err := ts.checkAbsent(ctx, "Write", key); err != nil {
+			return err
+		}
+
+		return ts.CloudStorage.Write(ctx, key, body, contentType, opts...)
+	}
+
+	_, err := ts.WriteIf(ctx, key, body, contentType, WriteCondition{DoesNotExist: true})
+	if errors.Is(err, ErrWriteOnce) {
+		return &WriteOnceError{Operation: "Write", Key: key}
+	}
+
+	return err
+}
+
+// WriteIf rejects the conditions on the generation of the write-once objects, which are overwrites
+func (ts *WriteOnceCloudStorage) WriteIf(
+	ctx context.Context,
+	key string,
+	body []byte,
+	contentType *string,
+	condition WriteCondition,
+) (string, error) {
+	if !ts.protected(key) {
+		return ts.CloudStorage.WriteIf(ctx, key, body, contentType, condition)
+	}
+
+	if !condition.DoesNotExist {
+		return "", &WriteOnceError{Operation: "WriteIf", Key: key}
+	}
+
+	generation, err := ts.CloudStorage.WriteIf(ctx, key, body, contentType, condition)
+	if errors.Is(err, ErrPreconditionFailed) {
+		return "", &WriteOnceError{Operation: "WriteIf", Key: key}
+	}
+
+	return generation, err
+}
+
+func (ts *WriteOnceCloudStorage) GetWriter(
+	ctx context.Context,
+	key string,
+	opts ...WriteOption,
+) (io.WriteCloser, error) {
+	if ts.protected(key) {
+		if err := ts.checkAbsent(ctx, "GetWriter", key); err != nil {
+			return nil, err
+		}
+	}
+
+	return ts.CloudStorage.GetWriter(ctx, key, opts...)
+}
+
+func (ts *WriteOnceCloudStorage) Upload(
+	ctx context.Context,
+	key string,
+	reader io.Reader,
+	opts *UploadOption,
+	writeOpts ...WriteOption,
+) error {
+	if ts.protected(key) {
+		if err := ts.checkAbsent(ctx, "Upload", key); err != nil {
+			return err
+		}
+	}
+
+	return ts.CloudStorage.Upload(ctx, key, reader, opts, writeOpts...)
+}
+
+func (ts *WriteOnceCloudStorage) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	if ts.protected(key) {
+		return &WriteOnceError{Operation: "Delete", Key: key}
+	}
+
+	return ts.CloudStorage.Delete(ctx, key)
+}
+
+func (ts *WriteOnceCloudStorage) DeleteIf(
+	ctx context.Context,
+	key string,
+	generation string,
+) error {
+	if ts.protected(key) {
+		return &WriteOnceError{Operation: "DeleteIf", Key: key}
+	}
+
+	return ts.CloudStorage.DeleteIf(ctx, key, generation)
+}
+
+func (ts *WriteOnceCloudStorage) Copy(
+	ctx context.Context,
+	srcKey string,
+	dstKey string,
+	opts *CopyOption,
+) error {
+	if ts.protected(dstKey) {
+		if err := ts.checkAbsent(ctx, "Copy", dstKey); err != nil {
+			return err
+		}
+	}
+
+	return ts.CloudStorage.Copy(ctx, srcKey, dstKey, opts)
+}
+
+func (ts *WriteOnceCloudStorage) BeginUpload(
+	ctx context.Context,
+	key string,
+	opts *UploadOption,
+) (*UploadSession, error) {
+	if !ts.protected(key) {
+		return ts.CloudStorage.BeginUpload(ctx, key, opts)
+	}
+
+	if err := ts.checkAbsent(ctx, "BeginUpload", key); err != nil {
+		return nil, err
+	}
+
+	session, err := ts.CloudStorage.BeginUpload(ctx, key, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.guardUpload(session), nil
+}
+
+func (ts *WriteOnceCloudStorage) ResumeUpload(
+	ctx context.Context,
+	state *UploadSessionState,
+) (*UploadSession, error) {
+	if state == nil || !ts.protected(state.Key) {
+		return ts.CloudStorage.ResumeUpload(ctx, state)
+	}
+
+	if err := ts.checkAbsent(ctx, "ResumeUpload", state.Key); err != nil {
+		return nil, err
+	}
+
+	session, err := ts.CloudStorage.ResumeUpload(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.guardUpload(session), nil
+}
+
+// guardUpload checks again that the key is absent when the session is completed, the object may have been
+// written since the session began
+func (ts *WriteOnceCloudStorage) guardUpload(session *UploadSession) *UploadSession {
+	return guardUploadSession(session, nil, func(
+		ctx context.Context,
+		state *UploadSessionState,
+		complete func() error,
+	) error {
+		if err := ts.checkAbsent(ctx, "Complete", state.Key); err != nil {
+			return err
+		}
+
+		return complete()
+	})
+}
+
+// Undelete would replace the current object of a write-once key, it's only allowed when the key is absent
+func (ts *WriteOnceCloudStorage) Undelete(
+	ctx context.Context,
+	key string,
+) error {
+	if ts.protected(key) {
+		if err := ts.checkAbsent(ctx, "Undelete", key); err != nil {
+			return err
+		}
+	}
+
+	return ts.CloudStorage.Undelete(ctx, key)
+}
+
+// RenameFolder is rejected when the folder or the new folder holds write-once objects
+func (ts *WriteOnceCloudStorage) RenameFolder(
+	ctx context.Context,
+	folder string,
+	newFolder string,
+) error {
+	for _, name := range []string{folder, newFolder} {
+		if ts.holdsProtected(name) {
+			return &WriteOnceError{Operation: "RenameFolder", Key: name}
+		}
+	}
+
+	return ts.CloudStorage.RenameFolder(ctx, folder, newFolder)
+}
+
+func (ts *WriteOnceCloudStorage) holdsProtected(folder string) bool {
+	if ts.protected(folder) {
+		return true
+	}
+
+	for _, prefix := range ts.opts.Prefixes {
+		if strings.HasPrefix(prefix, folder) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Flush flushes the wrapped storage
+func (ts *WriteOnceCloudStorage) Flush(ctx context.Context) error {
+	return flushStorage(ctx, ts.CloudStorage)
+}
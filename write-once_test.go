@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteOnceCloudStorage(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	storage := NewWriteOnceCloudStorage(fake, WriteOnceOption{Prefixes: []string{"audit/"}})
+
+	require.NoError(t, storage.Write(ctx, "audit/a.jsonl", []byte("a"), nil))
+
+	var writeOnceErr *WriteOnceError
+
+	err := storage.Write(ctx, "audit/a.jsonl", []byte("b"), nil)
+	require.True(t, errors.As(err, &writeOnceErr))
+	require.Equal(t, &WriteOnceError{Operation: "Write", Key: "audit/a.jsonl"}, writeOnceErr)
+
+	err = storage.Write(ctx, "audit/a.jsonl", []byte("b"), nil, WithMetadata(map[string]string{"k": "v"}))
+	require.True(t, errors.Is(err, ErrWriteOnce))
+
+	err = storage.Upload(ctx, "audit/a.jsonl", bytes.NewReader([]byte("b")), nil)
+	require.True(t, errors.Is(err, ErrWriteOnce))
+
+	_, err = storage.GetWriter(ctx, "audit/a.jsonl")
+	require.True(t, errors.Is(err, ErrWriteOnce))
+
+	attrs, err := storage.Attributes(ctx, "audit/a.jsonl")
+	require.NoError(t, err)
+
+	_, err = storage.WriteIf(ctx, "audit/a.jsonl", []byte("b"), nil, WriteCondition{Generation: attrs.Generation})
+	require.True(t, errors.Is(err, ErrWriteOnce))
+
+	require.True(t, errors.Is(storage.Delete(ctx, "audit/a.jsonl"), ErrWriteOnce))
+	require.True(t, errors.Is(storage.DeleteIf(ctx, "audit/a.jsonl", attrs.Generation), ErrWriteOnce))
+	require.True(t, errors.Is(storage.RenameFolder(ctx, "audit/", "archive/"), ErrWriteOnce))
+	require.True(t, errors.Is(storage.RenameFolder(ctx, "logs/", "audit/logs/"), ErrWriteOnce))
+
+	body, err := storage.Get(ctx, "audit/a.jsonl")
+	require.NoError(t, err)
+	require.Equal(t, "a", string(body))
+
+	// the new keys are written, and the other prefixes aren't write-once
+	require.NoError(t, storage.Upload(ctx, "audit/b.jsonl", bytes.NewReader([]byte("b")), nil))
+	require.NoError(t, storage.Write(ctx, "tmp/c.txt", []byte("c"), nil))
+	require.NoError(t, storage.Write(ctx, "tmp/c.txt", []byte("d"), nil))
+	require.NoError(t, storage.Delete(ctx, "tmp/c.txt"))
+}
+
+func TestWriteOnceCloudStorageWithoutPrefixes(t *testing.T) {
+	ctx := context.Background()
+	storage := NewWriteOnceCloudStorage(NewFakeCloudStorage("bucket"), WriteOnceOption{})
+
+	require.NoError(t, storage.Write(ctx, "a.txt", []byte("a"), nil))
+	require.True(t, errors.Is(storage.Write(ctx, "a.txt", []byte("b"), nil), ErrWriteOnce))
+	require.True(t, errors.Is(storage.Delete(ctx, "a.txt"), ErrWriteOnce))
+}
+
+func TestWriteOnceCloudStorageUploadSessions(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+	storage := NewWriteOnceCloudStorage(fake, WriteOnceOption{Prefixes: []string{"audit/"}})
+
+	require.NoError(t, storage.Write(ctx, "audit/1", []byte("original"), nil))
+
+	_, err := storage.BeginUpload(ctx, "audit/1", nil)
+	require.True(t, errors.Is(err, ErrWriteOnce))
+
+	// a session begun before the object was written can't complete
+	session, err := storage.BeginUpload(ctx, "audit/2", nil)
+	require.NoError(t, err)
+	require.NoError(t, session.UploadPart(ctx, 1, []byte("tampered")))
+	require.NoError(t, fake.Write(ctx, "audit/2", []byte("original"), nil))
+
+	state := session.State()
+	_, err = storage.ResumeUpload(ctx, &state)
+	require.True(t, errors.Is(err, ErrWriteOnce))
+
+	var writeOnceErr *WriteOnceError
+
+	err = session.Complete(ctx)
+	require.True(t, errors.As(err, &writeOnceErr))
+	require.Equal(t, &WriteOnceError{Operation: "Complete", Key: "audit/2"}, writeOnceErr)
+
+	for _, key := range []string{"audit/1", "audit/2"} {
+		body, err := storage.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "original", string(body))
+	}
+
+	require.True(t, errors.Is(storage.Undelete(ctx, "audit/1"), ErrWriteOnce))
+
+	// the sessions of the new keys complete
+	session, err = storage.BeginUpload(ctx, "audit/3", nil)
+	require.NoError(t, err)
+	require.NoError(t, session.UploadPart(ctx, 1, []byte("c")))
+	require.NoError(t, session.Complete(ctx))
+
+	body, err := storage.Get(ctx, "audit/3")
+	require.NoError(t, err)
+	require.Equal(t, "c", string(body))
+}
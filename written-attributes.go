@@ -0,0 +1,220 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"gocloud.dev/blob"
+)
+
+// writtenAttributes is the destination of WithWrittenAttributes.
+// The GCS writer of a gocloud bucket is captured by BeforeWrite, to read the attributes of the object once it's closed.
+type writtenAttributes struct {
+	attrs     *Attributes
+	gcsWriter *storage.Writer
+}
+
+// set stores the attributes of the written object, it does nothing when they aren't requested
+func (w *writtenAttributes) set(attrs *Attributes) {
+	if w != nil && w.attrs != nil {
+		*w.attrs = *attrs
+	}
+}
+
+// setFromGCSWriter stores the attributes of the closed GCS writer captured by BeforeWrite
+func (w *writtenAttributes) setFromGCSWriter() {
+	if w != nil && w.gcsWriter != nil {
+		w.set(gcsAttributes(w.gcsWriter.Attrs()))
+	}
+}
+
+// gcsWrittenWriter stores the attributes of the object once the writer of a gocloud GCS bucket is closed
+type gcsWrittenWriter struct {
+	*blob.Writer
+	written *writtenAttributes
+}
+
+func (w *gcsWrittenWriter) Close() error {
+	if err := w.Writer.Close(); err != nil {
+		return err
+	}
+
+	w.written.setFromGCSWriter()
+
+	return nil
+}
+
+// gcsWriter returns the writer of a gocloud GCS bucket, storing the written attributes on Close when they're requested
+func gcsWriter(writer *blob.Writer, call callOptions) io.WriteCloser {
+	if call.written == nil {
+		return writer
+	}
+
+	return &gcsWrittenWriter{Writer: writer, written: call.written}
+}
+
+// awsWrite writes the object with the transfer manager, since the ETag of the writes of the gocloud bucket isn't
+// exposed. It's used when the written attributes are requested.
+func awsWrite(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	key string,
+	body []byte,
+	contentType *string,
+	writeOpts []WriteOption,
+) error {
+	opts := &UploadOption{}
+	if value := writeOptions(writeOpts).contentTypeOf(contentType, key); value != nil {
+		opts.ContentType = *value
+	}
+
+	return awsUpload(ctx, client, bucketName, key, bytes.NewReader(body), opts, writeOpts)
+}
+
+// awsWriter uploads what's written with the transfer manager, Close returns the result of the upload.
+// It's used when the written attributes are requested.
+func awsWriter(
+	ctx context.Context,
+	client *s3.S3,
+	bucketName string,
+	key string,
+	writeOpts []WriteOption,
+) io.WriteCloser {
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+
+	go func() {
+		err := awsUpload(ctx, client, bucketName, key, reader, nil, writeOpts)
+		reader.CloseWithError(err)
+		done <- err
+	}()
+
+	return &uploadWriter{PipeWriter: writer, done: done}
+}
+
+// uploadWriter returns the result of the upload reading the pipe on Close
+type uploadWriter struct {
+	*io.PipeWriter
+	done chan error
+}
+
+func (w *uploadWriter) Close() error {
+	w.PipeWriter.Close()
+
+	return <-w.done
+}
+
+// awsUploadAttributes returns the attributes of an object uploaded by the transfer manager.
+// The modification time isn't returned by S3, it's left zero.
+func awsUploadAttributes(input *s3manager.UploadInput, output *s3manager.UploadOutput, size int64) *Attributes {
+	attrs := &Attributes{
+		CacheControl:       aws.StringValue(input.CacheControl),
+		ContentDisposition: aws.StringValue(input.ContentDisposition),
+		ContentType:        aws.StringValue(input.ContentType),
+		Size:               size,
+	}
+
+	if len(input.Metadata) > 0 {
+		attrs.Metadata = make(map[string]string, len(input.Metadata))
+		for k, v := range input.Metadata {
+			attrs.Metadata[strings.ToLower(k)] = aws.StringValue(v)
+		}
+	}
+
+	attrs.setS3Version(output.ETag)
+
+	return attrs
+}
+
+// gcsAttributes returns the attributes of the GCS object
+func gcsAttributes(objectAttrs *storage.ObjectAttrs) *Attributes {
+	attrs := &Attributes{
+		CacheControl:       objectAttrs.CacheControl,
+		ContentDisposition: objectAttrs.ContentDisposition,
+		ContentEncoding:    objectAttrs.ContentEncoding,
+		ContentLanguage:    objectAttrs.ContentLanguage,
+		ContentType:        objectAttrs.ContentType,
+		Metadata:           objectAttrs.Metadata,
+		ModTime:            objectAttrs.Updated,
+		Size:               objectAttrs.Size,
+		MD5:                objectAttrs.MD5,
+		Encryption:         gcsObjectEncryption(objectAttrs),
+	}
+
+	attrs.setGCSVersion(objectAttrs)
+
+	return attrs
+}
+
+// setVersion sets the generation, the ETag, the CRC32C and the encryption of the S3 or GCS object
+func (a *Attributes) setVersion(attrs *blob.Attributes) {
+	var headOutput s3.HeadObjectOutput
+	if attrs.As(&headOutput) {
+		a.setS3Version(headOutput.ETag)
+		a.Encryption = awsObjectEncryption(
+			headOutput.ServerSideEncryption,
+			headOutput.SSEKMSKeyId,
+			headOutput.SSECustomerAlgorithm,
+			headOutput.SSECustomerKeyMD5,
+		)
+
+		return
+	}
+
+	var objectAttrs storage.ObjectAttrs
+	if attrs.As(&objectAttrs) {
+		a.setGCSVersion(&objectAttrs)
+		a.Encryption = gcsObjectEncryption(&objectAttrs)
+	}
+}
+
+// setS3Version sets the generation and the ETag of the S3 object, and its MD5 unless it was uploaded in parts
+func (a *Attributes) setS3Version(etag *string) {
+	a.Generation = aws.StringValue(etag)
+	a.ETag = strings.Trim(a.Generation, `"`)
+
+	if a.MD5 == nil && !strings.Contains(a.ETag, "-") {
+		if sum, err := hex.DecodeString(a.ETag); err == nil {
+			a.MD5 = sum
+		}
+	}
+}
+
+func (a *Attributes) setGCSVersion(objectAttrs *storage.ObjectAttrs) {
+	a.Generation = strconv.FormatInt(objectAttrs.Generation, 10)
+	a.ETag = objectAttrs.Etag
+	a.CRC32C = crc32cBytes(objectAttrs.CRC32C)
+}
+
+func crc32cBytes(crc uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, crc)
+
+	return b
+}
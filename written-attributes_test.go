@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2020 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ *
+ */
+
+package commonblobgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithWrittenAttributes(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeCloudStorage("bucket")
+
+	var written Attributes
+
+	require.NoError(t, fake.Write(ctx, "config.json", []byte(`{}`), nil, WithWrittenAttributes(&written)))
+
+	attrs, err := fake.Attributes(ctx, "config.json")
+	require.NoError(t, err)
+	require.Equal(t, *attrs, written)
+	require.Equal(t, "99914b932bd37a50b983c5e7c90ae93b", written.ETag)
+	require.Len(t, written.CRC32C, 4)
+
+	writer, err := fake.GetWriter(ctx, "report.csv", WithWrittenAttributes(&written))
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("id,name\n"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	attrs, err = fake.Attributes(ctx, "report.csv")
+	require.NoError(t, err)
+	require.Equal(t, *attrs, written)
+
+	require.NoError(t, fake.Upload(ctx, "archive", bytes.NewReader([]byte("data")), nil, WithWrittenAttributes(&written)))
+
+	attrs, err = fake.Attributes(ctx, "archive")
+	require.NoError(t, err)
+	require.Equal(t, *attrs, written)
+}
+
+func TestAWSUploadAttributes(t *testing.T) {
+	input := &s3manager.UploadInput{
+		ContentType: aws.String("application/json"),
+		Metadata:    aws.StringMap(map[string]string{"Service": "billing"}),
+	}
+
+	attrs := awsUploadAttributes(input, &s3manager.UploadOutput{ETag: aws.String(`"99914b932bd37a50b983c5e7c90ae93b"`)}, 2)
+	require.Equal(t, `"99914b932bd37a50b983c5e7c90ae93b"`, attrs.Generation)
+	require.Equal(t, "99914b932bd37a50b983c5e7c90ae93b", attrs.ETag)
+	require.Equal(t, "99914b932bd37a50b983c5e7c90ae93b", hex.EncodeToString(attrs.MD5))
+	require.Equal(t, "application/json", attrs.ContentType)
+	require.Equal(t, map[string]string{"service": "billing"}, attrs.Metadata)
+	require.Equal(t, int64(2), attrs.Size)
+
+	// the ETag of the multipart uploads isn't the MD5 of the object
+	attrs = awsUploadAttributes(input, &s3manager.UploadOutput{ETag: aws.String(`"d41d8cd98f00b204e9800998ecf8427e-2"`)}, 2)
+	require.Equal(t, "d41d8cd98f00b204e9800998ecf8427e-2", attrs.ETag)
+	require.Nil(t, attrs.MD5)
+}
+
+func TestGCSAttributes(t *testing.T) {
+	updated := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	attrs := gcsAttributes(&storage.ObjectAttrs{
+		ContentType: "text/csv",
+		Updated:     updated,
+		Size:        8,
+		Generation:  1588334400000000,
+		Etag:        "CICAgMDo6OgCEAE=",
+		CRC32C:      0x1a2b3c4d,
+	})
+	require.Equal(t, "1588334400000000", attrs.Generation)
+	require.Equal(t, "CICAgMDo6OgCEAE=", attrs.ETag)
+	require.Equal(t, []byte{0x1a, 0x2b, 0x3c, 0x4d}, attrs.CRC32C)
+	require.Equal(t, updated, attrs.ModTime)
+	require.Equal(t, "text/csv", attrs.ContentType)
+}